@@ -1,11 +1,13 @@
 package balancers
 
 import (
+	"cmp"
 	"container/list"
 	"fmt"
 	"math/rand/v2"
 	"slices"
 
+	"github.com/atopos31/llmio/consts"
 	"github.com/samber/lo"
 )
 
@@ -16,7 +18,23 @@ type Balancer interface {
 	Success(key uint)
 }
 
+// New 按策略名创建负载均衡器并按 priorities 分层，未知策略使用默认策略
+// 新增策略只需在此注册，路由与批次等调用方共用同一入口
+func New(strategy string, items map[uint]int, priorities map[uint]int) Balancer {
+	return NewTiered(items, priorities, func(items map[uint]int) Balancer {
+		switch strategy {
+		case consts.BalancerRotor:
+			return NewRotor(items)
+		case consts.BalancerLatency:
+			return NewLatency(items)
+		default:
+			return NewLottery(items)
+		}
+	})
+}
+
 // 按权重概率抽取，类似抽签。
+// 权重为 0 的关联作为备用，仅在所有正权重关联都失败后按 ID 顺序使用
 type Lottery struct {
 	store   map[uint]int
 	success uint
@@ -38,13 +56,18 @@ func (w *Lottery) Pop() (uint, error) {
 	}
 	total := 0
 	for _, v := range w.store {
-		total += v
+		if v > 0 {
+			total += v
+		}
 	}
 	if total <= 0 {
-		return 0, fmt.Errorf("total provide weight must be greater than 0")
+		return lo.Min(lo.Keys(w.store)), nil
 	}
 	r := rand.IntN(total)
 	for k, v := range w.store {
+		if v <= 0 {
+			continue
+		}
 		if r < v {
 			return k, nil
 		}
@@ -68,8 +91,10 @@ func (w *Lottery) Success(key uint) {
 }
 
 // 按顺序循环轮转，每次降低权重后移到队尾
+// 权重为 0 的关联作为备用，仅在所有正权重关联都失败后使用
 type Rotor struct {
 	*list.List
+	standby map[uint]struct{}
 	success uint
 	fails   map[uint]struct{}
 	reduces map[uint]struct{}
@@ -79,13 +104,21 @@ func NewRotor(items map[uint]int) *Rotor {
 	l := list.New()
 	entries := lo.Entries(items)
 	slices.SortFunc(entries, func(a lo.Entry[uint, int], b lo.Entry[uint, int]) int {
+		if a.Value == b.Value {
+			return cmp.Compare(a.Key, b.Key)
+		}
 		return b.Value - a.Value
 	})
+	standby := make(map[uint]struct{})
 	for _, entry := range entries {
 		l.PushBack(entry.Key)
+		if entry.Value <= 0 {
+			standby[entry.Key] = struct{}{}
+		}
 	}
 	return &Rotor{
 		List:    l,
+		standby: standby,
 		fails:   map[uint]struct{}{},
 		reduces: map[uint]struct{}{},
 	}
@@ -95,8 +128,13 @@ func (w *Rotor) Pop() (uint, error) {
 	if w.Len() == 0 {
 		return 0, fmt.Errorf("no provide items")
 	}
-	e := w.Front()
-	return e.Value.(uint), nil
+	// 降权的正权重关联会被移到备用关联之后，因此需要跳过备用关联查找
+	for e := w.Front(); e != nil; e = e.Next() {
+		if _, ok := w.standby[e.Value.(uint)]; !ok {
+			return e.Value.(uint), nil
+		}
+	}
+	return w.Front().Value.(uint), nil
 }
 
 func (w *Rotor) Delete(key uint) {
@@ -122,3 +160,93 @@ func (w *Rotor) Reduce(key uint) {
 func (w *Rotor) Success(key uint) {
 	w.success = key
 }
+
+// 按优先级分层，数值小的层优先；层内按原策略与权重选择，整层失败后才回落到下一层
+type Tiered struct {
+	tiers []Balancer
+	index map[uint]int
+}
+
+// NewTiered 按 priorities 将 items 分层，每层由 newBalancer 创建；只有一层时直接返回该层的负载均衡器
+func NewTiered(items map[uint]int, priorities map[uint]int, newBalancer func(map[uint]int) Balancer) Balancer {
+	groups := make(map[int]map[uint]int)
+	for key, weight := range items {
+		priority := priorities[key]
+		if groups[priority] == nil {
+			groups[priority] = make(map[uint]int)
+		}
+		groups[priority][key] = weight
+	}
+	if len(groups) <= 1 {
+		return newBalancer(items)
+	}
+	levels := lo.Keys(groups)
+	slices.Sort(levels)
+	t := &Tiered{index: make(map[uint]int, len(items))}
+	for i, level := range levels {
+		t.tiers = append(t.tiers, newBalancer(groups[level]))
+		for key := range groups[level] {
+			t.index[key] = i
+		}
+	}
+	return t
+}
+
+func (t *Tiered) Pop() (uint, error) {
+	for _, tier := range t.tiers {
+		if key, err := tier.Pop(); err == nil {
+			return key, nil
+		}
+	}
+	return 0, fmt.Errorf("no provide items or all items are disabled")
+}
+
+func (t *Tiered) Delete(key uint) {
+	if i, ok := t.index[key]; ok {
+		t.tiers[i].Delete(key)
+	}
+}
+
+func (t *Tiered) Reduce(key uint) {
+	if i, ok := t.index[key]; ok {
+		t.tiers[i].Reduce(key)
+	}
+}
+
+func (t *Tiered) Success(key uint) {
+	if i, ok := t.index[key]; ok {
+		t.tiers[i].Success(key)
+	}
+}
+
+// 优先返回指定关联，该关联被移除或降权后回落到内部策略
+type Preferred struct {
+	Balancer
+	key    uint
+	usable bool
+}
+
+func WithPreferred(balancer Balancer, key uint) *Preferred {
+	return &Preferred{Balancer: balancer, key: key, usable: true}
+}
+
+func (p *Preferred) Pop() (uint, error) {
+	if p.usable {
+		return p.key, nil
+	}
+	return p.Balancer.Pop()
+}
+
+func (p *Preferred) Delete(key uint) {
+	if key == p.key {
+		p.usable = false
+	}
+	p.Balancer.Delete(key)
+}
+
+func (p *Preferred) Reduce(key uint) {
+	if key == p.key {
+		p.usable = false
+	}
+	p.Balancer.Reduce(key)
+}
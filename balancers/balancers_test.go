@@ -1,7 +1,10 @@
 package balancers
 
 import (
+	"fmt"
 	"testing"
+
+	"github.com/atopos31/llmio/consts"
 )
 
 func TestLotteryPopEmpty(t *testing.T) {
@@ -11,10 +14,35 @@ func TestLotteryPopEmpty(t *testing.T) {
 	}
 }
 
-func TestLotteryPopZeroTotal(t *testing.T) {
-	w := NewLottery(map[uint]int{1: 0, 2: 0})
+func TestLotteryStandby(t *testing.T) {
+	w := NewLottery(map[uint]int{1: 0, 2: 5, 3: 0})
+	for range 20 {
+		id, err := w.Pop()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if id != 2 {
+			t.Fatalf("expected positive weight id 2, got %d", id)
+		}
+	}
+	w.Reduce(2)
+	if id, _ := w.Pop(); id != 2 {
+		t.Fatalf("expected reduced id 2 before standby, got %d", id)
+	}
+
+	w.Delete(2)
+	for _, want := range []uint{1, 3} {
+		id, err := w.Pop()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if id != want {
+			t.Fatalf("expected standby id %d, got %d", want, id)
+		}
+		w.Delete(id)
+	}
 	if _, err := w.Pop(); err == nil {
-		t.Fatalf("expected error when total weight is zero")
+		t.Fatalf("expected error after all items failed")
 	}
 }
 
@@ -153,6 +181,21 @@ func TestRotor(t *testing.T) {
 		}
 	})
 
+	t.Run("Standby", func(t *testing.T) {
+		wl := NewRotor(map[uint]int{1: 0, 2: 10, 3: 20})
+		wl.Reduce(3)
+		wl.Reduce(2)
+		// 降权后队列为 [1, 3, 2]，备用关联仍排在正权重关联之后使用
+		if result, _ := wl.Pop(); result != 3 {
+			t.Errorf("Expected 3, got %d", result)
+		}
+		wl.Delete(3)
+		wl.Delete(2)
+		if result, _ := wl.Pop(); result != 1 {
+			t.Errorf("Expected standby 1, got %d", result)
+		}
+	})
+
 	t.Run("Multiple operations", func(t *testing.T) {
 		items := map[uint]int{
 			1: 10,
@@ -287,3 +330,96 @@ func BenchmarkRotor(b *testing.B) {
 		}
 	})
 }
+
+func TestTieredFailover(t *testing.T) {
+	newBalancers := map[string]func(map[uint]int) Balancer{
+		"lottery": func(items map[uint]int) Balancer { return NewLottery(items) },
+		"rotor":   func(items map[uint]int) Balancer { return NewRotor(items) },
+	}
+	for name, newBalancer := range newBalancers {
+		t.Run(name, func(t *testing.T) {
+			items := map[uint]int{1: 5, 2: 5, 3: 100, 4: 0}
+			priorities := map[uint]int{1: 1, 2: 1, 3: 2, 4: 2}
+			w := NewTiered(items, priorities, newBalancer)
+			seen := map[uint]bool{}
+			for range 20 {
+				id, err := w.Pop()
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if id != 1 && id != 2 {
+					t.Fatalf("expected priority-1 id, got %d", id)
+				}
+				seen[id] = true
+				w.Reduce(id)
+			}
+			if name == "rotor" && len(seen) != 2 {
+				t.Fatalf("expected both priority-1 ids, got %v", seen)
+			}
+
+			w.Delete(1)
+			w.Delete(2)
+			for _, want := range []uint{3, 4} {
+				id, err := w.Pop()
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if id != want {
+					t.Fatalf("expected id %d, got %d", want, id)
+				}
+				w.Delete(id)
+			}
+			if _, err := w.Pop(); err == nil {
+				t.Fatalf("expected error after all tiers failed")
+			}
+		})
+	}
+}
+
+func TestTieredSingleTier(t *testing.T) {
+	w := NewTiered(map[uint]int{1: 1, 2: 1}, map[uint]int{1: 1, 2: 1}, func(items map[uint]int) Balancer { return NewRotor(items) })
+	if _, ok := w.(*Rotor); !ok {
+		t.Fatalf("expected plain rotor for a single tier, got %T", w)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     string
+	}{
+		{consts.BalancerLottery, "*balancers.Lottery"},
+		{consts.BalancerRotor, "*balancers.Rotor"},
+		{consts.BalancerLatency, "*balancers.Latency"},
+		{"unknown", "*balancers.Lottery"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			if got := fmt.Sprintf("%T", New(tt.strategy, map[uint]int{1: 1}, nil)); got != tt.want {
+				t.Fatalf("New(%q) = %s, want %s", tt.strategy, got, tt.want)
+			}
+		})
+	}
+	if _, ok := New(consts.BalancerRotor, map[uint]int{1: 1, 2: 1}, map[uint]int{1: 1, 2: 2}).(*Tiered); !ok {
+		t.Fatalf("expected tiered balancer for multiple priorities")
+	}
+}
+
+func TestPreferred(t *testing.T) {
+	w := WithPreferred(NewRotor(map[uint]int{1: 2, 2: 1}), 2)
+	for range 3 {
+		if id, _ := w.Pop(); id != 2 {
+			t.Fatalf("expected preferred id 2, got %d", id)
+		}
+	}
+	w.Reduce(2)
+	if id, _ := w.Pop(); id != 1 {
+		t.Fatalf("expected fallback id 1 after reducing preferred, got %d", id)
+	}
+
+	w = WithPreferred(NewLottery(map[uint]int{1: 1, 2: 1}), 2)
+	w.Delete(2)
+	if id, _ := w.Pop(); id != 1 {
+		t.Fatalf("expected fallback id 1 after deleting preferred, got %d", id)
+	}
+}
@@ -14,10 +14,11 @@ const (
 )
 
 type Node struct {
-	state        State     // 熔断状态
-	failCount    int       // 失败次数
-	successCount int       // 成功次数
-	expiry       time.Time // 冷却结束时间
+	config       BreakerConfig // 最近一次使用该关联的模型的熔断阈值
+	state        State         // 熔断状态
+	failCount    int           // 失败次数
+	successCount int           // 成功次数
+	expiry       time.Time     // 冷却结束时间
 }
 
 func (n *Node) Reset(state State) {
@@ -26,30 +27,73 @@ func (n *Node) Reset(state State) {
 	n.successCount = 0
 }
 
+// NodeStatus 关联熔断器的状态快照
+type NodeStatus struct {
+	Key       uint
+	State     State
+	FailCount int
+	Expiry    time.Time // Open 状态的冷却结束时间
+}
+
+// OnStateChange 熔断状态变化时调用，用于持久化；调用时持有锁，实现不能阻塞
+var OnStateChange func(status NodeStatus)
+
+// changed 通知状态变化，调用方需持有锁
+func changed(key uint, node *Node) {
+	if OnStateChange != nil {
+		OnStateChange(NodeStatus{Key: key, State: node.state, FailCount: node.failCount, Expiry: node.expiry})
+	}
+}
+
 var (
-	mu          sync.Mutex
-	nodes       = make(map[uint]*Node)
-	MaxFailures = 5                // 最多失败次数
-	SleepWindow = 60 * time.Second // 冷却时间
-	MaxRequests = 2                // 在 HalfOpen 状态下, 如果请求成功次数超过此数值，熔断器关闭（恢复）；如果有一个失败，重新进入 Open 状态
+	mu    sync.Mutex
+	nodes = make(map[uint]*Node)
+)
+
+const (
+	DefaultMaxFailures = 5
+	DefaultSleepWindow = 60 * time.Second
+	DefaultMaxRequests = 2
 )
 
+// BreakerConfig 熔断阈值，零值字段使用默认值
+type BreakerConfig struct {
+	MaxFailures int           // 最多失败次数
+	SleepWindow time.Duration // 冷却时间
+	MaxRequests int           // 在 HalfOpen 状态下, 如果请求成功次数超过此数值，熔断器关闭（恢复）；如果有一个失败，重新进入 Open 状态
+}
+
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	if c.MaxFailures <= 0 {
+		c.MaxFailures = DefaultMaxFailures
+	}
+	if c.SleepWindow <= 0 {
+		c.SleepWindow = DefaultSleepWindow
+	}
+	if c.MaxRequests <= 0 {
+		c.MaxRequests = DefaultMaxRequests
+	}
+	return c
+}
+
 type Breaker struct {
 	Balancer
+	config BreakerConfig
 }
 
-func BalancerWrapperBreaker(balancer Balancer) *Breaker {
+func BalancerWrapperBreaker(balancer Balancer, config BreakerConfig) *Breaker {
 	mu.Lock()
 	defer mu.Unlock()
 	for key, node := range nodes {
 		if node.state == StateOpen && node.expiry.Before(time.Now()) {
 			node.Reset(StateHalfOpen)
+			changed(key, node)
 		}
 		if node.state == StateOpen {
 			balancer.Delete(key)
 		}
 	}
-	return &Breaker{Balancer: balancer}
+	return &Breaker{Balancer: balancer, config: config.withDefaults()}
 }
 
 func (b *Breaker) Pop() (uint, error) {
@@ -59,8 +103,10 @@ func (b *Breaker) Pop() (uint, error) {
 	}
 	mu.Lock()
 	defer mu.Unlock()
-	if _, ok := nodes[key]; !ok {
-		nodes[key] = &Node{state: StateClosed}
+	if node, ok := nodes[key]; ok {
+		node.config = b.config
+	} else {
+		nodes[key] = &Node{config: b.config, state: StateClosed}
 	}
 	return key, nil
 }
@@ -79,16 +125,34 @@ func (b *Breaker) failCountAdd(key uint) {
 	mu.Lock()
 	defer mu.Unlock()
 	if node, ok := nodes[key]; ok {
-		node.failCount += 1
-		if node.state == StateClosed && node.failCount >= MaxFailures {
-			node.Reset(StateOpen)
-			node.expiry = time.Now().Add(SleepWindow)
-		}
+		node.config = b.config
+		node.failCountAdd(key)
+	}
+}
 
-		if node.state == StateHalfOpen {
-			node.Reset(StateOpen)
-			node.expiry = time.Now().Add(SleepWindow)
-		}
+// ReportFailure 响应已开始转发后才发现的上游失败(如流中的错误事件)同样计入熔断失败次数，使用该关联最近一次的熔断阈值
+func ReportFailure(key uint) {
+	mu.Lock()
+	defer mu.Unlock()
+	if node, ok := nodes[key]; ok {
+		node.failCountAdd(key)
+	}
+}
+
+// failCountAdd 记录一次失败，调用方需持有锁
+func (n *Node) failCountAdd(key uint) {
+	config := n.config.withDefaults()
+	n.failCount += 1
+	if n.state == StateClosed && n.failCount >= config.MaxFailures {
+		n.Reset(StateOpen)
+		n.expiry = time.Now().Add(config.SleepWindow)
+		changed(key, n)
+	}
+
+	if n.state == StateHalfOpen {
+		n.Reset(StateOpen)
+		n.expiry = time.Now().Add(config.SleepWindow)
+		changed(key, n)
 	}
 }
 
@@ -96,12 +160,103 @@ func (b *Breaker) Success(key uint) {
 	mu.Lock()
 	defer mu.Unlock()
 	if node, ok := nodes[key]; ok {
+		node.config = b.config
 		if node.state == StateHalfOpen {
 			node.successCount += 1
-			if node.successCount >= MaxRequests {
+			if node.successCount >= b.config.MaxRequests {
 				node.Reset(StateClosed)
+				changed(key, node)
 			}
 		}
 	}
 	b.Balancer.Success(key)
 }
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// NodeState 返回关联的熔断状态，未被熔断器记录时视为关闭
+func NodeState(key uint) State {
+	mu.Lock()
+	defer mu.Unlock()
+	node, ok := nodes[key]
+	if !ok {
+		return StateClosed
+	}
+	if node.state == StateOpen && node.expiry.Before(time.Now()) {
+		return StateHalfOpen
+	}
+	return node.state
+}
+
+// ParseState 解析 State.String 的结果，无法识别时视为关闭
+func ParseState(s string) State {
+	switch s {
+	case "open":
+		return StateOpen
+	case "half_open":
+		return StateHalfOpen
+	default:
+		return StateClosed
+	}
+}
+
+// Nodes 返回所有被熔断器记录的关联状态，冷却结束的 Open 视为 HalfOpen
+func Nodes() []NodeStatus {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]NodeStatus, 0, len(nodes))
+	for key, node := range nodes {
+		status := NodeStatus{Key: key, State: node.state, FailCount: node.failCount, Expiry: node.expiry}
+		if status.State == StateOpen && node.expiry.Before(time.Now()) {
+			status.State = StateHalfOpen
+		}
+		list = append(list, status)
+	}
+	return list
+}
+
+// Restore 恢复持久化的熔断状态，用于进程重启后继续冷却
+func Restore(status NodeStatus) {
+	mu.Lock()
+	defer mu.Unlock()
+	nodes[status.Key] = &Node{state: status.State, failCount: status.FailCount, expiry: status.Expiry}
+}
+
+// TripNode 强制打开关联的熔断器，用于上游明确返回额度耗尽等不会很快恢复的错误
+func TripNode(key uint, config BreakerConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	node, ok := nodes[key]
+	if !ok {
+		node = &Node{}
+		nodes[key] = node
+	}
+	node.config = config.withDefaults()
+	node.Reset(StateOpen)
+	node.expiry = time.Now().Add(node.config.SleepWindow)
+	changed(key, node)
+}
+
+// ResetNode 手动关闭关联的熔断器，关联未被熔断器记录时返回 false
+func ResetNode(key uint) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	node, ok := nodes[key]
+	if !ok {
+		return false
+	}
+	if node.state != StateClosed {
+		node.Reset(StateClosed)
+		changed(key, node)
+	}
+	return true
+}
@@ -33,29 +33,12 @@ func resetBreakerState(t *testing.T) {
 	mu.Unlock()
 }
 
-func withBreakerConfig(t *testing.T, maxFailures int, sleepWindow time.Duration, maxRequests int) {
-	t.Helper()
-	oldMaxFailures := MaxFailures
-	oldSleepWindow := SleepWindow
-	oldMaxRequests := MaxRequests
-
-	MaxFailures = maxFailures
-	SleepWindow = sleepWindow
-	MaxRequests = maxRequests
-
-	t.Cleanup(func() {
-		MaxFailures = oldMaxFailures
-		SleepWindow = oldSleepWindow
-		MaxRequests = oldMaxRequests
-	})
-}
-
 func TestBreakerPopInitializesNode(t *testing.T) {
 	resetBreakerState(t)
-	withBreakerConfig(t, 3, 50*time.Millisecond, 2)
+	config := BreakerConfig{MaxFailures: 3, SleepWindow: 50 * time.Millisecond, MaxRequests: 2}
 
 	spy := &spyBalancer{nextKey: 42}
-	breaker := BalancerWrapperBreaker(spy)
+	breaker := BalancerWrapperBreaker(spy, config)
 
 	key, err := breaker.Pop()
 	if err != nil {
@@ -81,10 +64,10 @@ func TestBreakerPopInitializesNode(t *testing.T) {
 
 func TestBreakerDeleteTripsToOpen(t *testing.T) {
 	resetBreakerState(t)
-	withBreakerConfig(t, 3, 80*time.Millisecond, 2)
+	config := BreakerConfig{MaxFailures: 3, SleepWindow: 80 * time.Millisecond, MaxRequests: 2}
 
 	spy := &spyBalancer{nextKey: 7}
-	breaker := BalancerWrapperBreaker(spy)
+	breaker := BalancerWrapperBreaker(spy, config)
 
 	if _, err := breaker.Pop(); err != nil {
 		t.Fatalf("Pop() unexpected error: %v", err)
@@ -107,7 +90,7 @@ func TestBreakerDeleteTripsToOpen(t *testing.T) {
 	node := nodes[7]
 	mu.Unlock()
 	if node.state != StateOpen {
-		t.Fatalf("after %d failures, state = %v, want %v", MaxFailures, node.state, StateOpen)
+		t.Fatalf("after %d failures, state = %v, want %v", config.MaxFailures, node.state, StateOpen)
 	}
 	if node.expiry.Before(beforeTrip) {
 		t.Fatalf("expiry = %v, expected after %v", node.expiry, beforeTrip)
@@ -119,14 +102,14 @@ func TestBreakerDeleteTripsToOpen(t *testing.T) {
 
 func TestBreakerWrapperDeletesOpenNodes(t *testing.T) {
 	resetBreakerState(t)
-	withBreakerConfig(t, 3, 200*time.Millisecond, 2)
+	config := BreakerConfig{MaxFailures: 3, SleepWindow: 200 * time.Millisecond, MaxRequests: 2}
 
 	mu.Lock()
 	nodes[1] = &Node{state: StateOpen, expiry: time.Now().Add(5 * time.Second)}
 	mu.Unlock()
 
 	spy := &spyBalancer{nextKey: 1}
-	_ = BalancerWrapperBreaker(spy)
+	_ = BalancerWrapperBreaker(spy, config)
 
 	if len(spy.deletes) != 1 || spy.deletes[0] != 1 {
 		t.Fatalf("BalancerWrapperBreaker Delete calls = %v, want [1]", spy.deletes)
@@ -135,14 +118,14 @@ func TestBreakerWrapperDeletesOpenNodes(t *testing.T) {
 
 func TestBreakerWrapperMovesExpiredOpenToHalfOpen(t *testing.T) {
 	resetBreakerState(t)
-	withBreakerConfig(t, 3, 200*time.Millisecond, 2)
+	config := BreakerConfig{MaxFailures: 3, SleepWindow: 200 * time.Millisecond, MaxRequests: 2}
 
 	mu.Lock()
 	nodes[1] = &Node{state: StateOpen, expiry: time.Now().Add(-1 * time.Second)}
 	mu.Unlock()
 
 	spy := &spyBalancer{nextKey: 1}
-	_ = BalancerWrapperBreaker(spy)
+	_ = BalancerWrapperBreaker(spy, config)
 
 	if len(spy.deletes) != 0 {
 		t.Fatalf("expected no deletes for expired open node, got %v", spy.deletes)
@@ -158,14 +141,14 @@ func TestBreakerWrapperMovesExpiredOpenToHalfOpen(t *testing.T) {
 
 func TestBreakerHalfOpenSuccessClosesAfterMaxRequests(t *testing.T) {
 	resetBreakerState(t)
-	withBreakerConfig(t, 3, 200*time.Millisecond, 2)
+	config := BreakerConfig{MaxFailures: 3, SleepWindow: 200 * time.Millisecond, MaxRequests: 2}
 
 	mu.Lock()
 	nodes[7] = &Node{state: StateHalfOpen}
 	mu.Unlock()
 
 	spy := &spyBalancer{nextKey: 7}
-	breaker := BalancerWrapperBreaker(spy)
+	breaker := BalancerWrapperBreaker(spy, config)
 
 	breaker.Success(7)
 	mu.Lock()
@@ -180,7 +163,7 @@ func TestBreakerHalfOpenSuccessClosesAfterMaxRequests(t *testing.T) {
 	node := nodes[7]
 	mu.Unlock()
 	if node.state != StateClosed {
-		t.Fatalf("after %d successes, state = %v, want %v", MaxRequests, node.state, StateClosed)
+		t.Fatalf("after %d successes, state = %v, want %v", config.MaxRequests, node.state, StateClosed)
 	}
 	if node.successCount != 0 {
 		t.Fatalf("successCount = %d, want 0 after reset", node.successCount)
@@ -192,14 +175,14 @@ func TestBreakerHalfOpenSuccessClosesAfterMaxRequests(t *testing.T) {
 
 func TestBreakerHalfOpenDeleteReopens(t *testing.T) {
 	resetBreakerState(t)
-	withBreakerConfig(t, 3, 200*time.Millisecond, 2)
+	config := BreakerConfig{MaxFailures: 3, SleepWindow: 200 * time.Millisecond, MaxRequests: 2}
 
 	mu.Lock()
 	nodes[7] = &Node{state: StateHalfOpen}
 	mu.Unlock()
 
 	spy := &spyBalancer{nextKey: 7}
-	breaker := BalancerWrapperBreaker(spy)
+	breaker := BalancerWrapperBreaker(spy, config)
 
 	before := time.Now()
 	breaker.Delete(7)
@@ -217,3 +200,77 @@ func TestBreakerHalfOpenDeleteReopens(t *testing.T) {
 		t.Fatalf("underlying Delete calls = %v, want [7]", spy.deletes)
 	}
 }
+
+func TestBreakerStateChangeAndReset(t *testing.T) {
+	resetBreakerState(t)
+	config := BreakerConfig{MaxFailures: 1, SleepWindow: time.Minute, MaxRequests: 1}
+	var changes []NodeStatus
+	OnStateChange = func(status NodeStatus) { changes = append(changes, status) }
+	t.Cleanup(func() { OnStateChange = nil })
+
+	breaker := BalancerWrapperBreaker(&spyBalancer{nextKey: 7}, config)
+	if _, err := breaker.Pop(); err != nil {
+		t.Fatalf("Pop failed: %v", err)
+	}
+	ReportFailure(7)
+	if len(changes) != 1 || changes[0].Key != 7 || changes[0].State != StateOpen {
+		t.Fatalf("changes = %+v, want open for 7", changes)
+	}
+	if ResetNode(99) {
+		t.Fatal("ResetNode should report unknown key")
+	}
+	if !ResetNode(7) || NodeState(7) != StateClosed {
+		t.Fatalf("ResetNode did not close breaker, state = %v", NodeState(7))
+	}
+	if last := changes[len(changes)-1]; last.State != StateClosed {
+		t.Fatalf("last change = %+v, want closed", last)
+	}
+
+	Restore(NodeStatus{Key: 8, State: StateOpen, FailCount: 1, Expiry: time.Now().Add(-time.Second)})
+	if NodeState(8) != StateHalfOpen {
+		t.Fatalf("restored expired node state = %v, want half-open", NodeState(8))
+	}
+	if ParseState(StateHalfOpen.String()) != StateHalfOpen || ParseState("unknown") != StateClosed {
+		t.Fatal("ParseState round trip failed")
+	}
+}
+
+func TestBreakerPerModelThresholds(t *testing.T) {
+	resetBreakerState(t)
+
+	strict := BalancerWrapperBreaker(&spyBalancer{nextKey: 1}, BreakerConfig{MaxFailures: 1, SleepWindow: time.Minute})
+	lenient := BalancerWrapperBreaker(&spyBalancer{nextKey: 2}, BreakerConfig{})
+	for _, b := range []*Breaker{strict, lenient} {
+		if _, err := b.Pop(); err != nil {
+			t.Fatalf("Pop() unexpected error: %v", err)
+		}
+	}
+	ReportFailure(1)
+	ReportFailure(2)
+	if NodeState(1) != StateOpen {
+		t.Fatalf("strict node state = %v, want %v", NodeState(1), StateOpen)
+	}
+	if NodeState(2) != StateClosed {
+		t.Fatalf("lenient node state = %v, want %v", NodeState(2), StateClosed)
+	}
+	for range DefaultMaxFailures - 1 {
+		lenient.Delete(2)
+	}
+	if NodeState(2) != StateOpen {
+		t.Fatalf("lenient node after %d failures = %v, want %v", DefaultMaxFailures, NodeState(2), StateOpen)
+	}
+}
+
+func TestTripNode(t *testing.T) {
+	resetBreakerState(t)
+
+	TripNode(3, BreakerConfig{SleepWindow: time.Minute})
+	if NodeState(3) != StateOpen {
+		t.Fatalf("tripped node state = %v, want %v", NodeState(3), StateOpen)
+	}
+	spy := &spyBalancer{nextKey: 3}
+	BalancerWrapperBreaker(spy, BreakerConfig{})
+	if len(spy.deletes) != 1 || spy.deletes[0] != 3 {
+		t.Fatalf("BalancerWrapperBreaker Delete calls = %v, want [3]", spy.deletes)
+	}
+}
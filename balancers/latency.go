@@ -0,0 +1,108 @@
+package balancers
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+var (
+	latencyMu sync.RWMutex
+	latencies = make(map[uint]float64) // 关联首字耗时的指数移动平均，单位纳秒
+	// 新样本在移动平均中的占比
+	LatencyDecay = 0.3
+)
+
+// ObserveLatency 记录关联一次成功请求的首字耗时
+func ObserveLatency(key uint, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	latencyMu.Lock()
+	defer latencyMu.Unlock()
+	v := float64(d)
+	if old, ok := latencies[key]; ok {
+		v = old + LatencyDecay*(v-old)
+	}
+	latencies[key] = v
+}
+
+// faster a 是否比 b 更快，没有样本的关联优先以便尽快取得样本
+func faster(a, b uint) bool {
+	latencyMu.RLock()
+	defer latencyMu.RUnlock()
+	la, okA := latencies[a]
+	lb, okB := latencies[b]
+	if !okA || !okB {
+		return !okA && okB
+	}
+	return la < lb
+}
+
+// 两次随机选择(P2C)，随机抽取两个关联并选择首字耗时移动平均更低者
+// 权重为 0 的关联作为备用，降权的关联仅在其他正权重关联都失败后使用
+type Latency struct {
+	store   map[uint]int
+	success uint
+	fails   map[uint]struct{}
+	reduces map[uint]struct{}
+}
+
+func NewLatency(items map[uint]int) *Latency {
+	return &Latency{
+		store:   items,
+		fails:   map[uint]struct{}{},
+		reduces: map[uint]struct{}{},
+	}
+}
+
+func (w *Latency) Pop() (uint, error) {
+	if len(w.store) == 0 {
+		return 0, fmt.Errorf("no provide items or all items are disabled")
+	}
+	var candidates, reduced []uint
+	for k, v := range w.store {
+		if v <= 0 {
+			continue
+		}
+		if _, ok := w.reduces[k]; ok {
+			reduced = append(reduced, k)
+			continue
+		}
+		candidates = append(candidates, k)
+	}
+	if len(candidates) == 0 {
+		candidates = reduced
+	}
+	switch len(candidates) {
+	case 0:
+		return lo.Min(lo.Keys(w.store)), nil
+	case 1:
+		return candidates[0], nil
+	}
+	i := rand.IntN(len(candidates))
+	j := rand.IntN(len(candidates) - 1)
+	if j >= i {
+		j++
+	}
+	if faster(candidates[j], candidates[i]) {
+		return candidates[j], nil
+	}
+	return candidates[i], nil
+}
+
+func (w *Latency) Delete(key uint) {
+	w.fails[key] = struct{}{}
+	delete(w.store, key)
+}
+
+func (w *Latency) Reduce(key uint) {
+	w.reduces[key] = struct{}{}
+}
+
+func (w *Latency) Success(key uint) {
+	w.success = key
+}
@@ -0,0 +1,69 @@
+package balancers
+
+import (
+	"testing"
+	"time"
+)
+
+func resetLatencies(t *testing.T) {
+	t.Helper()
+	latencyMu.Lock()
+	latencies = make(map[uint]float64)
+	latencyMu.Unlock()
+	t.Cleanup(func() {
+		latencyMu.Lock()
+		latencies = make(map[uint]float64)
+		latencyMu.Unlock()
+	})
+}
+
+func TestObserveLatency(t *testing.T) {
+	resetLatencies(t)
+	ObserveLatency(1, 100*time.Millisecond)
+	ObserveLatency(1, 200*time.Millisecond)
+	ObserveLatency(1, 0)
+	want := float64(100*time.Millisecond) + LatencyDecay*float64(100*time.Millisecond)
+	if got := latencies[1]; got != want {
+		t.Fatalf("latency = %v, want %v", got, want)
+	}
+}
+
+func TestLatencyPop(t *testing.T) {
+	resetLatencies(t)
+	ObserveLatency(1, 500*time.Millisecond)
+	ObserveLatency(2, 100*time.Millisecond)
+
+	w := NewLatency(map[uint]int{1: 1, 2: 1, 3: 0})
+	for range 20 {
+		if id, _ := w.Pop(); id != 2 {
+			t.Fatalf("expected faster id 2, got %d", id)
+		}
+	}
+
+	// 降权后优先使用其他正权重关联
+	w.Reduce(2)
+	if id, _ := w.Pop(); id != 1 {
+		t.Fatalf("expected id 1 after reducing 2, got %d", id)
+	}
+	w.Delete(1)
+	if id, _ := w.Pop(); id != 2 {
+		t.Fatalf("expected reduced id 2 before standby, got %d", id)
+	}
+	w.Delete(2)
+	if id, _ := w.Pop(); id != 3 {
+		t.Fatalf("expected standby id 3, got %d", id)
+	}
+	w.Delete(3)
+	if _, err := w.Pop(); err == nil {
+		t.Fatalf("expected error after all items failed")
+	}
+}
+
+func TestLatencyPrefersUnsampled(t *testing.T) {
+	resetLatencies(t)
+	ObserveLatency(1, 100*time.Millisecond)
+	w := NewLatency(map[uint]int{1: 1, 2: 1})
+	if id, _ := w.Pop(); id != 2 {
+		t.Fatalf("expected unsampled id 2, got %d", id)
+	}
+}
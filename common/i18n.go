@@ -0,0 +1,27 @@
+package common
+
+import (
+	"errors"
+
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// Lang 返回当前请求使用的响应语言
+func Lang(c *gin.Context) string {
+	return i18n.Resolve(c.GetHeader("Accept-Language"))
+}
+
+// T 按当前请求语言翻译消息
+func T(c *gin.Context, msg i18n.Message, args ...any) string {
+	return i18n.T(Lang(c), msg, args...)
+}
+
+// ErrorText 返回错误在当前请求语言下的文本，非本地化错误原样返回
+func ErrorText(c *gin.Context, err error) string {
+	var localized *i18n.Error
+	if errors.As(err, &localized) {
+		return localized.Localize(Lang(c))
+	}
+	return err.Error()
+}
@@ -29,6 +29,9 @@ const DefaultPageSize = 20
 // MaxPageSize 最大每页大小
 const MaxPageSize = 100
 
+// MaxOffset 最大跳过行数，更深的翻页需要缩小筛选范围，避免大 OFFSET 扫描拖慢数据库
+const MaxOffset = 10000
+
 // ParsePagination 从 Gin Context 解析分页参数
 // 参数无效时返回错误信息，调用者应该使用 common.BadRequest 响应
 func ParsePagination(c *gin.Context) (PaginationParams, error) {
@@ -54,6 +57,9 @@ func ParsePaginationWithDefaults(c *gin.Context, defaultPage, defaultPageSize in
 		}
 		pageSize = ps
 	}
+	if (page-1)*pageSize > MaxOffset {
+		return PaginationParams{}, fmt.Errorf("page too deep (max %d rows skipped), narrow the filters", MaxOffset)
+	}
 
 	return PaginationParams{
 		Page:     page,
@@ -7,18 +7,110 @@ const (
 	StyleOpenAIRes Style = "openai-res"
 	StyleAnthropic Style = "anthropic"
 	StyleGemini    Style = "gemini"
+	// Gemini 的 OpenAI 兼容端点，按 openai 协议转发
+	StyleGeminiOpenAI Style = "gemini-openai"
+	// Ollama 原生 /api/chat，仅作为提供商类型，由 openai 请求转换后混入同一模型池
+	StyleOllama Style = "ollama"
+	// 向量化请求，由 openai 兼容提供商的 /embeddings 端点处理
+	StyleEmbedding Style = "embedding"
+	// 图片生成/编辑请求，由 openai 兼容提供商的 /images/* 端点处理
+	StyleImageGeneration Style = "image-generation"
+	// 重排序请求（Cohere/Jina 兼容），由 openai 兼容提供商的 /rerank 端点处理
+	StyleRerank Style = "rerank"
+	// 内容审核请求，由 openai 兼容提供商的 /moderations 端点处理
+	StyleModeration Style = "moderation"
 )
 
+// ProviderTypes 返回可处理某一请求协议的提供商类型
+func ProviderTypes(style Style) []string {
+	switch style {
+	case StyleOpenAI, StyleEmbedding, StyleImageGeneration, StyleRerank, StyleModeration:
+		return []string{StyleOpenAI, StyleGeminiOpenAI}
+	default:
+		return []string{style}
+	}
+}
+
 const (
 	// 按权重概率抽取，类似抽签。
 	BalancerLottery = "lottery"
 	// 按顺序循环轮转，每次降低权重后移到队尾
 	BalancerRotor = "rotor"
+	// 随机抽取两个关联，选择首字耗时移动平均更低者
+	BalancerLatency = "latency"
 	// 默认策略
 	BalancerDefault = BalancerLottery
 )
 
+// Balancers 可用的负载均衡策略
+var Balancers = []string{BalancerLottery, BalancerRotor, BalancerLatency}
+
+// StrategyHeader 管理员 Token 调用时可用该请求头临时指定负载均衡策略
+const StrategyHeader = "X-LLMIO-Strategy"
+
+// RequestIDHeader 请求 ID 请求头，客户端传入合法值时沿用，否则由服务端生成并在响应中返回
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	// 下线观察中，所有关联权重已置 0
+	RetireStateRetiring = "retiring"
+	// 已归档，关联全部停用
+	RetireStateArchived = "archived"
+)
+
+const (
+	// 参数越界处理方式
+	ParamRangeClamp  = "clamp"
+	ParamRangeReject = "reject"
+)
+
+const (
+	// IO 记录脱敏规则：去除图片等 base64 内容，其余规则按 JSON 字段名脱敏
+	IORedactImageBase64 = "image_base64"
+)
+
+const (
+	// 频率限制模式
+	RateLimitEnforce = "enforce"
+	RateLimitObserve = "observe"
+)
+
+const (
+	// 提供商预算周期
+	BudgetPeriodDay   = "day"
+	BudgetPeriodMonth = "month"
+)
+
+const (
+	// 上游错误分类
+	ErrorClassQuotaExhausted = "quota_exhausted"
+	ErrorClassAuthFailed     = "auth_failed"
+	ErrorClassContentFilter  = "content_filter"
+	ErrorClassTransient      = "transient"
+
+	// 错误分类命中后的处理动作
+	ErrorActionReduceWeight = "reduce_weight"
+	ErrorActionOpenBreaker  = "open_breaker"
+	ErrorActionDisableKey   = "disable_key"
+	ErrorActionFailFast     = "fail_fast"
+)
+
+const (
+	// 厂商状态
+	VendorOperational = "operational"
+	VendorDegraded    = "degraded"
+)
+
+const (
+	// 蓝绿路由分组
+	SlotBlue  = "blue"
+	SlotGreen = "green"
+)
+
 const (
 	KeyPrefix = "sk-llmio-"
 	KeyLength = 32
 )
+
+// 内置虚拟模型，由网关直接响应，不请求上游，用于连通性与鉴权探测
+const PingModel = "llmio-ping"
@@ -7,8 +7,16 @@ const (
 	ContextKeyAllowModels   ContextKey = "allow_models"
 	ContextKeyAuthKeyID     ContextKey = "auth_key_id"
 	ContextKeyAuthKeyIOLog  ContextKey = "auth_key_io_log"
+	// 该 Key 的频率限制，值为 models.RateLimit
+	ContextKeyAuthKeyRateLimit ContextKey = "auth_key_rate_limit"
+	// 使用管理员 Token 或未配置 Token 时为 true
+	ContextKeyAdmin ContextKey = "admin"
+	// 本次调用的请求 ID，用于关联访问日志与请求日志
+	ContextKeyRequestID ContextKey = "request_id"
 )
 
 const (
 	ContextKeyGeminiStream ContextKey = "gemini_stream"
+	// openai 兼容提供商的请求路径，为空时使用 /chat/completions
+	ContextKeyOpenAIEndpoint ContextKey = "openai_endpoint"
 )
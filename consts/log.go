@@ -6,4 +6,9 @@ const (
 	StatusSuccess Status = "success"
 	StatusRunning Status = "running"
 	StatusError   Status = "error"
+	// StatusCanceled 客户端在响应完成前断开，记录已转发部分的用量
+	StatusCanceled Status = "canceled"
 )
+
+// HealthExcludedStatuses 不反映提供商健康状况的日志状态：进行中的请求尚无结果，客户端中途取消与提供商无关
+var HealthExcludedStatuses = []string{StatusRunning, StatusCanceled}
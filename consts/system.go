@@ -1,5 +1,13 @@
 package consts
 
+import "time"
+
 const (
 	DefaultPort = "7070"
+
+	// GracefulShutdownTimeout 收到退出信号后等待进行中请求与日志写入完成的最长时间
+	GracefulShutdownTimeout = 30 * time.Second
+
+	// UserAgentPassthrough 提供商 User-Agent 设置为该值时透传客户端 UA
+	UserAgentPassthrough = "passthrough"
 )
@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"slices"
 	"strconv"
 	"strings"
@@ -11,6 +13,7 @@ import (
 	"github.com/atopos31/llmio/common"
 	"github.com/atopos31/llmio/consts"
 	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/i18n"
 	"github.com/atopos31/llmio/providers"
 	"github.com/atopos31/llmio/service"
 	"github.com/gin-gonic/gin"
@@ -20,22 +23,48 @@ import (
 
 // ProviderRequest represents the request body for creating/updating a provider
 type ProviderRequest struct {
-	Name         string `json:"name"`
-	Type         string `json:"type"`
-	Config       string `json:"config"`
-	Console      string `json:"console"`
-	Proxy        string `json:"proxy"`
-	ErrorMatcher string `json:"error_matcher"`
+	Name         string                  `json:"name"`
+	Type         string                  `json:"type"`
+	Config       string                  `json:"config"`
+	Console      string                  `json:"console"`
+	Proxy        string                  `json:"proxy"`
+	ErrorMatcher string                  `json:"error_matcher"`
+	ErrorClasses []models.ErrorClassRule `json:"error_classes"` // 错误分类规则，为空时保持不变，传 [] 清除
+	CostHeaders  string                  `json:"cost_headers"`
+	UserAgent    string                  `json:"user_agent"`
+	QuotaGroup   *string                 `json:"quota_group"`  // 共享额度分组，为空时保持不变，传空字符串移出分组
+	RateLimit    *models.RateLimit       `json:"rate_limit"`   // 为空时保持不变
+	TLS          *models.ProviderTLS     `json:"tls"`          // 为空时保持不变
+	Budget       *models.ProviderBudget  `json:"budget"`       // 为空时保持不变
+	Headers      map[string]string       `json:"headers"`      // 默认请求头，为空时保持不变，传 {} 清除
+	QueryParams  map[string]string       `json:"query_params"` // 默认查询参数，为空时保持不变，传 {} 清除
+	DNSServer    string                  `json:"dns_server"`
+	// HostOverrides 域名到 IP 的固定解析，为空时保持不变，传 {} 清除
+	HostOverrides map[string]string `json:"host_overrides"`
 }
 
 // ModelRequest represents the request body for creating/updating a model
 type ModelRequest struct {
-	Name     string `json:"name"`
-	Remark   string `json:"remark"`
-	MaxRetry int    `json:"max_retry"`
-	TimeOut  int    `json:"time_out"`
-	Strategy string `json:"strategy"`
-	Breaker  bool   `json:"breaker"`
+	Name              string                    `json:"name"`
+	Remark            string                    `json:"remark"`
+	MaxRetry          int                       `json:"max_retry"`
+	TimeOut           int                       `json:"time_out"`
+	RetryReserve      *int                      `json:"retry_reserve"`  // 剩余秒数不足时不再重试，为空时不修改，0 表示不限制
+	StickyMinutes     *int                      `json:"sticky_minutes"` // 会话粘滞分钟数，为空时不修改，0 表示关闭
+	HedgeAfter        *int                      `json:"hedge_after_ms"` // 对冲阈值毫秒，为空时不修改，0 表示关闭
+	StreamBuffer      *int                      `json:"stream_buffer"`  // 流式响应缓冲字节数，为空时不修改，0 表示关闭
+	Webhook           *string                   `json:"webhook"`        // 请求完成后的回调地址，为空时不修改，传空字符串关闭
+	ContextLength     *int                      `json:"context_length"` // 上下文长度，为空时不修改，0 表示未知
+	Strategy          string                    `json:"strategy"`
+	Breaker           bool                      `json:"breaker"`
+	ValidateResponse  bool                      `json:"validate_response"`  // 校验非流式响应，空响应视为失败
+	RawPassthrough    bool                      `json:"raw_passthrough"`    // 原始透传，跳过响应处理与用量统计
+	ParamRanges       *models.ParamRanges       `json:"param_ranges"`       // 为空时不修改，传 {} 清除
+	Deprecation       *models.Deprecation       `json:"deprecation"`        // 为空时不修改，传 {} 取消弃用
+	IOLogPolicy       *models.IOLogPolicy       `json:"io_log_policy"`      // 为空时不修改，传 {} 恢复全量记录
+	ResponseGuard     *models.ResponseGuard     `json:"response_guard"`     // 为空时不修改，传 {} 清除
+	BreakerThresholds *models.BreakerThresholds `json:"breaker_thresholds"` // 为空时不修改，传 {} 恢复默认阈值
+	RetryPolicy       *models.RetryPolicy       `json:"retry_policy"`       // 为空时不修改，传 {} 恢复立即重试
 }
 
 type ModelOrderRequest struct {
@@ -50,14 +79,29 @@ type ModelWithProviderRequest struct {
 	ToolCall         bool              `json:"tool_call"`
 	StructuredOutput bool              `json:"structured_output"`
 	Image            bool              `json:"image"`
+	Chat             *bool             `json:"chat"` // 未传入时默认可处理对话请求
+	Embedding        bool              `json:"embedding"`
+	ImageGeneration  bool              `json:"image_generation"`
+	Rerank           bool              `json:"rerank"`
+	Moderation       bool              `json:"moderation"`
 	WithHeader       bool              `json:"with_header"`
+	PseudoStream     bool              `json:"pseudo_stream"`
+	StreamAggregate  bool              `json:"stream_aggregate"`
 	CustomerHeaders  map[string]string `json:"customer_headers"`
 	ExtraBody        map[string]any    `json:"extra_body"`
 	Weight           int               `json:"weight"`
+	Priority         int               `json:"priority"`         // 未传入或小于 1 时为 1
+	MaxInFlight      int               `json:"max_in_flight"`    // 最大并发请求数，0 表示不限制
+	QueueTimeout     int               `json:"queue_timeout_ms"` // 并发已满时的排队等待毫秒数
+	QuotaRPM         int               `json:"quota_rpm"`        // 每分钟请求数配额，0 表示不限制
+	QuotaTPM         int               `json:"quota_tpm"`        // 每分钟 Token 配额，0 表示不限制
+	Schedule         string            `json:"schedule"`         // 可用时间窗口，如 "mon-fri 09:00-18:00"，空表示始终可用
 	InputPrice       float64           `json:"input_price"`
 	CacheReadPrice   float64           `json:"cache_read_price"`
 	OutputPrice      float64           `json:"output_price"`
 	Currency         string            `json:"currency"`
+	Slot             string            `json:"slot"`
+	Remark           string            `json:"remark"`
 }
 
 // ModelProviderStatusRequest represents the request body for updating provider status
@@ -112,7 +156,7 @@ func GetProviderModels(c *gin.Context) {
 		common.InternalServerError(c, err.Error())
 		return
 	}
-	chatModel, err := providers.New(provider.Type, provider.Config, provider.Proxy)
+	chatModel, err := providers.New(c.Request.Context(), provider.Type, provider.Config, provider.ClientOptions())
 	if err != nil {
 		common.InternalServerError(c, "Failed to get models: "+err.Error())
 		return
@@ -129,10 +173,31 @@ func GetProviderModels(c *gin.Context) {
 func CreateProvider(c *gin.Context) {
 	var req ProviderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request body: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
 		return
 	}
 
+	if req.RateLimit != nil && !validRateLimit(*req.RateLimit) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRateLimit))
+		return
+	}
+	if req.TLS != nil && req.TLS.CACert != "" {
+		if _, err := providers.ParseCACert(req.TLS.CACert); err != nil {
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidCACert, err.Error()))
+			return
+		}
+	}
+	if req.Budget != nil && !validBudget(*req.Budget) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidBudget))
+		return
+	}
+	for i, rule := range req.ErrorClasses {
+		if !service.ValidErrorClass(rule) {
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidErrorClass, i+1))
+			return
+		}
+	}
+
 	// Check if provider exists
 	count, err := gorm.G[models.Provider](models.DB).Where("name = ?", req.Name).Count(c.Request.Context(), "id")
 	if err != nil {
@@ -141,17 +206,28 @@ func CreateProvider(c *gin.Context) {
 	}
 
 	if count > 0 {
-		common.BadRequest(c, "Provider already exists")
+		common.BadRequest(c, common.T(c, i18n.MsgProviderExists))
 		return
 	}
 
 	provider := models.Provider{
-		Name:         req.Name,
-		Type:         req.Type,
-		Config:       req.Config,
-		Console:      req.Console,
-		Proxy:        req.Proxy,
-		ErrorMatcher: req.ErrorMatcher,
+		Name:          req.Name,
+		Type:          req.Type,
+		Config:        req.Config,
+		Console:       req.Console,
+		Proxy:         req.Proxy,
+		ErrorMatcher:  req.ErrorMatcher,
+		ErrorClasses:  req.ErrorClasses,
+		CostHeaders:   req.CostHeaders,
+		UserAgent:     req.UserAgent,
+		QuotaGroup:    strings.TrimSpace(lo.FromPtr(req.QuotaGroup)),
+		RateLimit:     lo.FromPtr(req.RateLimit),
+		TLS:           lo.FromPtr(req.TLS),
+		Budget:        lo.FromPtr(req.Budget),
+		Headers:       req.Headers,
+		QueryParams:   req.QueryParams,
+		DNSServer:     req.DNSServer,
+		HostOverrides: req.HostOverrides,
 	}
 
 	if err := gorm.G[models.Provider](models.DB).Create(c.Request.Context(), &provider); err != nil {
@@ -167,20 +243,41 @@ func UpdateProvider(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID format")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
 		return
 	}
 
 	var req ProviderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request body: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
+		return
+	}
+
+	if req.RateLimit != nil && !validRateLimit(*req.RateLimit) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRateLimit))
 		return
 	}
+	if req.TLS != nil && req.TLS.CACert != "" {
+		if _, err := providers.ParseCACert(req.TLS.CACert); err != nil {
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidCACert, err.Error()))
+			return
+		}
+	}
+	if req.Budget != nil && !validBudget(*req.Budget) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidBudget))
+		return
+	}
+	for i, rule := range req.ErrorClasses {
+		if !service.ValidErrorClass(rule) {
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidErrorClass, i+1))
+			return
+		}
+	}
 
 	// Check if provider exists
 	if _, err := gorm.G[models.Provider](models.DB).Where("id = ?", id).First(c.Request.Context()); err != nil {
 		if err == gorm.ErrRecordNotFound {
-			common.NotFound(c, "Provider not found")
+			common.NotFound(c, common.T(c, i18n.MsgProviderNotFound))
 			return
 		}
 		common.InternalServerError(c, "Database error: "+err.Error())
@@ -189,18 +286,55 @@ func UpdateProvider(c *gin.Context) {
 
 	// Update fields
 	updates := models.Provider{
-		Name:         req.Name,
-		Type:         req.Type,
-		Config:       req.Config,
-		Console:      req.Console,
-		Proxy:        req.Proxy,
-		ErrorMatcher: req.ErrorMatcher,
-	}
-
-	if _, err := gorm.G[models.Provider](models.DB).Where("id = ?", id).Updates(c.Request.Context(), updates); err != nil {
+		Name:          req.Name,
+		Type:          req.Type,
+		Config:        req.Config,
+		Console:       req.Console,
+		Proxy:         req.Proxy,
+		ErrorMatcher:  req.ErrorMatcher,
+		ErrorClasses:  req.ErrorClasses,
+		CostHeaders:   req.CostHeaders,
+		UserAgent:     req.UserAgent,
+		Headers:       req.Headers,
+		QueryParams:   req.QueryParams,
+		DNSServer:     req.DNSServer,
+		HostOverrides: req.HostOverrides,
+	}
+
+	// 在同一事务中写入全部字段并递增配置版本，路由读取到的要么是完整的旧版本，要么是完整的新版本
+	err = models.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		ctx := c.Request.Context()
+		if _, err := gorm.G[models.Provider](tx).Where("id = ?", id).Updates(ctx, updates); err != nil {
+			return err
+		}
+		// 频率限制允许设为 0（不限制），需显式更新
+		if req.RateLimit != nil {
+			if _, err := gorm.G[models.Provider](tx).Where("id = ?", id).Select("rate_limit_rpm", "rate_limit_mode").Updates(ctx, models.Provider{RateLimit: *req.RateLimit}); err != nil {
+				return fmt.Errorf("rate limit: %w", err)
+			}
+		}
+		if req.TLS != nil {
+			if _, err := gorm.G[models.Provider](tx).Where("id = ?", id).Select("tls_ca_cert", "tls_insecure_skip_verify").Updates(ctx, models.Provider{TLS: *req.TLS}); err != nil {
+				return fmt.Errorf("tls: %w", err)
+			}
+		}
+		if req.QuotaGroup != nil {
+			if _, err := gorm.G[models.Provider](tx).Where("id = ?", id).Select("quota_group").Updates(ctx, models.Provider{QuotaGroup: strings.TrimSpace(*req.QuotaGroup)}); err != nil {
+				return fmt.Errorf("quota group: %w", err)
+			}
+		}
+		if req.Budget != nil {
+			if _, err := gorm.G[models.Provider](tx).Where("id = ?", id).Select("budget_period", "budget_tokens", "budget_cost").Updates(ctx, models.Provider{Budget: *req.Budget}); err != nil {
+				return fmt.Errorf("budget: %w", err)
+			}
+		}
+		return tx.Model(&models.Provider{}).Where("id = ?", id).Update("config_version", gorm.Expr("config_version + 1")).Error
+	})
+	if err != nil {
 		common.InternalServerError(c, "Failed to update provider: "+err.Error())
 		return
 	}
+	service.InvalidateRouteCache()
 
 	// Get updated provider
 	updatedProvider, err := gorm.G[models.Provider](models.DB).Where("id = ?", id).First(c.Request.Context())
@@ -212,12 +346,35 @@ func UpdateProvider(c *gin.Context) {
 	common.Success(c, updatedProvider)
 }
 
+// validWebhook 回调地址为空或 http/https URL
+func validWebhook(webhook string) bool {
+	webhook = strings.TrimSpace(webhook)
+	if webhook == "" {
+		return true
+	}
+	u, err := url.Parse(webhook)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validBudget 预算周期只能为 day/month，上限不能为负数
+func validBudget(budget models.ProviderBudget) bool {
+	if budget.Tokens < 0 || budget.Cost < 0 {
+		return false
+	}
+	switch budget.Period {
+	case "", consts.BudgetPeriodDay, consts.BudgetPeriodMonth:
+		return true
+	default:
+		return false
+	}
+}
+
 // DeleteProvider 删除提供商
 func DeleteProvider(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID format")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
 		return
 	}
 
@@ -234,7 +391,7 @@ func DeleteProvider(c *gin.Context) {
 	}
 
 	if result == 0 {
-		common.NotFound(c, "Provider not found")
+		common.NotFound(c, common.T(c, i18n.MsgProviderNotFound))
 		return
 	}
 
@@ -257,13 +414,11 @@ func GetModels(c *gin.Context) {
 	}
 
 	if strategy := strings.TrimSpace(c.Query("strategy")); strategy != "" {
-		switch strategy {
-		case consts.BalancerLottery, consts.BalancerRotor:
-			query = query.Where("strategy = ?", strategy)
-		default:
-			common.BadRequest(c, "invalid strategy filter")
+		if !slices.Contains(consts.Balancers, strategy) {
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidStrategyFilter))
 			return
 		}
+		query = query.Where("strategy = ?", strategy)
 	}
 
 	list := make([]models.Model, 0)
@@ -292,7 +447,7 @@ func GetModelList(c *gin.Context) {
 func CreateModel(c *gin.Context) {
 	var req ModelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request body: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
 		return
 	}
 
@@ -303,13 +458,33 @@ func CreateModel(c *gin.Context) {
 		return
 	}
 	if count > 0 {
-		common.BadRequest(c, fmt.Sprintf("Model: %s already exists", req.Name))
+		common.BadRequest(c, common.T(c, i18n.MsgModelExists, req.Name))
 		return
 	}
 	strategy := req.Strategy
 	if strategy == "" {
 		strategy = consts.BalancerDefault
 	}
+	if !validParamRanges(req.ParamRanges) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidParamRangeMode))
+		return
+	}
+	if !validIOLogPolicy(req.IOLogPolicy) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidIOLogPolicy))
+		return
+	}
+	if !validBreakerThresholds(req.BreakerThresholds) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidBreakerThresholds))
+		return
+	}
+	if !validRetryPolicy(req.RetryPolicy) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRetryPolicy))
+		return
+	}
+	if req.Webhook != nil && !validWebhook(*req.Webhook) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidWebhook))
+		return
+	}
 
 	var maxDisplayOrder int
 	if err := models.DB.Model(&models.Model{}).
@@ -320,13 +495,27 @@ func CreateModel(c *gin.Context) {
 	}
 
 	model := models.Model{
-		Name:         req.Name,
-		Remark:       req.Remark,
-		MaxRetry:     req.MaxRetry,
-		TimeOut:      req.TimeOut,
-		Strategy:     strategy,
-		Breaker:      &req.Breaker,
-		DisplayOrder: maxDisplayOrder + 1,
+		Name:              req.Name,
+		Remark:            req.Remark,
+		MaxRetry:          req.MaxRetry,
+		TimeOut:           req.TimeOut,
+		RetryReserve:      max(lo.FromPtr(req.RetryReserve), 0),
+		StickyMinutes:     max(lo.FromPtr(req.StickyMinutes), 0),
+		HedgeAfter:        max(lo.FromPtr(req.HedgeAfter), 0),
+		StreamBuffer:      max(lo.FromPtr(req.StreamBuffer), 0),
+		Webhook:           strings.TrimSpace(lo.FromPtr(req.Webhook)),
+		ContextLength:     max(lo.FromPtr(req.ContextLength), 0),
+		Strategy:          strategy,
+		Breaker:           &req.Breaker,
+		ValidateResponse:  &req.ValidateResponse,
+		RawPassthrough:    &req.RawPassthrough,
+		DisplayOrder:      maxDisplayOrder + 1,
+		ParamRanges:       req.ParamRanges,
+		Deprecation:       req.Deprecation,
+		IOLogPolicy:       req.IOLogPolicy,
+		ResponseGuard:     req.ResponseGuard,
+		BreakerThresholds: req.BreakerThresholds,
+		RetryPolicy:       req.RetryPolicy,
 	}
 
 	if err := gorm.G[models.Model](models.DB).Create(c.Request.Context(), &model); err != nil {
@@ -342,13 +531,13 @@ func UpdateModel(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID format")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
 		return
 	}
 
 	var req ModelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request body: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
 		return
 	}
 
@@ -356,7 +545,7 @@ func UpdateModel(c *gin.Context) {
 	_, err = gorm.G[models.Model](models.DB).Where("id = ?", id).First(c.Request.Context())
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			common.NotFound(c, "Model not found")
+			common.NotFound(c, common.T(c, i18n.MsgModelNotFound))
 			return
 		}
 		common.InternalServerError(c, "Database error: "+err.Error())
@@ -367,15 +556,49 @@ func UpdateModel(c *gin.Context) {
 	if strategy == "" {
 		strategy = consts.BalancerDefault
 	}
+	if !validParamRanges(req.ParamRanges) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidParamRangeMode))
+		return
+	}
+	if !validIOLogPolicy(req.IOLogPolicy) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidIOLogPolicy))
+		return
+	}
+	if !validBreakerThresholds(req.BreakerThresholds) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidBreakerThresholds))
+		return
+	}
+	if !validRetryPolicy(req.RetryPolicy) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRetryPolicy))
+		return
+	}
+	if req.Webhook != nil && !validWebhook(*req.Webhook) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidWebhook))
+		return
+	}
 
 	// Update fields
 	updates := models.Model{
-		Name:     req.Name,
-		Remark:   req.Remark,
-		MaxRetry: req.MaxRetry,
-		TimeOut:  req.TimeOut,
-		Strategy: strategy,
-		Breaker:  &req.Breaker,
+		Name:              req.Name,
+		Remark:            req.Remark,
+		MaxRetry:          req.MaxRetry,
+		TimeOut:           req.TimeOut,
+		RetryReserve:      max(lo.FromPtr(req.RetryReserve), 0),
+		StickyMinutes:     max(lo.FromPtr(req.StickyMinutes), 0),
+		HedgeAfter:        max(lo.FromPtr(req.HedgeAfter), 0),
+		StreamBuffer:      max(lo.FromPtr(req.StreamBuffer), 0),
+		Webhook:           strings.TrimSpace(lo.FromPtr(req.Webhook)),
+		ContextLength:     max(lo.FromPtr(req.ContextLength), 0),
+		Strategy:          strategy,
+		Breaker:           &req.Breaker,
+		ValidateResponse:  &req.ValidateResponse,
+		RawPassthrough:    &req.RawPassthrough,
+		ParamRanges:       req.ParamRanges,
+		Deprecation:       req.Deprecation,
+		IOLogPolicy:       req.IOLogPolicy,
+		ResponseGuard:     req.ResponseGuard,
+		BreakerThresholds: req.BreakerThresholds,
+		RetryPolicy:       req.RetryPolicy,
 	}
 
 	if _, err := gorm.G[models.Model](models.DB).Where("id = ?", id).Updates(c.Request.Context(), updates); err != nil {
@@ -383,6 +606,27 @@ func UpdateModel(c *gin.Context) {
 		return
 	}
 
+	// Updates 会忽略零值，可设为 0 的字段传入时需要单独写入
+	var columns []string
+	for column, set := range map[string]bool{
+		"retry_reserve":  req.RetryReserve != nil,
+		"sticky_minutes": req.StickyMinutes != nil,
+		"hedge_after":    req.HedgeAfter != nil,
+		"stream_buffer":  req.StreamBuffer != nil,
+		"webhook":        req.Webhook != nil,
+		"context_length": req.ContextLength != nil,
+	} {
+		if set {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) > 0 {
+		if _, err := gorm.G[models.Model](models.DB).Where("id = ?", id).Select(columns[0], lo.ToAnySlice(columns[1:])...).Updates(c.Request.Context(), updates); err != nil {
+			common.InternalServerError(c, "Failed to update model: "+err.Error())
+			return
+		}
+	}
+
 	// Get updated model
 	updatedModel, err := gorm.G[models.Model](models.DB).Where("id = ?", id).First(c.Request.Context())
 	if err != nil {
@@ -393,15 +637,48 @@ func UpdateModel(c *gin.Context) {
 	common.Success(c, updatedModel)
 }
 
+func validParamRanges(ranges *models.ParamRanges) bool {
+	if ranges == nil {
+		return true
+	}
+	return ranges.Mode == "" || ranges.Mode == consts.ParamRangeClamp || ranges.Mode == consts.ParamRangeReject
+}
+
+func validIOLogPolicy(policy *models.IOLogPolicy) bool {
+	if policy == nil || policy.SampleRate == nil {
+		return true
+	}
+	return *policy.SampleRate >= 0 && *policy.SampleRate <= 100
+}
+
+func validBreakerThresholds(thresholds *models.BreakerThresholds) bool {
+	if thresholds == nil {
+		return true
+	}
+	return thresholds.MaxFailures >= 0 && thresholds.SleepWindow >= 0 && thresholds.MaxRequests >= 0
+}
+
+func validRetryPolicy(policy *models.RetryPolicy) bool {
+	if policy == nil {
+		return true
+	}
+	if policy.Backoff < 0 || policy.MaxBackoff < 0 {
+		return false
+	}
+	return !slices.ContainsFunc(policy.RetryOn, func(status int) bool {
+		return status < 100 || status > 599
+	})
+}
+
 // UpdateModelOrder 更新模型展示顺序
 func UpdateModelOrder(c *gin.Context) {
 	var req ModelOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request body: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
 		return
 	}
 	if len(req.ModelIDs) == 0 {
-		common.BadRequest(c, "model_ids cannot be empty")
+		common.BadRequest(c, common.T(c, i18n.MsgModelIDsEmpty))
 		return
 	}
 
@@ -409,11 +686,11 @@ func UpdateModelOrder(c *gin.Context) {
 	modelIDs := make([]uint, 0, len(req.ModelIDs))
 	for _, modelID := range req.ModelIDs {
 		if modelID == 0 {
-			common.BadRequest(c, "model_ids contains invalid value 0")
+			common.BadRequest(c, common.T(c, i18n.MsgModelIDsInvalidZero))
 			return
 		}
 		if _, exists := seen[modelID]; exists {
-			common.BadRequest(c, fmt.Sprintf("duplicated model_id: %d", modelID))
+			common.BadRequest(c, common.T(c, i18n.MsgModelIDDuplicated, modelID))
 			return
 		}
 		seen[modelID] = struct{}{}
@@ -426,7 +703,7 @@ func UpdateModelOrder(c *gin.Context) {
 		return
 	}
 	if existCount != int64(len(modelIDs)) {
-		common.BadRequest(c, "model_ids contains non-existing model")
+		common.BadRequest(c, common.T(c, i18n.MsgModelIDsNotExist))
 		return
 	}
 
@@ -455,7 +732,7 @@ func DeleteModel(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID format")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
 		return
 	}
 
@@ -466,7 +743,7 @@ func DeleteModel(c *gin.Context) {
 	}
 
 	if result == 0 {
-		common.NotFound(c, "Model not found")
+		common.NotFound(c, common.T(c, i18n.MsgModelNotFound))
 		return
 	}
 
@@ -493,6 +770,20 @@ var template = []ProviderTemplate{
 			"api_key": "YOUR_GEMINI_API_KEY"
 		}`,
 	},
+	{
+		Type: "gemini-openai",
+		Template: `{
+			"base_url": "https://generativelanguage.googleapis.com/v1beta/openai",
+			"api_key": "YOUR_GEMINI_API_KEY"
+		}`,
+	},
+	{
+		Type: "ollama",
+		Template: `{
+			"base_url": "http://localhost:11434",
+			"api_key": ""
+		}`,
+	},
 	{
 		Type: "openai-res",
 		Template: `{
@@ -505,7 +796,8 @@ var template = []ProviderTemplate{
 		Template: `{
 			"base_url": "https://api.anthropic.com/v1",
 			"api_key": "YOUR_API_KEY",
-			"version": "2023-06-01"
+			"version": "2023-06-01",
+			"default_max_tokens": 4096
 		}`,
 	},
 }
@@ -514,21 +806,30 @@ func GetProviderTemplates(c *gin.Context) {
 	common.Success(c, template)
 }
 
-// GetModelProviders 获取模型的提供商关联列表
+// GetModelProviders 获取模型的提供商关联列表，默认只返回当前生效分组，slot=all 返回全部分组
 func GetModelProviders(c *gin.Context) {
 	modelIDStr := c.Query("model_id")
 	if modelIDStr == "" {
-		common.BadRequest(c, "model_id query parameter is required")
+		common.BadRequest(c, common.T(c, i18n.MsgModelIDRequired))
 		return
 	}
 
 	modelID, err := strconv.ParseUint(modelIDStr, 10, 64)
 	if err != nil {
-		common.BadRequest(c, "Invalid model_id format")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidModelID))
 		return
 	}
 
-	modelProviders, err := gorm.G[models.ModelWithProvider](models.DB).Where("model_id = ?", modelID).Find(c.Request.Context())
+	query := gorm.G[models.ModelWithProvider](models.DB).Where("model_id = ?", modelID)
+	switch slot := c.DefaultQuery("slot", service.ActiveSlot()); {
+	case slot == "all":
+	case service.ValidSlot(slot):
+		query = query.Where("slot = ?", slot)
+	default:
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidSlot))
+		return
+	}
+	modelProviders, err := query.Find(c.Request.Context())
 	if err != nil {
 		common.InternalServerError(c, err.Error())
 		return
@@ -544,13 +845,13 @@ func GetModelProviderStatus(c *gin.Context) {
 	providerModel := c.Query("provider_model")
 
 	if providerIDStr == "" || modelName == "" || providerModel == "" {
-		common.BadRequest(c, "provider_id, model_name and provider_model query parameters are required")
+		common.BadRequest(c, common.T(c, i18n.MsgProviderStatusParams))
 		return
 	}
 
 	providerID, err := strconv.ParseUint(providerIDStr, 10, 64)
 	if err != nil {
-		common.BadRequest(c, "Invalid provider_id format")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidProviderID))
 		return
 	}
 
@@ -562,11 +863,11 @@ func GetModelProviderStatus(c *gin.Context) {
 	}
 
 	// 获取最近10次请求状态
-	logs, err := gorm.G[models.ChatLog](models.DB).
+	logs, err := gorm.G[models.ChatLog](models.ReadDB()).
 		Where("provider_name = ?", provider.Name).
 		Where("provider_model = ?", providerModel).
 		Where("name = ?", modelName).
-		Where("status != ?", consts.StatusRunning).
+		Where("status NOT IN ?", consts.HealthExcludedStatuses).
 		Limit(10).
 		Order("created_at DESC").
 		Find(c.Request.Context())
@@ -575,19 +876,34 @@ func GetModelProviderStatus(c *gin.Context) {
 		return
 	}
 
-	status := make([]bool, 0)
+	status := ModelProviderStatus{Recent: make([]bool, 0)}
 	for _, log := range logs {
-		status = append(status, log.Status == consts.StatusSuccess)
+		status.Recent = append(status.Recent, log.Status == consts.StatusSuccess)
+	}
+	slices.Reverse(status.Recent)
+	// 配置了预算时返回本周期用量，用尽的提供商在重置前不参与路由
+	if status.Budget, err = service.GetProviderBudgetUsage(c.Request.Context(), provider, time.Now()); err != nil {
+		common.InternalServerError(c, "Failed to retrieve provider budget: "+err.Error())
+		return
 	}
-	slices.Reverse(status)
 	common.Success(c, status)
 }
 
+// ModelProviderStatus 关联最近的请求结果与提供商预算状态
+type ModelProviderStatus struct {
+	Recent []bool                       `json:"recent"` // 最近 10 次请求是否成功，旧的在前
+	Budget *service.ProviderBudgetUsage `json:"budget"` // 未配置预算时为空
+}
+
 // CreateModelProvider 创建关联管理
 func CreateModelProvider(c *gin.Context) {
 	var req ModelWithProviderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request body: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
+		return
+	}
+	if err := service.ValidateSchedule(req.Schedule); err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidSchedule, err.Error()))
 		return
 	}
 
@@ -601,6 +917,15 @@ func CreateModelProvider(c *gin.Context) {
 		extraBody = map[string]any{}
 	}
 
+	slot := req.Slot
+	if slot == "" {
+		slot = service.ActiveSlot()
+	}
+	if !service.ValidSlot(slot) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidSlot))
+		return
+	}
+
 	modelProvider := models.ModelWithProvider{
 		ModelID:          req.ModelID,
 		ProviderModel:    req.ProviderModel,
@@ -608,14 +933,29 @@ func CreateModelProvider(c *gin.Context) {
 		ToolCall:         &req.ToolCall,
 		StructuredOutput: &req.StructuredOutput,
 		Image:            &req.Image,
+		Chat:             new(lo.FromPtrOr(req.Chat, true)),
+		Embedding:        &req.Embedding,
+		ImageGeneration:  &req.ImageGeneration,
+		Rerank:           &req.Rerank,
+		Moderation:       &req.Moderation,
 		WithHeader:       &req.WithHeader,
+		PseudoStream:     &req.PseudoStream,
+		StreamAggregate:  &req.StreamAggregate,
 		CustomerHeaders:  customerHeaders,
 		ExtraBody:        extraBody,
 		Weight:           req.Weight,
+		Priority:         max(req.Priority, 1),
+		MaxInFlight:      max(req.MaxInFlight, 0),
+		QueueTimeout:     max(req.QueueTimeout, 0),
+		QuotaRPM:         max(req.QuotaRPM, 0),
+		QuotaTPM:         max(req.QuotaTPM, 0),
+		Schedule:         strings.TrimSpace(req.Schedule),
 		InputPrice:       &req.InputPrice,
 		CacheReadPrice:   &req.CacheReadPrice,
 		OutputPrice:      &req.OutputPrice,
 		Currency:         req.Currency,
+		Slot:             slot,
+		Remark:           req.Remark,
 	}
 
 	defaultStatus := true
@@ -635,16 +975,20 @@ func UpdateModelProvider(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID format")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
 		return
 	}
 
 	var req ModelWithProviderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request body: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
 		return
 	}
 	slog.Info("UpdateModelProvider", "req", req)
+	if err := service.ValidateSchedule(req.Schedule); err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidSchedule, err.Error()))
+		return
+	}
 
 	customerHeaders := req.CustomerHeaders
 	if customerHeaders == nil {
@@ -660,13 +1004,18 @@ func UpdateModelProvider(c *gin.Context) {
 	_, err = gorm.G[models.ModelWithProvider](models.DB).Where("id = ?", id).First(c.Request.Context())
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			common.NotFound(c, "Model-provider association not found")
+			common.NotFound(c, common.T(c, i18n.MsgModelProviderNotFound))
 			return
 		}
 		common.InternalServerError(c, "Database error: "+err.Error())
 		return
 	}
 
+	if req.Slot != "" && !service.ValidSlot(req.Slot) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidSlot))
+		return
+	}
+
 	updates := models.ModelWithProvider{
 		ModelID:          req.ModelID,
 		ProviderID:       req.ProviderID,
@@ -674,14 +1023,29 @@ func UpdateModelProvider(c *gin.Context) {
 		ToolCall:         &req.ToolCall,
 		StructuredOutput: &req.StructuredOutput,
 		Image:            &req.Image,
+		Chat:             new(lo.FromPtrOr(req.Chat, true)),
+		Embedding:        &req.Embedding,
+		ImageGeneration:  &req.ImageGeneration,
+		Rerank:           &req.Rerank,
+		Moderation:       &req.Moderation,
 		WithHeader:       &req.WithHeader,
+		PseudoStream:     &req.PseudoStream,
+		StreamAggregate:  &req.StreamAggregate,
 		CustomerHeaders:  customerHeaders,
 		ExtraBody:        extraBody,
 		Weight:           req.Weight,
+		Priority:         max(req.Priority, 1),
+		MaxInFlight:      max(req.MaxInFlight, 0),
+		QueueTimeout:     max(req.QueueTimeout, 0),
+		QuotaRPM:         max(req.QuotaRPM, 0),
+		QuotaTPM:         max(req.QuotaTPM, 0),
+		Schedule:         strings.TrimSpace(req.Schedule),
 		InputPrice:       &req.InputPrice,
 		CacheReadPrice:   &req.CacheReadPrice,
 		OutputPrice:      &req.OutputPrice,
 		Currency:         req.Currency,
+		Slot:             req.Slot,
+		Remark:           req.Remark,
 	}
 
 	if _, err := gorm.G[models.ModelWithProvider](models.DB).Where("id = ?", id).Updates(c.Request.Context(), updates); err != nil {
@@ -689,6 +1053,12 @@ func UpdateModelProvider(c *gin.Context) {
 		return
 	}
 
+	// Updates 会忽略零值，权重为 0（备用）、清空备注、取消并发与配额限制、清除时间窗口需要单独写入
+	if _, err := gorm.G[models.ModelWithProvider](models.DB).Where("id = ?", id).Select("weight", "remark", "max_in_flight", "queue_timeout", "quota_rpm", "quota_tpm", "schedule").Updates(c.Request.Context(), updates); err != nil {
+		common.InternalServerError(c, "Failed to update model-provider association: "+err.Error())
+		return
+	}
+
 	// Get updated model-provider association
 	updatedModelProvider, err := gorm.G[models.ModelWithProvider](models.DB).Where("id = ?", id).First(c.Request.Context())
 	if err != nil {
@@ -704,20 +1074,20 @@ func UpdateModelProviderStatus(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID format")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
 		return
 	}
 
 	var req ModelProviderStatusRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request body: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
 		return
 	}
 
 	existing, err := gorm.G[models.ModelWithProvider](models.DB).Where("id = ?", id).First(c.Request.Context())
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			common.NotFound(c, "Model-provider association not found")
+			common.NotFound(c, common.T(c, i18n.MsgModelProviderNotFound))
 			return
 		}
 		common.InternalServerError(c, "Failed to retrieve model-provider association: "+err.Error())
@@ -734,6 +1104,10 @@ func UpdateModelProviderStatus(c *gin.Context) {
 		return
 	}
 
+	if status && !lo.FromPtr(existing.Status) {
+		service.WarmUp(existing.ID)
+	}
+
 	existing.Status = &status
 	common.Success(c, existing)
 }
@@ -743,7 +1117,7 @@ func DeleteModelProvider(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID format")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
 		return
 	}
 
@@ -754,7 +1128,7 @@ func DeleteModelProvider(c *gin.Context) {
 	}
 
 	if result == 0 {
-		common.NotFound(c, "Model-provider association not found")
+		common.NotFound(c, common.T(c, i18n.MsgModelProviderNotFound))
 		return
 	}
 
@@ -766,7 +1140,14 @@ type WrapLog struct {
 	KeyName string `json:"key_name"`
 }
 
+// LogsResponse 日志分页响应，按 before_id 游标翻页时 next_cursor 为下一页的 before_id，没有更多数据时为 0
+type LogsResponse struct {
+	common.PaginationResponse
+	NextCursor uint `json:"next_cursor"`
+}
+
 // GetRequestLogs 获取最近的请求日志（支持分页和筛选）
+// 传入 before_id 时按 ID 游标翻页，不受 OFFSET 深度限制，total 仍为筛选条件下的总数
 func GetRequestLogs(c *gin.Context) {
 	// 解析分页参数
 	params, err := common.ParsePagination(c)
@@ -782,11 +1163,37 @@ func GetRequestLogs(c *gin.Context) {
 	style := c.Query("style")
 	authKeyID := c.Query("auth_key_id")
 	traceID := c.Query("trace_id")
+	requestID := c.Query("request_id")
 	sessionID := c.Query("session_id")
+	strategy := c.Query("strategy")
 	logID := c.Query("id")
+	providerModel := c.Query("provider_model")
+	errorText := c.Query("error")
+
+	var beforeID uint64
+	if v := c.Query("before_id"); v != "" {
+		if beforeID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+			return
+		}
+		params.Page = 1
+	}
+	var timeRange [2]time.Time
+	for i, key := range []string{"start", "end"} {
+		if v := c.Query(key); v != "" {
+			if timeRange[i], err = time.Parse(time.RFC3339, v); err != nil {
+				common.BadRequest(c, common.T(c, i18n.MsgInvalidTimeFilter, key))
+				return
+			}
+		}
+	}
+	if !checkTimeRange(c, timeRange[0], timeRange[1]) {
+		return
+	}
 
 	// 构建查询条件
-	query := models.DB.Model(&models.ChatLog{})
+	// 时间线只在详情接口返回
+	query := models.ReadDB().Model(&models.ChatLog{}).Omit("timeline")
 
 	if providerName != "" {
 		query = query.Where("provider_name = ?", providerName)
@@ -812,22 +1219,49 @@ func GetRequestLogs(c *gin.Context) {
 		query = query.Where("trace_id = ?", traceID)
 	}
 
+	if requestID != "" {
+		query = query.Where("request_id = ?", requestID)
+	}
+
 	if sessionID != "" {
 		query = query.Where("session_id = ?", sessionID)
 	}
 
+	if strategy != "" {
+		query = query.Where("strategy = ?", strategy)
+	}
+
 	if logID != "" {
 		query = query.Where("id = ?", logID)
 	}
 
-	// 执行分页查询
+	if providerModel != "" {
+		query = query.Where("provider_model = ?", providerModel)
+	}
+
+	if errorText != "" {
+		query = query.Where("error LIKE ? ESCAPE '\\'", "%"+escapeLike(errorText)+"%")
+	}
+
+	if !timeRange[0].IsZero() {
+		query = query.Where("created_at >= ?", timeRange[0])
+	}
+
+	if !timeRange[1].IsZero() {
+		query = query.Where("created_at < ?", timeRange[1])
+	}
+
+	// 总数不受游标影响，游标只决定本页的起点
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		common.InternalServerError(c, "Failed to query logs: "+err.Error())
+		return
+	}
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
 	var logs []models.ChatLog
-	total, err := common.PaginateQuery(
-		query.Order("id DESC"),
-		params,
-		&logs,
-	)
-	if err != nil {
+	if err := common.ApplyPagination(query.Order("id DESC"), params).Find(&logs).Error; err != nil {
 		common.InternalServerError(c, "Failed to query logs: "+err.Error())
 		return
 	}
@@ -856,38 +1290,50 @@ func GetRequestLogs(c *gin.Context) {
 	}
 
 	// 返回分页响应
-	response := common.NewPaginationResponse(wrapLogs, total, params)
+	response := LogsResponse{PaginationResponse: common.NewPaginationResponse(wrapLogs, total, params)}
+	if len(logs) == params.PageSize {
+		response.NextCursor = logs[len(logs)-1].ID
+	}
 	common.Success(c, response)
 }
 
-// GetChatIO 查询指定日志的输入输出记录
-func GetChatIO(c *gin.Context) {
-	id := c.Param("id")
+// escapeLike 转义 LIKE 通配符，使搜索词按字面匹配
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
 
-	chatIO, err := gorm.G[models.ChatIO](models.DB).Where("log_id = ?", id).First(c.Request.Context())
+// GetRequestLog 查询单条日志详情，包含请求时间线
+func GetRequestLog(c *gin.Context) {
+	log, err := gorm.G[models.ChatLog](models.ReadDB()).Where("id = ?", c.Param("id")).First(c.Request.Context())
 	if err != nil {
-		common.NotFound(c, "ChatIO not found")
+		common.NotFound(c, common.T(c, i18n.MsgLogNotFound))
 		return
 	}
+	common.Success(c, log)
+}
 
-	// 查询关联的 ChatLog 获取 Style
+// GetLogIO 查询指定日志的请求体与响应体，凭证字段已遮蔽，limit 为单个字段返回的最大字节数
+func GetLogIO(c *gin.Context) {
+	limit := service.DefaultIOViewLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > service.MaxIOViewLimit {
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidIOViewLimit, service.MaxIOViewLimit))
+			return
+		}
+		limit = n
+	}
+
+	chatIO, err := gorm.G[models.ChatIO](models.ReadDB()).Where("log_id = ?", c.Param("id")).First(c.Request.Context())
+	if err != nil {
+		common.NotFound(c, common.T(c, i18n.MsgChatIONotFound))
+		return
+	}
 	var style string
-	chatLog, err := gorm.G[models.ChatLog](models.DB).Where("id = ?", id).First(c.Request.Context())
-	if err == nil {
+	if chatLog, err := gorm.G[models.ChatLog](models.ReadDB()).Where("id = ?", chatIO.LogId).First(c.Request.Context()); err == nil {
 		style = chatLog.Style
 	}
-
-	common.Success(c, gin.H{
-		"ID":            chatIO.ID,
-		"CreatedAt":     chatIO.CreatedAt,
-		"UpdatedAt":     chatIO.UpdatedAt,
-		"DeletedAt":     chatIO.DeletedAt,
-		"LogId":         chatIO.LogId,
-		"Input":         chatIO.Input,
-		"OfString":      chatIO.OfString,
-		"OfStringArray": chatIO.OfStringArray,
-		"Style":         style,
-	})
+	common.Success(c, service.NewLogIO(chatIO, style, limit))
 }
 
 // GetUserAgents 获取所有不重复的用户代理种类
@@ -895,7 +1341,7 @@ func GetUserAgents(c *gin.Context) {
 	var userAgents []string
 
 	// 查询所有不重复的非空用户代理
-	if err := models.DB.Model(&models.ChatLog{}).
+	if err := models.ReadDB().Model(&models.ChatLog{}).
 		Where("user_agent IS NOT NULL AND user_agent != ''").
 		Distinct("user_agent").
 		Pluck("user_agent", &userAgents).
@@ -937,7 +1383,16 @@ func UpdateConfigByKey(c *gin.Context) {
 
 	var req ConfigValueRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
+		return
+	}
+	if key == models.KeyModelFallback && !service.ValidModelFallback(req.Value) {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidModelFallback))
+		return
+	}
+	// 生效分组缓存在内存中，只能通过蓝绿切换接口修改，否则会与实际路由不一致
+	if key == models.KeyRoutingSlot {
+		common.BadRequest(c, common.T(c, i18n.MsgConfigKeyManaged, key, "/api/routing/slot/switch"))
 		return
 	}
 
@@ -967,6 +1422,10 @@ func UpdateConfigByKey(c *gin.Context) {
 		}
 	}
 
+	if key == models.KeyAPILanguage {
+		i18n.SetDefault(config.Value)
+	}
+
 	common.Success(c, map[string]string{
 		"key":   config.Key,
 		"value": config.Value,
@@ -984,12 +1443,12 @@ func CleanLogs(c *gin.Context) {
 	start := time.Now()
 	var req CleanLogsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
 		return
 	}
 
 	if req.Value <= 0 {
-		common.BadRequest(c, "Value must be greater than 0")
+		common.BadRequest(c, common.T(c, i18n.MsgValueMustBePositive))
 		return
 	}
 
@@ -1036,7 +1495,7 @@ func CleanLogs(c *gin.Context) {
 		deletedCount = count
 
 	default:
-		common.BadRequest(c, "Invalid type: must be 'count' or 'days'")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidCleanType))
 		return
 	}
 
@@ -1077,3 +1536,17 @@ func GetCleanupHistory(c *gin.Context) {
 
 	common.Success(c, common.NewPaginationResponse(records, total, params))
 }
+
+// VerifyLogs 校验全部日志的审计签名
+func VerifyLogs(c *gin.Context) {
+	report, err := service.VerifyChatLogs(c.Request.Context())
+	if errors.Is(err, service.ErrLogSigningDisabled) {
+		common.BadRequest(c, common.T(c, i18n.MsgLogSigningDisabled))
+		return
+	}
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, report)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -10,18 +11,25 @@ import (
 	"github.com/atopos31/llmio/common"
 	"github.com/atopos31/llmio/consts"
 	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/i18n"
 	"github.com/atopos31/llmio/pkg/token"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 	"gorm.io/gorm"
 )
 
 type AuthKeyRequest struct {
-	Name      string   `json:"name" binding:"required"`
-	Status    *bool    `json:"status"`
-	IOLog     *bool    `json:"io_log"`
-	AllowAll  *bool    `json:"allow_all"`
-	Models    []string `json:"models"`
-	ExpiresAt *string  `json:"expires_at"`
+	Name      string            `json:"name" binding:"required"`
+	Status    *bool             `json:"status"`
+	IOLog     *bool             `json:"io_log"`
+	NoIOLog   *bool             `json:"no_io_log"` // 禁止记录 IO，优先于 io_log，为空时保持不变
+	AllowAll  *bool             `json:"allow_all"`
+	Models    []string          `json:"models"`
+	ExpiresAt *string           `json:"expires_at"`
+	Remark    string            `json:"remark"`
+	RateLimit *models.RateLimit `json:"rate_limit"` // 为空时保持不变
+	// TrustedUser 受信任代理头中的用户名，为空时保持不变，传入空字符串解除绑定
+	TrustedUser *string `json:"trusted_user"`
 }
 
 func GetAuthKeys(c *gin.Context) {
@@ -38,7 +46,7 @@ func GetAuthKeys(c *gin.Context) {
 	// 搜索过滤
 	if search := strings.TrimSpace(c.Query("search")); search != "" {
 		like := "%" + search + "%"
-		query = query.Where("name LIKE ? OR key LIKE ?", like, like)
+		query = query.Where("name LIKE ? OR key LIKE ? OR remark LIKE ?", like, like, like)
 	}
 
 	// 状态过滤
@@ -49,7 +57,7 @@ func GetAuthKeys(c *gin.Context) {
 		case "inactive":
 			query = query.Where("status = ?", false)
 		default:
-			common.BadRequest(c, "Invalid status filter")
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidStatusFilter))
 			return
 		}
 	}
@@ -62,7 +70,7 @@ func GetAuthKeys(c *gin.Context) {
 		case "false":
 			query = query.Where("allow_all = ?", false)
 		default:
-			common.BadRequest(c, "Invalid allow_all filter")
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidAllowAllFilter))
 			return
 		}
 	}
@@ -87,12 +95,12 @@ func GetAuthKeys(c *gin.Context) {
 func CreateAuthKey(c *gin.Context) {
 	var req AuthKeyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request body: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
 		return
 	}
 
 	if err := validateAuthKeyRequest(req); err != nil {
-		common.BadRequest(c, err.Error())
+		common.BadRequest(c, common.ErrorText(c, err))
 		return
 	}
 
@@ -105,7 +113,7 @@ func CreateAuthKey(c *gin.Context) {
 	if req.ExpiresAt != nil {
 		parsedExpiresAt, err := time.Parse(time.RFC3339, *req.ExpiresAt)
 		if err != nil {
-			common.BadRequest(c, "Invalid expires_at format, must be RFC3339")
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidExpiresAt))
 			return
 		}
 		expiresAt = &parsedExpiresAt
@@ -122,9 +130,19 @@ func CreateAuthKey(c *gin.Context) {
 		Key:       fmt.Sprintf("%s%s", consts.KeyPrefix, key),
 		Status:    req.Status,
 		IOLog:     new(ioLog),
+		NoIOLog:   new(lo.FromPtr(req.NoIOLog)),
 		AllowAll:  req.AllowAll,
 		Models:    sanitizeModels(req.Models),
 		ExpiresAt: expiresAt,
+		Remark:    req.Remark,
+		RateLimit: lo.FromPtr(req.RateLimit),
+	}
+	if req.TrustedUser != nil {
+		authKey.TrustedUser = strings.TrimSpace(*req.TrustedUser)
+		if err := checkTrustedUser(ctx, authKey.TrustedUser, 0); err != nil {
+			common.BadRequest(c, common.ErrorText(c, err))
+			return
+		}
 	}
 
 	if err := gorm.G[models.AuthKey](models.DB).Create(ctx, &authKey); err != nil {
@@ -139,18 +157,18 @@ func UpdateAuthKey(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
 		return
 	}
 
 	var req AuthKeyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request body: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
 		return
 	}
 
 	if err := validateAuthKeyRequest(req); err != nil {
-		common.BadRequest(c, err.Error())
+		common.BadRequest(c, common.ErrorText(c, err))
 		return
 	}
 
@@ -158,7 +176,7 @@ func UpdateAuthKey(c *gin.Context) {
 
 	if _, err := gorm.G[models.AuthKey](models.DB).Where("id = ?", id).First(ctx); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			common.NotFound(c, "Auth key not found")
+			common.NotFound(c, common.T(c, i18n.MsgAuthKeyNotFound))
 			return
 		}
 		common.InternalServerError(c, "Failed to load auth key: "+err.Error())
@@ -169,7 +187,7 @@ func UpdateAuthKey(c *gin.Context) {
 	if req.ExpiresAt != nil {
 		parsedExpiresAt, err := time.Parse(time.RFC3339, *req.ExpiresAt)
 		if err != nil {
-			common.BadRequest(c, "Invalid expires_at format, must be RFC3339")
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidExpiresAt))
 			return
 		}
 		expiresAt = &parsedExpiresAt
@@ -184,9 +202,11 @@ func UpdateAuthKey(c *gin.Context) {
 		Name:      req.Name,
 		Status:    req.Status,
 		IOLog:     new(ioLog),
+		NoIOLog:   req.NoIOLog,
 		AllowAll:  req.AllowAll,
 		Models:    sanitizeModels(req.Models),
 		ExpiresAt: expiresAt,
+		Remark:    req.Remark,
 	}
 
 	if update.ExpiresAt == nil {
@@ -196,10 +216,35 @@ func UpdateAuthKey(c *gin.Context) {
 		}
 	}
 
+	if update.Remark == "" {
+		if _, err := gorm.G[models.AuthKey](models.DB).Where("id = ?", id).Update(ctx, "remark", ""); err != nil {
+			common.InternalServerError(c, "Failed to update remark: "+err.Error())
+			return
+		}
+	}
+
 	if _, err := gorm.G[models.AuthKey](models.DB).Where("id = ?", id).Updates(ctx, update); err != nil {
 		common.InternalServerError(c, "Failed to update auth key: "+err.Error())
 		return
 	}
+	if req.RateLimit != nil {
+		if _, err := gorm.G[models.AuthKey](models.DB).Where("id = ?", id).Select("rate_limit_rpm", "rate_limit_mode").Updates(ctx, models.AuthKey{RateLimit: *req.RateLimit}); err != nil {
+			common.InternalServerError(c, "Failed to update rate limit: "+err.Error())
+			return
+		}
+	}
+
+	if req.TrustedUser != nil {
+		trustedUser := strings.TrimSpace(*req.TrustedUser)
+		if err := checkTrustedUser(ctx, trustedUser, uint(id)); err != nil {
+			common.BadRequest(c, common.ErrorText(c, err))
+			return
+		}
+		if _, err := gorm.G[models.AuthKey](models.DB).Where("id = ?", id).Update(ctx, "trusted_user", trustedUser); err != nil {
+			common.InternalServerError(c, "Failed to update trusted user: "+err.Error())
+			return
+		}
+	}
 
 	updated, err := gorm.G[models.AuthKey](models.DB).Where("id = ?", id).First(ctx)
 	if err != nil {
@@ -214,7 +259,7 @@ func DeleteAuthKey(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
 		return
 	}
 	ctx := c.Request.Context()
@@ -230,7 +275,7 @@ func ToggleAuthKeyStatus(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		common.BadRequest(c, "Invalid ID")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
 		return
 	}
 
@@ -240,7 +285,7 @@ func ToggleAuthKeyStatus(c *gin.Context) {
 	authKey, err := gorm.G[models.AuthKey](models.DB).Where("id = ?", id).First(ctx)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			common.NotFound(c, "Auth key not found")
+			common.NotFound(c, common.T(c, i18n.MsgAuthKeyNotFound))
 			return
 		}
 		common.InternalServerError(c, "Failed to load auth key: "+err.Error())
@@ -293,7 +338,25 @@ func GetAuthKeysList(c *gin.Context) {
 
 func validateAuthKeyRequest(req AuthKeyRequest) error {
 	if req.AllowAll != nil && !*req.AllowAll && len(req.Models) == 0 {
-		return errors.New("请至少选择一个允许的模型或启用允许全部模型")
+		return i18n.NewError(i18n.MsgAuthKeyModelsRequired)
+	}
+	if req.RateLimit != nil && !validRateLimit(*req.RateLimit) {
+		return i18n.NewError(i18n.MsgInvalidRateLimit)
+	}
+	return nil
+}
+
+// checkTrustedUser 受信任用户名只能绑定一个 Key
+func checkTrustedUser(ctx context.Context, user string, id uint) error {
+	if user == "" {
+		return nil
+	}
+	count, err := gorm.G[models.AuthKey](models.DB).Where("trusted_user = ? AND id <> ?", user, id).Count(ctx, "id")
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return i18n.NewError(i18n.MsgTrustedUserTaken)
 	}
 	return nil
 }
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetBreakerStatus 列出熔断中或探测恢复中的关联: GET /api/breaker/status
+func GetBreakerStatus(c *gin.Context) {
+	list, err := service.GetBreakerStatus(c.Request.Context())
+	if err != nil {
+		common.InternalServerError(c, "Failed to retrieve breaker status: "+err.Error())
+		return
+	}
+	common.Success(c, list)
+}
+
+// ResetBreaker 手动关闭关联的熔断器: POST /api/breaker/reset/:id
+func ResetBreaker(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+	if !service.ResetBreaker(uint(id)) {
+		common.NotFound(c, common.T(c, i18n.MsgBreakerNotFound))
+		return
+	}
+	common.Success(c, nil)
+}
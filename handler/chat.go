@@ -8,14 +8,18 @@ import (
 	"log/slog"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/atopos31/llmio/common"
 	"github.com/atopos31/llmio/consts"
 	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/env"
+	"github.com/atopos31/llmio/pkg/i18n"
 	"github.com/atopos31/llmio/service"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 // flushWriter 包装 http.ResponseWriter，在每次 Write 后自动 Flush
@@ -38,6 +42,65 @@ func ChatCompletionsHandler(c *gin.Context) {
 	chatHandler(c, service.BeforerOpenAI, service.ProcesserOpenAI, consts.StyleOpenAI)
 }
 
+// EmbeddingsHandler 转发 OpenAI 兼容的向量化接口: POST /v1/embeddings
+func EmbeddingsHandler(c *gin.Context) {
+	ctx := context.WithValue(c.Request.Context(), consts.ContextKeyOpenAIEndpoint, "/embeddings")
+	c.Request = c.Request.WithContext(ctx)
+	chatHandler(c, service.BeforerEmbedding, service.ProcesserEmbedding, consts.StyleEmbedding)
+}
+
+// RerankHandler 转发 Cohere/Jina 兼容的重排序接口: POST /v1/rerank
+func RerankHandler(c *gin.Context) {
+	ctx := context.WithValue(c.Request.Context(), consts.ContextKeyOpenAIEndpoint, "/rerank")
+	c.Request = c.Request.WithContext(ctx)
+	chatHandler(c, service.BeforerRerank, service.ProcesserRerank, consts.StyleRerank)
+}
+
+// ModerationsHandler 转发内容审核接口到支持审核的提供商: POST /v1/moderations
+func ModerationsHandler(c *gin.Context) {
+	ctx := context.WithValue(c.Request.Context(), consts.ContextKeyOpenAIEndpoint, "/moderations")
+	c.Request = c.Request.WithContext(ctx)
+	chatHandler(c, service.BeforerModeration, service.ProcesserModeration, consts.StyleModeration)
+}
+
+// ImageGenerationsHandler 转发图片生成接口: POST /v1/images/generations
+func ImageGenerationsHandler(c *gin.Context) {
+	imageHandler(c, "/images/generations")
+}
+
+// ImageEditsHandler 转发图片编辑接口: POST /v1/images/edits
+func ImageEditsHandler(c *gin.Context) {
+	imageHandler(c, "/images/edits")
+}
+
+// ImageVariationsHandler 转发图片变体接口: POST /v1/images/variations
+func ImageVariationsHandler(c *gin.Context) {
+	imageHandler(c, "/images/variations")
+}
+
+func imageHandler(c *gin.Context, endpoint string) {
+	ctx := context.WithValue(c.Request.Context(), consts.ContextKeyOpenAIEndpoint, endpoint)
+	c.Request = c.Request.WithContext(ctx)
+	if c.ContentType() != "multipart/form-data" {
+		chatHandler(c, service.BeforerImage, service.ProcesserImage, consts.StyleImageGeneration)
+		return
+	}
+
+	// 表单上传可能很大，暂存后按流转发，不整体读入内存
+	upload, err := service.NewUpload(c.Request.Body, c.GetHeader("Content-Type"))
+	if err != nil {
+		chatError(c, consts.StyleImageGeneration, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer upload.Close()
+	before, err := service.NewImageFormBefore(upload)
+	if err != nil {
+		chatError(c, consts.StyleImageGeneration, http.StatusBadRequest, err.Error())
+		return
+	}
+	serveChat(c, before, service.ProcesserImage, consts.StyleImageGeneration)
+}
+
 func ResponsesHandler(c *gin.Context) {
 	chatHandler(c, service.BeforerOpenAIRes, service.ProcesserOpenAiRes, consts.StyleOpenAIRes)
 }
@@ -52,7 +115,7 @@ func GeminiGenerateContentHandler(c *gin.Context) {
 	modelAction := strings.TrimPrefix(c.Param("modelAction"), "/")
 	model, method, ok := strings.Cut(modelAction, ":")
 	if !ok || model == "" || method == "" {
-		common.BadRequest(c, "Invalid Gemini model action")
+		chatError(c, consts.StyleGemini, http.StatusBadRequest, common.T(c, i18n.MsgInvalidGeminiAction))
 		return
 	}
 	stream := false
@@ -62,7 +125,7 @@ func GeminiGenerateContentHandler(c *gin.Context) {
 	case "streamGenerateContent":
 		stream = true
 	default:
-		common.BadRequest(c, "Unsupported Gemini method: "+method)
+		chatError(c, consts.StyleGemini, http.StatusBadRequest, common.T(c, i18n.MsgUnsupportedGeminiMethod, method))
 		return
 	}
 
@@ -72,18 +135,51 @@ func GeminiGenerateContentHandler(c *gin.Context) {
 	chatHandler(c, service.NewBeforerGemini(model, stream), service.ProcesserGemini, consts.StyleGemini)
 }
 
+// maxRequestBodyMB JSON 请求体上限(MB)，0 表示不限制；multipart 表单按流转发不受此限制
+func maxRequestBodyMB() int {
+	return env.GetWithDefault("LLMIO_MAX_REQUEST_BODY_MB", 32)
+}
+
+// readRequestBody 读取完整请求体，超过上限时写入 413 并返回 false
+func readRequestBody(c *gin.Context, style string) ([]byte, bool) {
+	limit := maxRequestBodyMB()
+	if limit > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(limit)<<20)
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			chatError(c, style, http.StatusRequestEntityTooLarge, common.T(c, i18n.MsgRequestBodyTooLarge, limit))
+			return nil, false
+		}
+		chatError(c, style, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return body, true
+}
+
 func chatHandler(c *gin.Context, preProcessor service.Beforer, postProcessor service.Processer, style string) {
 	// 读取原始请求体
-	reqBody, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		common.InternalServerError(c, err.Error())
+	reqBody, ok := readRequestBody(c, style)
+	if !ok {
 		return
 	}
 	c.Request.Body.Close()
 	// 预处理、提取模型参数
 	before, err := preProcessor(reqBody)
 	if err != nil {
-		common.InternalServerError(c, err.Error())
+		chatError(c, style, http.StatusBadRequest, err.Error())
+		return
+	}
+	serveChat(c, before, postProcessor, style)
+}
+
+// serveChat 按预处理结果选择 provider 转发并记录日志
+func serveChat(c *gin.Context, before *service.Before, postProcessor service.Processer, style string) {
+	// 内置探测模型直接由网关响应，鉴权已由中间件完成
+	if before.Model == consts.PingModel {
+		pingHandler(c, style, before.Stream)
 		return
 	}
 
@@ -91,17 +187,47 @@ func chatHandler(c *gin.Context, preProcessor service.Beforer, postProcessor ser
 	// 校验 authKey 是否有权限使用该模型
 	valid, err := validateAuthKey(ctx, before.Model)
 	if err != nil {
-		common.InternalServerError(c, err.Error())
+		chatError(c, style, http.StatusInternalServerError, err.Error())
 		return
 	}
 	if !valid {
-		common.ErrorWithHttpStatus(c, http.StatusForbidden, http.StatusForbidden, fmt.Sprintf("auth key has no permission to use %s", before.Model))
+		chatError(c, style, http.StatusForbidden, common.T(c, i18n.MsgModelPermissionDenied, before.Model))
 		return
 	}
+	if err := service.CheckAuthKeyRate(ctx); err != nil {
+		chatError(c, style, http.StatusTooManyRequests, common.T(c, i18n.MsgRateLimited))
+		return
+	}
+	// 流式连接数达到全局上限时直接拒绝，名额在响应转发结束后释放
+	if before.Stream {
+		release, ok := service.AcquireStream()
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(service.StreamRetryAfter))
+			chatError(c, style, http.StatusServiceUnavailable, common.T(c, i18n.MsgTooManyStreams, service.GetStreamStats().Max))
+			return
+		}
+		defer release()
+	}
 	// 按模型获取可用 provider
 	providersWithMeta, err := service.ProvidersWithMetaBymodelsName(ctx, style, *before)
 	if err != nil {
-		common.InternalServerError(c, err.Error())
+		chatError(c, style, chatErrorStatus(err), err.Error())
+		return
+	}
+	setDeprecationHeaders(c, providersWithMeta.Deprecation)
+	// 管理员可按请求指定负载均衡策略，便于在真实流量上对比
+	if strategy := c.GetHeader(consts.StrategyHeader); strategy != "" {
+		if admin, _ := ctx.Value(consts.ContextKeyAdmin).(bool); admin {
+			if !slices.Contains(consts.Balancers, strategy) {
+				chatError(c, style, http.StatusBadRequest, common.T(c, i18n.MsgInvalidStrategy, strategy))
+				return
+			}
+			providersWithMeta.Strategy = strategy
+		}
+	}
+	// 按模型参数范围策略截断或拒绝越界参数
+	if err := before.ApplyParamRanges(style, providersWithMeta.ParamRanges); err != nil {
+		chatError(c, style, http.StatusBadRequest, common.ErrorText(c, err))
 		return
 	}
 
@@ -113,23 +239,33 @@ func chatHandler(c *gin.Context, preProcessor service.Beforer, postProcessor ser
 		UserAgent: c.Request.UserAgent(),
 	})
 	if err != nil {
-		common.InternalServerError(c, err.Error())
+		chatError(c, style, chatErrorStatus(err), err.Error())
 		return
 	}
 	defer res.Body.Close()
 
 	logId, err := service.SaveChatLog(ctx, *log)
 	if err != nil {
-		common.InternalServerError(c, err.Error())
+		chatError(c, style, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if providersWithMeta.RawPassthrough {
+		serveRaw(c, res, log, logId, *before, startReq, providersWithMeta.Webhook, style)
 		return
 	}
 
 	pr, pw := io.Pipe()
-	tee := io.TeeReader(res.Body, pw)
+	body := io.Reader(res.Body)
+	if before.Stream {
+		// 流式响应命中屏蔽词时停止读取，客户端与日志只收到违规前的内容
+		body = service.GuardStream(body, providersWithMeta.ResponseGuard)
+	}
+	tee := io.TeeReader(body, pw)
 	// 异步处理输出并记录 tokens
-	authKeyIOLog, _ := ctx.Value(consts.ContextKeyAuthKeyIOLog).(bool)
-	slog.Info("start recording log", "logId", logId, "authKeyIOLog", authKeyIOLog)
-	go service.RecordLog(context.Background(), startReq, pr, postProcessor, logId, *before, authKeyIOLog)
+	// log.ChatIO 为 Key 开关与模型采样的结果
+	slog.Info("start recording log", "request_id", log.RequestID, "logId", logId, "ioLog", log.ChatIO)
+	go service.RecordLog(context.Background(), startReq, pr, postProcessor, logId, log.ModelProviderID, *before, log.ChatIO, lo.FromPtr(providersWithMeta.IOLogPolicy).Redact, log.Timeline, providersWithMeta.Webhook)
 	writeHeader(c, before.Stream, res.Header)
 
 	// 流式响应使用 flushWriter 确保数据实时发送
@@ -138,17 +274,91 @@ func chatHandler(c *gin.Context, preProcessor service.Beforer, postProcessor ser
 		writer = &flushWriter{w: c.Writer}
 	}
 
-	if _, err := io.Copy(writer, tee); err != nil {
+	upstream := &upstreamReader{r: tee}
+	// 慢推理模型首个数据块到达前定时发送注释，避免代理或客户端空闲超时断开
+	if interval := sseKeepaliveInterval(); before.Stream && interval > 0 {
+		upstream.first = startKeepalive(writer, interval)
+		defer upstream.first()
+	}
+	if _, err := io.Copy(writer, upstream); err != nil {
+		if errors.Is(err, service.ErrResponseBlocked) {
+			pw.CloseWithError(err)
+			// 立即断开上游，避免继续生成消耗输出 tokens
+			res.Body.Close()
+			writeStreamError(writer, style, http.StatusBadRequest, err.Error())
+			return
+		}
+		if ctx.Err() != nil {
+			// 客户端已断开，同样立即断开上游，日志按已转发部分记录为取消
+			res.Body.Close()
+			pw.CloseWithError(fmt.Errorf("%w: %v", service.ErrClientCanceled, context.Cause(ctx)))
+			return
+		}
 		pw.CloseWithError(err)
 		slog.Error("io copy", "err:", err)
+		// 上游中途断开时补发协议对应的错误事件，客户端可据此区分正常结束与失败
+		if before.Stream && upstream.err != nil {
+			writeStreamError(writer, style, http.StatusBadGateway, upstream.err.Error())
+		}
 		return
 	}
 
 	pw.Close()
 }
 
+// serveRaw 原始透传：按字节转发上游响应，跳过屏蔽词与处理器，结束后只记录状态与大小
+func serveRaw(c *gin.Context, res *http.Response, log *models.ChatLog, logId uint, before service.Before, startReq time.Time, webhook string, style string) {
+	writeHeader(c, before.Stream, res.Header)
+	var writer io.Writer = c.Writer
+	if before.Stream {
+		writer = &flushWriter{w: c.Writer}
+	}
+	raw := service.NewRawReader(res.Body, startReq)
+	upstream := &upstreamReader{r: raw}
+	if interval := sseKeepaliveInterval(); before.Stream && interval > 0 {
+		upstream.first = startKeepalive(writer, interval)
+		defer upstream.first()
+	}
+	_, err := io.Copy(writer, upstream)
+	switch {
+	case err == nil:
+	case c.Request.Context().Err() != nil:
+		res.Body.Close()
+		err = fmt.Errorf("%w: %v", service.ErrClientCanceled, context.Cause(c.Request.Context()))
+	default:
+		slog.Error("io copy", "err:", err)
+		if before.Stream && upstream.err != nil {
+			writeStreamError(writer, style, http.StatusBadGateway, upstream.err.Error())
+		}
+	}
+	go service.RecordRawLog(context.Background(), raw, logId, log.ModelProviderID, before, webhook, err)
+}
+
+// upstreamReader 记录读取上游响应时的错误，用于区分上游断开与客户端写入失败
+type upstreamReader struct {
+	r     io.Reader
+	err   error
+	first func() // 首次读取返回后调用，用于在写入响应前停止保活
+}
+
+func (u *upstreamReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	if u.first != nil {
+		u.first()
+		u.first = nil
+	}
+	if err != nil && err != io.EOF {
+		u.err = err
+	}
+	return n, err
+}
+
 func writeHeader(c *gin.Context, stream bool, header http.Header) {
 	for k, values := range header {
+		// 上游的请求 ID 不覆盖本服务分配的请求 ID
+		if http.CanonicalHeaderKey(k) == http.CanonicalHeaderKey(consts.RequestIDHeader) {
+			continue
+		}
 		for _, value := range values {
 			c.Writer.Header().Add(k, value)
 		}
@@ -180,3 +390,15 @@ func validateAuthKey(ctx context.Context, model string) (bool, error) {
 	}
 	return slices.Contains(allowedModels, model), nil
 }
+
+// setDeprecationHeaders 模型已弃用时通过 Deprecation/Sunset/Warning 响应头提醒调用方
+func setDeprecationHeaders(c *gin.Context, deprecation *models.Deprecation) {
+	if deprecation == nil {
+		return
+	}
+	c.Header("Deprecation", "true")
+	if deprecation.SunsetAt != nil {
+		c.Header("Sunset", deprecation.SunsetAt.UTC().Format(http.TimeFormat))
+	}
+	c.Header("Warning", "299 llmio "+strconv.Quote(deprecation.Message))
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+)
+
+// chatError 按请求协议返回客户端 SDK 可解析的错误体
+func chatError(c *gin.Context, style string, status int, message string) {
+	switch style {
+	case consts.StyleAnthropic:
+		c.JSON(status, gin.H{
+			"type": "error",
+			"error": gin.H{
+				"type":    anthropicErrorType(status),
+				"message": message,
+			},
+		})
+	case consts.StyleGemini:
+		c.JSON(status, gin.H{
+			"error": gin.H{
+				"code":    status,
+				"message": message,
+				"status":  geminiErrorStatus(status),
+			},
+		})
+	default:
+		c.JSON(status, gin.H{
+			"error": gin.H{
+				"message": message,
+				"type":    openAIErrorType(status),
+				"param":   nil,
+				"code":    nil,
+			},
+		})
+	}
+}
+
+// writeStreamError 在已开始的 SSE 流末尾写入协议对应的错误事件
+func writeStreamError(w io.Writer, style string, status int, message string) {
+	var event string
+	var payload any
+	switch style {
+	case consts.StyleAnthropic:
+		event = "error"
+		payload = gin.H{
+			"type": "error",
+			"error": gin.H{
+				"type":    anthropicErrorType(status),
+				"message": message,
+			},
+		}
+	case consts.StyleOpenAIRes:
+		event = "error"
+		payload = gin.H{
+			"type":    "error",
+			"code":    openAIErrorType(status),
+			"message": message,
+			"param":   nil,
+		}
+	case consts.StyleGemini:
+		payload = gin.H{
+			"error": gin.H{
+				"code":    status,
+				"message": message,
+				"status":  geminiErrorStatus(status),
+			},
+		}
+	default:
+		payload = gin.H{
+			"error": gin.H{
+				"message": message,
+				"type":    openAIErrorType(status),
+				"param":   nil,
+				"code":    nil,
+			},
+		}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	// 先结束可能被截断的事件，避免错误事件与残留数据拼接
+	frame := "\n\n"
+	if event != "" {
+		frame += "event: " + event + "\n"
+	}
+	frame += fmt.Sprintf("data: %s\n\n", data)
+	if style == consts.StyleOpenAI {
+		frame += "data: [DONE]\n\n"
+	}
+	if _, err := io.WriteString(w, frame); err != nil {
+		slog.Error("write stream error", "error", err)
+	}
+}
+
+// chatErrorStatus 将转发失败映射为 HTTP 状态码，仅透传客户端可据此处理的上游状态码
+func chatErrorStatus(err error) int {
+	var upstreamErr *service.UpstreamError
+	var timeoutErr *service.FirstByteTimeoutError
+	switch {
+	case errors.Is(err, service.ErrModelNotFound), errors.Is(err, service.ErrNoProvider):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrRetryTimeout), errors.As(err, &timeoutErr):
+		return http.StatusGatewayTimeout
+	case errors.As(err, &upstreamErr):
+		switch upstreamErr.Status {
+		case http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity, http.StatusTooManyRequests:
+			return upstreamErr.Status
+		}
+		// 上游鉴权失败或服务异常属于网关侧问题，不透传
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func openAIErrorType(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	}
+	if status < http.StatusInternalServerError {
+		return "invalid_request_error"
+	}
+	return "server_error"
+}
+
+func anthropicErrorType(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable:
+		return "overloaded_error"
+	}
+	if status < http.StatusInternalServerError {
+		return "invalid_request_error"
+	}
+	return "api_error"
+}
+
+func geminiErrorStatus(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	case http.StatusGatewayTimeout:
+		return "DEADLINE_EXCEEDED"
+	}
+	if status < http.StatusInternalServerError {
+		return "INVALID_ARGUMENT"
+	}
+	return "INTERNAL"
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/atopos31/llmio/common"
 	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/i18n"
 	"github.com/atopos31/llmio/providers"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -20,7 +21,7 @@ func CountTokens(c *gin.Context) {
 	config, err := gorm.G[models.Config](models.DB).Where("key = ?", models.KeyAnthropicCountTokens).First(ctx)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			common.NotFound(c, "Anthropic count tokens config not found")
+			common.NotFound(c, common.T(c, i18n.MsgCountTokensConfigNotFound))
 			return
 		}
 		common.InternalServerError(c, "Failed to retrieve Anthropic count tokens config: "+err.Error())
@@ -88,7 +89,7 @@ func TestCountTokens(c *gin.Context) {
 	config, err := gorm.G[models.Config](models.DB).Where("key = ?", models.KeyAnthropicCountTokens).First(ctx)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			common.NotFound(c, "Anthropic count tokens config not found")
+			common.NotFound(c, common.T(c, i18n.MsgCountTokensConfigNotFound))
 			return
 		}
 		common.InternalServerError(c, "Failed to retrieve Anthropic count tokens config: "+err.Error())
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/pkg/i18n"
 	"github.com/gin-gonic/gin"
 )
 
@@ -52,7 +53,7 @@ type EventLoggingEnv struct {
 func EventLogging(c *gin.Context) {
 	var req EventLoggingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		common.BadRequest(c, "Invalid request: "+err.Error())
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -1,7 +1,7 @@
 package handler
 
 import (
-	"database/sql"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -9,40 +9,66 @@ import (
 
 	"github.com/atopos31/llmio/common"
 	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/env"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/atopos31/llmio/service"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
+// queryDays 解析统计天数，超过 LLMIO_ADMIN_MAX_DAYS 时拒绝，避免一次扫描过多日志
+func queryDays(c *gin.Context) (int, bool) {
+	days, err := strconv.Atoi(c.Param("days"))
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidDays))
+		return 0, false
+	}
+	return days, checkDays(c, days)
+}
+
+// checkDays 校验统计天数不超过 LLMIO_ADMIN_MAX_DAYS，超出时写入错误响应
+func checkDays(c *gin.Context, days int) bool {
+	if maxDays := env.GetWithDefault("LLMIO_ADMIN_MAX_DAYS", 90); days < 0 || (maxDays > 0 && days > maxDays) {
+		common.BadRequest(c, common.T(c, i18n.MsgDaysOutOfRange, maxDays))
+		return false
+	}
+	return true
+}
+
+// checkTimeRange 校验 start 到 end(为空时取当前时间)的跨度不超过 LLMIO_ADMIN_MAX_DAYS，未指定 start 时不限制
+func checkTimeRange(c *gin.Context, start, end time.Time) bool {
+	if start.IsZero() {
+		return true
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return checkDays(c, int(math.Ceil(max(end.Sub(start), 0).Hours()/24)))
+}
+
 type MetricsRes struct {
 	Reqs   int64 `json:"reqs"`
 	Tokens int64 `json:"tokens"`
 }
 
 func Metrics(c *gin.Context) {
-	days, err := strconv.Atoi(c.Param("days"))
-	if err != nil {
-		common.BadRequest(c, "Invalid days parameter")
+	days, ok := queryDays(c)
+	if !ok {
 		return
 	}
 
 	now := time.Now()
 	year, month, day := now.Date()
-	chain := gorm.G[models.ChatLog](models.DB).Where("created_at >= ?", time.Date(year, month, day, 0, 0, 0, 0, now.Location()).AddDate(0, 0, -days))
-
-	reqs, err := chain.Count(c.Request.Context(), "id")
+	sums, err := service.SumMetrics(c.Request.Context(), time.Date(year, month, day, 0, 0, 0, 0, now.Location()).AddDate(0, 0, -days), service.RollupByNone)
 	if err != nil {
-		common.InternalServerError(c, "Failed to count requests: "+err.Error())
+		common.InternalServerError(c, "Failed to sum requests: "+err.Error())
 		return
 	}
-	var tokens sql.NullInt64
-	if err := chain.Select("sum(total_tokens) as tokens").Scan(c.Request.Context(), &tokens); err != nil {
-		common.InternalServerError(c, "Failed to sum tokens: "+err.Error())
-		return
+	var res MetricsRes
+	for _, sum := range sums {
+		res.Reqs += sum.Requests
+		res.Tokens += sum.TotalTokens
 	}
-	common.Success(c, MetricsRes{
-		Reqs:   reqs,
-		Tokens: tokens.Int64,
-	})
+	common.Success(c, res)
 }
 
 type Count struct {
@@ -51,16 +77,15 @@ type Count struct {
 }
 
 func Counts(c *gin.Context) {
-	results := make([]Count, 0)
-	if err := models.DB.
-		Model(&models.ChatLog{}).
-		Select("name as model, COUNT(*) as calls").
-		Group("name").
-		Order("calls DESC").
-		Scan(&results).Error; err != nil {
+	sums, err := service.SumMetrics(c.Request.Context(), time.Time{}, service.RollupByModel)
+	if err != nil {
 		common.InternalServerError(c, err.Error())
 		return
 	}
+	results := make([]Count, 0, len(sums))
+	for _, sum := range sums {
+		results = append(results, Count{Model: sum.Model, Calls: sum.Requests})
+	}
 	const topN = 5
 	if len(results) > topN {
 		var othersCalls int64
@@ -83,18 +108,8 @@ type ProjectCount struct {
 }
 
 func ProjectCounts(c *gin.Context) {
-	type authKeyCount struct {
-		AuthKeyID uint  `gorm:"column:auth_key_id"`
-		Calls     int64 `gorm:"column:calls"`
-	}
-
-	rows := make([]authKeyCount, 0)
-	if err := models.DB.
-		Model(&models.ChatLog{}).
-		Select("auth_key_id, COUNT(*) as calls").
-		Group("auth_key_id").
-		Order("calls DESC").
-		Scan(&rows).Error; err != nil {
+	rows, err := service.SumMetrics(c.Request.Context(), time.Time{}, service.RollupByAuthKey)
+	if err != nil {
 		common.InternalServerError(c, err.Error())
 		return
 	}
@@ -109,7 +124,7 @@ func ProjectCounts(c *gin.Context) {
 
 	keys := make([]models.AuthKey, 0)
 	if len(ids) > 0 {
-		if err := models.DB.
+		if err := models.ReadDB().
 			Model(&models.AuthKey{}).
 			Where("id IN ?", ids).
 			Find(&keys).Error; err != nil {
@@ -131,7 +146,7 @@ func ProjectCounts(c *gin.Context) {
 		} else if name, ok := keyMap[row.AuthKeyID]; ok && name != "" {
 			project = name
 		}
-		projectCalls[project] += row.Calls
+		projectCalls[project] += row.Requests
 	}
 
 	results := make([]ProjectCount, 0, len(projectCalls))
@@ -158,3 +173,125 @@ func ProjectCounts(c *gin.Context) {
 
 	common.Success(c, results)
 }
+
+// ModelHistograms 按模型统计请求/响应大小与 token 分布，用于容量规划
+func ModelHistograms(c *gin.Context) {
+	days, ok := queryDays(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	year, month, day := now.Date()
+	since := time.Date(year, month, day, 0, 0, 0, 0, now.Location()).AddDate(0, 0, -days)
+
+	histograms, err := service.ModelHistograms(c.Request.Context(), since, c.Query("model"))
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, histograms)
+}
+
+// ProviderResponseSizes 按提供商统计平均响应大小与分块数
+func ProviderResponseSizes(c *gin.Context) {
+	days, ok := queryDays(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	year, month, day := now.Date()
+	since := time.Date(year, month, day, 0, 0, 0, 0, now.Location()).AddDate(0, 0, -days)
+
+	sizes, err := service.ProviderResponseSizes(c.Request.Context(), since)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, sizes)
+}
+
+// LatencyPercentiles 按模型或提供商统计首包耗时与总耗时的 p50/p95/p99: GET /api/metrics/latency/:days?by=model|provider
+func LatencyPercentiles(c *gin.Context) {
+	days, ok := queryDays(c)
+	if !ok {
+		return
+	}
+	by := c.DefaultQuery("by", service.LatencyByModel)
+	if by != service.LatencyByModel && by != service.LatencyByProvider {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidLatencyGroup))
+		return
+	}
+
+	now := time.Now()
+	year, month, day := now.Date()
+	stats, err := service.LatencyStats(c.Request.Context(), service.LatencyFilter{
+		Since:        time.Date(year, month, day, 0, 0, 0, 0, now.Location()).AddDate(0, 0, -days),
+		By:           by,
+		Model:        c.Query("model"),
+		ProviderName: c.Query("provider_name"),
+	})
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, stats)
+}
+
+// FinishReasons 按提供商统计结束原因分布: GET /api/metrics/finish-reasons/:days
+func FinishReasons(c *gin.Context) {
+	days, ok := queryDays(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	year, month, day := now.Date()
+	since := time.Date(year, month, day, 0, 0, 0, 0, now.Location()).AddDate(0, 0, -days)
+
+	stats, err := service.FinishReasonStats(c.Request.Context(), since)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, stats)
+}
+
+// MetricRollups 查询预聚合的小时或天统计，含延迟分位数: GET /api/metrics/rollups
+func MetricRollups(c *gin.Context) {
+	filter := service.RollupFilter{
+		Period:       c.DefaultQuery("period", models.RollupHour),
+		Model:        c.Query("model"),
+		ProviderName: c.Query("provider_name"),
+	}
+	if filter.Period != models.RollupHour && filter.Period != models.RollupDay {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRollupPeriod))
+		return
+	}
+	for key, t := range map[string]*time.Time{"start": &filter.Start, "end": &filter.End} {
+		if v := c.Query(key); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				common.BadRequest(c, common.T(c, i18n.MsgInvalidTimeFilter, key))
+				return
+			}
+			*t = parsed
+		}
+	}
+	if v := c.Query("auth_key_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+			return
+		}
+		filter.AuthKeyID = new(uint(id))
+	}
+
+	rollups, err := service.GetMetricRollups(c.Request.Context(), filter)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, rollups)
+}
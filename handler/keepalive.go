@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/atopos31/llmio/pkg/env"
+)
+
+// sseKeepaliveInterval 等待上游首个数据块期间发送 SSE 注释的间隔，0 表示关闭
+func sseKeepaliveInterval() time.Duration {
+	return time.Duration(env.GetWithDefault("LLMIO_SSE_KEEPALIVE", 0)) * time.Second
+}
+
+// startKeepalive 定时向客户端写入 `: keepalive` 注释，返回的 stop 会等待写入协程退出，之后可安全写入响应
+func startKeepalive(w io.Writer, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+					slog.Error("write sse keepalive", "error", err)
+					return
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+		})
+	}
+}
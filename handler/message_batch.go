@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateMessageBatch 创建 Anthropic 消息批处理: POST /v1/messages/batches
+func CreateMessageBatch(c *gin.Context) {
+	raw, ok := readRequestBody(c, consts.StyleAnthropic)
+	if !ok {
+		return
+	}
+	model, err := service.MessageBatchModel(raw)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgBatchModelMismatch))
+		return
+	}
+
+	ctx := c.Request.Context()
+	valid, err := validateAuthKey(ctx, model)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	if !valid {
+		common.ErrorWithHttpStatus(c, http.StatusForbidden, http.StatusForbidden, common.T(c, i18n.MsgModelPermissionDenied, model))
+		return
+	}
+
+	status, body, err := service.CreateMessageBatch(ctx, raw, model, batchAuthKeyID(c))
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	c.Data(status, "application/json", body)
+}
+
+// ListMessageBatches 列出当前密钥创建的批次: GET /v1/messages/batches
+func ListMessageBatches(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 1000 {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, "limit must be between 1 and 1000"))
+		return
+	}
+	list, err := service.ListMessageBatches(c.Request.Context(), batchAuthKeyID(c), limit, c.Query("after_id"))
+	if err != nil {
+		writeBatchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetMessageBatch 查询批次状态: GET /v1/messages/batches/:id
+func GetMessageBatch(c *gin.Context) {
+	forwardMessageBatch(c, http.MethodGet, "")
+}
+
+// CancelMessageBatch 取消批次: POST /v1/messages/batches/:id/cancel
+func CancelMessageBatch(c *gin.Context) {
+	forwardMessageBatch(c, http.MethodPost, "/cancel")
+}
+
+// DeleteMessageBatch 删除批次: DELETE /v1/messages/batches/:id
+func DeleteMessageBatch(c *gin.Context) {
+	forwardMessageBatch(c, http.MethodDelete, "")
+}
+
+// MessageBatchResults 获取批次结果: GET /v1/messages/batches/:id/results
+func MessageBatchResults(c *gin.Context) {
+	res, err := service.MessageBatchResults(c.Request.Context(), c.Param("id"), batchAuthKeyID(c))
+	if err != nil {
+		writeBatchError(c, err)
+		return
+	}
+	defer res.Body.Close()
+
+	for k, values := range res.Header {
+		for _, value := range values {
+			c.Writer.Header().Add(k, value)
+		}
+	}
+	c.Status(res.StatusCode)
+	if _, err := io.Copy(c.Writer, res.Body); err != nil {
+		slog.Error("copy batch results", "error", err)
+	}
+}
+
+func forwardMessageBatch(c *gin.Context, method, path string) {
+	status, body, err := service.ForwardMessageBatch(c.Request.Context(), c.Param("id"), batchAuthKeyID(c), method, path)
+	if err != nil {
+		writeBatchError(c, err)
+		return
+	}
+	c.Data(status, "application/json", body)
+}
+
+// batchAuthKeyID 返回当前请求的 AuthKey ID，管理员令牌为 0 可访问全部批次
+func batchAuthKeyID(c *gin.Context) uint {
+	id, _ := c.Request.Context().Value(consts.ContextKeyAuthKeyID).(uint)
+	return id
+}
+
+func writeBatchError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrBatchNotFound) {
+		common.NotFound(c, common.T(c, i18n.MsgMessageBatchNotFound))
+		return
+	}
+	common.InternalServerError(c, err.Error())
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/atopos31/llmio/providers"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// ModelImportRequest 从提供商批量导入模型
+type ModelImportRequest struct {
+	Models    []string          `json:"models"`     // 要导入的上游模型，为空时导入提供商返回的全部模型
+	Aliases   map[string]string `json:"aliases"`    // 上游模型到对外模型名的手动映射，优先于自动别名
+	AutoAlias bool              `json:"auto_alias"` // 自动去掉厂商前缀与 -latest 等后缀
+	DryRun    bool              `json:"dry_run"`    // 仅预览映射，不写入
+}
+
+// ModelImportResponse 导入映射与新建的关联数
+type ModelImportResponse struct {
+	Items   []service.ModelImportItem `json:"items"`
+	Created int                       `json:"created"`
+}
+
+// ImportProviderModels 批量导入提供商模型: POST /api/providers/:id/import
+func ImportProviderModels(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+	var req ModelImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
+		return
+	}
+	ctx := c.Request.Context()
+	provider, err := gorm.G[models.Provider](models.DB).Where("id = ?", id).First(ctx)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.NotFound(c, common.T(c, i18n.MsgProviderNotFound))
+			return
+		}
+		common.InternalServerError(c, "Database error: "+err.Error())
+		return
+	}
+
+	providerModels := lo.Compact(req.Models)
+	if len(providerModels) == 0 {
+		chatModel, err := providers.New(ctx, provider.Type, provider.Config, provider.ClientOptions())
+		if err != nil {
+			common.InternalServerError(c, "Failed to get models: "+err.Error())
+			return
+		}
+		upstream, err := chatModel.Models(ctx)
+		if err != nil {
+			common.NotFound(c, "Failed to get models: "+err.Error())
+			return
+		}
+		providerModels = lo.Map(upstream, func(m providers.Model, _ int) string { return m.ID })
+	}
+
+	items, err := service.PreviewModelImport(ctx, provider.ID, providerModels, req.Aliases, req.AutoAlias)
+	if err != nil {
+		common.InternalServerError(c, "Failed to preview import: "+err.Error())
+		return
+	}
+	res := ModelImportResponse{Items: items}
+	if !req.DryRun {
+		if res.Created, err = service.ApplyModelImport(ctx, provider.ID, items); err != nil {
+			common.InternalServerError(c, "Failed to import models: "+err.Error())
+			return
+		}
+	}
+	common.Success(c, res)
+}
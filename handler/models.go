@@ -3,7 +3,10 @@ package handler
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
 	"slices"
+	"strings"
 
 	"github.com/atopos31/llmio/common"
 	"github.com/atopos31/llmio/consts"
@@ -11,11 +14,31 @@ import (
 	"github.com/atopos31/llmio/providers"
 	"github.com/atopos31/llmio/service"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
+// OpenAIModel 在 OpenAI 模型对象上附加弃用信息扩展字段
+type OpenAIModel struct {
+	providers.Model
+	Deprecation *models.Deprecation `json:"deprecation,omitempty"`
+}
+
+// OpenAIModelDetail 单个模型对象，附加 llmio 扩展的能力与上下文长度
+type OpenAIModelDetail struct {
+	OpenAIModel
+	Capabilities  service.ModelCapabilities `json:"capabilities"`
+	ContextLength int                       `json:"context_length,omitempty"`
+}
+
+type OpenAIModelList struct {
+	Object string        `json:"object"`
+	Data   []OpenAIModel `json:"data"`
+}
+
+// OpenAIModelsHandler 列出可用模型；内置探测模型 llmio-ping 有意不列出，避免客户端自动选用
 func OpenAIModelsHandler(c *gin.Context) {
 	ctx := c.Request.Context()
-	models, err := service.ModelsByTypes(ctx, consts.StyleOpenAI, consts.StyleOpenAIRes)
+	models, err := service.ModelsByTypes(ctx, consts.StyleOpenAI, consts.StyleOpenAIRes, consts.StyleGeminiOpenAI, consts.StyleAnthropic, consts.StyleOllama)
 	if err != nil {
 		common.InternalServerError(c, err.Error())
 		return
@@ -25,24 +48,69 @@ func OpenAIModelsHandler(c *gin.Context) {
 		common.InternalServerError(c, err.Error())
 		return
 	}
-	resModels := make([]providers.Model, 0)
+	resModels := make([]OpenAIModel, 0)
 	for _, model := range models {
-		resModels = append(resModels, providers.Model{
+		item := OpenAIModel{Model: providers.Model{
 			ID:      model.Name,
 			Object:  "model",
 			Created: model.CreatedAt.Unix(),
 			OwnedBy: "llmio",
-		})
+		}}
+		if model.Deprecated() {
+			item.Deprecation = model.Deprecation
+		}
+		resModels = append(resModels, item)
 	}
-	common.SuccessRaw(c, providers.ModelList{
+	common.SuccessRaw(c, OpenAIModelList{
 		Object: "list",
 		Data:   resModels,
 	})
 }
 
+// OpenAIModelHandler 查询单个模型: GET /v1/models/:id，模型名可包含 "/"
+func OpenAIModelHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := strings.TrimPrefix(c.Param("id"), "/")
+	list, err := service.ModelsByTypes(ctx, consts.StyleOpenAI, consts.StyleOpenAIRes, consts.StyleGeminiOpenAI, consts.StyleAnthropic, consts.StyleOllama)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	list, err = filterByAuthKey(ctx, list)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	model, ok := lo.Find(list, func(m models.Model) bool { return m.Name == name })
+	if !ok {
+		chatError(c, consts.StyleOpenAI, http.StatusNotFound, fmt.Sprintf("The model '%s' does not exist", name))
+		return
+	}
+	caps, err := service.GetModelCapabilities(ctx, model.ID)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	item := OpenAIModelDetail{
+		OpenAIModel: OpenAIModel{Model: providers.Model{
+			ID:      model.Name,
+			Object:  "model",
+			Created: model.CreatedAt.Unix(),
+			OwnedBy: "llmio",
+		}},
+		Capabilities:  caps,
+		ContextLength: model.ContextLength,
+	}
+	if model.Deprecated() {
+		item.Deprecation = model.Deprecation
+	}
+	common.SuccessRaw(c, item)
+}
+
 func AnthropicModelsHandler(c *gin.Context) {
 	ctx := c.Request.Context()
-	models, err := service.ModelsByTypes(ctx, consts.StyleAnthropic)
+	// 仅有 OpenAI 提供商的模型可经协议转换通过 Messages 接口调用
+	models, err := service.ModelsByTypes(ctx, append([]string{consts.StyleAnthropic}, consts.ProviderTypes(consts.StyleOpenAI)...)...)
 	if err != nil {
 		common.InternalServerError(c, err.Error())
 		return
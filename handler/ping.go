@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/gin-gonic/gin"
+)
+
+const pingReply = "pong"
+
+// pingHandler 直接响应内置探测模型，不经过负载均衡与上游
+func pingHandler(c *gin.Context, style string, stream bool) {
+	created := time.Now().Unix()
+	id := fmt.Sprintf("llmio-ping-%d", time.Now().UnixNano())
+	if stream {
+		writeHeader(c, true, http.Header{})
+		for _, event := range pingEvents(style, id, created) {
+			if event.name != "" {
+				fmt.Fprintf(c.Writer, "event: %s\n", event.name)
+			}
+			fmt.Fprintf(c.Writer, "data: %s\n\n", event.data)
+		}
+		c.Writer.Flush()
+		return
+	}
+	c.JSON(http.StatusOK, pingBody(style, id, created))
+}
+
+type pingEvent struct {
+	name string
+	data string
+}
+
+func pingBody(style, id string, created int64) gin.H {
+	switch style {
+	case consts.StyleAnthropic:
+		return gin.H{
+			"id":            id,
+			"type":          "message",
+			"role":          "assistant",
+			"model":         consts.PingModel,
+			"content":       []gin.H{{"type": "text", "text": pingReply}},
+			"stop_reason":   "end_turn",
+			"stop_sequence": nil,
+			"usage":         gin.H{"input_tokens": 0, "output_tokens": 0},
+		}
+	case consts.StyleOpenAIRes:
+		return gin.H{
+			"id":         id,
+			"object":     "response",
+			"created_at": created,
+			"status":     "completed",
+			"model":      consts.PingModel,
+			"output": []gin.H{{
+				"type":    "message",
+				"id":      id + "-msg",
+				"status":  "completed",
+				"role":    "assistant",
+				"content": []gin.H{{"type": "output_text", "text": pingReply, "annotations": []any{}}},
+			}},
+			"usage": gin.H{"input_tokens": 0, "output_tokens": 0, "total_tokens": 0},
+		}
+	case consts.StyleGemini:
+		return gin.H{
+			"candidates": []gin.H{{
+				"content":      gin.H{"role": "model", "parts": []gin.H{{"text": pingReply}}},
+				"finishReason": "STOP",
+				"index":        0,
+			}},
+			"usageMetadata": gin.H{"promptTokenCount": 0, "candidatesTokenCount": 0, "totalTokenCount": 0},
+			"modelVersion":  consts.PingModel,
+		}
+	default:
+		return gin.H{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": created,
+			"model":   consts.PingModel,
+			"choices": []gin.H{{
+				"index":         0,
+				"message":       gin.H{"role": "assistant", "content": pingReply},
+				"finish_reason": "stop",
+			}},
+			"usage": gin.H{"prompt_tokens": 0, "completion_tokens": 0, "total_tokens": 0},
+		}
+	}
+}
+
+func pingEvents(style, id string, created int64) []pingEvent {
+	switch style {
+	case consts.StyleAnthropic:
+		return []pingEvent{
+			{"message_start", fmt.Sprintf(`{"type":"message_start","message":{"id":%q,"type":"message","role":"assistant","model":%q,"content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":0,"output_tokens":0}}}`, id, consts.PingModel)},
+			{"content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`},
+			{"content_block_delta", fmt.Sprintf(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":%q}}`, pingReply)},
+			{"content_block_stop", `{"type":"content_block_stop","index":0}`},
+			{"message_delta", `{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":0}}`},
+			{"message_stop", `{"type":"message_stop"}`},
+		}
+	case consts.StyleOpenAIRes:
+		return []pingEvent{
+			{"response.created", fmt.Sprintf(`{"type":"response.created","response":{"id":%q,"object":"response","created_at":%d,"status":"in_progress","model":%q,"output":[]}}`, id, created, consts.PingModel)},
+			{"response.output_text.delta", fmt.Sprintf(`{"type":"response.output_text.delta","item_id":%q,"output_index":0,"content_index":0,"delta":%q}`, id+"-msg", pingReply)},
+			{"response.completed", fmt.Sprintf(`{"type":"response.completed","response":{"id":%q,"object":"response","created_at":%d,"status":"completed","model":%q,"usage":{"input_tokens":0,"output_tokens":0,"total_tokens":0}}}`, id, created, consts.PingModel)},
+		}
+	case consts.StyleGemini:
+		return []pingEvent{
+			{"", fmt.Sprintf(`{"candidates":[{"content":{"role":"model","parts":[{"text":%q}]},"finishReason":"STOP","index":0}],"usageMetadata":{"promptTokenCount":0,"candidatesTokenCount":0,"totalTokenCount":0},"modelVersion":%q}`, pingReply, consts.PingModel)},
+		}
+	default:
+		return []pingEvent{
+			{"", fmt.Sprintf(`{"id":%q,"object":"chat.completion.chunk","created":%d,"model":%q,"choices":[{"index":0,"delta":{"role":"assistant","content":%q},"finish_reason":null}]}`, id, created, consts.PingModel, pingReply)},
+			{"", fmt.Sprintf(`{"id":%q,"object":"chat.completion.chunk","created":%d,"model":%q,"choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":0,"completion_tokens":0,"total_tokens":0}}`, id, created, consts.PingModel)},
+			{"", "[DONE]"},
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func TestPingHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/v1/chat/completions", ChatCompletionsHandler)
+	r.POST("/v1/messages", Messages)
+	r.POST("/v1/responses", ResponsesHandler)
+
+	tests := []struct {
+		path   string
+		body   string
+		fields map[string]string // gjson 路径 -> 期望值
+	}{
+		{
+			path: "/v1/chat/completions",
+			body: `{"model":"llmio-ping","messages":[{"role":"user","content":"hi"}]}`,
+			fields: map[string]string{
+				"object":                    "chat.completion",
+				"model":                     consts.PingModel,
+				"choices.0.message.content": pingReply,
+				"choices.0.finish_reason":   "stop",
+				"usage.total_tokens":        "0",
+			},
+		},
+		{
+			path: "/v1/messages",
+			body: `{"model":"llmio-ping","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`,
+			fields: map[string]string{
+				"type":               "message",
+				"model":              consts.PingModel,
+				"content.0.text":     pingReply,
+				"stop_reason":        "end_turn",
+				"usage.input_tokens": "0",
+			},
+		},
+		{
+			path: "/v1/responses",
+			body: `{"model":"llmio-ping","input":"hi"}`,
+			fields: map[string]string{
+				"object":                  "response",
+				"status":                  "completed",
+				"model":                   consts.PingModel,
+				"output.0.content.0.text": pingReply,
+				"usage.total_tokens":      "0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body %s", tt.path, w.Code, w.Body.String())
+		}
+		for path, want := range tt.fields {
+			if got := gjson.Get(w.Body.String(), path).String(); got != want {
+				t.Fatalf("%s: %s = %q, want %q", tt.path, path, got, want)
+			}
+		}
+	}
+}
+
+func TestPingHandlerStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/v1/chat/completions", ChatCompletionsHandler)
+	r.POST("/v1/messages", Messages)
+
+	tests := []struct {
+		path string
+		body string
+		want []string // 按顺序出现的 SSE 行
+	}{
+		{
+			path: "/v1/chat/completions",
+			body: `{"model":"llmio-ping","stream":true,"messages":[{"role":"user","content":"hi"}]}`,
+			want: []string{`"content":"pong"`, `"finish_reason":"stop"`, "data: [DONE]"},
+		},
+		{
+			path: "/v1/messages",
+			body: `{"model":"llmio-ping","stream":true,"max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`,
+			want: []string{"event: message_start", `"text":"pong"`, "event: message_stop"},
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+		if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("%s: content type = %q", tt.path, ct)
+		}
+		body := w.Body.String()
+		for _, want := range tt.want {
+			i := strings.Index(body, want)
+			if i < 0 {
+				t.Fatalf("%s: missing %q in %s", tt.path, want, w.Body.String())
+			}
+			body = body[i+len(want):]
+		}
+	}
+}
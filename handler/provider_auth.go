@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetAuthDisabledProviders 列出因上游鉴权失败被自动停用的提供商
+func GetAuthDisabledProviders(c *gin.Context) {
+	list, err := service.ListAuthDisabledProviders(c.Request.Context())
+	if err != nil {
+		common.InternalServerError(c, "Failed to query auth disabled providers: "+err.Error())
+		return
+	}
+	common.Success(c, list)
+}
+
+// EnableProviderAuth 手动恢复被自动停用的提供商
+func EnableProviderAuth(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+
+	switch err := service.EnableProviderAuth(c.Request.Context(), uint(id)); {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		common.NotFound(c, common.T(c, i18n.MsgProviderNotFound))
+	case errors.Is(err, service.ErrProviderNotAuthDisabled):
+		common.BadRequest(c, err.Error())
+	case err != nil:
+		common.InternalServerError(c, err.Error())
+	default:
+		common.Success(c, nil)
+	}
+}
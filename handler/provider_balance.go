@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetProviderBalance 查询提供商账户余额: GET /api/providers/:id/balance?refresh=true
+func GetProviderBalance(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+	refresh, _ := strconv.ParseBool(c.Query("refresh"))
+
+	balance, err := service.GetProviderBalance(c.Request.Context(), uint(id), refresh)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		common.NotFound(c, common.T(c, i18n.MsgProviderNotFound))
+	case errors.Is(err, service.ErrBalanceUnsupported):
+		common.BadRequest(c, common.T(c, i18n.MsgBalanceUnsupported))
+	case err != nil:
+		common.ErrorWithHttpStatus(c, http.StatusOK, http.StatusBadGateway, err.Error())
+	default:
+		common.Success(c, balance)
+	}
+}
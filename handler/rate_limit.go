@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetRateLimitReport 查看 Key 与提供商的超限统计: GET /api/rate-limits
+func GetRateLimitReport(c *gin.Context) {
+	common.Success(c, service.GetRateLimitReport())
+}
+
+func validRateLimit(limit models.RateLimit) bool {
+	if limit.RPM < 0 {
+		return false
+	}
+	switch limit.Mode {
+	case "", consts.RateLimitEnforce, consts.RateLimitObserve:
+		return true
+	}
+	return false
+}
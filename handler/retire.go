@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RetireProviderRequest struct {
+	ObserveDays int `json:"observe_days"`
+}
+
+// StartProviderRetire 开始提供商下线流程
+func StartProviderRetire(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+
+	var req RetireProviderRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
+			return
+		}
+	}
+
+	status, err := service.StartProviderRetire(c.Request.Context(), uint(id), req.ObserveDays)
+	if err != nil {
+		retireError(c, err)
+		return
+	}
+	common.Success(c, status)
+}
+
+// GetProviderRetireStatus 查询提供商下线进度
+func GetProviderRetireStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+
+	status, err := service.GetProviderRetireStatus(c.Request.Context(), uint(id))
+	if err != nil {
+		retireError(c, err)
+		return
+	}
+	common.Success(c, status)
+}
+
+// CancelProviderRetire 撤销下线并恢复权重
+func CancelProviderRetire(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+
+	status, err := service.CancelProviderRetire(c.Request.Context(), uint(id))
+	if err != nil {
+		retireError(c, err)
+		return
+	}
+	common.Success(c, status)
+}
+
+// ArchiveProvider 归档已完成观察期的提供商，force=true 时跳过检查
+func ArchiveProvider(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+
+	force := c.Query("force") == "true"
+	status, err := service.ArchiveProvider(c.Request.Context(), uint(id), force)
+	if err != nil {
+		retireError(c, err)
+		return
+	}
+	common.Success(c, status)
+}
+
+// GetProviderRetirements 列出所有下线中或已归档的提供商
+func GetProviderRetirements(c *gin.Context) {
+	list, err := service.ListProviderRetirements(c.Request.Context())
+	if err != nil {
+		common.InternalServerError(c, "Failed to query retirements: "+err.Error())
+		return
+	}
+	common.Success(c, list)
+}
+
+func retireError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		common.NotFound(c, common.T(c, i18n.MsgProviderNotFound))
+	case errors.Is(err, service.ErrProviderRetiring),
+		errors.Is(err, service.ErrProviderArchived),
+		errors.Is(err, service.ErrProviderNotRetiring),
+		errors.Is(err, service.ErrRetireNotReady):
+		common.BadRequest(c, err.Error())
+	default:
+		common.InternalServerError(c, err.Error())
+	}
+}
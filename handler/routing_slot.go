@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+)
+
+// StageRoutingSlotRequest 暂存分组请求
+type StageRoutingSlotRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// SwitchRoutingSlotRequest 切换分组请求
+type SwitchRoutingSlotRequest struct {
+	Slot string `json:"slot"`
+}
+
+// GetRoutingSlot 获取蓝绿路由状态
+func GetRoutingSlot(c *gin.Context) {
+	status, err := service.GetRoutingSlotStatus(c.Request.Context())
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, status)
+}
+
+// StageRoutingSlot 复制分组关联到待切换分组，之后可在该分组上调整提供商与权重
+func StageRoutingSlot(c *gin.Context) {
+	var req StageRoutingSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
+		return
+	}
+	count, err := service.StageRoutingSlot(c.Request.Context(), req.From, req.To)
+	if err != nil {
+		routingSlotError(c, err)
+		return
+	}
+	common.Success(c, map[string]any{
+		"slot":   req.To,
+		"copied": count,
+	})
+}
+
+// SwitchRoutingSlot 原子切换生效分组
+func SwitchRoutingSlot(c *gin.Context) {
+	var req SwitchRoutingSlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
+		return
+	}
+	slot, err := service.SwitchRoutingSlot(c.Request.Context(), req.Slot)
+	if err != nil {
+		routingSlotError(c, err)
+		return
+	}
+	common.Success(c, slot)
+}
+
+// RollbackRoutingSlot 一键回滚到上一个生效分组
+func RollbackRoutingSlot(c *gin.Context) {
+	slot, err := service.RollbackRoutingSlot(c.Request.Context())
+	if err != nil {
+		routingSlotError(c, err)
+		return
+	}
+	common.Success(c, slot)
+}
+
+func routingSlotError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidSlot):
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidSlot))
+	case errors.Is(err, service.ErrSlotEmpty),
+		errors.Is(err, service.ErrNoPreviousSlot),
+		errors.Is(err, service.ErrSlotActive):
+		common.BadRequest(c, err.Error())
+	default:
+		common.InternalServerError(c, err.Error())
+	}
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RunProviderSelftest 对提供商执行能力自检并返回通过/失败矩阵，可通过 model 参数指定上游模型
+func RunProviderSelftest(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+
+	result, err := service.RunProviderSelftest(c.Request.Context(), uint(id), c.Query("model"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.NotFound(c, common.T(c, i18n.MsgProviderNotFound))
+			return
+		}
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, result)
+}
+
+// GetProviderSelftests 查询提供商历史自检记录
+func GetProviderSelftests(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, "invalid limit"))
+		return
+	}
+
+	results, err := service.ProviderSelftests(c.Request.Context(), uint(id), limit)
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, results)
+}
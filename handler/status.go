@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+)
+
+// StatusPage 管理端厂商状态，按 base_url 主机聚合: GET /api/status
+func StatusPage(c *gin.Context) {
+	page, err := service.GetStatusPage(c.Request.Context())
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, page)
+}
+
+// PublicStatusPage 公开的厂商状态页，无需鉴权，只展示厂商或提供商类型与错误率: GET /status
+func PublicStatusPage(c *gin.Context) {
+	page, err := service.GetStatusPage(c.Request.Context())
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, service.NewPublicStatusPage(page))
+}
+
+// SystemStatus 网关进程资源占用: GET /api/system/status
+func SystemStatus(c *gin.Context) {
+	status, err := service.GetSystemStatus()
+	if err != nil {
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, status)
+}
+
+// Preflight 重新执行启动检查: GET /api/preflight
+func Preflight(c *gin.Context) {
+	common.Success(c, service.Preflight(c.Request.Context()))
+}
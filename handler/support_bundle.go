@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetSupportBundle 下载脱敏后的诊断包: GET /api/support-bundle
+func GetSupportBundle(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := service.WriteSupportBundle(c.Request.Context(), &buf); err != nil {
+		common.InternalServerError(c, "Failed to build support bundle: "+err.Error())
+		return
+	}
+	name := fmt.Sprintf("llmio-support-%s.zip", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// tailKeepAlive 无事件时发送心跳的间隔，避免代理断开空闲连接
+const tailKeepAlive = 15 * time.Second
+
+// TailModel 以 SSE 实时推送单个模型的请求事件: GET /api/models/:id/tail
+func TailModel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+	ctx := c.Request.Context()
+	model, err := gorm.G[models.Model](models.DB).Where("id = ?", id).First(ctx)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.NotFound(c, common.T(c, i18n.MsgModelNotFound))
+			return
+		}
+		common.InternalServerError(c, err.Error())
+		return
+	}
+
+	events, unsubscribe := service.SubscribeTail(model.Name)
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("ready", gin.H{"model": model.Name})
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(tailKeepAlive)
+	defer ticker.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+		case <-ticker.C:
+			c.SSEvent("ping", time.Now().Unix())
+		}
+		return true
+	})
+}
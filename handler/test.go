@@ -12,12 +12,14 @@ import (
 	"github.com/atopos31/llmio/common"
 	"github.com/atopos31/llmio/consts"
 	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/i18n"
 	"github.com/atopos31/llmio/providers"
 	"github.com/atopos31/llmio/service"
 	"github.com/atopos31/nsxno/react"
 	"github.com/gin-gonic/gin"
 	"github.com/openai/openai-go/v2"
 	"github.com/openai/openai-go/v2/option"
+	"github.com/samber/lo"
 	"github.com/tidwall/gjson"
 	"gorm.io/gorm"
 )
@@ -66,6 +68,16 @@ const (
     	]
  	}`
 
+	testOllama = `{
+		"stream": false,
+		"messages": [
+			{
+				"role": "user",
+				"content": "Please reply me yes or no"
+			}
+		]
+	}`
+
 	testGemini = `{
 		"contents": [
 			{
@@ -82,7 +94,7 @@ const (
 func ProviderTestHandler(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		common.BadRequest(c, "Invalid ID format")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
 		return
 	}
 	ctx := c.Request.Context()
@@ -90,7 +102,7 @@ func ProviderTestHandler(c *gin.Context) {
 	chatModel, err := FindChatModel(ctx, id)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			common.NotFound(c, "ModelWithProvider not found")
+			common.NotFound(c, common.T(c, i18n.MsgModelProviderNotFound))
 			return
 		}
 		common.InternalServerError(c, "Database error")
@@ -98,17 +110,17 @@ func ProviderTestHandler(c *gin.Context) {
 	}
 
 	// Create the provider instance
-	providerInstance, err := providers.New(chatModel.Type, chatModel.Config, chatModel.Proxy)
+	providerInstance, err := providers.New(ctx, chatModel.Type, chatModel.Config, chatModel.clientOptions())
 	if err != nil {
 		common.BadRequest(c, "Failed to create provider: "+err.Error())
 		return
 	}
 
 	// Test connectivity by fetching models
-	client := providers.GetClient(time.Second*360, chatModel.Proxy)
+	client := providers.GetClient(time.Second*360, chatModel.clientOptions())
 	var testBody []byte
 	switch chatModel.Type {
-	case consts.StyleOpenAI:
+	case consts.StyleOpenAI, consts.StyleGeminiOpenAI:
 		testBody = []byte(testOpenAI)
 	case consts.StyleAnthropic:
 		testBody = []byte(testAnthropic)
@@ -116,20 +128,23 @@ func ProviderTestHandler(c *gin.Context) {
 		testBody = []byte(testOpenAIRes)
 	case consts.StyleGemini:
 		testBody = []byte(testGemini)
+	case consts.StyleOllama:
+		testBody = []byte(testOllama)
 	default:
-		common.BadRequest(c, "Invalid provider type")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidProviderType))
 		return
 	}
 	withHeader := false
 	if chatModel.WithHeader != nil {
 		withHeader = *chatModel.WithHeader
 	}
-	header := service.BuildHeaders(c.Request.Header, withHeader, chatModel.CustomerHeaders, false)
+	header := service.BuildHeaders(c.Request.Header, withHeader, lo.Assign(chatModel.Headers, chatModel.CustomerHeaders), false, chatModel.UserAgent)
 	req, err := providerInstance.BuildReq(ctx, header, chatModel.Model, []byte(testBody))
 	if err != nil {
 		common.ErrorWithHttpStatus(c, http.StatusOK, 502, "Failed to connect to provider: "+err.Error())
 		return
 	}
+	service.ApplyQueryParams(req, chatModel.QueryParams)
 	res, err := client.Do(req)
 	if err != nil {
 		common.ErrorWithHttpStatus(c, http.StatusOK, 502, "Failed to connect to provider: "+err.Error())
@@ -155,28 +170,33 @@ func TestReactHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 	id := c.Param("id")
 	if id == "" {
-		common.BadRequest(c, "Invalid ID format")
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
 		return
 	}
 
 	chatModel, err := FindChatModel(ctx, id)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			common.NotFound(c, "ModelWithProvider not found")
+			common.NotFound(c, common.T(c, i18n.MsgModelProviderNotFound))
 			return
 		}
 		common.InternalServerError(c, "Database error")
 		return
 	}
 
-	if chatModel.Type != consts.StyleOpenAI {
+	if chatModel.Type != consts.StyleOpenAI && chatModel.Type != consts.StyleGeminiOpenAI {
 		c.SSEvent("error", "该测试仅支持 OpenAI 类型")
 		return
 	}
 
 	var config providers.OpenAI
 	if err := json.Unmarshal([]byte(chatModel.Config), &config); err != nil {
-		common.ErrorWithHttpStatus(c, http.StatusBadRequest, 400, "Invalid config format")
+		common.ErrorWithHttpStatus(c, http.StatusBadRequest, 400, common.T(c, i18n.MsgInvalidConfigFormat))
+		return
+	}
+	// 配置中保存的可能是密钥引用
+	if config.APIKey, err = providers.ResolveSecret(ctx, config.APIKey); err != nil {
+		common.BadRequest(c, "Failed to resolve api_key: "+err.Error())
 		return
 	}
 
@@ -293,13 +313,21 @@ func GetWeather(ctx context.Context, call openai.ChatCompletionChunkChoiceDeltaT
 }
 
 type ChatModel struct {
-	Name            string            `json:"name"`
-	Type            string            `json:"type"`
-	Model           string            `json:"model"`
-	Config          string            `json:"config"`
-	Proxy           string            `json:"proxy,omitempty"`
-	WithHeader      *bool             `json:"with_header,omitempty"`
-	CustomerHeaders map[string]string `json:"customer_headers,omitempty"`
+	Name            string             `json:"name"`
+	Type            string             `json:"type"`
+	Model           string             `json:"model"`
+	Config          string             `json:"config"`
+	Proxy           string             `json:"proxy,omitempty"`
+	TLS             models.ProviderTLS `json:"-"`
+	Headers         map[string]string  `json:"-"`
+	QueryParams     map[string]string  `json:"-"`
+	WithHeader      *bool              `json:"with_header,omitempty"`
+	CustomerHeaders map[string]string  `json:"customer_headers,omitempty"`
+	UserAgent       string             `json:"user_agent,omitempty"`
+}
+
+func (m *ChatModel) clientOptions() providers.ClientOptions {
+	return models.Provider{Proxy: m.Proxy, TLS: m.TLS}.ClientOptions()
 }
 
 func FindChatModel(ctx context.Context, id string) (*ChatModel, error) {
@@ -321,7 +349,11 @@ func FindChatModel(ctx context.Context, id string) (*ChatModel, error) {
 		Model:           modelWithProvider.ProviderModel,
 		Config:          provider.Config,
 		Proxy:           provider.Proxy,
+		TLS:             provider.TLS,
+		Headers:         provider.Headers,
+		QueryParams:     provider.QueryParams,
 		WithHeader:      modelWithProvider.WithHeader,
 		CustomerHeaders: modelWithProvider.CustomerHeaders,
+		UserAgent:       provider.UserAgent,
 	}, nil
 }
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetWeightAdvice 根据近期表现给出模型关联的权重调整建议
+func GetWeightAdvice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+	days := 0
+	if daysStr := c.Query("days"); daysStr != "" {
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days <= 0 {
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidDays))
+			return
+		}
+		if !checkDays(c, days) {
+			return
+		}
+	}
+	report, err := service.AdviseWeights(c.Request.Context(), uint(id), days)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.NotFound(c, common.T(c, i18n.MsgModelNotFound))
+			return
+		}
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, report)
+}
+
+// ApplyWeightAdviceRequest 管理员在预览中确认的权重，键为关联 ID
+type ApplyWeightAdviceRequest struct {
+	Weights map[uint]int `json:"weights"`
+}
+
+// ApplyWeightAdvice 按预览结果写入权重，不重新计算建议
+func ApplyWeightAdvice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+	var req ApplyWeightAdviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidRequestBody, err.Error()))
+		return
+	}
+	switch err := service.ApplyWeightAdvice(c.Request.Context(), uint(id), req.Weights); {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		common.NotFound(c, common.T(c, i18n.MsgModelNotFound))
+	case errors.Is(err, service.ErrInvalidWeightAdvice):
+		common.BadRequest(c, err.Error())
+	case err != nil:
+		common.InternalServerError(c, err.Error())
+	default:
+		common.Success(c, nil)
+	}
+}
+
+// GetWeightDistribution 对比各关联实际流量占比与配置权重占比
+func GetWeightDistribution(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.BadRequest(c, common.T(c, i18n.MsgInvalidID))
+		return
+	}
+	hours := 0
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		hours, err = strconv.Atoi(hoursStr)
+		if err != nil || hours <= 0 {
+			common.BadRequest(c, common.T(c, i18n.MsgInvalidHours))
+			return
+		}
+		if !checkDays(c, (hours+23)/24) {
+			return
+		}
+	}
+	report, err := service.WeightDistribution(c.Request.Context(), uint(id), hours)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.NotFound(c, common.T(c, i18n.MsgModelNotFound))
+			return
+		}
+		common.InternalServerError(c, err.Error())
+		return
+	}
+	common.Success(c, report)
+}
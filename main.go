@@ -6,101 +6,138 @@ import (
 	"io/fs"
 	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
 	_ "time/tzdata"
 
+	"github.com/atopos31/llmio/common"
 	"github.com/atopos31/llmio/consts"
 	"github.com/atopos31/llmio/handler"
 	"github.com/atopos31/llmio/middleware"
 	"github.com/atopos31/llmio/models"
 	"github.com/atopos31/llmio/pkg/env"
+	"github.com/atopos31/llmio/pkg/i18n"
 	"github.com/atopos31/llmio/service"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
 	_ "golang.org/x/crypto/x509roots/fallback"
 )
 
+const dbPath = "./db/llmio.db"
+
 func init() {
 	ctx := context.Background()
-	models.Init(ctx, "./db/llmio.db")
+	preflight(ctx)
+	models.Init(ctx, dbPath)
+	if err := service.LoadAPILanguage(ctx); err != nil {
+		slog.Error("load api language failed", "error", err)
+	}
+	if err := service.EnableRouteCache(models.DB, time.Duration(env.GetWithDefault("LLMIO_ROUTE_CACHE_TTL", 30))*time.Second); err != nil {
+		slog.Error("enable route cache failed", "error", err)
+	}
+	service.SetMaxStreams(env.GetWithDefault("LLMIO_MAX_STREAMS", 0))
+	service.SetLogRetention(env.GetWithDefault("LOG_RETENTION_DAYS", 0), env.GetWithDefault("LOG_IO_RETENTION_DAYS", 0), env.GetWithDefault("LOG_VACUUM", false))
+	service.SetLogSigningSecret(env.GetWithDefault("LLMIO_LOG_SIGNING_SECRET", ""))
+	if err := service.LoadRoutingSlot(ctx); err != nil {
+		slog.Error("load routing slot failed", "error", err)
+	}
+	if migrated, err := service.MigrateLegacyToken(ctx, env.GetWithDefault("TOKEN", "")); err != nil {
+		slog.Error("migrate legacy token failed", "error", err)
+	} else if migrated {
+		slog.Info("migrated TOKEN to auth key", "name", "legacy-token")
+	}
+	if _, err := service.Bootstrap(ctx, service.BootstrapConfigFromEnv()); err != nil {
+		slog.Error("bootstrap from env failed", "error", err)
+	}
+	if err := service.SeedBalancerLatency(ctx); err != nil {
+		slog.Error("seed balancer latency failed", "error", err)
+	}
+	if err := service.StartBreakerPersistence(context.Background()); err != nil {
+		slog.Error("restore breaker state failed", "error", err)
+	}
 	slog.Info("TZ", "time.Local", time.Local.String())
 }
 
 func main() {
 	service.StartLogCleanupScheduler(context.Background())
+	service.StartProviderAuthRecheck(context.Background())
+	service.StartProviderBalanceRefresh(context.Background())
+	service.StartConnectionRecycle(context.Background())
+	service.StartMetricRollup(context.Background())
 
-	router := gin.Default()
+	router := gin.New()
+	// 请求 ID 与结构化访问日志，替代 gin 默认的文本访问日志
+	router.Use(gin.Recovery(), middleware.RequestID())
+	// 额外的路由前缀，如 /llmio 时同时提供 /llmio/openai/v1/... 与 /llmio/v1/...
+	prefixes := routePrefixes(env.GetWithDefault("LLMIO_ROUTE_PREFIXES", ""))
 	// gzip压缩
-	router.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{"/openai", "/anthropic", "/gemini", "/v1"})))
+	router.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths(relayPaths(prefixes))))
 	// 跨域
 	router.Use(middleware.Cors())
 	// webui
-	setwebui(router)
+	setwebui(router, prefixes)
 
 	token := env.GetWithDefault("TOKEN", "")
+	// 部署在认证代理之后时，可用代理传入的用户名代替 Key
+	if err := middleware.SetTrustedHeader(env.GetWithDefault("LLMIO_TRUSTED_AUTH_HEADER", ""), env.GetWithDefault("LLMIO_TRUSTED_PROXIES", "")); err != nil {
+		panic(err)
+	}
 
 	authOpenAI := middleware.AuthOpenAI(token)
 	authAnthropic := middleware.AuthAnthropic(token)
 	authGemini := middleware.AuthGemini(token)
 
-	// openai
-	openai := router.Group("/openai")
-	{
-		v1 := openai.Group("/v1", authOpenAI)
-		{
-			v1.GET("/models", handler.OpenAIModelsHandler)
-			v1.POST("/chat/completions", handler.ChatCompletionsHandler)
-			v1.POST("/responses", handler.ResponsesHandler)
-		}
+	registerRelayRoutes(router, authOpenAI, authAnthropic, authGemini)
+	for _, prefix := range prefixes {
+		registerRelayRoutes(router.Group(prefix), authOpenAI, authAnthropic, authGemini)
 	}
 
-	// anthropic
-	anthropic := router.Group("/anthropic")
-	{
-		// claude code logging
-		anthropic.POST("/api/event_logging/batch", handler.EventLogging)
-
-		v1 := anthropic.Group("/v1", authAnthropic)
-		{
-			v1.GET("/models", handler.AnthropicModelsHandler)
-			v1.POST("/messages", handler.Messages)
-			v1.POST("/messages/count_tokens", handler.CountTokens)
-		}
+	// 公共状态页，默认关闭
+	if env.GetWithDefault("LLMIO_PUBLIC_STATUS", false) {
+		router.GET("/status", handler.PublicStatusPage)
 	}
 
-	// gemini
-	gemini := router.Group("/gemini")
+	api := router.Group("/api", middleware.AdminRateLimit(env.GetWithDefault("LLMIO_ADMIN_RPM", 600)), middleware.Auth(token))
+	// 日志与统计查询超时后取消，避免拖慢转发链路的数据库访问
+	slowQuery := middleware.QueryTimeout(time.Duration(env.GetWithDefault("LLMIO_ADMIN_QUERY_TIMEOUT", 15)) * time.Second)
 	{
-		v1beta := gemini.Group("/v1beta", authGemini)
-		{
-			v1beta.GET("/models", handler.GeminiModelsHandler)
-			v1beta.POST("/models/*modelAction", handler.GeminiGenerateContentHandler)
-		}
-	}
-
-	// 兼容性保留
-	v1 := router.Group("/v1")
-	{
-		v1.GET("/models", authOpenAI, handler.OpenAIModelsHandler)
-		v1.POST("/chat/completions", authOpenAI, handler.ChatCompletionsHandler)
-		v1.POST("/responses", authOpenAI, handler.ResponsesHandler)
-		v1.POST("/messages", authAnthropic, handler.Messages)
-		v1.POST("/messages/count_tokens", authAnthropic, handler.CountTokens)
-	}
-
-	api := router.Group("/api", middleware.Auth(token))
-	{
-		api.GET("/metrics/use/:days", handler.Metrics)
-		api.GET("/metrics/counts", handler.Counts)
-		api.GET("/metrics/projects", handler.ProjectCounts)
+		api.GET("/metrics/use/:days", slowQuery, handler.Metrics)
+		api.GET("/metrics/counts", slowQuery, handler.Counts)
+		api.GET("/metrics/projects", slowQuery, handler.ProjectCounts)
+		api.GET("/metrics/histograms/:days", slowQuery, handler.ModelHistograms)
+		api.GET("/metrics/response-sizes/:days", slowQuery, handler.ProviderResponseSizes)
+		api.GET("/metrics/finish-reasons/:days", slowQuery, handler.FinishReasons)
+		api.GET("/metrics/latency/:days", slowQuery, handler.LatencyPercentiles)
+		api.GET("/metrics/rollups", slowQuery, handler.MetricRollups)
+		api.GET("/status", handler.StatusPage)
 		// Provider management
 		api.GET("/providers/template", handler.GetProviderTemplates)
 		api.GET("/providers", handler.GetProviders)
 		api.GET("/providers/models/:id", handler.GetProviderModels)
+		api.POST("/providers/:id/import", handler.ImportProviderModels)
 		api.POST("/providers", handler.CreateProvider)
 		api.PUT("/providers/:id", handler.UpdateProvider)
 		api.DELETE("/providers/:id", handler.DeleteProvider)
+		api.GET("/providers/retirements", handler.GetProviderRetirements)
+		api.GET("/providers/auth-disabled", handler.GetAuthDisabledProviders)
+		api.POST("/providers/:id/auth/enable", handler.EnableProviderAuth)
+		api.GET("/providers/:id/balance", handler.GetProviderBalance)
+		api.GET("/providers/:id/retire", handler.GetProviderRetireStatus)
+		api.POST("/providers/:id/retire", handler.StartProviderRetire)
+		api.POST("/providers/:id/retire/cancel", handler.CancelProviderRetire)
+		api.POST("/providers/:id/retire/archive", handler.ArchiveProvider)
+		api.GET("/providers/:id/selftest", handler.GetProviderSelftests)
+		api.POST("/providers/:id/selftest", handler.RunProviderSelftest)
+
+		// Blue/green routing
+		api.GET("/routing/slot", handler.GetRoutingSlot)
+		api.POST("/routing/slot/stage", handler.StageRoutingSlot)
+		api.POST("/routing/slot/switch", handler.SwitchRoutingSlot)
+		api.POST("/routing/slot/rollback", handler.RollbackRoutingSlot)
 
 		// Model management
 		api.GET("/models", handler.GetModels)
@@ -109,6 +146,10 @@ func main() {
 		api.PATCH("/models/order", handler.UpdateModelOrder)
 		api.PUT("/models/:id", handler.UpdateModel)
 		api.DELETE("/models/:id", handler.DeleteModel)
+		api.GET("/models/:id/weight-advice", slowQuery, handler.GetWeightAdvice)
+		api.POST("/models/:id/weight-advice/apply", handler.ApplyWeightAdvice)
+		api.GET("/models/:id/distribution", slowQuery, handler.GetWeightDistribution)
+		api.GET("/models/:id/tail", handler.TailModel)
 
 		// Model-provider association management
 		api.GET("/model-providers", handler.GetModelProviders)
@@ -120,11 +161,19 @@ func main() {
 
 		// System status and monitoring
 		api.GET("/version", handler.GetVersion)
-		api.GET("/logs", handler.GetRequestLogs)
-		api.GET("/logs/:id/chat-io", handler.GetChatIO)
-		api.GET("/user-agents", handler.GetUserAgents)
+		api.GET("/system/status", handler.SystemStatus)
+		api.GET("/preflight", handler.Preflight)
+		api.GET("/support-bundle", handler.GetSupportBundle)
+		api.GET("/logs", slowQuery, handler.GetRequestLogs)
+		api.GET("/logs/:id", handler.GetRequestLog)
+		api.GET("/logs/:id/io", handler.GetLogIO)
+		api.GET("/user-agents", slowQuery, handler.GetUserAgents)
 		api.POST("/logs/cleanup", handler.CleanLogs)
 		api.GET("/logs/cleanup/history", handler.GetCleanupHistory)
+		api.GET("/logs/verify", handler.VerifyLogs)
+		api.GET("/rate-limits", handler.GetRateLimitReport)
+		api.GET("/breaker/status", handler.GetBreakerStatus)
+		api.POST("/breaker/reset/:id", handler.ResetBreaker)
 
 		// Auth key management
 		api.GET("/auth-keys", handler.GetAuthKeys)
@@ -144,7 +193,165 @@ func main() {
 		api.GET("/test/count_tokens", handler.TestCountTokens)
 	}
 
-	router.Run(":" + env.GetWithDefault("LLMIO_SERVER_PORT", consts.DefaultPort))
+	serve(router, ":"+env.GetWithDefault("LLMIO_SERVER_PORT", consts.DefaultPort))
+}
+
+// serve 监听直到收到 SIGINT/SIGTERM，随后停止接收新请求，等待进行中的请求（含流式响应）与日志写入完成后退出
+func serve(handler http.Handler, addr string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: addr, Handler: handler}
+	// 实时日志是不会自行结束的 SSE 长连接，Shutdown 开始时主动关闭
+	server.RegisterOnShutdown(service.CloseTailSubscribers)
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.ListenAndServe() }()
+	select {
+	case err := <-errCh:
+		slog.Error("server stopped", "error", err)
+		os.Exit(1)
+	case <-ctx.Done():
+	}
+	stop()
+
+	timeout := time.Duration(env.GetWithDefault("LLMIO_SHUTDOWN_TIMEOUT", int(consts.GracefulShutdownTimeout/time.Second))) * time.Second
+	slog.Info("shutting down, draining in-flight requests", "timeout", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("drain in-flight requests", "error", err)
+	}
+	if pending := service.WaitLogWrites(shutdownCtx); pending > 0 {
+		slog.Warn("exit with pending log writes", "pending", pending)
+	}
+	service.FlushKeyUsage(context.Background())
+	slog.Info("server exited")
+}
+
+// preflight 启动前检查前端产物、数据库写权限与时钟，失败时退出，LLMIO_PREFLIGHT=warn 时仅记录日志
+func preflight(ctx context.Context) {
+	webFS, err := fs.Sub(distFiles, "webui/dist")
+	if err != nil {
+		panic(err)
+	}
+	report := service.InitPreflight(ctx, service.PreflightOptions{
+		WebUI:   webFS,
+		DBPath:  dbPath,
+		TimeURL: env.GetWithDefault("LLMIO_PREFLIGHT_TIME_URL", ""),
+	})
+	for _, check := range report.Checks {
+		if check.Status == service.PreflightFail {
+			slog.Error("preflight check failed", "check", check.Name, "error", check.Message)
+		}
+	}
+	if !report.Passed && env.GetWithDefault("LLMIO_PREFLIGHT", "strict") != "warn" {
+		os.Exit(1)
+	}
+}
+
+// relayGroups 转发接口的路由分组，用于路由前缀与 NoRoute 判断
+var relayGroups = []string{"/openai", "/anthropic", "/gemini", "/v1", "/v1beta"}
+
+// registerRelayRoutes 注册各协议的转发接口，路由前缀别名复用同一组处理函数
+func registerRelayRoutes(r gin.IRouter, authOpenAI, authAnthropic, authGemini gin.HandlerFunc) {
+	// openai
+	openai := r.Group("/openai")
+	{
+		v1 := openai.Group("/v1", authOpenAI)
+		{
+			v1.GET("/models", handler.OpenAIModelsHandler)
+			v1.GET("/models/*id", handler.OpenAIModelHandler)
+			v1.POST("/chat/completions", handler.ChatCompletionsHandler)
+			v1.POST("/responses", handler.ResponsesHandler)
+			v1.POST("/embeddings", handler.EmbeddingsHandler)
+			v1.POST("/rerank", handler.RerankHandler)
+			v1.POST("/moderations", handler.ModerationsHandler)
+			v1.POST("/images/generations", handler.ImageGenerationsHandler)
+			v1.POST("/images/edits", handler.ImageEditsHandler)
+			v1.POST("/images/variations", handler.ImageVariationsHandler)
+		}
+	}
+
+	// anthropic
+	anthropic := r.Group("/anthropic")
+	{
+		// claude code logging
+		anthropic.POST("/api/event_logging/batch", handler.EventLogging)
+
+		v1 := anthropic.Group("/v1", authAnthropic)
+		{
+			v1.GET("/models", handler.AnthropicModelsHandler)
+			v1.POST("/messages", handler.Messages)
+			v1.POST("/messages/count_tokens", handler.CountTokens)
+			v1.POST("/messages/batches", handler.CreateMessageBatch)
+			v1.GET("/messages/batches", handler.ListMessageBatches)
+			v1.GET("/messages/batches/:id", handler.GetMessageBatch)
+			v1.GET("/messages/batches/:id/results", handler.MessageBatchResults)
+			v1.POST("/messages/batches/:id/cancel", handler.CancelMessageBatch)
+			v1.DELETE("/messages/batches/:id", handler.DeleteMessageBatch)
+		}
+	}
+
+	// gemini
+	gemini := r.Group("/gemini")
+	{
+		v1beta := gemini.Group("/v1beta", authGemini)
+		{
+			v1beta.GET("/models", handler.GeminiModelsHandler)
+			v1beta.POST("/models/*modelAction", handler.GeminiGenerateContentHandler)
+		}
+	}
+
+	// 兼容性保留
+	v1 := r.Group("/v1")
+	{
+		v1.GET("/models", authOpenAI, handler.OpenAIModelsHandler)
+		v1.GET("/models/*id", authOpenAI, handler.OpenAIModelHandler)
+		v1.POST("/chat/completions", authOpenAI, handler.ChatCompletionsHandler)
+		v1.POST("/responses", authOpenAI, handler.ResponsesHandler)
+		v1.POST("/embeddings", authOpenAI, handler.EmbeddingsHandler)
+		v1.POST("/rerank", authOpenAI, handler.RerankHandler)
+		v1.POST("/moderations", authOpenAI, handler.ModerationsHandler)
+		v1.POST("/images/generations", authOpenAI, handler.ImageGenerationsHandler)
+		v1.POST("/images/edits", authOpenAI, handler.ImageEditsHandler)
+		v1.POST("/images/variations", authOpenAI, handler.ImageVariationsHandler)
+		v1.POST("/messages", authAnthropic, handler.Messages)
+		v1.POST("/messages/count_tokens", authAnthropic, handler.CountTokens)
+		v1.POST("/messages/batches", authAnthropic, handler.CreateMessageBatch)
+		v1.GET("/messages/batches", authAnthropic, handler.ListMessageBatches)
+		v1.GET("/messages/batches/:id", authAnthropic, handler.GetMessageBatch)
+		v1.GET("/messages/batches/:id/results", authAnthropic, handler.MessageBatchResults)
+		v1.POST("/messages/batches/:id/cancel", authAnthropic, handler.CancelMessageBatch)
+		v1.DELETE("/messages/batches/:id", authAnthropic, handler.DeleteMessageBatch)
+	}
+	v1beta := r.Group("/v1beta", authGemini)
+	{
+		v1beta.GET("/models", handler.GeminiModelsHandler)
+		v1beta.POST("/models/*modelAction", handler.GeminiGenerateContentHandler)
+	}
+}
+
+// routePrefixes 解析逗号分隔的路由前缀，统一为 /xxx 形式
+func routePrefixes(value string) []string {
+	var prefixes []string
+	for prefix := range strings.SplitSeq(value, ",") {
+		prefix = "/" + strings.Trim(strings.TrimSpace(prefix), "/")
+		if prefix != "/" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// relayPaths 返回所有转发接口的路径前缀（含别名）
+func relayPaths(prefixes []string) []string {
+	paths := slices.Clone(relayGroups)
+	for _, prefix := range prefixes {
+		for _, group := range relayGroups {
+			paths = append(paths, prefix+group)
+		}
+	}
+	return paths
 }
 
 //go:embed webui/dist
@@ -153,7 +360,7 @@ var distFiles embed.FS
 //go:embed webui/dist/index.html
 var indexHTML []byte
 
-func setwebui(r *gin.Engine) {
+func setwebui(r *gin.Engine, prefixes []string) {
 	subFS, err := fs.Sub(distFiles, "webui/dist/assets")
 	if err != nil {
 		panic(err)
@@ -161,11 +368,17 @@ func setwebui(r *gin.Engine) {
 
 	r.StaticFS("/assets", http.FS(subFS))
 
+	apiPaths := append(relayPaths(prefixes), "/api")
 	r.NoRoute(func(c *gin.Context) {
-		if c.Request.Method == http.MethodGet && !strings.HasPrefix(c.Request.URL.Path, "/api/") && !strings.HasPrefix(c.Request.URL.Path, "/v1/") {
+		path := c.Request.URL.Path
+		isAPI := slices.ContainsFunc(apiPaths, func(prefix string) bool {
+			return path == prefix || strings.HasPrefix(path, prefix+"/")
+		})
+		if c.Request.Method == http.MethodGet && !isAPI {
 			c.Data(http.StatusOK, "text/html; charset=utf-8", indexHTML)
 			return
 		}
-		c.Data(http.StatusNotFound, "text/html; charset=utf-8", []byte("404 Not Found"))
+		// 接口路径返回 JSON，避免客户端把 SPA 页面当作响应解析
+		common.ErrorWithHttpStatus(c, http.StatusNotFound, http.StatusNotFound, common.T(c, i18n.MsgRouteNotFound, c.Request.Method, path))
 	})
 }
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// adminWindow 单个客户端当前分钟的请求计数
+type adminWindow struct {
+	start time.Time
+	count int
+}
+
+// AdminRateLimit 按连接来源 IP 限制管理接口每分钟请求数，避免看板刷新死循环等拖慢转发，rpm 为 0 时不限制
+// 不使用 ClientIP，否则客户端可伪造 X-Forwarded-For 绕过限制
+func AdminRateLimit(rpm int) gin.HandlerFunc {
+	var mu sync.Mutex
+	windows := make(map[string]*adminWindow)
+	return func(c *gin.Context) {
+		if rpm <= 0 {
+			return
+		}
+		now := time.Now()
+		ip := c.RemoteIP()
+		mu.Lock()
+		w, ok := windows[ip]
+		if !ok || now.Sub(w.start) >= time.Minute {
+			// 新窗口开始时顺带清理过期客户端，避免 IP 过多时持续占用内存
+			if !ok {
+				for key, old := range windows {
+					if now.Sub(old.start) >= time.Minute {
+						delete(windows, key)
+					}
+				}
+			}
+			w = &adminWindow{start: now}
+			windows[ip] = w
+		}
+		w.count++
+		count, reset := w.count, w.start.Add(time.Minute)
+		mu.Unlock()
+		if count > rpm {
+			c.Header("Retry-After", strconv.Itoa(max(int(time.Until(reset).Seconds()), 1)))
+			common.ErrorWithHttpStatus(c, http.StatusTooManyRequests, http.StatusTooManyRequests, common.T(c, i18n.MsgAdminRateLimited, rpm))
+			c.Abort()
+		}
+	}
+}
+
+// QueryTimeout 为日志与统计等耗时查询设置超时，超时后数据库查询随 context 取消，timeout 为 0 时不限制
+func QueryTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
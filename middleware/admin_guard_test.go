@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/test", AdminRateLimit(2), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		remoteAddr   string
+		forwardedFor string
+		wantStatus   int
+	}{
+		{"10.0.0.1:1000", "", http.StatusOK},
+		{"10.0.0.1:1001", "", http.StatusOK},
+		{"10.0.0.1:1002", "", http.StatusTooManyRequests},
+		// 伪造 X-Forwarded-For 不能绕过限制
+		{"10.0.0.1:1003", "1.2.3.4", http.StatusTooManyRequests},
+		// 不同客户端分别计数
+		{"10.0.0.2:1000", "", http.StatusOK},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus {
+			t.Fatalf("request %d from %s: status = %d, want %d", i, tt.remoteAddr, w.Code, tt.wantStatus)
+		}
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Fatalf("request %d: missing Retry-After", i)
+		}
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	var deadline time.Time
+	var ok bool
+	r.GET("/api/logs", QueryTimeout(time.Second), func(c *gin.Context) {
+		deadline, ok = c.Request.Context().Deadline()
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/logs", nil))
+	if !ok || time.Until(deadline) > time.Second {
+		t.Fatalf("deadline = %v, ok = %v, want within 1s", deadline, ok)
+	}
+}
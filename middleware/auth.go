@@ -8,6 +8,8 @@ import (
 
 	"github.com/atopos31/llmio/common"
 	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/i18n"
 	"github.com/atopos31/llmio/service"
 	"github.com/gin-gonic/gin"
 	"github.com/samber/lo"
@@ -22,21 +24,21 @@ func Auth(token string) gin.HandlerFunc {
 		}
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, "Authorization header is missing")
+			common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, common.T(c, i18n.MsgAuthHeaderMissing))
 			c.Abort()
 			return
 		}
 
 		parts := strings.SplitN(authHeader, " ", 2)
 		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, "Invalid authorization header")
+			common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, common.T(c, i18n.MsgInvalidAuthHeader))
 			c.Abort()
 			return
 		}
 
 		tokenString := parts[1]
 		if tokenString != token {
-			common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, "Invalid token")
+			common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, common.T(c, i18n.MsgInvalidToken))
 			c.Abort()
 			return
 		}
@@ -77,24 +79,43 @@ func checkAuthKey(c *gin.Context, key string, adminToken string) {
 	// 如果系统中未配置Token 或者使用的是最高权限的token 则允许访问所有模型
 	if adminToken == "" || key == adminToken {
 		ctx = context.WithValue(ctx, consts.ContextKeyAllowAllModel, true)
+		ctx = context.WithValue(ctx, consts.ContextKeyAdmin, true)
+		// TOKEN 已迁移为 AuthKey 时只按该 Key 统计用量，其模型、过期与限流设置不作用于管理员 TOKEN
+		if id := service.LegacyTokenKeyID(); key != "" && id != 0 {
+			ctx = context.WithValue(ctx, consts.ContextKeyAuthKeyID, id)
+			go service.KeyUpdate(id, time.Now())
+		}
 		c.Request = c.Request.WithContext(ctx)
 		return
 	}
+	// 未携带 Key 时尝试使用受信任代理传入的用户身份
+	if key == "" {
+		if user, ok := trustedUser(c); ok {
+			checkTrustedUser(c, user)
+			return
+		}
+	}
 	// 如果key为空 则拒绝访问
 	if key == "" {
-		common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, "Authorization key is missing")
+		common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, common.T(c, i18n.MsgAuthKeyMissing))
 		c.Abort()
 		return
 	}
 	authKey, err := service.GetAuthKey(ctx, key)
 	if err != nil {
-		common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, "Invalid token")
+		common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, common.T(c, i18n.MsgInvalidToken))
 		c.Abort()
 		return
 	}
+	applyAuthKey(c, authKey)
+}
+
+// applyAuthKey 检查 Key 是否过期并将其权限写入请求上下文
+func applyAuthKey(c *gin.Context, authKey *models.AuthKey) {
+	ctx := c.Request.Context()
 	// 检查是否过期
 	if authKey.ExpiresAt != nil && authKey.ExpiresAt.Before(time.Now()) {
-		common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, "Token has expired")
+		common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, common.T(c, i18n.MsgTokenExpired))
 		c.Abort()
 		return
 	}
@@ -102,7 +123,9 @@ func checkAuthKey(c *gin.Context, key string, adminToken string) {
 	go service.KeyUpdate(authKey.ID, time.Now())
 
 	ctx = context.WithValue(ctx, consts.ContextKeyAuthKeyID, authKey.ID)
-	ctx = context.WithValue(ctx, consts.ContextKeyAuthKeyIOLog, lo.FromPtrOr(authKey.IOLog, false))
+	// 禁止记录 IO 的 Key 不受 io_log 与模型采样影响
+	ctx = context.WithValue(ctx, consts.ContextKeyAuthKeyIOLog, lo.FromPtrOr(authKey.IOLog, false) && !lo.FromPtrOr(authKey.NoIOLog, false))
+	ctx = context.WithValue(ctx, consts.ContextKeyAuthKeyRateLimit, authKey.RateLimit)
 
 	allowAll := lo.FromPtrOr(authKey.AllowAll, false)
 	ctx = context.WithValue(ctx, consts.ContextKeyAllowAllModel, allowAll)
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/atopos31/llmio/consts"
 	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/service"
 	"github.com/gin-gonic/gin"
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
@@ -97,10 +99,54 @@ func TestCheckAuthKey_AdminTokenMatch(t *testing.T) {
 	if allowAll == nil || allowAll != true {
 		t.Error("expected AllowAllModel to be true")
 	}
+	if admin := ctx.Value(consts.ContextKeyAdmin); admin != true {
+		t.Error("expected Admin to be true")
+	}
 
 	t.Log("✓ Matching admin token allows access to all models")
 }
 
+func TestCheckAuthKey_MigratedAdminToken(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	if err := db.AutoMigrate(&models.Config{}); err != nil {
+		t.Fatalf("failed to migrate config: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := service.MigrateLegacyToken(ctx, "admin-token"); err != nil {
+		t.Fatalf("MigrateLegacyToken failed: %v", err)
+	}
+	// 移除 TOKEN 时删除迁移出的 Key，避免影响其他测试
+	defer service.MigrateLegacyToken(ctx, "")
+
+	// 迁移出的 Key 被限制模型并已过期，也不影响管理员 TOKEN
+	keyID := service.LegacyTokenKeyID()
+	if err := db.Model(&models.AuthKey{}).Where("id = ?", keyID).Updates(map[string]any{
+		"allow_all":  false,
+		"expires_at": time.Now().Add(-time.Hour),
+	}).Error; err != nil {
+		t.Fatalf("failed to restrict legacy key: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/", nil)
+	checkAuthKey(c, "admin-token", "admin-token")
+	if c.IsAborted() {
+		t.Fatal("expected request to not be aborted")
+	}
+	reqCtx := c.Request.Context()
+	if id := reqCtx.Value(consts.ContextKeyAuthKeyID); id != keyID {
+		t.Errorf("expected authKeyID %d, got %v", keyID, id)
+	}
+	if admin := reqCtx.Value(consts.ContextKeyAdmin); admin != true {
+		t.Error("expected Admin to stay true for the migrated token")
+	}
+	if allowAll := reqCtx.Value(consts.ContextKeyAllowAllModel); allowAll != true {
+		t.Error("expected the admin token to keep access to all models")
+	}
+}
+
 func TestCheckAuthKey_EmptyKey(t *testing.T) {
 	// Setup test database
 	_, cleanup := setupTestDB(t)
@@ -185,6 +231,11 @@ func TestCheckAuthKey_ValidAuthKey_AllowAll(t *testing.T) {
 		t.Error("expected AuthKeyIOLog to be true")
 	}
 
+	// AuthKey 即使允许全部模型也不是管理员
+	if admin := ctx.Value(consts.ContextKeyAdmin); admin != nil {
+		t.Error("expected Admin to be unset for auth keys")
+	}
+
 	// Should not have AllowModels when AllowAll is true
 	allowModels := ctx.Value(consts.ContextKeyAllowModels)
 	if allowModels != nil {
@@ -194,6 +245,35 @@ func TestCheckAuthKey_ValidAuthKey_AllowAll(t *testing.T) {
 	t.Log("✓ Valid auth key with AllowAll=true works correctly")
 }
 
+func TestCheckAuthKey_NoIOLogOverridesIOLog(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/", nil)
+
+	authKey := models.AuthKey{
+		Name:     "Private Project",
+		Key:      "test-key-private",
+		Status:   new(true),
+		IOLog:    new(true),
+		NoIOLog:  new(true),
+		AllowAll: new(true),
+	}
+	if err := db.Create(&authKey).Error; err != nil {
+		t.Fatalf("failed to create test auth key: %v", err)
+	}
+
+	checkAuthKey(c, "test-key-private", "admin-token")
+	if c.IsAborted() {
+		t.Fatal("expected request to not be aborted")
+	}
+	if ioLog := c.Request.Context().Value(consts.ContextKeyAuthKeyIOLog); ioLog != false {
+		t.Errorf("expected AuthKeyIOLog to be false when no_io_log is set, got %v", ioLog)
+	}
+}
+
 func TestCheckAuthKey_ValidAuthKey_RequireModel(t *testing.T) {
 	// Setup test database
 	db, cleanup := setupTestDB(t)
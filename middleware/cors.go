@@ -3,6 +3,7 @@ package middleware
 import (
 	"time"
 
+	"github.com/atopos31/llmio/consts"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -11,7 +12,8 @@ func Cors() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowAllOrigins:  true,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", consts.RequestIDHeader},
+		ExposeHeaders:    []string{consts.RequestIDHeader},
 		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
 	})
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/pkg/token"
+	"github.com/gin-gonic/gin"
+)
+
+// maxRequestIDLength 客户端传入请求 ID 的最大长度，超出时重新生成
+const maxRequestIDLength = 128
+
+// RequestID 为每次调用分配请求 ID，写入响应头与请求 context，并在请求结束后输出结构化访问日志
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(consts.RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(consts.RequestIDHeader, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), consts.ContextKeyRequestID, id))
+
+		c.Next()
+
+		attrs := []any{
+			"request_id", id,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"latency", time.Since(start),
+			"client_ip", c.ClientIP(),
+			"size", max(c.Writer.Size(), 0),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "error", c.Errors.String())
+		}
+		slog.Info("access", attrs...)
+	}
+}
+
+// validRequestID 只沿用可打印 ASCII 且不含空白的请求 ID，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id, err := token.GenerateRandomChars(16)
+	if err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return id
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated", "", false},
+		{"client supplied", "req-123_abc.def", true},
+		{"contains space", "req 123", false},
+		{"contains newline", "req\n123", false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctxID string
+			router := gin.New()
+			router.Use(RequestID())
+			router.GET("/", func(c *gin.Context) {
+				ctxID, _ = c.Request.Context().Value(consts.ContextKeyRequestID).(string)
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(consts.RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			got := w.Header().Get(consts.RequestIDHeader)
+			if got == "" {
+				t.Fatal("response missing request id")
+			}
+			if got != ctxID {
+				t.Errorf("context id = %q, header id = %q", ctxID, got)
+			}
+			if (got == tt.incoming) != tt.keep {
+				t.Errorf("request id = %q, incoming %q, keep %v", got, tt.incoming, tt.keep)
+			}
+		})
+	}
+}
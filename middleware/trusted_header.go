@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/atopos31/llmio/common"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/atopos31/llmio/service"
+	"github.com/gin-gonic/gin"
+)
+
+// trustedHeader 前置认证代理(Authelia、oauth2-proxy 等)传入用户名的请求头，为空时关闭
+var (
+	trustedHeader  string
+	trustedProxies []netip.Prefix
+)
+
+// SetTrustedHeader 设置受信任的用户名请求头，proxies 为逗号分隔的代理 IP 或 CIDR
+// 配置了请求头却未配置代理时返回错误，避免任何能访问端口的客户端伪造用户名；出错时关闭该功能
+func SetTrustedHeader(header, proxies string) error {
+	trustedHeader, trustedProxies = "", nil
+	header = strings.TrimSpace(header)
+	var prefixes []netip.Prefix
+	for item := range strings.SplitSeq(proxies, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return fmt.Errorf("invalid trusted proxy %q: %w", item, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", item, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	if header != "" && len(prefixes) == 0 {
+		return fmt.Errorf("trusted header %q requires trusted proxies", header)
+	}
+	trustedHeader = header
+	trustedProxies = prefixes
+	return nil
+}
+
+// trustedUser 返回受信任代理传入的用户名，请求不是来自受信任代理时忽略该请求头
+func trustedUser(c *gin.Context) (string, bool) {
+	if trustedHeader == "" || len(trustedProxies) == 0 {
+		return "", false
+	}
+	user := strings.TrimSpace(c.GetHeader(trustedHeader))
+	if user == "" {
+		return "", false
+	}
+	host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		host = c.Request.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return "", false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return user, true
+		}
+	}
+	return "", false
+}
+
+// checkTrustedUser 使用绑定到该用户名的 Key 的权限处理请求
+func checkTrustedUser(c *gin.Context, user string) {
+	authKey, err := service.GetAuthKeyByTrustedUser(c.Request.Context(), user)
+	if err != nil {
+		common.ErrorWithHttpStatus(c, http.StatusUnauthorized, http.StatusUnauthorized, common.T(c, i18n.MsgTrustedUserUnknown, user))
+		c.Abort()
+		return
+	}
+	applyAuthKey(c, authKey)
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckAuthKey_TrustedHeader(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	t.Cleanup(func() { SetTrustedHeader("", "") })
+
+	authKey := models.AuthKey{
+		Name:        "SSO",
+		Key:         "sso-key",
+		Status:      new(true),
+		AllowAll:    new(true),
+		TrustedUser: "alice",
+	}
+	if err := db.Create(&authKey).Error; err != nil {
+		t.Fatalf("failed to create test auth key: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		proxies    string
+		user       string
+		remoteAddr string
+		wantStatus int
+	}{
+		{"disabled", "", "", "alice", "10.0.0.2:1234", http.StatusUnauthorized},
+		{"trusted proxy", "X-Auth-Request-User", "10.0.0.0/24, 192.168.1.1", "alice", "10.0.0.2:1234", http.StatusOK},
+		{"untrusted source", "X-Auth-Request-User", "192.168.1.1", "alice", "10.0.0.2:1234", http.StatusUnauthorized},
+		{"unknown user", "X-Auth-Request-User", "10.0.0.0/24", "bob", "10.0.0.2:1234", http.StatusUnauthorized},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetTrustedHeader(tt.header, tt.proxies); err != nil {
+				t.Fatalf("SetTrustedHeader failed: %v", err)
+			}
+			r := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(r)
+			c.Request = httptest.NewRequest("POST", "/", nil)
+			c.Request.RemoteAddr = tt.remoteAddr
+			c.Request.Header.Set("X-Auth-Request-User", tt.user)
+
+			checkAuthKey(c, "", "admin-token")
+
+			if tt.wantStatus != http.StatusOK {
+				if !c.IsAborted() || c.Writer.Status() != tt.wantStatus {
+					t.Fatalf("aborted = %v, status = %d", c.IsAborted(), c.Writer.Status())
+				}
+				return
+			}
+			if c.IsAborted() {
+				t.Fatal("expected request to not be aborted")
+			}
+			if id := c.Request.Context().Value(consts.ContextKeyAuthKeyID); id != authKey.ID {
+				t.Fatalf("authKeyID = %v, want %d", id, authKey.ID)
+			}
+		})
+	}
+
+	if err := SetTrustedHeader("X-Auth-Request-User", "not-an-ip"); err == nil {
+		t.Fatal("expected invalid proxy error")
+	}
+	// 未配置代理时拒绝启用，任意来源的请求头都不被信任
+	if err := SetTrustedHeader("X-Auth-Request-User", ""); err == nil {
+		t.Fatal("expected error when trusted proxies are empty")
+	}
+	r := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(r)
+	c.Request = httptest.NewRequest("POST", "/", nil)
+	c.Request.RemoteAddr = "10.0.0.2:1234"
+	c.Request.Header.Set("X-Auth-Request-User", "alice")
+	checkAuthKey(c, "", "admin-token")
+	if !c.IsAborted() || c.Writer.Status() != http.StatusUnauthorized {
+		t.Fatalf("any source: aborted = %v, status = %d", c.IsAborted(), c.Writer.Status())
+	}
+}
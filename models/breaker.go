@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// BreakerNode 关联的熔断器状态，进程重启后恢复，关闭后删除
+type BreakerNode struct {
+	ModelProviderID uint `gorm:"primaryKey;autoIncrement:false"`
+	State           string
+	FailCount       int
+	Expiry          time.Time // Open 状态的冷却结束时间
+	UpdatedAt       time.Time
+}
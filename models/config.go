@@ -1,6 +1,10 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Config struct {
 	gorm.Model
@@ -11,6 +15,11 @@ type Config struct {
 const (
 	KeyAnthropicCountTokens = "anthropic_count_tokens"
 	KeyLogCleanupPolicy     = "log_cleanup_policy"
+	KeyAPILanguage          = "api_language" // 管理接口错误消息语言，空或 auto 表示按 Accept-Language 协商
+	KeyRoutingSlot          = "routing_slot"
+	KeyModelFallback        = "model_fallback"        // 未知模型的转发规则
+	KeyLegacyTokenMigrated  = "legacy_token_migrated" // TOKEN 迁移出的 AuthKey ID，存在时不再迁移
+	KeyMigrationPrefix      = "migration:"            // 一次性数据迁移的完成标记前缀，值为完成时间
 )
 
 type AnthropicCountTokens struct {
@@ -20,6 +29,26 @@ type AnthropicCountTokens struct {
 }
 
 type LogCleanupPolicy struct {
-	Enabled       bool `json:"enabled"`
-	RetentionDays int  `json:"retention_days"`
+	Enabled         bool `json:"enabled"`
+	RetentionDays   int  `json:"retention_days"`
+	IORetentionDays int  `json:"io_retention_days,omitempty"` // 请求与响应内容单独的保留天数，0 表示随日志一起删除
+	Vacuum          bool `json:"vacuum,omitempty"`            // 清理后执行 VACUUM 回收磁盘空间
+}
+
+// RoutingSlot 蓝绿路由配置，Active 为当前生效的关联分组
+type RoutingSlot struct {
+	Active     string     `json:"active"`
+	Previous   string     `json:"previous"`
+	SwitchedAt *time.Time `json:"switched_at"`
+}
+
+// ModelFallback 未知模型名的转发规则，按顺序匹配，模型名原样发给命中的提供商
+type ModelFallback struct {
+	Rules             []FallbackRule `json:"rules"`
+	DefaultProviderID uint           `json:"default_provider_id"` // 均未命中时使用的提供商，0 表示返回模型不存在
+}
+
+type FallbackRule struct {
+	Pattern    string `json:"pattern"` // 模型名匹配模式，* 匹配任意字符，如 deepseek-*
+	ProviderID uint   `json:"provider_id"`
 }
@@ -3,8 +3,10 @@ package models
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/atopos31/llmio/consts"
 	"github.com/atopos31/llmio/pkg/env"
@@ -32,6 +34,10 @@ func Init(ctx context.Context, path string) {
 		&Config{},
 		&AuthKey{},
 		&LogCleanupRecord{},
+		&MessageBatch{},
+		&ProviderSelftest{},
+		&BreakerNode{},
+		&MetricRollup{},
 	); err != nil {
 		panic(err)
 	}
@@ -53,9 +59,18 @@ func Init(ctx context.Context, path string) {
 	if _, err := gorm.G[Model](DB).Where("breaker IS NULL").Update(ctx, "breaker", false); err != nil {
 		panic(err)
 	}
+	if _, err := gorm.G[Model](DB).Where("validate_response IS NULL").Update(ctx, "validate_response", false); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Model](DB).Where("raw_passthrough IS NULL").Update(ctx, "raw_passthrough", false); err != nil {
+		panic(err)
+	}
 	if _, err := gorm.G[AuthKey](DB).Where("io_log IS NULL").Update(ctx, "io_log", false); err != nil {
 		panic(err)
 	}
+	if _, err := gorm.G[AuthKey](DB).Where("no_io_log IS NULL").Update(ctx, "no_io_log", false); err != nil {
+		panic(err)
+	}
 	if _, err := gorm.G[ChatLog](DB).Where("auth_key_id IS NULL").Update(ctx, "auth_key_id", 0); err != nil {
 		panic(err)
 	}
@@ -76,6 +91,146 @@ func Init(ctx context.Context, path string) {
 		panic(err)
 	}
 
+	if _, err := gorm.G[ModelWithProvider](DB).Where("embedding IS NULL").Update(ctx, "embedding", false); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("image_generation IS NULL").Update(ctx, "image_generation", false); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("chat IS NULL").Update(ctx, "chat", true); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("rerank IS NULL").Update(ctx, "rerank", false); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("moderation IS NULL").Update(ctx, "moderation", false); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("pseudo_stream IS NULL").Update(ctx, "pseudo_stream", false); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("stream_aggregate IS NULL").Update(ctx, "stream_aggregate", false); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("retire_state IS NULL").Update(ctx, "retire_state", ""); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("slot = '' OR slot IS NULL").Update(ctx, "slot", consts.SlotBlue); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("remark IS NULL").Update(ctx, "remark", ""); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("priority IS NULL OR priority < 1").Update(ctx, "priority", 1); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("max_in_flight IS NULL").Update(ctx, "max_in_flight", 0); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("queue_timeout IS NULL").Update(ctx, "queue_timeout", 0); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("quota_rpm IS NULL").Update(ctx, "quota_rpm", 0); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("quota_tpm IS NULL").Update(ctx, "quota_tpm", 0); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[ModelWithProvider](DB).Where("schedule IS NULL").Update(ctx, "schedule", ""); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[AuthKey](DB).Where("remark IS NULL").Update(ctx, "remark", ""); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("auth_disabled_reason IS NULL").Update(ctx, "auth_disabled_reason", ""); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("rate_limit_rpm IS NULL").Update(ctx, "rate_limit_rpm", 0); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("rate_limit_mode IS NULL").Update(ctx, "rate_limit_mode", ""); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("budget_period IS NULL").Update(ctx, "budget_period", ""); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("budget_tokens IS NULL").Update(ctx, "budget_tokens", 0); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("budget_cost IS NULL").Update(ctx, "budget_cost", 0); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[AuthKey](DB).Where("rate_limit_rpm IS NULL").Update(ctx, "rate_limit_rpm", 0); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[AuthKey](DB).Where("rate_limit_mode IS NULL").Update(ctx, "rate_limit_mode", ""); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[AuthKey](DB).Where("trusted_user IS NULL").Update(ctx, "trusted_user", ""); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("tls_ca_cert IS NULL").Update(ctx, "tls_ca_cert", ""); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("tls_insecure_skip_verify IS NULL").Update(ctx, "tls_insecure_skip_verify", false); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("headers IS NULL").Updates(ctx, Provider{
+		Headers: map[string]string{},
+	}); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("query_params IS NULL").Updates(ctx, Provider{
+		QueryParams: map[string]string{},
+	}); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("host_overrides IS NULL").Updates(ctx, Provider{
+		HostOverrides: map[string]string{},
+	}); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("dns_server IS NULL").Update(ctx, "dns_server", ""); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("balance_currency IS NULL").Update(ctx, "balance_currency", ""); err != nil {
+		panic(err)
+	}
+	// chat_logs 数据量大，新增列的回填只在升级后首次启动时执行
+	if err := migrateOnce(ctx, "chat_logs_signature", func() error {
+		_, err := gorm.G[ChatLog](DB).Where("signature IS NULL").Update(ctx, "signature", "")
+		return err
+	}); err != nil {
+		panic(err)
+	}
+	if err := migrateOnce(ctx, "chat_logs_strategy", func() error {
+		_, err := gorm.G[ChatLog](DB).Where("strategy IS NULL").Update(ctx, "strategy", "")
+		return err
+	}); err != nil {
+		panic(err)
+	}
+	if err := migrateOnce(ctx, "chat_logs_model_provider_id", func() error {
+		_, err := gorm.G[ChatLog](DB).Where("model_provider_id IS NULL").Update(ctx, "model_provider_id", 0)
+		return err
+	}); err != nil {
+		panic(err)
+	}
+	if err := migrateOnce(ctx, "chat_logs_request_id", func() error {
+		_, err := gorm.G[ChatLog](DB).Where("request_id IS NULL").Update(ctx, "request_id", "")
+		return err
+	}); err != nil {
+		panic(err)
+	}
+	if _, err := gorm.G[Provider](DB).Where("config_version IS NULL OR config_version < 1").Update(ctx, "config_version", 1); err != nil {
+		panic(err)
+	}
+
+	if dsn := env.GetWithDefault("DB_READ_DSN", ""); dsn != "" {
+		if replica, err = openReplica(dsn); err != nil {
+			panic(err)
+		}
+	}
+
 	if env.GetWithDefault("DB_VACUUM", false) {
 		// 启动时执行 VACUUM 回收空间
 		if err := db.Exec("VACUUM").Error; err != nil {
@@ -84,6 +239,22 @@ func Init(ctx context.Context, path string) {
 	}
 }
 
+// migrateOnce 执行一次性迁移并写入标记，标记存在时跳过
+func migrateOnce(ctx context.Context, name string, migrate func() error) error {
+	key := KeyMigrationPrefix + name
+	count, err := gorm.G[Config](DB).Where("key = ?", key).Count(ctx, "*")
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if err := migrate(); err != nil {
+		return fmt.Errorf("migrate %s: %w", name, err)
+	}
+	return gorm.G[Config](DB).Create(ctx, &Config{Key: key, Value: time.Now().Format(time.RFC3339)})
+}
+
 func ensureModelDisplayOrder(ctx context.Context) error {
 	needAssign, err := gorm.G[Model](DB).
 		Where("display_order = 0 OR display_order IS NULL").
@@ -56,3 +56,55 @@ func TestInit_BackfillsAuthKeyIOLogToFalse(t *testing.T) {
 		t.Fatal("expected io_log default to false")
 	}
 }
+
+func TestInit_ReadReplica(t *testing.T) {
+	dir := t.TempDir()
+	replicaPath := filepath.Join(dir, "replica.db")
+	Init(context.Background(), replicaPath)
+	if ReadDB() != DB {
+		t.Fatal("expected ReadDB to fall back to primary without DB_READ_DSN")
+	}
+	if err := gorm.G[ChatLog](DB).Create(context.Background(), &ChatLog{Name: "from-replica"}); err != nil {
+		t.Fatalf("failed to seed replica: %v", err)
+	}
+
+	t.Setenv("DB_READ_DSN", "file:"+replicaPath+"?mode=ro")
+	Init(context.Background(), filepath.Join(dir, "primary.db"))
+	t.Cleanup(func() { replica = nil })
+
+	if ReadDB() == DB {
+		t.Fatal("expected ReadDB to use the replica")
+	}
+	log, err := gorm.G[ChatLog](ReadDB()).Where("name = ?", "from-replica").First(context.Background())
+	if err != nil || log.Name != "from-replica" {
+		t.Fatalf("expected replica row, got %+v, err %v", log, err)
+	}
+	if err := gorm.G[ChatLog](ReadDB()).Create(context.Background(), &ChatLog{Name: "write"}); err == nil {
+		t.Fatal("expected writes to the read-only replica to fail")
+	}
+}
+
+func TestInit_LogBackfillsRunOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llmio.db")
+	Init(context.Background(), path)
+
+	for _, name := range []string{"chat_logs_signature", "chat_logs_strategy", "chat_logs_model_provider_id", "chat_logs_request_id"} {
+		count, err := gorm.G[Config](DB).Where("key = ?", KeyMigrationPrefix+name).Count(context.Background(), "*")
+		if err != nil || count != 1 {
+			t.Fatalf("expected migration marker %s, got %d, err %v", name, count, err)
+		}
+	}
+
+	// 标记写入后再次启动不再扫描 chat_logs
+	if err := DB.Exec(`INSERT INTO chat_logs (name, signature) VALUES (?, NULL)`, "late").Error; err != nil {
+		t.Fatalf("failed to seed log: %v", err)
+	}
+	Init(context.Background(), path)
+	var pending int64
+	if err := DB.Raw(`SELECT COUNT(*) FROM chat_logs WHERE signature IS NULL`).Scan(&pending).Error; err != nil {
+		t.Fatalf("failed to count logs: %v", err)
+	}
+	if pending != 1 {
+		t.Fatalf("expected backfill to be skipped on restart, %d rows pending", pending)
+	}
+}
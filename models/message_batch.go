@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MessageBatch Anthropic 消息批处理，记录批次所在提供商，后续查询固定转发到该提供商
+type MessageBatch struct {
+	gorm.Model
+	BatchID          string `gorm:"uniqueIndex"`
+	Name             string `gorm:"index"` // 模型名称
+	ProviderID       uint
+	AuthKeyID        uint       `gorm:"index"` // 创建批次的 AuthKey，0 表示管理员
+	ProcessingStatus string     // in_progress/canceling/ended
+	RequestCount     int        // 批次内请求数
+	EndedAt          *time.Time // 批次处理结束时间
+	Snapshot         string     // 最近一次上游返回的批次对象
+}
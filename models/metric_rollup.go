@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// 预聚合统计的时间粒度
+const (
+	RollupHour = "hour"
+	RollupDay  = "day"
+)
+
+// MetricRollup 按小时或天预聚合的请求统计，同一周期内按模型、提供商与 Key 分组
+// 延迟分位数只统计成功请求，单位毫秒
+type MetricRollup struct {
+	ID               uint      `gorm:"primarykey" json:"-"`
+	Period           string    `gorm:"uniqueIndex:idx_metric_rollup;size:8" json:"period"` // hour/day
+	BucketStart      time.Time `gorm:"uniqueIndex:idx_metric_rollup" json:"bucket_start"`
+	Model            string    `gorm:"uniqueIndex:idx_metric_rollup" json:"model"`
+	ProviderName     string    `gorm:"uniqueIndex:idx_metric_rollup" json:"provider_name"`
+	AuthKeyID        uint      `gorm:"uniqueIndex:idx_metric_rollup" json:"auth_key_id"`
+	Requests         int64     `json:"requests"`
+	Errors           int64     `json:"errors"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	TotalTokens      int64     `json:"total_tokens"`
+	FirstChunkP50Ms  int64     `json:"first_chunk_p50_ms"`
+	FirstChunkP95Ms  int64     `json:"first_chunk_p95_ms"`
+	FirstChunkP99Ms  int64     `json:"first_chunk_p99_ms"`
+	LatencyP50Ms     int64     `json:"latency_p50_ms"` // 请求开始到响应结束的完整耗时
+	LatencyP95Ms     int64     `json:"latency_p95_ms"`
+	LatencyP99Ms     int64     `json:"latency_p99_ms"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/providers"
 	"gorm.io/gorm"
 )
 
@@ -13,9 +14,79 @@ type Provider struct {
 	Name         string
 	Type         string
 	Config       string
-	Console      string // 控制台地址
-	Proxy        string // HTTP 代理地址
-	ErrorMatcher string // 响应体错误识别规则，多行或分号分隔 sample
+	Console      string            // 控制台地址
+	Proxy        string            // HTTP 代理地址
+	ErrorMatcher string            // 响应体错误识别规则，多行或分号分隔 sample
+	ErrorClasses []ErrorClassRule  `gorm:"serializer:json"` // 上游错误分类规则，按顺序匹配
+	CostHeaders  string            // 上游返回单次请求费用的响应头，逗号分隔，如 x-openrouter-cost
+	UserAgent    string            // 发往上游的 User-Agent，空为全局默认，passthrough 表示透传客户端 UA
+	Headers      map[string]string `gorm:"serializer:json"` // 发往该提供商的默认请求头，关联的自定义请求头优先
+	QueryParams  map[string]string `gorm:"serializer:json"` // 追加到上游请求地址的查询参数
+
+	ConfigVersion int `gorm:"default:1"` // 配置版本，每次编辑递增；进行中的请求沿用路由时读取的版本
+
+	RetireState       string       // 下线流程状态 空/retiring/archived
+	RetireStartedAt   *time.Time   // 下线观察开始时间
+	RetireObserveDays int          // 下线观察天数
+	RetireWeights     map[uint]int `gorm:"serializer:json"` // 下线前各关联的权重，用于撤销恢复
+	RetireDisabled    []uint       `gorm:"serializer:json"` // 归档时由启用改为停用的关联，撤销时只恢复这些
+	RetiredAt         *time.Time   // 归档时间
+
+	AuthDisabledAt     *time.Time // 上游连续返回 401/403 时自动停用的时间，非空时不参与路由
+	AuthDisabledReason string     // 自动停用原因
+
+	RateLimit RateLimit `gorm:"embedded;embeddedPrefix:rate_limit_"` // 发往该提供商的请求频率限制
+
+	QuotaGroup string `gorm:"index"` // 共享上游账号的分组名，同组提供商合并统计频率与预算，空表示单独统计
+
+	Budget ProviderBudget `gorm:"embedded;embeddedPrefix:budget_"` // 按天或按月的用量预算
+
+	TLS ProviderTLS `gorm:"embedded;embeddedPrefix:tls_"` // 私有 CA 等 TLS 选项
+
+	DNSServer     string            // 解析上游域名使用的 DNS 服务器 host:port，空为系统默认
+	HostOverrides map[string]string `gorm:"serializer:json"` // 域名到 IP 的固定解析，优先于 DNS
+
+	Balance          *float64   // 最近一次查询到的账户余额，为空表示未查询；余额耗尽时不参与路由
+	BalanceCurrency  string     // 余额币种
+	BalanceCheckedAt *time.Time // 余额查询时间
+}
+
+// ErrorClassRule 上游错误分类规则，状态码与响应体样本都配置时需同时满足
+type ErrorClassRule struct {
+	Class    string   `json:"class"`    // quota_exhausted/auth_failed/content_filter/transient
+	Statuses []int    `json:"statuses"` // 匹配的状态码，为空时不限制
+	Samples  []string `json:"samples"`  // 响应体样本，任一出现即匹配，不区分大小写与空白，为空时不限制
+	Action   string   `json:"action"`   // reduce_weight/open_breaker/disable_key/fail_fast，为空时使用分类的默认动作
+}
+
+// ProviderBudget 提供商用量预算，超出后在周期重置前不参与路由，适合每日限额的免费渠道
+type ProviderBudget struct {
+	Period string  `json:"period"` // day/month，空视为 day，按服务器时区重置
+	Tokens int64   `json:"tokens"` // 周期内 Token 上限，0 表示不限制
+	Cost   float64 `json:"cost"`   // 周期内费用上限，0 表示不限制
+}
+
+// ProviderTLS 提供商 TLS 选项
+type ProviderTLS struct {
+	CACert             string `json:"ca_cert"`              // 额外信任的根证书 PEM
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"` // 跳过证书校验，仅建议用于内网测试
+}
+
+// ClientOptions 请求该提供商使用的 HTTP 客户端选项
+func (p Provider) ClientOptions() providers.ClientOptions {
+	return providers.ClientOptions{
+		Proxy:              p.Proxy,
+		CACert:             p.TLS.CACert,
+		InsecureSkipVerify: p.TLS.InsecureSkipVerify,
+		DNSServer:          p.DNSServer,
+		HostOverrides:      providers.FormatHostOverrides(p.HostOverrides),
+	}
+}
+
+// RateLimit 每分钟请求数限制，observe 模式只记录超限不拒绝，便于在已有流量上试运行
+type RateLimit struct {
+	RPM  int    `json:"rpm"`  // 每分钟请求数上限，0 表示不限制
+	Mode string `json:"mode"` // enforce/observe，空视为 enforce
 }
 
 type AnthropicConfig struct {
@@ -26,13 +97,78 @@ type AnthropicConfig struct {
 
 type Model struct {
 	gorm.Model
-	Name         string
-	Remark       string
-	MaxRetry     int    // 重试次数限制
-	TimeOut      int    // 超时时间 单位秒
-	Strategy     string // 负载均衡策略 默认 lottery
-	Breaker      *bool  // 是否开启熔断
-	DisplayOrder int    // 模型展示顺序，值越大越靠前
+	Name              string
+	Remark            string
+	MaxRetry          int                // 重试次数限制
+	TimeOut           int                // 超时时间 单位秒
+	RetryReserve      int                // 剩余时间少于该秒数时不再发起重试，0 表示仅受重试次数限制
+	StickyMinutes     int                // 会话粘滞分钟数，同一 Key 与会话在期间内优先使用同一关联，0 表示关闭
+	HedgeAfter        int                // 对冲阈值 单位毫秒，首个提供商超过该时间未返回响应头时并发请求下一个，0 表示关闭
+	StreamBuffer      int                // 流式响应先缓冲的字节数，上游在此之前中断时换提供商重试，0 表示关闭
+	Webhook           string             // 请求完成后 POST 请求摘要的回调地址，记录 IO 时附带完整输入输出，空表示关闭
+	ContextLength     int                // 上下文长度 单位 Token，仅用于模型详情展示，0 表示未知
+	Strategy          string             // 负载均衡策略 默认 lottery
+	Breaker           *bool              // 是否开启熔断
+	DisplayOrder      int                // 模型展示顺序，值越大越靠前
+	ParamRanges       *ParamRanges       `gorm:"serializer:json"` // 采样参数允许范围
+	ValidateResponse  *bool              // 是否校验非流式响应，空响应或非法 JSON 视为失败并重试
+	RawPassthrough    *bool              // 原始透传，响应按字节转发不经处理器，不统计用量也不记录 IO
+	Deprecation       *Deprecation       `gorm:"serializer:json"` // 弃用通知，通过响应头与模型列表告知调用方
+	IOLogPolicy       *IOLogPolicy       `gorm:"serializer:json"` // IO 记录采样与脱敏，仅对开启 IO 记录的 Key 生效
+	ResponseGuard     *ResponseGuard     `gorm:"serializer:json"` // 流式响应屏蔽词，命中时中止上游
+	BreakerThresholds *BreakerThresholds `gorm:"serializer:json"` // 熔断阈值，为空或字段为 0 时使用默认值
+	RetryPolicy       *RetryPolicy       `gorm:"serializer:json"` // 重试策略，为空时立即重试所有失败
+}
+
+// RetryPolicy 模型级重试策略
+type RetryPolicy struct {
+	Backoff    int   `json:"backoff"`     // 首次重试前的等待毫秒数，之后每次翻倍并加入随机抖动，0 表示立即重试
+	MaxBackoff int   `json:"max_backoff"` // 单次等待上限 毫秒，0 表示不限制
+	RetryOn    []int `json:"retry_on"`    // 可重试的上游状态码，其他状态码直接返回给客户端，为空时全部重试
+	RetryAfter bool  `json:"retry_after"` // 遵循上游 Retry-After 响应头
+}
+
+// BreakerThresholds 模型级熔断阈值
+type BreakerThresholds struct {
+	MaxFailures int `json:"max_failures"` // 连续失败多少次后熔断
+	SleepWindow int `json:"sleep_window"` // 熔断冷却时间 单位秒
+	MaxRequests int `json:"max_requests"` // 探测恢复阶段成功多少次后关闭熔断
+}
+
+// ResponseGuard 响应内容策略，屏蔽词不区分大小写
+type ResponseGuard struct {
+	Blocklist []string `json:"blocklist"`
+}
+
+// IOLogPolicy 模型级 IO 记录策略
+type IOLogPolicy struct {
+	SampleRate *int     `json:"sample_rate"` // 记录比例 0-100，为空时全部记录
+	Redact     []string `json:"redact"`      // 输入脱敏规则，image_base64 或 JSON 字段名
+}
+
+// Deprecation 模型弃用信息，Message 为空时表示未弃用
+type Deprecation struct {
+	Message  string     `json:"message"`
+	SunsetAt *time.Time `json:"sunset_at"` // 计划下线时间
+}
+
+// Deprecated 模型是否已标记弃用
+func (m Model) Deprecated() bool {
+	return m.Deprecation != nil && m.Deprecation.Message != ""
+}
+
+// ParamRange 参数允许范围，Min/Max 为空表示不限制
+type ParamRange struct {
+	Min *float64 `json:"min"`
+	Max *float64 `json:"max"`
+}
+
+// ParamRanges 模型级采样参数策略，Mode 为 clamp(截断，默认) 或 reject(拒绝)
+type ParamRanges struct {
+	Mode        string      `json:"mode"`
+	Temperature *ParamRange `json:"temperature"`
+	TopP        *ParamRange `json:"top_p"`
+	MaxTokens   *ParamRange `json:"max_tokens"`
 }
 
 type ModelWithProvider struct {
@@ -43,30 +179,48 @@ type ModelWithProvider struct {
 	ToolCall         *bool             // 能否接受带有工具调用的请求
 	StructuredOutput *bool             // 能否接受带有结构化输出的请求
 	Image            *bool             // 能否接受带有图片的请求(视觉)
+	Chat             *bool             // 能否处理对话请求，仅用于向量化等端点的关联应关闭
+	Embedding        *bool             // 能否处理向量化请求
+	ImageGeneration  *bool             // 能否处理图片生成/编辑请求
+	Rerank           *bool             // 能否处理重排序请求
+	Moderation       *bool             // 能否处理内容审核请求
 	WithHeader       *bool             // 是否透传header
+	PseudoStream     *bool             // 流式请求以非流式发往上游，再将完整响应切分为 SSE 返回
+	StreamAggregate  *bool             // 非流式请求以流式发往上游，再将各分块合并为完整 JSON 返回
 	Status           *bool             // 是否启用
 	CustomerHeaders  map[string]string `gorm:"serializer:json"` // 自定义headers
 	ExtraBody        map[string]any    `gorm:"serializer:json"` // 额外请求体参数
 	Weight           int
+	Priority         int    // 优先级分层，数值小的先使用，同层内按权重选择
+	MaxInFlight      int    // 最大并发请求数，0 表示不限制
+	QueueTimeout     int    // 并发已满时排队等待的毫秒数，0 表示直接跳过该关联
+	QuotaRPM         int    // 每分钟请求数配额，滑动窗口统计，0 表示不限制
+	QuotaTPM         int    // 每分钟 Token 配额，滑动窗口统计，0 表示不限制
+	Schedule         string // 可用时间窗口，如 "mon-fri 09:00-18:00; sat,sun 22:00-06:00"，窗口外不参与负载均衡，空表示始终可用
 	InputPrice       *float64
 	CacheReadPrice   *float64
 	OutputPrice      *float64
 	Currency         string
+	Slot             string // 蓝绿路由分组 blue/green
+	Remark           string // 运维备注
 }
 
 type ChatLog struct {
 	gorm.Model
-	Name          string `gorm:"index"`
-	TraceID       string `gorm:"index"`
-	ProviderModel string `gorm:"index"`
-	ProviderName  string `gorm:"index"`
-	Status        string `gorm:"index"` // error or success
-	Style         string // 类型
-	UserAgent     string `gorm:"index"` // 用户代理
-	RemoteIP      string // 访问ip
-	AuthKeyID     uint   `gorm:"index"` // 使用的AuthKey ID
-	SessionID     string `gorm:"index"` // 请求体中的session_id
-	ChatIO        bool   // 是否开启IO记录
+	Name            string `gorm:"index"`
+	TraceID         string `gorm:"index"`
+	RequestID       string `gorm:"index"` // 客户端调用的请求 ID，同一次调用的重试日志相同
+	ModelProviderID uint   `gorm:"index"` // 本次使用的模型关联
+	ProviderModel   string `gorm:"index"`
+	ProviderName    string `gorm:"index"`
+	Status          string `gorm:"index"` // running/success/error/canceled
+	Style           string // 类型
+	UserAgent       string `gorm:"index"` // 用户代理
+	RemoteIP        string // 访问ip
+	AuthKeyID       uint   `gorm:"index"` // 使用的AuthKey ID
+	SessionID       string `gorm:"index"` // 请求体中的session_id
+	ChatIO          bool   // 是否开启IO记录
+	Strategy        string `gorm:"index"` // 本次使用的负载均衡策略，可能来自管理员请求头覆盖
 
 	Error          string        // if status is error, this field will be set
 	Retry          int           // 重试次数
@@ -74,12 +228,30 @@ type ChatLog struct {
 	FirstChunkTime time.Duration // 首个chunk耗时
 	ChunkTime      time.Duration // chunk耗时
 	Tps            float64
-	Size           int // 响应大小 字节
+	Size           int    // 响应大小 字节
+	ChunkCount     int    // 响应分块数，非流式为 1
+	ImageCount     int    // 图片生成数量
+	FinishReason   string `json:"finish_reason"` // 统一后的结束原因 stop/length/tool_use/content_filter
+	RequestSize    int    // 请求体大小 字节
 	Usage
-	InputPrice     float64 `json:"input_price"`
-	CacheReadPrice float64 `json:"cache_read_price"`
-	OutputPrice    float64 `json:"output_price"`
-	Currency       string  `json:"currency"`
+	InputPrice     float64         `json:"input_price"`
+	CacheReadPrice float64         `json:"cache_read_price"`
+	OutputPrice    float64         `json:"output_price"`
+	Currency       string          `json:"currency"`
+	Cost           *float64        `json:"cost"`                                      // 上游响应头返回的费用，非空时覆盖按单价计算的结果
+	Timeline       []TimelineEvent `gorm:"serializer:json" json:"timeline,omitempty"` // 请求各阶段时间线
+	Signature      string          `json:"signature,omitempty"`                       // 配置签名密钥时对用量与计费字段的 HMAC 签名
+}
+
+// TimelineEvent 请求处理过程中的阶段事件
+type TimelineEvent struct {
+	Stage     string    `json:"stage"` // routed/attempt/failed/response/first_chunk/done
+	At        time.Time `json:"at"`
+	ElapsedMs int64     `json:"elapsed_ms"` // 距请求开始的毫秒数
+	Attempt   int       `json:"attempt,omitempty"`
+	Provider  string    `json:"provider,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	Error     string    `json:"error,omitempty"`
 }
 
 func (l ChatLog) WithError(err error) ChatLog {
@@ -120,13 +292,17 @@ type ReqMeta struct {
 
 type AuthKey struct {
 	gorm.Model
-	Name       string // 项目名称
-	Key        string
-	Status     *bool      // 是否启用
-	IOLog      *bool      // 是否记录IO
-	AllowAll   *bool      // 是否允许所有模型
-	Models     []string   `gorm:"serializer:json"` // 允许的模型列表
-	ExpiresAt  *time.Time // nil=永不过期，有值=具体过期时间
-	UsageCount int64      // 使用次数统计
-	LastUsedAt *time.Time // 最后使用时间
+	Name        string // 项目名称
+	Key         string
+	Status      *bool      // 是否启用
+	IOLog       *bool      // 是否记录IO
+	NoIOLog     *bool      // 禁止记录IO，优先于 IOLog，该 Key 的请求正文不会写入数据库或回调
+	AllowAll    *bool      // 是否允许所有模型
+	Models      []string   `gorm:"serializer:json"` // 允许的模型列表
+	ExpiresAt   *time.Time // nil=永不过期，有值=具体过期时间
+	UsageCount  int64      // 使用次数统计
+	LastUsedAt  *time.Time // 最后使用时间
+	Remark      string     // 运维备注
+	RateLimit   RateLimit  `gorm:"embedded;embeddedPrefix:rate_limit_"` // 该 Key 的请求频率限制
+	TrustedUser string     `gorm:"index"`                               // 受信任代理请求头中的用户名，匹配的请求无需携带 Key
 }
@@ -0,0 +1,21 @@
+package models
+
+import "gorm.io/gorm"
+
+// ProviderSelftest 提供商能力自检记录，保留历史结果用于核对渠道宣称的能力
+type ProviderSelftest struct {
+	gorm.Model
+	ProviderID    uint           `gorm:"index"`
+	ProviderModel string         // 自检使用的上游模型
+	Passed        int            // 通过的用例数
+	Failed        int            // 失败的用例数
+	Cases         []SelftestCase `gorm:"serializer:json"`
+}
+
+// SelftestCase 单个自检用例的结果
+type SelftestCase struct {
+	Name      string `json:"name"`   // models/chat/stream/tool_call/json_mode/vision
+	Status    string `json:"status"` // pass/fail/skip
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
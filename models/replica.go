@@ -0,0 +1,32 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// replica 只读副本连接，未配置时为 nil
+var replica *gorm.DB
+
+// ReadDB 返回供指标与日志查询使用的连接，配置了只读副本时使用副本，否则使用主库
+func ReadDB() *gorm.DB {
+	if replica != nil {
+		return replica
+	}
+	return DB
+}
+
+// openReplica 以只读方式打开副本，目前仅支持 SQLite DSN（如由 LiteFS/Litestream 同步的副本文件）
+func openReplica(dsn string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(dsn))
+	if err != nil {
+		return nil, fmt.Errorf("open read replica: %w", err)
+	}
+	// 启动时校验副本可读，避免查询时才发现配置错误
+	if err := db.Exec("SELECT 1 FROM chat_logs LIMIT 1").Error; err != nil {
+		return nil, fmt.Errorf("check read replica: %w", err)
+	}
+	return db, nil
+}
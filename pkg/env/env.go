@@ -5,7 +5,7 @@ import (
 	"strconv"
 )
 
-func GetWithDefault[T ~string | ~bool](key string, defaultValue T) T {
+func GetWithDefault[T ~string | ~bool | ~int](key string, defaultValue T) T {
 	envValue, ok := os.LookupEnv(key)
 	if !ok {
 		return defaultValue
@@ -19,6 +19,12 @@ func GetWithDefault[T ~string | ~bool](key string, defaultValue T) T {
 			return defaultValue
 		}
 		return any(b).(T)
+	case int:
+		i, err := strconv.Atoi(envValue)
+		if err != nil {
+			return defaultValue
+		}
+		return any(i).(T)
 	default:
 		return defaultValue
 	}
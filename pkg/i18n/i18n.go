@@ -0,0 +1,106 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	LangEN   = "en"
+	LangZhCN = "zh-CN"
+	LangZhTW = "zh-TW"
+)
+
+// 系统配置强制指定的语言，为空时按 Accept-Language 协商
+var defaultLang atomic.Value
+
+func init() {
+	defaultLang.Store("")
+}
+
+// SetDefault 设置部署级别的固定语言，传入空值或 auto 时恢复按请求协商
+func SetDefault(lang string) {
+	lang = strings.TrimSpace(lang)
+	if lang == "" || strings.EqualFold(lang, "auto") {
+		defaultLang.Store("")
+		return
+	}
+	defaultLang.Store(normalize(lang))
+}
+
+// Default 返回系统配置的固定语言，未配置时返回空字符串
+func Default() string {
+	return defaultLang.Load().(string)
+}
+
+// Resolve 优先使用系统配置语言，否则从 Accept-Language 中协商
+func Resolve(acceptLanguage string) string {
+	if lang := Default(); lang != "" {
+		return lang
+	}
+	return Match(acceptLanguage)
+}
+
+// Match 解析 Accept-Language 并返回支持的首个语言，默认英文
+func Match(acceptLanguage string) string {
+	for part := range strings.SplitSeq(acceptLanguage, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		if lang := normalize(tag); lang != "" {
+			return lang
+		}
+	}
+	return LangEN
+}
+
+func normalize(tag string) string {
+	tag = strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+	switch {
+	case tag == "zh-tw" || tag == "zh-hk" || tag == "zh-mo" || strings.HasPrefix(tag, "zh-hant"):
+		return LangZhTW
+	case strings.HasPrefix(tag, "zh"):
+		return LangZhCN
+	case strings.HasPrefix(tag, "en"):
+		return LangEN
+	default:
+		return ""
+	}
+}
+
+// T 按语言翻译消息，缺失时回退英文，仍缺失则返回消息 key
+func T(lang string, msg Message, args ...any) string {
+	format, ok := catalog[lang][msg]
+	if !ok {
+		format, ok = catalog[LangEN][msg]
+	}
+	if !ok {
+		format = string(msg)
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Error 可本地化的错误，Error() 返回英文文本以便日志记录
+type Error struct {
+	Msg  Message
+	Args []any
+}
+
+func NewError(msg Message, args ...any) *Error {
+	return &Error{Msg: msg, Args: args}
+}
+
+func (e *Error) Error() string {
+	return T(LangEN, e.Msg, e.Args...)
+}
+
+// Localize 返回指定语言下的错误文本
+func (e *Error) Localize(lang string) string {
+	return T(lang, e.Msg, e.Args...)
+}
@@ -0,0 +1,48 @@
+package i18n
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", LangEN},
+		{"zh-CN,zh;q=0.9,en;q=0.8", LangZhCN},
+		{"zh-TW", LangZhTW},
+		{"zh-Hant-HK;q=0.9", LangZhTW},
+		{"fr-FR, en-US;q=0.7", LangEN},
+		{"de, *", LangEN},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.header); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePrefersSystemDefault(t *testing.T) {
+	SetDefault(LangZhCN)
+	t.Cleanup(func() { SetDefault("") })
+
+	if got := Resolve("en-US"); got != LangZhCN {
+		t.Fatalf("Resolve() = %q, want %q", got, LangZhCN)
+	}
+
+	SetDefault("auto")
+	if got := Resolve("en-US"); got != LangEN {
+		t.Fatalf("Resolve() after auto = %q, want %q", got, LangEN)
+	}
+}
+
+func TestTFallback(t *testing.T) {
+	if got := T(LangZhCN, MsgModelExists, "gpt-4o"); got != "模型 gpt-4o 已存在" {
+		t.Fatalf("unexpected zh-CN message: %q", got)
+	}
+	if got := T("ja", MsgInvalidToken); got != "Invalid token" {
+		t.Fatalf("expected english fallback, got %q", got)
+	}
+	if got := NewError(MsgAuthKeyModelsRequired).Localize(LangZhCN); got != "请至少选择一个允许的模型或启用允许全部模型" {
+		t.Fatalf("unexpected localized error: %q", got)
+	}
+}
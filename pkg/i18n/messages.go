@@ -0,0 +1,295 @@
+package i18n
+
+type Message string
+
+const (
+	MsgInvalidID                 Message = "invalid_id"
+	MsgInvalidRequestBody        Message = "invalid_request_body"
+	MsgInvalidDays               Message = "invalid_days"
+	MsgInvalidStrategyFilter     Message = "invalid_strategy_filter"
+	MsgInvalidStatusFilter       Message = "invalid_status_filter"
+	MsgInvalidAllowAllFilter     Message = "invalid_allow_all_filter"
+	MsgInvalidExpiresAt          Message = "invalid_expires_at"
+	MsgInvalidProviderType       Message = "invalid_provider_type"
+	MsgInvalidConfigFormat       Message = "invalid_config_format"
+	MsgProviderExists            Message = "provider_exists"
+	MsgProviderNotFound          Message = "provider_not_found"
+	MsgModelExists               Message = "model_exists"
+	MsgModelNotFound             Message = "model_not_found"
+	MsgModelProviderNotFound     Message = "model_provider_not_found"
+	MsgAuthKeyNotFound           Message = "auth_key_not_found"
+	MsgChatIONotFound            Message = "chat_io_not_found"
+	MsgCountTokensConfigNotFound Message = "count_tokens_config_not_found"
+	MsgModelIDsEmpty             Message = "model_ids_empty"
+	MsgModelIDsInvalidZero       Message = "model_ids_invalid_zero"
+	MsgModelIDDuplicated         Message = "model_id_duplicated"
+	MsgModelIDsNotExist          Message = "model_ids_not_exist"
+	MsgModelIDRequired           Message = "model_id_required"
+	MsgInvalidModelID            Message = "invalid_model_id"
+	MsgProviderStatusParams      Message = "provider_status_params"
+	MsgInvalidProviderID         Message = "invalid_provider_id"
+	MsgValueMustBePositive       Message = "value_must_be_positive"
+	MsgInvalidCleanType          Message = "invalid_clean_type"
+	MsgAuthKeyModelsRequired     Message = "auth_key_models_required"
+	MsgInvalidGeminiAction       Message = "invalid_gemini_action"
+	MsgUnsupportedGeminiMethod   Message = "unsupported_gemini_method"
+	MsgModelPermissionDenied     Message = "model_permission_denied"
+	MsgAuthHeaderMissing         Message = "auth_header_missing"
+	MsgInvalidAuthHeader         Message = "invalid_auth_header"
+	MsgInvalidToken              Message = "invalid_token"
+	MsgAuthKeyMissing            Message = "auth_key_missing"
+	MsgTokenExpired              Message = "token_expired"
+	MsgInvalidSlot               Message = "invalid_slot"
+	MsgParamOutOfRange           Message = "param_out_of_range"
+	MsgInvalidParamRangeMode     Message = "invalid_param_range_mode"
+	MsgInvalidHours              Message = "invalid_hours"
+	MsgBatchModelMismatch        Message = "batch_model_mismatch"
+	MsgMessageBatchNotFound      Message = "message_batch_not_found"
+	MsgLogNotFound               Message = "log_not_found"
+	MsgRouteNotFound             Message = "route_not_found"
+	MsgLogSigningDisabled        Message = "log_signing_disabled"
+	MsgRateLimited               Message = "rate_limited"
+	MsgInvalidRateLimit          Message = "invalid_rate_limit"
+	MsgInvalidIOLogPolicy        Message = "invalid_io_log_policy"
+	MsgInvalidCACert             Message = "invalid_ca_cert"
+	MsgBalanceUnsupported        Message = "balance_unsupported"
+	MsgInvalidStrategy           Message = "invalid_strategy"
+	MsgTrustedUserTaken          Message = "trusted_user_taken"
+	MsgTrustedUserUnknown        Message = "trusted_user_unknown"
+	MsgInvalidBudget             Message = "invalid_budget"
+	MsgInvalidSchedule           Message = "invalid_schedule"
+	MsgInvalidWebhook            Message = "invalid_webhook"
+	MsgBreakerNotFound           Message = "breaker_not_found"
+	MsgInvalidBreakerThresholds  Message = "invalid_breaker_thresholds"
+	MsgInvalidRetryPolicy        Message = "invalid_retry_policy"
+	MsgInvalidErrorClass         Message = "invalid_error_class"
+	MsgAdminRateLimited          Message = "admin_rate_limited"
+	MsgDaysOutOfRange            Message = "days_out_of_range"
+	MsgInvalidModelFallback      Message = "invalid_model_fallback"
+	MsgTooManyStreams            Message = "too_many_streams"
+	MsgInvalidTimeFilter         Message = "invalid_time_filter"
+	MsgInvalidIOViewLimit        Message = "invalid_io_view_limit"
+	MsgInvalidRollupPeriod       Message = "invalid_rollup_period"
+	MsgInvalidLatencyGroup       Message = "invalid_latency_group"
+	MsgRequestBodyTooLarge       Message = "request_body_too_large"
+	MsgConfigKeyManaged          Message = "config_key_managed"
+)
+
+var catalog = map[string]map[Message]string{
+	LangEN: {
+		MsgInvalidID:                 "Invalid ID format",
+		MsgInvalidRequestBody:        "Invalid request body: %s",
+		MsgInvalidDays:               "Invalid days parameter",
+		MsgInvalidStrategyFilter:     "Invalid strategy filter",
+		MsgInvalidStatusFilter:       "Invalid status filter",
+		MsgInvalidAllowAllFilter:     "Invalid allow_all filter",
+		MsgInvalidExpiresAt:          "Invalid expires_at format, must be RFC3339",
+		MsgInvalidProviderType:       "Invalid provider type",
+		MsgInvalidConfigFormat:       "Invalid config format",
+		MsgProviderExists:            "Provider already exists",
+		MsgProviderNotFound:          "Provider not found",
+		MsgModelExists:               "Model: %s already exists",
+		MsgModelNotFound:             "Model not found",
+		MsgModelProviderNotFound:     "Model-provider association not found",
+		MsgAuthKeyNotFound:           "Auth key not found",
+		MsgChatIONotFound:            "ChatIO not found",
+		MsgCountTokensConfigNotFound: "Anthropic count tokens config not found",
+		MsgModelIDsEmpty:             "model_ids cannot be empty",
+		MsgModelIDsInvalidZero:       "model_ids contains invalid value 0",
+		MsgModelIDDuplicated:         "Duplicated model_id: %d",
+		MsgModelIDsNotExist:          "model_ids contains non-existing model",
+		MsgModelIDRequired:           "model_id query parameter is required",
+		MsgInvalidModelID:            "Invalid model_id format",
+		MsgProviderStatusParams:      "provider_id, model_name and provider_model query parameters are required",
+		MsgInvalidProviderID:         "Invalid provider_id format",
+		MsgValueMustBePositive:       "Value must be greater than 0",
+		MsgInvalidCleanType:          "Invalid type: must be 'count' or 'days'",
+		MsgAuthKeyModelsRequired:     "Select at least one allowed model or enable allow all models",
+		MsgInvalidGeminiAction:       "Invalid Gemini model action",
+		MsgUnsupportedGeminiMethod:   "Unsupported Gemini method: %s",
+		MsgModelPermissionDenied:     "Auth key has no permission to use %s",
+		MsgAuthHeaderMissing:         "Authorization header is missing",
+		MsgInvalidAuthHeader:         "Invalid authorization header",
+		MsgInvalidToken:              "Invalid token",
+		MsgAuthKeyMissing:            "Authorization key is missing",
+		MsgTokenExpired:              "Token has expired",
+		MsgInvalidSlot:               "Invalid routing slot, expected blue or green",
+		MsgParamOutOfRange:           "Parameter %s=%s is out of the allowed range %s",
+		MsgInvalidParamRangeMode:     "Invalid param range mode, expected clamp or reject",
+		MsgInvalidHours:              "Invalid hours parameter",
+		MsgBatchModelMismatch:        "All batch requests must use the same model",
+		MsgMessageBatchNotFound:      "Message batch not found",
+		MsgLogNotFound:               "Log not found",
+		MsgRouteNotFound:             "Route not found: %s %s",
+		MsgLogSigningDisabled:        "Log signing is not enabled, set LLMIO_LOG_SIGNING_SECRET first",
+		MsgRateLimited:               "Rate limit exceeded for this API key, please retry later",
+		MsgInvalidRateLimit:          "Invalid rate limit: rpm must not be negative and mode must be enforce or observe",
+		MsgInvalidIOLogPolicy:        "io_log_policy.sample_rate must be between 0 and 100",
+		MsgInvalidCACert:             "Invalid CA certificate: %s",
+		MsgBalanceUnsupported:        "This provider type does not support balance query",
+		MsgInvalidStrategy:           "Invalid strategy: %s",
+		MsgTrustedUserTaken:          "Trusted user is already bound to another key",
+		MsgTrustedUserUnknown:        "No key is bound to trusted user %s",
+		MsgInvalidBudget:             "Invalid budget: period must be day or month and limits must not be negative",
+		MsgInvalidSchedule:           "Invalid schedule: %s",
+		MsgInvalidWebhook:            "Webhook must be an http or https URL",
+		MsgBreakerNotFound:           "No circuit breaker is recorded for this association",
+		MsgInvalidBreakerThresholds:  "Breaker thresholds must not be negative",
+		MsgInvalidRetryPolicy:        "Retry backoff must not be negative and retry_on must contain HTTP status codes",
+		MsgInvalidErrorClass:         "Invalid error class rule %d: class must be quota_exhausted/auth_failed/content_filter/transient, action must be reduce_weight/open_breaker/disable_key/fail_fast, and statuses or samples is required",
+		MsgAdminRateLimited:          "Too many admin API requests, limit is %d per minute",
+		MsgDaysOutOfRange:            "days must be between 0 and %d",
+		MsgInvalidModelFallback:      "Invalid model fallback: every rule needs a pattern and a provider_id",
+		MsgTooManyStreams:            "Too many concurrent streaming requests (limit %d), please retry later",
+		MsgInvalidTimeFilter:         "Invalid %s parameter, must be RFC3339",
+		MsgInvalidIOViewLimit:        "Invalid limit parameter (1-%d bytes)",
+		MsgInvalidRollupPeriod:       "Invalid period, must be hour or day",
+		MsgInvalidLatencyGroup:       "Invalid by, must be model or provider",
+		MsgRequestBodyTooLarge:       "Request body exceeds the %d MB limit",
+		MsgConfigKeyManaged:          "Config %s is managed by its own endpoint: %s",
+	},
+	LangZhCN: {
+		MsgInvalidID:                 "ID 格式无效",
+		MsgInvalidRequestBody:        "请求体无效: %s",
+		MsgInvalidDays:               "天数参数无效",
+		MsgInvalidStrategyFilter:     "负载均衡策略筛选无效",
+		MsgInvalidStatusFilter:       "状态筛选无效",
+		MsgInvalidAllowAllFilter:     "allow_all 筛选无效",
+		MsgInvalidExpiresAt:          "expires_at 格式无效，必须为 RFC3339",
+		MsgInvalidProviderType:       "提供商类型无效",
+		MsgInvalidConfigFormat:       "配置格式无效",
+		MsgProviderExists:            "提供商已存在",
+		MsgProviderNotFound:          "提供商不存在",
+		MsgModelExists:               "模型 %s 已存在",
+		MsgModelNotFound:             "模型不存在",
+		MsgModelProviderNotFound:     "模型提供商关联不存在",
+		MsgAuthKeyNotFound:           "密钥不存在",
+		MsgChatIONotFound:            "输入输出记录不存在",
+		MsgCountTokensConfigNotFound: "未配置 Anthropic Token 计数",
+		MsgModelIDsEmpty:             "model_ids 不能为空",
+		MsgModelIDsInvalidZero:       "model_ids 包含无效值 0",
+		MsgModelIDDuplicated:         "model_id 重复: %d",
+		MsgModelIDsNotExist:          "model_ids 包含不存在的模型",
+		MsgModelIDRequired:           "缺少 model_id 查询参数",
+		MsgInvalidModelID:            "model_id 格式无效",
+		MsgProviderStatusParams:      "缺少 provider_id、model_name 或 provider_model 查询参数",
+		MsgInvalidProviderID:         "provider_id 格式无效",
+		MsgValueMustBePositive:       "数值必须大于 0",
+		MsgInvalidCleanType:          "类型无效: 必须为 'count' 或 'days'",
+		MsgAuthKeyModelsRequired:     "请至少选择一个允许的模型或启用允许全部模型",
+		MsgInvalidGeminiAction:       "Gemini 模型操作无效",
+		MsgUnsupportedGeminiMethod:   "不支持的 Gemini 方法: %s",
+		MsgModelPermissionDenied:     "该密钥无权使用模型 %s",
+		MsgAuthHeaderMissing:         "缺少 Authorization 请求头",
+		MsgInvalidAuthHeader:         "Authorization 请求头格式无效",
+		MsgInvalidToken:              "令牌无效",
+		MsgAuthKeyMissing:            "缺少鉴权密钥",
+		MsgTokenExpired:              "令牌已过期",
+		MsgInvalidSlot:               "无效的路由分组，仅支持 blue 或 green",
+		MsgParamOutOfRange:           "参数 %s=%s 超出允许范围 %s",
+		MsgInvalidParamRangeMode:     "无效的参数范围模式，仅支持 clamp 或 reject",
+		MsgInvalidHours:              "无效的小时参数",
+		MsgBatchModelMismatch:        "批处理中的所有请求必须使用同一模型",
+		MsgMessageBatchNotFound:      "消息批处理不存在",
+		MsgLogNotFound:               "日志不存在",
+		MsgRouteNotFound:             "路由不存在: %s %s",
+		MsgLogSigningDisabled:        "未开启日志签名，请先设置 LLMIO_LOG_SIGNING_SECRET",
+		MsgRateLimited:               "该 API Key 请求过于频繁，请稍后重试",
+		MsgInvalidRateLimit:          "频率限制无效：rpm 不能为负数，mode 只能为 enforce 或 observe",
+		MsgInvalidIOLogPolicy:        "io_log_policy.sample_rate 必须在 0 到 100 之间",
+		MsgInvalidCACert:             "CA 证书无效：%s",
+		MsgBalanceUnsupported:        "该提供商类型不支持余额查询",
+		MsgInvalidStrategy:           "无效的负载均衡策略：%s",
+		MsgTrustedUserTaken:          "该受信任用户已绑定到其他 Key",
+		MsgTrustedUserUnknown:        "受信任用户 %s 未绑定任何 Key",
+		MsgInvalidBudget:             "预算无效：周期须为 day 或 month，上限不能为负数",
+		MsgInvalidSchedule:           "时间窗口无效：%s",
+		MsgInvalidWebhook:            "回调地址必须是 http 或 https URL",
+		MsgBreakerNotFound:           "该关联没有熔断记录",
+		MsgInvalidBreakerThresholds:  "熔断阈值不能为负数",
+		MsgInvalidRetryPolicy:        "重试退避时间不能为负数，retry_on 必须为 HTTP 状态码",
+		MsgInvalidErrorClass:         "第 %d 条错误分类规则无效：class 须为 quota_exhausted/auth_failed/content_filter/transient，action 须为 reduce_weight/open_breaker/disable_key/fail_fast，且需配置 statuses 或 samples",
+		MsgAdminRateLimited:          "管理接口请求过于频繁，每分钟最多 %d 次",
+		MsgDaysOutOfRange:            "days 需在 0 到 %d 之间",
+		MsgInvalidModelFallback:      "模型转发规则无效：每条规则都需要指定 pattern 与 provider_id",
+		MsgTooManyStreams:            "并发流式请求过多（上限 %d），请稍后重试",
+		MsgInvalidTimeFilter:         "%s 参数无效，必须为 RFC3339 格式",
+		MsgInvalidIOViewLimit:        "limit 参数无效（1-%d 字节）",
+		MsgInvalidRollupPeriod:       "period 参数无效，必须为 hour 或 day",
+		MsgInvalidLatencyGroup:       "by 参数无效，必须为 model 或 provider",
+		MsgRequestBodyTooLarge:       "请求体超过 %d MB 上限",
+		MsgConfigKeyManaged:          "配置 %s 由专用接口管理：%s",
+	},
+	LangZhTW: {
+		MsgInvalidID:                 "ID 格式無效",
+		MsgInvalidRequestBody:        "請求內容無效: %s",
+		MsgInvalidDays:               "天數參數無效",
+		MsgInvalidStrategyFilter:     "負載平衡策略篩選無效",
+		MsgInvalidStatusFilter:       "狀態篩選無效",
+		MsgInvalidAllowAllFilter:     "allow_all 篩選無效",
+		MsgInvalidExpiresAt:          "expires_at 格式無效，必須為 RFC3339",
+		MsgInvalidProviderType:       "提供商類型無效",
+		MsgInvalidConfigFormat:       "設定格式無效",
+		MsgProviderExists:            "提供商已存在",
+		MsgProviderNotFound:          "提供商不存在",
+		MsgModelExists:               "模型 %s 已存在",
+		MsgModelNotFound:             "模型不存在",
+		MsgModelProviderNotFound:     "模型提供商關聯不存在",
+		MsgAuthKeyNotFound:           "金鑰不存在",
+		MsgChatIONotFound:            "輸入輸出紀錄不存在",
+		MsgCountTokensConfigNotFound: "未設定 Anthropic Token 計數",
+		MsgModelIDsEmpty:             "model_ids 不能為空",
+		MsgModelIDsInvalidZero:       "model_ids 包含無效值 0",
+		MsgModelIDDuplicated:         "model_id 重複: %d",
+		MsgModelIDsNotExist:          "model_ids 包含不存在的模型",
+		MsgModelIDRequired:           "缺少 model_id 查詢參數",
+		MsgInvalidModelID:            "model_id 格式無效",
+		MsgProviderStatusParams:      "缺少 provider_id、model_name 或 provider_model 查詢參數",
+		MsgInvalidProviderID:         "provider_id 格式無效",
+		MsgValueMustBePositive:       "數值必須大於 0",
+		MsgInvalidCleanType:          "類型無效: 必須為 'count' 或 'days'",
+		MsgAuthKeyModelsRequired:     "請至少選擇一個允許的模型或啟用允許全部模型",
+		MsgInvalidGeminiAction:       "Gemini 模型操作無效",
+		MsgUnsupportedGeminiMethod:   "不支援的 Gemini 方法: %s",
+		MsgModelPermissionDenied:     "此金鑰無權使用模型 %s",
+		MsgAuthHeaderMissing:         "缺少 Authorization 請求標頭",
+		MsgInvalidAuthHeader:         "Authorization 請求標頭格式無效",
+		MsgInvalidToken:              "權杖無效",
+		MsgAuthKeyMissing:            "缺少驗證金鑰",
+		MsgTokenExpired:              "權杖已過期",
+		MsgInvalidSlot:               "無效的路由分組，僅支援 blue 或 green",
+		MsgParamOutOfRange:           "參數 %s=%s 超出允許範圍 %s",
+		MsgInvalidParamRangeMode:     "無效的參數範圍模式，僅支援 clamp 或 reject",
+		MsgInvalidHours:              "無效的小時參數",
+		MsgBatchModelMismatch:        "批次中的所有請求必須使用同一模型",
+		MsgMessageBatchNotFound:      "訊息批次不存在",
+		MsgLogNotFound:               "日誌不存在",
+		MsgRouteNotFound:             "路由不存在: %s %s",
+		MsgLogSigningDisabled:        "未開啟日誌簽名，請先設定 LLMIO_LOG_SIGNING_SECRET",
+		MsgRateLimited:               "該 API Key 請求過於頻繁，請稍後重試",
+		MsgInvalidRateLimit:          "頻率限制無效：rpm 不能為負數，mode 只能為 enforce 或 observe",
+		MsgInvalidIOLogPolicy:        "io_log_policy.sample_rate 必須介於 0 到 100 之間",
+		MsgInvalidCACert:             "CA 憑證無效：%s",
+		MsgBalanceUnsupported:        "該提供商類型不支援餘額查詢",
+		MsgInvalidStrategy:           "無效的負載均衡策略：%s",
+		MsgTrustedUserTaken:          "該受信任使用者已綁定到其他 Key",
+		MsgTrustedUserUnknown:        "受信任使用者 %s 未綁定任何 Key",
+		MsgInvalidBudget:             "預算無效：週期須為 day 或 month，上限不能為負數",
+		MsgInvalidSchedule:           "時間窗口無效：%s",
+		MsgInvalidWebhook:            "回呼位址必須是 http 或 https URL",
+		MsgBreakerNotFound:           "該關聯沒有熔斷記錄",
+		MsgInvalidBreakerThresholds:  "熔斷閾值不能為負數",
+		MsgInvalidRetryPolicy:        "重試退避時間不能為負數，retry_on 必須為 HTTP 狀態碼",
+		MsgInvalidErrorClass:         "第 %d 條錯誤分類規則無效：class 須為 quota_exhausted/auth_failed/content_filter/transient，action 須為 reduce_weight/open_breaker/disable_key/fail_fast，且需設定 statuses 或 samples",
+		MsgAdminRateLimited:          "管理介面請求過於頻繁，每分鐘最多 %d 次",
+		MsgDaysOutOfRange:            "days 需在 0 到 %d 之間",
+		MsgInvalidModelFallback:      "模型轉發規則無效：每條規則都需要指定 pattern 與 provider_id",
+		MsgTooManyStreams:            "並發串流請求過多（上限 %d），請稍後重試",
+		MsgInvalidTimeFilter:         "%s 參數無效，必須為 RFC3339 格式",
+		MsgInvalidIOViewLimit:        "limit 參數無效（1-%d 位元組）",
+		MsgInvalidRollupPeriod:       "period 參數無效，必須為 hour 或 day",
+		MsgInvalidLatencyGroup:       "by 參數無效，必須為 model 或 provider",
+		MsgRequestBodyTooLarge:       "請求主體超過 %d MB 上限",
+		MsgConfigKeyManaged:          "配置 %s 由專用介面管理：%s",
+	},
+}
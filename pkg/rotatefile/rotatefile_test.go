@@ -0,0 +1,40 @@
+package rotatefile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriterRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mirror", "requests.jsonl")
+	w := New(path, 10, 2)
+	defer w.Close()
+
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{path, "dddddd\n"},
+		{path + ".1", "cccccc\n"},
+		{path + ".2", "bbbbbb\n"},
+	}
+	for _, tt := range tests {
+		got, err := os.ReadFile(tt.name)
+		if err != nil {
+			t.Fatalf("ReadFile(%s): %v", tt.name, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, stat err = %v", err)
+	}
+}
@@ -9,14 +9,19 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
 
+// DefaultAnthropicMaxTokens Anthropic 要求必须传 max_tokens，请求与提供商配置均未指定时使用该值
+const DefaultAnthropicMaxTokens = 4096
+
 type Anthropic struct {
-	BaseURL string `json:"base_url"`
-	APIKey  string `json:"api_key"`
-	Version string `json:"version"`
-	Proxy   string `json:"-"`
+	BaseURL          string        `json:"base_url"`
+	APIKey           string        `json:"api_key"`
+	Version          string        `json:"version"`
+	DefaultMaxTokens int           `json:"default_max_tokens,omitempty"` // 请求未指定 max_tokens 时补全的值
+	Client           ClientOptions `json:"-"`
 }
 
 func (a *Anthropic) BuildReq(ctx context.Context, header http.Header, model string, rawBody []byte) (*http.Request, error) {
@@ -24,6 +29,16 @@ func (a *Anthropic) BuildReq(ctx context.Context, header http.Header, model stri
 	if err != nil {
 		return nil, err
 	}
+	// 许多 OpenAI 风格客户端不传 max_tokens，补全默认值避免上游直接拒绝
+	if gjson.GetBytes(body, "max_tokens").Int() <= 0 {
+		maxTokens := a.DefaultMaxTokens
+		if maxTokens <= 0 {
+			maxTokens = DefaultAnthropicMaxTokens
+		}
+		if body, err = sjson.SetBytes(body, "max_tokens", maxTokens); err != nil {
+			return nil, err
+		}
+	}
 	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/messages", a.BaseURL), bytes.NewReader(body))
 	if err != nil {
 		return nil, err
@@ -59,7 +74,7 @@ func (a *Anthropic) Models(ctx context.Context) ([]Model, error) {
 	req.Header.Set("content-type", "application/json")
 	req.Header.Set("x-api-key", a.APIKey)
 	req.Header.Set("anthropic-version", a.Version)
-	res, err := GetClient(30*time.Second, a.Proxy).Do(req)
+	res, err := GetClient(30*time.Second, a.Client).Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -83,6 +98,18 @@ func (a *Anthropic) Models(ctx context.Context) ([]Model, error) {
 	return modelList.Data, nil
 }
 
+// BuildBatchReq 构建 Message Batches 接口请求，path 为 /messages/batches 之后的部分
+func (a *Anthropic) BuildBatchReq(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/messages/batches%s", a.BaseURL, path), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("x-api-key", a.APIKey)
+	req.Header.Set("anthropic-version", a.Version)
+	return req, nil
+}
+
 func (a *Anthropic) BuildCountTokensReq(ctx context.Context, header http.Header, body io.Reader) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/messages/count_tokens", a.BaseURL), body)
 	if err != nil {
@@ -0,0 +1,34 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestAnthropicBuildReqMaxTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Anthropic
+		body     string
+		want     int64
+	}{
+		{"missing uses builtin default", Anthropic{}, `{"messages":[]}`, DefaultAnthropicMaxTokens},
+		{"missing uses configured default", Anthropic{DefaultMaxTokens: 1024}, `{"messages":[]}`, 1024},
+		{"explicit value kept", Anthropic{DefaultMaxTokens: 1024}, `{"max_tokens":256,"messages":[]}`, 256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := tt.provider.BuildReq(context.Background(), nil, "claude", []byte(tt.body))
+			if err != nil {
+				t.Fatalf("BuildReq: %v", err)
+			}
+			body, _ := io.ReadAll(req.Body)
+			if got := gjson.GetBytes(body, "max_tokens").Int(); got != tt.want {
+				t.Errorf("max_tokens = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Balance 提供商账户剩余额度
+type Balance struct {
+	Remaining float64 `json:"remaining"`
+	Currency  string  `json:"currency"`
+}
+
+// BalanceQuerier 由支持查询账户余额的提供商实现
+type BalanceQuerier interface {
+	Balance(ctx context.Context) (*Balance, error)
+}
+
+// Balance 按厂商调用对应的余额接口，未识别的厂商按 one-api/new-api 兼容的 /dashboard/billing 查询
+func (o *OpenAI) Balance(ctx context.Context) (*Balance, error) {
+	base := strings.TrimRight(o.BaseURL, "/")
+	switch o.vendor() {
+	case VendorDeepSeek:
+		// 余额接口不在 /v1 下
+		u, err := url.Parse(base)
+		if err != nil {
+			return nil, err
+		}
+		res, err := o.getJSON(ctx, u.Scheme+"://"+u.Host+"/user/balance")
+		if err != nil {
+			return nil, err
+		}
+		info := res.Get("balance_infos.0")
+		if !info.Exists() {
+			return nil, fmt.Errorf("unexpected balance response: %s", res.Raw)
+		}
+		return &Balance{Remaining: info.Get("total_balance").Float(), Currency: info.Get("currency").String()}, nil
+	case VendorSiliconFlow:
+		res, err := o.getJSON(ctx, base+"/user/info")
+		if err != nil {
+			return nil, err
+		}
+		return &Balance{Remaining: res.Get("data.totalBalance").Float(), Currency: "CNY"}, nil
+	case VendorOpenRouter:
+		res, err := o.getJSON(ctx, base+"/credits")
+		if err != nil {
+			return nil, err
+		}
+		return &Balance{Remaining: res.Get("data.total_credits").Float() - res.Get("data.total_usage").Float(), Currency: "USD"}, nil
+	}
+
+	subscription, err := o.getJSON(ctx, base+"/dashboard/billing/subscription")
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	usage, err := o.getJSON(ctx, fmt.Sprintf("%s/dashboard/billing/usage?start_date=%s&end_date=%s",
+		base, now.AddDate(0, 0, -99).Format(time.DateOnly), now.AddDate(0, 0, 1).Format(time.DateOnly)))
+	if err != nil {
+		return nil, err
+	}
+	// total_usage 单位为美分
+	return &Balance{
+		Remaining: subscription.Get("hard_limit_usd").Float() - usage.Get("total_usage").Float()/100,
+		Currency:  "USD",
+	}, nil
+}
+
+func (o *OpenAI) getJSON(ctx context.Context, endpoint string) (gjson.Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", o.APIKey))
+	res, err := GetClient(30*time.Second, o.Client).Do(req)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return gjson.Result{}, fmt.Errorf("status code: %d, body: %s", res.StatusCode, string(body))
+	}
+	if !gjson.ValidBytes(body) {
+		return gjson.Result{}, fmt.Errorf("invalid json response: %s", string(body))
+	}
+	return gjson.ParseBytes(body), nil
+}
@@ -0,0 +1,68 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIBalance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/user/balance":
+			w.Write([]byte(`{"is_available":true,"balance_infos":[{"currency":"CNY","total_balance":"12.50"}]}`))
+		case "/v1/user/info":
+			w.Write([]byte(`{"data":{"balance":"1.00","totalBalance":"8.25"}}`))
+		case "/api/v1/credits":
+			w.Write([]byte(`{"data":{"total_credits":20,"total_usage":4.5}}`))
+		case "/v1/dashboard/billing/subscription":
+			w.Write([]byte(`{"hard_limit_usd":100}`))
+		case "/v1/dashboard/billing/usage":
+			if r.URL.Query().Get("start_date") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"total_usage":2550}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		openai   OpenAI
+		want     float64
+		currency string
+		wantErr  bool
+	}{
+		{"deepseek", OpenAI{BaseURL: server.URL + "/v1", Vendor: VendorDeepSeek}, 12.5, "CNY", false},
+		{"siliconflow", OpenAI{BaseURL: server.URL + "/v1", Vendor: VendorSiliconFlow}, 8.25, "CNY", false},
+		{"openrouter", OpenAI{BaseURL: server.URL + "/api/v1", Vendor: VendorOpenRouter}, 15.5, "USD", false},
+		{"dashboard billing", OpenAI{BaseURL: server.URL + "/v1"}, 74.5, "USD", false},
+		{"unsupported", OpenAI{BaseURL: server.URL + "/none"}, 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.openai.APIKey = "sk-test"
+			balance, err := tt.openai.Balance(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", balance)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Balance failed: %v", err)
+			}
+			if balance.Remaining != tt.want || balance.Currency != tt.currency {
+				t.Fatalf("balance = %+v, want %v %s", balance, tt.want, tt.currency)
+			}
+		})
+	}
+}
@@ -1,16 +1,32 @@
 package providers
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
 
+// ClientOptions 提供商级别的连接选项，相同选项的提供商共用连接池
+type ClientOptions struct {
+	Proxy              string // HTTP 代理地址
+	CACert             string // 额外信任的根证书 PEM，用于私有 CA 签发的自建网关
+	InsecureSkipVerify bool   // 跳过证书校验
+	DNSServer          string // 解析上游域名使用的 DNS 服务器，如 1.1.1.1:53，空为系统默认
+	HostOverrides      string // 固定解析，格式 host=ip，逗号分隔，见 FormatHostOverrides
+}
+
 type clientKey struct {
 	timeout time.Duration
-	proxy   string
+	opts    ClientOptions
 }
 
 type clientCache struct {
@@ -27,11 +43,102 @@ var dialer = &net.Dialer{
 	KeepAlive: 30 * time.Second,
 }
 
-// GetClient returns an http.Client with the specified responseHeaderTimeout and proxy.
-// If a client with the same timeout and proxy already exists, it returns the cached one.
+// http2Config 定期发送 PING 检测失效连接，上游 DNS 切换或节点下线后尽快重建连接
+var http2Config = &http.HTTP2Config{
+	SendPingTimeout: 30 * time.Second,
+	PingTimeout:     15 * time.Second,
+}
+
+// FormatHostOverrides 将域名到 IP 的固定解析按域名排序拼接，使相同配置得到相同的客户端缓存键
+func FormatHostOverrides(overrides map[string]string) string {
+	hosts := make([]string, 0, len(overrides))
+	for host, addr := range overrides {
+		host, addr = strings.ToLower(strings.TrimSpace(host)), strings.TrimSpace(addr)
+		if host != "" && addr != "" {
+			hosts = append(hosts, host+"="+addr)
+		}
+	}
+	slices.Sort(hosts)
+	return strings.Join(hosts, ",")
+}
+
+// dialContext 按固定解析替换目标地址，并使用指定的 DNS 服务器解析域名
+func (o ClientOptions) dialContext() func(ctx context.Context, network, address string) (net.Conn, error) {
+	if o.DNSServer == "" && o.HostOverrides == "" {
+		return dialer.DialContext
+	}
+	d := *dialer
+	if o.DNSServer != "" {
+		server := o.DNSServer
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		d.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+	overrides := make(map[string]string)
+	for item := range strings.SplitSeq(o.HostOverrides, ",") {
+		if host, addr, ok := strings.Cut(item, "="); ok {
+			overrides[host] = addr
+		}
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if host, port, err := net.SplitHostPort(address); err == nil {
+			if addr, ok := overrides[strings.ToLower(host)]; ok {
+				address = net.JoinHostPort(addr, port)
+			}
+		}
+		return d.DialContext(ctx, network, address)
+	}
+}
+
+// CloseIdleConnections 关闭所有缓存客户端的空闲连接，之后的请求重新解析域名并建立连接
+func CloseIdleConnections() {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	for _, client := range cache.clients {
+		client.CloseIdleConnections()
+	}
+}
+
+// ParseCACert 解析 PEM 格式的根证书，返回包含系统根证书与该证书的证书池
+func ParseCACert(pem string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM([]byte(pem)) {
+		return nil, errors.New("no valid certificate found in ca_cert")
+	}
+	return pool, nil
+}
+
+func (o ClientOptions) tlsConfig() *tls.Config {
+	if o.CACert == "" && !o.InsecureSkipVerify {
+		return nil
+	}
+	config := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+	if o.CACert != "" {
+		pool, err := ParseCACert(o.CACert)
+		if err != nil {
+			// 证书保存时已校验，此处出错时退回系统根证书
+			slog.Error("parse provider ca cert", "error", err)
+		} else {
+			config.RootCAs = pool
+		}
+	}
+	return config
+}
+
+// GetClient returns an http.Client with the specified responseHeaderTimeout and client options.
+// If a client with the same timeout and options already exists, it returns the cached one.
 // Otherwise, it creates a new client and caches it.
-func GetClient(responseHeaderTimeout time.Duration, proxyURL string) *http.Client {
-	key := clientKey{timeout: responseHeaderTimeout, proxy: proxyURL}
+func GetClient(responseHeaderTimeout time.Duration, opts ClientOptions) *http.Client {
+	key := clientKey{timeout: responseHeaderTimeout, opts: opts}
 
 	cache.mu.RLock()
 	if client, exists := cache.clients[key]; exists {
@@ -49,16 +156,18 @@ func GetClient(responseHeaderTimeout time.Duration, proxyURL string) *http.Clien
 	}
 
 	proxyFunc := http.ProxyFromEnvironment
-	if proxyURL != "" {
-		if u, err := url.Parse(proxyURL); err == nil {
+	if opts.Proxy != "" {
+		if u, err := url.Parse(opts.Proxy); err == nil {
 			proxyFunc = http.ProxyURL(u)
 		}
 	}
 
 	transport := &http.Transport{
 		Proxy:                 proxyFunc,
-		DialContext:           dialer.DialContext,
+		DialContext:           opts.dialContext(),
+		TLSClientConfig:       opts.tlsConfig(),
 		ForceAttemptHTTP2:     true,
+		HTTP2:                 http2Config,
 		MaxIdleConns:          100,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
@@ -0,0 +1,80 @@
+package providers
+
+import (
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetClientTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	tests := []struct {
+		name    string
+		opts    ClientOptions
+		wantErr bool
+	}{
+		{name: "system roots", opts: ClientOptions{}, wantErr: true},
+		{name: "custom ca", opts: ClientOptions{CACert: caCert}},
+		{name: "skip verify", opts: ClientOptions{InsecureSkipVerify: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := GetClient(5*time.Second, tt.opts).Get(server.URL)
+			if tt.wantErr {
+				if err == nil {
+					res.Body.Close()
+					t.Fatal("expected certificate error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			res.Body.Close()
+		})
+	}
+
+	if GetClient(5*time.Second, ClientOptions{CACert: caCert}) == GetClient(5*time.Second, ClientOptions{}) {
+		t.Fatal("clients with different tls options should not be shared")
+	}
+	if _, err := ParseCACert("not a pem"); err == nil {
+		t.Fatal("expected error for invalid pem")
+	}
+}
+
+func TestGetClientHostOverrides(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("parse server url: %v", err)
+	}
+
+	overrides := FormatHostOverrides(map[string]string{" API.Vendor.test ": "127.0.0.1", "b.test": "10.0.0.1", "empty.test": ""})
+	if overrides != "api.vendor.test=127.0.0.1,b.test=10.0.0.1" {
+		t.Fatalf("FormatHostOverrides = %q", overrides)
+	}
+	res, err := GetClient(5*time.Second, ClientOptions{HostOverrides: overrides}).Get("http://api.vendor.test:" + port + "/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	// 只替换连接地址，Host 请求头保持原域名
+	if string(body) != "api.vendor.test:"+port {
+		t.Fatalf("host = %q", body)
+	}
+	CloseIdleConnections()
+}
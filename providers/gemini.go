@@ -16,9 +16,9 @@ import (
 // BaseURL 推荐: https://generativelanguage.googleapis.com/v1beta
 // 通过 POST /models/{model}:generateContent 进行内容生成。
 type Gemini struct {
-	BaseURL string `json:"base_url"`
-	APIKey  string `json:"api_key"`
-	Proxy   string `json:"-"`
+	BaseURL string        `json:"base_url"`
+	APIKey  string        `json:"api_key"`
+	Client  ClientOptions `json:"-"`
 }
 
 func (g *Gemini) BuildReq(ctx context.Context, header http.Header, model string, rawBody []byte) (*http.Request, error) {
@@ -69,7 +69,7 @@ func (g *Gemini) Models(ctx context.Context) ([]Model, error) {
 	req.Header.Set("x-goog-api-key", g.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	res, err := GetClient(30*time.Second, g.Proxy).Do(req)
+	res, err := GetClient(30*time.Second, g.Client).Do(req)
 	if err != nil {
 		return nil, err
 	}
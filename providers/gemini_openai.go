@@ -0,0 +1,40 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// GeminiOpenAI 调用 Gemini 的 OpenAI 兼容端点。
+// BaseURL 推荐: https://generativelanguage.googleapis.com/v1beta/openai
+// 与 OpenAI 协议一致，但模型列表返回带 models/ 前缀的 ID。
+type GeminiOpenAI struct {
+	OpenAI
+}
+
+func (g *GeminiOpenAI) BuildReq(ctx context.Context, header http.Header, model string, rawBody []byte) (*http.Request, error) {
+	o := g.OpenAI
+	o.BaseURL = strings.TrimSuffix(o.BaseURL, "/")
+	return o.BuildReq(ctx, header, strings.TrimPrefix(model, "models/"), rawBody)
+}
+
+func (g *GeminiOpenAI) BuildFormReq(ctx context.Context, header http.Header, model string, body io.Reader, contentType string) (*http.Request, error) {
+	o := g.OpenAI
+	o.BaseURL = strings.TrimSuffix(o.BaseURL, "/")
+	return o.BuildFormReq(ctx, header, strings.TrimPrefix(model, "models/"), body, contentType)
+}
+
+func (g *GeminiOpenAI) Models(ctx context.Context) ([]Model, error) {
+	o := g.OpenAI
+	o.BaseURL = strings.TrimSuffix(o.BaseURL, "/")
+	models, err := o.Models(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range models {
+		models[i].ID = strings.TrimPrefix(models[i].ID, "models/")
+	}
+	return models, nil
+}
@@ -0,0 +1,60 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/tidwall/gjson"
+)
+
+func TestGeminiOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/openai/models" || r.Header.Get("Authorization") != "Bearer gemini-key" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[{"id":"models/gemini-2.5-flash","object":"model","owned_by":"google"},{"id":"gemini-2.5-pro","object":"model","owned_by":"google"}]}`))
+	}))
+	defer server.Close()
+
+	// base_url 末尾的斜杠不应产生双斜杠路径
+	provider, err := New(context.Background(), consts.StyleGeminiOpenAI, `{"base_url":"`+server.URL+`/v1beta/openai/","api_key":"gemini-key"}`, ClientOptions{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	models, err := provider.Models(context.Background())
+	if err != nil {
+		t.Fatalf("Models: %v", err)
+	}
+	if len(models) != 2 || models[0].ID != "gemini-2.5-flash" || models[1].ID != "gemini-2.5-pro" {
+		t.Fatalf("unexpected models: %+v", models)
+	}
+
+	req, err := provider.BuildReq(context.Background(), nil, "models/gemini-2.5-flash", []byte(`{"model":"alias","messages":[]}`))
+	if err != nil {
+		t.Fatalf("BuildReq: %v", err)
+	}
+	if req.URL.String() != server.URL+"/v1beta/openai/chat/completions" {
+		t.Fatalf("unexpected url: %s", req.URL)
+	}
+	if req.Header.Get("Authorization") != "Bearer gemini-key" || req.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected headers: %v", req.Header)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if model := gjson.GetBytes(body, "model").String(); model != "gemini-2.5-flash" {
+		t.Fatalf("model = %q, want models/ prefix stripped", model)
+	}
+
+	ctx := context.WithValue(context.Background(), consts.ContextKeyOpenAIEndpoint, "/embeddings")
+	req, err = provider.BuildReq(ctx, nil, "text-embedding-004", []byte(`{"input":"hi"}`))
+	if err != nil {
+		t.Fatalf("BuildReq embeddings: %v", err)
+	}
+	if req.URL.String() != server.URL+"/v1beta/openai/embeddings" {
+		t.Fatalf("unexpected embeddings url: %s", req.URL)
+	}
+}
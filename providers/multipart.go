@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/atopos31/llmio/consts"
+)
+
+// FormRequestBuilder 由支持 multipart 表单上传的提供商实现，请求体按流转发不整体读入内存
+type FormRequestBuilder interface {
+	BuildFormReq(ctx context.Context, header http.Header, model string, body io.Reader, contentType string) (*http.Request, error)
+}
+
+func (o *OpenAI) BuildFormReq(ctx context.Context, header http.Header, model string, body io.Reader, contentType string) (*http.Request, error) {
+	endpoint, _ := ctx.Value(consts.ContextKeyOpenAIEndpoint).(string)
+	if endpoint == "" {
+		return nil, errors.New("multipart upload requires an endpoint")
+	}
+	pr, formContentType, err := rewriteMultipartModel(body, contentType, model)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", o.BaseURL+endpoint, pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	if header != nil {
+		req.Header = header
+	}
+	req.Header.Set("Content-Type", formContentType)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", o.APIKey))
+	return req, nil
+}
+
+// rewriteMultipartModel 按流替换 multipart 表单中的 model 字段，返回新的请求体与 Content-Type
+func rewriteMultipartModel(body io.Reader, contentType, model string) (io.ReadCloser, string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, "", err
+	}
+	boundary := params["boundary"]
+	if !strings.EqualFold(mediaType, "multipart/form-data") || boundary == "" {
+		return nil, "", errors.New("invalid multipart content type")
+	}
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(copyMultipart(multipart.NewReader(body, boundary), writer, model))
+	}()
+	return pr, writer.FormDataContentType(), nil
+}
+
+func copyMultipart(reader *multipart.Reader, writer *multipart.Writer, model string) error {
+	if err := writer.WriteField("model", model); err != nil {
+		return err
+	}
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if part.FormName() == "model" {
+			continue
+		}
+		w, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, part); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
@@ -0,0 +1,49 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+)
+
+func TestOpenAIBuildFormReq(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("model", "alias")
+	writer.WriteField("prompt", "a cat")
+	part, _ := writer.CreateFormFile("image", "cat.png")
+	part.Write([]byte("\x89PNG"))
+	writer.Close()
+
+	ctx := context.WithValue(context.Background(), consts.ContextKeyOpenAIEndpoint, "/images/edits")
+	o := &OpenAI{BaseURL: "https://api.openai.com/v1", APIKey: "sk"}
+	req, err := o.BuildFormReq(ctx, nil, "gpt-image-1", &body, writer.FormDataContentType())
+	if err != nil {
+		t.Fatalf("BuildFormReq failed: %v", err)
+	}
+	if req.URL.String() != "https://api.openai.com/v1/images/edits" {
+		t.Fatalf("unexpected url: %s", req.URL)
+	}
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("parse rewritten form: %v", err)
+	}
+	if got := req.MultipartForm.Value["model"]; len(got) != 1 || got[0] != "gpt-image-1" {
+		t.Fatalf("model = %v, want [gpt-image-1]", got)
+	}
+	if got := req.FormValue("prompt"); got != "a cat" {
+		t.Fatalf("prompt = %q", got)
+	}
+	file, _, err := req.FormFile("image")
+	if err != nil {
+		t.Fatalf("image part missing: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	if !strings.HasPrefix(string(data), "\x89PNG") {
+		t.Fatalf("image content changed: %q", data)
+	}
+}
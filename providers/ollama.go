@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tidwall/sjson"
+)
+
+// Ollama 调用本地或自建 Ollama 的原生 API。
+// BaseURL 推荐: http://localhost:11434
+// 通过 POST /api/chat 对话，GET /api/tags 获取已拉取的模型；API Key 可选，用于前置了鉴权代理的部署。
+type Ollama struct {
+	BaseURL string        `json:"base_url"`
+	APIKey  string        `json:"api_key,omitempty"`
+	Client  ClientOptions `json:"-"`
+}
+
+func (o *Ollama) BuildReq(ctx context.Context, header http.Header, model string, rawBody []byte) (*http.Request, error) {
+	body, err := sjson.SetBytes(rawBody, "model", model)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(o.BaseURL, "/")+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if header != nil {
+		req.Header = header
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", o.APIKey))
+	}
+	return req, nil
+}
+
+type ollamaTagsResponse struct {
+	Models []ollamaModel `json:"models"`
+}
+
+type ollamaModel struct {
+	Name       string    `json:"name"` // e.g. "llama3.2:latest"
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+func (o *Ollama) Models(ctx context.Context) ([]Model, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(o.BaseURL, "/")+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", o.APIKey))
+	}
+	res, err := GetClient(30*time.Second, o.Client).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d", res.StatusCode)
+	}
+
+	var tags ollamaTagsResponse
+	if err := json.NewDecoder(res.Body).Decode(&tags); err != nil {
+		return nil, err
+	}
+	models := make([]Model, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		models = append(models, Model{
+			ID:      m.Name,
+			Object:  "model",
+			Created: m.ModifiedAt.Unix(),
+			OwnedBy: "ollama",
+		})
+	}
+	return models, nil
+}
@@ -0,0 +1,36 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOllama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"models":[{"name":"llama3.2:latest","modified_at":"2026-01-02T03:04:05Z"}]}`))
+	}))
+	defer server.Close()
+
+	ollama := Ollama{BaseURL: server.URL + "/"}
+	models, err := ollama.Models(context.Background())
+	if err != nil {
+		t.Fatalf("Models: %v", err)
+	}
+	if len(models) != 1 || models[0].ID != "llama3.2:latest" || models[0].OwnedBy != "ollama" {
+		t.Fatalf("unexpected models: %+v", models)
+	}
+
+	req, err := ollama.BuildReq(context.Background(), nil, "llama3.2", []byte(`{"messages":[]}`))
+	if err != nil {
+		t.Fatalf("BuildReq: %v", err)
+	}
+	if req.URL.String() != server.URL+"/api/chat" || req.Header.Get("Authorization") != "" {
+		t.Fatalf("unexpected request: %s %v", req.URL, req.Header)
+	}
+}
@@ -8,13 +8,16 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/atopos31/llmio/consts"
+
 	"github.com/tidwall/sjson"
 )
 
 type OpenAI struct {
-	BaseURL string `json:"base_url"`
-	APIKey  string `json:"api_key"`
-	Proxy   string `json:"-"`
+	BaseURL string        `json:"base_url"`
+	APIKey  string        `json:"api_key"`
+	Vendor  string        `json:"vendor,omitempty"` // 可选，xai/groq/mistral/deepseek/siliconflow/openrouter，为空时按 base_url 识别
+	Client  ClientOptions `json:"-"`
 }
 
 func (o *OpenAI) BuildReq(ctx context.Context, header http.Header, model string, rawBody []byte) (*http.Request, error) {
@@ -22,7 +25,15 @@ func (o *OpenAI) BuildReq(ctx context.Context, header http.Header, model string,
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/chat/completions", o.BaseURL), bytes.NewReader(body))
+	body, err = o.normalizeRequest(model, body)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, _ := ctx.Value(consts.ContextKeyOpenAIEndpoint).(string)
+	if endpoint == "" {
+		endpoint = "/chat/completions"
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", o.BaseURL+endpoint, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +52,7 @@ func (o *OpenAI) Models(ctx context.Context) ([]Model, error) {
 		return nil, err
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", o.APIKey))
-	res, err := GetClient(30*time.Second, o.Proxy).Do(req)
+	res, err := GetClient(30*time.Second, o.Client).Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -13,9 +13,9 @@ import (
 
 // openai responses api
 type OpenAIRes struct {
-	BaseURL string `json:"base_url"`
-	APIKey  string `json:"api_key"`
-	Proxy   string `json:"-"`
+	BaseURL string        `json:"base_url"`
+	APIKey  string        `json:"api_key"`
+	Client  ClientOptions `json:"-"`
 }
 
 func (o *OpenAIRes) BuildReq(ctx context.Context, header http.Header, model string, rawBody []byte) (*http.Request, error) {
@@ -42,7 +42,7 @@ func (o *OpenAIRes) Models(ctx context.Context) ([]Model, error) {
 		return nil, err
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", o.APIKey))
-	res, err := GetClient(30*time.Second, o.Proxy).Do(req)
+	res, err := GetClient(30*time.Second, o.Client).Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,145 @@
+package providers
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// OpenAI 兼容厂商标识，未显式配置 vendor 时按 base_url 主机识别
+const (
+	VendorXAI      = "xai"
+	VendorGroq     = "groq"
+	VendorMistral  = "mistral"
+	VendorDeepSeek = "deepseek"
+	// 以下厂商仅用于余额查询
+	VendorSiliconFlow = "siliconflow"
+	VendorOpenRouter  = "openrouter"
+)
+
+var vendorHosts = map[string]string{
+	"api.x.ai":         VendorXAI,
+	"api.groq.com":     VendorGroq,
+	"api.mistral.ai":   VendorMistral,
+	"api.deepseek.com": VendorDeepSeek,
+
+	"api.siliconflow.cn":  VendorSiliconFlow,
+	"api.siliconflow.com": VendorSiliconFlow,
+	"openrouter.ai":       VendorOpenRouter,
+}
+
+// VendorOfHost 按 base_url 主机识别已知厂商，未知主机返回空字符串
+func VendorOfHost(host string) string {
+	return vendorHosts[host]
+}
+
+// ResponseNormalizer 由需要修正响应格式的提供商实现
+type ResponseNormalizer interface {
+	NormalizeResponse(body io.ReadCloser, stream bool) io.ReadCloser
+}
+
+func (o *OpenAI) vendor() string {
+	if o.Vendor != "" {
+		return o.Vendor
+	}
+	if u, err := url.Parse(o.BaseURL); err == nil {
+		return vendorHosts[u.Hostname()]
+	}
+	return ""
+}
+
+// normalizeRequest 处理各厂商不兼容的请求参数
+func (o *OpenAI) normalizeRequest(model string, body []byte) ([]byte, error) {
+	var err error
+	// 未传 tools 时 OpenAI 兼容接口会拒绝 parallel_tool_calls，DeepSeek 则完全不支持该参数
+	if tools := gjson.GetBytes(body, "tools"); !tools.IsArray() || len(tools.Array()) == 0 || o.vendor() == VendorDeepSeek {
+		if body, err = sjson.DeleteBytes(body, "parallel_tool_calls"); err != nil {
+			return nil, err
+		}
+	}
+	switch o.vendor() {
+	case VendorMistral:
+		// Mistral 使用 any 表示必须调用工具
+		if gjson.GetBytes(body, "tool_choice").String() == "required" {
+			body, err = sjson.SetBytes(body, "tool_choice", "any")
+		}
+	case VendorXAI:
+		// grok 推理模型不支持以下参数，传入会直接报错
+		if strings.HasPrefix(model, "grok-4") || strings.HasPrefix(model, "grok-3-mini") {
+			for _, key := range []string{"presence_penalty", "frequency_penalty", "stop"} {
+				if body, err = sjson.DeleteBytes(body, key); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return body, err
+}
+
+// NormalizeResponse 将厂商特有的 usage 字段还原为标准 OpenAI 格式
+func (o *OpenAI) NormalizeResponse(body io.ReadCloser, stream bool) io.ReadCloser {
+	var normalize func(data []byte) []byte
+	switch o.vendor() {
+	case VendorGroq:
+		normalize = normalizeGroqUsage
+	case VendorDeepSeek:
+		normalize = normalizeDeepSeekUsage
+	default:
+		return body
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer body.Close()
+		if !stream {
+			data, err := io.ReadAll(body)
+			if err == nil {
+				_, err = pw.Write(normalize(data))
+			}
+			pw.CloseWithError(err)
+			return
+		}
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 8*1024), 64*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if data, ok := strings.CutPrefix(string(line), "data: "); ok && data != "[DONE]" {
+				line = append([]byte("data: "), normalize([]byte(data))...)
+			}
+			if _, err := pw.Write(append(line, '\n')); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(scanner.Err())
+	}()
+	return pr
+}
+
+// normalizeGroqUsage Groq 流式响应把 usage 放在 x_groq.usage 中
+func normalizeGroqUsage(data []byte) []byte {
+	usage := gjson.GetBytes(data, "x_groq.usage")
+	if !usage.Exists() || gjson.GetBytes(data, "usage").Exists() {
+		return data
+	}
+	if out, err := sjson.SetRawBytes(data, "usage", []byte(usage.Raw)); err == nil {
+		return out
+	}
+	return data
+}
+
+// normalizeDeepSeekUsage DeepSeek 使用 prompt_cache_hit_tokens 表示缓存命中
+func normalizeDeepSeekUsage(data []byte) []byte {
+	hit := gjson.GetBytes(data, "usage.prompt_cache_hit_tokens")
+	if !hit.Exists() || gjson.GetBytes(data, "usage.prompt_tokens_details.cached_tokens").Exists() {
+		return data
+	}
+	if out, err := sjson.SetBytes(data, "usage.prompt_tokens_details.cached_tokens", hit.Int()); err == nil {
+		return out
+	}
+	return data
+}
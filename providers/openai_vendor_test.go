@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestOpenAIVendorRequestQuirks(t *testing.T) {
+	tests := []struct {
+		name    string
+		openai  OpenAI
+		model   string
+		body    string
+		path    string
+		want    string
+		deleted bool
+	}{
+		{"mistral tool_choice", OpenAI{BaseURL: "https://api.mistral.ai/v1"}, "mistral-large", `{"tool_choice":"required"}`, "tool_choice", "any", false},
+		{"xai reasoning penalties", OpenAI{BaseURL: "https://api.x.ai/v1"}, "grok-4", `{"presence_penalty":1}`, "presence_penalty", "", true},
+		{"explicit vendor", OpenAI{BaseURL: "https://proxy.local/v1", Vendor: VendorMistral}, "m", `{"tool_choice":"required"}`, "tool_choice", "any", false},
+		{"parallel without tools", OpenAI{BaseURL: "https://api.openai.com/v1"}, "gpt", `{"parallel_tool_calls":false}`, "parallel_tool_calls", "", true},
+		{"parallel with tools kept", OpenAI{BaseURL: "https://api.openai.com/v1"}, "gpt", `{"tools":[{"type":"function"}],"parallel_tool_calls":false}`, "parallel_tool_calls", "false", false},
+		{"deepseek parallel", OpenAI{BaseURL: "https://api.deepseek.com"}, "deepseek-chat", `{"tools":[{"type":"function"}],"parallel_tool_calls":false}`, "parallel_tool_calls", "", true},
+		{"plain openai untouched", OpenAI{BaseURL: "https://api.openai.com/v1"}, "gpt", `{"tool_choice":"required"}`, "tool_choice", "required", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := tt.openai.BuildReq(context.Background(), nil, tt.model, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("BuildReq failed: %v", err)
+			}
+			body, _ := io.ReadAll(req.Body)
+			got := gjson.GetBytes(body, tt.path)
+			if tt.deleted {
+				if got.Exists() {
+					t.Fatalf("%s should be removed, body: %s", tt.path, body)
+				}
+				return
+			}
+			if got.String() != tt.want {
+				t.Fatalf("%s = %q, want %q", tt.path, got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIVendorResponseUsage(t *testing.T) {
+	groq := &OpenAI{BaseURL: "https://api.groq.com/openai/v1"}
+	stream := "data: {\"choices\":[],\"x_groq\":{\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2,\"total_tokens\":5}}}\n\ndata: [DONE]\n"
+	out, _ := io.ReadAll(groq.NormalizeResponse(io.NopCloser(strings.NewReader(stream)), true))
+	if !strings.Contains(string(out), `"usage":{"prompt_tokens":3`) || !strings.Contains(string(out), "data: [DONE]") {
+		t.Fatalf("groq usage not normalized: %s", out)
+	}
+
+	deepseek := &OpenAI{BaseURL: "https://api.deepseek.com"}
+	body := `{"usage":{"prompt_tokens":10,"prompt_cache_hit_tokens":6}}`
+	out, _ = io.ReadAll(deepseek.NormalizeResponse(io.NopCloser(strings.NewReader(body)), false))
+	if gjson.GetBytes(out, "usage.prompt_tokens_details.cached_tokens").Int() != 6 {
+		t.Fatalf("deepseek cache tokens not normalized: %s", out)
+	}
+}
@@ -26,36 +26,56 @@ type Provider interface {
 	Models(ctx context.Context) ([]Model, error)
 }
 
-func New(Type, providerConfig, proxy string) (Provider, error) {
+// New 构建提供商，ctx 用于解析密钥引用，Vault 请求随调用方取消
+func New(ctx context.Context, Type, providerConfig string, opts ClientOptions) (Provider, error) {
+	// 密钥引用在构建提供商时解析，实际密钥不落库
+	providerConfig, err := resolveConfigSecrets(ctx, providerConfig)
+	if err != nil {
+		return nil, err
+	}
 	switch Type {
 	case consts.StyleOpenAI:
 		var openai OpenAI
 		if err := json.Unmarshal([]byte(providerConfig), &openai); err != nil {
 			return nil, errors.New("invalid openai config")
 		}
-		openai.Proxy = proxy
+		openai.Client = opts
 		return &openai, nil
 	case consts.StyleOpenAIRes:
 		var openaiRes OpenAIRes
 		if err := json.Unmarshal([]byte(providerConfig), &openaiRes); err != nil {
 			return nil, errors.New("invalid openai-res config")
 		}
-		openaiRes.Proxy = proxy
+		openaiRes.Client = opts
 		return &openaiRes, nil
 	case consts.StyleAnthropic:
 		var anthropic Anthropic
 		if err := json.Unmarshal([]byte(providerConfig), &anthropic); err != nil {
 			return nil, errors.New("invalid anthropic config")
 		}
-		anthropic.Proxy = proxy
+		anthropic.Client = opts
 		return &anthropic, nil
+	case consts.StyleGeminiOpenAI:
+		var geminiOpenAI GeminiOpenAI
+		if err := json.Unmarshal([]byte(providerConfig), &geminiOpenAI); err != nil {
+			return nil, errors.New("invalid gemini-openai config")
+		}
+		geminiOpenAI.Client = opts
+		return &geminiOpenAI, nil
 	case consts.StyleGemini:
 		var gemini Gemini
 		if err := json.Unmarshal([]byte(providerConfig), &gemini); err != nil {
 			return nil, errors.New("invalid gemini config")
 		}
-		gemini.Proxy = proxy
+		gemini.Client = opts
 		return &gemini, nil
+	case consts.StyleOllama:
+		var ollama Ollama
+		if err := json.Unmarshal([]byte(providerConfig), &ollama); err != nil {
+			return nil, errors.New("invalid ollama config")
+		}
+		ollama.Client = opts
+		return &ollama, nil
 	default:
 		return nil, errors.New("unknown provider")
 	}
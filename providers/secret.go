@@ -0,0 +1,132 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atopos31/llmio/pkg/env"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// vaultCacheTTL Vault 密钥缓存时间，避免每次请求都访问 Vault
+const vaultCacheTTL = 5 * time.Minute
+
+// SecretEnvPrefix 允许被 api_key 引用的环境变量前缀，避免管理员借此读取签名密钥、VAULT_TOKEN 等进程变量并发往任意 base_url
+const SecretEnvPrefix = "LLMIO_SECRET_"
+
+// bootstrapKeyEnv 环境变量预置提供商时保存的引用，不受前缀限制
+const bootstrapKeyEnv = "LLMIO_BOOTSTRAP_API_KEY"
+
+var (
+	envRefPattern = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+	vaultClient = &http.Client{Timeout: 10 * time.Second}
+	vaultCache  sync.Map // ref -> vaultSecret
+)
+
+type vaultSecret struct {
+	value  string
+	expiry time.Time
+}
+
+// IsSecretRef 判断配置值是否为密钥引用: ${LLMIO_SECRET_*} 或 vault:<path>#<field>
+func IsSecretRef(value string) bool {
+	return envRefPattern.MatchString(value) || strings.HasPrefix(value, "vault:")
+}
+
+// ResolveSecret 解析密钥引用，非引用值原样返回
+func ResolveSecret(ctx context.Context, value string) (string, error) {
+	if match := envRefPattern.FindStringSubmatch(value); match != nil {
+		if !strings.HasPrefix(match[1], SecretEnvPrefix) && match[1] != bootstrapKeyEnv {
+			return "", fmt.Errorf("environment variable %s is not allowed, only %s* can be referenced", match[1], SecretEnvPrefix)
+		}
+		secret, ok := os.LookupEnv(match[1])
+		if !ok || secret == "" {
+			return "", fmt.Errorf("environment variable %s is not set", match[1])
+		}
+		return secret, nil
+	}
+	if ref, ok := strings.CutPrefix(value, "vault:"); ok {
+		return resolveVault(ctx, ref)
+	}
+	return value, nil
+}
+
+// resolveConfigSecrets 将提供商配置中的 api_key 引用替换为实际密钥，数据库中只保存引用
+func resolveConfigSecrets(ctx context.Context, providerConfig string) (string, error) {
+	apiKey := gjson.Get(providerConfig, "api_key")
+	if apiKey.Type != gjson.String || !IsSecretRef(apiKey.String()) {
+		return providerConfig, nil
+	}
+	secret, err := ResolveSecret(ctx, apiKey.String())
+	if err != nil {
+		return "", fmt.Errorf("resolve api_key: %w", err)
+	}
+	return sjson.Set(providerConfig, "api_key", secret)
+}
+
+// resolveVault 读取 Vault KV 密钥，ref 格式为 <path>#<field>，同时兼容 KV v1 与 v2
+func resolveVault(ctx context.Context, ref string) (string, error) {
+	if cached, ok := vaultCache.Load(ref); ok {
+		if secret := cached.(vaultSecret); time.Now().Before(secret.expiry) {
+			return secret.value, nil
+		}
+	}
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("invalid vault reference %q, want vault:<path>#<field>", ref)
+	}
+	addr := env.GetWithDefault("VAULT_ADDR", "")
+	if addr == "" {
+		return "", errors.New("VAULT_ADDR is not set")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", env.GetWithDefault("VAULT_TOKEN", ""))
+	if namespace := env.GetWithDefault("VAULT_NAMESPACE", ""); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	res, err := vaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("read vault secret: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("read vault secret: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("read vault secret: status %d", res.StatusCode)
+	}
+
+	var secret struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("decode vault secret: %w", err)
+	}
+	data := gjson.ParseBytes(secret.Data)
+	// KV v2 的字段位于 data.data 下
+	if data.Get("data").IsObject() && data.Get("metadata").IsObject() {
+		data = data.Get("data")
+	}
+	value := data.Get(gjson.Escape(field))
+	if value.Type != gjson.String || value.String() == "" {
+		return "", fmt.Errorf("vault secret %s has no field %s", path, field)
+	}
+	vaultCache.Store(ref, vaultSecret{value: value.String(), expiry: time.Now().Add(vaultCacheTTL)})
+	return value.String(), nil
+}
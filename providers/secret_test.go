@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestResolveConfigSecrets(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/llm":
+			w.Write([]byte(`{"data":{"data":{"openai":"sk-v2"},"metadata":{"version":1}}}`))
+		case "/v1/kv/llm":
+			w.Write([]byte(`{"data":{"openai":"sk-v1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+	t.Setenv("LLMIO_SECRET_OPENAI", "sk-env")
+	t.Setenv("LLMIO_LOG_SIGNING_SECRET", "signing")
+
+	tests := []struct {
+		name    string
+		apiKey  string
+		want    string
+		wantErr bool
+	}{
+		{"literal", "sk-literal", "sk-literal", false},
+		{"env", "${LLMIO_SECRET_OPENAI}", "sk-env", false},
+		{"env missing", "${LLMIO_SECRET_MISSING}", "", true},
+		{"env not allowed", "${LLMIO_LOG_SIGNING_SECRET}", "", true},
+		{"vault token not allowed", "${VAULT_TOKEN}", "", true},
+		{"vault kv v2", "vault:secret/data/llm#openai", "sk-v2", false},
+		{"vault kv v1", "vault:kv/llm#openai", "sk-v1", false},
+		{"vault missing field", "vault:kv/llm#anthropic", "", true},
+		{"vault invalid ref", "vault:kv/llm", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := resolveConfigSecrets(context.Background(), `{"base_url":"https://api.openai.com/v1","api_key":"`+tt.apiKey+`"}`)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got config %s", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveConfigSecrets failed: %v", err)
+			}
+			if got := gjson.Get(config, "api_key").String(); got != tt.want {
+				t.Fatalf("api_key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -28,6 +28,15 @@ func GetAuthKey(ctx context.Context, key string) (*models.AuthKey, error) {
 	}
 }
 
+// GetAuthKeyByTrustedUser 按受信任代理传入的用户名查找启用的 Key
+func GetAuthKeyByTrustedUser(ctx context.Context, user string) (*models.AuthKey, error) {
+	authKey, err := gorm.G[models.AuthKey](models.DB).Where("trusted_user = ?", user).Where("status = ?", true).First(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &authKey, nil
+}
+
 type KeyUpdateItem struct {
 	Count  int
 	UsedAt time.Time
@@ -55,17 +64,21 @@ func KeyUpdate(keyID uint, usedAt time.Time) {
 
 func backgroundFlush() {
 	for range time.Tick(10 * time.Second) {
-		ctx := context.Background()
-		mu.Lock()
-		for keyID, item := range updateCounts {
-			if err := models.DB.Model(&models.AuthKey{}).WithContext(ctx).Where("id = ?", keyID).Updates(map[string]any{
-				"usage_count":  gorm.Expr(fmt.Sprintf("usage_count + %d", item.Count)),
-				"last_used_at": item.UsedAt,
-			}).Error; err != nil {
-				slog.Error("Failed to update auth key usage count", "error", err)
-			}
+		FlushKeyUsage(context.Background())
+	}
+}
+
+// FlushKeyUsage 将内存中累计的 Key 使用次数写入数据库，退出前调用避免丢失最后一个周期的计数
+func FlushKeyUsage(ctx context.Context) {
+	mu.Lock()
+	defer mu.Unlock()
+	for keyID, item := range updateCounts {
+		if err := models.DB.Model(&models.AuthKey{}).WithContext(ctx).Where("id = ?", keyID).Updates(map[string]any{
+			"usage_count":  gorm.Expr(fmt.Sprintf("usage_count + %d", item.Count)),
+			"last_used_at": item.UsedAt,
+		}).Error; err != nil {
+			slog.Error("Failed to update auth key usage count", "error", err)
 		}
-		updateCounts = make(map[uint]KeyUpdateItem) // 清空计数
-		mu.Unlock()
 	}
+	updateCounts = make(map[uint]KeyUpdateItem) // 清空计数
 }
@@ -2,6 +2,10 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"slices"
 	"strings"
 
 	"github.com/tidwall/gjson"
@@ -14,6 +18,11 @@ type Before struct {
 	toolCall         bool
 	structuredOutput bool
 	image            bool
+	embedding        bool
+	imageGeneration  bool
+	rerank           bool
+	moderation       bool
+	upload           *Upload // multipart 表单上传，按流转发，此时 raw 为空
 	SessionID        string
 	raw              []byte
 }
@@ -258,3 +267,99 @@ func BeforerAnthropic(data []byte) (*Before, error) {
 		raw:              data,
 	}, nil
 }
+
+// BeforerEmbedding 解析 OpenAI 向量化请求，向量化接口不支持流式
+func BeforerEmbedding(data []byte) (*Before, error) {
+	model := gjson.GetBytes(data, "model").String()
+	if model == "" {
+		return nil, errors.New("model is empty")
+	}
+	return &Before{
+		Model:     model,
+		embedding: true,
+		raw:       data,
+	}, nil
+}
+
+// BeforerImage 解析 JSON 格式的图片生成请求，multipart 表单见 NewImageFormBefore
+func BeforerImage(data []byte) (*Before, error) {
+	model := gjson.GetBytes(data, "model").String()
+	if model == "" {
+		return nil, errors.New("model is empty")
+	}
+	return &Before{
+		Model:           model,
+		Stream:          gjson.GetBytes(data, "stream").Bool(),
+		imageGeneration: true,
+		raw:             data,
+	}, nil
+}
+
+// multipartFields 按流读取 multipart 表单中指定的文本字段，跳过文件内容
+func multipartFields(r io.Reader, boundary string, names ...string) (map[string]string, error) {
+	fields := make(map[string]string, len(names))
+	reader := multipart.NewReader(r, boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return fields, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse multipart form: %w", err)
+		}
+		if !slices.Contains(names, part.FormName()) {
+			continue
+		}
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("read form field %s: %w", part.FormName(), err)
+		}
+		fields[part.FormName()] = string(value)
+	}
+}
+
+// BeforerRerank 解析 Cohere/Jina 兼容的重排序请求
+func BeforerRerank(data []byte) (*Before, error) {
+	model := gjson.GetBytes(data, "model").String()
+	if model == "" {
+		return nil, errors.New("model is empty")
+	}
+	return &Before{
+		Model:  model,
+		rerank: true,
+		raw:    data,
+	}, nil
+}
+
+// size 请求体大小
+func (b *Before) size() int {
+	if b.upload != nil {
+		return int(b.upload.Size())
+	}
+	return len(b.raw)
+}
+
+// defaultModerationModel 审核请求未指定模型时使用的模型名，与 OpenAI 默认值一致
+const defaultModerationModel = "omni-moderation-latest"
+
+// BeforerModeration 解析 OpenAI 兼容的内容审核请求
+func BeforerModeration(data []byte) (*Before, error) {
+	model := gjson.GetBytes(data, "model").String()
+	if model == "" {
+		model = defaultModerationModel
+		var err error
+		if data, err = sjson.SetBytes(data, "model", model); err != nil {
+			return nil, err
+		}
+	}
+	return &Before{
+		Model:      model,
+		moderation: true,
+		raw:        data,
+	}, nil
+}
+
+// endpoint 请求是否为向量化、重排序等非对话端点
+func (b *Before) endpoint() bool {
+	return b.embedding || b.imageGeneration || b.rerank || b.moderation
+}
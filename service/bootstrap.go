@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/env"
+	"github.com/atopos31/llmio/providers"
+	"gorm.io/gorm"
+)
+
+const (
+	bootstrapMaxRetry = 10
+	bootstrapTimeOut  = 60
+	// bootstrapKeyRef 密钥以环境变量引用保存，实际密钥不落库
+	bootstrapKeyRef = "${LLMIO_BOOTSTRAP_API_KEY}"
+)
+
+// BootstrapConfig 首次启动时通过环境变量预置的提供商与模型
+type BootstrapConfig struct {
+	ProviderType string
+	ProviderName string
+	BaseURL      string
+	APIKey       string
+	Models       []string // 模型名，name=upstream 表示对外名称与上游模型不同
+}
+
+// BootstrapConfigFromEnv 读取 LLMIO_BOOTSTRAP_* 环境变量
+func BootstrapConfigFromEnv() BootstrapConfig {
+	cfg := BootstrapConfig{
+		ProviderType: env.GetWithDefault("LLMIO_BOOTSTRAP_PROVIDER_TYPE", ""),
+		ProviderName: env.GetWithDefault("LLMIO_BOOTSTRAP_PROVIDER_NAME", ""),
+		BaseURL:      env.GetWithDefault("LLMIO_BOOTSTRAP_BASE_URL", ""),
+		APIKey:       env.GetWithDefault("LLMIO_BOOTSTRAP_API_KEY", ""),
+	}
+	for name := range strings.SplitSeq(env.GetWithDefault("LLMIO_BOOTSTRAP_MODELS", ""), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.Models = append(cfg.Models, name)
+		}
+	}
+	return cfg
+}
+
+// Bootstrap 数据库中没有任何提供商时按配置创建提供商、模型与关联，返回是否执行了预置
+func Bootstrap(ctx context.Context, cfg BootstrapConfig) (bool, error) {
+	if cfg.ProviderType == "" {
+		return false, nil
+	}
+	if cfg.BaseURL == "" || len(cfg.Models) == 0 {
+		return false, errors.New("LLMIO_BOOTSTRAP_BASE_URL and LLMIO_BOOTSTRAP_MODELS are required")
+	}
+	// 包含已删除与已归档的提供商，避免清空后重启又被重新创建
+	count, err := gorm.G[models.Provider](models.DB.Unscoped()).Count(ctx, "id")
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	config := map[string]any{"base_url": cfg.BaseURL, "api_key": ""}
+	if cfg.APIKey != "" {
+		config["api_key"] = bootstrapKeyRef
+	}
+	if cfg.ProviderType == consts.StyleAnthropic {
+		config["version"] = "2023-06-01"
+	}
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return false, err
+	}
+	if _, err := providers.New(ctx, cfg.ProviderType, string(raw), providers.ClientOptions{}); err != nil {
+		return false, fmt.Errorf("invalid bootstrap provider: %w", err)
+	}
+	name := cfg.ProviderName
+	if name == "" {
+		name = cfg.ProviderType
+	}
+
+	err = models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		provider := models.Provider{Name: name, Type: cfg.ProviderType, Config: string(raw)}
+		if err := tx.Create(&provider).Error; err != nil {
+			return fmt.Errorf("create provider: %w", err)
+		}
+		for i, item := range cfg.Models {
+			modelName, upstream, ok := strings.Cut(item, "=")
+			if !ok {
+				upstream = modelName
+			}
+			model := models.Model{
+				Name:             modelName,
+				MaxRetry:         bootstrapMaxRetry,
+				TimeOut:          bootstrapTimeOut,
+				Strategy:         consts.BalancerDefault,
+				Breaker:          new(false),
+				ValidateResponse: new(false),
+				DisplayOrder:     len(cfg.Models) - i,
+			}
+			if err := tx.Where("name = ?", modelName).FirstOrCreate(&model).Error; err != nil {
+				return fmt.Errorf("create model %s: %w", modelName, err)
+			}
+			association := models.ModelWithProvider{
+				ModelID:          model.ID,
+				ProviderModel:    upstream,
+				ProviderID:       provider.ID,
+				ToolCall:         new(true),
+				StructuredOutput: new(true),
+				Image:            new(false),
+				Chat:             new(true),
+				Embedding:        new(false),
+				ImageGeneration:  new(false),
+				Rerank:           new(false),
+				Moderation:       new(false),
+				WithHeader:       new(false),
+				PseudoStream:     new(false),
+				StreamAggregate:  new(false),
+				Status:           new(true),
+				CustomerHeaders:  map[string]string{},
+				ExtraBody:        map[string]any{},
+				Weight:           1,
+				Priority:         1,
+				InputPrice:       new(0.0),
+				CacheReadPrice:   new(0.0),
+				OutputPrice:      new(0.0),
+				Currency:         "CNY",
+				Slot:             ActiveSlot(),
+			}
+			if err := tx.Create(&association).Error; err != nil {
+				return fmt.Errorf("create association %s: %w", modelName, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	slog.Info("bootstrap provider created", "provider", name, "type", cfg.ProviderType, "models", cfg.Models)
+	return true, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/tidwall/gjson"
+	"gorm.io/gorm"
+)
+
+func TestBootstrap(t *testing.T) {
+	db := setupTestDB(t, &models.Provider{}, &models.Model{}, &models.ModelWithProvider{})
+	ctx := context.Background()
+
+	t.Setenv("LLMIO_BOOTSTRAP_PROVIDER_TYPE", consts.StyleOpenAI)
+	t.Setenv("LLMIO_BOOTSTRAP_BASE_URL", "https://api.example.com/v1")
+	t.Setenv("LLMIO_BOOTSTRAP_API_KEY", "sk-secret")
+	t.Setenv("LLMIO_BOOTSTRAP_MODELS", "gpt-4o, fast=gpt-4o-mini")
+	cfg := BootstrapConfigFromEnv()
+
+	created, err := Bootstrap(ctx, cfg)
+	if err != nil || !created {
+		t.Fatalf("Bootstrap = %v, %v, want created", created, err)
+	}
+	provider, err := gorm.G[models.Provider](db).First(ctx)
+	if err != nil {
+		t.Fatalf("load provider: %v", err)
+	}
+	if provider.Name != consts.StyleOpenAI || gjson.Get(provider.Config, "api_key").String() != bootstrapKeyRef {
+		t.Fatalf("unexpected provider: %+v", provider)
+	}
+	fast, err := gorm.G[models.Model](db).Where("name = ?", "fast").First(ctx)
+	if err != nil {
+		t.Fatalf("load model: %v", err)
+	}
+	mp, err := gorm.G[models.ModelWithProvider](db).Where("model_id = ?", fast.ID).First(ctx)
+	if err != nil || mp.ProviderModel != "gpt-4o-mini" || mp.Weight != 1 {
+		t.Fatalf("unexpected association: %+v, %v", mp, err)
+	}
+
+	// 已有提供商时不再重复预置
+	created, err = Bootstrap(ctx, cfg)
+	if err != nil || created {
+		t.Fatalf("second Bootstrap = %v, %v, want skipped", created, err)
+	}
+	if count, _ := gorm.G[models.Provider](db).Count(ctx, "id"); count != 1 {
+		t.Fatalf("provider count = %d, want 1", count)
+	}
+
+	if _, err := Bootstrap(ctx, BootstrapConfig{ProviderType: consts.StyleOpenAI}); err == nil {
+		t.Fatal("expected error without base url and models")
+	}
+}
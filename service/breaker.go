@@ -0,0 +1,143 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/atopos31/llmio/balancers"
+	"github.com/atopos31/llmio/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// breakerChanges 待持久化的熔断状态变化，由单个协程按顺序写库
+var breakerChanges = make(chan balancers.NodeStatus, 256)
+
+// BreakerStatus 未关闭的熔断器
+type BreakerStatus struct {
+	ModelProviderID uint       `json:"model_provider_id"`
+	ModelName       string     `json:"model_name"`
+	ProviderName    string     `json:"provider_name"`
+	ProviderModel   string     `json:"provider_model"`
+	State           string     `json:"state"`
+	FailCount       int        `json:"fail_count"`
+	Expiry          *time.Time `json:"expiry,omitempty"` // Open 状态的冷却结束时间
+}
+
+// StartBreakerPersistence 恢复上次运行时的熔断状态，并持久化之后的每次状态变化
+func StartBreakerPersistence(ctx context.Context) error {
+	saved, err := gorm.G[models.BreakerNode](models.DB).Find(ctx)
+	if err != nil {
+		return err
+	}
+	for _, node := range saved {
+		balancers.Restore(balancers.NodeStatus{
+			Key:       node.ModelProviderID,
+			State:     balancers.ParseState(node.State),
+			FailCount: node.FailCount,
+			Expiry:    node.Expiry,
+		})
+	}
+	balancers.OnStateChange = func(status balancers.NodeStatus) {
+		select {
+		case breakerChanges <- status:
+		default:
+			slog.Warn("breaker state change dropped", "model_provider_id", status.Key, "state", status.State.String())
+		}
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case status := <-breakerChanges:
+				if err := saveBreakerNode(ctx, status); err != nil {
+					slog.Error("save breaker state", "model_provider_id", status.Key, "error", err)
+				}
+				if status.State == balancers.StateClosed {
+					WarmUp(status.Key)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// saveBreakerNode 写入熔断状态，关闭时删除记录
+func saveBreakerNode(ctx context.Context, status balancers.NodeStatus) error {
+	if status.State == balancers.StateClosed {
+		_, err := gorm.G[models.BreakerNode](models.DB).Where("model_provider_id = ?", status.Key).Delete(ctx)
+		return err
+	}
+	return gorm.G[models.BreakerNode](models.DB, clause.OnConflict{UpdateAll: true}).Create(ctx, &models.BreakerNode{
+		ModelProviderID: status.Key,
+		State:           status.State.String(),
+		FailCount:       status.FailCount,
+		Expiry:          status.Expiry,
+	})
+}
+
+// breakerConfig 将模型的熔断阈值转换为熔断器配置，未设置的字段使用默认值
+func breakerConfig(thresholds *models.BreakerThresholds) balancers.BreakerConfig {
+	if thresholds == nil {
+		return balancers.BreakerConfig{}
+	}
+	return balancers.BreakerConfig{
+		MaxFailures: thresholds.MaxFailures,
+		SleepWindow: time.Duration(thresholds.SleepWindow) * time.Second,
+		MaxRequests: thresholds.MaxRequests,
+	}
+}
+
+// GetBreakerStatus 列出处于 Open 或 HalfOpen 的关联
+func GetBreakerStatus(ctx context.Context) ([]BreakerStatus, error) {
+	list := make([]BreakerStatus, 0)
+	var ids []uint
+	for _, node := range balancers.Nodes() {
+		if node.State == balancers.StateClosed {
+			continue
+		}
+		status := BreakerStatus{ModelProviderID: node.Key, State: node.State.String(), FailCount: node.FailCount}
+		if node.State == balancers.StateOpen {
+			status.Expiry = new(node.Expiry)
+		}
+		list = append(list, status)
+		ids = append(ids, node.Key)
+	}
+	if len(list) == 0 {
+		return list, nil
+	}
+	associations, err := gorm.G[models.ModelWithProvider](models.DB).Where("id IN ?", ids).Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	modelList, err := gorm.G[models.Model](models.DB).Where("id IN ?", lo.Map(associations, func(mp models.ModelWithProvider, _ int) uint { return mp.ModelID })).Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	providerList, err := gorm.G[models.Provider](models.DB).Where("id IN ?", lo.Map(associations, func(mp models.ModelWithProvider, _ int) uint { return mp.ProviderID })).Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	modelMap := lo.KeyBy(modelList, func(m models.Model) uint { return m.ID })
+	providerMap := lo.KeyBy(providerList, func(p models.Provider) uint { return p.ID })
+	byID := lo.KeyBy(associations, func(mp models.ModelWithProvider) uint { return mp.ID })
+	for i := range list {
+		if mp, ok := byID[list[i].ModelProviderID]; ok {
+			list[i].ModelName = modelMap[mp.ModelID].Name
+			list[i].ProviderName = providerMap[mp.ProviderID].Name
+			list[i].ProviderModel = mp.ProviderModel
+		}
+	}
+	slices.SortFunc(list, func(a, b BreakerStatus) int { return cmp.Compare(a.ModelProviderID, b.ModelProviderID) })
+	return list, nil
+}
+
+// ResetBreaker 手动关闭关联的熔断器，关联未被熔断器记录时返回 false
+func ResetBreaker(id uint) bool {
+	return balancers.ResetNode(id)
+}
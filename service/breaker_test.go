@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/balancers"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+func TestSaveBreakerNode(t *testing.T) {
+	db := setupTestDB(t, &models.BreakerNode{})
+	ctx := context.Background()
+
+	expiry := time.Now().Add(time.Minute)
+	for _, status := range []balancers.NodeStatus{
+		{Key: 1, State: balancers.StateOpen, FailCount: 3, Expiry: expiry},
+		{Key: 1, State: balancers.StateHalfOpen},
+		{Key: 2, State: balancers.StateOpen, FailCount: 5, Expiry: expiry},
+		{Key: 2, State: balancers.StateClosed},
+	} {
+		if err := saveBreakerNode(ctx, status); err != nil {
+			t.Fatalf("saveBreakerNode(%+v) failed: %v", status, err)
+		}
+	}
+	saved, err := gorm.G[models.BreakerNode](db).Find(ctx)
+	if err != nil {
+		t.Fatalf("load breaker nodes: %v", err)
+	}
+	if len(saved) != 1 || saved[0].ModelProviderID != 1 || saved[0].State != "half_open" {
+		t.Fatalf("saved = %+v, want only association 1 half_open", saved)
+	}
+}
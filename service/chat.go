@@ -8,12 +8,15 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/atopos31/llmio/balancers"
 	"github.com/atopos31/llmio/consts"
 	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/env"
 	"github.com/atopos31/llmio/pkg/token"
 	"github.com/atopos31/llmio/providers"
 	"github.com/samber/lo"
@@ -21,165 +24,394 @@ import (
 	"gorm.io/gorm"
 )
 
-func BalanceChat(ctx context.Context, start time.Time, style string, before Before, providersWithMeta ProvidersWithMeta, reqMeta models.ReqMeta) (*http.Response, *models.ChatLog, error) {
-	slog.Info("request", "model", before.Model, "stream", before.Stream, "tool_call", before.toolCall, "structured_output", before.structuredOutput, "image", before.image)
+var (
+	ErrModelNotFound = errors.New("not found model")
+	ErrNoProvider    = errors.New("not provider for model")
+	ErrRetryTimeout  = errors.New("retry time out")
+)
+
+// UpstreamError 所有提供商均尝试失败，Status 为最后一次上游返回的状态码，未收到响应时为 0
+type UpstreamError struct {
+	Status int
+	Err    error
+}
+
+func (e *UpstreamError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// retryAllowed 剩余时间不足 reserve 秒时不再发起重试，保证客户端在超时前收到响应；首次请求不受限制
+func retryAllowed(retry int, deadline time.Time, reserve int) bool {
+	return retry == 0 || reserve <= 0 || time.Until(deadline) >= time.Second*time.Duration(reserve)
+}
+
+func BalanceChat(ctx context.Context, start time.Time, style string, before Before, providersWithMeta ProvidersWithMeta, reqMeta models.ReqMeta) (_ *http.Response, _ *models.ChatLog, err error) {
+	requestID, _ := ctx.Value(consts.ContextKeyRequestID).(string)
+	slog.Info("request", "request_id", requestID, "model", before.Model, "stream", before.Stream, "tool_call", before.toolCall, "structured_output", before.structuredOutput, "image", before.image)
 
 	providerMap := providersWithMeta.ProviderMap
 
 	// 收集重试过程中的err日志
 	retryLog := make(chan models.ChatLog, providersWithMeta.MaxRetry)
+	// 最终失败时由 RecordRetryLog 为最后一次尝试发送 webhook，需在关闭通道前写入
+	var failed atomic.Bool
 	defer close(retryLog)
+	defer func() { failed.Store(err != nil) }()
 
-	go RecordRetryLog(context.Background(), retryLog)
+	go RecordRetryLog(context.Background(), retryLog, &before, providersWithMeta.Webhook, &failed)
 
-	// 选择负载均衡策略
-	var balancer balancers.Balancer
-	switch providersWithMeta.Strategy {
-	case consts.BalancerLottery:
-		balancer = balancers.NewLottery(providersWithMeta.WeightItems)
-	case consts.BalancerRotor:
-		balancer = balancers.NewRotor(providersWithMeta.WeightItems)
-	default:
-		balancer = balancers.NewLottery(providersWithMeta.WeightItems)
+	// 选择负载均衡策略，按优先级分层，高优先级全部失败后才使用低优先级
+	balancer := balancers.New(providersWithMeta.Strategy, providersWithMeta.WeightItems, providersWithMeta.PriorityItems)
+
+	authKeyID, _ := ctx.Value(consts.ContextKeyAuthKeyID).(uint)
+
+	// 会话粘滞：同一会话优先使用上次成功的关联以命中上游提示词缓存，失败时回落到原策略
+	var sticky string
+	if providersWithMeta.StickyMinutes > 0 {
+		sticky = stickyKey(authKeyID, before)
+		if id, ok := stickyRoute(sticky, time.Now()); ok {
+			if _, ok := providersWithMeta.WeightItems[id]; ok {
+				balancer = balancers.WithPreferred(balancer, id)
+			}
+		}
 	}
 
 	// 是否开启熔断
 	if providersWithMeta.Breaker {
-		balancer = balancers.BalancerWrapperBreaker(balancer)
+		balancer = balancers.BalancerWrapperBreaker(balancer, providersWithMeta.BreakerConfig)
 	}
 
-	// 设置请求超时
-	responseHeaderTimeout := time.Second * time.Duration(providersWithMeta.TimeOut)
-	// 流式超时时间缩短
-	if before.Stream {
-		responseHeaderTimeout = responseHeaderTimeout / 3
-	}
-
-	authKeyID, _ := ctx.Value(consts.ContextKeyAuthKeyID).(uint)
 	authKeyIOLog, _ := ctx.Value(consts.ContextKeyAuthKeyIOLog).(bool)
+	// 同一请求的重试共用一次采样结果，原始透传不记录 IO
+	ioLog := authKeyIOLog && !providersWithMeta.RawPassthrough && sampleIOLog(providersWithMeta.IOLogPolicy)
 
 	traceID, err := token.GenerateRandomChars(10)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	timer := time.NewTimer(time.Second * time.Duration(providersWithMeta.TimeOut))
+	events := newTimeline(start)
+	events.add(models.TimelineEvent{Stage: StageRouted})
+
+	// 最后一次上游返回的非 200 状态码，全部失败时用于决定返回给客户端的状态码
+	var lastStatus int
+	// 最后一次失败为首字节超时时的错误，全部失败时返回给客户端
+	var lastTimeout error
+	deadline := time.Now().Add(time.Second * time.Duration(providersWithMeta.TimeOut))
+	timer := time.NewTimer(time.Until(deadline))
 	defer timer.Stop()
-	for retry := range providersWithMeta.MaxRetry {
+	// 上游失败次数与下一次重试前的退避等待
+	var failures int
+	var backoff time.Duration
+	// prepare 为选中的关联构建上游请求，返回 nil 表示该关联不可用且已移除待选；cancelable 为 true 时请求可单独取消
+	prepare := func(ctx context.Context, id uint, retry int, cancelable bool) (*attempt, error) {
+		modelWithProvider, ok := providersWithMeta.ModelWithProviderMap[id]
+		if !ok {
+			// 数据不一致，移除该模型避免下次重复命中
+			balancer.Delete(id)
+			return nil, nil
+		}
+
+		provider := providerMap[modelWithProvider.ProviderID]
+
+		chatModel, err := providers.New(ctx, provider.Type, provider.Config, provider.ClientOptions())
+		if err != nil {
+			return nil, err
+		}
+
+		convertible := streamConvertible(style) && before.upload == nil
+		// 伪流式: 上游按非流式请求，完整响应再切分为 SSE 返回客户端
+		pseudo := before.Stream && lo.FromPtrOr(modelWithProvider.PseudoStream, false) && convertible
+		// 流式聚合: 上游按流式请求，合并为完整 JSON 返回非流式客户端
+		aggregate := !before.Stream && lo.FromPtrOr(modelWithProvider.StreamAggregate, false) && convertible
+		upstreamStream := (before.Stream && !pseudo) || aggregate
+		resStream := before.Stream && !pseudo
+
+		// 设置请求超时，流式超时时间缩短
+		responseHeaderTimeout := time.Second * time.Duration(providersWithMeta.TimeOut)
+		if upstreamStream {
+			responseHeaderTimeout = responseHeaderTimeout / 3
+		}
+		client := providers.GetClient(responseHeaderTimeout, provider.ClientOptions())
+
+		slog.Info("using provider", "request_id", requestID, "provider", provider.Name, "config_version", provider.ConfigVersion, "model", modelWithProvider.ProviderModel)
+
+		log := models.ChatLog{
+			Name:            before.Model,
+			TraceID:         traceID,
+			RequestID:       requestID,
+			ModelProviderID: modelWithProvider.ID,
+			ProviderModel:   modelWithProvider.ProviderModel,
+			ProviderName:    provider.Name,
+			Status:          consts.StatusRunning,
+			Style:           style,
+			UserAgent:       reqMeta.UserAgent,
+			RemoteIP:        reqMeta.RemoteIP,
+			AuthKeyID:       authKeyID,
+			SessionID:       before.SessionID,
+			ChatIO:          ioLog,
+			Strategy:        providersWithMeta.Strategy,
+			Retry:           retry,
+			ProxyTime:       time.Since(start),
+			RequestSize:     before.size(),
+			InputPrice:      lo.FromPtrOr(modelWithProvider.InputPrice, 0),
+			CacheReadPrice:  lo.FromPtrOr(modelWithProvider.CacheReadPrice, 0),
+			OutputPrice:     lo.FromPtrOr(modelWithProvider.OutputPrice, 0),
+			Currency:        modelWithProvider.Currency,
+		}
+		// 并发已满时在排队时间内等待空闲名额，超时则跳过该关联；先于频率与配额检查，避免占用用不到的名额
+		release, ok := acquireInflight(ctx, id, modelWithProvider.MaxInFlight, time.Duration(modelWithProvider.QueueTimeout)*time.Millisecond)
+		if !ok {
+			retryLog <- events.failed(log, http.StatusServiceUnavailable, fmt.Errorf("%w: provider %s", ErrProviderSaturated, provider.Name))
+			balancer.Delete(id)
+			return nil, nil
+		}
+		// 之后任一步骤失败都未发出请求，归还并发名额
+		prepared := false
+		defer func() {
+			if !prepared {
+				release()
+			}
+		}()
+		// 提供商频率超限时视为本地 429，换下一个提供商
+		if !takeProviderRate(provider, time.Now()) {
+			lastStatus = http.StatusTooManyRequests
+			lastTimeout = nil
+			retryLog <- events.failed(log, http.StatusTooManyRequests, fmt.Errorf("%w: provider %s", ErrRateLimited, provider.Name))
+			balancer.Delete(id)
+			return nil, nil
+		}
+		// 关联配额用尽时主动跳过，避免触发上游 429
+		if !takeQuota(id, modelWithProvider.QuotaRPM, modelWithProvider.QuotaTPM, time.Now()) {
+			lastStatus = http.StatusTooManyRequests
+			lastTimeout = nil
+			retryLog <- events.failed(log, http.StatusTooManyRequests, fmt.Errorf("%w: provider %s", ErrQuotaExceeded, provider.Name))
+			balancer.Delete(id)
+			return nil, nil
+		}
+		// 根据请求原始请求头 是否透传请求头 自定义请求头 构建新的请求头
+		withHeader := lo.FromPtrOr(modelWithProvider.WithHeader, false)
+		headers := BuildHeaders(reqMeta.Header, withHeader, MergeProviderHeaders(provider, modelWithProvider.CustomerHeaders), upstreamStream, provider.UserAgent)
+
+		rawBody := before.raw
+		translator := providersWithMeta.translatorFor(provider.Type)
+		if translator != nil {
+			rawBody, err = translator.Request(rawBody, upstreamStream)
+			if err != nil {
+				return nil, fmt.Errorf("translate request: %w", err)
+			}
+		}
+		switch {
+		case pseudo:
+			if rawBody, err = disableUpstreamStream(rawBody, provider.Type); err != nil {
+				return nil, fmt.Errorf("disable upstream stream: %w", err)
+			}
+		case aggregate:
+			if rawBody, err = enableUpstreamStream(rawBody, provider.Type); err != nil {
+				return nil, fmt.Errorf("enable upstream stream: %w", err)
+			}
+		}
+		// Gemini 通过请求路径区分是否流式
+		reqCtx := ctx
+		if provider.Type == consts.StyleGemini {
+			reqCtx = context.WithValue(ctx, consts.ContextKeyGeminiStream, upstreamStream)
+		}
+		// 注入 ExtraBody 参数到请求体，multipart 表单不支持
+		if len(modelWithProvider.ExtraBody) > 0 && before.upload == nil {
+			for key, value := range modelWithProvider.ExtraBody {
+				rawBody, err = sjson.SetBytes(rawBody, key, value)
+				if err != nil {
+					slog.Warn("failed to set extra body key", "key", key, "error", err)
+				}
+			}
+		}
+
+		cancel := context.CancelFunc(func() {})
+		if cancelable {
+			reqCtx, cancel = context.WithCancel(reqCtx)
+		}
+		var req *http.Request
+		if before.upload != nil {
+			req, err = buildUploadReq(ctx, chatModel, headers, modelWithProvider.ProviderModel, before.upload)
+		} else {
+			req, err = chatModel.BuildReq(reqCtx, headers, modelWithProvider.ProviderModel, rawBody)
+		}
+		if err != nil {
+			cancel()
+			retryLog <- events.failed(log, 0, err)
+			// 构建请求失败 移除待选
+			balancer.Delete(id)
+			return nil, nil
+		}
+		ApplyQueryParams(req, provider.QueryParams)
+		prepared = true
+		return &attempt{
+			id:                    id,
+			provider:              provider,
+			chatModel:             chatModel,
+			translator:            translator,
+			pseudo:                pseudo,
+			aggregate:             aggregate,
+			resStream:             resStream,
+			responseHeaderTimeout: responseHeaderTimeout,
+			client:                client,
+			req:                   req,
+			cancel:                cancel,
+			release:               release,
+			log:                   log,
+		}, nil
+	}
+
+	// requestFailed 记录未收到响应的失败并移除该关联
+	requestFailed := func(at *attempt, err error) {
+		var timeout bool
+		err, timeout = firstByteTimeout(err, at.provider.Name, at.responseHeaderTimeout)
+		lastTimeout = lo.Ternary(timeout, err, nil)
+		retryLog <- events.failed(at.log, 0, err)
+		balancer.Delete(at.id)
+	}
+	// statusFailed 处理上游非 200 响应：记录日志，按错误分类或状态码调整待选与提供商状态；
+	// 返回非 nil 表示不应重试，直接返回给客户端
+	statusFailed := func(at *attempt, res *http.Response) error {
+		byteBody, err := io.ReadAll(res.Body)
+		if err != nil {
+			slog.Error("read body error", "error", err)
+		}
+		lastStatus = res.StatusCode
+		lastTimeout = nil
+		res.Body.Close()
+
+		// 命中错误分类时按分类动作处理，否则沿用按状态码的默认处理
+		if class, action, ok := classifyError(at.provider.ErrorClasses, res.StatusCode, string(byteBody)); ok {
+			reason := fmt.Sprintf("%s: status: %d, body: %s", class, res.StatusCode, string(byteBody))
+			retryLog <- events.failed(at.log, res.StatusCode, errors.New(reason))
+			if applyErrorAction(ctx, action, balancer, at.id, at.provider, providersWithMeta.BreakerConfig, reason) {
+				return &UpstreamError{Status: res.StatusCode, Err: fmt.Errorf("%s, trace ID: %s", reason, traceID)}
+			}
+			return nil
+		}
+		retryLog <- events.failed(at.log, res.StatusCode, fmt.Errorf("status: %d, body: %s", res.StatusCode, string(byteBody)))
+
+		if isAuthFailure(res.StatusCode, string(byteBody)) {
+			recordAuthFailure(ctx, at.provider, res.StatusCode, string(byteBody))
+		}
+		if res.StatusCode == http.StatusTooManyRequests {
+			// 达到RPM限制 降低权重
+			balancer.Reduce(at.id)
+		} else {
+			// 非RPM限制 移除待选
+			balancer.Delete(at.id)
+		}
+		// 不在可重试状态码内的错误(如 400)直接返回给客户端
+		if !retryableStatus(providersWithMeta.RetryPolicy, res.StatusCode) {
+			return &UpstreamError{Status: res.StatusCode, Err: fmt.Errorf("status: %d, body: %s, trace ID: %s", res.StatusCode, string(byteBody), traceID)}
+		}
+		return nil
+	}
+
+	for retry := 0; retry < providersWithMeta.MaxRetry; retry++ {
 		select {
 		case <-ctx.Done():
 			return nil, nil, ctx.Err()
 		case <-timer.C:
-			return nil, nil, errors.New("retry time out")
+			return nil, nil, ErrRetryTimeout
 		default:
+			if !retryAllowed(retry, deadline, providersWithMeta.RetryReserve) {
+				return nil, nil, fmt.Errorf("%w: less than %ds remaining, traceID: %s", ErrRetryTimeout, providersWithMeta.RetryReserve, traceID)
+			}
+			if backoff > 0 {
+				if err := waitRetry(ctx, timer.C, backoff); err != nil {
+					return nil, nil, err
+				}
+				backoff = 0
+			}
 			// 加权负载均衡
 			id, err := balancer.Pop()
 			if err != nil {
-				return nil, nil, fmt.Errorf("balancer pop err: %v, traceID: %s", err, traceID)
+				return nil, nil, allFailedError(lastStatus, lastTimeout, fmt.Errorf("balancer pop err: %v, traceID: %s", err, traceID))
 			}
 
-			modelWithProvider, ok := providersWithMeta.ModelWithProviderMap[id]
-			if !ok {
-				// 数据不一致，移除该模型避免下次重复命中
-				balancer.Delete(id)
-				continue
-			}
-
-			provider := providerMap[modelWithProvider.ProviderID]
-
-			chatModel, err := providers.New(provider.Type, provider.Config, provider.Proxy)
+			// 对冲请求仅用于首次尝试，multipart 表单无法重复发送
+			hedging := retry == 0 && providersWithMeta.HedgeAfter > 0 && before.upload == nil
+			at, err := prepare(ctx, id, retry, hedging)
 			if err != nil {
 				return nil, nil, err
 			}
-
-			client := providers.GetClient(responseHeaderTimeout, provider.Proxy)
-
-			slog.Info("using provider", "provider", provider.Name, "model", modelWithProvider.ProviderModel)
-
-			log := models.ChatLog{
-				Name:           before.Model,
-				TraceID:        traceID,
-				ProviderModel:  modelWithProvider.ProviderModel,
-				ProviderName:   provider.Name,
-				Status:         consts.StatusRunning,
-				Style:          style,
-				UserAgent:      reqMeta.UserAgent,
-				RemoteIP:       reqMeta.RemoteIP,
-				AuthKeyID:      authKeyID,
-				SessionID:      before.SessionID,
-				ChatIO:         authKeyIOLog,
-				Retry:          retry,
-				ProxyTime:      time.Since(start),
-				InputPrice:     lo.FromPtrOr(modelWithProvider.InputPrice, 0),
-				CacheReadPrice: lo.FromPtrOr(modelWithProvider.CacheReadPrice, 0),
-				OutputPrice:    lo.FromPtrOr(modelWithProvider.OutputPrice, 0),
-				Currency:       modelWithProvider.Currency,
+			if at == nil {
+				// 并发、频率或配额不足时在本地跳过，未发出请求，不占用重试次数；该关联已移除待选，循环终会结束
+				retry--
+				continue
 			}
-			// 根据请求原始请求头 是否透传请求头 自定义请求头 构建新的请求头
-			withHeader := lo.FromPtrOr(modelWithProvider.WithHeader, false)
-			headers := BuildHeaders(reqMeta.Header, withHeader, modelWithProvider.CustomerHeaders, before.Stream)
-
-			// 注入 ExtraBody 参数到请求体
-			rawBody := before.raw
-			if len(modelWithProvider.ExtraBody) > 0 {
-				for key, value := range modelWithProvider.ExtraBody {
-					rawBody, err = sjson.SetBytes(rawBody, key, value)
-					if err != nil {
-						slog.Warn("failed to set extra body key", "key", key, "error", err)
+			events.add(models.TimelineEvent{Stage: StageAttempt, Attempt: retry + 1, Provider: at.provider.Name})
+			var res *http.Response
+			if hedging {
+				next := func() *attempt {
+					return nextHedge(providersWithMeta, id, func(id uint) (*attempt, error) {
+						return prepare(ctx, id, retry, true)
+					}, events, retry)
+				}
+				winner, failed := doHedged(at, time.Duration(providersWithMeta.HedgeAfter)*time.Millisecond, next)
+				// 先失败的一方与普通尝试同样处理，另一方仍在进行，因此不据此中止请求
+				for _, r := range failed {
+					if r.err != nil {
+						requestFailed(r.at, r.err)
+						continue
 					}
+					_ = statusFailed(r.at, r.res)
 				}
+				at, res, err = winner.at, winner.res, winner.err
+				id = at.id
+			} else {
+				res, err = at.do()
 			}
-
-			req, err := chatModel.BuildReq(ctx, headers, modelWithProvider.ProviderModel, rawBody)
+			provider, chatModel, translator, log := at.provider, at.chatModel, at.translator, at.log
+			pseudo, aggregate, resStream := at.pseudo, at.aggregate, at.resStream
 			if err != nil {
-				retryLog <- log.WithError(err)
-				// 构建请求失败 移除待选
-				balancer.Delete(id)
-				continue
-			}
-
-			res, err := client.Do(req)
-			if err != nil {
-				retryLog <- log.WithError(err)
-				// 请求失败 移除待选
-				balancer.Delete(id)
+				requestFailed(at, err)
+				failures++
+				backoff = retryDelay(providersWithMeta.RetryPolicy, failures, nil, time.Now(), deadline)
 				continue
 			}
 
 			if res.StatusCode != http.StatusOK {
-				byteBody, err := io.ReadAll(res.Body)
-				if err != nil {
-					slog.Error("read body error", "error", err)
+				if err := statusFailed(at, res); err != nil {
+					return nil, nil, err
 				}
-				retryLog <- log.WithError(fmt.Errorf("status: %d, body: %s", res.StatusCode, string(byteBody)))
-
-				if res.StatusCode == http.StatusTooManyRequests {
-					// 达到RPM限制 降低权重
-					balancer.Reduce(id)
-				} else {
-					// 非RPM限制 移除待选
-					balancer.Delete(id)
-				}
-				res.Body.Close()
+				failures++
+				backoff = retryDelay(providersWithMeta.RetryPolicy, failures, res.Header, time.Now(), deadline)
 				continue
 			}
 
-			if provider.ErrorMatcher != "" {
+			if provider.ErrorMatcher != "" || len(provider.ErrorClasses) > 0 {
 				contentType := strings.ToLower(res.Header.Get("Content-Type"))
-				// 流式正常返回通常是 text/event-stream，不提前消费响应体避免影响转发。
-				if !strings.Contains(contentType, "text/event-stream") {
+				// 流式正常返回通常是 text/event-stream（Ollama 为 application/x-ndjson），不提前消费响应体避免影响转发。
+				if !strings.Contains(contentType, "text/event-stream") && !strings.Contains(contentType, "application/x-ndjson") {
 					byteBody, err := io.ReadAll(res.Body)
 					if err != nil {
-						retryLog <- log.WithError(fmt.Errorf("read body failed: %w", err))
+						retryLog <- events.failed(log, res.StatusCode, fmt.Errorf("read body failed: %w", err))
 						balancer.Delete(id)
 						res.Body.Close()
 						continue
 					}
 
+					// 已完整读取，关闭原响应体以释放连接与并发名额
+					res.Body.Close()
+					// 部分渠道以 200 返回错误，同样按错误分类处理
+					if class, action, ok := classifyError(provider.ErrorClasses, res.StatusCode, string(byteBody)); ok {
+						reason := fmt.Sprintf("%s: status: %d, body: %s", class, res.StatusCode, string(byteBody))
+						retryLog <- events.failed(log, res.StatusCode, errors.New(reason))
+						if applyErrorAction(ctx, action, balancer, id, provider, providersWithMeta.BreakerConfig, reason) {
+							return nil, nil, &UpstreamError{Status: http.StatusBadRequest, Err: fmt.Errorf("%s, trace ID: %s", reason, traceID)}
+						}
+						continue
+					}
 					if matched, sample := matchProviderBodyError(string(byteBody), provider.ErrorMatcher); matched {
-						retryLog <- log.WithError(fmt.Errorf("response matched provider error sample %q, body: %s", sample, string(byteBody)))
+						retryLog <- events.failed(log, res.StatusCode, fmt.Errorf("response matched provider error sample %q, body: %s", sample, string(byteBody)))
 						balancer.Delete(id)
-						res.Body.Close()
 						continue
 					}
 
@@ -187,42 +419,208 @@ func BalanceChat(ctx context.Context, start time.Time, style string, before Befo
 				}
 			}
 
+			if aggregate {
+				byteBody, err := aggregateStream(res.Body, provider.Type)
+				res.Body.Close()
+				if err != nil {
+					retryLog <- events.failed(log, res.StatusCode, fmt.Errorf("aggregate stream: %w", err))
+					balancer.Delete(id)
+					continue
+				}
+				res.Body = io.NopCloser(bytes.NewReader(byteBody))
+				res.Header.Del("Content-Length")
+				res.Header.Set("Content-Type", "application/json")
+			}
+
+			// 部分渠道会以 200 返回空结果或截断的 JSON，按失败处理以触发重试
+			if providersWithMeta.ValidateResponse && !resStream {
+				byteBody, err := io.ReadAll(res.Body)
+				res.Body.Close()
+				if err == nil {
+					err = validateCompletion(style, provider.Type, byteBody)
+				}
+				if err != nil {
+					retryLog <- events.failed(log, res.StatusCode, fmt.Errorf("invalid response: %w, body: %s", err, string(byteBody)))
+					balancer.Delete(id)
+					continue
+				}
+				res.Body = io.NopCloser(bytes.NewReader(byteBody))
+			}
+
+			// 缓冲流式响应开头，尚未向客户端发送任何内容时上游中断可换提供商重试
+			if resStream && providersWithMeta.StreamBuffer > 0 {
+				body, err := bufferStreamHead(res.Body, providersWithMeta.StreamBuffer)
+				if err != nil {
+					retryLog <- events.failed(log, res.StatusCode, fmt.Errorf("stream interrupted before first flush: %w", err))
+					balancer.Delete(id)
+					continue
+				}
+				res.Body = body
+			}
+
 			balancer.Success(id)
+			if sticky != "" {
+				rememberSticky(sticky, id, time.Duration(providersWithMeta.StickyMinutes)*time.Minute, time.Now())
+			}
+			resetAuthFailures(provider.ID)
+			events.add(models.TimelineEvent{Stage: StageResponse, Attempt: retry + 1, Provider: provider.Name, Status: res.StatusCode})
+			log.Timeline = events.snapshot()
+
+			// 部分聚合商在响应头中返回本次请求费用
+			if cost, ok := parseCostHeader(res.Header, provider.CostHeaders); ok {
+				log.Cost = &cost
+			}
+
+			// 修正厂商特有的响应格式
+			if normalizer, ok := chatModel.(providers.ResponseNormalizer); ok {
+				if body := normalizer.NormalizeResponse(res.Body, resStream); body != res.Body {
+					res.Body = body
+					res.Header.Del("Content-Length")
+				}
+			}
+
+			if translator != nil {
+				res.Body = translator.Response(res.Body, resStream, before.Model)
+				res.Header.Del("Content-Length")
+				res.Header.Set("Content-Type", translator.ContentType(resStream))
+			}
+
+			if pseudo {
+				res.Body = pseudoStream(res.Body, style)
+				res.Header.Del("Content-Length")
+				res.Header.Set("Content-Type", "text/event-stream")
+			}
 
 			return res, &log, nil
 		}
 	}
 
-	return nil, nil, fmt.Errorf("All retry failed, trace ID: %s", traceID)
+	return nil, nil, allFailedError(lastStatus, lastTimeout, fmt.Errorf("All retry failed, trace ID: %s", traceID))
+}
+
+// attempt 一次发往上游的请求及其处理方式
+type attempt struct {
+	id                    uint
+	provider              models.Provider
+	chatModel             providers.Provider
+	translator            *Translator
+	pseudo                bool
+	aggregate             bool
+	resStream             bool
+	responseHeaderTimeout time.Duration
+	client                *http.Client
+	req                   *http.Request
+	cancel                context.CancelFunc // 取消本次请求，仅对冲请求会使用
+	release               func()             // 释放关联的并发名额
+	log                   models.ChatLog
 }
 
-func RecordRetryLog(ctx context.Context, retryLog chan models.ChatLog) {
+// do 发送请求，请求失败或响应体关闭时释放并发名额
+func (a *attempt) do() (*http.Response, error) {
+	res, err := a.client.Do(a.req)
+	if err != nil {
+		a.release()
+		return nil, err
+	}
+	res.Body = &releaseOnClose{ReadCloser: res.Body, release: a.release}
+	return res, nil
+}
+
+// RecordRetryLog 保存重试失败日志，请求最终失败时为最后一条日志发送 webhook
+func RecordRetryLog(ctx context.Context, retryLog chan models.ChatLog, before *Before, webhook string, failed *atomic.Bool) {
+	var lastID uint
 	for log := range retryLog {
-		if _, err := SaveChatLog(ctx, log); err != nil {
+		done := beginLogWrite()
+		start := time.Now()
+		id, err := SaveChatLog(ctx, log)
+		observeLogFlush(start, err)
+		if err != nil {
 			slog.Error("save chat log error", "error", err)
+		} else {
+			mirrorLog(ctx, id, before)
+			lastID = id
 		}
+		done()
+	}
+	if failed.Load() && lastID != 0 {
+		// 失败尝试不记录输入输出
+		dispatchWebhook(ctx, webhook, lastID, before, false)
 	}
 }
 
-func RecordLog(ctx context.Context, reqStart time.Time, reader io.ReadCloser, processer Processer, logId uint, before Before, ioLog bool) {
+func RecordLog(ctx context.Context, reqStart time.Time, reader io.ReadCloser, processer Processer, logId uint, modelProviderID uint, before Before, ioLog bool, redact []string, timelineEvents []models.TimelineEvent, webhook string) {
+	defer beginLogWrite()()
+	events := &timeline{start: reqStart, events: slices.Clone(timelineEvents)}
+	// 日志写库从更新 ChatLog 开始计时，所有写入结束后统计一次
+	var flushStart time.Time
 	recordFunc := func() error {
 		defer reader.Close()
 		if ioLog {
+			input := redactIOInput(string(before.raw), redact)
+			if before.upload != nil {
+				// multipart 表单包含二进制文件，不记录原文
+				input = fmt.Sprintf("[multipart form, %d bytes]", before.size())
+			}
 			if err := gorm.G[models.ChatIO](models.DB).Create(ctx, &models.ChatIO{
-				Input: string(before.raw),
+				Input: input,
 				LogId: logId,
 			}); err != nil {
 				return err
 			}
 		}
-		log, output, err := processer(ctx, reader, before.Stream, reqStart)
+		blocked := &blockedReader{r: reader}
+		counter := &countingReader{r: blocked}
+		log, output, err := processer(ctx, counter, before.Stream, reqStart)
 		if err != nil {
 			return err
 		}
+		// 处理器可能提前结束读取，读完剩余内容以统计完整响应大小，同时避免阻塞客户端转发
+		if _, err := io.Copy(io.Discard, counter); err != nil {
+			slog.Warn("drain response body", "logId", logId, "error", err)
+		}
+		log.Size = counter.n
+		recordQuotaTokens(modelProviderID, log.TotalTokens, time.Now())
+		log.ChunkCount = 1
+		if before.Stream {
+			log.ChunkCount = len(output.OfStringArray)
+		}
 		log.Status = consts.StatusSuccess
+		if log.Error != "" {
+			// 上游在流中返回错误事件，计入熔断失败次数
+			log.Status = consts.StatusError
+			balancers.ReportFailure(modelProviderID)
+		}
+		switch {
+		case errors.Is(blocked.err, ErrClientCanceled):
+			// 客户端中途断开，不计入提供商失败
+			log.Status = consts.StatusCanceled
+			log.Error = blocked.err.Error()
+		case blocked.err != nil:
+			// 响应违规被中止，保留已转发部分的用量
+			log.Status = consts.StatusError
+			log.Error = blocked.err.Error()
+			log.FinishReason = FinishContentFilter
+		}
+		if log.Status == consts.StatusSuccess && modelProviderID > 0 {
+			balancers.ObserveLatency(modelProviderID, log.FirstChunkTime)
+		}
+		events.addAt(reqStart.Add(log.FirstChunkTime), models.TimelineEvent{Stage: StageFirstChunk})
+		events.add(models.TimelineEvent{Stage: StageDone})
+		log.Timeline = events.events
+		flushStart = time.Now()
 		if _, err := gorm.G[models.ChatLog](models.DB).Where("id = ?", logId).Updates(ctx, *log); err != nil {
 			return err
 		}
+		signChatLog(ctx, logId)
+		publishTail(TailEvent{
+			Type:         TailFinish,
+			LogID:        logId,
+			Model:        before.Model,
+			TotalTokens:  log.TotalTokens,
+			FirstChunkMs: log.FirstChunkTime.Milliseconds(),
+			ElapsedMs:    time.Since(reqStart).Milliseconds(),
+			At:           time.Now(),
+		})
 		if ioLog {
 			if _, err := gorm.G[models.ChatIO](models.DB).Where("log_id = ?", logId).Updates(ctx, models.ChatIO{OutputUnion: *output}); err != nil {
 				return err
@@ -230,29 +628,67 @@ func RecordLog(ctx context.Context, reqStart time.Time, reader io.ReadCloser, pr
 		}
 		return nil
 	}
+	var flushErr error
 	if err := recordFunc(); err != nil {
-		if _, err := gorm.G[models.ChatLog](models.DB).Where("id = ?", logId).Updates(ctx, models.ChatLog{
-			Status: consts.StatusError,
-			Error:  err.Error(),
-		}); err != nil {
-			slog.Error("record log error", "error", err)
+		events.add(models.TimelineEvent{Stage: StageStreamError, Error: err.Error()})
+		publishTail(TailEvent{Type: TailError, LogID: logId, Model: before.Model, Error: err.Error(), ElapsedMs: time.Since(reqStart).Milliseconds(), At: time.Now()})
+		if flushStart.IsZero() {
+			flushStart = time.Now()
 		}
+		_, flushErr = gorm.G[models.ChatLog](models.DB).Where("id = ?", logId).Updates(ctx, models.ChatLog{
+			Status:   consts.StatusError,
+			Error:    err.Error(),
+			Timeline: events.events,
+		})
+		if flushErr != nil {
+			slog.Error("record log error", "error", flushErr)
+		}
+		signChatLog(ctx, logId)
 	}
+	observeLogFlush(flushStart, flushErr)
+	mirrorLog(ctx, logId, &before)
+	dispatchWebhook(ctx, webhook, logId, &before, ioLog)
+}
+
+// countingReader 统计已读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
 }
 
 func SaveChatLog(ctx context.Context, log models.ChatLog) (uint, error) {
 	if err := gorm.G[models.ChatLog](models.DB).Create(ctx, &log); err != nil {
 		return 0, err
 	}
+	signChatLog(ctx, log.ID)
+	publishLogTail(log.ID, log)
 	return log.ID, nil
 }
 
-func BuildHeaders(source http.Header, withHeader bool, customHeaders map[string]string, stream bool) http.Header {
+func BuildHeaders(source http.Header, withHeader bool, customHeaders map[string]string, stream bool, userAgent string) http.Header {
 	header := http.Header{}
 	if withHeader {
 		header = source.Clone()
 	}
 
+	// 提供商单独配置优先，透传请求头时保留客户端 UA，否则使用全局默认值
+	switch userAgent {
+	case consts.UserAgentPassthrough:
+		header.Set("User-Agent", source.Get("User-Agent"))
+	case "":
+		if !withHeader {
+			header.Set("User-Agent", DefaultUserAgent())
+		}
+	default:
+		header.Set("User-Agent", userAgent)
+	}
+
 	if stream {
 		header.Set("X-Accel-Buffering", "no")
 	}
@@ -260,6 +696,7 @@ func BuildHeaders(source http.Header, withHeader bool, customHeaders map[string]
 	header.Del("Authorization")
 	header.Del("X-Api-Key")
 	header.Del("X-Goog-Api-Key")
+	header.Del(consts.StrategyHeader)
 
 	for key, value := range customHeaders {
 		header.Set(key, value)
@@ -268,20 +705,71 @@ func BuildHeaders(source http.Header, withHeader bool, customHeaders map[string]
 	return header
 }
 
+// MergeProviderHeaders 合并提供商默认请求头与关联自定义请求头，同名时关联配置优先
+func MergeProviderHeaders(provider models.Provider, customHeaders map[string]string) map[string]string {
+	return lo.Assign(provider.Headers, customHeaders)
+}
+
+// ApplyQueryParams 将提供商配置的查询参数写入上游请求地址，同名参数以配置为准
+func ApplyQueryParams(req *http.Request, params map[string]string) {
+	if len(params) == 0 {
+		return
+	}
+	query := req.URL.Query()
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	req.URL.RawQuery = query.Encode()
+}
+
+// DefaultUserAgent 发往上游的默认 User-Agent，可通过 LLMIO_USER_AGENT 覆盖
+func DefaultUserAgent() string {
+	return env.GetWithDefault("LLMIO_USER_AGENT", "llmio/"+consts.Version)
+}
+
 type ProvidersWithMeta struct {
 	ModelWithProviderMap map[uint]models.ModelWithProvider
 	WeightItems          map[uint]int
+	PriorityItems        map[uint]int // 关联 ID 对应的优先级
 	ProviderMap          map[uint]models.Provider
 	MaxRetry             int
 	TimeOut              int
+	RetryReserve         int
+	StickyMinutes        int
+	HedgeAfter           int
+	StreamBuffer         int
+	Webhook              string
 	Strategy             string
 	Breaker              bool
+	BreakerConfig        balancers.BreakerConfig
+	RetryPolicy          *models.RetryPolicy
+	ValidateResponse     bool
+	RawPassthrough       bool
+	ParamRanges          *models.ParamRanges
+	Deprecation          *models.Deprecation // 模型已弃用时非空
+	IOLogPolicy          *models.IOLogPolicy
+	ResponseGuard        *models.ResponseGuard
+	Translator           *Translator // 非空时表示需要协议转换
+	// MixedTranslators 按提供商类型需要单独转换的提供商，与原生提供商混合调度
+	MixedTranslators map[string]*Translator
+}
+
+// translatorFor 返回请求该提供商时使用的协议转换，无需转换时返回 nil
+func (p *ProvidersWithMeta) translatorFor(providerType string) *Translator {
+	if t, ok := p.MixedTranslators[providerType]; ok {
+		return t
+	}
+	return p.Translator
 }
 
 func ProvidersWithMetaBymodelsName(ctx context.Context, style string, before Before) (*ProvidersWithMeta, error) {
-	model, err := gorm.G[models.Model](models.DB).Where("name = ?", before.Model).First(ctx)
+	model, err := routeModel(ctx, before.Model)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 未知模型按转发规则原样发给指定提供商
+			if meta, ok, err := fallbackProvidersWithMeta(ctx, style, before); err != nil || ok {
+				return meta, err
+			}
 			if _, err := SaveChatLog(ctx, models.ChatLog{
 				Name:      before.Model,
 				Status:    consts.StatusError,
@@ -291,61 +779,102 @@ func ProvidersWithMetaBymodelsName(ctx context.Context, style string, before Bef
 			}); err != nil {
 				return nil, err
 			}
-			return nil, errors.New("not found model " + before.Model)
+			return nil, fmt.Errorf("%w %s", ErrModelNotFound, before.Model)
 		}
 		return nil, err
 	}
 
-	modelWithProviderChain := gorm.G[models.ModelWithProvider](models.DB).Where("model_id = ?", model.ID).Where("status = ?", true)
-
-	if before.toolCall {
-		modelWithProviderChain = modelWithProviderChain.Where("tool_call = ?", true)
-	}
-
-	if before.structuredOutput {
-		modelWithProviderChain = modelWithProviderChain.Where("structured_output = ?", true)
-	}
-
-	if before.image {
-		modelWithProviderChain = modelWithProviderChain.Where("image = ?", true)
-	}
-
-	modelWithProviders, err := modelWithProviderChain.Find(ctx)
+	modelWithProviders, err := routeAssociations(ctx, model.ID)
 	if err != nil {
 		return nil, err
 	}
+	modelWithProviders = routeCandidates(modelWithProviders, before)
+	// 不在可用时间窗口内的关联不参与负载均衡
+	now := time.Now()
+	modelWithProviders = lo.Filter(modelWithProviders, func(mp models.ModelWithProvider, _ int) bool {
+		return inSchedule(mp.Schedule, now)
+	})
+	// 预热中的关联暂不分配真实请求，全部在预热时仍照常使用
+	if ready := lo.Reject(modelWithProviders, func(mp models.ModelWithProvider, _ int) bool { return isWarming(mp.ID) }); len(ready) > 0 {
+		modelWithProviders = ready
+	}
 
 	if len(modelWithProviders) == 0 {
-		return nil, errors.New("not provider for model " + before.Model)
+		return nil, fmt.Errorf("%w %s", ErrNoProvider, before.Model)
 	}
+	return buildProvidersWithMeta(ctx, style, model, modelWithProviders)
+}
 
+// buildProvidersWithMeta 查询关联的提供商并按模型配置组装路由信息
+func buildProvidersWithMeta(ctx context.Context, style string, model models.Model, modelWithProviders []models.ModelWithProvider) (*ProvidersWithMeta, error) {
 	modelWithProviderMap := lo.KeyBy(modelWithProviders, func(mp models.ModelWithProvider) uint { return mp.ID })
 
-	providers, err := gorm.G[models.Provider](models.DB).
-		Where("id IN ?", lo.Map(modelWithProviders, func(mp models.ModelWithProvider, _ int) uint { return mp.ProviderID })).
-		Where("type = ?", style).
-		Find(ctx)
+	providerIDs := lo.Map(modelWithProviders, func(mp models.ModelWithProvider, _ int) uint { return mp.ProviderID })
+	providers, err := providersByTypes(ctx, providerIDs, append(consts.ProviderTypes(style), lo.Keys(mixedTranslators[style])...))
 	if err != nil {
 		return nil, err
 	}
+	// 没有原生协议的提供商时，尝试协议转换
+	var translator *Translator
+	for _, t := range translators[style] {
+		if len(providers) > 0 {
+			break
+		}
+		providers, err = providersByTypes(ctx, providerIDs, t.ProviderTypes)
+		if err != nil {
+			return nil, err
+		}
+		if len(providers) > 0 {
+			translator = t
+		}
+	}
 
 	providerMap := lo.KeyBy(providers, func(p models.Provider) uint { return p.ID })
 
 	weightItems := make(map[uint]int)
+	priorityItems := make(map[uint]int)
 	for _, mp := range modelWithProviders {
 		if _, ok := providerMap[mp.ProviderID]; !ok {
 			continue
 		}
 		weightItems[mp.ID] = mp.Weight
+		priorityItems[mp.ID] = mp.Priority
 	}
 
 	return &ProvidersWithMeta{
 		ModelWithProviderMap: modelWithProviderMap,
 		WeightItems:          weightItems,
+		PriorityItems:        priorityItems,
 		ProviderMap:          providerMap,
 		MaxRetry:             model.MaxRetry,
 		TimeOut:              model.TimeOut,
+		RetryReserve:         model.RetryReserve,
+		StickyMinutes:        model.StickyMinutes,
+		HedgeAfter:           model.HedgeAfter,
+		StreamBuffer:         model.StreamBuffer,
+		Webhook:              model.Webhook,
 		Strategy:             model.Strategy,
 		Breaker:              lo.FromPtrOr(model.Breaker, false),
+		BreakerConfig:        breakerConfig(model.BreakerThresholds),
+		RetryPolicy:          model.RetryPolicy,
+		ValidateResponse:     lo.FromPtrOr(model.ValidateResponse, false),
+		RawPassthrough:       lo.FromPtrOr(model.RawPassthrough, false),
+		ParamRanges:          model.ParamRanges,
+		Deprecation:          lo.Ternary(model.Deprecated(), model.Deprecation, nil),
+		IOLogPolicy:          model.IOLogPolicy,
+		ResponseGuard:        model.ResponseGuard,
+		Translator:           translator,
+		MixedTranslators:     mixedTranslators[style],
 	}, nil
 }
+
+func providersByTypes(ctx context.Context, ids []uint, types []string) ([]models.Provider, error) {
+	list, err := routeProviders(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	// 只保留可处理该协议的类型，本周期预算用尽的提供商在重置前不参与路由
+	return lo.Reject(list, func(provider models.Provider, _ int) bool {
+		return !slices.Contains(types, provider.Type) || budgetExhausted(ctx, provider)
+	}), nil
+}
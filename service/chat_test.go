@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+func TestBuildHeadersUserAgent(t *testing.T) {
+	source := http.Header{}
+	source.Set("User-Agent", "codex/1.0")
+
+	tests := []struct {
+		name       string
+		withHeader bool
+		userAgent  string
+		custom     map[string]string
+		want       string
+	}{
+		{"default", false, "", nil, "llmio/" + consts.Version},
+		{"with header keeps client", true, "", nil, "codex/1.0"},
+		{"provider override", true, "vendor-cli/2.0", nil, "vendor-cli/2.0"},
+		{"passthrough", false, consts.UserAgentPassthrough, nil, "codex/1.0"},
+		{"custom header wins", false, "vendor-cli/2.0", map[string]string{"User-Agent": "custom"}, "custom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := BuildHeaders(source, tt.withHeader, tt.custom, false, tt.userAgent)
+			if got := header.Get("User-Agent"); got != tt.want {
+				t.Fatalf("User-Agent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAllowed(t *testing.T) {
+	deadline := time.Now().Add(10 * time.Second)
+	tests := []struct {
+		name    string
+		retry   int
+		reserve int
+		want    bool
+	}{
+		{"first attempt", 0, 30, true},
+		{"no reserve", 3, 0, true},
+		{"enough time", 1, 5, true},
+		{"too little time", 1, 15, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryAllowed(tt.retry, deadline, tt.reserve); got != tt.want {
+				t.Fatalf("retryAllowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderDefaults(t *testing.T) {
+	provider := models.Provider{
+		Headers:     map[string]string{"OpenAI-Organization": "org-1", "X-Env": "prod"},
+		QueryParams: map[string]string{"api-version": "2024-10-21"},
+	}
+	header := BuildHeaders(nil, false, MergeProviderHeaders(provider, map[string]string{"X-Env": "staging"}), false, "")
+	if got := header.Get("OpenAI-Organization"); got != "org-1" {
+		t.Fatalf("OpenAI-Organization = %q, want org-1", got)
+	}
+	if got := header.Get("X-Env"); got != "staging" {
+		t.Fatalf("X-Env = %q, want association header to win", got)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/openai/chat?api-version=old&deployment=gpt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyQueryParams(req, provider.QueryParams)
+	if got, want := req.URL.RawQuery, "api-version=2024-10-21&deployment=gpt"; got != want {
+		t.Fatalf("query = %q, want %q", got, want)
+	}
+}
+
+func TestProvidersWithMetaEndpointIsolation(t *testing.T) {
+	db := setupTestDB(t, &models.Model{}, &models.Provider{}, &models.ModelWithProvider{}, &models.ChatLog{})
+
+	model := models.Model{Name: "qwen", MaxRetry: 3, TimeOut: 30}
+	chatProvider := models.Provider{Name: "chat", Type: consts.StyleOpenAI}
+	embedProvider := models.Provider{Name: "embed", Type: consts.StyleOpenAI}
+	for _, v := range []any{&model, &chatProvider, &embedProvider} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	associations := []models.ModelWithProvider{
+		{ModelID: model.ID, ProviderID: chatProvider.ID, Weight: 1, Status: new(true), Slot: ActiveSlot(), Chat: new(true), Embedding: new(false)},
+		{ModelID: model.ID, ProviderID: embedProvider.ID, Weight: 1, Status: new(true), Slot: ActiveSlot(), Chat: new(false), Embedding: new(true)},
+	}
+	if err := db.Create(&associations).Error; err != nil {
+		t.Fatalf("create associations: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		style  string
+		before Before
+		want   uint
+	}{
+		{"chat", consts.StyleOpenAI, Before{Model: "qwen"}, chatProvider.ID},
+		{"embedding", consts.StyleEmbedding, Before{Model: "qwen", embedding: true}, embedProvider.ID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, err := ProvidersWithMetaBymodelsName(context.Background(), tt.style, tt.before)
+			if err != nil {
+				t.Fatalf("ProvidersWithMetaBymodelsName failed: %v", err)
+			}
+			if len(meta.WeightItems) != 1 {
+				t.Fatalf("weight items = %v, want exactly one", meta.WeightItems)
+			}
+			for id := range meta.WeightItems {
+				if got := meta.ModelWithProviderMap[id].ProviderID; got != tt.want {
+					t.Fatalf("provider = %d, want %d", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordLogClientCanceled(t *testing.T) {
+	db := setupTestDB(t, &models.ChatLog{}, &models.ChatIO{})
+	ctx := context.Background()
+
+	id, err := SaveChatLog(ctx, models.ChatLog{Name: "m", Status: consts.StatusRunning})
+	if err != nil {
+		t.Fatalf("SaveChatLog failed: %v", err)
+	}
+	partial := "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}],\"usage\":{\"prompt_tokens\":7,\"completion_tokens\":1,\"total_tokens\":8}}\n\n"
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte(partial))
+		pw.CloseWithError(fmt.Errorf("%w: %v", ErrClientCanceled, context.Canceled))
+	}()
+	RecordLog(ctx, time.Now(), pr, ProcesserOpenAI, id, 0, Before{Model: "m", Stream: true}, false, nil, nil, "")
+
+	log, err := gorm.G[models.ChatLog](db).Where("id = ?", id).First(ctx)
+	if err != nil {
+		t.Fatalf("load log: %v", err)
+	}
+	if log.Status != consts.StatusCanceled || log.TotalTokens != 8 || log.Size != len(partial) {
+		t.Fatalf("log = status %s tokens %d size %d", log.Status, log.TotalTokens, log.Size)
+	}
+}
+
+// testChatMeta 为每个上游地址创建 OpenAI 提供商与关联，优先级按顺序递增，使尝试顺序固定
+func testChatMeta(t *testing.T, db *gorm.DB, urls ...string) (ProvidersWithMeta, []models.Provider) {
+	t.Helper()
+	meta := ProvidersWithMeta{
+		ModelWithProviderMap: map[uint]models.ModelWithProvider{},
+		WeightItems:          map[uint]int{},
+		PriorityItems:        map[uint]int{},
+		ProviderMap:          map[uint]models.Provider{},
+		MaxRetry:             len(urls),
+		TimeOut:              10,
+		Strategy:             consts.BalancerDefault,
+	}
+	providerList := make([]models.Provider, len(urls))
+	for i, url := range urls {
+		providerList[i] = models.Provider{Name: fmt.Sprintf("p%d", i+1), Type: consts.StyleOpenAI, Config: `{"base_url":"` + url + `","api_key":"k"}`}
+		if err := db.Create(&providerList[i]).Error; err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+		id := uint(i + 1)
+		meta.ModelWithProviderMap[id] = models.ModelWithProvider{Model: gorm.Model{ID: id}, ProviderID: providerList[i].ID, ProviderModel: "m"}
+		meta.WeightItems[id] = 1
+		meta.PriorityItems[id] = i + 1
+		meta.ProviderMap[providerList[i].ID] = providerList[i]
+	}
+	return meta, providerList
+}
+
+func testChatBefore(t *testing.T) Before {
+	t.Helper()
+	before, err := BeforerOpenAI([]byte(`{"model":"m","messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatalf("BeforerOpenAI: %v", err)
+	}
+	return *before
+}
+
+// waitChatLogs 等待异步写入的重试日志落库，避免测试结束后仍有写入
+func waitChatLogs(t *testing.T, db *gorm.DB, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		var count int64
+		db.Model(&models.ChatLog{}).Count(&count)
+		if count >= want {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("chat logs = %d, want %d", count, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	WaitLogWrites(context.Background())
+}
+
+func TestBalanceChatReleasesInflight(t *testing.T) {
+	db := setupTestDB(t, &models.Provider{}, &models.ChatLog{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer server.Close()
+
+	meta, _ := testChatMeta(t, db, server.URL)
+	association := meta.ModelWithProviderMap[1]
+	association.MaxInFlight = 1
+	meta.ModelWithProviderMap[1] = association
+	// 并发上限为 1，响应体关闭后名额归还，下一次请求才能成功
+	for range 2 {
+		res, _, err := BalanceChat(context.Background(), time.Now(), consts.StyleOpenAI, testChatBefore(t), meta, models.ReqMeta{Header: http.Header{}})
+		if err != nil {
+			t.Fatalf("BalanceChat: %v", err)
+		}
+		res.Body.Close()
+	}
+}
+
+func TestBalanceChatLocalSkipKeepsRetries(t *testing.T) {
+	db := setupTestDB(t, &models.Provider{}, &models.ChatLog{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer server.Close()
+
+	meta, _ := testChatMeta(t, db, server.URL, server.URL, server.URL)
+	meta.MaxRetry = 1
+	ctx := context.Background()
+	// 前两个关联并发已满，只有一次重试机会时仍应跳过它们并请求第三个
+	for id := uint(1); id <= 2; id++ {
+		association := meta.ModelWithProviderMap[id]
+		association.MaxInFlight = 1
+		meta.ModelWithProviderMap[id] = association
+		release, ok := acquireInflight(ctx, id, 1, 0)
+		if !ok {
+			t.Fatalf("acquire slot %d", id)
+		}
+		defer release()
+	}
+	res, log, err := BalanceChat(ctx, time.Now(), consts.StyleOpenAI, testChatBefore(t), meta, models.ReqMeta{Header: http.Header{}})
+	if err != nil {
+		t.Fatalf("BalanceChat: %v", err)
+	}
+	res.Body.Close()
+	if log.ProviderName != "p3" || log.Retry != 0 {
+		t.Fatalf("provider = %s, retry = %d, want p3 on the first attempt", log.ProviderName, log.Retry)
+	}
+	waitChatLogs(t, db, 2)
+}
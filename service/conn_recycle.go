@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/atopos31/llmio/pkg/env"
+	"github.com/atopos31/llmio/providers"
+)
+
+// defaultConnRecycleSeconds 默认每 5 分钟回收一次上游空闲连接
+const defaultConnRecycleSeconds = 300
+
+// StartConnectionRecycle 定期关闭上游空闲连接，使长期复用的连接池重新解析 DNS，避免厂商切换 IP 后仍连接旧地址；
+// LLMIO_CONN_RECYCLE_INTERVAL 为 0 时关闭
+func StartConnectionRecycle(ctx context.Context) {
+	seconds := env.GetWithDefault("LLMIO_CONN_RECYCLE_INTERVAL", defaultConnRecycleSeconds)
+	if seconds <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(seconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				providers.CloseIdleConnections()
+			}
+		}
+	}()
+}
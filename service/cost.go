@@ -0,0 +1,25 @@
+package service
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// parseCostHeader 按配置顺序读取第一个可解析的费用响应头，忽略货币符号
+func parseCostHeader(header http.Header, names string) (float64, bool) {
+	for name := range strings.SplitSeq(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		value := strings.TrimSpace(header.Get(name))
+		value = strings.TrimLeft(value, "$¥")
+		cost, err := strconv.ParseFloat(value, 64)
+		if err != nil || cost < 0 {
+			continue
+		}
+		return cost, true
+	}
+	return 0, false
+}
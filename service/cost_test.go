@@ -0,0 +1,31 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseCostHeader(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Openrouter-Cost", "$0.0012")
+	header.Set("X-Ratelimit-Cost", "invalid")
+	tests := []struct {
+		name   string
+		names  string
+		want   float64
+		wantOK bool
+	}{
+		{"not configured", "", 0, false},
+		{"single header", "x-openrouter-cost", 0.0012, true},
+		{"skip unparsable", "x-ratelimit-cost, x-openrouter-cost", 0.0012, true},
+		{"missing header", "x-cost", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCostHeader(header, tt.names)
+			if got != tt.want || ok != tt.wantOK {
+				t.Fatalf("parseCostHeader() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
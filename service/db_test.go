@@ -0,0 +1,24 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/atopos31/llmio/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDB 创建内存数据库并迁移指定表，设置为全局 DB，测试结束后重置
+func setupTestDB(t *testing.T, tables ...any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	models.DB = db
+	t.Cleanup(func() { models.DB = nil })
+	return db
+}
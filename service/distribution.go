@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/atopos31/llmio/balancers"
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDistributionHours = 24
+	distributionMinRequests  = 20   // 总请求数不足时不做判断
+	distributionTolerance    = 0.10 // 实际占比与权重占比相差超过 10 个百分点时标记
+)
+
+// 分布偏差标记
+const (
+	DistributionOK           = "ok"
+	DistributionUnder        = "under"
+	DistributionOver         = "over"
+	DistributionInsufficient = "insufficient"
+)
+
+// AssociationDistribution 单个关联的实际流量占比与权重占比
+type AssociationDistribution struct {
+	ModelProviderID uint    `json:"model_provider_id"`
+	ProviderName    string  `json:"provider_name"`
+	ProviderModel   string  `json:"provider_model"`
+	Weight          int     `json:"weight"`
+	ExpectedShare   float64 `json:"expected_share"`
+	Attempts        int64   `json:"attempts"`
+	Successes       int64   `json:"successes"`
+	AttemptShare    float64 `json:"attempt_share"` // 被选中（含重试）的占比
+	ActualShare     float64 `json:"actual_share"`  // 实际承接成功请求的占比
+	Deviation       float64 `json:"deviation"`
+	Breaker         string  `json:"breaker"`
+	Flag            string  `json:"flag"`
+	Reason          string  `json:"reason,omitempty"`
+	Remark          string  `json:"remark,omitempty"`
+}
+
+// DistributionReport 模型流量分布报告
+type DistributionReport struct {
+	ModelID      uint                      `json:"model_id"`
+	ModelName    string                    `json:"model_name"`
+	Hours        int                       `json:"hours"`
+	Requests     int64                     `json:"requests"`
+	Associations []AssociationDistribution `json:"associations"`
+}
+
+// WeightDistribution 对比窗口内各关联实际承接的流量与配置权重
+func WeightDistribution(ctx context.Context, modelID uint, hours int) (*DistributionReport, error) {
+	if hours <= 0 {
+		hours = defaultDistributionHours
+	}
+	model, err := gorm.G[models.Model](models.DB).Where("id = ?", modelID).First(ctx)
+	if err != nil {
+		return nil, err
+	}
+	associations, err := gorm.G[models.ModelWithProvider](models.DB).
+		Where("model_id = ?", modelID).
+		Where("status = ?", true).
+		Where("slot = ?", ActiveSlot()).
+		Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	providers, err := gorm.G[models.Provider](models.DB).
+		Where("id IN ?", lo.Map(associations, func(mp models.ModelWithProvider, _ int) uint { return mp.ProviderID })).
+		Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	providerNames := lo.SliceToMap(providers, func(p models.Provider) (uint, string) { return p.ID, p.Name })
+
+	type row struct {
+		ProviderName  string
+		ProviderModel string
+		Attempts      int64
+		Successes     int64
+	}
+	rows := make([]row, 0)
+	if err := models.ReadDB().WithContext(ctx).
+		Model(&models.ChatLog{}).
+		Select("provider_name, provider_model, COUNT(*) AS attempts, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS successes", consts.StatusSuccess).
+		Where("name = ?", model.Name).
+		Where("created_at >= ?", time.Now().Add(-time.Duration(hours)*time.Hour)).
+		Where("status NOT IN ?", consts.HealthExcludedStatuses).
+		Group("provider_name, provider_model").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	stats := lo.SliceToMap(rows, func(r row) (string, row) { return r.ProviderName + "/" + r.ProviderModel, r })
+
+	report := &DistributionReport{
+		ModelID:      model.ID,
+		ModelName:    model.Name,
+		Hours:        hours,
+		Associations: make([]AssociationDistribution, 0, len(associations)),
+	}
+	var totalWeight int
+	var totalAttempts, totalSuccesses int64
+	for _, mp := range associations {
+		name := providerNames[mp.ProviderID]
+		stat := stats[name+"/"+mp.ProviderModel]
+		totalWeight += mp.Weight
+		totalAttempts += stat.Attempts
+		totalSuccesses += stat.Successes
+		report.Associations = append(report.Associations, AssociationDistribution{
+			ModelProviderID: mp.ID,
+			ProviderName:    name,
+			ProviderModel:   mp.ProviderModel,
+			Weight:          mp.Weight,
+			Attempts:        stat.Attempts,
+			Successes:       stat.Successes,
+			Breaker:         balancers.NodeState(mp.ID).String(),
+			Remark:          mp.Remark,
+		})
+	}
+	report.Requests = totalSuccesses
+	for i := range report.Associations {
+		a := &report.Associations[i]
+		if totalWeight > 0 {
+			a.ExpectedShare = float64(a.Weight) / float64(totalWeight)
+		}
+		if totalAttempts > 0 {
+			a.AttemptShare = float64(a.Attempts) / float64(totalAttempts)
+		}
+		if totalSuccesses > 0 {
+			a.ActualShare = float64(a.Successes) / float64(totalSuccesses)
+		}
+		a.Deviation = a.ActualShare - a.ExpectedShare
+		a.Flag, a.Reason = distributionFlag(*a, totalSuccesses)
+	}
+	return report, nil
+}
+
+func distributionFlag(a AssociationDistribution, total int64) (string, string) {
+	if total < distributionMinRequests {
+		return DistributionInsufficient, ""
+	}
+	if math.Abs(a.Deviation) < distributionTolerance {
+		return DistributionOK, ""
+	}
+	if a.Deviation > 0 {
+		return DistributionOver, "other channels are failing or unavailable"
+	}
+	switch {
+	case a.Breaker != balancers.StateClosed.String():
+		return DistributionUnder, "breaker " + a.Breaker
+	case a.Attempts > a.Successes:
+		return DistributionUnder, "failures"
+	default:
+		return DistributionUnder, "selected less often than weight"
+	}
+}
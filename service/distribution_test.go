@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestWeightDistribution(t *testing.T) {
+	db := setupTestDB(t, &models.Model{}, &models.Provider{}, &models.ModelWithProvider{}, &models.ChatLog{})
+
+	model := models.Model{Name: "gpt"}
+	db.Create(&model)
+	providers := []models.Provider{{Name: "a"}, {Name: "b"}}
+	db.Create(&providers)
+	associations := []models.ModelWithProvider{
+		{ModelID: model.ID, ProviderID: providers[0].ID, ProviderModel: "m", Weight: 1, Status: new(true), Slot: consts.SlotBlue},
+		{ModelID: model.ID, ProviderID: providers[1].ID, ProviderModel: "m", Weight: 1, Status: new(true), Slot: consts.SlotBlue},
+	}
+	db.Create(&associations)
+
+	logs := make([]models.ChatLog, 0)
+	for range 25 {
+		logs = append(logs, models.ChatLog{Name: "gpt", ProviderName: "a", ProviderModel: "m", Status: consts.StatusSuccess})
+	}
+	for range 5 {
+		logs = append(logs,
+			models.ChatLog{Name: "gpt", ProviderName: "b", ProviderModel: "m", Status: consts.StatusError},
+			models.ChatLog{Name: "gpt", ProviderName: "b", ProviderModel: "m", Status: consts.StatusSuccess},
+		)
+	}
+	db.Create(&logs)
+
+	report, err := WeightDistribution(context.Background(), model.ID, 1)
+	if err != nil {
+		t.Fatalf("WeightDistribution failed: %v", err)
+	}
+	if report.Requests != 30 || len(report.Associations) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	a, b := report.Associations[0], report.Associations[1]
+	if a.Flag != DistributionOver || a.ExpectedShare != 0.5 {
+		t.Fatalf("expected a to be over-receiving: %+v", a)
+	}
+	if b.Flag != DistributionUnder || b.Reason != "failures" || b.Attempts != 10 {
+		t.Fatalf("expected b to be under-receiving due to failures: %+v", b)
+	}
+}
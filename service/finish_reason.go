@@ -0,0 +1,83 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// 统一后的结束原因
+const (
+	FinishStop          = "stop"
+	FinishLength        = "length"
+	FinishToolUse       = "tool_use"
+	FinishContentFilter = "content_filter"
+)
+
+// finishReasons 各协议结束原因到统一取值的映射，未列出的原样小写记录
+var finishReasons = map[string]string{
+	// OpenAI
+	"stop":           FinishStop,
+	"length":         FinishLength,
+	"tool_calls":     FinishToolUse,
+	"function_call":  FinishToolUse,
+	"content_filter": FinishContentFilter,
+	// Anthropic
+	"end_turn":      FinishStop,
+	"stop_sequence": FinishStop,
+	"max_tokens":    FinishLength,
+	"tool_use":      FinishToolUse,
+	"refusal":       FinishContentFilter,
+	// OpenAI Responses incomplete_details.reason
+	"max_output_tokens": FinishLength,
+	// Gemini
+	"finish_reason_unspecified": "",
+	"safety":                    FinishContentFilter,
+	"recitation":                FinishContentFilter,
+	"blocklist":                 FinishContentFilter,
+	"prohibited_content":        FinishContentFilter,
+	"spii":                      FinishContentFilter,
+	"image_safety":              FinishContentFilter,
+	"malformed_function_call":   FinishToolUse,
+}
+
+// normalizeFinishReason 将上游结束原因统一为 stop/length/tool_use/content_filter
+func normalizeFinishReason(reason string) string {
+	reason = strings.ToLower(reason)
+	if normalized, ok := finishReasons[reason]; ok {
+		return normalized
+	}
+	return reason
+}
+
+// responsesFinishReason 从 Responses API 的 response 对象推断结束原因
+func responsesFinishReason(response gjson.Result) string {
+	switch response.Get("status").String() {
+	case "incomplete":
+		return normalizeFinishReason(response.Get("incomplete_details.reason").String())
+	case "completed":
+		for _, item := range response.Get("output").Array() {
+			if item.Get("type").String() == "function_call" {
+				return FinishToolUse
+			}
+		}
+		return FinishStop
+	}
+	return ""
+}
+
+// geminiFinishReason 从 Gemini 响应推断结束原因，函数调用时上游仍返回 STOP
+func geminiFinishReason(res gjson.Result) string {
+	candidate := res.Get("candidates.0")
+	if !candidate.Exists() {
+		if res.Get("promptFeedback.blockReason").Exists() {
+			return FinishContentFilter
+		}
+		return ""
+	}
+	reason := normalizeFinishReason(candidate.Get("finishReason").String())
+	if reason == FinishStop && candidate.Get(`content.parts.#(functionCall)`).Exists() {
+		return FinishToolUse
+	}
+	return reason
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/atopos31/llmio/balancers"
+	"github.com/atopos31/llmio/models"
+)
+
+// attemptResult 一次上游请求的结果
+type attemptResult struct {
+	at  *attempt
+	res *http.Response
+	err error
+}
+
+func (r attemptResult) ok() bool {
+	return r.err == nil && r.res.StatusCode == http.StatusOK
+}
+
+// cancelOnClose 响应体关闭时释放对冲请求的 context，以指针使用，调用方会比较响应体是否被替换
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// doHedged 主请求在 after 内未返回响应头时调用 next 发起对冲请求，采用先成功返回的一方并取消另一方。
+// 全部失败时返回最后完成的结果；在胜者之前完成的失败结果通过 failed 交由调用方记录
+func doHedged(primary *attempt, after time.Duration, next func() *attempt) (winner attemptResult, failed []attemptResult) {
+	results := make(chan attemptResult, 2)
+	started := []*attempt{primary}
+	send := func(at *attempt) {
+		go func() {
+			res, err := at.do()
+			results <- attemptResult{at: at, res: res, err: err}
+		}()
+	}
+	send(primary)
+	pending := 1
+
+	timer := time.NewTimer(after)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			if at := next(); at != nil {
+				slog.Info("hedge request", "primary", primary.provider.Name, "hedge", at.provider.Name, "after", after)
+				started = append(started, at)
+				send(at)
+				pending++
+			}
+		case r := <-results:
+			pending--
+			if !r.ok() && pending > 0 {
+				failed = append(failed, r)
+				continue
+			}
+			for _, at := range started {
+				if at != r.at {
+					at.cancel()
+				}
+			}
+			if pending > 0 {
+				// 被取消的请求不计入失败，只需释放响应
+				go func() {
+					if loser := <-results; loser.res != nil {
+						loser.res.Body.Close()
+					}
+				}()
+			}
+			if r.res != nil {
+				r.res.Body = &cancelOnClose{ReadCloser: r.res.Body, cancel: r.at.cancel}
+			} else {
+				r.at.cancel()
+			}
+			return r, failed
+		}
+	}
+}
+
+// nextHedge 从主请求之外的关联中按模型策略选出对冲目标并构建请求，没有可用关联时返回 nil
+func nextHedge(meta ProvidersWithMeta, primaryID uint, prepare func(uint) (*attempt, error), events *timeline, retry int) *attempt {
+	items := maps.Clone(meta.WeightItems)
+	delete(items, primaryID)
+	balancer := balancers.New(meta.Strategy, items, meta.PriorityItems)
+	if meta.Breaker {
+		balancer = balancers.BalancerWrapperBreaker(balancer, meta.BreakerConfig)
+	}
+	id, err := balancer.Pop()
+	if err != nil {
+		return nil
+	}
+	at, err := prepare(id)
+	if err != nil || at == nil {
+		return nil
+	}
+	events.add(models.TimelineEvent{Stage: StageHedge, Attempt: retry + 1, Provider: at.provider.Name})
+	return at
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestDoHedged(t *testing.T) {
+	handler := func(delay time.Duration, status int, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}
+	}
+	fast := httptest.NewServer(handler(0, http.StatusOK, "fast"))
+	defer fast.Close()
+	slow := httptest.NewServer(handler(500*time.Millisecond, http.StatusOK, "slow"))
+	defer slow.Close()
+	broken := httptest.NewServer(handler(0, http.StatusInternalServerError, "broken"))
+	defer broken.Close()
+
+	newAttempt := func(t *testing.T, name, url string) *attempt {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		return &attempt{provider: models.Provider{Name: name}, client: http.DefaultClient, req: req, cancel: cancel, release: func() {}}
+	}
+
+	tests := []struct {
+		name       string
+		primary    string
+		hedge      string
+		wantBody   string
+		wantFailed int
+		wantHedged bool
+	}{
+		{"primary before threshold", fast.URL, slow.URL, "fast", 0, false},
+		{"hedge wins", slow.URL, fast.URL, "fast", 0, true},
+		{"hedge fails", slow.URL, broken.URL, "slow", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hedged := false
+			primary := newAttempt(t, "primary", tt.primary)
+			winner, failed := doHedged(primary, 50*time.Millisecond, func() *attempt {
+				hedged = true
+				return newAttempt(t, "hedge", tt.hedge)
+			})
+			if winner.err != nil {
+				t.Fatalf("winner err: %v", winner.err)
+			}
+			body, _ := io.ReadAll(winner.res.Body)
+			winner.res.Body.Close()
+			if string(body) != tt.wantBody {
+				t.Fatalf("body = %q, want %q", body, tt.wantBody)
+			}
+			if hedged != tt.wantHedged {
+				t.Fatalf("hedged = %v, want %v", hedged, tt.wantHedged)
+			}
+			if len(failed) != tt.wantFailed {
+				t.Fatalf("failed = %d, want %d", len(failed), tt.wantFailed)
+			}
+			for _, r := range failed {
+				r.res.Body.Close()
+				if r.res.StatusCode != http.StatusInternalServerError {
+					t.Fatalf("failed status = %d", r.res.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+func TestHedgeFailureClassified(t *testing.T) {
+	db := setupTestDB(t, &models.Provider{}, &models.ChatLog{})
+	t.Setenv("LLMIO_AUTH_DISABLE_THRESHOLD", "1")
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer slow.Close()
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid api key"}`))
+	}))
+	defer unauthorized.Close()
+
+	// 主请求固定为第一个提供商，对冲请求发往第二个
+	meta, providerList := testChatMeta(t, db, slow.URL, unauthorized.URL)
+	meta.HedgeAfter = 20
+	res, _, err := BalanceChat(context.Background(), time.Now(), consts.StyleOpenAI, testChatBefore(t), meta, models.ReqMeta{Header: http.Header{}})
+	if err != nil {
+		t.Fatalf("BalanceChat: %v", err)
+	}
+	res.Body.Close()
+	waitChatLogs(t, db, 1)
+
+	// 对冲一方返回 401 时与普通尝试一样计入鉴权失败
+	var hedge models.Provider
+	if err := db.First(&hedge, providerList[1].ID).Error; err != nil {
+		t.Fatalf("load provider: %v", err)
+	}
+	if hedge.AuthDisabledAt == nil {
+		t.Fatal("hedge provider returning 401 was not auth-disabled")
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+// 分桶上界（不含），最后一个桶无上界
+var (
+	tokenBuckets = []int64{256, 1024, 4096, 16384, 32768, 65536, 131072, 200000}
+	sizeBuckets  = []int64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20}
+)
+
+// HistogramBucket 直方图分桶，Upper 为空表示无上界
+type HistogramBucket struct {
+	Lower int64  `json:"lower"`
+	Upper *int64 `json:"upper"`
+	Count int64  `json:"count"`
+}
+
+// ModelHistogram 单个模型的请求/响应大小与 token 分布
+type ModelHistogram struct {
+	Model            string            `json:"model"`
+	Requests         int64             `json:"requests"`
+	PromptTokens     []HistogramBucket `json:"prompt_tokens"`
+	CompletionTokens []HistogramBucket `json:"completion_tokens"`
+	RequestSize      []HistogramBucket `json:"request_size"`
+	ResponseSize     []HistogramBucket `json:"response_size"`
+}
+
+// ModelHistograms 统计 since 之后成功请求的分布，model 为空时返回全部模型
+func ModelHistograms(ctx context.Context, since time.Time, model string) ([]ModelHistogram, error) {
+	result := make(map[string]*ModelHistogram)
+	order := make([]string, 0)
+	get := func(name string) *ModelHistogram {
+		h, ok := result[name]
+		if !ok {
+			h = &ModelHistogram{
+				Model:            name,
+				PromptTokens:     newBuckets(tokenBuckets),
+				CompletionTokens: newBuckets(tokenBuckets),
+				RequestSize:      newBuckets(sizeBuckets),
+				ResponseSize:     newBuckets(sizeBuckets),
+			}
+			result[name] = h
+			order = append(order, name)
+		}
+		return h
+	}
+
+	metrics := []struct {
+		column  string
+		bounds  []int64
+		buckets func(h *ModelHistogram) []HistogramBucket
+	}{
+		{"prompt_tokens", tokenBuckets, func(h *ModelHistogram) []HistogramBucket { return h.PromptTokens }},
+		{"completion_tokens", tokenBuckets, func(h *ModelHistogram) []HistogramBucket { return h.CompletionTokens }},
+		{"request_size", sizeBuckets, func(h *ModelHistogram) []HistogramBucket { return h.RequestSize }},
+		{"size", sizeBuckets, func(h *ModelHistogram) []HistogramBucket { return h.ResponseSize }},
+	}
+	for i, metric := range metrics {
+		type row struct {
+			Name   string
+			Bucket int
+			Count  int64
+		}
+		rows := make([]row, 0)
+		query := models.ReadDB().WithContext(ctx).
+			Model(&models.ChatLog{}).
+			Select(fmt.Sprintf("name, %s AS bucket, COUNT(*) AS count", bucketExpr(metric.column, metric.bounds))).
+			Where("created_at >= ?", since).
+			Where("status = ?", consts.StatusSuccess)
+		if model != "" {
+			query = query.Where("name = ?", model)
+		}
+		if err := query.Group("name, bucket").Order("name").Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("histogram %s: %w", metric.column, err)
+		}
+		for _, r := range rows {
+			h := get(r.Name)
+			buckets := metric.buckets(h)
+			if r.Bucket >= 0 && r.Bucket < len(buckets) {
+				buckets[r.Bucket].Count += r.Count
+			}
+			// 每个指标统计的请求数相同，仅取第一个
+			if i == 0 {
+				h.Requests += r.Count
+			}
+		}
+	}
+
+	histograms := make([]ModelHistogram, 0, len(order))
+	for _, name := range order {
+		histograms = append(histograms, *result[name])
+	}
+	return histograms, nil
+}
+
+func newBuckets(bounds []int64) []HistogramBucket {
+	buckets := make([]HistogramBucket, 0, len(bounds)+1)
+	var lower int64
+	for _, upper := range bounds {
+		buckets = append(buckets, HistogramBucket{Lower: lower, Upper: new(upper)})
+		lower = upper
+	}
+	return append(buckets, HistogramBucket{Lower: lower})
+}
+
+// bucketExpr 生成按上界分桶的 CASE 表达式，返回桶下标
+func bucketExpr(column string, bounds []int64) string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for i, upper := range bounds {
+		fmt.Fprintf(&b, " WHEN COALESCE(%s, 0) < %d THEN %d", column, upper, i)
+	}
+	fmt.Fprintf(&b, " ELSE %d END", len(bounds))
+	return b.String()
+}
+
+// ProviderResponseSize 提供商的平均响应大小与分块数
+type ProviderResponseSize struct {
+	Provider  string  `json:"provider"`
+	Requests  int64   `json:"requests"`
+	AvgSize   float64 `json:"avg_size"`
+	AvgChunks float64 `json:"avg_chunks"`
+}
+
+// ProviderResponseSizes 统计 since 之后成功请求按提供商聚合的响应大小
+func ProviderResponseSizes(ctx context.Context, since time.Time) ([]ProviderResponseSize, error) {
+	rows := make([]ProviderResponseSize, 0)
+	if err := models.ReadDB().WithContext(ctx).
+		Model(&models.ChatLog{}).
+		Select("provider_name AS provider, COUNT(*) AS requests, AVG(size) AS avg_size, AVG(chunk_count) AS avg_chunks").
+		Where("created_at >= ?", since).
+		Where("status = ?", consts.StatusSuccess).
+		Group("provider_name").
+		Order("avg_size DESC").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("provider response sizes: %w", err)
+	}
+	return rows, nil
+}
+
+// ProviderFinishReasons 提供商的结束原因分布，用于追踪截断率与内容过滤率
+type ProviderFinishReasons struct {
+	Provider      string           `json:"provider"`
+	Requests      int64            `json:"requests"`
+	Reasons       map[string]int64 `json:"reasons"`
+	TruncatedRate float64          `json:"truncated_rate"`
+	FilteredRate  float64          `json:"filtered_rate"`
+}
+
+// FinishReasonStats 统计 since 之后成功请求按提供商聚合的结束原因
+func FinishReasonStats(ctx context.Context, since time.Time) ([]ProviderFinishReasons, error) {
+	var rows []struct {
+		Provider     string
+		FinishReason string
+		Requests     int64
+	}
+	if err := models.ReadDB().WithContext(ctx).
+		Model(&models.ChatLog{}).
+		Select("provider_name AS provider, finish_reason, COUNT(*) AS requests").
+		Where("created_at >= ?", since).
+		Where("status = ?", consts.StatusSuccess).
+		Where("finish_reason IS NOT NULL AND finish_reason != ''").
+		Group("provider_name, finish_reason").
+		Order("provider_name").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("finish reason stats: %w", err)
+	}
+
+	stats := make([]ProviderFinishReasons, 0)
+	for _, row := range rows {
+		if len(stats) == 0 || stats[len(stats)-1].Provider != row.Provider {
+			stats = append(stats, ProviderFinishReasons{Provider: row.Provider, Reasons: map[string]int64{}})
+		}
+		stat := &stats[len(stats)-1]
+		stat.Requests += row.Requests
+		stat.Reasons[row.FinishReason] = row.Requests
+	}
+	for i := range stats {
+		stats[i].TruncatedRate = float64(stats[i].Reasons[FinishLength]) / float64(stats[i].Requests)
+		stats[i].FilteredRate = float64(stats[i].Reasons[FinishContentFilter]) / float64(stats[i].Requests)
+	}
+	return stats, nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestModelHistograms(t *testing.T) {
+	db := setupTestDB(t, &models.ChatLog{})
+
+	logs := []models.ChatLog{
+		{Name: "gpt", Status: consts.StatusSuccess, RequestSize: 512, Size: 2048, Usage: models.Usage{PromptTokens: 100, CompletionTokens: 2000}},
+		{Name: "gpt", Status: consts.StatusSuccess, RequestSize: 5000, Size: 100, Usage: models.Usage{PromptTokens: 300000, CompletionTokens: 10}},
+		{Name: "gpt", Status: consts.StatusError, RequestSize: 5000},
+		{Name: "claude", Status: consts.StatusSuccess, RequestSize: 10, Size: 10},
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("create logs: %v", err)
+	}
+
+	histograms, err := ModelHistograms(context.Background(), time.Now().Add(-time.Hour), "gpt")
+	if err != nil {
+		t.Fatalf("ModelHistograms failed: %v", err)
+	}
+	if len(histograms) != 1 || histograms[0].Requests != 2 {
+		t.Fatalf("unexpected histograms: %+v", histograms)
+	}
+	h := histograms[0]
+	if h.PromptTokens[0].Count != 1 || h.PromptTokens[len(tokenBuckets)].Count != 1 {
+		t.Fatalf("unexpected prompt buckets: %+v", h.PromptTokens)
+	}
+	if h.CompletionTokens[2].Count != 1 {
+		t.Fatalf("expected 2000 completion tokens in [1024, 4096), got %+v", h.CompletionTokens)
+	}
+	if h.RequestSize[0].Count != 1 || h.RequestSize[2].Count != 1 {
+		t.Fatalf("unexpected request size buckets: %+v", h.RequestSize)
+	}
+	if h.ResponseSize[len(sizeBuckets)].Upper != nil {
+		t.Fatalf("last bucket should be unbounded")
+	}
+}
+
+func TestProviderResponseSizes(t *testing.T) {
+	db := setupTestDB(t, &models.ChatLog{})
+
+	logs := []models.ChatLog{
+		{ProviderName: "a", Status: consts.StatusSuccess, Size: 1000, ChunkCount: 10},
+		{ProviderName: "a", Status: consts.StatusSuccess, Size: 3000, ChunkCount: 30},
+		{ProviderName: "a", Status: consts.StatusError},
+		{ProviderName: "b", Status: consts.StatusSuccess, Size: 100, ChunkCount: 1},
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("create logs: %v", err)
+	}
+
+	sizes, err := ProviderResponseSizes(context.Background(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ProviderResponseSizes failed: %v", err)
+	}
+	if len(sizes) != 2 {
+		t.Fatalf("unexpected sizes: %+v", sizes)
+	}
+	if got := sizes[0]; got.Provider != "a" || got.Requests != 2 || got.AvgSize != 2000 || got.AvgChunks != 20 {
+		t.Fatalf("unexpected provider a: %+v", got)
+	}
+}
+
+func TestFinishReasonStats(t *testing.T) {
+	db := setupTestDB(t, &models.ChatLog{})
+
+	logs := []models.ChatLog{
+		{ProviderName: "a", Status: consts.StatusSuccess, FinishReason: FinishStop},
+		{ProviderName: "a", Status: consts.StatusSuccess, FinishReason: FinishStop},
+		{ProviderName: "a", Status: consts.StatusSuccess, FinishReason: FinishLength},
+		{ProviderName: "a", Status: consts.StatusSuccess, FinishReason: FinishContentFilter},
+		{ProviderName: "a", Status: consts.StatusError},
+		{ProviderName: "b", Status: consts.StatusSuccess, FinishReason: FinishToolUse},
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("create logs: %v", err)
+	}
+
+	stats, err := FinishReasonStats(context.Background(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("FinishReasonStats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if got := stats[0]; got.Provider != "a" || got.Requests != 4 || got.Reasons[FinishStop] != 2 || got.TruncatedRate != 0.25 || got.FilteredRate != 0.25 {
+		t.Fatalf("unexpected provider a: %+v", got)
+	}
+}
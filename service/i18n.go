@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"gorm.io/gorm"
+)
+
+// LoadAPILanguage 从系统配置加载固定响应语言
+func LoadAPILanguage(ctx context.Context) error {
+	config, err := gorm.G[models.Config](models.DB).Where("key = ?", models.KeyAPILanguage).First(ctx)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			i18n.SetDefault("")
+			return nil
+		}
+		return err
+	}
+	i18n.SetDefault(config.Value)
+	return nil
+}
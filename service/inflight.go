@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var ErrProviderSaturated = errors.New("provider concurrency limit reached")
+
+// inflightSlot 关联的并发名额，上限变化时重新创建
+type inflightSlot struct {
+	max int
+	ch  chan struct{}
+}
+
+var (
+	inflightMu    sync.Mutex
+	inflightSlots = make(map[uint]*inflightSlot)
+)
+
+// acquireInflight 占用关联的一个并发名额，已满时最多等待 wait；limit 为 0 时不限制
+func acquireInflight(ctx context.Context, id uint, limit int, wait time.Duration) (func(), bool) {
+	if limit <= 0 {
+		return func() {}, true
+	}
+	inflightMu.Lock()
+	slot, ok := inflightSlots[id]
+	if !ok || slot.max != limit {
+		slot = &inflightSlot{max: limit, ch: make(chan struct{}, limit)}
+		inflightSlots[id] = slot
+	}
+	inflightMu.Unlock()
+
+	release := sync.OnceFunc(func() { <-slot.ch })
+	select {
+	case slot.ch <- struct{}{}:
+		return release, true
+	default:
+	}
+	if wait <= 0 {
+		return nil, false
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case slot.ch <- struct{}{}:
+		return release, true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return nil, false
+}
+
+// releaseOnClose 响应体关闭时释放并发名额，以指针使用，调用方会比较响应体是否被替换
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	defer r.release()
+	return r.ReadCloser.Close()
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAcquireInflight(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := acquireInflight(ctx, 100, 0, 0); !ok {
+		t.Fatalf("unlimited association should always acquire")
+	}
+
+	release, ok := acquireInflight(ctx, 101, 1, 0)
+	if !ok {
+		t.Fatalf("first acquire should succeed")
+	}
+	if _, ok := acquireInflight(ctx, 101, 1, 0); ok {
+		t.Fatalf("saturated association should be skipped without queueing")
+	}
+	if _, ok := acquireInflight(ctx, 101, 1, 20*time.Millisecond); ok {
+		t.Fatalf("queued acquire should time out while saturated")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		release()
+		// 重复释放不应多归还名额
+		release()
+	}()
+	second, ok := acquireInflight(ctx, 101, 1, time.Second)
+	if !ok {
+		t.Fatalf("queued acquire should succeed after release")
+	}
+	if _, ok := acquireInflight(ctx, 101, 1, 0); ok {
+		t.Fatalf("double release should not free an extra slot")
+	}
+	second()
+}
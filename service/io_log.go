@@ -0,0 +1,96 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+const redactedValue = "[redacted]"
+
+// sampleIOLog 按模型采样比例决定本次请求是否记录 IO
+func sampleIOLog(policy *models.IOLogPolicy) bool {
+	if policy == nil || policy.SampleRate == nil {
+		return true
+	}
+	return rand.IntN(100) < *policy.SampleRate
+}
+
+// redactIOInput 按脱敏规则处理待保存的请求体，非 JSON 输入原样返回
+func redactIOInput(input string, rules []string) string {
+	if len(rules) == 0 {
+		return input
+	}
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	var body any
+	if err := decoder.Decode(&body); err != nil {
+		return input
+	}
+	r := redactor{keys: map[string]bool{}}
+	for _, rule := range rules {
+		if rule == consts.IORedactImageBase64 {
+			r.images = true
+			continue
+		}
+		r.keys[rule] = true
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(r.walk(body)); err != nil {
+		return input
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+type redactor struct {
+	images bool
+	keys   map[string]bool
+}
+
+func (r redactor) walk(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			switch {
+			case r.keys[key]:
+				v[key] = redactedValue
+			case r.images && key == "data" && isMediaObject(v):
+				if data, ok := value.(string); ok {
+					v[key] = fmt.Sprintf("[redacted %d bytes]", len(data))
+				}
+			default:
+				v[key] = r.walk(value)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = r.walk(value)
+		}
+	case string:
+		// OpenAI 等格式的 data URL 保留 MIME 前缀
+		if r.images && strings.HasPrefix(v, "data:") {
+			if i := strings.Index(v, ";base64,"); i >= 0 {
+				return fmt.Sprintf("%s[redacted %d bytes]", v[:i+len(";base64,")], len(v)-i-len(";base64,"))
+			}
+		}
+	}
+	return v
+}
+
+// isMediaObject 判断是否为 Gemini inline_data、Anthropic source 或 OpenAI input_audio 这类内联媒体对象
+func isMediaObject(m map[string]any) bool {
+	for _, key := range []string{"mime_type", "mimeType", "media_type", "format"} {
+		if _, ok := m[key]; ok {
+			return true
+		}
+	}
+	return false
+}
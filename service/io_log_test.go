@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestSampleIOLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *models.IOLogPolicy
+		want   bool
+	}{
+		{name: "no policy", policy: nil, want: true},
+		{name: "no rate", policy: &models.IOLogPolicy{}, want: true},
+		{name: "zero", policy: &models.IOLogPolicy{SampleRate: new(0)}, want: false},
+		{name: "full", policy: &models.IOLogPolicy{SampleRate: new(100)}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 20 {
+				if got := sampleIOLog(tt.policy); got != tt.want {
+					t.Fatalf("sampleIOLog() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactIOInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		rules []string
+		want  string
+	}{
+		{
+			name:  "no rules",
+			input: `{"user":"bob"}`,
+			want:  `{"user":"bob"}`,
+		},
+		{
+			name:  "not json",
+			input: `[multipart`,
+			rules: []string{"user"},
+			want:  `[multipart`,
+		},
+		{
+			name:  "field",
+			input: `{"model":"m","metadata":{"email":"a@b.c"},"user":"bob"}`,
+			rules: []string{"metadata", "user"},
+			want:  `{"metadata":"[redacted]","model":"m","user":"[redacted]"}`,
+		},
+		{
+			name:  "openai data url",
+			input: `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}},{"type":"image_url","image_url":{"url":"https://x/y.png"}}]}]}`,
+			rules: []string{consts.IORedactImageBase64},
+			want:  `{"messages":[{"content":[{"image_url":{"url":"data:image/png;base64,[redacted 4 bytes]"},"type":"image_url"},{"image_url":{"url":"https://x/y.png"},"type":"image_url"}],"role":"user"}]}`,
+		},
+		{
+			name:  "anthropic and gemini",
+			input: `{"a":{"type":"base64","media_type":"image/png","data":"AAAAAA"},"b":{"inline_data":{"mime_type":"image/png","data":"AA"}},"c":{"data":"keep"}}`,
+			rules: []string{consts.IORedactImageBase64},
+			want:  `{"a":{"data":"[redacted 6 bytes]","media_type":"image/png","type":"base64"},"b":{"inline_data":{"data":"[redacted 2 bytes]","mime_type":"image/png"}},"c":{"data":"keep"}}`,
+		},
+		{
+			name:  "numbers kept",
+			input: `{"temperature":0.70,"max_tokens":12345678901234567890}`,
+			rules: []string{"user"},
+			want:  `{"max_tokens":12345678901234567890,"temperature":0.70}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactIOInput(tt.input, tt.rules); got != tt.want {
+				t.Fatalf("redactIOInput() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
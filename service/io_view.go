@@ -0,0 +1,151 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/atopos31/llmio/models"
+)
+
+// DefaultIOViewLimit 详情接口默认返回的请求体与响应体字节上限
+const DefaultIOViewLimit = 64 * 1024
+
+// MaxIOViewLimit 详情接口允许的最大字节上限
+const MaxIOViewLimit = 4 * 1024 * 1024
+
+// secretKeys 查看 IO 详情时需要遮蔽的字段名，比较前统一小写并把 - 替换为 _
+var secretKeys = map[string]bool{
+	"authorization":       true,
+	"proxy_authorization": true,
+	"api_key":             true,
+	"apikey":              true,
+	"x_api_key":           true,
+	"x_goog_api_key":      true,
+	"access_token":        true,
+	"refresh_token":       true,
+	"secret":              true,
+	"client_secret":       true,
+	"password":            true,
+}
+
+// secretPatterns 文本中常见的凭证形式，如 Bearer 令牌与 sk- 开头的密钥
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]{8,}`),
+	regexp.MustCompile(`\b(sk-)[A-Za-z0-9_-]{16,}`),
+}
+
+// LogIO 脱敏并截断后的请求体与响应体
+type LogIO struct {
+	LogID           uint      `json:"log_id"`
+	Style           string    `json:"style"`
+	CreatedAt       time.Time `json:"created_at"`
+	Input           string    `json:"input"`
+	InputSize       int       `json:"input_size"` // 截断前的字节数
+	InputTruncated  bool      `json:"input_truncated"`
+	Output          string    `json:"output,omitempty"`        // 非流式响应体
+	OutputChunks    []string  `json:"output_chunks,omitempty"` // 流式响应的各个分块
+	OutputSize      int       `json:"output_size"`
+	OutputTruncated bool      `json:"output_truncated"`
+}
+
+// NewLogIO 遮蔽凭证字段后按 limit 截断请求体与响应体，流式分块超出上限后的部分整体丢弃
+func NewLogIO(chatIO models.ChatIO, style string, limit int) LogIO {
+	view := LogIO{
+		LogID:     chatIO.LogId,
+		Style:     style,
+		CreatedAt: chatIO.CreatedAt,
+		InputSize: len(chatIO.Input),
+	}
+	view.Input, view.InputTruncated = truncateUTF8(maskSecrets(chatIO.Input), limit)
+
+	if chatIO.OfStringArray == nil {
+		view.OutputSize = len(chatIO.OfString)
+		view.Output, view.OutputTruncated = truncateUTF8(maskSecrets(chatIO.OfString), limit)
+		return view
+	}
+	remain := limit
+	for _, chunk := range chatIO.OfStringArray {
+		view.OutputSize += len(chunk)
+		if view.OutputTruncated {
+			continue
+		}
+		chunk, truncated := truncateUTF8(maskSecrets(chunk), remain)
+		if !truncated || chunk != "" {
+			view.OutputChunks = append(view.OutputChunks, chunk)
+		}
+		remain -= len(chunk)
+		view.OutputTruncated = truncated
+	}
+	return view
+}
+
+// maskSecrets 遮蔽 JSON 中的凭证字段与文本中的令牌，非 JSON 内容只按文本规则处理
+func maskSecrets(s string) string {
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var body any
+	if err := decoder.Decode(&body); err != nil || decoder.More() {
+		return maskText(s)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(maskValue(body)); err != nil {
+		return maskText(s)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func maskValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if secret, ok := value.(string); ok && secretKeys[strings.ReplaceAll(strings.ToLower(key), "-", "_")] {
+				v[key] = maskSecret(secret)
+				continue
+			}
+			v[key] = maskValue(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = maskValue(value)
+		}
+	case string:
+		return maskText(v)
+	}
+	return v
+}
+
+func maskText(s string) string {
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllStringFunc(s, func(match string) string {
+			prefix := pattern.FindStringSubmatch(match)[1]
+			return prefix + maskSecret(match[len(prefix):])
+		})
+	}
+	return s
+}
+
+// maskSecret 保留末尾 4 位便于核对是哪个密钥，过短时全部遮蔽
+func maskSecret(s string) string {
+	if len(s) <= 12 {
+		return "****"
+	}
+	return "****" + s[len(s)-4:]
+}
+
+// truncateUTF8 截断到不超过 limit 字节，不拆分多字节字符
+func truncateUTF8(s string, limit int) (string, bool) {
+	if len(s) <= limit {
+		return s, false
+	}
+	limit = max(limit, 0)
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit], true
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/atopos31/llmio/models"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "json keys",
+			input: `{"api_key":"abcdefghijklmnop","headers":{"Authorization":"Bearer token-1234567890"},"model":"m"}`,
+			want:  `{"api_key":"****mnop","headers":{"Authorization":"****7890"},"model":"m"}`,
+		},
+		{
+			name:  "short secret",
+			input: `{"X-Api-Key":"short"}`,
+			want:  `{"X-Api-Key":"****"}`,
+		},
+		{
+			name:  "token in message text",
+			input: `{"messages":[{"role":"user","content":"my key is sk-abcdefghijklmnopqrstuv ok"}]}`,
+			want:  `{"messages":[{"content":"my key is sk-****stuv ok","role":"user"}]}`,
+		},
+		{
+			name:  "plain text",
+			input: "data: curl -H 'Authorization: Bearer abcdefghijklmnop'",
+			want:  "data: curl -H 'Authorization: Bearer ****mnop'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maskSecrets(tt.input); got != tt.want {
+				t.Fatalf("maskSecrets() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLogIO(t *testing.T) {
+	chatIO := models.ChatIO{
+		LogId: 7,
+		Input: `{"content":"你好你好"}`,
+		OutputUnion: models.OutputUnion{
+			OfStringArray: []string{"data: 12345", "data: 67890", "data: abcde"},
+		},
+	}
+	view := NewLogIO(chatIO, "openai", 26)
+	if view.LogID != 7 || view.InputTruncated || view.InputSize != len(chatIO.Input) {
+		t.Fatalf("input = %+v", view)
+	}
+	if !view.OutputTruncated || len(view.OutputChunks) != 3 || view.OutputChunks[2] != "data" || view.OutputSize != 33 {
+		t.Fatalf("output chunks = %q, truncated = %v, size = %d", view.OutputChunks, view.OutputTruncated, view.OutputSize)
+	}
+
+	// 截断不拆分多字节字符
+	view = NewLogIO(chatIO, "openai", 19)
+	if !view.InputTruncated || !strings.HasSuffix(view.Input, "你好") || len(view.Input) != 18 {
+		t.Fatalf("input = %q, truncated = %v", view.Input, view.InputTruncated)
+	}
+}
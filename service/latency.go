@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/atopos31/llmio/balancers"
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// latencySeedWindow 启动时用于初始化首字耗时统计的日志范围
+const latencySeedWindow = 24 * time.Hour
+
+// SeedBalancerLatency 用近期成功日志的平均首字耗时初始化 latency 策略，避免重启后重新探测
+func SeedBalancerLatency(ctx context.Context) error {
+	associations, err := gorm.G[models.ModelWithProvider](models.DB).Find(ctx)
+	if err != nil {
+		return err
+	}
+	modelList, err := gorm.G[models.Model](models.DB).Find(ctx)
+	if err != nil {
+		return err
+	}
+	providerList, err := gorm.G[models.Provider](models.DB).Find(ctx)
+	if err != nil {
+		return err
+	}
+	modelNames := lo.SliceToMap(modelList, func(m models.Model) (uint, string) { return m.ID, m.Name })
+	providerNames := lo.SliceToMap(providerList, func(p models.Provider) (uint, string) { return p.ID, p.Name })
+
+	var rows []struct {
+		Name          string
+		ProviderName  string
+		ProviderModel string
+		Latency       float64
+	}
+	if err := models.ReadDB().WithContext(ctx).Model(&models.ChatLog{}).
+		Select("name, provider_name, provider_model, AVG(first_chunk_time) AS latency").
+		Where("status = ?", consts.StatusSuccess).
+		Where("first_chunk_time > 0").
+		Where("created_at >= ?", time.Now().Add(-latencySeedWindow)).
+		Group("name, provider_name, provider_model").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	latencies := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		latencies[row.Name+"/"+row.ProviderName+"/"+row.ProviderModel] = row.Latency
+	}
+	for _, mp := range associations {
+		if latency, ok := latencies[modelNames[mp.ModelID]+"/"+providerNames[mp.ProviderID]+"/"+mp.ProviderModel]; ok {
+			balancers.ObserveLatency(mp.ID, time.Duration(latency))
+		}
+	}
+	return nil
+}
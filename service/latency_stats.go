@@ -0,0 +1,107 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+// 延迟分位数的分组维度
+const (
+	LatencyByModel    = "model"
+	LatencyByProvider = "provider"
+)
+
+// LatencyFilter 延迟分位数查询条件，Model 与 ProviderName 为空时不过滤
+type LatencyFilter struct {
+	Since        time.Time
+	By           string
+	Model        string
+	ProviderName string
+}
+
+// LatencyPercentiles 单个模型或提供商成功请求的首包耗时与总耗时分位数，单位毫秒
+type LatencyPercentiles struct {
+	Model           string `json:"model,omitempty"`
+	Provider        string `json:"provider,omitempty"`
+	Requests        int64  `json:"requests"`
+	FirstChunkP50Ms int64  `json:"first_chunk_p50_ms"`
+	FirstChunkP95Ms int64  `json:"first_chunk_p95_ms"`
+	FirstChunkP99Ms int64  `json:"first_chunk_p99_ms"`
+	LatencyP50Ms    int64  `json:"latency_p50_ms"`
+	LatencyP95Ms    int64  `json:"latency_p95_ms"`
+	LatencyP99Ms    int64  `json:"latency_p99_ms"`
+}
+
+// LatencyStats 按原始日志计算 since 之后成功请求的延迟分位数，分位数无法由汇总行合并，因此不读取 metric_rollups
+// 首包耗时只统计记录了首包时间的请求，结果按请求数降序
+func LatencyStats(ctx context.Context, filter LatencyFilter) ([]LatencyPercentiles, error) {
+	group := "name"
+	if filter.By == LatencyByProvider {
+		group = "provider_name"
+	}
+	query := models.ReadDB().WithContext(ctx).Model(&models.ChatLog{}).
+		Select(group+", first_chunk_time, chunk_time").
+		Where("created_at >= ?", filter.Since).
+		Where("status = ?", consts.StatusSuccess)
+	if filter.Model != "" {
+		query = query.Where("name = ?", filter.Model)
+	}
+	if filter.ProviderName != "" {
+		query = query.Where("provider_name = ?", filter.ProviderName)
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		return nil, fmt.Errorf("query latency: %w", err)
+	}
+	defer rows.Close()
+
+	type samples struct {
+		firstChunk []time.Duration
+		latency    []time.Duration
+	}
+	groups := make(map[string]*samples)
+	for rows.Next() {
+		var (
+			key                       string
+			firstChunkTime, chunkTime time.Duration
+		)
+		if err := rows.Scan(&key, &firstChunkTime, &chunkTime); err != nil {
+			return nil, fmt.Errorf("scan latency: %w", err)
+		}
+		s, ok := groups[key]
+		if !ok {
+			s = &samples{}
+			groups[key] = s
+		}
+		if firstChunkTime > 0 {
+			s.firstChunk = append(s.firstChunk, firstChunkTime)
+		}
+		s.latency = append(s.latency, firstChunkTime+chunkTime)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read latency: %w", err)
+	}
+
+	stats := make([]LatencyPercentiles, 0, len(groups))
+	for key, s := range groups {
+		stat := LatencyPercentiles{Requests: int64(len(s.latency))}
+		if filter.By == LatencyByProvider {
+			stat.Provider = key
+		} else {
+			stat.Model = key
+		}
+		stat.FirstChunkP50Ms, stat.FirstChunkP95Ms, stat.FirstChunkP99Ms = percentilesMs(s.firstChunk)
+		stat.LatencyP50Ms, stat.LatencyP95Ms, stat.LatencyP99Ms = percentilesMs(s.latency)
+		stats = append(stats, stat)
+	}
+	slices.SortFunc(stats, func(a, b LatencyPercentiles) int {
+		return cmp.Or(cmp.Compare(b.Requests, a.Requests), cmp.Compare(a.Model+a.Provider, b.Model+b.Provider))
+	})
+	return stats, nil
+}
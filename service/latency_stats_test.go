@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestLatencyStats(t *testing.T) {
+	db := setupTestDB(t, &models.ChatLog{})
+
+	var logs []models.ChatLog
+	// gpt 经 a 的 10 次请求首包 100ms..1000ms，各自另有 1s 输出耗时
+	for i := 1; i <= 10; i++ {
+		logs = append(logs, models.ChatLog{Name: "gpt", ProviderName: "a", Status: consts.StatusSuccess, FirstChunkTime: time.Duration(i) * 100 * time.Millisecond, ChunkTime: time.Second})
+	}
+	logs = append(logs,
+		// 非流式请求没有首包时间，只计入总耗时
+		models.ChatLog{Name: "gpt", ProviderName: "b", Status: consts.StatusSuccess, ChunkTime: 3 * time.Second},
+		models.ChatLog{Name: "gpt", ProviderName: "b", Status: consts.StatusError, FirstChunkTime: time.Minute},
+		models.ChatLog{Name: "claude", ProviderName: "b", Status: consts.StatusSuccess, FirstChunkTime: 200 * time.Millisecond, ChunkTime: 300 * time.Millisecond},
+	)
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("create logs: %v", err)
+	}
+	since := time.Now().Add(-time.Hour)
+
+	byModel, err := LatencyStats(context.Background(), LatencyFilter{Since: since, By: LatencyByModel})
+	if err != nil {
+		t.Fatalf("LatencyStats failed: %v", err)
+	}
+	if len(byModel) != 2 || byModel[0].Model != "gpt" || byModel[0].Requests != 11 {
+		t.Fatalf("unexpected stats by model: %+v", byModel)
+	}
+	gpt := byModel[0]
+	if gpt.FirstChunkP50Ms != 500 || gpt.FirstChunkP95Ms != 1000 || gpt.FirstChunkP99Ms != 1000 {
+		t.Fatalf("unexpected first chunk percentiles: %+v", gpt)
+	}
+	if gpt.LatencyP50Ms != 1600 || gpt.LatencyP99Ms != 3000 {
+		t.Fatalf("unexpected latency percentiles: %+v", gpt)
+	}
+
+	byProvider, err := LatencyStats(context.Background(), LatencyFilter{Since: since, By: LatencyByProvider, Model: "gpt"})
+	if err != nil {
+		t.Fatalf("LatencyStats failed: %v", err)
+	}
+	if len(byProvider) != 2 || byProvider[0].Provider != "a" || byProvider[1].Provider != "b" {
+		t.Fatalf("unexpected stats by provider: %+v", byProvider)
+	}
+	if b := byProvider[1]; b.Requests != 1 || b.FirstChunkP50Ms != 0 || b.LatencyP50Ms != 3000 {
+		t.Fatalf("unexpected provider b stats: %+v", b)
+	}
+
+	recent, err := LatencyStats(context.Background(), LatencyFilter{Since: time.Now().Add(time.Hour), By: LatencyByModel})
+	if err != nil {
+		t.Fatalf("LatencyStats failed: %v", err)
+	}
+	if len(recent) != 0 {
+		t.Fatalf("expected no stats after window, got %+v", recent)
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/balancers"
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestSeedBalancerLatency(t *testing.T) {
+	db := setupTestDB(t, &models.Model{}, &models.Provider{}, &models.ModelWithProvider{}, &models.ChatLog{})
+
+	model := models.Model{Name: "gpt", Strategy: consts.BalancerLatency}
+	slow := models.Provider{Name: "slow", Type: consts.StyleOpenAI}
+	fast := models.Provider{Name: "fast", Type: consts.StyleOpenAI}
+	for _, v := range []any{&model, &slow, &fast} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	slowMP := models.ModelWithProvider{ModelID: model.ID, ProviderID: slow.ID, ProviderModel: "gpt-4o", Weight: 1}
+	fastMP := models.ModelWithProvider{ModelID: model.ID, ProviderID: fast.ID, ProviderModel: "gpt-4o", Weight: 1}
+	for _, v := range []*models.ModelWithProvider{&slowMP, &fastMP} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatalf("create association: %v", err)
+		}
+	}
+	logs := []models.ChatLog{
+		{Name: "gpt", ProviderName: "slow", ProviderModel: "gpt-4o", Status: consts.StatusSuccess, FirstChunkTime: 2 * time.Second},
+		{Name: "gpt", ProviderName: "fast", ProviderModel: "gpt-4o", Status: consts.StatusSuccess, FirstChunkTime: 300 * time.Millisecond},
+		{Name: "gpt", ProviderName: "fast", ProviderModel: "gpt-4o", Status: consts.StatusSuccess, FirstChunkTime: 500 * time.Millisecond},
+		// 失败请求不计入
+		{Name: "gpt", ProviderName: "fast", ProviderModel: "gpt-4o", Status: consts.StatusError, FirstChunkTime: 10 * time.Second},
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("create logs: %v", err)
+	}
+
+	if err := SeedBalancerLatency(context.Background()); err != nil {
+		t.Fatalf("SeedBalancerLatency failed: %v", err)
+	}
+	w := balancers.NewLatency(map[uint]int{slowMP.ID: 1, fastMP.ID: 1})
+	for range 10 {
+		if id, _ := w.Pop(); id != fastMP.ID {
+			t.Fatalf("expected faster association %d, got %d", fastMP.ID, id)
+		}
+	}
+}
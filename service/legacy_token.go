@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+// legacyTokenKeyName 由 TOKEN 迁移生成的 AuthKey 名称
+const legacyTokenKeyName = "legacy-token"
+
+// legacyTokenKeyID 迁移出的 AuthKey ID，管理员 TOKEN 的转发请求按该 Key 统计用量
+var legacyTokenKeyID atomic.Uint64
+
+// LegacyTokenKeyID 返回 TOKEN 对应的 AuthKey ID，未迁移或已删除时为 0
+func LegacyTokenKeyID() uint {
+	return uint(legacyTokenKeyID.Load())
+}
+
+// MigrateLegacyToken 将仅配置 TOKEN 的部署迁移为同值的 AuthKey，使转发请求按该 Key 统计用量
+// 只创建一次，管理员删除迁移出的 Key 后不会重新创建；之后每次启动将该 Key 与当前 TOKEN 同步，
+// TOKEN 更换后旧值随即失效，TOKEN 移除时删除该 Key
+func MigrateLegacyToken(ctx context.Context, token string) (bool, error) {
+	legacyTokenKeyID.Store(0)
+	marker, err := gorm.G[models.Config](models.DB).Where("key = ?", models.KeyLegacyTokenMigrated).First(ctx)
+	switch {
+	case err == nil:
+		return false, syncLegacyTokenKey(ctx, marker.Value, token)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return false, fmt.Errorf("check legacy token migration: %w", err)
+	}
+	if token == "" {
+		return false, nil
+	}
+
+	var keyID uint
+	err = models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.AuthKey
+		err := tx.Where("key = ?", token).First(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			authKey := models.AuthKey{
+				Name:     legacyTokenKeyName,
+				Key:      token,
+				Status:   new(true),
+				IOLog:    new(false),
+				NoIOLog:  new(false),
+				AllowAll: new(true),
+				Remark:   "migrated from TOKEN",
+			}
+			if err := tx.Create(&authKey).Error; err != nil {
+				return fmt.Errorf("create legacy auth key: %w", err)
+			}
+			keyID = authKey.ID
+		case err != nil:
+			return err
+		}
+		// 已存在同值的 Key 由管理员自行维护，不与 TOKEN 绑定
+		value := ""
+		if keyID != 0 {
+			value = strconv.FormatUint(uint64(keyID), 10)
+		}
+		return tx.Create(&models.Config{Key: models.KeyLegacyTokenMigrated, Value: value}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	legacyTokenKeyID.Store(uint64(keyID))
+	return keyID != 0, nil
+}
+
+// syncLegacyTokenKey 将迁移出的 Key 更新为当前 TOKEN，TOKEN 为空时删除
+func syncLegacyTokenKey(ctx context.Context, value string, token string) error {
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		return nil
+	}
+	authKey, err := gorm.G[models.AuthKey](models.DB).Where("id = ?", id).First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load legacy auth key: %w", err)
+	}
+	if token == "" {
+		if _, err := gorm.G[models.AuthKey](models.DB).Where("id = ?", id).Delete(ctx); err != nil {
+			return fmt.Errorf("delete legacy auth key: %w", err)
+		}
+		return nil
+	}
+	if authKey.Key != token {
+		if _, err := gorm.G[models.AuthKey](models.DB).Where("id = ?", id).Update(ctx, "key", token); err != nil {
+			return fmt.Errorf("rotate legacy auth key: %w", err)
+		}
+	}
+	legacyTokenKeyID.Store(id)
+	return nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atopos31/llmio/models"
+)
+
+func TestMigrateLegacyToken(t *testing.T) {
+	db := setupTestDB(t, &models.AuthKey{}, &models.Config{})
+	ctx := context.Background()
+
+	if created, err := MigrateLegacyToken(ctx, ""); err != nil || created {
+		t.Fatalf("empty token: created = %v, err = %v", created, err)
+	}
+	if created, err := MigrateLegacyToken(ctx, "legacy-secret"); err != nil || !created {
+		t.Fatalf("first migration: created = %v, err = %v", created, err)
+	}
+	authKey, err := GetAuthKey(ctx, "legacy-secret")
+	if err != nil || authKey.Name != legacyTokenKeyName || !*authKey.AllowAll {
+		t.Fatalf("migrated key = %+v, err = %v", authKey, err)
+	}
+
+	if LegacyTokenKeyID() != authKey.ID {
+		t.Fatalf("LegacyTokenKeyID = %d, want %d", LegacyTokenKeyID(), authKey.ID)
+	}
+
+	// 更换 TOKEN 后旧值不再是有效的 Key
+	if created, err := MigrateLegacyToken(ctx, "rotated-secret"); err != nil || created {
+		t.Fatalf("rotation: created = %v, err = %v", created, err)
+	}
+	if _, err := GetAuthKey(ctx, "legacy-secret"); err == nil {
+		t.Fatal("old TOKEN still accepted after rotation")
+	}
+	if rotated, err := GetAuthKey(ctx, "rotated-secret"); err != nil || rotated.ID != authKey.ID {
+		t.Fatalf("rotated key = %+v, err = %v", rotated, err)
+	}
+
+	// 移除 TOKEN 时删除迁移出的 Key
+	if _, err := MigrateLegacyToken(ctx, ""); err != nil {
+		t.Fatalf("remove token: %v", err)
+	}
+	if _, err := GetAuthKey(ctx, "rotated-secret"); err == nil || LegacyTokenKeyID() != 0 {
+		t.Fatalf("legacy key kept after TOKEN removed, id = %d", LegacyTokenKeyID())
+	}
+
+	// 删除迁移出的 Key 后不会再次创建
+	if err := db.Delete(&models.AuthKey{}, authKey.ID).Error; err != nil {
+		t.Fatalf("delete key: %v", err)
+	}
+	if created, err := MigrateLegacyToken(ctx, "legacy-secret"); err != nil || created {
+		t.Fatalf("second migration: created = %v, err = %v", created, err)
+	}
+}
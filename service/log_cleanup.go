@@ -8,14 +8,30 @@ import (
 	"time"
 
 	"github.com/atopos31/llmio/models"
+	"github.com/samber/lo"
 	"gorm.io/gorm"
 )
 
 const (
 	defaultLogRetentionDays = 30
 	logCleanupCheckInterval = time.Hour
+	// logCleanupBatchSize 每批删除的行数，分批提交避免长事务长时间阻塞日志写入
+	logCleanupBatchSize = 5000
 )
 
+// logRetentionOverride 由环境变量设置的保留策略，优先于配置项
+var logRetentionOverride models.LogCleanupPolicy
+
+// SetLogRetention 设置环境变量中的日志与 IO 保留天数，大于 0 时覆盖配置项并开启定时清理
+func SetLogRetention(days, ioDays int, vacuum bool) {
+	logRetentionOverride = models.LogCleanupPolicy{
+		Enabled:         days > 0,
+		RetentionDays:   days,
+		IORetentionDays: ioDays,
+		Vacuum:          vacuum,
+	}
+}
+
 func DefaultLogCleanupPolicy() *models.LogCleanupPolicy {
 	return &models.LogCleanupPolicy{
 		Enabled:       false,
@@ -27,13 +43,13 @@ func GetLogCleanupPolicy(ctx context.Context) (*models.LogCleanupPolicy, error)
 	config, err := gorm.G[models.Config](models.DB).Where("key = ?", models.KeyLogCleanupPolicy).First(ctx)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return DefaultLogCleanupPolicy(), nil
+			return applyLogRetentionOverride(DefaultLogCleanupPolicy()), nil
 		}
 		return nil, err
 	}
 
 	if config.Value == "" {
-		return DefaultLogCleanupPolicy(), nil
+		return applyLogRetentionOverride(DefaultLogCleanupPolicy()), nil
 	}
 
 	policy := DefaultLogCleanupPolicy()
@@ -43,7 +59,21 @@ func GetLogCleanupPolicy(ctx context.Context) (*models.LogCleanupPolicy, error)
 	if policy.RetentionDays <= 0 {
 		policy.RetentionDays = defaultLogRetentionDays
 	}
-	return policy, nil
+	return applyLogRetentionOverride(policy), nil
+}
+
+func applyLogRetentionOverride(policy *models.LogCleanupPolicy) *models.LogCleanupPolicy {
+	if logRetentionOverride.Enabled {
+		policy.Enabled = true
+		policy.RetentionDays = logRetentionOverride.RetentionDays
+	}
+	if logRetentionOverride.IORetentionDays > 0 {
+		policy.IORetentionDays = logRetentionOverride.IORetentionDays
+	}
+	if logRetentionOverride.Vacuum {
+		policy.Vacuum = true
+	}
+	return policy
 }
 
 func CleanLogsByDays(ctx context.Context, days int) (int64, error) {
@@ -51,28 +81,89 @@ func CleanLogsByDays(ctx context.Context, days int) (int64, error) {
 		return 0, fmt.Errorf("days must be greater than 0")
 	}
 
-	cutoffTime := time.Now().AddDate(0, 0, -days)
+	cutoffTime, err := retentionCutoff(ctx, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return 0, err
+	}
 	var deletedCount int64
+	for {
+		var ids []uint
+		if err := models.DB.WithContext(ctx).Unscoped().Model(&models.ChatLog{}).
+			Where("created_at < ?", cutoffTime).
+			Order("id").Limit(logCleanupBatchSize).
+			Pluck("id", &ids).Error; err != nil {
+			return deletedCount, fmt.Errorf("select logs: %w", err)
+		}
+		if len(ids) == 0 {
+			return deletedCount, nil
+		}
+
+		err := models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Where("log_id IN ?", ids).Delete(&models.ChatIO{}).Error; err != nil {
+				return fmt.Errorf("delete chat io: %w", err)
+			}
+			result := tx.Unscoped().Where("id IN ?", ids).Delete(&models.ChatLog{})
+			if result.Error != nil {
+				return fmt.Errorf("delete logs: %w", result.Error)
+			}
+			deletedCount += result.RowsAffected
+			return nil
+		})
+		if err != nil {
+			return deletedCount, err
+		}
+		if len(ids) < logCleanupBatchSize {
+			return deletedCount, nil
+		}
+	}
+}
+
+// CleanChatIOByDays 只删除超过保留天数的请求与响应内容，日志保留并标记为无 IO 记录
+func CleanChatIOByDays(ctx context.Context, days int) (int64, error) {
+	if days <= 0 {
+		return 0, fmt.Errorf("days must be greater than 0")
+	}
 
-	err := models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Unscoped().
-			Where("log_id IN (SELECT id FROM chat_logs WHERE created_at < ?)", cutoffTime).
-			Delete(&models.ChatIO{}).Error; err != nil {
-			return fmt.Errorf("delete chat io: %w", err)
+	cutoffTime := time.Now().AddDate(0, 0, -days)
+	var deletedCount int64
+	for {
+		var rows []models.ChatIO
+		if err := models.DB.WithContext(ctx).Unscoped().
+			Select("id", "log_id").
+			Where("created_at < ?", cutoffTime).
+			Order("id").Limit(logCleanupBatchSize).
+			Find(&rows).Error; err != nil {
+			return deletedCount, fmt.Errorf("select chat io: %w", err)
+		}
+		if len(rows) == 0 {
+			return deletedCount, nil
 		}
 
-		result := tx.Unscoped().Where("created_at < ?", cutoffTime).Delete(&models.ChatLog{})
-		if result.Error != nil {
-			return fmt.Errorf("delete logs: %w", result.Error)
+		err := models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			result := tx.Unscoped().Where("id IN ?", lo.Map(rows, func(io models.ChatIO, _ int) uint { return io.ID })).Delete(&models.ChatIO{})
+			if result.Error != nil {
+				return fmt.Errorf("delete chat io: %w", result.Error)
+			}
+			if err := tx.Model(&models.ChatLog{}).
+				Where("id IN ?", lo.Map(rows, func(io models.ChatIO, _ int) uint { return io.LogId })).
+				Update("chat_io", false).Error; err != nil {
+				return fmt.Errorf("update logs: %w", err)
+			}
+			deletedCount += result.RowsAffected
+			return nil
+		})
+		if err != nil {
+			return deletedCount, err
+		}
+		if len(rows) < logCleanupBatchSize {
+			return deletedCount, nil
 		}
-		deletedCount = result.RowsAffected
-		return nil
-	})
-	if err != nil {
-		return 0, err
 	}
+}
 
-	return deletedCount, nil
+// vacuumDB 重建数据库文件以回收删除后留下的空闲页，执行期间会阻塞写入
+func vacuumDB(ctx context.Context) error {
+	return models.DB.WithContext(ctx).Exec("VACUUM").Error
 }
 
 func StartLogCleanupScheduler(ctx context.Context) {
@@ -87,7 +178,10 @@ func StartLogCleanupScheduler(ctx context.Context) {
 				slog.Error("load log cleanup policy failed", "error", err)
 				return
 			}
-			if !policy.Enabled || policy.RetentionDays <= 0 {
+			logEnabled := policy.Enabled && policy.RetentionDays > 0
+			// IO 保留天数不短于日志时随日志一起删除即可
+			ioEnabled := policy.IORetentionDays > 0 && (!logEnabled || policy.IORetentionDays < policy.RetentionDays)
+			if !logEnabled && !ioEnabled {
 				return
 			}
 
@@ -96,31 +190,55 @@ func StartLogCleanupScheduler(ctx context.Context) {
 				return
 			}
 
-			start := time.Now()
-			deletedCount, err := CleanLogsByDays(ctx, policy.RetentionDays)
-			if err != nil {
-				slog.Error("scheduled log cleanup failed", "retention_days", policy.RetentionDays, "error", err)
-				return
+			var total int64
+			for _, job := range []struct {
+				enabled bool
+				typ     string
+				days    int
+				clean   func(context.Context, int) (int64, error)
+			}{
+				{logEnabled, "days", policy.RetentionDays, CleanLogsByDays},
+				{ioEnabled, "io_days", policy.IORetentionDays, CleanChatIOByDays},
+			} {
+				if !job.enabled {
+					continue
+				}
+				start := time.Now()
+				deletedCount, err := job.clean(ctx, job.days)
+				total += deletedCount
+				if err != nil {
+					slog.Error("scheduled log cleanup failed", "type", job.typ, "retention_days", job.days, "deleted_count", deletedCount, "error", err)
+					return
+				}
+				slog.Info("scheduled log cleanup completed",
+					"type", job.typ,
+					"retention_days", job.days,
+					"deleted_count", deletedCount,
+					"duration", time.Since(start),
+				)
+
+				record := models.LogCleanupRecord{
+					RetentionDays: job.days,
+					DeletedCount:  deletedCount,
+					DurationMs:    time.Since(start).Milliseconds(),
+					Source:        "scheduled",
+					Type:          job.typ,
+				}
+				if err := gorm.G[models.LogCleanupRecord](models.DB).Create(ctx, &record); err != nil {
+					slog.Error("failed to save cleanup record", "error", err)
+				}
 			}
-
 			lastRunDay = today
-			slog.Info("scheduled log cleanup completed",
-				"retention_days", policy.RetentionDays,
-				"deleted_count", deletedCount,
-				"duration", time.Since(start),
-			)
-
-			record := models.LogCleanupRecord{
-				RetentionDays: policy.RetentionDays,
-				DeletedCount:  deletedCount,
-				DurationMs:    time.Since(start).Milliseconds(),
-				Source:        "scheduled",
-				Type:          "days",
-			}
-			if err := gorm.G[models.LogCleanupRecord](models.DB).Create(ctx, &record); err != nil {
-				slog.Error("failed to save cleanup record", "error", err)
-			}
 			models.TrimLogCleanupRecords(ctx, 100)
+
+			if policy.Vacuum && total > 0 {
+				start := time.Now()
+				if err := vacuumDB(ctx); err != nil {
+					slog.Error("vacuum after log cleanup failed", "error", err)
+					return
+				}
+				slog.Info("vacuum after log cleanup completed", "duration", time.Since(start))
+			}
 		}
 
 		run()
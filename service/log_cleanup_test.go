@@ -7,29 +7,11 @@ import (
 	"time"
 
 	"github.com/atopos31/llmio/models"
-	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
 )
 
-func setupLogCleanupTestDB(t *testing.T) func() {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("failed to open test database: %v", err)
-	}
-
-	if err := db.AutoMigrate(&models.ChatLog{}, &models.ChatIO{}, &models.Config{}, &models.LogCleanupRecord{}); err != nil {
-		t.Fatalf("failed to migrate test database: %v", err)
-	}
-
-	models.DB = db
-	return func() {
-		models.DB = nil
-	}
-}
-
 func TestCleanLogsByDays(t *testing.T) {
-	cleanup := setupLogCleanupTestDB(t)
-	defer cleanup()
+	setupTestDB(t, &models.ChatLog{}, &models.ChatIO{}, &models.Config{}, &models.LogCleanupRecord{})
 
 	ctx := context.Background()
 
@@ -98,8 +80,7 @@ func TestCleanLogsByDays(t *testing.T) {
 }
 
 func TestGetLogCleanupPolicyDefaultsWhenMissing(t *testing.T) {
-	cleanup := setupLogCleanupTestDB(t)
-	defer cleanup()
+	setupTestDB(t, &models.ChatLog{}, &models.ChatIO{}, &models.Config{}, &models.LogCleanupRecord{})
 
 	policy, err := GetLogCleanupPolicy(context.Background())
 	if err != nil {
@@ -117,8 +98,7 @@ func TestGetLogCleanupPolicyDefaultsWhenMissing(t *testing.T) {
 }
 
 func TestGetLogCleanupPolicyReadsConfig(t *testing.T) {
-	cleanup := setupLogCleanupTestDB(t)
-	defer cleanup()
+	setupTestDB(t, &models.ChatLog{}, &models.ChatIO{}, &models.Config{}, &models.LogCleanupRecord{})
 
 	value, err := json.Marshal(models.LogCleanupPolicy{
 		Enabled:       true,
@@ -149,3 +129,71 @@ func TestGetLogCleanupPolicyReadsConfig(t *testing.T) {
 		t.Fatalf("expected retention days 7, got %d", policy.RetentionDays)
 	}
 }
+
+func TestGetLogCleanupPolicyEnvOverride(t *testing.T) {
+	setupTestDB(t, &models.ChatLog{}, &models.ChatIO{}, &models.Config{}, &models.LogCleanupRecord{})
+	SetLogRetention(14, 3, true)
+	defer SetLogRetention(0, 0, false)
+
+	if err := gorm.G[models.Config](models.DB).Create(context.Background(), &models.Config{
+		Key:   models.KeyLogCleanupPolicy,
+		Value: `{"enabled":false,"retention_days":7}`,
+	}); err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+
+	policy, err := GetLogCleanupPolicy(context.Background())
+	if err != nil {
+		t.Fatalf("GetLogCleanupPolicy failed: %v", err)
+	}
+	if !policy.Enabled || policy.RetentionDays != 14 || policy.IORetentionDays != 3 || !policy.Vacuum {
+		t.Fatalf("expected env override, got %+v", policy)
+	}
+}
+
+func TestCleanChatIOByDays(t *testing.T) {
+	setupTestDB(t, &models.ChatLog{}, &models.ChatIO{}, &models.Config{}, &models.LogCleanupRecord{})
+
+	ctx := context.Background()
+	var logs []models.ChatLog
+	for _, age := range []int{10, 1} {
+		log := models.ChatLog{Name: "log", Status: "success", ChatIO: true}
+		if err := gorm.G[models.ChatLog](models.DB).Create(ctx, &log); err != nil {
+			t.Fatalf("failed to create log: %v", err)
+		}
+		chatIO := models.ChatIO{LogId: log.ID, Input: "input"}
+		if err := gorm.G[models.ChatIO](models.DB).Create(ctx, &chatIO); err != nil {
+			t.Fatalf("failed to create chat io: %v", err)
+		}
+		if err := models.DB.Model(&models.ChatIO{}).Where("id = ?", chatIO.ID).Update("created_at", time.Now().AddDate(0, 0, -age)).Error; err != nil {
+			t.Fatalf("failed to age chat io: %v", err)
+		}
+		logs = append(logs, log)
+	}
+
+	deletedCount, err := CleanChatIOByDays(ctx, 7)
+	if err != nil {
+		t.Fatalf("CleanChatIOByDays failed: %v", err)
+	}
+	if deletedCount != 1 {
+		t.Fatalf("expected 1 deleted chat io, got %d", deletedCount)
+	}
+
+	remaining, err := gorm.G[models.ChatIO](models.DB).Find(ctx)
+	if err != nil || len(remaining) != 1 || remaining[0].LogId != logs[1].ID {
+		t.Fatalf("remaining chat io = %+v, err = %v", remaining, err)
+	}
+	for i, want := range []bool{false, true} {
+		log, err := gorm.G[models.ChatLog](models.DB).Where("id = ?", logs[i].ID).First(ctx)
+		if err != nil {
+			t.Fatalf("failed to load log: %v", err)
+		}
+		if log.ChatIO != want {
+			t.Fatalf("log %d ChatIO = %v, want %v", log.ID, log.ChatIO, want)
+		}
+	}
+	var logCount int64
+	if err := models.DB.Model(&models.ChatLog{}).Count(&logCount).Error; err != nil || logCount != 2 {
+		t.Fatalf("expected logs to remain, count = %d, err = %v", logCount, err)
+	}
+}
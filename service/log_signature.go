@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// logVerifyBatchSize 校验签名时每批读取的日志数
+	logVerifyBatchSize = 500
+	// logVerifyMaxReported 报告中最多列出的异常日志 ID 数
+	logVerifyMaxReported = 100
+)
+
+var ErrLogSigningDisabled = errors.New("log signing is not enabled")
+
+var (
+	logSigningMu  sync.RWMutex
+	logSigningKey []byte
+)
+
+// SetLogSigningSecret 设置日志签名密钥，为空时不签名
+func SetLogSigningSecret(secret string) {
+	logSigningMu.Lock()
+	defer logSigningMu.Unlock()
+	logSigningKey = []byte(secret)
+}
+
+func logSigningSecret() []byte {
+	logSigningMu.RLock()
+	defer logSigningMu.RUnlock()
+	return logSigningKey
+}
+
+// chatLogSignature 对日志中与用量、计费相关的字段计算 HMAC-SHA256
+func chatLogSignature(key []byte, log models.ChatLog) string {
+	cost := ""
+	if log.Cost != nil {
+		cost = strconv.FormatFloat(*log.Cost, 'g', -1, 64)
+	}
+	fields := []string{
+		strconv.FormatUint(uint64(log.ID), 10),
+		// 以微秒保存，避免数据库时间精度差异导致校验失败
+		strconv.FormatInt(log.CreatedAt.UnixMicro(), 10),
+		log.Name,
+		log.ProviderName,
+		log.ProviderModel,
+		log.Status,
+		strconv.FormatUint(uint64(log.AuthKeyID), 10),
+		strconv.FormatInt(log.PromptTokens, 10),
+		strconv.FormatInt(log.CompletionTokens, 10),
+		strconv.FormatInt(log.TotalTokens, 10),
+		strconv.FormatInt(log.PromptTokensDetails.CachedTokens, 10),
+		strconv.FormatFloat(log.InputPrice, 'g', -1, 64),
+		strconv.FormatFloat(log.CacheReadPrice, 'g', -1, 64),
+		strconv.FormatFloat(log.OutputPrice, 'g', -1, 64),
+		log.Currency,
+		cost,
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signChatLog 按数据库中的最新内容为日志签名，未配置密钥时跳过
+func signChatLog(ctx context.Context, id uint) {
+	key := logSigningSecret()
+	if len(key) == 0 {
+		return
+	}
+	log, err := gorm.G[models.ChatLog](models.DB).Where("id = ?", id).First(ctx)
+	if err != nil {
+		slog.Error("load chat log for signing", "logId", id, "error", err)
+		return
+	}
+	if _, err := gorm.G[models.ChatLog](models.DB).Where("id = ?", id).Update(ctx, "signature", chatLogSignature(key, log)); err != nil {
+		slog.Error("sign chat log", "logId", id, "error", err)
+	}
+}
+
+// LogVerifyReport 日志签名校验结果
+type LogVerifyReport struct {
+	Checked    int64  `json:"checked"`
+	Unsigned   int64  `json:"unsigned"`    // 未开启签名前写入的日志
+	Invalid    int64  `json:"invalid"`     // 签名不匹配，内容可能被篡改
+	InvalidIDs []uint `json:"invalid_ids"` // 签名不匹配的日志 ID，最多 100 条
+}
+
+// VerifyChatLogs 重新计算全部日志的签名并与已保存的签名比对
+func VerifyChatLogs(ctx context.Context) (*LogVerifyReport, error) {
+	key := logSigningSecret()
+	if len(key) == 0 {
+		return nil, ErrLogSigningDisabled
+	}
+	report := &LogVerifyReport{InvalidIDs: make([]uint, 0)}
+	var logs []models.ChatLog
+	result := models.ReadDB().WithContext(ctx).Omit("timeline").FindInBatches(&logs, logVerifyBatchSize, func(_ *gorm.DB, _ int) error {
+		for _, log := range logs {
+			report.Checked++
+			switch {
+			case log.Signature == "":
+				report.Unsigned++
+			case !hmac.Equal([]byte(log.Signature), []byte(chatLogSignature(key, log))):
+				report.Invalid++
+				if len(report.InvalidIDs) < logVerifyMaxReported {
+					report.InvalidIDs = append(report.InvalidIDs, log.ID)
+				}
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return nil, fmt.Errorf("verify chat logs: %w", result.Error)
+	}
+	return report, nil
+}
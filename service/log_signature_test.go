@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+func TestVerifyChatLogs(t *testing.T) {
+	setupTestDB(t, &models.ChatLog{}, &models.ChatIO{}, &models.Config{}, &models.LogCleanupRecord{})
+	ctx := context.Background()
+
+	if _, err := VerifyChatLogs(ctx); !errors.Is(err, ErrLogSigningDisabled) {
+		t.Fatalf("expected ErrLogSigningDisabled, got %v", err)
+	}
+	// 开启签名前写入的日志视为未签名
+	if _, err := SaveChatLog(ctx, models.ChatLog{Name: "legacy"}); err != nil {
+		t.Fatalf("save legacy log: %v", err)
+	}
+
+	SetLogSigningSecret("secret")
+	t.Cleanup(func() { SetLogSigningSecret("") })
+	ids := make([]uint, 0)
+	for _, name := range []string{"a", "b", "c"} {
+		id, err := SaveChatLog(ctx, models.ChatLog{Name: name, Usage: models.Usage{TotalTokens: 10}, InputPrice: 1.5})
+		if err != nil {
+			t.Fatalf("save log: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	// 正常流程中用量更新后重新签名
+	if _, err := gorm.G[models.ChatLog](models.DB).Where("id = ?", ids[0]).Update(ctx, "total_tokens", 20); err != nil {
+		t.Fatalf("update log: %v", err)
+	}
+	signChatLog(ctx, ids[0])
+	// 绕过网关直接改写用量
+	if _, err := gorm.G[models.ChatLog](models.DB).Where("id = ?", ids[1]).Update(ctx, "total_tokens", 1); err != nil {
+		t.Fatalf("tamper log: %v", err)
+	}
+
+	report, err := VerifyChatLogs(ctx)
+	if err != nil {
+		t.Fatalf("VerifyChatLogs: %v", err)
+	}
+	if report.Checked != 4 || report.Unsigned != 1 || report.Invalid != 1 || len(report.InvalidIDs) != 1 || report.InvalidIDs[0] != ids[1] {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
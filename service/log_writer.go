@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// logWriter 请求日志异步写入的运行状态，日志在响应转发的同时由独立协程写库
+var logWriter struct {
+	pending      atomic.Int64
+	maxPending   atomic.Int64
+	written      atomic.Int64
+	failed       atomic.Int64
+	flushTotalUs atomic.Int64
+	flushMaxUs   atomic.Int64
+	lastFlushUs  atomic.Int64
+}
+
+// LogWriterStats 日志写入积压、失败与写库耗时，用于发现高负载下的日志丢失
+type LogWriterStats struct {
+	Pending     int64   `json:"pending"`     // 尚未写完的请求日志数
+	MaxPending  int64   `json:"max_pending"` // 启动以来的最大积压
+	Written     int64   `json:"written"`
+	Failed      int64   `json:"failed"` // 写库失败而丢失或停留在 running 状态的日志数
+	LastFlushMs float64 `json:"last_flush_ms"`
+	AvgFlushMs  float64 `json:"avg_flush_ms"`
+	MaxFlushMs  float64 `json:"max_flush_ms"`
+}
+
+// beginLogWrite 记录一条待写入的日志，返回写入结束时调用的函数
+func beginLogWrite() func() {
+	pending := logWriter.pending.Add(1)
+	for {
+		current := logWriter.maxPending.Load()
+		if pending <= current || logWriter.maxPending.CompareAndSwap(current, pending) {
+			break
+		}
+	}
+	return func() { logWriter.pending.Add(-1) }
+}
+
+// observeLogFlush 统计一次日志写库的耗时与结果
+func observeLogFlush(start time.Time, err error) {
+	if err != nil {
+		logWriter.failed.Add(1)
+		return
+	}
+	us := time.Since(start).Microseconds()
+	logWriter.written.Add(1)
+	logWriter.flushTotalUs.Add(us)
+	logWriter.lastFlushUs.Store(us)
+	for {
+		current := logWriter.flushMaxUs.Load()
+		if us <= current || logWriter.flushMaxUs.CompareAndSwap(current, us) {
+			break
+		}
+	}
+}
+
+// GetLogWriterStats 返回进程启动以来的日志写入统计
+func GetLogWriterStats() LogWriterStats {
+	stats := LogWriterStats{
+		Pending:     logWriter.pending.Load(),
+		MaxPending:  logWriter.maxPending.Load(),
+		Written:     logWriter.written.Load(),
+		Failed:      logWriter.failed.Load(),
+		LastFlushMs: float64(logWriter.lastFlushUs.Load()) / 1000,
+		MaxFlushMs:  float64(logWriter.flushMaxUs.Load()) / 1000,
+	}
+	if stats.Written > 0 {
+		stats.AvgFlushMs = float64(logWriter.flushTotalUs.Load()) / float64(stats.Written) / 1000
+	}
+	return stats
+}
+
+// WaitLogWrites 等待进行中的日志写入完成，ctx 结束时返回剩余的积压数
+// 日志在响应结束后由独立协程写入，首次检查前留出协程启动的时间
+func WaitLogWrites(ctx context.Context) int64 {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return logWriter.pending.Load()
+		case <-ticker.C:
+			if logWriter.pending.Load() == 0 {
+				return 0
+			}
+		}
+	}
+}
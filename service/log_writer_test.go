@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/models"
+)
+
+func TestLogWriterStats(t *testing.T) {
+	before := GetLogWriterStats()
+
+	end1 := beginLogWrite()
+	end2 := beginLogWrite()
+	if got := GetLogWriterStats(); got.Pending != before.Pending+2 || got.MaxPending < got.Pending {
+		t.Fatalf("pending = %d, max = %d, want %d", got.Pending, got.MaxPending, before.Pending+2)
+	}
+	observeLogFlush(time.Now().Add(-5*time.Millisecond), nil)
+	end1()
+	observeLogFlush(time.Now(), errors.New("database is locked"))
+	end2()
+
+	got := GetLogWriterStats()
+	if got.Pending != before.Pending {
+		t.Fatalf("pending = %d, want %d", got.Pending, before.Pending)
+	}
+	if got.Written != before.Written+1 || got.Failed != before.Failed+1 {
+		t.Fatalf("written = %d, failed = %d, want +1 each from %+v", got.Written, got.Failed, before)
+	}
+	if got.LastFlushMs < 5 || got.MaxFlushMs < 5 || got.AvgFlushMs <= 0 {
+		t.Fatalf("flush latency not recorded: %+v", got)
+	}
+}
+
+func TestWaitLogWrites(t *testing.T) {
+	end := beginLogWrite()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if pending := WaitLogWrites(ctx); pending < 1 {
+		t.Fatalf("pending = %d, want at least 1 before timeout", pending)
+	}
+
+	time.AfterFunc(20*time.Millisecond, end)
+	if pending := WaitLogWrites(context.Background()); pending != 0 {
+		t.Fatalf("pending = %d, want 0 after write finished", pending)
+	}
+}
+
+func TestRecordLogObservesFlushOnce(t *testing.T) {
+	db := setupTestDB(t, &models.ChatLog{}, &models.ChatIO{})
+
+	log := models.ChatLog{Name: "gpt"}
+	if err := db.Create(&log).Error; err != nil {
+		t.Fatalf("create log: %v", err)
+	}
+	// 处理期间删除 ChatIO 表，使 ChatLog 更新成功后输出写入失败
+	processer := func(ctx context.Context, pr io.Reader, stream bool, start time.Time) (*models.ChatLog, *models.OutputUnion, error) {
+		if err := db.Migrator().DropTable(&models.ChatIO{}); err != nil {
+			t.Fatalf("drop chat io: %v", err)
+		}
+		return ProcesserOpenAI(ctx, pr, stream, start)
+	}
+	body := `{"choices":[{"message":{"content":"a"}}],"usage":{"total_tokens":3}}`
+
+	before := GetLogWriterStats()
+	RecordLog(context.Background(), time.Now(), io.NopCloser(strings.NewReader(body)), processer, log.ID, 0, Before{}, true, nil, nil, "")
+	got := GetLogWriterStats()
+	if flushes := got.Written + got.Failed - before.Written - before.Failed; flushes != 1 {
+		t.Fatalf("observed %d flushes, want 1", flushes)
+	}
+}
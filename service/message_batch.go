@@ -0,0 +1,252 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/atopos31/llmio/balancers"
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/providers"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBatchModelMismatch = errors.New("all batch requests must use the same model")
+	ErrBatchNotFound      = errors.New("message batch not found")
+	ErrBatchNoProvider    = errors.New("no anthropic provider available for batch")
+)
+
+// batchTimeout 批处理接口只做提交与查询，使用固定的响应头超时
+const batchTimeout = 60 * time.Second
+
+// MessageBatchModel 返回批次请求使用的模型，所有请求必须使用同一模型
+func MessageBatchModel(raw []byte) (string, error) {
+	var model string
+	for _, req := range gjson.GetBytes(raw, "requests").Array() {
+		name := req.Get("params.model").String()
+		if name == "" || (model != "" && name != model) {
+			return "", ErrBatchModelMismatch
+		}
+		model = name
+	}
+	if model == "" {
+		return "", ErrBatchModelMismatch
+	}
+	return model, nil
+}
+
+// CreateMessageBatch 按权重选择原生 Anthropic 提供商创建批次，并记录批次与提供商的对应关系
+func CreateMessageBatch(ctx context.Context, raw []byte, model string, authKeyID uint) (int, []byte, error) {
+	meta, err := ProvidersWithMetaBymodelsName(ctx, consts.StyleAnthropic, Before{Model: model})
+	if err != nil {
+		return 0, nil, err
+	}
+	if meta.Translator != nil || len(meta.WeightItems) == 0 {
+		return 0, nil, ErrBatchNoProvider
+	}
+
+	count := int(gjson.GetBytes(raw, "requests.#").Int())
+	balancer := balancers.New(consts.BalancerLottery, meta.WeightItems, meta.PriorityItems)
+	var lastErr error = ErrBatchNoProvider
+	for {
+		id, err := balancer.Pop()
+		if err != nil {
+			return 0, nil, lastErr
+		}
+		mp := meta.ModelWithProviderMap[id]
+		provider := meta.ProviderMap[mp.ProviderID]
+
+		body := raw
+		for i := range count {
+			if body, err = sjson.SetBytes(body, fmt.Sprintf("requests.%d.params.model", i), mp.ProviderModel); err != nil {
+				return 0, nil, err
+			}
+		}
+		status, resBody, err := doBatchRequest(ctx, &provider, http.MethodPost, "", body)
+		if err != nil {
+			lastErr = err
+			balancer.Delete(id)
+			continue
+		}
+		// 上游故障或限流时换下一个提供商，其余错误原样返回给客户端
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			lastErr = fmt.Errorf("status: %d, body: %s", status, string(resBody))
+			balancer.Delete(id)
+			continue
+		}
+		if status != http.StatusOK {
+			return status, resBody, nil
+		}
+
+		batch := models.MessageBatch{
+			BatchID:      gjson.GetBytes(resBody, "id").String(),
+			Name:         model,
+			ProviderID:   provider.ID,
+			AuthKeyID:    authKeyID,
+			RequestCount: count,
+		}
+		applyBatchSnapshot(&batch, resBody)
+		if err := gorm.G[models.MessageBatch](models.DB).Create(ctx, &batch); err != nil {
+			return 0, nil, fmt.Errorf("save message batch: %w", err)
+		}
+		return status, resBody, nil
+	}
+}
+
+// ForwardMessageBatch 将批次查询/取消/删除请求转发到创建批次的提供商，并同步本地状态
+func ForwardMessageBatch(ctx context.Context, batchID string, authKeyID uint, method, path string) (int, []byte, error) {
+	batch, provider, err := findMessageBatch(ctx, batchID, authKeyID)
+	if err != nil {
+		return 0, nil, err
+	}
+	status, body, err := doBatchRequest(ctx, provider, method, "/"+batchID+path, nil)
+	if err != nil || status != http.StatusOK {
+		return status, body, err
+	}
+	if method == http.MethodDelete {
+		if _, err := gorm.G[models.MessageBatch](models.DB).Where("id = ?", batch.ID).Delete(ctx); err != nil {
+			return 0, nil, fmt.Errorf("delete message batch: %w", err)
+		}
+		return status, body, nil
+	}
+	applyBatchSnapshot(batch, body)
+	if err := models.DB.WithContext(ctx).Save(batch).Error; err != nil {
+		return 0, nil, fmt.Errorf("update message batch: %w", err)
+	}
+	return status, body, nil
+}
+
+// MessageBatchResults 返回批次结果的上游响应，结果为 JSONL，调用方负责关闭响应体
+func MessageBatchResults(ctx context.Context, batchID string, authKeyID uint) (*http.Response, error) {
+	_, provider, err := findMessageBatch(ctx, batchID, authKeyID)
+	if err != nil {
+		return nil, err
+	}
+	anthropic, err := batchProvider(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	req, err := anthropic.BuildBatchReq(ctx, http.MethodGet, "/"+batchID+"/results", nil)
+	if err != nil {
+		return nil, err
+	}
+	return providers.GetClient(batchTimeout, provider.ClientOptions()).Do(req)
+}
+
+// MessageBatchList 批次列表分页结果，格式与 Anthropic 接口一致
+type MessageBatchList struct {
+	Data    []json.RawMessage `json:"data"`
+	HasMore bool              `json:"has_more"`
+	FirstID *string           `json:"first_id"`
+	LastID  *string           `json:"last_id"`
+}
+
+// ListMessageBatches 从本地记录返回批次列表，按创建时间倒序，afterID 为上一页最后一个批次
+func ListMessageBatches(ctx context.Context, authKeyID uint, limit int, afterID string) (*MessageBatchList, error) {
+	query := gorm.G[models.MessageBatch](models.DB).Order("id DESC").Limit(limit + 1)
+	if authKeyID != 0 {
+		query = query.Where("auth_key_id = ?", authKeyID)
+	}
+	if afterID != "" {
+		cursor, _, err := findMessageBatch(ctx, afterID, authKeyID)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("id < ?", cursor.ID)
+	}
+	batches, err := query.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &MessageBatchList{Data: make([]json.RawMessage, 0, len(batches))}
+	if len(batches) > limit {
+		list.HasMore = true
+		batches = batches[:limit]
+	}
+	for _, batch := range batches {
+		list.Data = append(list.Data, json.RawMessage(batch.Snapshot))
+	}
+	if len(batches) > 0 {
+		list.FirstID = &batches[0].BatchID
+		list.LastID = &batches[len(batches)-1].BatchID
+	}
+	return list, nil
+}
+
+// findMessageBatch 查找批次及其提供商，非管理员只能访问自己创建的批次
+func findMessageBatch(ctx context.Context, batchID string, authKeyID uint) (*models.MessageBatch, *models.Provider, error) {
+	query := gorm.G[models.MessageBatch](models.DB).Where("batch_id = ?", batchID)
+	if authKeyID != 0 {
+		query = query.Where("auth_key_id = ?", authKeyID)
+	}
+	batch, err := query.First(ctx)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrBatchNotFound
+		}
+		return nil, nil, err
+	}
+	provider, err := gorm.G[models.Provider](models.DB).Where("id = ?", batch.ProviderID).First(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load batch provider: %w", err)
+	}
+	return &batch, &provider, nil
+}
+
+func batchProvider(ctx context.Context, provider *models.Provider) (*providers.Anthropic, error) {
+	chatModel, err := providers.New(ctx, provider.Type, provider.Config, provider.ClientOptions())
+	if err != nil {
+		return nil, err
+	}
+	anthropic, ok := chatModel.(*providers.Anthropic)
+	if !ok {
+		return nil, ErrBatchNoProvider
+	}
+	return anthropic, nil
+}
+
+func doBatchRequest(ctx context.Context, provider *models.Provider, method, path string, body []byte) (int, []byte, error) {
+	anthropic, err := batchProvider(ctx, provider)
+	if err != nil {
+		return 0, nil, err
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := anthropic.BuildBatchReq(ctx, method, path, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	res, err := providers.GetClient(batchTimeout, provider.ClientOptions()).Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, resBody, nil
+}
+
+// applyBatchSnapshot 用上游返回的批次对象更新本地状态
+func applyBatchSnapshot(batch *models.MessageBatch, body []byte) {
+	batch.Snapshot = string(body)
+	batch.ProcessingStatus = gjson.GetBytes(body, "processing_status").String()
+	if endedAt := gjson.GetBytes(body, "ended_at"); endedAt.Type == gjson.String {
+		if t, err := time.Parse(time.RFC3339, endedAt.String()); err == nil {
+			batch.EndedAt = &t
+		}
+	}
+}
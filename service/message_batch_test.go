@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/tidwall/gjson"
+)
+
+func TestMessageBatchProviderPinning(t *testing.T) {
+	var created string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/messages/batches":
+			body, _ := io.ReadAll(r.Body)
+			created = string(body)
+			io.WriteString(w, `{"id":"msgbatch_1","type":"message_batch","processing_status":"in_progress","ended_at":null}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/messages/batches/msgbatch_1":
+			io.WriteString(w, `{"id":"msgbatch_1","type":"message_batch","processing_status":"ended","ended_at":"2026-01-02T03:04:05Z"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	db := setupTestDB(t, &models.Provider{}, &models.Model{}, &models.ModelWithProvider{}, &models.MessageBatch{})
+	ctx := context.Background()
+
+	provider := models.Provider{Name: "claude", Type: consts.StyleAnthropic, Config: `{"base_url":"` + upstream.URL + `/v1","api_key":"k","version":"2023-06-01"}`}
+	model := models.Model{Name: "sonnet", MaxRetry: 3, TimeOut: 30}
+	db.Create(&provider)
+	db.Create(&model)
+	db.Create(&models.ModelWithProvider{ModelID: model.ID, ProviderID: provider.ID, ProviderModel: "claude-sonnet-4-5", Weight: 1, Status: new(true), Slot: consts.SlotBlue})
+
+	raw := []byte(`{"requests":[{"custom_id":"a","params":{"model":"sonnet","max_tokens":10,"messages":[]}},{"custom_id":"b","params":{"model":"sonnet","max_tokens":10,"messages":[]}}]}`)
+	name, err := MessageBatchModel(raw)
+	if err != nil || name != "sonnet" {
+		t.Fatalf("MessageBatchModel = %q, %v", name, err)
+	}
+	if _, err := MessageBatchModel([]byte(`{"requests":[{"params":{"model":"a"}},{"params":{"model":"b"}}]}`)); err != ErrBatchModelMismatch {
+		t.Fatalf("expected ErrBatchModelMismatch, got %v", err)
+	}
+
+	status, _, err := CreateMessageBatch(ctx, raw, name, 7)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("CreateMessageBatch = %d, %v", status, err)
+	}
+	if got := gjson.Get(created, "requests.1.params.model").String(); got != "claude-sonnet-4-5" {
+		t.Fatalf("upstream model = %q, want provider model", got)
+	}
+
+	if _, _, err := ForwardMessageBatch(ctx, "msgbatch_1", 8, http.MethodGet, ""); err != ErrBatchNotFound {
+		t.Fatalf("other keys should not see the batch, got %v", err)
+	}
+	if _, _, err := ForwardMessageBatch(ctx, "msgbatch_1", 7, http.MethodGet, ""); err != nil {
+		t.Fatalf("ForwardMessageBatch failed: %v", err)
+	}
+	list, err := ListMessageBatches(ctx, 7, 20, "")
+	if err != nil {
+		t.Fatalf("ListMessageBatches failed: %v", err)
+	}
+	if len(list.Data) != 1 || gjson.GetBytes(list.Data[0], "processing_status").String() != "ended" {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+	var batch models.MessageBatch
+	db.First(&batch)
+	if batch.ProviderID != provider.ID || batch.RequestCount != 2 || batch.EndedAt == nil {
+		t.Fatalf("unexpected local batch: %+v", batch)
+	}
+}
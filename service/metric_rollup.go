@@ -0,0 +1,378 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/env"
+	"gorm.io/gorm"
+)
+
+// metricRollupEnabled 汇总任务已启动时，日志清理不会删除尚未汇总的小时
+var metricRollupEnabled atomic.Bool
+
+// StartMetricRollup 定期把已结束的小时与天的日志汇总到 metric_rollups，看板查询只需扫描最近一小时的原始日志
+// LLMIO_METRIC_ROLLUP_INTERVAL 为汇总间隔分钟数，0 表示关闭
+func StartMetricRollup(ctx context.Context) {
+	minutes := env.GetWithDefault("LLMIO_METRIC_ROLLUP_INTERVAL", 5)
+	if minutes <= 0 {
+		return
+	}
+	metricRollupEnabled.Store(true)
+	go func() {
+		ticker := time.NewTicker(time.Duration(minutes) * time.Minute)
+		defer ticker.Stop()
+		for {
+			start := time.Now()
+			if n, err := RunMetricRollup(ctx, start); err != nil {
+				slog.Error("metric rollup failed", "error", err)
+			} else if n > 1 {
+				slog.Info("metric rollup completed", "hours", n, "duration", time.Since(start))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunMetricRollup 汇总 now 之前已结束的小时，返回处理的小时数
+// 每次都会重新汇总最近一个已汇总的小时，覆盖小时结束时仍在运行、之后才写入结果的请求
+func RunMetricRollup(ctx context.Context, now time.Time) (int, error) {
+	end := hourStart(now)
+	next, err := rollupBoundary(ctx, models.DB)
+	if err != nil {
+		return 0, err
+	}
+	start := next.Add(-time.Hour)
+	if next.IsZero() {
+		first, err := gorm.G[models.ChatLog](models.DB).Order("id").First(ctx)
+		if err == gorm.ErrRecordNotFound {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("load first log: %w", err)
+		}
+		start = hourStart(first.CreatedAt)
+	}
+
+	n := 0
+	for hour := start; hour.Before(end); hour = hour.Add(time.Hour) {
+		if err := rollupBucket(ctx, models.RollupHour, hour, hour.Add(time.Hour)); err != nil {
+			return n, err
+		}
+		n++
+		// 一天的最后一个小时汇总后再汇总整天，天级分位数按原始日志计算
+		if dayEnd := dayStart(hour).AddDate(0, 0, 1); !hour.Add(time.Hour).Before(dayEnd) {
+			if err := rollupBucket(ctx, models.RollupDay, dayStart(hour), dayEnd); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// rollupBoundary 返回第一个尚未汇总的小时，此前的小时均已汇总，没有汇总数据时返回零值
+// 没有请求的小时不会产生汇总行，由原始日志兜底时同样为空，不影响结果
+func rollupBoundary(ctx context.Context, db *gorm.DB) (time.Time, error) {
+	last, err := gorm.G[models.MetricRollup](db).Where("period = ?", models.RollupHour).Order("bucket_start DESC").First(ctx)
+	if err == gorm.ErrRecordNotFound {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("load rollup boundary: %w", err)
+	}
+	return last.BucketStart.Add(time.Hour), nil
+}
+
+// retentionCutoff 将日志清理的截止时间限制在汇总边界的前一小时之前，
+// 尚未汇总的小时以及每次会重新汇总的最近一小时都保留原始日志，避免清理先于汇总执行时统计永久丢失
+func retentionCutoff(ctx context.Context, cutoff time.Time) (time.Time, error) {
+	if !metricRollupEnabled.Load() {
+		return cutoff, nil
+	}
+	boundary, err := rollupBoundary(ctx, models.DB)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if boundary.IsZero() {
+		// 尚未汇总过，等待首次汇总完成后再清理
+		return time.Time{}, nil
+	}
+	if keep := boundary.Add(-time.Hour); keep.Before(cutoff) {
+		return keep, nil
+	}
+	return cutoff, nil
+}
+
+type rollupKey struct {
+	model     string
+	provider  string
+	authKeyID uint
+}
+
+type rollupAcc struct {
+	row        models.MetricRollup
+	firstChunk []time.Duration
+	latency    []time.Duration
+}
+
+// rollupBucket 按原始日志重新计算一个周期的汇总，并替换该周期已有的汇总行
+// 读取主库：只读副本的延迟会让汇总行永久少计
+func rollupBucket(ctx context.Context, period string, start, end time.Time) error {
+	rows, err := models.DB.WithContext(ctx).Model(&models.ChatLog{}).
+		Select("name, provider_name, auth_key_id, status, prompt_tokens, completion_tokens, total_tokens, first_chunk_time, chunk_time").
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Rows()
+	if err != nil {
+		return fmt.Errorf("query logs: %w", err)
+	}
+	defer rows.Close()
+
+	accs := make(map[rollupKey]*rollupAcc)
+	for rows.Next() {
+		var (
+			key                       rollupKey
+			status                    string
+			prompt, completion, total int64
+			firstChunkTime, chunkTime time.Duration
+		)
+		if err := rows.Scan(&key.model, &key.provider, &key.authKeyID, &status, &prompt, &completion, &total, &firstChunkTime, &chunkTime); err != nil {
+			return fmt.Errorf("scan log: %w", err)
+		}
+		acc, ok := accs[key]
+		if !ok {
+			acc = &rollupAcc{row: models.MetricRollup{
+				Period:       period,
+				BucketStart:  start,
+				Model:        key.model,
+				ProviderName: key.provider,
+				AuthKeyID:    key.authKeyID,
+			}}
+			accs[key] = acc
+		}
+		acc.row.Requests++
+		acc.row.PromptTokens += prompt
+		acc.row.CompletionTokens += completion
+		acc.row.TotalTokens += total
+		switch status {
+		case consts.StatusError:
+			acc.row.Errors++
+		case consts.StatusSuccess:
+			if firstChunkTime > 0 {
+				acc.firstChunk = append(acc.firstChunk, firstChunkTime)
+			}
+			acc.latency = append(acc.latency, firstChunkTime+chunkTime)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read logs: %w", err)
+	}
+
+	list := make([]models.MetricRollup, 0, len(accs))
+	for _, acc := range accs {
+		acc.row.FirstChunkP50Ms, acc.row.FirstChunkP95Ms, acc.row.FirstChunkP99Ms = percentilesMs(acc.firstChunk)
+		acc.row.LatencyP50Ms, acc.row.LatencyP95Ms, acc.row.LatencyP99Ms = percentilesMs(acc.latency)
+		list = append(list, acc.row)
+	}
+	return models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("period = ? AND bucket_start = ?", period, start).Delete(&models.MetricRollup{}).Error; err != nil {
+			return fmt.Errorf("delete rollups: %w", err)
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(list, 500).Error; err != nil {
+			return fmt.Errorf("create rollups: %w", err)
+		}
+		return nil
+	})
+}
+
+// percentilesMs 按最近秩法计算 p50/p95/p99，单位毫秒
+func percentilesMs(values []time.Duration) (int64, int64, int64) {
+	if len(values) == 0 {
+		return 0, 0, 0
+	}
+	slices.Sort(values)
+	at := func(p int) int64 {
+		i := (len(values)*p + 99) / 100
+		return values[max(i-1, 0)].Milliseconds()
+	}
+	return at(50), at(95), at(99)
+}
+
+// RollupSum 按维度合计的请求数、错误数与 Token 用量
+type RollupSum struct {
+	Model            string `json:"model,omitempty"`
+	AuthKeyID        uint   `json:"auth_key_id,omitempty"`
+	Requests         int64  `json:"requests"`
+	Errors           int64  `json:"errors"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	TotalTokens      int64  `json:"total_tokens"`
+}
+
+// 合计维度
+const (
+	RollupByNone    = ""
+	RollupByModel   = "model"
+	RollupByAuthKey = "auth_key_id"
+)
+
+// SumMetrics 合计 since 之后的请求，已汇总的天与小时读取 metric_rollups，尚未汇总的部分读取原始日志
+// since 应为整点或零值，by 为合计维度，结果按请求数降序
+func SumMetrics(ctx context.Context, since time.Time, by string) ([]RollupSum, error) {
+	boundary, err := rollupBoundary(ctx, models.ReadDB())
+	if err != nil {
+		return nil, err
+	}
+	// 汇总表与日志表的分组列名不同，日志的 name 对应汇总的 model
+	var rollupGroup, logGroup string
+	switch by {
+	case RollupByModel:
+		rollupGroup, logGroup = "model", "name"
+	case RollupByAuthKey:
+		rollupGroup, logGroup = "auth_key_id", "auth_key_id"
+	}
+	sums := make(map[RollupSum]*RollupSum)
+	add := func(rows []RollupSum) {
+		for _, row := range rows {
+			key := RollupSum{Model: row.Model, AuthKeyID: row.AuthKeyID}
+			sum, ok := sums[key]
+			if !ok {
+				sum = &key
+				sums[key] = sum
+			}
+			sum.Requests += row.Requests
+			sum.Errors += row.Errors
+			sum.PromptTokens += row.PromptTokens
+			sum.CompletionTokens += row.CompletionTokens
+			sum.TotalTokens += row.TotalTokens
+		}
+	}
+	columns := "COALESCE(SUM(requests), 0) AS requests, COALESCE(SUM(errors), 0) AS errors, COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, COALESCE(SUM(completion_tokens), 0) AS completion_tokens, COALESCE(SUM(total_tokens), 0) AS total_tokens"
+	queryRollups := func(period string, from, to time.Time) error {
+		if !from.Before(to) {
+			return nil
+		}
+		var rows []RollupSum
+		query := models.ReadDB().WithContext(ctx).Model(&models.MetricRollup{}).
+			Where("period = ? AND bucket_start >= ? AND bucket_start < ?", period, from, to)
+		if rollupGroup != "" {
+			query = query.Select(rollupGroup + ", " + columns).Group(rollupGroup)
+		} else {
+			query = query.Select(columns)
+		}
+		if err := query.Scan(&rows).Error; err != nil {
+			return fmt.Errorf("query %s rollups: %w", period, err)
+		}
+		add(rows)
+		return nil
+	}
+
+	logsFrom := since
+	if !boundary.IsZero() && since.Before(boundary) {
+		// 完整的天读取天级汇总，其余小时读取小时汇总
+		daysEnd := dayStart(boundary)
+		daysFrom := dayStart(since)
+		if daysFrom.Before(since) {
+			daysFrom = daysFrom.AddDate(0, 0, 1)
+		}
+		leadingEnd := daysFrom
+		if boundary.Before(leadingEnd) {
+			leadingEnd = boundary
+		}
+		trailingStart := daysEnd
+		if trailingStart.Before(daysFrom) {
+			trailingStart = daysFrom
+		}
+		if err := queryRollups(models.RollupHour, since, leadingEnd); err != nil {
+			return nil, err
+		}
+		if err := queryRollups(models.RollupDay, daysFrom, daysEnd); err != nil {
+			return nil, err
+		}
+		if err := queryRollups(models.RollupHour, trailingStart, boundary); err != nil {
+			return nil, err
+		}
+		logsFrom = boundary
+	}
+
+	var rows []RollupSum
+	query := models.ReadDB().WithContext(ctx).Model(&models.ChatLog{}).
+		Where("created_at >= ?", logsFrom)
+	logColumns := fmt.Sprintf("COUNT(*) AS requests, COALESCE(SUM(CASE WHEN status = '%s' THEN 1 ELSE 0 END), 0) AS errors, COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, COALESCE(SUM(completion_tokens), 0) AS completion_tokens, COALESCE(SUM(total_tokens), 0) AS total_tokens", consts.StatusError)
+	if logGroup != "" {
+		query = query.Select(logGroup + " AS " + rollupGroup + ", " + logColumns).Group(logGroup)
+	} else {
+		query = query.Select(logColumns)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("query logs: %w", err)
+	}
+	add(rows)
+
+	result := make([]RollupSum, 0, len(sums))
+	for _, sum := range sums {
+		if sum.Requests > 0 {
+			result = append(result, *sum)
+		}
+	}
+	slices.SortFunc(result, func(a, b RollupSum) int {
+		return cmp.Or(cmp.Compare(b.Requests, a.Requests), cmp.Compare(a.Model, b.Model), cmp.Compare(a.AuthKeyID, b.AuthKeyID))
+	})
+	return result, nil
+}
+
+// RollupFilter 查询汇总行的筛选条件，零值表示不限制
+type RollupFilter struct {
+	Period       string
+	Start        time.Time
+	End          time.Time
+	Model        string
+	ProviderName string
+	AuthKeyID    *uint
+}
+
+// GetMetricRollups 按时间升序返回汇总行
+func GetMetricRollups(ctx context.Context, filter RollupFilter) ([]models.MetricRollup, error) {
+	query := gorm.G[models.MetricRollup](models.ReadDB()).Where("period = ?", filter.Period)
+	if !filter.Start.IsZero() {
+		query = query.Where("bucket_start >= ?", filter.Start)
+	}
+	if !filter.End.IsZero() {
+		query = query.Where("bucket_start < ?", filter.End)
+	}
+	if filter.Model != "" {
+		query = query.Where("model = ?", filter.Model)
+	}
+	if filter.ProviderName != "" {
+		query = query.Where("provider_name = ?", filter.ProviderName)
+	}
+	if filter.AuthKeyID != nil {
+		query = query.Where("auth_key_id = ?", *filter.AuthKeyID)
+	}
+	return query.Order("bucket_start, id").Find(ctx)
+}
+
+// hourStart 本地时区的整点，兼容非整小时偏移的时区
+func hourStart(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, t.Hour(), 0, 0, 0, t.Location())
+}
+
+// dayStart 本地时区的零点
+func dayStart(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
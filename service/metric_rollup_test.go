@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestPercentilesMs(t *testing.T) {
+	values := make([]time.Duration, 0, 100)
+	for i := 100; i >= 1; i-- {
+		values = append(values, time.Duration(i)*time.Millisecond)
+	}
+	if p50, p95, p99 := percentilesMs(values); p50 != 50 || p95 != 95 || p99 != 99 {
+		t.Fatalf("percentiles = %d/%d/%d, want 50/95/99", p50, p95, p99)
+	}
+	if p50, p95, p99 := percentilesMs([]time.Duration{7 * time.Millisecond}); p50 != 7 || p95 != 7 || p99 != 7 {
+		t.Fatalf("single value percentiles = %d/%d/%d", p50, p95, p99)
+	}
+}
+
+func TestMetricRollup(t *testing.T) {
+	db := setupTestDB(t, &models.ChatLog{}, &models.MetricRollup{})
+	ctx := context.Background()
+
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.Local)
+	logs := []struct {
+		at     time.Time
+		model  string
+		key    uint
+		status string
+		tokens int64
+		first  time.Duration
+	}{
+		{now.AddDate(0, 0, -2), "a", 1, consts.StatusSuccess, 10, 100 * time.Millisecond},
+		{now.AddDate(0, 0, -2), "a", 1, consts.StatusSuccess, 20, 300 * time.Millisecond},
+		{now.AddDate(0, 0, -1), "b", 2, consts.StatusError, 0, 0},
+		{now.Add(-2 * time.Hour), "a", 2, consts.StatusSuccess, 5, 200 * time.Millisecond},
+		// 当前小时尚未汇总，由原始日志补齐
+		{now.Add(-10 * time.Minute), "b", 1, consts.StatusSuccess, 7, 50 * time.Millisecond},
+	}
+	for _, l := range logs {
+		log := models.ChatLog{Name: l.model, ProviderName: "p", AuthKeyID: l.key, Status: l.status, FirstChunkTime: l.first, ChunkTime: time.Second}
+		log.TotalTokens = l.tokens
+		log.CreatedAt = l.at
+		if err := db.Create(&log).Error; err != nil {
+			t.Fatalf("create log: %v", err)
+		}
+	}
+
+	hours, err := RunMetricRollup(ctx, now)
+	if err != nil {
+		t.Fatalf("RunMetricRollup failed: %v", err)
+	}
+	if want := int(hourStart(now).Sub(hourStart(now.AddDate(0, 0, -2))) / time.Hour); hours != want {
+		t.Fatalf("hours = %d, want %d", hours, want)
+	}
+
+	days, err := GetMetricRollups(ctx, RollupFilter{Period: models.RollupDay})
+	if err != nil || len(days) != 2 {
+		t.Fatalf("day rollups = %+v, err = %v", days, err)
+	}
+	first := days[0]
+	if first.Model != "a" || first.Requests != 2 || first.TotalTokens != 30 || first.FirstChunkP50Ms != 100 || first.FirstChunkP99Ms != 300 || first.LatencyP99Ms != 1300 {
+		t.Fatalf("first day rollup = %+v", first)
+	}
+	if days[1].Errors != 1 {
+		t.Fatalf("second day rollup = %+v", days[1])
+	}
+
+	// 重复执行只会重算最近一个小时，结果不变
+	if hours, err := RunMetricRollup(ctx, now); err != nil || hours != 2 {
+		t.Fatalf("rerun hours = %d, err = %v", hours, err)
+	}
+
+	byModel, err := SumMetrics(ctx, time.Time{}, RollupByModel)
+	if err != nil {
+		t.Fatalf("SumMetrics failed: %v", err)
+	}
+	if len(byModel) != 2 || byModel[0].Model != "a" || byModel[0].Requests != 3 || byModel[0].TotalTokens != 35 || byModel[1].Requests != 2 || byModel[1].Errors != 1 {
+		t.Fatalf("sums by model = %+v", byModel)
+	}
+
+	since := dayStart(now).AddDate(0, 0, -1)
+	total, err := SumMetrics(ctx, since, RollupByNone)
+	if err != nil || len(total) != 1 || total[0].Requests != 3 || total[0].TotalTokens != 12 {
+		t.Fatalf("sums since %s = %+v, err = %v", since, total, err)
+	}
+
+	byKey, err := SumMetrics(ctx, since, RollupByAuthKey)
+	if err != nil || len(byKey) != 2 || byKey[0].AuthKeyID != 2 || byKey[0].Requests != 2 {
+		t.Fatalf("sums by key = %+v, err = %v", byKey, err)
+	}
+}
+
+func TestRetentionKeepsUnrolledLogs(t *testing.T) {
+	db := setupTestDB(t, &models.ChatLog{}, &models.ChatIO{}, &models.MetricRollup{})
+	metricRollupEnabled.Store(true)
+	t.Cleanup(func() { metricRollupEnabled.Store(false) })
+	ctx := context.Background()
+
+	now := time.Now()
+	for _, at := range []time.Time{now.AddDate(0, 0, -40), now.AddDate(0, 0, -35), now} {
+		log := models.ChatLog{Name: "a", Status: consts.StatusSuccess}
+		log.CreatedAt = at
+		if err := db.Create(&log).Error; err != nil {
+			t.Fatalf("create log: %v", err)
+		}
+	}
+
+	// 尚未汇总时不清理，避免统计丢失
+	if deleted, err := CleanLogsByDays(ctx, 30); err != nil || deleted != 0 {
+		t.Fatalf("before rollup: deleted = %d, err = %v", deleted, err)
+	}
+	if _, err := RunMetricRollup(ctx, now.AddDate(0, 0, -36)); err != nil {
+		t.Fatalf("RunMetricRollup failed: %v", err)
+	}
+	// 最近汇总的小时每次会重新汇总，其原始日志同样保留
+	if deleted, err := CleanLogsByDays(ctx, 30); err != nil || deleted != 0 {
+		t.Fatalf("partial rollup: deleted = %d, err = %v", deleted, err)
+	}
+	if _, err := RunMetricRollup(ctx, now); err != nil {
+		t.Fatalf("RunMetricRollup failed: %v", err)
+	}
+	// 40 天前的日志已完成汇总，35 天前的日志所在小时是最近汇总的小时
+	if deleted, err := CleanLogsByDays(ctx, 30); err != nil || deleted != 1 {
+		t.Fatalf("after rollup: deleted = %d, err = %v", deleted, err)
+	}
+	sums, err := SumMetrics(ctx, time.Time{}, RollupByNone)
+	if err != nil || len(sums) != 1 || sums[0].Requests != 3 {
+		t.Fatalf("expected all requests kept in rollups, got %+v, %v", sums, err)
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/env"
+	"github.com/atopos31/llmio/pkg/rotatefile"
+	"gorm.io/gorm"
+)
+
+// MirrorRecord 镜像到本地文件的请求摘要，只包含元数据，不含请求与响应正文
+type MirrorRecord struct {
+	Time             time.Time `json:"time"`
+	LogID            uint      `json:"log_id"`
+	TraceID          string    `json:"trace_id"`
+	Model            string    `json:"model"`
+	ProviderName     string    `json:"provider_name"`
+	ProviderModel    string    `json:"provider_model"`
+	Style            string    `json:"style"`
+	Status           string    `json:"status"`
+	Error            string    `json:"error,omitempty"`
+	Retry            int       `json:"retry"`
+	AuthKeyID        uint      `json:"auth_key_id"`
+	SessionID        string    `json:"session_id,omitempty"`
+	Stream           bool      `json:"stream"`
+	ToolCall         bool      `json:"tool_call"`
+	StructuredOutput bool      `json:"structured_output"`
+	Image            bool      `json:"image"`
+	RequestSize      int       `json:"request_size"`
+	ResponseSize     int       `json:"response_size"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	TotalTokens      int64     `json:"total_tokens"`
+	CachedTokens     int64     `json:"cached_tokens"`
+	FinishReason     string    `json:"finish_reason,omitempty"`
+	ProxyMs          int64     `json:"proxy_ms"`
+	FirstChunkMs     int64     `json:"first_chunk_ms"`
+	ChunkMs          int64     `json:"chunk_ms"`
+}
+
+var (
+	mirrorOnce   sync.Once
+	mirrorWriter io.Writer
+)
+
+// mirrorOutput 返回镜像文件写入器，未配置 LLMIO_MIRROR_FILE 时为 nil
+func mirrorOutput() io.Writer {
+	mirrorOnce.Do(func() {
+		path := env.GetWithDefault("LLMIO_MIRROR_FILE", "")
+		if path == "" {
+			return
+		}
+		maxBytes := int64(env.GetWithDefault("LLMIO_MIRROR_MAX_MB", 100)) << 20
+		mirrorWriter = rotatefile.New(path, maxBytes, env.GetWithDefault("LLMIO_MIRROR_MAX_FILES", 5))
+	})
+	return mirrorWriter
+}
+
+// newMirrorRecord 由日志与请求特征生成请求摘要
+func newMirrorRecord(log models.ChatLog, before *Before) MirrorRecord {
+	record := MirrorRecord{
+		Time:             log.CreatedAt,
+		LogID:            log.ID,
+		TraceID:          log.TraceID,
+		Model:            log.Name,
+		ProviderName:     log.ProviderName,
+		ProviderModel:    log.ProviderModel,
+		Style:            log.Style,
+		Status:           log.Status,
+		Error:            log.Error,
+		Retry:            log.Retry,
+		AuthKeyID:        log.AuthKeyID,
+		SessionID:        log.SessionID,
+		RequestSize:      log.RequestSize,
+		ResponseSize:     log.Size,
+		PromptTokens:     log.PromptTokens,
+		CompletionTokens: log.CompletionTokens,
+		TotalTokens:      log.TotalTokens,
+		CachedTokens:     log.PromptTokensDetails.CachedTokens,
+		FinishReason:     log.FinishReason,
+		ProxyMs:          log.ProxyTime.Milliseconds(),
+		FirstChunkMs:     log.FirstChunkTime.Milliseconds(),
+		ChunkMs:          log.ChunkTime.Milliseconds(),
+	}
+	if before != nil {
+		record.Stream = before.Stream
+		record.ToolCall = before.toolCall
+		record.StructuredOutput = before.structuredOutput
+		record.Image = before.image
+	}
+	return record
+}
+
+// writeMirror 将请求摘要以 JSONL 追加到镜像文件
+func writeMirror(w io.Writer, log models.ChatLog, before *Before) error {
+	line, err := json.Marshal(newMirrorRecord(log, before))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// mirrorLog 读取日志最终状态并写入镜像文件，未开启镜像时不做任何操作
+func mirrorLog(ctx context.Context, logID uint, before *Before) {
+	w := mirrorOutput()
+	if w == nil {
+		return
+	}
+	log, err := gorm.G[models.ChatLog](models.DB).Omit("timeline").Where("id = ?", logID).First(ctx)
+	if err != nil {
+		slog.Error("load log for mirror", "logId", logID, "error", err)
+		return
+	}
+	if err := writeMirror(w, log, before); err != nil {
+		slog.Error("write request mirror", "logId", logID, "error", err)
+	}
+}
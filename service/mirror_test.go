@@ -0,0 +1,42 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/atopos31/llmio/models"
+)
+
+func TestWriteMirror(t *testing.T) {
+	var buf bytes.Buffer
+	log := models.ChatLog{
+		Name:         "gpt",
+		ProviderName: "openai",
+		Status:       "success",
+		Usage:        models.Usage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8},
+	}
+	log.ID = 7
+	before := &Before{Stream: true, toolCall: true, raw: []byte(`{"messages":[{"role":"user","content":"secret prompt"}]}`)}
+	for range 2 {
+		if err := writeMirror(&buf, log, before); err != nil {
+			t.Fatalf("writeMirror: %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %d, want 2", len(lines))
+	}
+	var record MirrorRecord
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("invalid JSONL line: %v", err)
+	}
+	if record.LogID != 7 || record.TotalTokens != 8 || !record.Stream || !record.ToolCall {
+		t.Errorf("unexpected record: %+v", record)
+	}
+	if strings.Contains(buf.String(), "secret prompt") {
+		t.Error("mirror record must not contain request content")
+	}
+}
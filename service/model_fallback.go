@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+// GetModelFallback 读取未知模型的转发规则，未配置时返回 nil
+func GetModelFallback(ctx context.Context) (*models.ModelFallback, error) {
+	config, err := gorm.G[models.Config](models.DB).Where("key = ?", models.KeyModelFallback).First(ctx)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if config.Value == "" {
+		return nil, nil
+	}
+	var fallback models.ModelFallback
+	if err := json.Unmarshal([]byte(config.Value), &fallback); err != nil {
+		return nil, fmt.Errorf("unmarshal model fallback: %w", err)
+	}
+	return &fallback, nil
+}
+
+// ValidModelFallback 校验转发规则配置，规则必须同时指定匹配模式与提供商
+func ValidModelFallback(value string) bool {
+	var fallback models.ModelFallback
+	if err := json.Unmarshal([]byte(value), &fallback); err != nil {
+		return false
+	}
+	for _, rule := range fallback.Rules {
+		if strings.TrimSpace(rule.Pattern) == "" || rule.ProviderID == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fallbackProvider 按规则顺序匹配模型名，均未命中时使用默认提供商，返回 0 表示不转发
+func fallbackProvider(fallback *models.ModelFallback, name string) uint {
+	if fallback == nil {
+		return 0
+	}
+	for _, rule := range fallback.Rules {
+		if globMatch(strings.TrimSpace(rule.Pattern), name) {
+			return rule.ProviderID
+		}
+	}
+	return fallback.DefaultProviderID
+}
+
+// globMatch 匹配仅包含 * 通配符的模式，* 可匹配任意字符(包括 /)
+func globMatch(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return strings.HasSuffix(name, last)
+}
+
+// fallbackProvidersWithMeta 未知模型命中转发规则时，构造只包含该提供商的路由，模型名原样发往上游
+func fallbackProvidersWithMeta(ctx context.Context, style string, before Before) (*ProvidersWithMeta, bool, error) {
+	fallback, err := GetModelFallback(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	providerID := fallbackProvider(fallback, before.Model)
+	if providerID == 0 {
+		return nil, false, nil
+	}
+	model := models.Model{
+		Name:     before.Model,
+		MaxRetry: bootstrapMaxRetry,
+		TimeOut:  bootstrapTimeOut,
+		Strategy: consts.BalancerDefault,
+	}
+	// 关联不落库，ID 为 0 表示不计入熔断、配额等按关联统计的功能
+	association := models.ModelWithProvider{
+		ProviderModel: before.Model,
+		ProviderID:    providerID,
+		Weight:        1,
+	}
+	meta, err := buildProvidersWithMeta(ctx, style, model, []models.ModelWithProvider{association})
+	if err != nil {
+		return nil, false, err
+	}
+	return meta, true, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"deepseek-*", "deepseek-chat", true},
+		{"deepseek-*", "deepseek", false},
+		{"*", "anything/with-slash", true},
+		{"gpt-*-mini", "gpt-4o-mini", true},
+		{"gpt-*-mini", "gpt-4o", false},
+		{"a*a", "a", false},
+		{"qwen", "qwen", true},
+		{"qwen", "qwen-max", false},
+	}
+	for _, tt := range tests {
+		if got := globMatch(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestModelFallback(t *testing.T) {
+	db := setupTestDB(t, &models.Provider{}, &models.Model{}, &models.ModelWithProvider{}, &models.ChatLog{}, &models.Config{})
+	ctx := context.Background()
+
+	deepseek := models.Provider{Name: "deepseek", Type: consts.StyleOpenAI, Config: `{"base_url":"http://127.0.0.1","api_key":"k"}`}
+	fallback := models.Provider{Name: "default", Type: consts.StyleOpenAI, Config: `{"base_url":"http://127.0.0.1","api_key":"k"}`}
+	for _, p := range []*models.Provider{&deepseek, &fallback} {
+		if err := db.Create(p).Error; err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+	}
+
+	if _, err := ProvidersWithMetaBymodelsName(ctx, consts.StyleOpenAI, Before{Model: "deepseek-chat"}); !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("err = %v, want ErrModelNotFound without rules", err)
+	}
+
+	value := `{"rules":[{"pattern":"deepseek-*","provider_id":1}],"default_provider_id":2}`
+	if !ValidModelFallback(value) || ValidModelFallback(`{"rules":[{"pattern":"","provider_id":1}]}`) {
+		t.Fatal("ValidModelFallback mismatch")
+	}
+	if err := db.Create(&models.Config{Key: models.KeyModelFallback, Value: value}).Error; err != nil {
+		t.Fatalf("create config: %v", err)
+	}
+
+	for name, want := range map[string]uint{"deepseek-chat": deepseek.ID, "some-new-model": fallback.ID} {
+		meta, err := ProvidersWithMetaBymodelsName(ctx, consts.StyleOpenAI, Before{Model: name})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		association := meta.ModelWithProviderMap[0]
+		if association.ProviderID != want || association.ProviderModel != name || len(meta.WeightItems) != 1 {
+			t.Fatalf("%s routed to %+v", name, association)
+		}
+	}
+}
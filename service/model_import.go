@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// aliasSuffixes 生成别名时去掉的版本标记
+var aliasSuffixes = []string{"-latest", ":latest"}
+
+// ModelImportItem 导入提供商模型时上游模型与对外模型名的映射
+type ModelImportItem struct {
+	ProviderModel string `json:"provider_model"`
+	Alias         string `json:"alias"`
+	ModelExists   bool   `json:"model_exists"` // 同名模型已存在，导入时只添加关联
+	Associated    bool   `json:"associated"`   // 该提供商模型已关联到同名模型，导入时跳过
+	Duplicate     bool   `json:"duplicate"`    // 与本次导入中前面的模型别名相同，导入时跳过
+}
+
+// ModelAlias 去掉 models/ 前缀、厂商路径前缀与 -latest 等后缀，生成简洁的对外模型名
+func ModelAlias(name string) string {
+	alias := strings.TrimPrefix(strings.TrimSpace(name), "models/")
+	if i := strings.LastIndex(alias, "/"); i >= 0 && i < len(alias)-1 {
+		alias = alias[i+1:]
+	}
+	for _, suffix := range aliasSuffixes {
+		alias = strings.TrimSuffix(alias, suffix)
+	}
+	if alias == "" {
+		return name
+	}
+	return strings.ToLower(alias)
+}
+
+// PreviewModelImport 计算导入映射，aliases 中的手动别名优先，autoAlias 为 false 时沿用上游模型名
+func PreviewModelImport(ctx context.Context, providerID uint, providerModels []string, aliases map[string]string, autoAlias bool) ([]ModelImportItem, error) {
+	items := make([]ModelImportItem, 0, len(providerModels))
+	seen := make(map[string]bool)
+	for _, providerModel := range lo.Uniq(providerModels) {
+		alias := strings.TrimSpace(aliases[providerModel])
+		if alias == "" {
+			alias = lo.Ternary(autoAlias, ModelAlias(providerModel), providerModel)
+		}
+		items = append(items, ModelImportItem{ProviderModel: providerModel, Alias: alias, Duplicate: seen[alias]})
+		seen[alias] = true
+	}
+
+	existing, err := gorm.G[models.Model](models.DB).Where("name IN ?", lo.Map(items, func(item ModelImportItem, _ int) string { return item.Alias })).Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	modelIDs := lo.SliceToMap(existing, func(m models.Model) (string, uint) { return m.Name, m.ID })
+	associations, err := gorm.G[models.ModelWithProvider](models.DB).Where("provider_id = ?", providerID).Where("model_id IN ?", lo.Values(modelIDs)).Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		id, ok := modelIDs[items[i].Alias]
+		items[i].ModelExists = ok
+		items[i].Associated = ok && lo.ContainsBy(associations, func(mp models.ModelWithProvider) bool {
+			return mp.ModelID == id && mp.ProviderModel == items[i].ProviderModel
+		})
+	}
+	return items, nil
+}
+
+// ApplyModelImport 按映射创建缺少的模型与关联，返回新建的关联数
+func ApplyModelImport(ctx context.Context, providerID uint, items []ModelImportItem) (int, error) {
+	created := 0
+	err := models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var maxDisplayOrder int
+		if err := tx.Model(&models.Model{}).Select("COALESCE(MAX(display_order), 0)").Scan(&maxDisplayOrder).Error; err != nil {
+			return fmt.Errorf("query model order: %w", err)
+		}
+		for _, item := range items {
+			if item.Duplicate || item.Associated {
+				continue
+			}
+			model := models.Model{
+				Name:             item.Alias,
+				MaxRetry:         bootstrapMaxRetry,
+				TimeOut:          bootstrapTimeOut,
+				Strategy:         consts.BalancerDefault,
+				Breaker:          new(false),
+				ValidateResponse: new(false),
+				DisplayOrder:     maxDisplayOrder + 1,
+			}
+			result := tx.Where("name = ?", item.Alias).FirstOrCreate(&model)
+			if result.Error != nil {
+				return fmt.Errorf("create model %s: %w", item.Alias, result.Error)
+			}
+			if result.RowsAffected > 0 {
+				maxDisplayOrder++
+			}
+			association := models.ModelWithProvider{
+				ModelID:          model.ID,
+				ProviderModel:    item.ProviderModel,
+				ProviderID:       providerID,
+				ToolCall:         new(true),
+				StructuredOutput: new(true),
+				Image:            new(false),
+				Chat:             new(true),
+				Embedding:        new(false),
+				ImageGeneration:  new(false),
+				Rerank:           new(false),
+				Moderation:       new(false),
+				WithHeader:       new(false),
+				PseudoStream:     new(false),
+				StreamAggregate:  new(false),
+				Status:           new(true),
+				CustomerHeaders:  map[string]string{},
+				ExtraBody:        map[string]any{},
+				Weight:           1,
+				Priority:         1,
+				InputPrice:       new(0.0),
+				CacheReadPrice:   new(0.0),
+				OutputPrice:      new(0.0),
+				Currency:         "CNY",
+				Slot:             ActiveSlot(),
+			}
+			if err := tx.Create(&association).Error; err != nil {
+				return fmt.Errorf("create association %s: %w", item.ProviderModel, err)
+			}
+			created++
+		}
+		return nil
+	})
+	InvalidateRouteCache()
+	return created, err
+}
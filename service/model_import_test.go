@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+func TestModelAlias(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"gpt-4o", "gpt-4o"},
+		{"models/gemini-2.5-pro", "gemini-2.5-pro"},
+		{"deepseek-ai/DeepSeek-V3", "deepseek-v3"},
+		{"anthropic/claude-sonnet-latest", "claude-sonnet"},
+		{"qwen3:latest", "qwen3"},
+		{"vendor/", "vendor/"},
+	}
+	for _, tt := range tests {
+		if got := ModelAlias(tt.name); got != tt.want {
+			t.Errorf("ModelAlias(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestModelImport(t *testing.T) {
+	db := setupTestDB(t, &models.Model{}, &models.ModelWithProvider{})
+	ctx := context.Background()
+
+	if err := db.Create(&models.Model{Name: "deepseek-v3"}).Error; err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+	upstream := []string{"deepseek-ai/DeepSeek-V3", "Pro/deepseek-ai/DeepSeek-V3", "Qwen/Qwen3-8B", "Qwen/Qwen3-8B"}
+	items, err := PreviewModelImport(ctx, 1, upstream, map[string]string{"Qwen/Qwen3-8B": "qwen3"}, true)
+	if err != nil {
+		t.Fatalf("PreviewModelImport failed: %v", err)
+	}
+	want := []ModelImportItem{
+		{ProviderModel: "deepseek-ai/DeepSeek-V3", Alias: "deepseek-v3", ModelExists: true},
+		{ProviderModel: "Pro/deepseek-ai/DeepSeek-V3", Alias: "deepseek-v3", ModelExists: true, Duplicate: true},
+		{ProviderModel: "Qwen/Qwen3-8B", Alias: "qwen3"},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("items = %+v, want %+v", items, want)
+	}
+	for i := range want {
+		if items[i] != want[i] {
+			t.Fatalf("items[%d] = %+v, want %+v", i, items[i], want[i])
+		}
+	}
+
+	created, err := ApplyModelImport(ctx, 1, items)
+	if err != nil || created != 2 {
+		t.Fatalf("ApplyModelImport = %d, %v, want 2", created, err)
+	}
+	// 再次导入时已存在的关联会被跳过
+	items, err = PreviewModelImport(ctx, 1, upstream, map[string]string{"Qwen/Qwen3-8B": "qwen3"}, true)
+	if err != nil {
+		t.Fatalf("PreviewModelImport failed: %v", err)
+	}
+	if !items[0].Associated || !items[2].Associated {
+		t.Fatalf("items = %+v, want existing associations marked", items)
+	}
+	if created, err = ApplyModelImport(ctx, 1, items); err != nil || created != 0 {
+		t.Fatalf("second ApplyModelImport = %d, %v, want 0", created, err)
+	}
+	count, err := gorm.G[models.Model](db).Count(ctx, "id")
+	if err != nil || count != 2 {
+		t.Fatalf("model count = %d, %v, want 2", count, err)
+	}
+}
@@ -14,7 +14,7 @@ func ModelsByTypes(ctx context.Context, modelTypes ...string) ([]models.Model, e
 		return nil, err
 	}
 
-	modelWithProviders, err := gorm.G[models.ModelWithProvider](models.DB).Where("provider_id IN ?", lo.Map(llmproviders, func(p models.Provider, _ int) uint { return p.ID })).Find(ctx)
+	modelWithProviders, err := gorm.G[models.ModelWithProvider](models.DB).Where("provider_id IN ?", lo.Map(llmproviders, func(p models.Provider, _ int) uint { return p.ID })).Where("slot = ?", ActiveSlot()).Find(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -25,3 +25,35 @@ func ModelsByTypes(ctx context.Context, modelTypes ...string) ([]models.Model, e
 	}
 	return models, nil
 }
+
+// ModelCapabilities 模型在当前分组已启用关联上的能力，任一关联支持即视为支持
+type ModelCapabilities struct {
+	Chat             bool `json:"chat"`
+	ToolCall         bool `json:"tool_call"`
+	StructuredOutput bool `json:"structured_output"`
+	Vision           bool `json:"vision"`
+	Embedding        bool `json:"embedding"`
+	ImageGeneration  bool `json:"image_generation"`
+	Rerank           bool `json:"rerank"`
+	Moderation       bool `json:"moderation"`
+}
+
+// GetModelCapabilities 汇总模型已启用关联的能力
+func GetModelCapabilities(ctx context.Context, modelID uint) (ModelCapabilities, error) {
+	var caps ModelCapabilities
+	list, err := gorm.G[models.ModelWithProvider](models.DB).Where("model_id = ?", modelID).Where("status = ?", true).Where("slot = ?", ActiveSlot()).Find(ctx)
+	if err != nil {
+		return caps, err
+	}
+	for _, mp := range list {
+		caps.Chat = caps.Chat || lo.FromPtrOr(mp.Chat, true)
+		caps.ToolCall = caps.ToolCall || lo.FromPtr(mp.ToolCall)
+		caps.StructuredOutput = caps.StructuredOutput || lo.FromPtr(mp.StructuredOutput)
+		caps.Vision = caps.Vision || lo.FromPtr(mp.Image)
+		caps.Embedding = caps.Embedding || lo.FromPtr(mp.Embedding)
+		caps.ImageGeneration = caps.ImageGeneration || lo.FromPtr(mp.ImageGeneration)
+		caps.Rerank = caps.Rerank || lo.FromPtr(mp.Rerank)
+		caps.Moderation = caps.Moderation || lo.FromPtr(mp.Moderation)
+	}
+	return caps, nil
+}
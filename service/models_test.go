@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atopos31/llmio/models"
+)
+
+func TestGetModelCapabilities(t *testing.T) {
+	db := setupTestDB(t, &models.ModelWithProvider{})
+
+	associations := []models.ModelWithProvider{
+		{ModelID: 1, Status: new(true), Slot: ActiveSlot(), Chat: new(true), ToolCall: new(true)},
+		{ModelID: 1, Status: new(true), Slot: ActiveSlot(), Image: new(true)},
+		// 停用的关联不计入能力
+		{ModelID: 1, Status: new(false), Slot: ActiveSlot(), Embedding: new(true)},
+		{ModelID: 2, Status: new(true), Slot: ActiveSlot(), Chat: new(false), Rerank: new(true)},
+	}
+	if err := db.Create(&associations).Error; err != nil {
+		t.Fatalf("create associations: %v", err)
+	}
+
+	tests := []struct {
+		modelID uint
+		want    ModelCapabilities
+	}{
+		{1, ModelCapabilities{Chat: true, ToolCall: true, Vision: true}},
+		{2, ModelCapabilities{Rerank: true}},
+		{3, ModelCapabilities{}},
+	}
+	for _, tt := range tests {
+		got, err := GetModelCapabilities(context.Background(), tt.modelID)
+		if err != nil {
+			t.Fatalf("GetModelCapabilities(%d) failed: %v", tt.modelID, err)
+		}
+		if got != tt.want {
+			t.Fatalf("GetModelCapabilities(%d) = %+v, want %+v", tt.modelID, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/providers"
+	"github.com/tidwall/gjson"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+const (
+	outageWindow         = 10 * time.Minute
+	outageMinRequests    = 5
+	outageErrorRate      = 0.5
+	outageMinFailingMods = 2 // 同一厂商下至少两个关联同时失败才判定为厂商故障
+	statusCacheTTL       = 30 * time.Second
+)
+
+// VendorStatus 按 base_url 主机聚合的厂商状态
+type VendorStatus struct {
+	Vendor              string     `json:"vendor"`
+	Status              string     `json:"status"` // operational / degraded
+	Since               *time.Time `json:"since,omitempty"`
+	Requests            int64      `json:"requests"`
+	Errors              int64      `json:"errors"`
+	ErrorRate           float64    `json:"error_rate"`
+	FailingAssociations int        `json:"failing_associations"`
+	Label               string     `json:"-"` // 公开状态页展示的厂商或提供商类型，不暴露主机
+}
+
+// StatusPage 公共状态页
+type StatusPage struct {
+	Status    string         `json:"status"`
+	Window    string         `json:"window"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Vendors   []VendorStatus `json:"vendors"`
+}
+
+var (
+	statusPage    atomic.Pointer[StatusPage]
+	statusRefresh singleflight.Group
+	// statusDegraded 仅在 statusRefresh 内读写，同一时刻只有一次刷新
+	statusDegraded map[string]bool
+)
+
+// GetStatusPage 返回带缓存的厂商状态，缓存未过期时无锁读取；过期后并发请求合并为一次刷新，状态变化时输出告警日志
+func GetStatusPage(ctx context.Context) (*StatusPage, error) {
+	if page := statusPage.Load(); page != nil && time.Since(page.UpdatedAt) < statusCacheTTL {
+		return page, nil
+	}
+	ch := statusRefresh.DoChan("status", func() (any, error) {
+		if page := statusPage.Load(); page != nil && time.Since(page.UpdatedAt) < statusCacheTTL {
+			return page, nil
+		}
+		// 刷新结果由所有等待者共享，不受发起者断开影响
+		page, err := DetectVendorOutages(context.WithoutCancel(ctx), time.Now())
+		if err != nil {
+			return nil, err
+		}
+		degraded := make(map[string]bool, len(page.Vendors))
+		for _, vendor := range page.Vendors {
+			isDegraded := vendor.Status == consts.VendorDegraded
+			degraded[vendor.Vendor] = isDegraded
+			if isDegraded != statusDegraded[vendor.Vendor] {
+				if isDegraded {
+					slog.Warn("vendor degraded", "vendor", vendor.Vendor, "since", vendor.Since, "error_rate", vendor.ErrorRate, "failing_associations", vendor.FailingAssociations)
+				} else {
+					slog.Info("vendor recovered", "vendor", vendor.Vendor)
+				}
+			}
+		}
+		statusDegraded = degraded
+		statusPage.Store(page)
+		return page, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*StatusPage), nil
+	}
+}
+
+// DetectVendorOutages 关联同一厂商（base_url 主机）下多个关联在窗口期内的失败，识别厂商级故障
+func DetectVendorOutages(ctx context.Context, now time.Time) (*StatusPage, error) {
+	providerList, err := gorm.G[models.Provider](models.DB).Where("retire_state != ?", consts.RetireStateArchived).Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vendorByProvider := make(map[string]string, len(providerList))
+	labelByVendor := make(map[string]string)
+	for _, provider := range providerList {
+		vendor := providerVendor(provider)
+		vendorByProvider[provider.Name] = vendor
+		if _, ok := labelByVendor[vendor]; !ok {
+			labelByVendor[vendor] = providerLabel(provider)
+		}
+	}
+
+	since := now.Add(-outageWindow)
+	// 进行中与客户端取消的请求不反映厂商健康状况，分子分母均不计入
+	var rows []struct {
+		ProviderName  string
+		ProviderModel string
+		Requests      int64
+		Errors        int64
+	}
+	if err := models.ReadDB().WithContext(ctx).Model(&models.ChatLog{}).
+		Select("provider_name, provider_model, COUNT(*) AS requests, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS errors", consts.StatusError).
+		Where("created_at >= ?", since).
+		Where("status NOT IN ?", consts.HealthExcludedStatuses).
+		Group("provider_name, provider_model").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	type vendorStat struct {
+		requests  int64
+		errors    int64
+		failing   int
+		providers []string
+	}
+	stats := make(map[string]*vendorStat)
+	for _, row := range rows {
+		vendor, ok := vendorByProvider[row.ProviderName]
+		if !ok {
+			continue
+		}
+		stat, ok := stats[vendor]
+		if !ok {
+			stat = &vendorStat{}
+			stats[vendor] = stat
+		}
+		stat.requests += row.Requests
+		stat.errors += row.Errors
+		if row.Errors > 0 {
+			stat.failing++
+		}
+		if !slices.Contains(stat.providers, row.ProviderName) {
+			stat.providers = append(stat.providers, row.ProviderName)
+		}
+	}
+
+	page := &StatusPage{
+		Status:    consts.VendorOperational,
+		Window:    outageWindow.String(),
+		UpdatedAt: now,
+		Vendors:   make([]VendorStatus, 0, len(stats)),
+	}
+	for vendor, stat := range stats {
+		status := VendorStatus{
+			Vendor:              vendor,
+			Status:              consts.VendorOperational,
+			Requests:            stat.requests,
+			Errors:              stat.errors,
+			ErrorRate:           float64(stat.errors) / float64(stat.requests),
+			FailingAssociations: stat.failing,
+			Label:               labelByVendor[vendor],
+		}
+		if stat.requests >= outageMinRequests && status.ErrorRate >= outageErrorRate && stat.failing >= outageMinFailingMods {
+			status.Status = consts.VendorDegraded
+			status.Since, err = outageSince(ctx, stat.providers, since)
+			if err != nil {
+				return nil, err
+			}
+			page.Status = consts.VendorDegraded
+		}
+		page.Vendors = append(page.Vendors, status)
+	}
+	sort.Slice(page.Vendors, func(i, j int) bool { return page.Vendors[i].Vendor < page.Vendors[j].Vendor })
+	return page, nil
+}
+
+// outageSince 以窗口内最近一次成功之后的第一次失败作为故障开始时间，仅对判定为故障的厂商查询
+func outageSince(ctx context.Context, providerNames []string, since time.Time) (*time.Time, error) {
+	query := gorm.G[models.ChatLog](models.ReadDB()).
+		Select("created_at").
+		Where("provider_name IN ?", providerNames)
+	lastSuccess, err := query.Where("created_at >= ?", since).Where("status = ?", consts.StatusSuccess).Order("created_at DESC").First(ctx)
+	switch {
+	case err == nil:
+		since = lastSuccess.CreatedAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+	firstError, err := query.Where("created_at > ?", since).Where("status = ?", consts.StatusError).Order("created_at ASC").First(ctx)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &firstError.CreatedAt, nil
+}
+
+// providerLabel 公开展示用的厂商名，未知主机(含自建与内网地址)只展示提供商类型
+func providerLabel(provider models.Provider) string {
+	if u, err := url.Parse(gjson.Get(provider.Config, "base_url").String()); err == nil {
+		if vendor := providers.VendorOfHost(u.Hostname()); vendor != "" {
+			return vendor
+		}
+	}
+	return provider.Type
+}
+
+// PublicVendorStatus 公开状态页的厂商状态，不含主机与请求量
+type PublicVendorStatus struct {
+	Vendor    string     `json:"vendor"`
+	Status    string     `json:"status"`
+	Since     *time.Time `json:"since,omitempty"`
+	ErrorRate float64    `json:"error_rate"`
+}
+
+// PublicStatusPage 无需鉴权的公开状态页
+type PublicStatusPage struct {
+	Status    string               `json:"status"`
+	Window    string               `json:"window"`
+	UpdatedAt time.Time            `json:"updated_at"`
+	Vendors   []PublicVendorStatus `json:"vendors"`
+}
+
+// NewPublicStatusPage 按展示名合并厂商状态，任一主机故障即视为该厂商故障
+func NewPublicStatusPage(page *StatusPage) PublicStatusPage {
+	type merged struct {
+		status           PublicVendorStatus
+		requests, errors int64
+	}
+	byLabel := make(map[string]*merged)
+	for _, vendor := range page.Vendors {
+		m, ok := byLabel[vendor.Label]
+		if !ok {
+			m = &merged{status: PublicVendorStatus{Vendor: vendor.Label, Status: consts.VendorOperational}}
+			byLabel[vendor.Label] = m
+		}
+		m.requests += vendor.Requests
+		m.errors += vendor.Errors
+		if vendor.Status == consts.VendorDegraded {
+			m.status.Status = consts.VendorDegraded
+			if vendor.Since != nil && (m.status.Since == nil || vendor.Since.Before(*m.status.Since)) {
+				m.status.Since = vendor.Since
+			}
+		}
+	}
+	public := PublicStatusPage{
+		Status:    page.Status,
+		Window:    page.Window,
+		UpdatedAt: page.UpdatedAt,
+		Vendors:   make([]PublicVendorStatus, 0, len(byLabel)),
+	}
+	for _, m := range byLabel {
+		if m.requests > 0 {
+			m.status.ErrorRate = float64(m.errors) / float64(m.requests)
+		}
+		public.Vendors = append(public.Vendors, m.status)
+	}
+	sort.Slice(public.Vendors, func(i, j int) bool { return public.Vendors[i].Vendor < public.Vendors[j].Vendor })
+	return public
+}
+
+// providerVendor 以 base_url 主机标识厂商，解析失败时退化为提供商类型
+func providerVendor(provider models.Provider) string {
+	if u, err := url.Parse(gjson.Get(provider.Config, "base_url").String()); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return provider.Type
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestDetectVendorOutages(t *testing.T) {
+	db := setupTestDB(t, &models.Provider{}, &models.ChatLog{})
+
+	providers := []models.Provider{
+		{Name: "oa-1", Type: consts.StyleOpenAI, Config: `{"base_url":"https://api.openai.com/v1"}`},
+		{Name: "oa-2", Type: consts.StyleOpenAI, Config: `{"base_url":"https://api.openai.com/v1"}`},
+		{Name: "claude", Type: consts.StyleAnthropic, Config: `{"base_url":"https://api.anthropic.com/v1"}`},
+	}
+	if err := db.Create(&providers).Error; err != nil {
+		t.Fatalf("create providers: %v", err)
+	}
+
+	now := time.Now()
+	logs := []models.ChatLog{
+		{ProviderName: "oa-1", ProviderModel: "gpt", Status: consts.StatusSuccess},
+		{ProviderName: "oa-1", ProviderModel: "gpt", Status: consts.StatusError},
+		{ProviderName: "oa-2", ProviderModel: "gpt", Status: consts.StatusError},
+		{ProviderName: "oa-1", ProviderModel: "gpt", Status: consts.StatusError},
+		{ProviderName: "oa-2", ProviderModel: "gpt", Status: consts.StatusError},
+		{ProviderName: "claude", ProviderModel: "sonnet", Status: consts.StatusError},
+		{ProviderName: "claude", ProviderModel: "sonnet", Status: consts.StatusSuccess},
+		// 客户端取消不计入请求数，否则会稀释错误率
+		{ProviderName: "oa-1", ProviderModel: "gpt", Status: consts.StatusCanceled},
+		{ProviderName: "oa-1", ProviderModel: "gpt", Status: consts.StatusCanceled},
+		{ProviderName: "oa-2", ProviderModel: "gpt", Status: consts.StatusCanceled},
+		{ProviderName: "oa-2", ProviderModel: "gpt", Status: consts.StatusCanceled},
+	}
+	for i := range logs {
+		logs[i].CreatedAt = now.Add(time.Duration(i-len(logs)) * 30 * time.Second)
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("create logs: %v", err)
+	}
+
+	page, err := DetectVendorOutages(context.Background(), now)
+	if err != nil {
+		t.Fatalf("DetectVendorOutages failed: %v", err)
+	}
+	if page.Status != consts.VendorDegraded || len(page.Vendors) != 2 {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+	anthropic, openai := page.Vendors[0], page.Vendors[1]
+	if anthropic.Vendor != "api.anthropic.com" || anthropic.Status != consts.VendorOperational {
+		t.Fatalf("unexpected anthropic status: %+v", anthropic)
+	}
+	if openai.Vendor != "api.openai.com" || openai.Status != consts.VendorDegraded || openai.FailingAssociations != 2 || openai.Requests != 5 || openai.Errors != 4 {
+		t.Fatalf("unexpected openai status: %+v", openai)
+	}
+	if openai.Since == nil || !openai.Since.Equal(logs[1].CreatedAt) {
+		t.Fatalf("expected outage since first error after last success, got %v", openai.Since)
+	}
+
+	cached, err := GetStatusPage(context.Background())
+	if err != nil {
+		t.Fatalf("GetStatusPage failed: %v", err)
+	}
+	t.Cleanup(func() { statusPage.Store(nil) })
+	if again, _ := GetStatusPage(context.Background()); again != cached || cached.Status != consts.VendorDegraded {
+		t.Fatalf("expected cached page to be reused, got %+v", again)
+	}
+}
+
+func TestNewPublicStatusPage(t *testing.T) {
+	since := time.Now().Add(-time.Minute)
+	page := &StatusPage{
+		Status: consts.VendorDegraded,
+		Vendors: []VendorStatus{
+			{Vendor: "10.0.0.5:8000", Label: consts.StyleOpenAI, Status: consts.VendorDegraded, Since: &since, Requests: 6, Errors: 6},
+			{Vendor: "api.openai.com", Label: consts.StyleOpenAI, Status: consts.VendorOperational, Requests: 4},
+			{Vendor: "api.deepseek.com", Label: "deepseek", Status: consts.VendorOperational, Requests: 3, Errors: 1},
+		},
+	}
+	public := NewPublicStatusPage(page)
+	if len(public.Vendors) != 2 {
+		t.Fatalf("unexpected vendors: %+v", public.Vendors)
+	}
+	deepseek, openai := public.Vendors[0], public.Vendors[1]
+	if deepseek.Vendor != "deepseek" || deepseek.Status != consts.VendorOperational {
+		t.Fatalf("unexpected deepseek status: %+v", deepseek)
+	}
+	if openai.Vendor != consts.StyleOpenAI || openai.Status != consts.VendorDegraded || openai.Since != &since || openai.ErrorRate != 0.6 {
+		t.Fatalf("unexpected openai status: %+v", openai)
+	}
+
+	for _, tt := range []struct {
+		config string
+		want   string
+	}{
+		{`{"base_url":"http://10.0.0.5:8000/v1"}`, consts.StyleOpenAI},
+		{`{"base_url":"https://api.deepseek.com/v1"}`, "deepseek"},
+	} {
+		if got := providerLabel(models.Provider{Type: consts.StyleOpenAI, Config: tt.config}); got != tt.want {
+			t.Fatalf("providerLabel(%s) = %q, want %q", tt.config, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type paramPaths struct {
+	temperature []string
+	topP        []string
+	maxTokens   []string
+}
+
+// 各协议中采样参数所在的 JSON 路径
+var stylesParamPaths = map[string]paramPaths{
+	consts.StyleOpenAI: {
+		temperature: []string{"temperature"},
+		topP:        []string{"top_p"},
+		maxTokens:   []string{"max_tokens", "max_completion_tokens"},
+	},
+	consts.StyleOpenAIRes: {
+		temperature: []string{"temperature"},
+		topP:        []string{"top_p"},
+		maxTokens:   []string{"max_output_tokens"},
+	},
+	consts.StyleAnthropic: {
+		temperature: []string{"temperature"},
+		topP:        []string{"top_p"},
+		maxTokens:   []string{"max_tokens"},
+	},
+	consts.StyleGemini: {
+		temperature: []string{"generationConfig.temperature", "generation_config.temperature"},
+		topP:        []string{"generationConfig.topP", "generation_config.top_p"},
+		maxTokens:   []string{"generationConfig.maxOutputTokens", "generation_config.max_output_tokens"},
+	},
+}
+
+// ApplyParamRanges 按模型配置校验请求中的采样参数，clamp 模式下截断越界值，reject 模式下返回错误
+func (b *Before) ApplyParamRanges(style string, ranges *models.ParamRanges) error {
+	if ranges == nil {
+		return nil
+	}
+	paths, ok := stylesParamPaths[style]
+	if !ok {
+		return nil
+	}
+	checks := []struct {
+		paths   []string
+		r       *models.ParamRange
+		integer bool
+	}{
+		{paths.temperature, ranges.Temperature, false},
+		{paths.topP, ranges.TopP, false},
+		{paths.maxTokens, ranges.MaxTokens, true},
+	}
+	for _, check := range checks {
+		if check.r == nil {
+			continue
+		}
+		for _, path := range check.paths {
+			value := gjson.GetBytes(b.raw, path)
+			if !value.Exists() || value.Type != gjson.Number {
+				continue
+			}
+			clamped, inRange := clampParam(value.Float(), check.r)
+			if inRange {
+				continue
+			}
+			if ranges.Mode == consts.ParamRangeReject {
+				return i18n.NewError(i18n.MsgParamOutOfRange, path, value.Raw, formatParamRange(check.r))
+			}
+			var err error
+			if check.integer {
+				b.raw, err = sjson.SetBytes(b.raw, path, int64(clamped))
+			} else {
+				b.raw, err = sjson.SetBytes(b.raw, path, clamped)
+			}
+			if err != nil {
+				return fmt.Errorf("clamp %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func clampParam(value float64, r *models.ParamRange) (float64, bool) {
+	if r.Min != nil && value < *r.Min {
+		return *r.Min, false
+	}
+	if r.Max != nil && value > *r.Max {
+		return *r.Max, false
+	}
+	return value, true
+}
+
+func formatParamRange(r *models.ParamRange) string {
+	bound := func(v *float64, open string) string {
+		if v == nil {
+			return open
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+	return "[" + bound(r.Min, "-inf") + ", " + bound(r.Max, "+inf") + "]"
+}
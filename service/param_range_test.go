@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/i18n"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyParamRanges(t *testing.T) {
+	ranges := &models.ParamRanges{
+		Temperature: &models.ParamRange{Min: new(0.0), Max: new(1.0)},
+		MaxTokens:   &models.ParamRange{Max: new(1024.0)},
+	}
+	tests := []struct {
+		name    string
+		style   string
+		mode    string
+		body    string
+		path    string
+		want    float64
+		wantErr bool
+	}{
+		{"openai clamp temperature", consts.StyleOpenAI, consts.ParamRangeClamp, `{"temperature":1.8}`, "temperature", 1, false},
+		{"openai clamp max_completion_tokens", consts.StyleOpenAI, "", `{"max_completion_tokens":4096}`, "max_completion_tokens", 1024, false},
+		{"anthropic in range", consts.StyleAnthropic, consts.ParamRangeReject, `{"temperature":0.5}`, "temperature", 0.5, false},
+		{"anthropic reject", consts.StyleAnthropic, consts.ParamRangeReject, `{"temperature":-1}`, "", 0, true},
+		{"gemini clamp", consts.StyleGemini, consts.ParamRangeClamp, `{"generationConfig":{"maxOutputTokens":9000}}`, "generationConfig.maxOutputTokens", 1024, false},
+		{"responses clamp", consts.StyleOpenAIRes, consts.ParamRangeClamp, `{"max_output_tokens":2048}`, "max_output_tokens", 1024, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := *ranges
+			r.Mode = tt.mode
+			before := &Before{raw: []byte(tt.body)}
+			err := before.ApplyParamRanges(tt.style, &r)
+			if tt.wantErr {
+				var i18nErr *i18n.Error
+				if !errors.As(err, &i18nErr) || i18nErr.Msg != i18n.MsgParamOutOfRange {
+					t.Fatalf("expected param out of range error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := gjson.GetBytes(before.raw, tt.path).Float(); got != tt.want {
+				t.Fatalf("%s = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
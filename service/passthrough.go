@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/atopos31/llmio/balancers"
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// RawReader 原始透传时只统计响应字节数与首包耗时，不解析内容
+type RawReader struct {
+	r     io.Reader
+	start time.Time
+	n     int
+	first time.Duration
+}
+
+func NewRawReader(r io.Reader, start time.Time) *RawReader {
+	return &RawReader{r: r, start: start}
+}
+
+func (r *RawReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 && r.n == 0 {
+		r.first = time.Since(r.start)
+	}
+	r.n += n
+	return n, err
+}
+
+// RecordRawLog 原始透传结束后更新日志状态、大小与耗时，err 为转发过程中的错误
+func RecordRawLog(ctx context.Context, reader *RawReader, logId uint, modelProviderID uint, before Before, webhook string, err error) {
+	defer beginLogWrite()()
+	log := models.ChatLog{
+		Status:         consts.StatusSuccess,
+		FirstChunkTime: reader.first,
+		ChunkTime:      time.Since(reader.start) - reader.first,
+		Size:           reader.n,
+	}
+	if err != nil {
+		log.Status = lo.Ternary(errors.Is(err, ErrClientCanceled), consts.StatusCanceled, consts.StatusError)
+		log.Error = err.Error()
+	} else if modelProviderID > 0 {
+		balancers.ObserveLatency(modelProviderID, reader.first)
+	}
+	flushStart := time.Now()
+	_, updateErr := gorm.G[models.ChatLog](models.DB).Where("id = ?", logId).Updates(ctx, log)
+	observeLogFlush(flushStart, updateErr)
+	if updateErr != nil {
+		slog.Error("record raw log error", "error", updateErr)
+	}
+	signChatLog(ctx, logId)
+	sendWebhook(ctx, webhook, logId, &before, false)
+}
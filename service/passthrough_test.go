@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+func TestRecordRawLog(t *testing.T) {
+	db := setupTestDB(t, &models.ChatLog{})
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		err    error
+		status string
+	}{
+		{"success", nil, consts.StatusSuccess},
+		{"client gone", errors.New("broken pipe"), consts.StatusError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := SaveChatLog(ctx, models.ChatLog{Name: "m", Status: consts.StatusRunning})
+			if err != nil {
+				t.Fatalf("SaveChatLog failed: %v", err)
+			}
+			body := "data: {\"choices\":[]}\n\ndata: [DONE]\n\n"
+			reader := NewRawReader(strings.NewReader(body), time.Now())
+			if _, err := io.Copy(io.Discard, reader); err != nil {
+				t.Fatalf("copy: %v", err)
+			}
+			RecordRawLog(ctx, reader, id, 0, Before{Model: "m", Stream: true}, "", tt.err)
+
+			log, err := gorm.G[models.ChatLog](db).Where("id = ?", id).First(ctx)
+			if err != nil {
+				t.Fatalf("load log: %v", err)
+			}
+			if log.Status != tt.status || log.Size != len(body) || log.TotalTokens != 0 {
+				t.Fatalf("log = status %s size %d tokens %d", log.Status, log.Size, log.TotalTokens)
+			}
+			if tt.err != nil && log.Error != tt.err.Error() {
+				t.Fatalf("error = %q", log.Error)
+			}
+		})
+	}
+}
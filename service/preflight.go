@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+const (
+	PreflightPass = "pass"
+	PreflightFail = "fail"
+	PreflightSkip = "skip"
+
+	// preflightMaxClockSkew 允许的本机时钟偏差
+	preflightMaxClockSkew = 5 * time.Minute
+)
+
+// PreflightCheck 单项启动检查结果
+type PreflightCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// PreflightReport 启动检查报告
+type PreflightReport struct {
+	Passed    bool             `json:"passed"`
+	CheckedAt time.Time        `json:"checked_at"`
+	Checks    []PreflightCheck `json:"checks"`
+}
+
+// PreflightOptions 启动检查所需的外部信息
+type PreflightOptions struct {
+	WebUI   fs.FS  // 前端构建产物，包含 index.html 与 assets/
+	DBPath  string // SQLite 数据库文件路径
+	TimeURL string // 用于校对时钟的地址，取响应头 Date，空则跳过
+}
+
+var (
+	preflightMu   sync.Mutex
+	preflightOpts PreflightOptions
+)
+
+// assetRef 匹配 index.html 中引用的构建产物
+var assetRef = regexp.MustCompile(`(?:src|href)="(?:\./|/)?(assets/[^"]+)"`)
+
+// InitPreflight 记录检查参数并执行一次检查，供启动时使用
+func InitPreflight(ctx context.Context, opts PreflightOptions) PreflightReport {
+	preflightMu.Lock()
+	preflightOpts = opts
+	preflightMu.Unlock()
+	return Preflight(ctx)
+}
+
+// Preflight 按启动时的参数重新执行检查
+func Preflight(ctx context.Context) PreflightReport {
+	preflightMu.Lock()
+	opts := preflightOpts
+	preflightMu.Unlock()
+
+	report := PreflightReport{Passed: true, CheckedAt: time.Now()}
+	for _, check := range []struct {
+		name string
+		fn   func(context.Context, PreflightOptions) (string, error)
+	}{
+		{"webui", checkWebUI},
+		{"db_write", checkDBWrite},
+		{"clock", checkClock},
+	} {
+		status, err := check.fn(ctx, opts)
+		item := PreflightCheck{Name: check.name, Status: status}
+		if err != nil {
+			item.Status = PreflightFail
+			item.Message = err.Error()
+			report.Passed = false
+		} else if item.Status == "" {
+			item.Status = PreflightPass
+		}
+		report.Checks = append(report.Checks, item)
+	}
+	return report
+}
+
+// checkWebUI 校验前端产物已构建，且 index.html 引用的资源都存在
+func checkWebUI(_ context.Context, opts PreflightOptions) (string, error) {
+	if opts.WebUI == nil {
+		return PreflightSkip, nil
+	}
+	index, err := fs.ReadFile(opts.WebUI, "index.html")
+	if err != nil {
+		return "", fmt.Errorf("web UI index.html not found, build webui first: %w", err)
+	}
+	refs := assetRef.FindAllSubmatch(index, -1)
+	if len(refs) == 0 {
+		return "", errors.New("web UI index.html references no assets, build webui first (pnpm build)")
+	}
+	for _, ref := range refs {
+		if _, err := fs.Stat(opts.WebUI, string(ref[1])); err != nil {
+			return "", fmt.Errorf("web UI asset %s is missing, rebuild webui", ref[1])
+		}
+	}
+	return "", nil
+}
+
+// checkDBWrite 校验数据库目录与文件可写，避免首次写入时才报错
+func checkDBWrite(_ context.Context, opts PreflightOptions) (string, error) {
+	if opts.DBPath == "" {
+		return PreflightSkip, nil
+	}
+	dir := filepath.Dir(opts.DBPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create database directory %s: %w", dir, err)
+	}
+	// SQLite 需要在同一目录创建日志文件，因此目录本身也必须可写
+	f, err := os.CreateTemp(dir, ".preflight-*")
+	if err != nil {
+		return "", fmt.Errorf("database directory %s is not writable: %w", dir, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	db, err := os.OpenFile(opts.DBPath, os.O_RDWR, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("database file %s is not writable: %w", opts.DBPath, err)
+	}
+	db.Close()
+	return "", nil
+}
+
+// checkClock 与 TimeURL 的 Date 响应头及最新日志时间比对，发现本机时钟明显偏差
+func checkClock(ctx context.Context, opts PreflightOptions) (string, error) {
+	checked := false
+	now := time.Now()
+	if opts.TimeURL != "" {
+		remote, err := fetchRemoteTime(ctx, opts.TimeURL)
+		if err != nil {
+			return "", err
+		}
+		if skew := now.Sub(remote); skew > preflightMaxClockSkew || skew < -preflightMaxClockSkew {
+			return "", fmt.Errorf("local clock is %s off from %s", skew.Round(time.Second), opts.TimeURL)
+		}
+		checked = true
+	}
+	if models.DB != nil {
+		latest, err := gorm.G[models.ChatLog](models.DB).Select("created_at").Order("id DESC").First(ctx)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("load latest log: %w", err)
+		}
+		if err == nil {
+			if behind := latest.CreatedAt.Sub(now); behind > preflightMaxClockSkew {
+				return "", fmt.Errorf("local clock is %s behind the latest request log", behind.Round(time.Second))
+			}
+			checked = true
+		}
+	}
+	if !checked {
+		return PreflightSkip, nil
+	}
+	return "", nil
+}
+
+func fetchRemoteTime(ctx context.Context, url string) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	req.Header.Set("User-Agent", "llmio/"+consts.Version)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("fetch time from %s: %w", url, err)
+	}
+	res.Body.Close()
+	remote, err := http.ParseTime(res.Header.Get("Date"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse Date header from %s: %w", url, err)
+	}
+	return remote, nil
+}
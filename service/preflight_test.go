@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestPreflight(t *testing.T) {
+	built := fstest.MapFS{
+		"index.html":           {Data: []byte(`<script type="module" src="/assets/index-abc.js"></script><link rel="stylesheet" href="/assets/index-abc.css">`)},
+		"assets/index-abc.js":  {Data: []byte("")},
+		"assets/index-abc.css": {Data: []byte("")},
+	}
+	skewed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	}))
+	defer skewed.Close()
+	synced := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer synced.Close()
+
+	tests := []struct {
+		name string
+		opts PreflightOptions
+		want map[string]string
+	}{
+		{
+			name: "all pass",
+			opts: PreflightOptions{WebUI: built, DBPath: filepath.Join(t.TempDir(), "db", "llmio.db"), TimeURL: synced.URL},
+			want: map[string]string{"webui": PreflightPass, "db_write": PreflightPass, "clock": PreflightPass},
+		},
+		{
+			name: "placeholder webui",
+			opts: PreflightOptions{WebUI: fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}},
+			want: map[string]string{"webui": PreflightFail, "db_write": PreflightSkip, "clock": PreflightSkip},
+		},
+		{
+			name: "missing asset",
+			opts: PreflightOptions{WebUI: fstest.MapFS{"index.html": built["index.html"]}},
+			want: map[string]string{"webui": PreflightFail},
+		},
+		{
+			name: "skewed clock",
+			opts: PreflightOptions{TimeURL: skewed.URL},
+			want: map[string]string{"webui": PreflightSkip, "clock": PreflightFail},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := InitPreflight(context.Background(), tt.opts)
+			passed := true
+			for _, check := range report.Checks {
+				if want, ok := tt.want[check.Name]; ok && check.Status != want {
+					t.Errorf("%s = %s (%s), want %s", check.Name, check.Status, check.Message, want)
+				}
+				if check.Status == PreflightFail {
+					passed = false
+				}
+			}
+			if report.Passed != passed {
+				t.Errorf("passed = %v, want %v", report.Passed, passed)
+			}
+		})
+	}
+}
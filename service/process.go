@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"iter"
 	"strings"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/atopos31/llmio/models"
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
 const (
@@ -28,19 +30,19 @@ func ProcesserOpenAI(ctx context.Context, pr io.Reader, stream bool, start time.
 	var once sync.Once
 
 	var usageStr string
+	var finishReason string
 	var output models.OutputUnion
-	var size int
 
 	scanner := bufio.NewScanner(pr)
 	scanner.Buffer(make([]byte, 0, InitScannerBufferSize), MaxScannerBufferSize)
-	for chunk, chunkSize := range ScannerToken(scanner) {
-		size += chunkSize
+	for chunk := range ScannerToken(scanner) {
 		once.Do(func() {
 			firstChunkTime = time.Since(start)
 		})
 		if !stream {
 			output.OfString = chunk
 			usageStr = gjson.Get(chunk, "usage").String()
+			finishReason = gjson.Get(chunk, "choices.0.finish_reason").String()
 			break
 		}
 		chunk = strings.TrimPrefix(chunk, "data: ")
@@ -53,6 +55,9 @@ func ProcesserOpenAI(ctx context.Context, pr io.Reader, stream bool, start time.
 			return nil, nil, errors.New(errStr.String())
 		}
 		output.OfStringArray = append(output.OfStringArray, chunk)
+		if reason := gjson.Get(chunk, "choices.0.finish_reason").String(); reason != "" {
+			finishReason = reason
+		}
 
 		// 部分厂商openai格式中 每段sse响应都会返回usage 兼容性考虑
 		// if usageStr != "" {
@@ -84,7 +89,7 @@ func ProcesserOpenAI(ctx context.Context, pr io.Reader, stream bool, start time.
 		ChunkTime:      chunkTime,
 		Usage:          openaiUsage,
 		Tps:            float64(openaiUsage.CompletionTokens) / time.Since(start).Seconds(),
-		Size:           size,
+		FinishReason:   normalizeFinishReason(finishReason),
 	}, &output, nil
 }
 
@@ -113,20 +118,20 @@ func ProcesserOpenAiRes(ctx context.Context, pr io.Reader, stream bool, start ti
 	var once sync.Once
 
 	var usageStr string
+	var finishReason string
 	var output models.OutputUnion
-	var size int
 
 	scanner := bufio.NewScanner(pr)
 	scanner.Buffer(make([]byte, 0, InitScannerBufferSize), MaxScannerBufferSize)
 	var event string
-	for chunk, chunkSize := range ScannerToken(scanner) {
-		size += chunkSize
+	for chunk := range ScannerToken(scanner) {
 		once.Do(func() {
 			firstChunkTime = time.Since(start)
 		})
 		if !stream {
 			output.OfString = chunk
 			usageStr = gjson.Get(chunk, "usage").String()
+			finishReason = responsesFinishReason(gjson.Parse(chunk))
 			break
 		}
 
@@ -139,8 +144,15 @@ func ProcesserOpenAiRes(ctx context.Context, pr io.Reader, stream bool, start ti
 			continue
 		}
 		output.OfStringArray = append(output.OfStringArray, content)
-		if event == "response.completed" {
+		// 部分上游省略 event 行，回退到 data 中的 type 字段
+		eventType := event
+		if eventType == "" {
+			eventType = gjson.Get(content, "type").String()
+		}
+		switch eventType {
+		case "response.completed", "response.incomplete", "response.failed":
 			usageStr = gjson.Get(content, "response.usage").String()
+			finishReason = responsesFinishReason(gjson.Get(content, "response"))
 		}
 	}
 	if err := scanner.Err(); err != nil {
@@ -168,8 +180,8 @@ func ProcesserOpenAiRes(ctx context.Context, pr io.Reader, stream bool, start ti
 				CachedTokens: openAIResUsage.InputTokensDetails.CachedTokens,
 			},
 		},
-		Tps:  float64(openAIResUsage.OutputTokens) / time.Since(start).Seconds(),
-		Size: size,
+		Tps:          float64(openAIResUsage.OutputTokens) / time.Since(start).Seconds(),
+		FinishReason: finishReason,
 	}, &output, nil
 }
 
@@ -179,21 +191,23 @@ func ProcesserAnthropic(ctx context.Context, pr io.Reader, stream bool, start ti
 	var once sync.Once
 
 	var usageStr string
+	var finishReason string
+	// 上游在流中返回的 error 事件，如 overloaded_error
+	var streamErr string
 
 	var output models.OutputUnion
-	var size int
 
 	scanner := bufio.NewScanner(pr)
 	scanner.Buffer(make([]byte, 0, InitScannerBufferSize), MaxScannerBufferSize)
 	var event string
-	for chunk, chunkSize := range ScannerToken(scanner) {
-		size += chunkSize
+	for chunk := range ScannerToken(scanner) {
 		once.Do(func() {
 			firstChunkTime = time.Since(start)
 		})
 		if !stream {
 			output.OfString = chunk
 			usageStr = gjson.Get(chunk, "usage").String()
+			finishReason = gjson.Get(chunk, "stop_reason").String()
 			break
 		}
 
@@ -210,6 +224,12 @@ func ProcesserAnthropic(ctx context.Context, pr io.Reader, stream bool, start ti
 		output.OfStringArray = append(output.OfStringArray, after)
 		if event == "message_delta" {
 			usageStr = gjson.Get(after, "usage").String()
+			if reason := gjson.Get(after, "delta.stop_reason").String(); reason != "" {
+				finishReason = reason
+			}
+		}
+		if event == "error" || gjson.Get(after, "type").String() == "error" {
+			streamErr = fmt.Sprintf("upstream stream error: %s: %s", gjson.Get(after, "error.type").String(), gjson.Get(after, "error.message").String())
 		}
 	}
 	if err := scanner.Err(); err != nil {
@@ -237,8 +257,9 @@ func ProcesserAnthropic(ctx context.Context, pr io.Reader, stream bool, start ti
 				CachedTokens: anthropicUsage.CacheReadInputTokens,
 			},
 		},
-		Tps:  float64(anthropicUsage.OutputTokens) / time.Since(start).Seconds(),
-		Size: size,
+		Tps:          float64(anthropicUsage.OutputTokens) / time.Since(start).Seconds(),
+		FinishReason: normalizeFinishReason(finishReason),
+		Error:        streamErr,
 	}, &output, nil
 }
 
@@ -248,27 +269,26 @@ func ProcesserGemini(ctx context.Context, pr io.Reader, stream bool, start time.
 	var once sync.Once
 
 	var usageStr string
+	var finishReason string
 	var output models.OutputUnion
-	var size int
 
 	if !stream {
 		bodyBytes, err := io.ReadAll(pr)
 		if err != nil {
 			return nil, nil, err
 		}
-		size += len(bodyBytes)
 		once.Do(func() {
 			firstChunkTime = time.Since(start)
 		})
 		output.OfString = string(bodyBytes)
 
 		usageStr = gjson.GetBytes(bodyBytes, "usageMetadata").String()
+		finishReason = geminiFinishReason(gjson.ParseBytes(bodyBytes))
 
 	} else {
 		scanner := bufio.NewScanner(pr)
 		scanner.Buffer(make([]byte, 0, InitScannerBufferSize), MaxScannerBufferSize)
-		for chunk, chunkSize := range ScannerToken(scanner) {
-			size += chunkSize
+		for chunk := range ScannerToken(scanner) {
 			once.Do(func() {
 				firstChunkTime = time.Since(start)
 			})
@@ -299,6 +319,19 @@ func ProcesserGemini(ctx context.Context, pr io.Reader, stream bool, start time.
 			}
 
 			output.OfStringArray = append(output.OfStringArray, payload)
+			// 函数调用与结束原因可能分布在不同分块中
+			switch reason := geminiFinishReason(gjson.Parse(payload)); reason {
+			case "":
+			case FinishStop:
+				if finishReason != FinishToolUse {
+					finishReason = reason
+				}
+			default:
+				finishReason = reason
+			}
+			if gjson.Get(payload, `candidates.0.content.parts.#(functionCall)`).Exists() {
+				finishReason = FinishToolUse
+			}
 			usageMetadata := gjson.Get(payload, "usageMetadata")
 			if usageMetadata.Exists() && usageMetadata.Get("totalTokenCount").Int() != 0 {
 				usageStr = usageMetadata.String()
@@ -327,18 +360,178 @@ func ProcesserGemini(ctx context.Context, pr io.Reader, stream bool, start time.
 		ChunkTime:      chunkTime,
 		Usage:          usage,
 		Tps:            float64(usage.CompletionTokens) / time.Since(start).Seconds(),
-		Size:           size,
+		FinishReason:   finishReason,
 	}, &output, nil
 }
 
-func ScannerToken(reader *bufio.Scanner) iter.Seq2[string, int] {
-	return func(yield func(string, int) bool) {
+// ProcesserEmbedding 记录向量化请求的 token 用量，输出中省略向量数据避免日志膨胀
+func ProcesserEmbedding(ctx context.Context, pr io.Reader, stream bool, start time.Time) (*models.ChatLog, *models.OutputUnion, error) {
+	body, err := io.ReadAll(pr)
+	if err != nil {
+		return nil, nil, err
+	}
+	firstChunkTime := time.Since(start)
+	if errStr := gjson.GetBytes(body, "error"); errStr.Exists() {
+		return nil, nil, errors.New(errStr.String())
+	}
+
+	var usage models.Usage
+	if raw := gjson.GetBytes(body, "usage").Raw; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &usage); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	output := body
+	for i := range gjson.GetBytes(body, "data.#").Int() {
+		if output, err = sjson.DeleteBytes(output, fmt.Sprintf("data.%d.embedding", i)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &models.ChatLog{
+		FirstChunkTime: firstChunkTime,
+		Usage:          usage,
+	}, &models.OutputUnion{OfString: string(output)}, nil
+}
+
+// ProcesserImage 记录图片生成数量与 token 用量（gpt-image 返回），输出中省略 base64 图片数据
+func ProcesserImage(ctx context.Context, pr io.Reader, stream bool, start time.Time) (*models.ChatLog, *models.OutputUnion, error) {
+	var firstChunkTime time.Duration
+	var once sync.Once
+
+	var usageStr string
+	var output models.OutputUnion
+	var count int
+
+	if !stream {
+		body, err := io.ReadAll(pr)
+		if err != nil {
+			return nil, nil, err
+		}
+		firstChunkTime = time.Since(start)
+		if errStr := gjson.GetBytes(body, "error"); errStr.Exists() {
+			return nil, nil, errors.New(errStr.String())
+		}
+		count = int(gjson.GetBytes(body, "data.#").Int())
+		usageStr = gjson.GetBytes(body, "usage").Raw
+		for i := range count {
+			if body, err = sjson.DeleteBytes(body, fmt.Sprintf("data.%d.b64_json", i)); err != nil {
+				return nil, nil, err
+			}
+		}
+		output.OfString = string(body)
+	} else {
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 0, InitScannerBufferSize), MaxScannerBufferSize)
+		for chunk := range ScannerToken(scanner) {
+			once.Do(func() {
+				firstChunkTime = time.Since(start)
+			})
+			payload, ok := strings.CutPrefix(chunk, "data: ")
+			if !ok || payload == "" {
+				continue
+			}
+			if payload == "[DONE]" {
+				break
+			}
+			if errStr := gjson.Get(payload, "error"); errStr.Exists() {
+				return nil, nil, errors.New(errStr.String())
+			}
+			// image_generation.completed / image_edit.completed 表示一张图片生成完成
+			if strings.HasSuffix(gjson.Get(payload, "type").String(), ".completed") {
+				count++
+				if usage := gjson.Get(payload, "usage"); usage.Exists() {
+					usageStr = usage.Raw
+				}
+			}
+			if trimmed, err := sjson.Delete(payload, "b64_json"); err == nil {
+				payload = trimmed
+			}
+			output.OfStringArray = append(output.OfStringArray, payload)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var imageUsage OpenAIResUsage
+	if usageStr != "" {
+		if err := json.Unmarshal([]byte(usageStr), &imageUsage); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &models.ChatLog{
+		FirstChunkTime: firstChunkTime,
+		ChunkTime:      time.Since(start) - firstChunkTime,
+		ImageCount:     count,
+		Usage: models.Usage{
+			PromptTokens:     imageUsage.InputTokens,
+			CompletionTokens: imageUsage.OutputTokens,
+			TotalTokens:      imageUsage.TotalTokens,
+		},
+	}, &output, nil
+}
+
+// ProcesserRerank 记录重排序请求用量，兼容 Jina usage、SiliconFlow meta.tokens 与 Cohere billed_units
+func ProcesserRerank(ctx context.Context, pr io.Reader, stream bool, start time.Time) (*models.ChatLog, *models.OutputUnion, error) {
+	body, err := io.ReadAll(pr)
+	if err != nil {
+		return nil, nil, err
+	}
+	firstChunkTime := time.Since(start)
+	res := gjson.ParseBytes(body)
+	if errStr := res.Get("error"); errStr.Exists() {
+		return nil, nil, errors.New(errStr.String())
+	}
+
+	var usage models.Usage
+	switch {
+	case res.Get("usage.total_tokens").Exists():
+		usage.PromptTokens = res.Get("usage.prompt_tokens").Int()
+		usage.TotalTokens = res.Get("usage.total_tokens").Int()
+	case res.Get("meta.tokens").Exists():
+		usage.PromptTokens = res.Get("meta.tokens.input_tokens").Int()
+		usage.CompletionTokens = res.Get("meta.tokens.output_tokens").Int()
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	case res.Get("meta.billed_units.search_units").Exists():
+		// Cohere 按搜索单元计费，按 token 字段记录单元数
+		usage.TotalTokens = res.Get("meta.billed_units.search_units").Int()
+	}
+	if usage.PromptTokens == 0 {
+		usage.PromptTokens = usage.TotalTokens - usage.CompletionTokens
+	}
+
+	return &models.ChatLog{
+		FirstChunkTime: firstChunkTime,
+		Usage:          usage,
+	}, &models.OutputUnion{OfString: string(body)}, nil
+}
+
+// ProcesserModeration 记录内容审核请求，审核接口不返回用量
+func ProcesserModeration(ctx context.Context, pr io.Reader, stream bool, start time.Time) (*models.ChatLog, *models.OutputUnion, error) {
+	body, err := io.ReadAll(pr)
+	if err != nil {
+		return nil, nil, err
+	}
+	firstChunkTime := time.Since(start)
+	if errStr := gjson.GetBytes(body, "error"); errStr.Exists() {
+		return nil, nil, errors.New(errStr.String())
+	}
+	return &models.ChatLog{
+		FirstChunkTime: firstChunkTime,
+	}, &models.OutputUnion{OfString: string(body)}, nil
+}
+
+func ScannerToken(reader *bufio.Scanner) iter.Seq[string] {
+	return func(yield func(string) bool) {
 		for reader.Scan() {
 			chunk := reader.Text()
 			if chunk == "" {
 				continue
 			}
-			if !yield(chunk, len(reader.Bytes())) {
+			if !yield(chunk) {
 				return
 			}
 		}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/models"
+	"github.com/samber/lo"
+)
+
+func TestProcesserOpenAiResStreamUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"with event lines", "event: response.created\ndata: {\"type\":\"response.created\"}\n\nevent: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"usage\":{\"input_tokens\":10,\"output_tokens\":5,\"total_tokens\":15}}}\n\n"},
+		{"without event lines", "data: {\"type\":\"response.created\"}\n\ndata: {\"type\":\"response.completed\",\"response\":{\"usage\":{\"input_tokens\":10,\"output_tokens\":5,\"total_tokens\":15}}}\n\n"},
+		{"incomplete response", "event: response.incomplete\ndata: {\"type\":\"response.incomplete\",\"response\":{\"usage\":{\"input_tokens\":10,\"output_tokens\":5,\"total_tokens\":15}}}\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, _, err := ProcesserOpenAiRes(context.Background(), strings.NewReader(tt.body), true, time.Now())
+			if err != nil {
+				t.Fatalf("ProcesserOpenAiRes failed: %v", err)
+			}
+			if log.PromptTokens != 10 || log.CompletionTokens != 5 || log.TotalTokens != 15 {
+				t.Fatalf("unexpected usage: %+v", log.Usage)
+			}
+		})
+	}
+}
+
+func TestProcesserAnthropicStreamError(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"success", "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":3}}\n\n", ""},
+		{"overloaded", "event: message_start\ndata: {\"type\":\"message_start\"}\n\nevent: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n", "upstream stream error: overloaded_error: Overloaded"},
+		{"error without event line", "data: {\"type\":\"error\",\"error\":{\"type\":\"api_error\",\"message\":\"boom\"}}\n\n", "upstream stream error: api_error: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, _, err := ProcesserAnthropic(context.Background(), strings.NewReader(tt.body), true, time.Now())
+			if err != nil {
+				t.Fatalf("ProcesserAnthropic failed: %v", err)
+			}
+			if log.Error != tt.wantErr {
+				t.Fatalf("Error = %q, want %q", log.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcesserEmbedding(t *testing.T) {
+	body := `{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1,0.2]},{"object":"embedding","index":1,"embedding":[0.3]}],"usage":{"prompt_tokens":8,"total_tokens":8}}`
+	log, output, err := ProcesserEmbedding(context.Background(), strings.NewReader(body), false, time.Now())
+	if err != nil {
+		t.Fatalf("ProcesserEmbedding failed: %v", err)
+	}
+	if log.PromptTokens != 8 || log.TotalTokens != 8 {
+		t.Fatalf("unexpected usage: %+v", log.Usage)
+	}
+	if strings.Contains(output.OfString, "embedding\":[") {
+		t.Fatalf("embedding vectors should be omitted: %s", output.OfString)
+	}
+}
+
+func TestRecordLogSize(t *testing.T) {
+	db := setupTestDB(t, &models.ChatLog{})
+
+	log := models.ChatLog{Name: "gpt"}
+	if err := db.Create(&log).Error; err != nil {
+		t.Fatalf("create log: %v", err)
+	}
+	body := "data: {\"choices\":[{\"delta\":{\"content\":\"a\"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"b\"}}]}\n\ndata: [DONE]\n\n"
+	start := time.Now()
+	events := newTimeline(start)
+	events.add(models.TimelineEvent{Stage: StageRouted})
+	events.add(models.TimelineEvent{Stage: StageResponse, Attempt: 1})
+	RecordLog(context.Background(), start, io.NopCloser(strings.NewReader(body)), ProcesserOpenAI, log.ID, 0, Before{Stream: true}, false, nil, events.snapshot(), "")
+
+	var got models.ChatLog
+	if err := db.First(&got, log.ID).Error; err != nil {
+		t.Fatalf("load log: %v", err)
+	}
+	if got.Size != len(body) || got.ChunkCount != 2 {
+		t.Fatalf("size = %d, chunks = %d, want %d, 2", got.Size, got.ChunkCount, len(body))
+	}
+	stages := lo.Map(got.Timeline, func(e models.TimelineEvent, _ int) string { return e.Stage })
+	if want := []string{StageRouted, StageResponse, StageFirstChunk, StageDone}; !slices.Equal(stages, want) {
+		t.Fatalf("timeline = %v, want %v", stages, want)
+	}
+}
+
+func TestProcesserImage(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		stream bool
+	}{
+		{"json", `{"created":1,"data":[{"b64_json":"AAAA"},{"b64_json":"BBBB"}],"usage":{"input_tokens":10,"output_tokens":20,"total_tokens":30}}`, false},
+		{"stream", "event: image_generation.partial_image\ndata: {\"type\":\"image_generation.partial_image\",\"b64_json\":\"AAAA\"}\n\n" +
+			"event: image_generation.completed\ndata: {\"type\":\"image_generation.completed\",\"b64_json\":\"BBBB\",\"usage\":{\"input_tokens\":10,\"output_tokens\":20,\"total_tokens\":30}}\n\n" +
+			"event: image_generation.completed\ndata: {\"type\":\"image_generation.completed\",\"b64_json\":\"CCCC\",\"usage\":{\"input_tokens\":10,\"output_tokens\":20,\"total_tokens\":30}}\n\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, output, err := ProcesserImage(context.Background(), strings.NewReader(tt.body), tt.stream, time.Now())
+			if err != nil {
+				t.Fatalf("ProcesserImage failed: %v", err)
+			}
+			if log.ImageCount != 2 || log.TotalTokens != 30 {
+				t.Fatalf("images = %d, usage = %+v", log.ImageCount, log.Usage)
+			}
+			if strings.Contains(output.OfString+strings.Join(output.OfStringArray, ""), "b64_json") {
+				t.Fatalf("b64 data should be omitted: %+v", output)
+			}
+		})
+	}
+}
+
+func TestProcesserRerankUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		prompt int64
+		total  int64
+	}{
+		{"jina", `{"results":[{"index":0,"relevance_score":0.9}],"usage":{"total_tokens":42}}`, 42, 42},
+		{"siliconflow", `{"results":[{"index":0}],"meta":{"tokens":{"input_tokens":30,"output_tokens":0}}}`, 30, 30},
+		{"cohere", `{"results":[{"index":0}],"meta":{"billed_units":{"search_units":1}}}`, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, _, err := ProcesserRerank(context.Background(), strings.NewReader(tt.body), false, time.Now())
+			if err != nil {
+				t.Fatalf("ProcesserRerank failed: %v", err)
+			}
+			if log.PromptTokens != tt.prompt || log.TotalTokens != tt.total {
+				t.Fatalf("unexpected usage: %+v", log.Usage)
+			}
+		})
+	}
+}
+
+func TestProcesserFinishReason(t *testing.T) {
+	tests := []struct {
+		name      string
+		processer Processer
+		body      string
+		stream    bool
+		want      string
+	}{
+		{"openai json", ProcesserOpenAI, `{"choices":[{"message":{"content":"hi"},"finish_reason":"stop"}]}`, false, FinishStop},
+		{"openai stream tool", ProcesserOpenAI, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[]}}]}\n\ndata: {\"choices\":[{\"delta\":{},\"finish_reason\":\"tool_calls\"}]}\n\ndata: [DONE]\n\n", true, FinishToolUse},
+		{"anthropic json", ProcesserAnthropic, `{"content":[{"type":"text","text":"hi"}],"stop_reason":"max_tokens"}`, false, FinishLength},
+		{"anthropic stream", ProcesserAnthropic, "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"refusal\"},\"usage\":{\"output_tokens\":1}}\n\n", true, FinishContentFilter},
+		{"responses incomplete", ProcesserOpenAiRes, `{"status":"incomplete","incomplete_details":{"reason":"max_output_tokens"},"output":[]}`, false, FinishLength},
+		{"responses stream tool", ProcesserOpenAiRes, "event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"status\":\"completed\",\"output\":[{\"type\":\"function_call\"}]}}\n\n", true, FinishToolUse},
+		{"gemini safety", ProcesserGemini, `{"candidates":[{"finishReason":"SAFETY"}]}`, false, FinishContentFilter},
+		{"gemini stream function call", ProcesserGemini, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"functionCall\":{\"name\":\"f\"}}]}}]}\n\ndata: {\"candidates\":[{\"finishReason\":\"STOP\"}]}\n\n", true, FinishToolUse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, _, err := tt.processer(context.Background(), strings.NewReader(tt.body), tt.stream, time.Now())
+			if err != nil {
+				t.Fatalf("processer failed: %v", err)
+			}
+			if log.FinishReason != tt.want {
+				t.Fatalf("finish reason = %q, want %q", log.FinishReason, tt.want)
+			}
+		})
+	}
+}
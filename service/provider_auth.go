@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/env"
+	"github.com/atopos31/llmio/providers"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultAuthDisableThreshold 连续鉴权失败多少次后停用提供商
+	defaultAuthDisableThreshold = 3
+	// defaultAuthRecheckMinutes 自动复检停用提供商的间隔
+	defaultAuthRecheckMinutes = 30
+	// authReasonBodyLimit 停用原因中保留的上游响应长度
+	authReasonBodyLimit = 200
+)
+
+var ErrProviderNotAuthDisabled = errors.New("provider is not auth disabled")
+
+// authFailures 各提供商连续鉴权失败次数，成功一次即清零
+var authFailures = struct {
+	sync.Mutex
+	counts map[uint]int
+}{counts: map[uint]int{}}
+
+// ProviderAuthStatus 因鉴权失败被停用的提供商
+type ProviderAuthStatus struct {
+	ProviderID   uint      `json:"provider_id"`
+	ProviderName string    `json:"provider_name"`
+	DisabledAt   time.Time `json:"disabled_at"`
+	Reason       string    `json:"reason"`
+}
+
+func authDisableThreshold() int {
+	return env.GetWithDefault("LLMIO_AUTH_DISABLE_THRESHOLD", defaultAuthDisableThreshold)
+}
+
+// authErrorKeywords 403 响应体中表明密钥问题的关键字
+var authErrorKeywords = []string{"api key", "api_key", "apikey", "authentication", "unauthorized", "invalid token", "invalid_token", "credential"}
+
+// isAuthFailure 401 一律视为密钥失效；403 也可能是地区、模型权限或内容拦截，仅当响应体表明是鉴权错误时计入
+func isAuthFailure(status int, body string) bool {
+	switch status {
+	case http.StatusUnauthorized:
+		return true
+	case http.StatusForbidden:
+		body = strings.ToLower(body)
+		return slices.ContainsFunc(authErrorKeywords, func(keyword string) bool {
+			return strings.Contains(body, keyword)
+		})
+	default:
+		return false
+	}
+}
+
+// recordAuthFailure 记录一次鉴权失败，连续达到阈值时停用提供商并记录原因
+func recordAuthFailure(ctx context.Context, provider models.Provider, status int, body string) {
+	threshold := authDisableThreshold()
+	if threshold <= 0 {
+		return
+	}
+	authFailures.Lock()
+	authFailures.counts[provider.ID]++
+	count := authFailures.counts[provider.ID]
+	if count >= threshold {
+		delete(authFailures.counts, provider.ID)
+	}
+	authFailures.Unlock()
+	if count < threshold {
+		return
+	}
+
+	if len(body) > authReasonBodyLimit {
+		body = body[:authReasonBodyLimit]
+	}
+	disableProvider(ctx, provider, fmt.Sprintf("upstream returned %d for %d consecutive requests: %s", status, count, body))
+}
+
+// disableProvider 停用提供商(即其密钥)并记录原因，通过 EnableProviderAuth 恢复
+func disableProvider(ctx context.Context, provider models.Provider, reason string) {
+	rows, err := gorm.G[models.Provider](models.DB).
+		Where("id = ?", provider.ID).
+		Where("auth_disabled_at IS NULL").
+		Updates(ctx, models.Provider{AuthDisabledAt: new(time.Now()), AuthDisabledReason: reason})
+	if err != nil {
+		slog.Error("disable provider", "provider", provider.Name, "error", err)
+		return
+	}
+	if rows > 0 {
+		slog.Warn("provider disabled", "provider", provider.Name, "reason", reason)
+	}
+}
+
+// resetAuthFailures 请求成功后清零连续失败计数
+func resetAuthFailures(providerID uint) {
+	authFailures.Lock()
+	delete(authFailures.counts, providerID)
+	authFailures.Unlock()
+}
+
+// ListAuthDisabledProviders 列出因鉴权失败被停用的提供商
+func ListAuthDisabledProviders(ctx context.Context) ([]ProviderAuthStatus, error) {
+	list, err := gorm.G[models.Provider](models.DB).Where("auth_disabled_at IS NOT NULL").Order("auth_disabled_at DESC").Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]ProviderAuthStatus, 0, len(list))
+	for _, p := range list {
+		result = append(result, ProviderAuthStatus{
+			ProviderID:   p.ID,
+			ProviderName: p.Name,
+			DisabledAt:   *p.AuthDisabledAt,
+			Reason:       p.AuthDisabledReason,
+		})
+	}
+	return result, nil
+}
+
+// EnableProviderAuth 恢复因鉴权失败被停用的提供商
+func EnableProviderAuth(ctx context.Context, providerID uint) error {
+	provider, err := gorm.G[models.Provider](models.DB).Where("id = ?", providerID).First(ctx)
+	if err != nil {
+		return err
+	}
+	if provider.AuthDisabledAt == nil {
+		return ErrProviderNotAuthDisabled
+	}
+	if err := models.DB.WithContext(ctx).Model(&models.Provider{}).Where("id = ?", providerID).Updates(map[string]any{
+		"auth_disabled_at":     nil,
+		"auth_disabled_reason": "",
+	}).Error; err != nil {
+		return fmt.Errorf("enable provider: %w", err)
+	}
+	resetAuthFailures(providerID)
+	WarmUpProvider(ctx, providerID)
+	return nil
+}
+
+// recheckAuthDisabledProviders 重新请求停用提供商的模型列表，鉴权恢复时自动启用
+func recheckAuthDisabledProviders(ctx context.Context) {
+	list, err := gorm.G[models.Provider](models.DB).Where("auth_disabled_at IS NOT NULL").Find(ctx)
+	if err != nil {
+		slog.Error("load auth disabled providers", "error", err)
+		return
+	}
+	for _, provider := range list {
+		chatModel, err := providers.New(ctx, provider.Type, provider.Config, provider.ClientOptions())
+		if err != nil {
+			continue
+		}
+		if _, err := chatModel.Models(ctx); err != nil {
+			slog.Info("provider auth recheck failed", "provider", provider.Name, "error", err)
+			continue
+		}
+		if err := EnableProviderAuth(ctx, provider.ID); err != nil {
+			slog.Error("re-enable provider", "provider", provider.Name, "error", err)
+			continue
+		}
+		slog.Info("provider re-enabled after auth recheck", "provider", provider.Name)
+	}
+}
+
+// StartProviderAuthRecheck 定期复检停用的提供商，LLMIO_AUTH_RECHECK_INTERVAL 为 0 时仅能手动恢复
+func StartProviderAuthRecheck(ctx context.Context) {
+	minutes := env.GetWithDefault("LLMIO_AUTH_RECHECK_INTERVAL", defaultAuthRecheckMinutes)
+	if minutes <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(minutes) * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				recheckAuthDisabledProviders(ctx)
+			}
+		}
+	}()
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestProviderAuthDisable(t *testing.T) {
+	setupTestDB(t, &models.Provider{}, &models.ModelWithProvider{}, &models.ChatLog{})
+	t.Setenv("LLMIO_AUTH_DISABLE_THRESHOLD", "2")
+	ctx := context.Background()
+
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[]}`))
+	}))
+	defer server.Close()
+
+	provider := models.Provider{Name: "vendor", Type: consts.StyleOpenAI, Config: `{"base_url":"` + server.URL + `","api_key":"k"}`}
+	if err := models.DB.Create(&provider).Error; err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	routable := func() bool {
+		list, err := providersByTypes(ctx, []uint{provider.ID}, []string{consts.StyleOpenAI})
+		if err != nil {
+			t.Fatalf("providersByTypes: %v", err)
+		}
+		return len(list) == 1
+	}
+
+	// 中间有成功请求时计数清零
+	recordAuthFailure(ctx, provider, http.StatusUnauthorized, "bad key")
+	resetAuthFailures(provider.ID)
+	recordAuthFailure(ctx, provider, http.StatusUnauthorized, "bad key")
+	if !routable() {
+		t.Fatal("provider disabled before reaching threshold")
+	}
+	recordAuthFailure(ctx, provider, http.StatusForbidden, "bad key")
+	if routable() {
+		t.Fatal("provider still routable after consecutive auth failures")
+	}
+	list, err := ListAuthDisabledProviders(ctx)
+	if err != nil || len(list) != 1 || list[0].ProviderID != provider.ID || list[0].Reason == "" {
+		t.Fatalf("unexpected auth disabled list: %+v, %v", list, err)
+	}
+
+	// 复检仍失败时保持停用，恢复后自动启用
+	healthy = false
+	recheckAuthDisabledProviders(ctx)
+	if routable() {
+		t.Fatal("provider re-enabled while upstream still rejects key")
+	}
+	healthy = true
+	recheckAuthDisabledProviders(ctx)
+	if !routable() {
+		t.Fatal("provider not re-enabled after successful recheck")
+	}
+	if err := EnableProviderAuth(ctx, provider.ID); !errors.Is(err, ErrProviderNotAuthDisabled) {
+		t.Fatalf("expected ErrProviderNotAuthDisabled, got %v", err)
+	}
+}
+
+func TestIsAuthFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"unauthorized", http.StatusUnauthorized, "", true},
+		{"forbidden invalid key", http.StatusForbidden, `{"error":{"message":"Incorrect API key provided"}}`, true},
+		{"forbidden region", http.StatusForbidden, `{"error":{"message":"unsupported_country_region_territory"}}`, false},
+		{"forbidden model access", http.StatusForbidden, `{"error":{"message":"You do not have access to this model"}}`, false},
+		{"server error", http.StatusInternalServerError, "authentication backend down", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAuthFailure(tt.status, tt.body); got != tt.want {
+				t.Fatalf("isAuthFailure(%d, %q) = %v, want %v", tt.status, tt.body, got, tt.want)
+			}
+		})
+	}
+}
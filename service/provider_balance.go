@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/env"
+	"github.com/atopos31/llmio/providers"
+	"gorm.io/gorm"
+)
+
+// balanceCacheTTL 余额缓存有效期，期间重复查询直接返回缓存
+const balanceCacheTTL = 5 * time.Minute
+
+var ErrBalanceUnsupported = errors.New("provider does not support balance query")
+
+// ProviderBalance 提供商余额查询结果
+type ProviderBalance struct {
+	ProviderID   uint      `json:"provider_id"`
+	ProviderName string    `json:"provider_name"`
+	Remaining    float64   `json:"remaining"`
+	Currency     string    `json:"currency"`
+	CheckedAt    time.Time `json:"checked_at"`
+	Cached       bool      `json:"cached"`
+}
+
+// GetProviderBalance 返回提供商余额，缓存过期或 refresh 为 true 时重新查询上游
+func GetProviderBalance(ctx context.Context, providerID uint, refresh bool) (*ProviderBalance, error) {
+	provider, err := gorm.G[models.Provider](models.DB).Where("id = ?", providerID).First(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !refresh && provider.Balance != nil && provider.BalanceCheckedAt != nil && time.Since(*provider.BalanceCheckedAt) < balanceCacheTTL {
+		return &ProviderBalance{
+			ProviderID:   provider.ID,
+			ProviderName: provider.Name,
+			Remaining:    *provider.Balance,
+			Currency:     provider.BalanceCurrency,
+			CheckedAt:    *provider.BalanceCheckedAt,
+			Cached:       true,
+		}, nil
+	}
+	return refreshProviderBalance(ctx, provider)
+}
+
+// refreshProviderBalance 查询上游余额并写入提供商
+func refreshProviderBalance(ctx context.Context, provider models.Provider) (*ProviderBalance, error) {
+	chatModel, err := providers.New(ctx, provider.Type, provider.Config, provider.ClientOptions())
+	if err != nil {
+		return nil, err
+	}
+	querier, ok := chatModel.(providers.BalanceQuerier)
+	if !ok {
+		return nil, ErrBalanceUnsupported
+	}
+	balance, err := querier.Balance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query balance: %w", err)
+	}
+	now := time.Now()
+	if _, err := gorm.G[models.Provider](models.DB).Where("id = ?", provider.ID).
+		Select("balance", "balance_currency", "balance_checked_at").
+		Updates(ctx, models.Provider{Balance: &balance.Remaining, BalanceCurrency: balance.Currency, BalanceCheckedAt: &now}); err != nil {
+		return nil, fmt.Errorf("save balance: %w", err)
+	}
+	return &ProviderBalance{
+		ProviderID:   provider.ID,
+		ProviderName: provider.Name,
+		Remaining:    balance.Remaining,
+		Currency:     balance.Currency,
+		CheckedAt:    now,
+	}, nil
+}
+
+// defaultBalanceRefreshMinutes 默认余额刷新间隔
+const defaultBalanceRefreshMinutes = 30
+
+// balanceMaxAge 余额耗尽的判断有效期，超过后不再据此排除提供商，避免充值后仍被一次旧查询永久挡在路由之外
+var balanceMaxAge atomic.Int64
+
+func init() {
+	balanceMaxAge.Store(int64(time.Hour))
+}
+
+// balanceStaleBefore 早于该时间查询到的余额不参与路由判断
+func balanceStaleBefore(now time.Time) time.Time {
+	return now.Add(-time.Duration(balanceMaxAge.Load()))
+}
+
+// StartProviderBalanceRefresh 定期刷新已查询过余额的提供商，LLMIO_BALANCE_REFRESH_INTERVAL 为 0 时不刷新
+// 开启刷新时余额在两个刷新周期内有效，关闭时为一小时
+func StartProviderBalanceRefresh(ctx context.Context) {
+	minutes := env.GetWithDefault("LLMIO_BALANCE_REFRESH_INTERVAL", defaultBalanceRefreshMinutes)
+	if minutes <= 0 {
+		return
+	}
+	balanceMaxAge.Store(int64(2 * time.Duration(minutes) * time.Minute))
+	go func() {
+		ticker := time.NewTicker(time.Duration(minutes) * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				list, err := gorm.G[models.Provider](models.DB).Where("balance IS NOT NULL").Find(ctx)
+				if err != nil {
+					slog.Error("load providers for balance refresh", "error", err)
+					continue
+				}
+				for _, provider := range list {
+					if _, err := refreshProviderBalance(ctx, provider); err != nil {
+						slog.Warn("refresh provider balance", "provider", provider.Name, "error", err)
+					}
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestGetProviderBalance(t *testing.T) {
+	db := setupTestDB(t, &models.Provider{})
+
+	usage := 4.5
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if usage >= 20 {
+			w.Write([]byte(`{"data":{"total_credits":20,"total_usage":20}}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"total_credits":20,"total_usage":4.5}}`))
+	}))
+	defer server.Close()
+
+	provider := models.Provider{
+		Name:   "openrouter",
+		Type:   consts.StyleOpenAI,
+		Config: `{"base_url":"` + server.URL + `/api/v1","api_key":"k","vendor":"openrouter"}`,
+	}
+	unsupported := models.Provider{Name: "gemini", Type: consts.StyleGemini, Config: `{"base_url":"http://127.0.0.1","api_key":"k"}`}
+	for _, p := range []*models.Provider{&provider, &unsupported} {
+		if err := db.Create(p).Error; err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+	}
+	ctx := context.Background()
+
+	balance, err := GetProviderBalance(ctx, provider.ID, false)
+	if err != nil {
+		t.Fatalf("GetProviderBalance failed: %v", err)
+	}
+	if balance.Remaining != 15.5 || balance.Currency != "USD" || balance.Cached {
+		t.Fatalf("balance = %+v", balance)
+	}
+	if balance, err = GetProviderBalance(ctx, provider.ID, false); err != nil || !balance.Cached || calls != 1 {
+		t.Fatalf("second query should hit cache: %+v, calls = %d, err = %v", balance, calls, err)
+	}
+
+	// 余额耗尽后不参与路由
+	usage = 20
+	if balance, err = GetProviderBalance(ctx, provider.ID, true); err != nil || balance.Remaining != 0 {
+		t.Fatalf("refresh = %+v, err = %v", balance, err)
+	}
+	list, err := providersByTypes(ctx, []uint{provider.ID}, []string{consts.StyleOpenAI})
+	if err != nil {
+		t.Fatalf("providersByTypes failed: %v", err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("exhausted provider should be skipped, got %d", len(list))
+	}
+
+	// 过期的余额不再阻止路由
+	if err := db.Model(&provider).Update("balance_checked_at", time.Now().Add(-2*time.Hour)).Error; err != nil {
+		t.Fatalf("age balance: %v", err)
+	}
+	if list, err = providersByTypes(ctx, []uint{provider.ID}, []string{consts.StyleOpenAI}); err != nil || len(list) != 1 {
+		t.Fatalf("stale exhausted balance should not block routing, got %d, err = %v", len(list), err)
+	}
+
+	if _, err := GetProviderBalance(ctx, unsupported.ID, true); err != ErrBalanceUnsupported {
+		t.Fatalf("err = %v, want ErrBalanceUnsupported", err)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// budgetCacheTTL 预算用量缓存有效期，路由时最多按该间隔重新统计日志
+const budgetCacheTTL = time.Minute
+
+// ProviderBudgetUsage 提供商本周期的用量与预算
+type ProviderBudgetUsage struct {
+	ProviderID uint      `json:"provider_id"`
+	Period     string    `json:"period"`
+	Tokens     int64     `json:"tokens"`
+	Cost       float64   `json:"cost"`
+	TokenLimit int64     `json:"token_limit"`
+	CostLimit  float64   `json:"cost_limit"`
+	Exhausted  bool      `json:"exhausted"` // 用尽后在 ResetAt 前不参与路由
+	ResetAt    time.Time `json:"reset_at"`
+}
+
+type budgetCacheEntry struct {
+	usage     ProviderBudgetUsage
+	start     time.Time
+	checkedAt time.Time
+}
+
+// budgetCache 按提供商 ID 或共享分组名缓存用量
+var budgetCache = struct {
+	sync.Mutex
+	entries map[string]budgetCacheEntry
+}{entries: map[string]budgetCacheEntry{}}
+
+// budgetWindow 返回预算周期的开始与重置时间
+func budgetWindow(period string, now time.Time) (time.Time, time.Time) {
+	if period == consts.BudgetPeriodMonth {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// GetProviderBudgetUsage 统计提供商本周期的 Token 与费用，属于共享分组时统计同组全部提供商，未配置预算时返回 nil
+func GetProviderBudgetUsage(ctx context.Context, provider models.Provider, now time.Time) (*ProviderBudgetUsage, error) {
+	budget := provider.Budget
+	if budget.Tokens <= 0 && budget.Cost <= 0 {
+		return nil, nil
+	}
+	period := budget.Period
+	if period == "" {
+		period = consts.BudgetPeriodDay
+	}
+	start, reset := budgetWindow(period, now)
+
+	key := strconv.FormatUint(uint64(provider.ID), 10)
+	names := []string{provider.Name}
+	if provider.QuotaGroup != "" {
+		key = "group:" + provider.QuotaGroup
+	}
+
+	budgetCache.Lock()
+	entry, ok := budgetCache.entries[key]
+	budgetCache.Unlock()
+	if !ok || !entry.start.Equal(start) || now.Sub(entry.checkedAt) >= budgetCacheTTL {
+		if provider.QuotaGroup != "" {
+			group, err := gorm.G[models.Provider](models.ReadDB()).Where("quota_group = ?", provider.QuotaGroup).Find(ctx)
+			if err != nil {
+				return nil, err
+			}
+			names = lo.Uniq(append(names, lo.Map(group, func(p models.Provider, _ int) string { return p.Name })...))
+		}
+		logs, err := gorm.G[models.ChatLog](models.ReadDB()).
+			Select("prompt_tokens", "completion_tokens", "total_tokens", "prompt_tokens_details", "input_price", "cache_read_price", "output_price", "cost").
+			Where("provider_name IN ?", names).
+			Where("created_at >= ?", start).
+			Find(ctx)
+		if err != nil {
+			return nil, err
+		}
+		entry = budgetCacheEntry{start: start, checkedAt: now}
+		for _, log := range logs {
+			entry.usage.Tokens += log.TotalTokens
+			entry.usage.Cost += logCost(log)
+		}
+		budgetCache.Lock()
+		budgetCache.entries[key] = entry
+		budgetCache.Unlock()
+	}
+
+	usage := entry.usage
+	usage.ProviderID = provider.ID
+	usage.Period = period
+	usage.TokenLimit = budget.Tokens
+	usage.CostLimit = budget.Cost
+	usage.ResetAt = reset
+	usage.Exhausted = (budget.Tokens > 0 && usage.Tokens >= budget.Tokens) || (budget.Cost > 0 && usage.Cost >= budget.Cost)
+	return &usage, nil
+}
+
+// budgetExhausted 提供商本周期预算是否已用尽，统计失败时不影响路由
+func budgetExhausted(ctx context.Context, provider models.Provider) bool {
+	usage, err := GetProviderBudgetUsage(ctx, provider, time.Now())
+	if err != nil {
+		slog.Warn("check provider budget", "provider", provider.Name, "error", err)
+		return false
+	}
+	return usage != nil && usage.Exhausted
+}
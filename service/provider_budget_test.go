@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+func TestBudgetWindow(t *testing.T) {
+	now := time.Date(2026, 3, 15, 13, 4, 5, 0, time.UTC)
+	tests := []struct {
+		period     string
+		start, end time.Time
+	}{
+		{"", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{consts.BudgetPeriodDay, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{consts.BudgetPeriodMonth, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		start, end := budgetWindow(tt.period, now)
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Fatalf("%q window = %v - %v, want %v - %v", tt.period, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestProviderBudget(t *testing.T) {
+	db := setupTestDB(t, &models.Provider{}, &models.ChatLog{})
+
+	tokens := models.Provider{Name: "free-tier", Type: consts.StyleOpenAI, Budget: models.ProviderBudget{Tokens: 1000}}
+	cost := models.Provider{Name: "paid", Type: consts.StyleOpenAI, Budget: models.ProviderBudget{Period: consts.BudgetPeriodMonth, Cost: 1}}
+	unlimited := models.Provider{Name: "unlimited", Type: consts.StyleOpenAI}
+	for _, p := range []*models.Provider{&tokens, &cost, &unlimited} {
+		if err := db.Create(p).Error; err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+	}
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	logs := []models.ChatLog{
+		{ProviderName: "free-tier", Usage: models.Usage{TotalTokens: 1200}},
+		// 上一周期的用量不计入
+		{Model: gorm.Model{CreatedAt: yesterday}, ProviderName: "paid", Usage: models.Usage{TotalTokens: 10}},
+		{ProviderName: "paid", Usage: models.Usage{PromptTokens: 100000, CompletionTokens: 10000, TotalTokens: 110000}, InputPrice: 2, OutputPrice: 8},
+		{ProviderName: "unlimited", Usage: models.Usage{TotalTokens: 1 << 20}},
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("create logs: %v", err)
+	}
+	ctx := context.Background()
+
+	usage, err := GetProviderBudgetUsage(ctx, tokens, now)
+	if err != nil || usage == nil || !usage.Exhausted || usage.Tokens != 1200 || usage.Period != consts.BudgetPeriodDay {
+		t.Fatalf("token budget = %+v, err = %v", usage, err)
+	}
+	usage, err = GetProviderBudgetUsage(ctx, cost, now)
+	if err != nil || usage == nil || usage.Exhausted || usage.Cost < 0.279 || usage.Cost > 0.281 {
+		t.Fatalf("cost budget = %+v, err = %v", usage, err)
+	}
+	if usage, err := GetProviderBudgetUsage(ctx, unlimited, now); err != nil || usage != nil {
+		t.Fatalf("unlimited budget = %+v, err = %v", usage, err)
+	}
+
+	list, err := providersByTypes(ctx, []uint{tokens.ID, cost.ID, unlimited.ID}, []string{consts.StyleOpenAI})
+	if err != nil {
+		t.Fatalf("providersByTypes failed: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != cost.ID || list[1].ID != unlimited.ID {
+		t.Fatalf("exhausted provider should be skipped, got %+v", list)
+	}
+
+	// 共享分组内的用量合并计算
+	east := models.Provider{Name: "east", Type: consts.StyleOpenAI, QuotaGroup: "shared", Budget: models.ProviderBudget{Tokens: 1000}}
+	west := models.Provider{Name: "west", Type: consts.StyleOpenAI, QuotaGroup: "shared", Budget: models.ProviderBudget{Tokens: 1000}}
+	for _, p := range []*models.Provider{&east, &west} {
+		if err := db.Create(p).Error; err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+	}
+	if err := db.Create(&[]models.ChatLog{
+		{ProviderName: "east", Usage: models.Usage{TotalTokens: 600}},
+		{ProviderName: "west", Usage: models.Usage{TotalTokens: 500}},
+	}).Error; err != nil {
+		t.Fatalf("create logs: %v", err)
+	}
+	if usage, err := GetProviderBudgetUsage(ctx, west, now); err != nil || usage == nil || usage.Tokens != 1100 || !usage.Exhausted {
+		t.Fatalf("group budget = %+v, err = %v", usage, err)
+	}
+}
@@ -1,8 +1,15 @@
 package service
 
 import (
+	"context"
+	"slices"
 	"strings"
 	"unicode"
+
+	"github.com/atopos31/llmio/balancers"
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/samber/lo"
 )
 
 func splitProviderErrorMatchers(raw string) []string {
@@ -38,7 +45,11 @@ func compactLower(s string) string {
 }
 
 func matchProviderBodyError(body string, rawMatchers string) (bool, string) {
-	matchers := splitProviderErrorMatchers(rawMatchers)
+	return matchBodySamples(body, splitProviderErrorMatchers(rawMatchers))
+}
+
+// matchBodySamples 响应体中是否出现任一样本，不区分大小写与空白
+func matchBodySamples(body string, matchers []string) (bool, string) {
 	if len(matchers) == 0 {
 		return false, ""
 	}
@@ -46,6 +57,9 @@ func matchProviderBodyError(body string, rawMatchers string) (bool, string) {
 	bodyLower := strings.ToLower(body)
 	bodyCompact := compactLower(body)
 	for _, matcher := range matchers {
+		if strings.TrimSpace(matcher) == "" {
+			continue
+		}
 		matcherLower := strings.ToLower(matcher)
 		if strings.Contains(bodyLower, matcherLower) {
 			return true, matcher
@@ -59,3 +73,63 @@ func matchProviderBodyError(body string, rawMatchers string) (bool, string) {
 
 	return false, ""
 }
+
+// errorClassActions 各错误分类的默认处理动作
+var errorClassActions = map[string]string{
+	consts.ErrorClassQuotaExhausted: consts.ErrorActionOpenBreaker,
+	consts.ErrorClassAuthFailed:     consts.ErrorActionDisableKey,
+	consts.ErrorClassContentFilter:  consts.ErrorActionFailFast,
+	consts.ErrorClassTransient:      consts.ErrorActionReduceWeight,
+}
+
+// ValidErrorClass 分类与动作是否合法，且至少配置了状态码或样本之一
+func ValidErrorClass(rule models.ErrorClassRule) bool {
+	if _, ok := errorClassActions[rule.Class]; !ok {
+		return false
+	}
+	if rule.Action != "" && !slices.Contains(lo.Values(errorClassActions), rule.Action) {
+		return false
+	}
+	return len(rule.Statuses) > 0 || slices.ContainsFunc(rule.Samples, func(sample string) bool { return strings.TrimSpace(sample) != "" })
+}
+
+// classifyError 按顺序匹配错误分类规则，返回命中的分类与处理动作
+func classifyError(rules []models.ErrorClassRule, status int, body string) (class string, action string, ok bool) {
+	for _, rule := range rules {
+		if len(rule.Statuses) == 0 && len(rule.Samples) == 0 {
+			continue
+		}
+		if len(rule.Statuses) > 0 && !slices.Contains(rule.Statuses, status) {
+			continue
+		}
+		if len(rule.Samples) > 0 {
+			if matched, _ := matchBodySamples(body, rule.Samples); !matched {
+				continue
+			}
+		}
+		return rule.Class, lo.CoalesceOrEmpty(rule.Action, errorClassActions[rule.Class]), true
+	}
+	return "", "", false
+}
+
+// applyErrorAction 执行错误分类的处理动作，返回 true 表示直接将错误返回给客户端，不再重试
+func applyErrorAction(ctx context.Context, action string, balancer balancers.Balancer, id uint, provider models.Provider, config balancers.BreakerConfig, reason string) bool {
+	switch action {
+	case consts.ErrorActionReduceWeight:
+		balancer.Reduce(id)
+	case consts.ErrorActionOpenBreaker:
+		balancers.TripNode(id, config)
+		balancer.Delete(id)
+	case consts.ErrorActionDisableKey:
+		if len(reason) > authReasonBodyLimit {
+			reason = reason[:authReasonBodyLimit]
+		}
+		disableProvider(ctx, provider, reason)
+		balancer.Delete(id)
+	case consts.ErrorActionFailFast:
+		return true
+	default:
+		balancer.Delete(id)
+	}
+	return false
+}
@@ -1,6 +1,11 @@
 package service
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
 
 func TestSplitProviderErrorMatchers(t *testing.T) {
 	got := splitProviderErrorMatchers("\"status\":\"439\";\n\"status\": \"500\"；API Token has expired")
@@ -59,3 +64,51 @@ func TestMatchProviderBodyError(t *testing.T) {
 		})
 	}
 }
+
+func TestClassifyError(t *testing.T) {
+	rules := []models.ErrorClassRule{
+		{Class: consts.ErrorClassQuotaExhausted, Statuses: []int{429}, Samples: []string{"insufficient_quota"}},
+		{Class: consts.ErrorClassContentFilter, Samples: []string{"content_filter; policy"}},
+		{Class: consts.ErrorClassTransient, Statuses: []int{500, 502, 503}, Action: consts.ErrorActionOpenBreaker},
+		{Class: consts.ErrorClassAuthFailed},
+	}
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantClass  string
+		wantAction string
+	}{
+		{"quota", 429, `{"error":{"code":"insufficient_quota"}}`, consts.ErrorClassQuotaExhausted, consts.ErrorActionOpenBreaker},
+		{"plain rate limit", 429, `{"error":"slow down"}`, "", ""},
+		{"content filter on 200", 200, `{"finish_reason":"Content_Filter; Policy"}`, consts.ErrorClassContentFilter, consts.ErrorActionFailFast},
+		{"action override", 502, `bad gateway`, consts.ErrorClassTransient, consts.ErrorActionOpenBreaker},
+		{"empty rule ignored", 401, `unauthorized`, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, action, ok := classifyError(rules, tt.status, tt.body)
+			if ok != (tt.wantClass != "") || class != tt.wantClass || action != tt.wantAction {
+				t.Fatalf("classifyError = (%q, %q, %v), want (%q, %q)", class, action, ok, tt.wantClass, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestValidErrorClass(t *testing.T) {
+	tests := []struct {
+		rule models.ErrorClassRule
+		want bool
+	}{
+		{models.ErrorClassRule{Class: consts.ErrorClassTransient, Statuses: []int{503}}, true},
+		{models.ErrorClassRule{Class: consts.ErrorClassAuthFailed, Samples: []string{"expired"}, Action: consts.ErrorActionFailFast}, true},
+		{models.ErrorClassRule{Class: "unknown", Statuses: []int{503}}, false},
+		{models.ErrorClassRule{Class: consts.ErrorClassTransient, Statuses: []int{503}, Action: "retry"}, false},
+		{models.ErrorClassRule{Class: consts.ErrorClassTransient, Samples: []string{" "}}, false},
+	}
+	for _, tt := range tests {
+		if got := ValidErrorClass(tt.rule); got != tt.want {
+			t.Errorf("ValidErrorClass(%+v) = %v, want %v", tt.rule, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+const defaultRetireObserveDays = 7
+
+var (
+	ErrProviderRetiring    = errors.New("provider is already retiring")
+	ErrProviderArchived    = errors.New("provider is already archived")
+	ErrProviderNotRetiring = errors.New("provider is not retiring")
+	ErrRetireNotReady      = errors.New("provider is not ready to archive")
+)
+
+// RetireStatus 下线流程状态
+type RetireStatus struct {
+	ProviderID       uint       `json:"provider_id"`
+	ProviderName     string     `json:"provider_name"`
+	State            string     `json:"state"`
+	StartedAt        *time.Time `json:"started_at"`
+	ObserveDays      int        `json:"observe_days"`
+	ObserveUntil     *time.Time `json:"observe_until"`
+	RetiredAt        *time.Time `json:"retired_at"`
+	Associations     int        `json:"associations"`
+	RemainingTraffic int64      `json:"remaining_traffic"` // 观察期内仍被路由到的请求数，理想情况为 0
+	ReadyToArchive   bool       `json:"ready_to_archive"`
+}
+
+// StartProviderRetire 开始下线：记录并清零所有关联权重，进入观察期
+func StartProviderRetire(ctx context.Context, providerID uint, observeDays int) (*RetireStatus, error) {
+	if observeDays <= 0 {
+		observeDays = defaultRetireObserveDays
+	}
+	err := models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var provider models.Provider
+		if err := tx.Where("id = ?", providerID).First(&provider).Error; err != nil {
+			return err
+		}
+		switch provider.RetireState {
+		case consts.RetireStateRetiring:
+			return ErrProviderRetiring
+		case consts.RetireStateArchived:
+			return ErrProviderArchived
+		}
+
+		var associations []models.ModelWithProvider
+		if err := tx.Where("provider_id = ?", providerID).Find(&associations).Error; err != nil {
+			return err
+		}
+		weights := make(map[uint]int, len(associations))
+		for _, mp := range associations {
+			weights[mp.ID] = mp.Weight
+		}
+		if err := tx.Model(&models.ModelWithProvider{}).Where("provider_id = ?", providerID).Update("weight", 0).Error; err != nil {
+			return fmt.Errorf("reset weights: %w", err)
+		}
+
+		now := time.Now()
+		return tx.Model(&models.Provider{}).Where("id = ?", providerID).Updates(models.Provider{
+			RetireState:       consts.RetireStateRetiring,
+			RetireStartedAt:   &now,
+			RetireObserveDays: observeDays,
+			RetireWeights:     weights,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	InvalidateRouteCache()
+	return GetProviderRetireStatus(ctx, providerID)
+}
+
+// CancelProviderRetire 撤销下线，恢复原有权重；已归档的只重新启用归档时停用的关联
+func CancelProviderRetire(ctx context.Context, providerID uint) (*RetireStatus, error) {
+	err := models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var provider models.Provider
+		if err := tx.Where("id = ?", providerID).First(&provider).Error; err != nil {
+			return err
+		}
+		if provider.RetireState == "" {
+			return ErrProviderNotRetiring
+		}
+		for id, weight := range provider.RetireWeights {
+			if err := tx.Model(&models.ModelWithProvider{}).Where("id = ? AND provider_id = ?", id, providerID).Update("weight", weight).Error; err != nil {
+				return fmt.Errorf("restore weight: %w", err)
+			}
+		}
+		if provider.RetireState == consts.RetireStateArchived && len(provider.RetireDisabled) > 0 {
+			if err := tx.Model(&models.ModelWithProvider{}).Where("id IN ? AND provider_id = ?", provider.RetireDisabled, providerID).Update("status", true).Error; err != nil {
+				return fmt.Errorf("restore associations: %w", err)
+			}
+		}
+		return tx.Model(&models.Provider{}).Where("id = ?", providerID).Updates(map[string]any{
+			"retire_state":        "",
+			"retire_started_at":   nil,
+			"retire_observe_days": 0,
+			"retire_weights":      "{}",
+			"retire_disabled":     "[]",
+			"retired_at":          nil,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	InvalidateRouteCache()
+	return GetProviderRetireStatus(ctx, providerID)
+}
+
+// ArchiveProvider 观察期结束且无剩余流量后归档，停用全部关联并记录原本启用的关联
+func ArchiveProvider(ctx context.Context, providerID uint, force bool) (*RetireStatus, error) {
+	status, err := GetProviderRetireStatus(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+	switch status.State {
+	case consts.RetireStateArchived:
+		return nil, ErrProviderArchived
+	case consts.RetireStateRetiring:
+	default:
+		return nil, ErrProviderNotRetiring
+	}
+	if !status.ReadyToArchive && !force {
+		return nil, ErrRetireNotReady
+	}
+
+	err = models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var disabled []uint
+		if err := tx.Model(&models.ModelWithProvider{}).Where("provider_id = ? AND status = ?", providerID, true).Pluck("id", &disabled).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ModelWithProvider{}).Where("provider_id = ?", providerID).Update("status", false).Error; err != nil {
+			return fmt.Errorf("disable associations: %w", err)
+		}
+		now := time.Now()
+		return tx.Model(&models.Provider{}).Where("id = ?", providerID).Updates(models.Provider{
+			RetireState:    consts.RetireStateArchived,
+			RetireDisabled: disabled,
+			RetiredAt:      &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	InvalidateRouteCache()
+	return GetProviderRetireStatus(ctx, providerID)
+}
+
+// GetProviderRetireStatus 查询下线进度与观察期内剩余流量
+func GetProviderRetireStatus(ctx context.Context, providerID uint) (*RetireStatus, error) {
+	provider, err := gorm.G[models.Provider](models.DB).Where("id = ?", providerID).First(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return buildRetireStatus(ctx, provider)
+}
+
+// ListProviderRetirements 列出所有处于下线流程中的提供商
+func ListProviderRetirements(ctx context.Context) ([]RetireStatus, error) {
+	providers, err := gorm.G[models.Provider](models.DB).Where("retire_state IN ?", []string{consts.RetireStateRetiring, consts.RetireStateArchived}).Order("id DESC").Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]RetireStatus, 0, len(providers))
+	for _, provider := range providers {
+		status, err := buildRetireStatus(ctx, provider)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *status)
+	}
+	return result, nil
+}
+
+func buildRetireStatus(ctx context.Context, provider models.Provider) (*RetireStatus, error) {
+	associations, err := gorm.G[models.ModelWithProvider](models.DB).Where("provider_id = ?", provider.ID).Count(ctx, "id")
+	if err != nil {
+		return nil, err
+	}
+	status := &RetireStatus{
+		ProviderID:   provider.ID,
+		ProviderName: provider.Name,
+		State:        provider.RetireState,
+		StartedAt:    provider.RetireStartedAt,
+		ObserveDays:  provider.RetireObserveDays,
+		RetiredAt:    provider.RetiredAt,
+		Associations: int(associations),
+	}
+	if provider.RetireStartedAt == nil {
+		return status, nil
+	}
+
+	observeUntil := provider.RetireStartedAt.AddDate(0, 0, provider.RetireObserveDays)
+	status.ObserveUntil = &observeUntil
+
+	end := time.Now()
+	if provider.RetiredAt != nil {
+		end = *provider.RetiredAt
+	}
+	traffic, err := gorm.G[models.ChatLog](models.ReadDB()).
+		Where("provider_name = ?", provider.Name).
+		Where("created_at >= ? AND created_at <= ?", *provider.RetireStartedAt, end).
+		Count(ctx, "id")
+	if err != nil {
+		return nil, err
+	}
+	status.RemainingTraffic = traffic
+	status.ReadyToArchive = provider.RetireState == consts.RetireStateRetiring && !time.Now().Before(observeUntil) && traffic == 0
+	return status, nil
+}
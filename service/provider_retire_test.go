@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestProviderRetireLifecycle(t *testing.T) {
+	setupTestDB(t, &models.Provider{}, &models.ModelWithProvider{}, &models.ChatLog{})
+	ctx := context.Background()
+
+	provider := models.Provider{Name: "old-vendor", Type: consts.StyleOpenAI}
+	if err := models.DB.Create(&provider).Error; err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	mp := models.ModelWithProvider{ModelID: 1, ProviderID: provider.ID, Weight: 8, Status: new(true)}
+	if err := models.DB.Create(&mp).Error; err != nil {
+		t.Fatalf("create association: %v", err)
+	}
+
+	status, err := StartProviderRetire(ctx, provider.ID, 3)
+	if err != nil {
+		t.Fatalf("StartProviderRetire failed: %v", err)
+	}
+	if status.State != consts.RetireStateRetiring || status.ReadyToArchive {
+		t.Fatalf("unexpected status after start: %+v", status)
+	}
+
+	var reloaded models.ModelWithProvider
+	models.DB.First(&reloaded, mp.ID)
+	if reloaded.Weight != 0 {
+		t.Fatalf("expected weight 0 during retirement, got %d", reloaded.Weight)
+	}
+
+	if _, err := StartProviderRetire(ctx, provider.ID, 3); err != ErrProviderRetiring {
+		t.Fatalf("expected ErrProviderRetiring, got %v", err)
+	}
+	if _, err := ArchiveProvider(ctx, provider.ID, false); err != ErrRetireNotReady {
+		t.Fatalf("expected ErrRetireNotReady, got %v", err)
+	}
+
+	if _, err := CancelProviderRetire(ctx, provider.ID); err != nil {
+		t.Fatalf("CancelProviderRetire failed: %v", err)
+	}
+	models.DB.First(&reloaded, mp.ID)
+	if reloaded.Weight != 8 {
+		t.Fatalf("expected weight restored to 8, got %d", reloaded.Weight)
+	}
+}
+
+func TestProviderRetireReadyAndArchive(t *testing.T) {
+	setupTestDB(t, &models.Provider{}, &models.ModelWithProvider{}, &models.ChatLog{})
+	ctx := context.Background()
+
+	provider := models.Provider{Name: "idle-vendor", Type: consts.StyleOpenAI}
+	if err := models.DB.Create(&provider).Error; err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	mp := models.ModelWithProvider{ModelID: 1, ProviderID: provider.ID, Weight: 5, Status: new(true)}
+	if err := models.DB.Create(&mp).Error; err != nil {
+		t.Fatalf("create association: %v", err)
+	}
+	// 下线前已被手动停用的关联，撤销归档后不应被重新启用
+	off := models.ModelWithProvider{ModelID: 2, ProviderID: provider.ID, Weight: 3, Status: new(true)}
+	if err := models.DB.Create(&off).Error; err != nil {
+		t.Fatalf("create association: %v", err)
+	}
+	models.DB.Model(&off).Update("status", false)
+
+	if _, err := StartProviderRetire(ctx, provider.ID, 1); err != nil {
+		t.Fatalf("StartProviderRetire failed: %v", err)
+	}
+	// 将观察期提前到两天前
+	started := time.Now().AddDate(0, 0, -2)
+	models.DB.Model(&models.Provider{}).Where("id = ?", provider.ID).Update("retire_started_at", started)
+
+	status, err := GetProviderRetireStatus(ctx, provider.ID)
+	if err != nil {
+		t.Fatalf("GetProviderRetireStatus failed: %v", err)
+	}
+	if !status.ReadyToArchive {
+		t.Fatalf("expected ready to archive, got %+v", status)
+	}
+
+	// 观察期内仍有流量则不可归档
+	models.DB.Create(&models.ChatLog{Name: "m", ProviderName: provider.Name, Status: consts.StatusSuccess})
+	status, _ = GetProviderRetireStatus(ctx, provider.ID)
+	if status.RemainingTraffic != 1 || status.ReadyToArchive {
+		t.Fatalf("expected remaining traffic to block archive, got %+v", status)
+	}
+
+	status, err = ArchiveProvider(ctx, provider.ID, true)
+	if err != nil {
+		t.Fatalf("ArchiveProvider failed: %v", err)
+	}
+	if status.State != consts.RetireStateArchived {
+		t.Fatalf("expected archived state, got %q", status.State)
+	}
+	var reloaded models.ModelWithProvider
+	models.DB.First(&reloaded, mp.ID)
+	if reloaded.Status == nil || *reloaded.Status {
+		t.Fatal("expected association to be disabled after archive")
+	}
+
+	if _, err := CancelProviderRetire(ctx, provider.ID); err != nil {
+		t.Fatalf("CancelProviderRetire failed: %v", err)
+	}
+	models.DB.First(&reloaded, mp.ID)
+	if reloaded.Status == nil || !*reloaded.Status || reloaded.Weight != 5 {
+		t.Fatalf("expected association restored, got %+v", reloaded)
+	}
+	var untouched models.ModelWithProvider
+	models.DB.First(&untouched, off.ID)
+	if untouched.Status == nil || *untouched.Status {
+		t.Fatal("expected manually disabled association to stay disabled")
+	}
+}
+
+func TestRetiringProviderNotUsedAsStandby(t *testing.T) {
+	setupTestDB(t, &models.Provider{}, &models.ModelWithProvider{}, &models.ChatLog{})
+	ctx := context.Background()
+
+	retiring := models.Provider{Name: "retiring", Type: consts.StyleOpenAI}
+	standby := models.Provider{Name: "standby", Type: consts.StyleOpenAI}
+	primary := models.Provider{Name: "primary", Type: consts.StyleOpenAI}
+	for _, p := range []*models.Provider{&retiring, &standby, &primary} {
+		if err := models.DB.Create(p).Error; err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+	}
+	associations := []models.ModelWithProvider{
+		{ModelID: 1, ProviderID: retiring.ID, Weight: 5, Status: new(true)},
+		{ModelID: 1, ProviderID: standby.ID, Weight: 0, Status: new(true)},
+		{ModelID: 1, ProviderID: primary.ID, Weight: 5, Status: new(true)},
+	}
+	if err := models.DB.Create(&associations).Error; err != nil {
+		t.Fatalf("create associations: %v", err)
+	}
+	if _, err := StartProviderRetire(ctx, retiring.ID, 3); err != nil {
+		t.Fatalf("StartProviderRetire failed: %v", err)
+	}
+
+	// 下线中的提供商与备用提供商权重都为 0，只有备用提供商仍可承接故障转移
+	list, err := routeProviders(ctx, []uint{retiring.ID, standby.ID, primary.ID})
+	if err != nil {
+		t.Fatalf("routeProviders failed: %v", err)
+	}
+	names := make([]string, 0, len(list))
+	for _, p := range list {
+		names = append(names, p.Name)
+	}
+	if len(names) != 2 || names[0] != "standby" || names[1] != "primary" {
+		t.Fatalf("routable providers = %v, want [standby primary]", names)
+	}
+
+	if _, err := CancelProviderRetire(ctx, retiring.ID); err != nil {
+		t.Fatalf("CancelProviderRetire failed: %v", err)
+	}
+	if list, err = routeProviders(ctx, []uint{retiring.ID}); err != nil || len(list) != 1 {
+		t.Fatalf("expected provider routable after cancel, got %v, %v", list, err)
+	}
+}
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/providers"
+	"github.com/tidwall/gjson"
+	"gorm.io/gorm"
+)
+
+const (
+	SelftestPass = "pass"
+	SelftestFail = "fail"
+	SelftestSkip = "skip"
+
+	selftestTimeout   = 60 * time.Second
+	selftestMaxTokens = 256
+	selftestPrompt    = "Please reply me yes or no"
+)
+
+var ErrSelftestNoModel = errors.New("no model available for selftest")
+
+// selftestCases 自检用例，按顺序执行
+var selftestCases = []string{"models", "chat", "stream", "tool_call", "json_mode", "vision"}
+
+var selftestTool = map[string]any{
+	"name":        "get_weather",
+	"description": "Get weather at the given location",
+	"parameters": map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"location": map[string]any{"type": "string", "description": "The city name"},
+		},
+		"required": []string{"location"},
+	},
+}
+
+// RunProviderSelftest 对提供商依次执行模型列表、对话、流式、工具调用、JSON 模式、图片理解用例并保存结果
+// model 为空时使用该提供商第一个关联的上游模型，没有关联时使用模型列表中的第一个
+func RunProviderSelftest(ctx context.Context, providerID uint, model string) (*models.ProviderSelftest, error) {
+	provider, err := gorm.G[models.Provider](models.DB).Where("id = ?", providerID).First(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatModel, err := providers.New(ctx, provider.Type, provider.Config, provider.ClientOptions())
+	if err != nil {
+		return nil, err
+	}
+	if model == "" {
+		if mp, err := gorm.G[models.ModelWithProvider](models.DB).Where("provider_id = ?", providerID).Order("id").First(ctx); err == nil {
+			model = mp.ProviderModel
+		}
+	}
+
+	result := models.ProviderSelftest{ProviderID: providerID}
+	for _, name := range selftestCases {
+		start := time.Now()
+		var status string
+		var caseErr error
+		if name == "models" {
+			var list []providers.Model
+			list, caseErr = chatModel.Models(ctx)
+			if caseErr == nil && len(list) == 0 {
+				caseErr = errors.New("empty model list")
+			}
+			if caseErr == nil && model == "" {
+				model = list[0].ID
+			}
+		} else if model == "" {
+			caseErr = ErrSelftestNoModel
+		} else {
+			status, caseErr = runSelftestCase(ctx, chatModel, &provider, model, name)
+		}
+		if caseErr != nil {
+			status = SelftestFail
+		} else if status == "" {
+			status = SelftestPass
+		}
+
+		item := models.SelftestCase{Name: name, Status: status, LatencyMs: time.Since(start).Milliseconds()}
+		if caseErr != nil {
+			item.Error = caseErr.Error()
+		}
+		switch status {
+		case SelftestPass:
+			result.Passed++
+		case SelftestFail:
+			result.Failed++
+		}
+		result.Cases = append(result.Cases, item)
+	}
+	result.ProviderModel = model
+
+	if err := gorm.G[models.ProviderSelftest](models.DB).Create(ctx, &result); err != nil {
+		return nil, fmt.Errorf("save provider selftest: %w", err)
+	}
+	return &result, nil
+}
+
+// ProviderSelftests 返回提供商最近的自检记录，按时间倒序
+func ProviderSelftests(ctx context.Context, providerID uint, limit int) ([]models.ProviderSelftest, error) {
+	return gorm.G[models.ProviderSelftest](models.DB).Where("provider_id = ?", providerID).Order("id DESC").Limit(limit).Find(ctx)
+}
+
+// runSelftestCase 执行单个请求类用例，返回 skip 表示该协议不支持此能力
+func runSelftestCase(ctx context.Context, chatModel providers.Provider, provider *models.Provider, model, name string) (string, error) {
+	body, ok := selftestBody(provider.Type, name)
+	if !ok {
+		return SelftestSkip, nil
+	}
+	stream := name == "stream"
+	if stream && provider.Type == consts.StyleGemini {
+		ctx = context.WithValue(ctx, consts.ContextKeyGeminiStream, true)
+	}
+	header := BuildHeaders(nil, false, provider.Headers, stream, provider.UserAgent)
+	req, err := chatModel.BuildReq(ctx, header, model, body)
+	if err != nil {
+		return "", err
+	}
+	ApplyQueryParams(req, provider.QueryParams)
+	res, err := providers.GetClient(selftestTimeout, provider.ClientOptions()).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		content, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return "", fmt.Errorf("status: %d, body: %s", res.StatusCode, string(content))
+	}
+	if stream {
+		return "", checkSelftestStream(res.Body)
+	}
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return "", checkSelftestResponse(provider.Type, name, content)
+}
+
+// selftestBody 按提供商协议构建用例请求体，不支持的能力返回 false
+func selftestBody(providerType, name string) ([]byte, bool) {
+	text := selftestPrompt
+	switch name {
+	case "tool_call":
+		text = "What is the weather in Beijing?"
+	case "json_mode":
+		text = `Reply in JSON with a single key "answer" whose value is yes or no`
+	case "vision":
+		text = "What color is this image? Reply with one word."
+	}
+	image := selftestImage()
+
+	body := map[string]any{}
+	switch providerType {
+	case consts.StyleOpenAI, consts.StyleGeminiOpenAI:
+		var content any = text
+		if name == "vision" {
+			content = []map[string]any{
+				{"type": "text", "text": text},
+				{"type": "image_url", "image_url": map[string]any{"url": "data:image/png;base64," + image}},
+			}
+		}
+		body["messages"] = []map[string]any{{"role": "user", "content": content}}
+		switch name {
+		case "stream":
+			body["stream"] = true
+		case "tool_call":
+			body["tools"] = []map[string]any{{"type": "function", "function": selftestTool}}
+			body["tool_choice"] = "required"
+		case "json_mode":
+			body["response_format"] = map[string]any{"type": "json_object"}
+		}
+	case consts.StyleOpenAIRes:
+		content := []map[string]any{{"type": "input_text", "text": text}}
+		if name == "vision" {
+			content = append(content, map[string]any{"type": "input_image", "image_url": "data:image/png;base64," + image})
+		}
+		body["input"] = []map[string]any{{"role": "user", "content": content}}
+		switch name {
+		case "stream":
+			body["stream"] = true
+		case "tool_call":
+			body["tools"] = []map[string]any{{
+				"type":        "function",
+				"name":        selftestTool["name"],
+				"description": selftestTool["description"],
+				"parameters":  selftestTool["parameters"],
+			}}
+			body["tool_choice"] = "required"
+		case "json_mode":
+			body["text"] = map[string]any{"format": map[string]any{"type": "json_object"}}
+		}
+	case consts.StyleAnthropic:
+		// Anthropic 没有原生 JSON 模式
+		if name == "json_mode" {
+			return nil, false
+		}
+		content := []map[string]any{{"type": "text", "text": text}}
+		if name == "vision" {
+			content = append(content, map[string]any{
+				"type":   "image",
+				"source": map[string]any{"type": "base64", "media_type": "image/png", "data": image},
+			})
+		}
+		body["max_tokens"] = selftestMaxTokens
+		body["messages"] = []map[string]any{{"role": "user", "content": content}}
+		switch name {
+		case "stream":
+			body["stream"] = true
+		case "tool_call":
+			body["tools"] = []map[string]any{{
+				"name":         selftestTool["name"],
+				"description":  selftestTool["description"],
+				"input_schema": selftestTool["parameters"],
+			}}
+			body["tool_choice"] = map[string]any{"type": "any"}
+		}
+	case consts.StyleGemini:
+		parts := []map[string]any{{"text": text}}
+		if name == "vision" {
+			parts = append(parts, map[string]any{"inline_data": map[string]any{"mime_type": "image/png", "data": image}})
+		}
+		body["contents"] = []map[string]any{{"role": "user", "parts": parts}}
+		switch name {
+		case "tool_call":
+			body["tools"] = []map[string]any{{"functionDeclarations": []map[string]any{selftestTool}}}
+			body["toolConfig"] = map[string]any{"functionCallingConfig": map[string]any{"mode": "ANY"}}
+		case "json_mode":
+			body["generationConfig"] = map[string]any{"responseMimeType": "application/json"}
+		}
+	default:
+		return nil, false
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// checkSelftestResponse 校验非流式用例的响应内容
+func checkSelftestResponse(providerType, name string, body []byte) error {
+	if err := validateCompletion(providerType, providerType, body); err != nil {
+		return err
+	}
+	res := gjson.ParseBytes(body)
+	switch name {
+	case "tool_call":
+		var called bool
+		switch providerType {
+		case consts.StyleOpenAI, consts.StyleGeminiOpenAI:
+			called = res.Get("choices.0.message.tool_calls.0.function.name").String() != ""
+		case consts.StyleOpenAIRes:
+			called = res.Get(`output.#(type=="function_call")`).Exists()
+		case consts.StyleAnthropic:
+			called = res.Get(`content.#(type=="tool_use")`).Exists()
+		case consts.StyleGemini:
+			called = len(res.Get("candidates.0.content.parts.#.functionCall").Array()) > 0
+		}
+		if !called {
+			return errors.New("no tool call in response")
+		}
+	case "json_mode":
+		var text string
+		switch providerType {
+		case consts.StyleOpenAI, consts.StyleGeminiOpenAI:
+			text = res.Get("choices.0.message.content").String()
+		case consts.StyleOpenAIRes:
+			text = res.Get(`output.#(type=="message").content.0.text`).String()
+		case consts.StyleGemini:
+			text = res.Get("candidates.0.content.parts.0.text").String()
+		}
+		if !gjson.Valid(text) {
+			return errors.New("response content is not valid JSON")
+		}
+	}
+	return nil
+}
+
+// checkSelftestStream 校验流式响应至少包含一个数据事件且没有错误事件
+func checkSelftestStream(body io.Reader) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	var events int
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "event: error" {
+			return errors.New("stream returned error event")
+		}
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		if gjson.Get(data, "error").Exists() {
+			return fmt.Errorf("stream error: %s", data)
+		}
+		events++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if events == 0 {
+		return errors.New("stream has no data events")
+	}
+	return nil
+}
+
+// selftestImage 返回图片理解用例使用的纯红色 PNG，base64 编码
+func selftestImage() string {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for x := range 32 {
+		for y := range 32 {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/tidwall/gjson"
+)
+
+func TestRunProviderSelftest(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/models" {
+			io.WriteString(w, `{"object":"list","data":[{"id":"gpt-4.1"}]}`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		req := gjson.ParseBytes(body)
+		switch {
+		case req.Get("stream").Bool():
+			io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"yes\"}}]}\n\ndata: [DONE]\n\n")
+		case req.Get("tools").Exists():
+			io.WriteString(w, `{"choices":[{"message":{"tool_calls":[{"function":{"name":"get_weather","arguments":"{}"}}]}}]}`)
+		case req.Get("response_format").Exists():
+			// 模拟不支持 JSON 模式的渠道
+			io.WriteString(w, `{"choices":[{"message":{"content":"yes"}}]}`)
+		case req.Get("messages.0.content.1.image_url").Exists():
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":{"message":"image input not supported"}}`)
+		default:
+			io.WriteString(w, `{"choices":[{"message":{"content":"yes"}}]}`)
+		}
+	}))
+	defer upstream.Close()
+
+	db := setupTestDB(t, &models.Provider{}, &models.ModelWithProvider{}, &models.ProviderSelftest{})
+	ctx := context.Background()
+
+	provider := models.Provider{Name: "openai", Type: consts.StyleOpenAI, Config: `{"base_url":"` + upstream.URL + `/v1","api_key":"k"}`}
+	db.Create(&provider)
+
+	result, err := RunProviderSelftest(ctx, provider.ID, "")
+	if err != nil {
+		t.Fatalf("RunProviderSelftest: %v", err)
+	}
+	if result.ProviderModel != "gpt-4.1" {
+		t.Errorf("ProviderModel = %q, want model from list", result.ProviderModel)
+	}
+	want := map[string]string{
+		"models":    SelftestPass,
+		"chat":      SelftestPass,
+		"stream":    SelftestPass,
+		"tool_call": SelftestPass,
+		"json_mode": SelftestFail,
+		"vision":    SelftestFail,
+	}
+	for _, item := range result.Cases {
+		if item.Status != want[item.Name] {
+			t.Errorf("case %s = %s (%s), want %s", item.Name, item.Status, item.Error, want[item.Name])
+		}
+	}
+	if result.Passed != 4 || result.Failed != 2 {
+		t.Errorf("passed/failed = %d/%d, want 4/2", result.Passed, result.Failed)
+	}
+
+	history, err := ProviderSelftests(ctx, provider.ID, 10)
+	if err != nil || len(history) != 1 || len(history[0].Cases) != len(selftestCases) {
+		t.Fatalf("ProviderSelftests = %+v, %v", history, err)
+	}
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"io"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// pseudoStreamChunkRunes 伪流式每个文本增量包含的字符数
+const pseudoStreamChunkRunes = 20
+
+// streamConvertible 仅对话类协议支持流式与非流式响应之间的转换
+func streamConvertible(style string) bool {
+	switch style {
+	case consts.StyleOpenAI, consts.StyleOpenAIRes, consts.StyleAnthropic, consts.StyleGemini:
+		return true
+	}
+	return false
+}
+
+// disableUpstreamStream 将上游请求体改为非流式，Gemini 通过请求路径区分由调用方处理
+func disableUpstreamStream(raw []byte, providerType string) ([]byte, error) {
+	switch providerType {
+	case consts.StyleGemini:
+		return raw, nil
+	}
+	raw, err := sjson.DeleteBytes(raw, "stream_options")
+	if err != nil {
+		return nil, err
+	}
+	return sjson.SetBytes(raw, "stream", false)
+}
+
+// pseudoStream 将客户端协议的非流式响应切分为同协议的 SSE 事件，保留用量信息供日志统计
+func pseudoStream(body io.ReadCloser, style string) io.ReadCloser {
+	return pipeTranslate(body, func(r io.Reader, w io.Writer) error {
+		raw, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		res := gjson.ParseBytes(raw)
+		sse := sseWriter{w: w}
+		switch style {
+		case consts.StyleAnthropic:
+			return pseudoStreamAnthropic(sse, res)
+		case consts.StyleOpenAIRes:
+			return pseudoStreamResponses(sse, res)
+		case consts.StyleGemini:
+			return pseudoStreamGemini(sse, res)
+		default:
+			return pseudoStreamOpenAI(sse, res)
+		}
+	})
+}
+
+func pseudoStreamOpenAI(sse sseWriter, res gjson.Result) error {
+	created := res.Get("created").Int()
+	if created == 0 {
+		created = time.Now().Unix()
+	}
+	chunk := func(choices []map[string]any) map[string]any {
+		return map[string]any{
+			"id":      res.Get("id").String(),
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   res.Get("model").String(),
+			"choices": choices,
+		}
+	}
+	delta := func(index int64, delta map[string]any) error {
+		return sse.event("", chunk([]map[string]any{{"index": index, "delta": delta, "finish_reason": nil}}))
+	}
+
+	for _, choice := range res.Get("choices").Array() {
+		index := choice.Get("index").Int()
+		message := choice.Get("message")
+		if err := delta(index, map[string]any{"role": "assistant", "content": ""}); err != nil {
+			return err
+		}
+		for _, key := range []string{"reasoning_content", "content", "refusal"} {
+			for _, piece := range splitRunes(message.Get(key).String(), pseudoStreamChunkRunes) {
+				if err := delta(index, map[string]any{key: piece}); err != nil {
+					return err
+				}
+			}
+		}
+		for i, call := range message.Get("tool_calls").Array() {
+			if err := delta(index, map[string]any{"tool_calls": []map[string]any{{
+				"index":    i,
+				"id":       call.Get("id").String(),
+				"type":     "function",
+				"function": call.Get("function").Value(),
+			}}}); err != nil {
+				return err
+			}
+		}
+		if err := sse.event("", chunk([]map[string]any{{"index": index, "delta": map[string]any{}, "finish_reason": choice.Get("finish_reason").Value()}})); err != nil {
+			return err
+		}
+	}
+	if usage := res.Get("usage"); usage.Exists() {
+		final := chunk([]map[string]any{})
+		final["usage"] = usage.Value()
+		if err := sse.event("", final); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(sse.w, "data: [DONE]\n\n")
+	return err
+}
+
+func pseudoStreamAnthropic(sse sseWriter, res gjson.Result) error {
+	message, _ := res.Value().(map[string]any)
+	if message == nil {
+		message = map[string]any{}
+	}
+	start := map[string]any{}
+	for k, v := range message {
+		start[k] = v
+	}
+	start["content"] = []any{}
+	start["stop_reason"] = nil
+	start["stop_sequence"] = nil
+	if err := sse.event("message_start", map[string]any{"type": "message_start", "message": start}); err != nil {
+		return err
+	}
+
+	for i, block := range res.Get("content").Array() {
+		blockType := block.Get("type").String()
+		var head any
+		var deltas []map[string]any
+		switch blockType {
+		case "text":
+			head = map[string]any{"type": "text", "text": ""}
+			for _, piece := range splitRunes(block.Get("text").String(), pseudoStreamChunkRunes) {
+				deltas = append(deltas, map[string]any{"type": "text_delta", "text": piece})
+			}
+		case "thinking":
+			head = map[string]any{"type": "thinking", "thinking": ""}
+			for _, piece := range splitRunes(block.Get("thinking").String(), pseudoStreamChunkRunes) {
+				deltas = append(deltas, map[string]any{"type": "thinking_delta", "thinking": piece})
+			}
+			if signature := block.Get("signature").String(); signature != "" {
+				deltas = append(deltas, map[string]any{"type": "signature_delta", "signature": signature})
+			}
+		case "tool_use", "server_tool_use":
+			head = map[string]any{"type": blockType, "id": block.Get("id").String(), "name": block.Get("name").String(), "input": map[string]any{}}
+			deltas = append(deltas, map[string]any{"type": "input_json_delta", "partial_json": block.Get("input").Raw})
+		default:
+			// 其余内容块无增量格式，原样放在 content_block_start 中
+			head = block.Value()
+		}
+
+		if err := sse.event("content_block_start", map[string]any{"type": "content_block_start", "index": i, "content_block": head}); err != nil {
+			return err
+		}
+		for _, d := range deltas {
+			if err := sse.event("content_block_delta", map[string]any{"type": "content_block_delta", "index": i, "delta": d}); err != nil {
+				return err
+			}
+		}
+		if err := sse.event("content_block_stop", map[string]any{"type": "content_block_stop", "index": i}); err != nil {
+			return err
+		}
+	}
+
+	if err := sse.event("message_delta", map[string]any{
+		"type":  "message_delta",
+		"delta": map[string]any{"stop_reason": res.Get("stop_reason").Value(), "stop_sequence": res.Get("stop_sequence").Value()},
+		"usage": res.Get("usage").Value(),
+	}); err != nil {
+		return err
+	}
+	return sse.event("message_stop", map[string]any{"type": "message_stop"})
+}
+
+func pseudoStreamResponses(sse sseWriter, res gjson.Result) error {
+	var seq int
+	emit := func(name string, data map[string]any) error {
+		data["type"] = name
+		data["sequence_number"] = seq
+		seq++
+		return sse.event(name, data)
+	}
+
+	created, _ := res.Value().(map[string]any)
+	if created == nil {
+		created = map[string]any{}
+	}
+	inProgress := map[string]any{}
+	for k, v := range created {
+		inProgress[k] = v
+	}
+	inProgress["status"] = "in_progress"
+	inProgress["output"] = []any{}
+	if err := emit("response.created", map[string]any{"response": inProgress}); err != nil {
+		return err
+	}
+
+	for i, item := range res.Get("output").Array() {
+		itemID := item.Get("id").String()
+		added := item.Value()
+		if item.Get("type").String() == "message" {
+			added = map[string]any{"id": itemID, "type": "message", "role": item.Get("role").String(), "status": "in_progress", "content": []any{}}
+		}
+		if err := emit("response.output_item.added", map[string]any{"output_index": i, "item": added}); err != nil {
+			return err
+		}
+		if item.Get("type").String() == "message" {
+			for j, part := range item.Get("content").Array() {
+				if part.Get("type").String() != "output_text" {
+					continue
+				}
+				text := part.Get("text").String()
+				if err := emit("response.content_part.added", map[string]any{
+					"item_id": itemID, "output_index": i, "content_index": j,
+					"part": map[string]any{"type": "output_text", "text": "", "annotations": []any{}},
+				}); err != nil {
+					return err
+				}
+				for _, piece := range splitRunes(text, pseudoStreamChunkRunes) {
+					if err := emit("response.output_text.delta", map[string]any{"item_id": itemID, "output_index": i, "content_index": j, "delta": piece}); err != nil {
+						return err
+					}
+				}
+				if err := emit("response.output_text.done", map[string]any{"item_id": itemID, "output_index": i, "content_index": j, "text": text}); err != nil {
+					return err
+				}
+				if err := emit("response.content_part.done", map[string]any{"item_id": itemID, "output_index": i, "content_index": j, "part": part.Value()}); err != nil {
+					return err
+				}
+			}
+		}
+		if err := emit("response.output_item.done", map[string]any{"output_index": i, "item": item.Value()}); err != nil {
+			return err
+		}
+	}
+
+	done := "response.completed"
+	if res.Get("status").String() == "incomplete" {
+		done = "response.incomplete"
+	}
+	return emit(done, map[string]any{"response": res.Value()})
+}
+
+func pseudoStreamGemini(sse sseWriter, res gjson.Result) error {
+	candidate := res.Get("candidates.0")
+	chunk := func(parts []any) map[string]any {
+		return map[string]any{
+			"candidates":   []map[string]any{{"content": map[string]any{"role": "model", "parts": parts}, "index": 0}},
+			"modelVersion": res.Get("modelVersion").Value(),
+			"responseId":   res.Get("responseId").Value(),
+		}
+	}
+
+	// 文本分片发送，函数调用等其余部分随最后一个事件一起发送
+	var rest []any
+	for _, part := range candidate.Get("content.parts").Array() {
+		text := part.Get("text")
+		if !text.Exists() || part.Get("functionCall").Exists() {
+			rest = append(rest, part.Value())
+			continue
+		}
+		for _, piece := range splitRunes(text.String(), pseudoStreamChunkRunes) {
+			p := map[string]any{"text": piece}
+			if part.Get("thought").Bool() {
+				p["thought"] = true
+			}
+			if err := sse.event("", chunk([]any{p})); err != nil {
+				return err
+			}
+		}
+	}
+	if len(rest) == 0 {
+		rest = []any{map[string]any{"text": ""}}
+	}
+
+	final := chunk(rest)
+	last := final["candidates"].([]map[string]any)[0]
+	for _, key := range []string{"finishReason", "safetyRatings", "groundingMetadata"} {
+		if v := candidate.Get(key); v.Exists() {
+			last[key] = v.Value()
+		}
+	}
+	if usage := res.Get("usageMetadata"); usage.Exists() {
+		final["usageMetadata"] = usage.Value()
+	}
+	return sse.event("", final)
+}
+
+// splitRunes 按字符数切分文本，避免截断多字节字符
+func splitRunes(s string, n int) []string {
+	if s == "" {
+		return nil
+	}
+	runes := []rune(s)
+	pieces := make([]string, 0, (len(runes)+n-1)/n)
+	for i := 0; i < len(runes); i += n {
+		pieces = append(pieces, string(runes[i:min(i+n, len(runes))]))
+	}
+	return pieces
+}
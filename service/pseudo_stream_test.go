@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+)
+
+func TestPseudoStream(t *testing.T) {
+	long := strings.Repeat("你好world", 10)
+	tests := []struct {
+		name      string
+		style     string
+		processer Processer
+		body      string
+		total     int64
+		finish    string
+	}{
+		{
+			"openai", consts.StyleOpenAI, ProcesserOpenAI,
+			`{"id":"c1","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"` + long + `","tool_calls":[{"id":"t1","type":"function","function":{"name":"f","arguments":"{}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":3,"completion_tokens":5,"total_tokens":8}}`,
+			8, FinishToolUse,
+		},
+		{
+			"anthropic", consts.StyleAnthropic, ProcesserAnthropic,
+			`{"id":"m1","type":"message","role":"assistant","model":"m","content":[{"type":"text","text":"` + long + `"},{"type":"tool_use","id":"t1","name":"f","input":{"a":1}}],"stop_reason":"tool_use","usage":{"input_tokens":3,"output_tokens":5}}`,
+			8, FinishToolUse,
+		},
+		{
+			"responses", consts.StyleOpenAIRes, ProcesserOpenAiRes,
+			`{"id":"r1","object":"response","status":"completed","output":[{"id":"i1","type":"message","role":"assistant","content":[{"type":"output_text","text":"` + long + `","annotations":[]}]}],"usage":{"input_tokens":3,"output_tokens":5,"total_tokens":8}}`,
+			8, FinishStop,
+		},
+		{
+			"gemini", consts.StyleGemini, ProcesserGemini,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"` + long + `"}]},"finishReason":"MAX_TOKENS"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":5,"totalTokenCount":8}}`,
+			8, FinishLength,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := pseudoStream(io.NopCloser(strings.NewReader(tt.body)), tt.style)
+			log, output, err := tt.processer(context.Background(), stream, true, time.Now())
+			if err != nil {
+				t.Fatalf("processer failed: %v", err)
+			}
+			if log.TotalTokens != tt.total {
+				t.Errorf("total tokens = %d, want %d", log.TotalTokens, tt.total)
+			}
+			if log.FinishReason != tt.finish {
+				t.Errorf("finish reason = %q, want %q", log.FinishReason, tt.finish)
+			}
+			// 长文本应被切分为多个增量事件
+			if len(output.OfStringArray) < 4 {
+				t.Errorf("chunks = %d, want multiple deltas", len(output.OfStringArray))
+			}
+		})
+	}
+}
+
+func TestDisableUpstreamStream(t *testing.T) {
+	body, err := disableUpstreamStream([]byte(`{"stream":true,"stream_options":{"include_usage":true}}`), consts.StyleOpenAI)
+	if err != nil {
+		t.Fatalf("disableUpstreamStream: %v", err)
+	}
+	if string(body) != `{"stream":false}` {
+		t.Fatalf("body = %s", body)
+	}
+}
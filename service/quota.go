@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// quotaWindow 配额滑动窗口长度
+const quotaWindow = time.Minute
+
+var ErrQuotaExceeded = errors.New("provider quota exceeded")
+
+type tokenUsage struct {
+	at     time.Time
+	tokens int64
+}
+
+// associationQuota 关联最近一分钟内的请求时间与 Token 用量
+type associationQuota struct {
+	requests []time.Time
+	tokens   []tokenUsage
+}
+
+// prune 移除滑动窗口外的记录
+func (q *associationQuota) prune(now time.Time) {
+	cutoff := now.Add(-quotaWindow)
+	i := 0
+	for i < len(q.requests) && !q.requests[i].After(cutoff) {
+		i++
+	}
+	q.requests = q.requests[i:]
+	j := 0
+	for j < len(q.tokens) && !q.tokens[j].at.After(cutoff) {
+		j++
+	}
+	q.tokens = q.tokens[j:]
+}
+
+func (q *associationQuota) usedTokens() int64 {
+	var used int64
+	for _, usage := range q.tokens {
+		used += usage.tokens
+	}
+	return used
+}
+
+var (
+	quotaMu sync.Mutex
+	quotas  = make(map[uint]*associationQuota)
+)
+
+// takeQuota 检查关联最近一分钟的请求数与 Token 用量，未超出配额时计入本次请求；rpm、tpm 为 0 时不限制
+func takeQuota(id uint, rpm, tpm int, now time.Time) bool {
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	if rpm <= 0 && tpm <= 0 {
+		// 取消配额后不再统计
+		delete(quotas, id)
+		return true
+	}
+	q, ok := quotas[id]
+	if !ok {
+		q = &associationQuota{}
+		quotas[id] = q
+	}
+	q.prune(now)
+	if rpm > 0 && len(q.requests) >= rpm {
+		return false
+	}
+	// Token 用量在响应结束后才知道，已用满时拒绝新请求
+	if tpm > 0 && q.usedTokens() >= int64(tpm) {
+		return false
+	}
+	q.requests = append(q.requests, now)
+	return true
+}
+
+// recordQuotaTokens 记录请求结束后的 Token 用量，仅统计配置了配额的关联
+func recordQuotaTokens(id uint, tokens int64, now time.Time) {
+	if tokens <= 0 {
+		return
+	}
+	quotaMu.Lock()
+	defer quotaMu.Unlock()
+	q, ok := quotas[id]
+	if !ok {
+		return
+	}
+	q.prune(now)
+	q.tokens = append(q.tokens, tokenUsage{at: now, tokens: tokens})
+}
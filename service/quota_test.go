@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakeQuota(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		id     uint
+		rpm    int
+		tpm    int
+		tokens int64
+		want   []bool
+	}{
+		{"unlimited", 201, 0, 0, 100, []bool{true, true, true}},
+		{"rpm", 202, 2, 0, 0, []bool{true, true, false}},
+		{"tpm", 203, 0, 150, 100, []bool{true, true, false}},
+		{"rpm and tpm", 204, 5, 100, 100, []bool{true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				at := now.Add(time.Duration(i) * time.Second)
+				if got := takeQuota(tt.id, tt.rpm, tt.tpm, at); got != want {
+					t.Fatalf("request %d allowed = %v, want %v", i+1, got, want)
+				}
+				if want {
+					recordQuotaTokens(tt.id, tt.tokens, at)
+				}
+			}
+			// 窗口滑过第一次请求后恢复
+			if !takeQuota(tt.id, tt.rpm, tt.tpm, now.Add(time.Minute+time.Second)) {
+				t.Fatal("request after window slid rejected")
+			}
+		})
+	}
+	if _, ok := quotas[201]; ok {
+		t.Fatal("unlimited association should not be tracked")
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+const (
+	RateScopeAuthKey  = "auth_key"
+	RateScopeProvider = "provider"
+	// RateScopeProviderGroup 同一分组的提供商共用计数，Name 为分组名
+	RateScopeProviderGroup = "provider_group"
+
+	// rateViolationHistory 保留的最近超限记录数
+	rateViolationHistory = 200
+)
+
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateViolation 一次超限请求，observe 模式下请求仍被放行
+type RateViolation struct {
+	Scope    string    `json:"scope"`
+	TargetID uint      `json:"target_id"`
+	Name     string    `json:"name,omitempty"`
+	RPM      int       `json:"rpm"`
+	Count    int       `json:"count"` // 当前分钟内含本次的请求数
+	Mode     string    `json:"mode"`
+	Rejected bool      `json:"rejected"`
+	At       time.Time `json:"at"`
+}
+
+// RateViolationTotal 各限制对象累计超限次数
+type RateViolationTotal struct {
+	Scope      string    `json:"scope"`
+	TargetID   uint      `json:"target_id"`
+	Name       string    `json:"name,omitempty"`
+	Mode       string    `json:"mode"`
+	Violations int       `json:"violations"`
+	Rejected   int       `json:"rejected"`
+	LastAt     time.Time `json:"last_at"`
+}
+
+// RateLimitReport 进程启动以来的超限统计
+type RateLimitReport struct {
+	Totals []RateViolationTotal `json:"totals"`
+	Recent []RateViolation      `json:"recent"` // 最近的超限请求，新的在前
+}
+
+type rateTarget struct {
+	scope string
+	id    uint
+	group string
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+var rateLimiter = struct {
+	sync.Mutex
+	windows map[rateTarget]*rateWindow
+	totals  map[rateTarget]*RateViolationTotal
+	recent  []RateViolation
+}{
+	windows: map[rateTarget]*rateWindow{},
+	totals:  map[rateTarget]*RateViolationTotal{},
+}
+
+// takeRate 按分钟窗口计数，返回本次请求是否放行；observe 模式超限时只记录
+func takeRate(scope string, id uint, name string, limit models.RateLimit, now time.Time) bool {
+	if limit.RPM <= 0 {
+		return true
+	}
+	observe := limit.Mode == consts.RateLimitObserve
+	target := rateTarget{scope: scope, id: id}
+	if scope == RateScopeProviderGroup {
+		target.group = name
+	}
+	minute := now.Truncate(time.Minute)
+
+	rateLimiter.Lock()
+	defer rateLimiter.Unlock()
+	window, ok := rateLimiter.windows[target]
+	if !ok || !window.start.Equal(minute) {
+		window = &rateWindow{start: minute}
+		rateLimiter.windows[target] = window
+	}
+	if window.count < limit.RPM {
+		window.count++
+		return true
+	}
+
+	count := window.count + 1
+	mode := consts.RateLimitEnforce
+	if observe {
+		mode = consts.RateLimitObserve
+		// 放行的请求同样计入窗口
+		window.count = count
+	}
+	violation := RateViolation{
+		Scope:    scope,
+		TargetID: id,
+		Name:     name,
+		RPM:      limit.RPM,
+		Count:    count,
+		Mode:     mode,
+		Rejected: !observe,
+		At:       now,
+	}
+	rateLimiter.recent = append(rateLimiter.recent, violation)
+	if len(rateLimiter.recent) > rateViolationHistory {
+		rateLimiter.recent = rateLimiter.recent[len(rateLimiter.recent)-rateViolationHistory:]
+	}
+	total, ok := rateLimiter.totals[target]
+	if !ok {
+		total = &RateViolationTotal{Scope: scope, TargetID: id}
+		rateLimiter.totals[target] = total
+	}
+	total.Name = name
+	total.Mode = mode
+	total.Violations++
+	if !observe {
+		total.Rejected++
+	}
+	total.LastAt = now
+	slog.Warn("rate limit exceeded", "scope", scope, "id", id, "name", name, "rpm", limit.RPM, "count", violation.Count, "mode", mode)
+	return observe
+}
+
+// takeProviderRate 提供商属于共享分组时与同组提供商共用一个计数窗口，上限取本提供商的配置
+func takeProviderRate(provider models.Provider, now time.Time) bool {
+	if provider.QuotaGroup != "" {
+		return takeRate(RateScopeProviderGroup, 0, provider.QuotaGroup, provider.RateLimit, now)
+	}
+	return takeRate(RateScopeProvider, provider.ID, provider.Name, provider.RateLimit, now)
+}
+
+// CheckAuthKeyRate 校验当前请求所用 Key 的频率限制
+func CheckAuthKeyRate(ctx context.Context) error {
+	limit, _ := ctx.Value(consts.ContextKeyAuthKeyRateLimit).(models.RateLimit)
+	authKeyID, _ := ctx.Value(consts.ContextKeyAuthKeyID).(uint)
+	if !takeRate(RateScopeAuthKey, authKeyID, "", limit, time.Now()) {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// GetRateLimitReport 返回超限统计，observe 模式下即为拟拒绝的请求
+func GetRateLimitReport() RateLimitReport {
+	rateLimiter.Lock()
+	defer rateLimiter.Unlock()
+	report := RateLimitReport{
+		Totals: make([]RateViolationTotal, 0, len(rateLimiter.totals)),
+		Recent: slices.Clone(rateLimiter.recent),
+	}
+	for _, total := range rateLimiter.totals {
+		report.Totals = append(report.Totals, *total)
+	}
+	slices.SortFunc(report.Totals, func(a, b RateViolationTotal) int {
+		return cmp.Compare(b.Violations, a.Violations)
+	})
+	slices.Reverse(report.Recent)
+	return report
+}
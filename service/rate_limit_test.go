@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+func TestTakeRate(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		id   uint
+		mode string
+		want []bool
+	}{
+		{"enforce rejects over limit", 101, consts.RateLimitEnforce, []bool{true, true, false, false}},
+		{"empty mode enforces", 102, "", []bool{true, true, false}},
+		{"observe only records", 103, consts.RateLimitObserve, []bool{true, true, true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := models.RateLimit{RPM: 2, Mode: tt.mode}
+			for i, want := range tt.want {
+				if got := takeRate(RateScopeProvider, tt.id, "p", limit, now); got != want {
+					t.Fatalf("request %d allowed = %v, want %v", i+1, got, want)
+				}
+			}
+			// 下一分钟窗口重新计数
+			if !takeRate(RateScopeProvider, tt.id, "p", limit, now.Add(time.Minute)) {
+				t.Fatal("request in next window rejected")
+			}
+		})
+	}
+	if !takeRate(RateScopeAuthKey, 104, "", models.RateLimit{}, now) {
+		t.Fatal("unlimited request rejected")
+	}
+
+	report := GetRateLimitReport()
+	totals := map[uint]RateViolationTotal{}
+	for _, total := range report.Totals {
+		totals[total.TargetID] = total
+	}
+	if got := totals[101]; got.Violations != 2 || got.Rejected != 2 {
+		t.Fatalf("unexpected enforce totals: %+v", got)
+	}
+	if got := totals[103]; got.Violations != 2 || got.Rejected != 0 || got.Mode != consts.RateLimitObserve {
+		t.Fatalf("unexpected observe totals: %+v", got)
+	}
+	if len(report.Recent) == 0 || report.Recent[0].TargetID != 103 || report.Recent[0].Count != 4 {
+		t.Fatalf("unexpected recent violations: %+v", report.Recent)
+	}
+}
+
+func TestTakeProviderRateGroup(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	limit := models.RateLimit{RPM: 2}
+	east := models.Provider{Model: gorm.Model{ID: 201}, Name: "east", QuotaGroup: "shared-account", RateLimit: limit}
+	west := models.Provider{Model: gorm.Model{ID: 202}, Name: "west", QuotaGroup: "shared-account", RateLimit: limit}
+	solo := models.Provider{Model: gorm.Model{ID: 203}, Name: "solo", RateLimit: limit}
+
+	// 同组提供商共用计数，单独的提供商不受影响
+	for i, tt := range []struct {
+		provider models.Provider
+		want     bool
+	}{{east, true}, {west, true}, {east, false}, {west, false}, {solo, true}, {solo, true}} {
+		if got := takeProviderRate(tt.provider, now); got != tt.want {
+			t.Fatalf("request %d to %s allowed = %v, want %v", i+1, tt.provider.Name, got, tt.want)
+		}
+	}
+}
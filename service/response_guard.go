@@ -0,0 +1,135 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/atopos31/llmio/models"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	ErrResponseBlocked = errors.New("response blocked by policy")
+	ErrClientCanceled  = errors.New("client canceled")
+)
+
+// streamTextPaths 各协议流式事件中的文本增量
+var streamTextPaths = []string{
+	"choices.0.delta.content",
+	"delta.text",
+	"candidates.0.content.parts.#.text",
+}
+
+// streamGuard 按行检查流式响应的文本增量，命中屏蔽词后停止转发
+type streamGuard struct {
+	r       io.Reader
+	terms   []string
+	maxTerm int
+	window  string // 上一段文本的末尾，用于匹配跨数据块的屏蔽词
+	pending []byte // 尚未读到换行的半行
+	out     []byte // 已检查、待返回的内容
+	err     error
+}
+
+// GuardStream 模型配置了响应屏蔽词时包装流式响应，违规时返回 ErrResponseBlocked
+func GuardStream(r io.Reader, guard *models.ResponseGuard) io.Reader {
+	if guard == nil || len(guard.Blocklist) == 0 {
+		return r
+	}
+	g := &streamGuard{r: r}
+	for _, term := range guard.Blocklist {
+		if term = strings.ToLower(strings.TrimSpace(term)); term != "" {
+			g.terms = append(g.terms, term)
+			g.maxTerm = max(g.maxTerm, len(term))
+		}
+	}
+	if len(g.terms) == 0 {
+		return r
+	}
+	return g
+}
+
+func (g *streamGuard) Read(p []byte) (int, error) {
+	for len(g.out) == 0 {
+		if g.err != nil {
+			return 0, g.err
+		}
+		buf := make([]byte, len(p))
+		n, err := g.r.Read(buf)
+		g.pending = append(g.pending, buf[:n]...)
+		// 只转发完整的行，违规行及之后的内容都不会发给客户端
+		for {
+			i := bytes.IndexByte(g.pending, '\n')
+			if i < 0 {
+				break
+			}
+			line := g.pending[:i+1]
+			if term, ok := g.violation(line); ok {
+				g.pending = nil
+				g.err = fmt.Errorf("%w: %q", ErrResponseBlocked, term)
+				break
+			}
+			g.out = append(g.out, line...)
+			g.pending = g.pending[i+1:]
+		}
+		if err != nil && g.err == nil {
+			g.out = append(g.out, g.pending...)
+			g.pending = nil
+			g.err = err
+		}
+	}
+	n := copy(p, g.out)
+	g.out = g.out[n:]
+	return n, nil
+}
+
+// violation 提取行内文本增量并与屏蔽词比对，返回命中的屏蔽词
+func (g *streamGuard) violation(line []byte) (string, bool) {
+	data := bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(line), []byte("data:")))
+	if !gjson.ValidBytes(data) {
+		return "", false
+	}
+	event := gjson.ParseBytes(data)
+	var text strings.Builder
+	for _, path := range streamTextPaths {
+		for _, v := range event.Get(path).Array() {
+			text.WriteString(v.String())
+		}
+	}
+	// Responses API 文本增量
+	if strings.HasSuffix(event.Get("type").String(), "output_text.delta") {
+		text.WriteString(event.Get("delta").String())
+	}
+	if text.Len() == 0 {
+		return "", false
+	}
+	content := g.window + strings.ToLower(text.String())
+	for _, term := range g.terms {
+		if strings.Contains(content, term) {
+			return term, true
+		}
+	}
+	if keep := g.maxTerm - 1; len(content) > keep {
+		content = content[len(content)-keep:]
+	}
+	g.window = content
+	return "", false
+}
+
+// blockedReader 将 ErrResponseBlocked 与 ErrClientCanceled 视为流结束，使日志仍能统计已转发部分的用量
+type blockedReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *blockedReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && (errors.Is(err, ErrResponseBlocked) || errors.Is(err, ErrClientCanceled)) {
+		b.err = err
+		return n, io.EOF
+	}
+	return n, err
+}
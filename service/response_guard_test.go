@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/atopos31/llmio/models"
+)
+
+func TestGuardStream(t *testing.T) {
+	tests := []struct {
+		name      string
+		stream    string
+		blocklist []string
+		want      string
+		blocked   bool
+	}{
+		{
+			name:      "no guard",
+			stream:    "data: {\"choices\":[{\"delta\":{\"content\":\"secret\"}}]}\n\n",
+			blocklist: nil,
+			want:      "data: {\"choices\":[{\"delta\":{\"content\":\"secret\"}}]}\n\n",
+		},
+		{
+			name:      "openai split across chunks",
+			stream:    "data: {\"choices\":[{\"delta\":{\"content\":\"the SEC\"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"RET is\"}}]}\n\ndata: [DONE]\n\n",
+			blocklist: []string{"secret"},
+			want:      "data: {\"choices\":[{\"delta\":{\"content\":\"the SEC\"}}]}\n\n",
+			blocked:   true,
+		},
+		{
+			name:      "anthropic",
+			stream:    "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"hello\"}}\n\nevent: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"密码是\"}}\n\n",
+			blocklist: []string{"密码"},
+			want:      "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"hello\"}}\n\nevent: content_block_delta\n",
+			blocked:   true,
+		},
+		{
+			name:      "responses and gemini pass",
+			stream:    "data: {\"type\":\"response.output_text.delta\",\"delta\":\"fine\"}\n\ndata: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"ok\"}]}}]}",
+			blocklist: []string{"secret"},
+			want:      "data: {\"type\":\"response.output_text.delta\",\"delta\":\"fine\"}\n\ndata: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"ok\"}]}}]}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := GuardStream(io.MultiReader(strings.NewReader(tt.stream[:len(tt.stream)/2]), strings.NewReader(tt.stream[len(tt.stream)/2:])), &models.ResponseGuard{Blocklist: tt.blocklist})
+			got, err := io.ReadAll(r)
+			if tt.blocked != errors.Is(err, ErrResponseBlocked) {
+				t.Fatalf("err = %v, blocked = %v", err, tt.blocked)
+			}
+			if !tt.blocked && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("forwarded = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockedReader(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("data: partial\n\n"))
+		pw.CloseWithError(ErrResponseBlocked)
+	}()
+	r := &blockedReader{r: pr}
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != "data: partial\n\n" {
+		t.Fatalf("ReadAll = %q, %v", got, err)
+	}
+	if !errors.Is(r.err, ErrResponseBlocked) {
+		t.Fatalf("blocked err = %v", r.err)
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	ErrInvalidJSON   = errors.New("response is not valid JSON")
+	ErrEmptyResponse = errors.New("response has no content")
+)
+
+// validateCompletion 按上游提供商协议校验非流式响应是否为有效结果
+func validateCompletion(style, providerType string, body []byte) error {
+	if !gjson.ValidBytes(body) {
+		return ErrInvalidJSON
+	}
+	res := gjson.ParseBytes(body)
+	switch style {
+	case consts.StyleEmbedding, consts.StyleImageGeneration, consts.StyleRerank, consts.StyleModeration:
+		providerType = style
+	}
+	var ok bool
+	switch providerType {
+	case consts.StyleEmbedding, consts.StyleImageGeneration:
+		ok = len(res.Get("data").Array()) > 0
+	case consts.StyleRerank, consts.StyleModeration:
+		ok = len(res.Get("results").Array()) > 0
+	case consts.StyleOpenAI, consts.StyleGeminiOpenAI:
+		message := res.Get("choices.0.message")
+		ok = message.Get("content").String() != "" ||
+			len(message.Get("tool_calls").Array()) > 0 ||
+			message.Get("refusal").String() != ""
+	case consts.StyleOpenAIRes:
+		ok = len(res.Get("output").Array()) > 0
+	case consts.StyleAnthropic:
+		ok = len(res.Get("content").Array()) > 0
+	case consts.StyleGemini:
+		ok = len(res.Get("candidates.0.content.parts").Array()) > 0
+	case consts.StyleOllama:
+		ok = res.Get("message.content").String() != "" || len(res.Get("message.tool_calls").Array()) > 0
+	default:
+		return nil
+	}
+	if !ok {
+		return ErrEmptyResponse
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+)
+
+func TestValidateCompletion(t *testing.T) {
+	tests := []struct {
+		name         string
+		style        string
+		providerType string
+		body         string
+		want         error
+	}{
+		{"openai ok", consts.StyleOpenAI, consts.StyleOpenAI, `{"choices":[{"message":{"content":"hi"}}]}`, nil},
+		{"openai tool calls", consts.StyleOpenAI, consts.StyleOpenAI, `{"choices":[{"message":{"content":null,"tool_calls":[{"id":"1"}]}}]}`, nil},
+		{"openai empty choices", consts.StyleOpenAI, consts.StyleOpenAI, `{"choices":[]}`, ErrEmptyResponse},
+		{"gemini-openai empty content", consts.StyleOpenAI, consts.StyleGeminiOpenAI, `{"choices":[{"message":{"content":""}}]}`, ErrEmptyResponse},
+		{"truncated json", consts.StyleOpenAI, consts.StyleOpenAI, `{"choices":[{"message":{"content":"hi`, ErrInvalidJSON},
+		{"responses ok", consts.StyleOpenAIRes, consts.StyleOpenAIRes, `{"output":[{"type":"message"}]}`, nil},
+		{"responses empty", consts.StyleOpenAIRes, consts.StyleOpenAIRes, `{"output":[]}`, ErrEmptyResponse},
+		{"anthropic ok", consts.StyleAnthropic, consts.StyleAnthropic, `{"content":[{"type":"text","text":"hi"}]}`, nil},
+		{"anthropic empty", consts.StyleAnthropic, consts.StyleAnthropic, `{"content":[]}`, ErrEmptyResponse},
+		{"gemini ok", consts.StyleGemini, consts.StyleGemini, `{"candidates":[{"content":{"parts":[{"text":"hi"}]}}]}`, nil},
+		{"embedding ok", consts.StyleEmbedding, consts.StyleOpenAI, `{"data":[{"embedding":[0.1]}]}`, nil},
+		{"embedding empty", consts.StyleEmbedding, consts.StyleOpenAI, `{"data":[]}`, ErrEmptyResponse},
+		{"rerank empty", consts.StyleRerank, consts.StyleOpenAI, `{"results":[]}`, ErrEmptyResponse},
+		{"moderation ok", consts.StyleModeration, consts.StyleOpenAI, `{"results":[{"flagged":false}]}`, nil},
+		{"gemini no candidates", consts.StyleGemini, consts.StyleGemini, `{"promptFeedback":{}}`, ErrEmptyResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateCompletion(tt.style, tt.providerType, []byte(tt.body)); !errors.Is(err, tt.want) {
+				t.Fatalf("validateCompletion() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
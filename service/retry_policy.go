@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/atopos31/llmio/models"
+)
+
+// retryableStatus 上游返回该状态码时是否换提供商重试，未配置可重试状态码时全部重试
+func retryableStatus(policy *models.RetryPolicy, status int) bool {
+	return policy == nil || len(policy.RetryOn) == 0 || slices.Contains(policy.RetryOn, status)
+}
+
+// retryDelay 第 failures 次上游失败后到下一次重试的等待时间，指数退避并加入随机抖动；
+// 开启 Retry-After 时取两者较大值，但超出剩余时间的 Retry-After 会被忽略
+func retryDelay(policy *models.RetryPolicy, failures int, header http.Header, now, deadline time.Time) time.Duration {
+	if policy == nil {
+		return 0
+	}
+	var delay time.Duration
+	if policy.Backoff > 0 {
+		delay = time.Duration(policy.Backoff) * time.Millisecond << min(max(failures-1, 0), 16)
+		if policy.MaxBackoff > 0 {
+			delay = min(delay, time.Duration(policy.MaxBackoff)*time.Millisecond)
+		}
+		// 等待时间在 [delay/2, delay) 之间随机，避免多个请求同时重试
+		delay = delay/2 + rand.N(delay/2+1)
+	}
+	if policy.RetryAfter && header != nil {
+		if after, ok := parseRetryAfter(header.Get("Retry-After"), now); ok && now.Add(after).Before(deadline) {
+			delay = max(delay, after)
+		}
+	}
+	return delay
+}
+
+// parseRetryAfter 解析秒数或 HTTP 日期格式的 Retry-After
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
+}
+
+// waitRetry 等待退避时间，期间请求取消或总超时则返回对应错误
+func waitRetry(ctx context.Context, timeout <-chan time.Time, delay time.Duration) error {
+	wait := time.NewTimer(delay)
+	defer wait.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timeout:
+		return ErrRetryTimeout
+	case <-wait.C:
+		return nil
+	}
+}
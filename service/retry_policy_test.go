@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/models"
+)
+
+func TestRetryableStatus(t *testing.T) {
+	policy := &models.RetryPolicy{RetryOn: []int{429, 500, 502, 503}}
+	tests := []struct {
+		name   string
+		policy *models.RetryPolicy
+		status int
+		want   bool
+	}{
+		{"no policy", nil, http.StatusBadRequest, true},
+		{"empty list", &models.RetryPolicy{}, http.StatusBadRequest, true},
+		{"listed", policy, http.StatusBadGateway, true},
+		{"fail fast", policy, http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryableStatus(tt.policy, tt.status); got != tt.want {
+				t.Fatalf("retryableStatus(%d) = %v, want %v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	deadline := now.Add(time.Minute)
+	tests := []struct {
+		name     string
+		policy   *models.RetryPolicy
+		failures int
+		header   http.Header
+		min, max time.Duration
+	}{
+		{"no policy", nil, 1, nil, 0, 0},
+		{"first backoff", &models.RetryPolicy{Backoff: 200}, 1, nil, 100 * time.Millisecond, 200 * time.Millisecond},
+		{"exponential", &models.RetryPolicy{Backoff: 200}, 3, nil, 400 * time.Millisecond, 800 * time.Millisecond},
+		{"capped", &models.RetryPolicy{Backoff: 200, MaxBackoff: 300}, 5, nil, 150 * time.Millisecond, 300 * time.Millisecond},
+		{"retry after seconds", &models.RetryPolicy{Backoff: 100, RetryAfter: true}, 1, http.Header{"Retry-After": {"2"}}, 2 * time.Second, 2 * time.Second},
+		{"retry after date", &models.RetryPolicy{RetryAfter: true}, 1, http.Header{"Retry-After": {now.Add(3 * time.Second).Format(http.TimeFormat)}}, 3 * time.Second, 3 * time.Second},
+		{"retry after ignored", &models.RetryPolicy{Backoff: 100}, 1, http.Header{"Retry-After": {"2"}}, 50 * time.Millisecond, 100 * time.Millisecond},
+		{"retry after past deadline", &models.RetryPolicy{RetryAfter: true}, 1, http.Header{"Retry-After": {"120"}}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := retryDelay(tt.policy, tt.failures, tt.header, now, deadline)
+			if got < tt.min || got > tt.max {
+				t.Fatalf("retryDelay = %v, want between %v and %v", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestWaitRetry(t *testing.T) {
+	if err := waitRetry(context.Background(), nil, time.Millisecond); err != nil {
+		t.Fatalf("waitRetry = %v, want nil", err)
+	}
+	timeout := make(chan time.Time, 1)
+	timeout <- time.Now()
+	if err := waitRetry(context.Background(), timeout, time.Minute); !errors.Is(err, ErrRetryTimeout) {
+		t.Fatalf("waitRetry = %v, want ErrRetryTimeout", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := waitRetry(ctx, nil, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Fatalf("waitRetry = %v, want context.Canceled", err)
+	}
+}
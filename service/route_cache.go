@@ -0,0 +1,215 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// routeCacheTables 写入后需要清空路由缓存的表，configs 包含蓝绿分组等路由配置
+var routeCacheTables = map[string]bool{
+	"providers":            true,
+	"models":               true,
+	"model_with_providers": true,
+	"configs":              true,
+}
+
+// routeSnapshot 路由所需的全部模型、当前分组已启用的关联与可路由的提供商
+type routeSnapshot struct {
+	slot         string // 加载时的生效分组，切换分组后缓存失效
+	models       map[string]models.Model
+	associations map[uint][]models.ModelWithProvider // 按模型 ID 分组
+	providers    map[uint]models.Provider
+}
+
+var routeCache = struct {
+	sync.Mutex
+	ttl        time.Duration // 0 表示未开启缓存
+	generation uint64        // 每次写入递增，加载期间发生写入时丢弃加载结果
+	snapshot   *routeSnapshot
+	loadedAt   time.Time
+	loadMu     sync.Mutex // 缓存失效时只由一个请求重新加载
+}{}
+
+// EnableRouteCache 注册写入回调并开启路由缓存，模型、关联、提供商或配置任一写入后立即失效，ttl 兜底外部直接修改数据库的情况
+// 事务内的写入在提交前触发失效，提交前并发加载可能缓存旧数据，事务提交后需再调用 InvalidateRouteCache
+func EnableRouteCache(db *gorm.DB, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	invalidate := func(tx *gorm.DB) {
+		if tx.Error == nil && routeCacheTables[tx.Statement.Table] {
+			InvalidateRouteCache()
+		}
+	}
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("llmio:route_cache", invalidate); err != nil {
+		return fmt.Errorf("register create callback: %w", err)
+	}
+	if err := callbacks.Update().After("gorm:update").Register("llmio:route_cache", invalidate); err != nil {
+		return fmt.Errorf("register update callback: %w", err)
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("llmio:route_cache", invalidate); err != nil {
+		return fmt.Errorf("register delete callback: %w", err)
+	}
+	routeCache.Lock()
+	routeCache.ttl = ttl
+	routeCache.Unlock()
+	return nil
+}
+
+// InvalidateRouteCache 清空路由缓存，下一个请求重新加载
+func InvalidateRouteCache() {
+	routeCache.Lock()
+	defer routeCache.Unlock()
+	routeCache.generation++
+	routeCache.snapshot = nil
+}
+
+// currentRouteSnapshot 返回有效的路由缓存，未开启缓存时返回 nil
+func currentRouteSnapshot(ctx context.Context) (*routeSnapshot, error) {
+	cached := func() (*routeSnapshot, uint64, bool) {
+		routeCache.Lock()
+		defer routeCache.Unlock()
+		if routeCache.ttl <= 0 {
+			return nil, 0, true
+		}
+		if routeCache.snapshot != nil && routeCache.snapshot.slot == ActiveSlot() && time.Since(routeCache.loadedAt) < routeCache.ttl {
+			return routeCache.snapshot, 0, true
+		}
+		return nil, routeCache.generation, false
+	}
+	if snapshot, _, ok := cached(); ok {
+		return snapshot, nil
+	}
+	routeCache.loadMu.Lock()
+	defer routeCache.loadMu.Unlock()
+	// 等待期间可能已由其他请求加载完成
+	snapshot, generation, ok := cached()
+	if ok {
+		return snapshot, nil
+	}
+	start := time.Now()
+	snapshot, err := loadRouteSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	routeCache.Lock()
+	if routeCache.generation == generation {
+		routeCache.snapshot = snapshot
+		routeCache.loadedAt = start
+	}
+	routeCache.Unlock()
+	return snapshot, nil
+}
+
+func loadRouteSnapshot(ctx context.Context) (*routeSnapshot, error) {
+	slot := ActiveSlot()
+	modelList, err := gorm.G[models.Model](models.DB).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load models: %w", err)
+	}
+	associations, err := enabledAssociations(slot).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load associations: %w", err)
+	}
+	providerList, err := routableProviders().Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load providers: %w", err)
+	}
+	return &routeSnapshot{
+		slot:         slot,
+		models:       lo.KeyBy(modelList, func(m models.Model) string { return m.Name }),
+		associations: lo.GroupBy(associations, func(mp models.ModelWithProvider) uint { return mp.ModelID }),
+		providers:    lo.KeyBy(providerList, func(p models.Provider) uint { return p.ID }),
+	}, nil
+}
+
+// enabledAssociations 当前生效分组中已启用的关联
+func enabledAssociations(slot string) gorm.ChainInterface[models.ModelWithProvider] {
+	return gorm.G[models.ModelWithProvider](models.DB).Where("status = ?", true).Where("slot = ?", slot)
+}
+
+// routableProviders 未下线、未因鉴权失败停用且余额未耗尽的提供商
+// 下线观察中的提供商权重已清零，但权重 0 表示备用，需在此排除才不会承接故障转移流量
+func routableProviders() gorm.ChainInterface[models.Provider] {
+	return gorm.G[models.Provider](models.DB).
+		Where("retire_state NOT IN ?", []string{consts.RetireStateRetiring, consts.RetireStateArchived}).
+		Where("auth_disabled_at IS NULL").
+		// 近期查询到余额耗尽的提供商不参与路由，过期的余额不再作数
+		Where("balance IS NULL OR balance > 0 OR balance_checked_at IS NULL OR balance_checked_at < ?", balanceStaleBefore(time.Now()))
+}
+
+// routeModel 按名称查找模型，不存在时返回 gorm.ErrRecordNotFound
+func routeModel(ctx context.Context, name string) (models.Model, error) {
+	snapshot, err := currentRouteSnapshot(ctx)
+	if err != nil {
+		return models.Model{}, err
+	}
+	if snapshot == nil {
+		return gorm.G[models.Model](models.DB).Where("name = ?", name).First(ctx)
+	}
+	model, ok := snapshot.models[name]
+	if !ok {
+		return models.Model{}, gorm.ErrRecordNotFound
+	}
+	return model, nil
+}
+
+// routeAssociations 返回模型在当前分组中已启用的关联
+func routeAssociations(ctx context.Context, modelID uint) ([]models.ModelWithProvider, error) {
+	snapshot, err := currentRouteSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return enabledAssociations(ActiveSlot()).Where("model_id = ?", modelID).Find(ctx)
+	}
+	return slices.Clone(snapshot.associations[modelID]), nil
+}
+
+// routeProviders 返回给定 ID 中可路由的提供商，按 ID 排序
+func routeProviders(ctx context.Context, ids []uint) ([]models.Provider, error) {
+	snapshot, err := currentRouteSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return routableProviders().Where("id IN ?", ids).Order("id").Find(ctx)
+	}
+	list := make([]models.Provider, 0, len(ids))
+	for _, id := range lo.Uniq(ids) {
+		if provider, ok := snapshot.providers[id]; ok {
+			list = append(list, provider)
+		}
+	}
+	slices.SortFunc(list, func(a, b models.Provider) int { return cmp.Compare(a.ID, b.ID) })
+	return list, nil
+}
+
+// routeCandidates 按请求所需能力筛选关联
+func routeCandidates(list []models.ModelWithProvider, before Before) []models.ModelWithProvider {
+	return lo.Filter(list, func(mp models.ModelWithProvider, _ int) bool {
+		switch {
+		case before.toolCall && !lo.FromPtr(mp.ToolCall),
+			before.structuredOutput && !lo.FromPtr(mp.StructuredOutput),
+			before.image && !lo.FromPtr(mp.Image),
+			// 对话请求只路由到可处理对话的关联，避免同名模型的向量化等端点关联被选中，未设置时视为可对话
+			!before.endpoint() && !lo.FromPtrOr(mp.Chat, true),
+			before.embedding && !lo.FromPtr(mp.Embedding),
+			before.imageGeneration && !lo.FromPtr(mp.ImageGeneration),
+			before.rerank && !lo.FromPtr(mp.Rerank),
+			before.moderation && !lo.FromPtr(mp.Moderation):
+			return false
+		}
+		return true
+	})
+}
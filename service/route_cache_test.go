@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+func TestRouteCache(t *testing.T) {
+	db := setupTestDB(t, &models.Provider{}, &models.Model{}, &models.ModelWithProvider{}, &models.ChatLog{}, &models.Config{})
+	if err := EnableRouteCache(db, time.Minute); err != nil {
+		t.Fatalf("EnableRouteCache failed: %v", err)
+	}
+	t.Cleanup(func() {
+		InvalidateRouteCache()
+		routeCache.ttl = 0
+	})
+	ctx := context.Background()
+
+	provider := models.Provider{Name: "p", Type: consts.StyleOpenAI, Config: `{"base_url":"http://127.0.0.1","api_key":"k"}`}
+	model := models.Model{Name: "m", MaxRetry: 3, TimeOut: 30}
+	for _, v := range []any{&provider, &model} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	association := models.ModelWithProvider{ModelID: model.ID, ProviderID: provider.ID, ProviderModel: "upstream", Status: new(true), Weight: 1, Slot: consts.SlotBlue}
+	if err := gorm.G[models.ModelWithProvider](db).Create(ctx, &association); err != nil {
+		t.Fatalf("create association: %v", err)
+	}
+
+	meta, err := ProvidersWithMetaBymodelsName(ctx, consts.StyleOpenAI, Before{Model: "m"})
+	if err != nil || len(meta.WeightItems) != 1 {
+		t.Fatalf("meta = %+v, err = %v", meta, err)
+	}
+	if _, err := ProvidersWithMetaBymodelsName(ctx, consts.StyleOpenAI, Before{Model: "m", toolCall: true}); !errors.Is(err, ErrNoProvider) {
+		t.Fatalf("tool call without capable association: err = %v", err)
+	}
+
+	// 绕过 GORM 回调的修改在缓存有效期内不可见
+	if err := db.Exec("UPDATE models SET max_retry = 9").Error; err != nil {
+		t.Fatalf("raw update: %v", err)
+	}
+	if meta, err = ProvidersWithMetaBymodelsName(ctx, consts.StyleOpenAI, Before{Model: "m"}); err != nil || meta.MaxRetry != 3 {
+		t.Fatalf("cached max retry = %d, err = %v", meta.MaxRetry, err)
+	}
+
+	// 通过 GORM 写入后立即失效
+	if _, err := gorm.G[models.ModelWithProvider](db).Where("id = ?", association.ID).Update(ctx, "status", false); err != nil {
+		t.Fatalf("disable association: %v", err)
+	}
+	if _, err := ProvidersWithMetaBymodelsName(ctx, consts.StyleOpenAI, Before{Model: "m"}); !errors.Is(err, ErrNoProvider) {
+		t.Fatalf("disabled association still routed: err = %v", err)
+	}
+	if model, err := routeModel(ctx, "m"); err != nil || model.MaxRetry != 9 {
+		t.Fatalf("reloaded model = %+v, err = %v", model, err)
+	}
+}
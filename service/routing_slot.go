@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidSlot    = errors.New("invalid routing slot")
+	ErrSlotEmpty      = errors.New("routing slot has no enabled associations")
+	ErrNoPreviousSlot = errors.New("no previous routing slot to roll back to")
+	ErrSlotActive     = errors.New("cannot overwrite the active routing slot")
+)
+
+var activeSlot atomic.Pointer[models.RoutingSlot]
+
+// ValidSlot 是否为合法的路由分组
+func ValidSlot(slot string) bool {
+	return slot == consts.SlotBlue || slot == consts.SlotGreen
+}
+
+// ActiveSlot 当前生效的路由分组
+func ActiveSlot() string {
+	if slot := activeSlot.Load(); slot != nil && slot.Active != "" {
+		return slot.Active
+	}
+	return consts.SlotBlue
+}
+
+// SlotSummary 分组概览
+type SlotSummary struct {
+	Slot         string `json:"slot"`
+	Associations int64  `json:"associations"`
+	Enabled      int64  `json:"enabled"`
+}
+
+// RoutingSlotStatus 蓝绿路由状态
+type RoutingSlotStatus struct {
+	models.RoutingSlot
+	Slots []SlotSummary `json:"slots"`
+}
+
+// LoadRoutingSlot 从系统配置加载当前生效分组
+func LoadRoutingSlot(ctx context.Context) error {
+	slot, err := getRoutingSlot(ctx)
+	if err != nil {
+		return err
+	}
+	activeSlot.Store(slot)
+	return nil
+}
+
+func getRoutingSlot(ctx context.Context) (*models.RoutingSlot, error) {
+	config, err := gorm.G[models.Config](models.DB).Where("key = ?", models.KeyRoutingSlot).First(ctx)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.RoutingSlot{Active: consts.SlotBlue}, nil
+		}
+		return nil, err
+	}
+	var slot models.RoutingSlot
+	if err := json.Unmarshal([]byte(config.Value), &slot); err != nil {
+		return nil, err
+	}
+	if !ValidSlot(slot.Active) {
+		slot.Active = consts.SlotBlue
+	}
+	return &slot, nil
+}
+
+func saveRoutingSlot(ctx context.Context, slot models.RoutingSlot) error {
+	value, err := json.Marshal(slot)
+	if err != nil {
+		return err
+	}
+	config := models.Config{Key: models.KeyRoutingSlot, Value: string(value)}
+	rows, err := gorm.G[models.Config](models.DB).Where("key = ?", models.KeyRoutingSlot).Updates(ctx, config)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return gorm.G[models.Config](models.DB).Create(ctx, &config)
+	}
+	return nil
+}
+
+// GetRoutingSlotStatus 获取蓝绿路由状态与各分组关联数
+func GetRoutingSlotStatus(ctx context.Context) (*RoutingSlotStatus, error) {
+	slot, err := getRoutingSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &RoutingSlotStatus{RoutingSlot: *slot}
+	for _, name := range []string{consts.SlotBlue, consts.SlotGreen} {
+		total, err := gorm.G[models.ModelWithProvider](models.DB).Where("slot = ?", name).Count(ctx, "id")
+		if err != nil {
+			return nil, err
+		}
+		enabled, err := gorm.G[models.ModelWithProvider](models.DB).Where("slot = ?", name).Where("status = ?", true).Count(ctx, "id")
+		if err != nil {
+			return nil, err
+		}
+		status.Slots = append(status.Slots, SlotSummary{Slot: name, Associations: total, Enabled: enabled})
+	}
+	return status, nil
+}
+
+// StageRoutingSlot 将 from 分组的关联完整复制到 to 分组（覆盖 to 分组原有关联）
+func StageRoutingSlot(ctx context.Context, from, to string) (int, error) {
+	if !ValidSlot(from) || !ValidSlot(to) || from == to {
+		return 0, ErrInvalidSlot
+	}
+	if to == ActiveSlot() {
+		return 0, ErrSlotActive
+	}
+	var count int
+	err := models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("slot = ?", to).Delete(&models.ModelWithProvider{}).Error; err != nil {
+			return err
+		}
+		var associations []models.ModelWithProvider
+		if err := tx.Where("slot = ?", from).Find(&associations).Error; err != nil {
+			return err
+		}
+		for i := range associations {
+			associations[i].Model = gorm.Model{}
+			associations[i].Slot = to
+		}
+		count = len(associations)
+		if count == 0 {
+			return nil
+		}
+		return tx.Create(&associations).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// SwitchRoutingSlot 原子切换生效分组，切换前要求目标分组至少有一个启用关联
+func SwitchRoutingSlot(ctx context.Context, to string) (*models.RoutingSlot, error) {
+	if !ValidSlot(to) {
+		return nil, ErrInvalidSlot
+	}
+	current, err := getRoutingSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current.Active == to {
+		return current, nil
+	}
+	enabled, err := gorm.G[models.ModelWithProvider](models.DB).Where("slot = ?", to).Where("status = ?", true).Count(ctx, "id")
+	if err != nil {
+		return nil, err
+	}
+	if enabled == 0 {
+		return nil, ErrSlotEmpty
+	}
+	now := time.Now()
+	next := models.RoutingSlot{Active: to, Previous: current.Active, SwitchedAt: &now}
+	if err := saveRoutingSlot(ctx, next); err != nil {
+		return nil, err
+	}
+	activeSlot.Store(&next)
+	return &next, nil
+}
+
+// RollbackRoutingSlot 切回上一个生效分组
+func RollbackRoutingSlot(ctx context.Context) (*models.RoutingSlot, error) {
+	current, err := getRoutingSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ValidSlot(current.Previous) || current.Previous == current.Active {
+		return nil, ErrNoPreviousSlot
+	}
+	return SwitchRoutingSlot(ctx, current.Previous)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestRoutingSlotSwitchAndRollback(t *testing.T) {
+	db := setupTestDB(t, &models.ModelWithProvider{}, &models.Config{})
+	t.Cleanup(func() { activeSlot.Store(nil) })
+	ctx := context.Background()
+
+	if err := LoadRoutingSlot(ctx); err != nil {
+		t.Fatalf("LoadRoutingSlot failed: %v", err)
+	}
+	if ActiveSlot() != consts.SlotBlue {
+		t.Fatalf("expected default slot blue, got %s", ActiveSlot())
+	}
+
+	blue := models.ModelWithProvider{ModelID: 1, ProviderID: 1, Weight: 5, Status: new(true), Slot: consts.SlotBlue}
+	if err := db.Create(&blue).Error; err != nil {
+		t.Fatalf("create association: %v", err)
+	}
+
+	if _, err := SwitchRoutingSlot(ctx, consts.SlotGreen); err != ErrSlotEmpty {
+		t.Fatalf("expected ErrSlotEmpty, got %v", err)
+	}
+	if _, err := StageRoutingSlot(ctx, consts.SlotGreen, consts.SlotBlue); err != ErrSlotActive {
+		t.Fatalf("expected ErrSlotActive, got %v", err)
+	}
+
+	copied, err := StageRoutingSlot(ctx, consts.SlotBlue, consts.SlotGreen)
+	if err != nil || copied != 1 {
+		t.Fatalf("StageRoutingSlot = %d, %v", copied, err)
+	}
+	if err := db.Model(&models.ModelWithProvider{}).Where("slot = ?", consts.SlotGreen).Update("weight", 9).Error; err != nil {
+		t.Fatalf("update green weight: %v", err)
+	}
+
+	slot, err := SwitchRoutingSlot(ctx, consts.SlotGreen)
+	if err != nil {
+		t.Fatalf("SwitchRoutingSlot failed: %v", err)
+	}
+	if slot.Active != consts.SlotGreen || slot.Previous != consts.SlotBlue || ActiveSlot() != consts.SlotGreen {
+		t.Fatalf("unexpected slot after switch: %+v", slot)
+	}
+
+	if _, err := RollbackRoutingSlot(ctx); err != nil {
+		t.Fatalf("RollbackRoutingSlot failed: %v", err)
+	}
+	if ActiveSlot() != consts.SlotBlue {
+		t.Fatalf("expected blue after rollback, got %s", ActiveSlot())
+	}
+
+	var reloaded models.ModelWithProvider
+	db.First(&reloaded, blue.ID)
+	if reloaded.Weight != 5 {
+		t.Fatalf("blue slot should be untouched, got weight %d", reloaded.Weight)
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// scheduleWindow 一个可用时间窗口，结束早于开始时表示跨越午夜
+type scheduleWindow struct {
+	days       [7]bool
+	start, end int // 距零点的分钟数
+}
+
+func (w scheduleWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return w.days[t.Weekday()] && minute >= w.start && minute < w.end
+	}
+	// 跨午夜的窗口，凌晨部分属于前一天
+	return (w.days[t.Weekday()] && minute >= w.start) || (w.days[t.AddDate(0, 0, -1).Weekday()] && minute < w.end)
+}
+
+// parseSchedule 解析关联的可用时间窗口，如 "mon-fri 09:00-18:00; sat,sun 22:00-06:00"，省略星期表示每天，按服务器时区计算
+func parseSchedule(spec string) ([]scheduleWindow, error) {
+	var windows []scheduleWindow
+	for item := range strings.SplitSeq(spec, ";") {
+		fields := strings.Fields(strings.ToLower(item))
+		if len(fields) == 0 {
+			continue
+		}
+		var window scheduleWindow
+		switch len(fields) {
+		case 1:
+			window.days = [7]bool{true, true, true, true, true, true, true}
+		case 2:
+			days, err := parseWeekdays(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			window.days = days
+		default:
+			return nil, fmt.Errorf("invalid schedule window %q", strings.TrimSpace(item))
+		}
+		startText, endText, ok := strings.Cut(fields[len(fields)-1], "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid time range %q", fields[len(fields)-1])
+		}
+		var err error
+		if window.start, err = parseClock(startText); err != nil {
+			return nil, err
+		}
+		if window.end, err = parseClock(endText); err != nil {
+			return nil, err
+		}
+		if window.start == window.end || window.start == 24*60 {
+			return nil, fmt.Errorf("invalid time range %q", fields[len(fields)-1])
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// ValidateSchedule 校验可用时间窗口格式
+func ValidateSchedule(spec string) error {
+	_, err := parseSchedule(spec)
+	return err
+}
+
+// parseWeekdays 解析 mon-fri、sat,sun 这类星期列表，范围可跨周末，如 fri-mon
+func parseWeekdays(text string) ([7]bool, error) {
+	var days [7]bool
+	for part := range strings.SplitSeq(text, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		start, ok := weekdays[from]
+		if !ok {
+			return days, fmt.Errorf("invalid weekday %q", from)
+		}
+		end := start
+		if isRange {
+			if end, ok = weekdays[to]; !ok {
+				return days, fmt.Errorf("invalid weekday %q", to)
+			}
+		}
+		for d := start; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == end {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseClock 解析 HH:MM，允许 24:00 作为结束时间
+func parseClock(text string) (int, error) {
+	hourText, minuteText, ok := strings.Cut(text, ":")
+	hour, err1 := strconv.Atoi(hourText)
+	minute, err2 := strconv.Atoi(minuteText)
+	if !ok || err1 != nil || err2 != nil || hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	return hour*60 + minute, nil
+}
+
+// inSchedule 当前时间是否处于关联的可用时间窗口内，未配置时始终可用
+func inSchedule(spec string, now time.Time) bool {
+	windows, err := parseSchedule(spec)
+	if err != nil {
+		// 保存时已校验，解析失败时不排除该关联
+		slog.Warn("invalid provider schedule", "schedule", spec, "error", err)
+		return true
+	}
+	if len(windows) == 0 {
+		return true
+	}
+	for _, window := range windows {
+		if window.contains(now) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInSchedule(t *testing.T) {
+	// 2026-01-05 是星期一
+	monday := func(hour, minute int) time.Time { return time.Date(2026, 1, 5, hour, minute, 0, 0, time.Local) }
+	tests := []struct {
+		name     string
+		schedule string
+		at       time.Time
+		want     bool
+	}{
+		{"empty", "", monday(3, 0), true},
+		{"every day inside", "09:00-18:00", monday(9, 0), true},
+		{"every day end exclusive", "09:00-18:00", monday(18, 0), false},
+		{"weekday range", "mon-fri 09:00-18:00", monday(12, 0), true},
+		{"weekend only", "sat,sun 00:00-24:00", monday(12, 0), false},
+		{"overnight same day", "mon 22:00-06:00", monday(23, 0), true},
+		{"overnight early morning belongs to previous day", "sun 22:00-06:00", monday(5, 59), true},
+		{"overnight not previous day", "mon 22:00-06:00", monday(5, 0), false},
+		{"wrapping weekday range", "fri-mon 08:00-09:00", monday(8, 30), true},
+		{"multiple windows", "tue 01:00-02:00; mon 10:00-11:00", monday(10, 15), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inSchedule(tt.schedule, tt.at); got != tt.want {
+				t.Fatalf("inSchedule(%q, %v) = %v, want %v", tt.schedule, tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	for _, spec := range []string{"", "09:00-18:00", "mon-fri 09:00-18:00; sat 10:00-24:00", "Sun 22:30-06:00"} {
+		if err := ValidateSchedule(spec); err != nil {
+			t.Fatalf("ValidateSchedule(%q) = %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"9-18", "monday 09:00-18:00", "mon 09:00", "mon 25:00-26:00", "09:00-09:00", "mon fri 09:00-10:00", "24:00-01:00"} {
+		if err := ValidateSchedule(spec); err == nil {
+			t.Fatalf("ValidateSchedule(%q) should fail", spec)
+		}
+	}
+}
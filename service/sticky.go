@@ -0,0 +1,80 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// stickyFingerprintPaths 各协议中可标识同一会话的字段，按顺序取第一个非空值
+var stickyFingerprintPaths = []string{
+	"user",
+	"metadata.user_id",
+	"system",
+	"instructions",
+	"systemInstruction",
+	"system_instruction",
+	`messages.#(role=="system").content`,
+	`messages.#(role=="developer").content`,
+}
+
+type stickyEntry struct {
+	modelProviderID uint
+	expiry          time.Time
+}
+
+var (
+	stickyMu        sync.Mutex
+	stickyRoutes    = make(map[string]stickyEntry)
+	stickyLastSweep time.Time
+)
+
+// stickyKey 由 Key、模型与会话指纹计算粘滞路由键，无法识别会话时返回空
+func stickyKey(authKeyID uint, before Before) string {
+	fingerprint := before.SessionID
+	if fingerprint == "" && len(before.raw) > 0 {
+		for _, path := range stickyFingerprintPaths {
+			if v := gjson.GetBytes(before.raw, path); v.Exists() && v.Raw != `""` && v.Raw != "null" {
+				fingerprint = v.Raw
+				break
+			}
+		}
+	}
+	if fingerprint == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(strconv.FormatUint(uint64(authKeyID), 10) + "\x00" + before.Model + "\x00" + fingerprint))
+	return hex.EncodeToString(sum[:])
+}
+
+// stickyRoute 返回会话上次成功使用的关联
+func stickyRoute(key string, now time.Time) (uint, bool) {
+	stickyMu.Lock()
+	defer stickyMu.Unlock()
+	entry, ok := stickyRoutes[key]
+	if !ok || now.After(entry.expiry) {
+		return 0, false
+	}
+	return entry.modelProviderID, true
+}
+
+// rememberSticky 记录会话成功使用的关联，每次成功都会顺延有效期
+func rememberSticky(key string, modelProviderID uint, ttl time.Duration, now time.Time) {
+	stickyMu.Lock()
+	defer stickyMu.Unlock()
+	stickyRoutes[key] = stickyEntry{modelProviderID: modelProviderID, expiry: now.Add(ttl)}
+	// 定期清理过期会话，避免长期运行后占用内存
+	if now.Sub(stickyLastSweep) < time.Minute {
+		return
+	}
+	stickyLastSweep = now
+	for k, entry := range stickyRoutes {
+		if now.After(entry.expiry) {
+			delete(stickyRoutes, k)
+		}
+	}
+}
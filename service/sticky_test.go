@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStickyKey(t *testing.T) {
+	openai := Before{Model: "gpt", raw: []byte(`{"messages":[{"role":"system","content":"you are a bot"},{"role":"user","content":"hi"}]}`)}
+	openaiNextTurn := Before{Model: "gpt", raw: []byte(`{"messages":[{"role":"system","content":"you are a bot"},{"role":"user","content":"hi"},{"role":"assistant","content":"hello"},{"role":"user","content":"bye"}]}`)}
+	tests := []struct {
+		name  string
+		a, b  Before
+		keyA  uint
+		keyB  uint
+		equal bool
+	}{
+		{"same system prompt", openai, openaiNextTurn, 1, 1, true},
+		{"different auth key", openai, openai, 1, 2, false},
+		{"different model", openai, Before{Model: "other", raw: openai.raw}, 1, 1, false},
+		{"user field", Before{Model: "gpt", raw: []byte(`{"user":"u1","messages":[]}`)}, Before{Model: "gpt", raw: []byte(`{"user":"u2","messages":[]}`)}, 1, 1, false},
+		{"anthropic system", Before{Model: "claude", raw: []byte(`{"system":"s","messages":[{"role":"user","content":"a"}]}`)}, Before{Model: "claude", raw: []byte(`{"system":"s","messages":[{"role":"user","content":"b"}]}`)}, 1, 1, true},
+		{"session id", Before{Model: "gpt", SessionID: "s1"}, Before{Model: "gpt", SessionID: "s1", raw: []byte(`{"user":"u"}`)}, 1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := stickyKey(tt.keyA, tt.a), stickyKey(tt.keyB, tt.b)
+			if a == "" || b == "" {
+				t.Fatalf("empty sticky key: %q %q", a, b)
+			}
+			if (a == b) != tt.equal {
+				t.Fatalf("keys equal = %v, want %v", a == b, tt.equal)
+			}
+		})
+	}
+	if key := stickyKey(1, Before{Model: "gpt", raw: []byte(`{"messages":[{"role":"user","content":"hi"}]}`)}); key != "" {
+		t.Fatalf("expected no sticky key without fingerprint, got %q", key)
+	}
+}
+
+func TestStickyRoute(t *testing.T) {
+	now := time.Now()
+	rememberSticky("k", 7, time.Minute, now)
+	if id, ok := stickyRoute("k", now.Add(30*time.Second)); !ok || id != 7 {
+		t.Fatalf("stickyRoute = %d, %v", id, ok)
+	}
+	if _, ok := stickyRoute("k", now.Add(2*time.Minute)); ok {
+		t.Fatalf("expected sticky route to expire")
+	}
+	rememberSticky("other", 8, time.Minute, now.Add(3*time.Minute))
+	stickyMu.Lock()
+	_, ok := stickyRoutes["k"]
+	stickyMu.Unlock()
+	if ok {
+		t.Fatalf("expected expired sticky route to be swept")
+	}
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+var ErrStreamIncomplete = errors.New("upstream stream ended before completion")
+
+// enableUpstreamStream 将上游请求体改为流式，OpenAI 协议同时要求返回用量，Gemini 通过请求路径区分由调用方处理
+func enableUpstreamStream(raw []byte, providerType string) ([]byte, error) {
+	switch providerType {
+	case consts.StyleGemini:
+		return raw, nil
+	case consts.StyleOpenAI, consts.StyleGeminiOpenAI:
+		var err error
+		if raw, err = sjson.SetBytes(raw, "stream_options.include_usage", true); err != nil {
+			return nil, err
+		}
+	}
+	return sjson.SetBytes(raw, "stream", true)
+}
+
+// aggregateStream 读取上游协议的完整 SSE 流并合并为该协议的非流式响应体
+func aggregateStream(r io.Reader, providerType string) ([]byte, error) {
+	if providerType == consts.StyleOllama {
+		return aggregateOllama(r)
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, InitScannerBufferSize), MaxScannerBufferSize)
+	var events []gjson.Result
+	for chunk := range ScannerToken(scanner) {
+		data, ok := strings.CutPrefix(chunk, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		if !gjson.Valid(data) {
+			return nil, fmt.Errorf("invalid stream chunk: %s", data)
+		}
+		event := gjson.Parse(data)
+		if event.Get("error").Exists() {
+			return nil, fmt.Errorf("stream error: %s", data)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, ErrStreamIncomplete
+	}
+
+	var body any
+	var err error
+	switch providerType {
+	case consts.StyleAnthropic:
+		body, err = aggregateAnthropic(events)
+	case consts.StyleOpenAIRes:
+		body, err = aggregateResponses(events)
+	case consts.StyleGemini:
+		body, err = aggregateGemini(events)
+	default:
+		body, err = aggregateOpenAI(events)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(body)
+}
+
+func aggregateOpenAI(events []gjson.Result) (any, error) {
+	type toolCall struct {
+		ID        string
+		Type      string
+		Name      strings.Builder
+		Arguments strings.Builder
+	}
+	type choice struct {
+		role         string
+		fields       map[string]*strings.Builder
+		order        []string
+		toolCalls    []*toolCall
+		finishReason any
+	}
+
+	first := events[0]
+	var choices []*choice
+	var usage any
+	for _, event := range events {
+		if u := event.Get("usage"); u.Exists() && u.Type != gjson.Null {
+			usage = u.Value()
+		}
+		for _, c := range event.Get("choices").Array() {
+			index := int(c.Get("index").Int())
+			for len(choices) <= index {
+				choices = append(choices, &choice{role: "assistant", fields: map[string]*strings.Builder{}})
+			}
+			ch := choices[index]
+			delta := c.Get("delta")
+			delta.ForEach(func(key, value gjson.Result) bool {
+				switch k := key.String(); {
+				case k == "role":
+					ch.role = value.String()
+				case k == "tool_calls":
+					for _, call := range value.Array() {
+						i := int(call.Get("index").Int())
+						for len(ch.toolCalls) <= i {
+							ch.toolCalls = append(ch.toolCalls, &toolCall{Type: "function"})
+						}
+						tc := ch.toolCalls[i]
+						if id := call.Get("id").String(); id != "" {
+							tc.ID = id
+						}
+						if typ := call.Get("type").String(); typ != "" {
+							tc.Type = typ
+						}
+						tc.Name.WriteString(call.Get("function.name").String())
+						tc.Arguments.WriteString(call.Get("function.arguments").String())
+					}
+				case value.Type == gjson.String:
+					// content/reasoning_content/refusal 以及厂商自定义文本字段按顺序拼接
+					b, ok := ch.fields[k]
+					if !ok {
+						b = &strings.Builder{}
+						ch.fields[k] = b
+						ch.order = append(ch.order, k)
+					}
+					b.WriteString(value.String())
+				}
+				return true
+			})
+			if reason := c.Get("finish_reason"); reason.Exists() && reason.Type != gjson.Null {
+				ch.finishReason = reason.Value()
+			}
+		}
+	}
+	if len(choices) == 0 {
+		return nil, ErrStreamIncomplete
+	}
+
+	outChoices := make([]map[string]any, 0, len(choices))
+	for i, ch := range choices {
+		message := map[string]any{"role": ch.role, "content": nil}
+		for _, key := range ch.order {
+			message[key] = ch.fields[key].String()
+		}
+		if len(ch.toolCalls) > 0 {
+			calls := make([]map[string]any, 0, len(ch.toolCalls))
+			for _, tc := range ch.toolCalls {
+				calls = append(calls, map[string]any{
+					"id":   tc.ID,
+					"type": tc.Type,
+					"function": map[string]any{
+						"name":      tc.Name.String(),
+						"arguments": tc.Arguments.String(),
+					},
+				})
+			}
+			message["tool_calls"] = calls
+		}
+		outChoices = append(outChoices, map[string]any{"index": i, "message": message, "finish_reason": ch.finishReason})
+	}
+	body := map[string]any{
+		"id":      first.Get("id").String(),
+		"object":  "chat.completion",
+		"created": first.Get("created").Int(),
+		"model":   first.Get("model").String(),
+		"choices": outChoices,
+	}
+	if fp := first.Get("system_fingerprint"); fp.Exists() {
+		body["system_fingerprint"] = fp.Value()
+	}
+	if usage != nil {
+		body["usage"] = usage
+	}
+	return body, nil
+}
+
+func aggregateAnthropic(events []gjson.Result) (any, error) {
+	var message map[string]any
+	var blocks []map[string]any
+	partialJSON := map[int]*strings.Builder{}
+	var stopped bool
+	for _, event := range events {
+		switch event.Get("type").String() {
+		case "message_start":
+			message, _ = event.Get("message").Value().(map[string]any)
+		case "content_block_start":
+			index := int(event.Get("index").Int())
+			for len(blocks) <= index {
+				blocks = append(blocks, nil)
+			}
+			blocks[index], _ = event.Get("content_block").Value().(map[string]any)
+		case "content_block_delta":
+			index := int(event.Get("index").Int())
+			if index >= len(blocks) || blocks[index] == nil {
+				return nil, fmt.Errorf("delta for unknown content block %d", index)
+			}
+			block := blocks[index]
+			delta := event.Get("delta")
+			switch delta.Get("type").String() {
+			case "text_delta":
+				text, _ := block["text"].(string)
+				block["text"] = text + delta.Get("text").String()
+			case "thinking_delta":
+				thinking, _ := block["thinking"].(string)
+				block["thinking"] = thinking + delta.Get("thinking").String()
+			case "signature_delta":
+				block["signature"] = delta.Get("signature").String()
+			case "input_json_delta":
+				b, ok := partialJSON[index]
+				if !ok {
+					b = &strings.Builder{}
+					partialJSON[index] = b
+				}
+				b.WriteString(delta.Get("partial_json").String())
+			case "citations_delta":
+				citations, _ := block["citations"].([]any)
+				block["citations"] = append(citations, delta.Get("citation").Value())
+			}
+		case "message_delta":
+			if message == nil {
+				return nil, ErrStreamIncomplete
+			}
+			delta := event.Get("delta")
+			message["stop_reason"] = delta.Get("stop_reason").Value()
+			message["stop_sequence"] = delta.Get("stop_sequence").Value()
+			usage, _ := message["usage"].(map[string]any)
+			if usage == nil {
+				usage = map[string]any{}
+			}
+			event.Get("usage").ForEach(func(key, value gjson.Result) bool {
+				usage[key.String()] = value.Value()
+				return true
+			})
+			message["usage"] = usage
+		case "message_stop":
+			stopped = true
+		}
+	}
+	if message == nil || !stopped {
+		return nil, ErrStreamIncomplete
+	}
+	for index, b := range partialJSON {
+		input := map[string]any{}
+		if raw := b.String(); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &input); err != nil {
+				return nil, fmt.Errorf("invalid tool input: %w", err)
+			}
+		}
+		blocks[index]["input"] = input
+	}
+	content := make([]map[string]any, 0, len(blocks))
+	for _, block := range blocks {
+		if block != nil {
+			content = append(content, block)
+		}
+	}
+	message["content"] = content
+	return message, nil
+}
+
+func aggregateResponses(events []gjson.Result) (any, error) {
+	for i := len(events) - 1; i >= 0; i-- {
+		switch events[i].Get("type").String() {
+		case "response.completed", "response.incomplete":
+			return events[i].Get("response").Value(), nil
+		case "response.failed":
+			return nil, fmt.Errorf("response failed: %s", events[i].Get("response.error").Raw)
+		}
+	}
+	return nil, ErrStreamIncomplete
+}
+
+func aggregateGemini(events []gjson.Result) (any, error) {
+	var parts []map[string]any
+	candidate := map[string]any{"index": 0}
+	body := map[string]any{}
+	for _, event := range events {
+		c := event.Get("candidates.0")
+		for _, part := range c.Get("content.parts").Array() {
+			text := part.Get("text")
+			isText := text.Exists() && !part.Get("functionCall").Exists()
+			// 相邻且同为思考或非思考的文本片段合并
+			if isText && len(parts) > 0 {
+				last := parts[len(parts)-1]
+				if lastText, ok := last["text"].(string); ok && last["thought"] == part.Get("thought").Value() {
+					last["text"] = lastText + text.String()
+					continue
+				}
+			}
+			value, _ := part.Value().(map[string]any)
+			if value != nil {
+				parts = append(parts, value)
+			}
+		}
+		for _, key := range []string{"finishReason", "safetyRatings", "groundingMetadata", "citationMetadata"} {
+			if v := c.Get(key); v.Exists() {
+				candidate[key] = v.Value()
+			}
+		}
+		for _, key := range []string{"usageMetadata", "modelVersion", "responseId", "promptFeedback"} {
+			if v := event.Get(key); v.Exists() {
+				body[key] = v.Value()
+			}
+		}
+	}
+	if _, ok := candidate["finishReason"]; !ok {
+		return nil, ErrStreamIncomplete
+	}
+	candidate["content"] = map[string]any{"role": "model", "parts": parts}
+	body["candidates"] = []any{candidate}
+	return body, nil
+}
+
+// aggregateOllama 合并 Ollama 按行输出的 JSON 对象，结束行携带 done_reason 与用量
+func aggregateOllama(r io.Reader) ([]byte, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, InitScannerBufferSize), MaxScannerBufferSize)
+	var content, thinking strings.Builder
+	var toolCalls []any
+	var body map[string]any
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !gjson.Valid(line) {
+			return nil, fmt.Errorf("invalid stream chunk: %s", line)
+		}
+		event := gjson.Parse(line)
+		if event.Get("error").Exists() {
+			return nil, fmt.Errorf("stream error: %s", line)
+		}
+		content.WriteString(event.Get("message.content").String())
+		thinking.WriteString(event.Get("message.thinking").String())
+		for _, call := range event.Get("message.tool_calls").Array() {
+			toolCalls = append(toolCalls, call.Value())
+		}
+		if event.Get("done").Bool() {
+			body, _ = event.Value().(map[string]any)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, ErrStreamIncomplete
+	}
+	message := map[string]any{"role": "assistant", "content": content.String()}
+	if thinking.Len() > 0 {
+		message["thinking"] = thinking.String()
+	}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+	}
+	body["message"] = message
+	return json.Marshal(body)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/tidwall/gjson"
+)
+
+func TestAggregateStream(t *testing.T) {
+	long := strings.Repeat("你好world", 10)
+	tests := []struct {
+		name  string
+		style string
+		body  string
+		want  map[string]string
+	}{
+		{
+			"openai", consts.StyleOpenAI,
+			`{"id":"c1","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"` + long + `","tool_calls":[{"id":"t1","type":"function","function":{"name":"f","arguments":"{\"a\":1}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":3,"completion_tokens":5,"total_tokens":8}}`,
+			map[string]string{
+				"choices.0.message.content":                         long,
+				"choices.0.message.tool_calls.0.function.arguments": `{"a":1}`,
+				"choices.0.finish_reason":                           "tool_calls",
+				"usage.total_tokens":                                "8",
+			},
+		},
+		{
+			"anthropic", consts.StyleAnthropic,
+			`{"id":"m1","type":"message","role":"assistant","model":"m","content":[{"type":"text","text":"` + long + `"},{"type":"tool_use","id":"t1","name":"f","input":{"a":1}}],"stop_reason":"tool_use","usage":{"input_tokens":3,"output_tokens":5}}`,
+			map[string]string{
+				"content.0.text":      long,
+				"content.1.input.a":   "1",
+				"stop_reason":         "tool_use",
+				"usage.output_tokens": "5",
+			},
+		},
+		{
+			"responses", consts.StyleOpenAIRes,
+			`{"id":"r1","object":"response","status":"completed","output":[{"id":"i1","type":"message","role":"assistant","content":[{"type":"output_text","text":"` + long + `","annotations":[]}]}],"usage":{"input_tokens":3,"output_tokens":5,"total_tokens":8}}`,
+			map[string]string{
+				"output.0.content.0.text": long,
+				"usage.total_tokens":      "8",
+			},
+		},
+		{
+			"gemini", consts.StyleGemini,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"` + long + `"},{"functionCall":{"name":"f","args":{}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":5,"totalTokenCount":8}}`,
+			map[string]string{
+				"candidates.0.content.parts.0.text":              long,
+				"candidates.0.content.parts.1.functionCall.name": "f",
+				"candidates.0.finishReason":                      "STOP",
+				"usageMetadata.totalTokenCount":                  "8",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 伪流式切分后的 SSE 再聚合应还原出原响应
+			stream := pseudoStream(io.NopCloser(strings.NewReader(tt.body)), tt.style)
+			body, err := aggregateStream(stream, tt.style)
+			if err != nil {
+				t.Fatalf("aggregateStream: %v", err)
+			}
+			if err := validateCompletion(tt.style, tt.style, body); err != nil {
+				t.Fatalf("aggregated body invalid: %v, body: %s", err, body)
+			}
+			for path, want := range tt.want {
+				if got := gjson.GetBytes(body, path).String(); got != want {
+					t.Errorf("%s = %q, want %q", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateStreamIncomplete(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		body  string
+	}{
+		{"anthropic without message_stop", consts.StyleAnthropic, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"content\":[]}}\n\n"},
+		{"gemini without finish reason", consts.StyleGemini, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"hi\"}]}}]}\n\n"},
+		{"empty", consts.StyleOpenAI, "data: [DONE]\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := aggregateStream(strings.NewReader(tt.body), tt.style); !errors.Is(err, ErrStreamIncomplete) {
+				t.Fatalf("err = %v, want ErrStreamIncomplete", err)
+			}
+		})
+	}
+	if _, err := aggregateStream(strings.NewReader("data: {\"error\":{\"message\":\"boom\"}}\n\n"), consts.StyleOpenAI); err == nil {
+		t.Fatal("expected error event to fail aggregation")
+	}
+}
+
+func TestEnableUpstreamStream(t *testing.T) {
+	body, err := enableUpstreamStream([]byte(`{"model":"m"}`), consts.StyleOpenAI)
+	if err != nil {
+		t.Fatalf("enableUpstreamStream: %v", err)
+	}
+	if !gjson.GetBytes(body, "stream").Bool() || !gjson.GetBytes(body, "stream_options.include_usage").Bool() {
+		t.Fatalf("body = %s", body)
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"bytes"
+	"io"
+)
+
+// bufferedBody 先返回已缓冲的流开头，再继续读取上游
+type bufferedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// bufferStreamHead 读取流式响应的前 size 字节后再交给客户端，期间上游出错时关闭响应并返回错误
+func bufferStreamHead(body io.ReadCloser, size int) (io.ReadCloser, error) {
+	head := make([]byte, size)
+	n, err := io.ReadFull(body, head)
+	switch err {
+	case nil, io.EOF, io.ErrUnexpectedEOF:
+		// 流在缓冲区填满前正常结束
+		return &bufferedBody{Reader: io.MultiReader(bytes.NewReader(head[:n]), body), Closer: body}, nil
+	default:
+		body.Close()
+		return nil, err
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// brokenBody 返回部分内容后报错
+type brokenBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *brokenBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestBufferStreamHead(t *testing.T) {
+	errBroken := errors.New("connection reset")
+	tests := []struct {
+		name    string
+		body    io.Reader
+		size    int
+		want    string
+		wantErr bool
+	}{
+		{"longer than buffer", strings.NewReader("data: 1\n\ndata: 2\n\n"), 4, "data: 1\n\ndata: 2\n\n", false},
+		{"ends before buffer full", strings.NewReader("data: [DONE]\n\n"), 64, "data: [DONE]\n\n", false},
+		{"broken before buffer full", io.MultiReader(strings.NewReader("data: 1\n\n"), iotest.ErrReader(errBroken)), 64, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &brokenBody{Reader: tt.body}
+			r, err := bufferStreamHead(body, tt.size)
+			if tt.wantErr {
+				if !errors.Is(err, errBroken) || !body.closed {
+					t.Fatalf("err = %v, closed = %v", err, body.closed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bufferStreamHead failed: %v", err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil || string(got) != tt.want {
+				t.Fatalf("ReadAll = %q, %v", got, err)
+			}
+			r.Close()
+			if !body.closed {
+				t.Fatal("close should reach upstream body")
+			}
+		})
+	}
+}
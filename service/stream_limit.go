@@ -0,0 +1,45 @@
+package service
+
+import "sync/atomic"
+
+// StreamRetryAfter 流式连接数超限时建议客户端等待的秒数
+const StreamRetryAfter = 5
+
+// streamLimit 全局流式连接数上限，避免突发流量耗尽文件描述符与内存
+var streamLimit struct {
+	max      atomic.Int64 // 0 表示不限制
+	active   atomic.Int64
+	rejected atomic.Int64
+}
+
+// StreamStats 当前流式连接数与启动以来因超限被拒绝的请求数
+type StreamStats struct {
+	Active   int64 `json:"active"`
+	Max      int64 `json:"max"`
+	Rejected int64 `json:"rejected"`
+}
+
+// SetMaxStreams 设置全局最大并发流式连接数，0 表示不限制
+func SetMaxStreams(n int) {
+	streamLimit.max.Store(int64(max(n, 0)))
+}
+
+// AcquireStream 占用一个流式连接名额，超出上限时返回 false，成功时需调用返回的函数释放
+func AcquireStream() (func(), bool) {
+	active := streamLimit.active.Add(1)
+	if limit := streamLimit.max.Load(); limit > 0 && active > limit {
+		streamLimit.active.Add(-1)
+		streamLimit.rejected.Add(1)
+		return nil, false
+	}
+	return func() { streamLimit.active.Add(-1) }, true
+}
+
+// GetStreamStats 返回流式连接统计
+func GetStreamStats() StreamStats {
+	return StreamStats{
+		Active:   streamLimit.active.Load(),
+		Max:      streamLimit.max.Load(),
+		Rejected: streamLimit.rejected.Load(),
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import "testing"
+
+func TestAcquireStream(t *testing.T) {
+	SetMaxStreams(2)
+	t.Cleanup(func() { SetMaxStreams(0) })
+	before := GetStreamStats()
+
+	release1, ok1 := AcquireStream()
+	release2, ok2 := AcquireStream()
+	if !ok1 || !ok2 {
+		t.Fatal("streams under the limit should be accepted")
+	}
+	if _, ok := AcquireStream(); ok {
+		t.Fatal("stream over the limit should be rejected")
+	}
+	if got := GetStreamStats(); got.Active != before.Active+2 || got.Rejected != before.Rejected+1 {
+		t.Fatalf("stats = %+v, want active +2 and rejected +1 from %+v", got, before)
+	}
+
+	release1()
+	release3, ok := AcquireStream()
+	if !ok {
+		t.Fatal("released slot should be reusable")
+	}
+	release2()
+	release3()
+
+	SetMaxStreams(0)
+	release, ok := AcquireStream()
+	if !ok {
+		t.Fatal("unlimited streams should always be accepted")
+	}
+	release()
+	if got := GetStreamStats(); got.Active != before.Active {
+		t.Fatalf("active = %d, want %d", got.Active, before.Active)
+	}
+}
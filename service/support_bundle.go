@@ -0,0 +1,206 @@
+package service
+
+import (
+	"archive/zip"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/atopos31/llmio/balancers"
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+// supportBundleErrorLogs 诊断包中保留的最近错误日志条数
+const supportBundleErrorLogs = 200
+
+// sensitiveConfigKeys 字段名包含这些词时视为密钥，诊断包中替换为 [redacted]
+var sensitiveConfigKeys = []string{"key", "token", "secret", "password", "credential", "auth"}
+
+// SupportBundleVersion 诊断包中的版本与运行环境
+type SupportBundleVersion struct {
+	Version     string    `json:"version"`
+	GoVersion   string    `json:"go_version"`
+	OS          string    `json:"os"`
+	Arch        string    `json:"arch"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// SupportBundleBreaker 非关闭状态的熔断器
+type SupportBundleBreaker struct {
+	ModelProviderID uint   `json:"model_provider_id"`
+	State           string `json:"state"`
+}
+
+// WriteSupportBundle 将脱敏后的配置、最近错误日志、熔断状态、版本与系统状态打包为 zip，便于附在 GitHub issue 中
+func WriteSupportBundle(ctx context.Context, w io.Writer) error {
+	now := time.Now()
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		load func() (any, error)
+	}{
+		{"version.json", func() (any, error) {
+			return SupportBundleVersion{
+				Version:     consts.Version,
+				GoVersion:   runtime.Version(),
+				OS:          runtime.GOOS,
+				Arch:        runtime.GOARCH,
+				GeneratedAt: now,
+			}, nil
+		}},
+		{"system.json", func() (any, error) { return GetSystemStatus() }},
+		{"providers.json", func() (any, error) { return bundleProviders(ctx) }},
+		{"models.json", func() (any, error) { return gorm.G[models.Model](models.DB).Find(ctx) }},
+		{"model_providers.json", func() (any, error) { return bundleModelProviders(ctx) }},
+		{"auth_keys.json", func() (any, error) { return bundleAuthKeys(ctx) }},
+		{"error_logs.json", func() (any, error) { return bundleErrorLogs(ctx) }},
+		{"breakers.json", func() (any, error) { return bundleBreakers(), nil }},
+		{"rate_limits.json", func() (any, error) { return GetRateLimitReport(), nil }},
+	}
+	for _, file := range files {
+		data, err := file.load()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.name, err)
+		}
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(data); err != nil {
+			return fmt.Errorf("%s: %w", file.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// bundleProviders 提供商配置中的密钥、请求头取值与代理账号均被移除
+func bundleProviders(ctx context.Context) ([]models.Provider, error) {
+	list, err := gorm.G[models.Provider](models.DB).Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Config = redactConfig(list[i].Config)
+		list[i].Headers = redactHeaderValues(list[i].Headers)
+		list[i].QueryParams = redactHeaderValues(list[i].QueryParams)
+		list[i].Proxy = redactURLUser(list[i].Proxy)
+	}
+	return list, nil
+}
+
+func bundleModelProviders(ctx context.Context) ([]models.ModelWithProvider, error) {
+	list, err := gorm.G[models.ModelWithProvider](models.DB).Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].CustomerHeaders = redactHeaderValues(list[i].CustomerHeaders)
+	}
+	return list, nil
+}
+
+// bundleAuthKeys 只保留 Key 的权限配置，不包含 Key 本身与名称等身份信息
+func bundleAuthKeys(ctx context.Context) ([]models.AuthKey, error) {
+	list, err := gorm.G[models.AuthKey](models.DB).Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Key = redactedValue
+		list[i].Name = ""
+		list[i].Remark = ""
+		if list[i].TrustedUser != "" {
+			list[i].TrustedUser = redactedValue
+		}
+	}
+	return list, nil
+}
+
+// bundleErrorLogs 最近的失败请求，去除客户端 IP 与 User-Agent
+func bundleErrorLogs(ctx context.Context) ([]models.ChatLog, error) {
+	logs, err := gorm.G[models.ChatLog](models.ReadDB()).
+		Where("status = ?", consts.StatusError).
+		Order("id DESC").
+		Limit(supportBundleErrorLogs).
+		Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range logs {
+		logs[i].RemoteIP = ""
+		logs[i].UserAgent = ""
+	}
+	return logs, nil
+}
+
+func bundleBreakers() []SupportBundleBreaker {
+	breakers := make([]SupportBundleBreaker, 0)
+	for _, node := range balancers.Nodes() {
+		if node.State != balancers.StateClosed {
+			breakers = append(breakers, SupportBundleBreaker{ModelProviderID: node.Key, State: node.State.String()})
+		}
+	}
+	slices.SortFunc(breakers, func(a, b SupportBundleBreaker) int { return cmp.Compare(a.ModelProviderID, b.ModelProviderID) })
+	return breakers
+}
+
+// redactConfig 移除提供商配置 JSON 中的密钥字段，无法解析时整体移除
+func redactConfig(config string) string {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(config), &fields); err != nil {
+		return redactedValue
+	}
+	for key, value := range fields {
+		name := strings.ToLower(key)
+		if slices.ContainsFunc(sensitiveConfigKeys, func(word string) bool { return strings.Contains(name, word) }) {
+			fields[key] = redactedValue
+			continue
+		}
+		if s, ok := value.(string); ok {
+			fields[key] = redactURLUser(s)
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return redactedValue
+	}
+	return string(data)
+}
+
+// redactHeaderValues 保留请求头名称，取值可能包含凭据，全部移除
+func redactHeaderValues(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(values))
+	for key := range values {
+		redacted[key] = redactedValue
+	}
+	return redacted
+}
+
+// redactURLUser 移除 URL 中的账号密码与查询参数
+func redactURLUser(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	if u.User != nil {
+		u.User = url.User("redacted")
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = redactedValue
+	}
+	return u.String()
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+)
+
+// processStart 进程启动时间，用于计算运行时长与首次 CPU 占用
+var processStart = time.Now()
+
+// SystemStatus 网关进程的资源占用，用于无需登录服务器即可排查异常实例
+type SystemStatus struct {
+	Version       string         `json:"version"`
+	UptimeSeconds int64          `json:"uptime_seconds"`
+	Goroutines    int            `json:"goroutines"`
+	NumCPU        int            `json:"num_cpu"`
+	CPUPercent    float64        `json:"cpu_percent"` // 距上次采样的进程 CPU 占用，多核可超过 100
+	RSSBytes      uint64         `json:"rss_bytes"`
+	HeapBytes     uint64         `json:"heap_bytes"`
+	OpenFDs       int            `json:"open_fds"`
+	Connections   map[string]int `json:"connections"` // 按 TCP 状态统计的本进程连接数
+	ProcSupported bool           `json:"proc_supported"`
+	LogWriter     LogWriterStats `json:"log_writer"`
+	Streams       StreamStats    `json:"streams"`
+}
+
+var cpuSample struct {
+	sync.Mutex
+	at   time.Time
+	used time.Duration
+}
+
+// GetSystemStatus 采集当前进程的资源占用，CPU/RSS/FD/连接数仅在 Linux 上可用
+func GetSystemStatus() (*SystemStatus, error) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	status := &SystemStatus{
+		Version:       consts.Version,
+		UptimeSeconds: int64(time.Since(processStart).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		NumCPU:        runtime.NumCPU(),
+		HeapBytes:     mem.HeapAlloc,
+		Connections:   map[string]int{},
+		LogWriter:     GetLogWriterStats(),
+		Streams:       GetStreamStats(),
+	}
+	if err := readProcStats(status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// cpuPercent 根据累计 CPU 时间计算与上次采样之间的占用百分比
+func cpuPercent(now time.Time, used time.Duration) float64 {
+	cpuSample.Lock()
+	defer cpuSample.Unlock()
+	lastAt, lastUsed := cpuSample.at, cpuSample.used
+	if lastAt.IsZero() {
+		lastAt = processStart
+	}
+	cpuSample.at, cpuSample.used = now, used
+	wall := now.Sub(lastAt)
+	if wall <= 0 {
+		return 0
+	}
+	return float64(used-lastUsed) / float64(wall) * 100
+}
@@ -0,0 +1,100 @@
+//go:build linux
+
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// tcpStates /proc/net/tcp 中的状态码
+var tcpStates = map[string]string{
+	"01": "established",
+	"02": "syn_sent",
+	"03": "syn_recv",
+	"04": "fin_wait1",
+	"05": "fin_wait2",
+	"06": "time_wait",
+	"07": "close",
+	"08": "close_wait",
+	"09": "last_ack",
+	"0A": "listen",
+	"0B": "closing",
+}
+
+func readProcStats(status *SystemStatus) error {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return fmt.Errorf("getrusage: %w", err)
+	}
+	used := time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+	status.CPUPercent = cpuPercent(time.Now(), used)
+
+	statm, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return fmt.Errorf("read statm: %w", err)
+	}
+	if fields := strings.Fields(string(statm)); len(fields) > 1 {
+		pages, _ := strconv.ParseUint(fields[1], 10, 64)
+		status.RSSBytes = pages * uint64(os.Getpagesize())
+	}
+
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return fmt.Errorf("read fd dir: %w", err)
+	}
+	status.OpenFDs = len(fds)
+
+	// 通过 fd 指向的 socket inode 筛选出本进程持有的连接
+	inodes := make(map[string]struct{})
+	for _, fd := range fds {
+		target, err := os.Readlink("/proc/self/fd/" + fd.Name())
+		if err != nil {
+			continue
+		}
+		if inode, ok := strings.CutPrefix(target, "socket:["); ok {
+			inodes[strings.TrimSuffix(inode, "]")] = struct{}{}
+		}
+	}
+	for _, path := range []string{"/proc/self/net/tcp", "/proc/self/net/tcp6"} {
+		if err := countConnections(path, inodes, status.Connections); err != nil {
+			return err
+		}
+	}
+	status.ProcSupported = true
+	return nil
+}
+
+func countConnections(path string, inodes map[string]struct{}, counts map[string]int) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // 跳过表头
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		if _, ok := inodes[fields[9]]; !ok {
+			continue
+		}
+		state, ok := tcpStates[fields[3]]
+		if !ok {
+			state = "unknown"
+		}
+		counts[state]++
+	}
+	return scanner.Err()
+}
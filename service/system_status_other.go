@@ -0,0 +1,7 @@
+//go:build !linux
+
+package service
+
+func readProcStats(status *SystemStatus) error {
+	return nil
+}
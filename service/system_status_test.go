@@ -0,0 +1,32 @@
+package service
+
+import (
+	"net"
+	"runtime"
+	"testing"
+)
+
+func TestGetSystemStatus(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	status, err := GetSystemStatus()
+	if err != nil {
+		t.Fatalf("GetSystemStatus failed: %v", err)
+	}
+	if status.Goroutines == 0 || status.HeapBytes == 0 {
+		t.Fatalf("runtime stats missing: %+v", status)
+	}
+	if runtime.GOOS != "linux" {
+		return
+	}
+	if !status.ProcSupported || status.RSSBytes == 0 || status.OpenFDs == 0 {
+		t.Fatalf("proc stats missing: %+v", status)
+	}
+	if status.Connections["listen"] < 1 {
+		t.Fatalf("connections = %v, want at least one listener", status.Connections)
+	}
+}
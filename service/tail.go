@@ -0,0 +1,107 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+// 实时日志事件类型
+const (
+	TailStart  = "start"
+	TailFinish = "finish"
+	TailError  = "error"
+)
+
+// tailBufferSize 每个订阅者的事件缓冲，消费过慢时丢弃新事件，不阻塞请求链路
+const tailBufferSize = 64
+
+// TailEvent 单个模型的请求事件，用于控制台实时查看
+type TailEvent struct {
+	Type          string    `json:"type"`
+	LogID         uint      `json:"log_id"`
+	Model         string    `json:"model"`
+	ProviderName  string    `json:"provider_name,omitempty"`
+	ProviderModel string    `json:"provider_model,omitempty"`
+	Retry         int       `json:"retry"`
+	Error         string    `json:"error,omitempty"`
+	TotalTokens   int64     `json:"total_tokens,omitempty"`
+	FirstChunkMs  int64     `json:"first_chunk_ms,omitempty"`
+	ElapsedMs     int64     `json:"elapsed_ms,omitempty"`
+	At            time.Time `json:"at"`
+}
+
+var tailHub = struct {
+	sync.RWMutex
+	subs   map[string]map[chan TailEvent]struct{}
+	closed bool // 服务关闭后不再接受订阅
+}{subs: make(map[string]map[chan TailEvent]struct{})}
+
+// SubscribeTail 订阅指定模型的请求事件，返回的函数用于取消订阅；通道关闭表示服务正在退出
+func SubscribeTail(model string) (<-chan TailEvent, func()) {
+	ch := make(chan TailEvent, tailBufferSize)
+	tailHub.Lock()
+	if tailHub.closed {
+		tailHub.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if tailHub.subs[model] == nil {
+		tailHub.subs[model] = make(map[chan TailEvent]struct{})
+	}
+	tailHub.subs[model][ch] = struct{}{}
+	tailHub.Unlock()
+
+	return ch, func() {
+		tailHub.Lock()
+		defer tailHub.Unlock()
+		delete(tailHub.subs[model], ch)
+		if len(tailHub.subs[model]) == 0 {
+			delete(tailHub.subs, model)
+		}
+	}
+}
+
+// CloseTailSubscribers 关闭所有订阅通道，使实时日志连接结束，避免优雅退出一直等待这些长连接
+func CloseTailSubscribers() {
+	tailHub.Lock()
+	defer tailHub.Unlock()
+	tailHub.closed = true
+	for model, subs := range tailHub.subs {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(tailHub.subs, model)
+	}
+}
+
+func publishTail(event TailEvent) {
+	tailHub.RLock()
+	defer tailHub.RUnlock()
+	for ch := range tailHub.subs[event.Model] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// publishLogTail 将新建的日志记录作为开始或失败事件发布
+func publishLogTail(logID uint, log models.ChatLog) {
+	event := TailEvent{
+		Type:          TailStart,
+		LogID:         logID,
+		Model:         log.Name,
+		ProviderName:  log.ProviderName,
+		ProviderModel: log.ProviderModel,
+		Retry:         log.Retry,
+		At:            time.Now(),
+	}
+	if log.Status == consts.StatusError {
+		event.Type = TailError
+		event.Error = log.Error
+	}
+	publishTail(event)
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestSubscribeTail(t *testing.T) {
+	setupTestDB(t, &models.ChatLog{})
+
+	events, unsubscribe := SubscribeTail("gpt")
+	defer unsubscribe()
+
+	ctx := context.Background()
+	logs := []models.ChatLog{
+		{Name: "other", Status: consts.StatusRunning},
+		{Name: "gpt", Status: consts.StatusError, Error: "status: 429", ProviderName: "p1"},
+		{Name: "gpt", Status: consts.StatusRunning, ProviderName: "p2", Retry: 1},
+	}
+	for _, log := range logs {
+		if _, err := SaveChatLog(ctx, log); err != nil {
+			t.Fatalf("save log: %v", err)
+		}
+	}
+
+	want := []TailEvent{
+		{Type: TailError, Model: "gpt", ProviderName: "p1", Error: "status: 429"},
+		{Type: TailStart, Model: "gpt", ProviderName: "p2", Retry: 1},
+	}
+	for _, w := range want {
+		got := <-events
+		if got.Type != w.Type || got.Model != w.Model || got.ProviderName != w.ProviderName || got.Error != w.Error || got.Retry != w.Retry {
+			t.Fatalf("event = %+v, want %+v", got, w)
+		}
+	}
+	select {
+	case got := <-events:
+		t.Fatalf("unexpected event %+v", got)
+	default:
+	}
+}
+
+func TestCloseTailSubscribers(t *testing.T) {
+	t.Cleanup(func() {
+		tailHub.Lock()
+		tailHub.closed = false
+		tailHub.Unlock()
+	})
+	events, unsubscribe := SubscribeTail("gpt")
+	CloseTailSubscribers()
+	if _, ok := <-events; ok {
+		t.Fatal("subscriber channel still open after close")
+	}
+	unsubscribe()
+	// 关闭后不再阻塞新的订阅，发布事件也不会写入已关闭的通道
+	late, _ := SubscribeTail("gpt")
+	if _, ok := <-late; ok {
+		t.Fatal("subscription after close should be closed")
+	}
+	publishTail(TailEvent{Type: TailStart, Model: "gpt"})
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"slices"
+	"time"
+
+	"github.com/atopos31/llmio/models"
+)
+
+// 请求时间线阶段
+const (
+	StageRouted      = "routed"
+	StageAttempt     = "attempt"
+	StageHedge       = "hedge"
+	StageFailed      = "failed"
+	StageResponse    = "response"
+	StageFirstChunk  = "first_chunk"
+	StageDone        = "done"
+	StageStreamError = "stream_error"
+)
+
+// timeline 记录单次请求在网关内各阶段的时间点
+type timeline struct {
+	start  time.Time
+	events []models.TimelineEvent
+}
+
+func newTimeline(start time.Time) *timeline {
+	return &timeline{start: start}
+}
+
+func (t *timeline) addAt(at time.Time, event models.TimelineEvent) {
+	event.At = at
+	event.ElapsedMs = at.Sub(t.start).Milliseconds()
+	t.events = append(t.events, event)
+}
+
+func (t *timeline) add(event models.TimelineEvent) {
+	t.addAt(time.Now(), event)
+}
+
+// failed 记录一次失败的尝试，返回附带当前时间线的错误日志
+func (t *timeline) failed(log models.ChatLog, status int, err error) models.ChatLog {
+	t.add(models.TimelineEvent{Stage: StageFailed, Attempt: log.Retry + 1, Provider: log.ProviderName, Status: status, Error: err.Error()})
+	log.Timeline = t.snapshot()
+	return log.WithError(err)
+}
+
+func (t *timeline) snapshot() []models.TimelineEvent {
+	return slices.Clone(t.events)
+}
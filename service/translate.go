@@ -0,0 +1,93 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/atopos31/llmio/consts"
+)
+
+// Translator 在客户端协议与上游提供商协议之间转换请求与响应
+type Translator struct {
+	From          string   // 客户端请求协议
+	ProviderTypes []string // 可被转换到的上游提供商类型
+	// Request 将客户端请求体转换为上游协议
+	Request func(raw []byte, stream bool) ([]byte, error)
+	// Response 将上游响应体转换回客户端协议
+	Response func(body io.ReadCloser, stream bool, model string) io.ReadCloser
+	// ContentType 转换后响应的 Content-Type，空表示沿用上游
+	ContentType func(stream bool) string
+}
+
+// translators 按客户端协议注册，仅当模型没有原生协议的提供商时按顺序尝试
+var translators = map[string][]*Translator{
+	consts.StyleAnthropic: {{
+		From:          consts.StyleAnthropic,
+		ProviderTypes: consts.ProviderTypes(consts.StyleOpenAI),
+		Request:       anthropicToOpenAIRequest,
+		Response:      openAIToAnthropicResponse,
+		ContentType:   sseOrJSON,
+	}},
+	consts.StyleOpenAI: {{
+		From:          consts.StyleOpenAI,
+		ProviderTypes: []string{consts.StyleAnthropic},
+		Request:       openAIToAnthropicRequest,
+		Response:      anthropicToOpenAIResponse,
+		ContentType:   sseOrJSON,
+	}, {
+		From:          consts.StyleOpenAI,
+		ProviderTypes: []string{consts.StyleGemini},
+		Request:       openAIToGeminiRequest,
+		Response:      geminiToOpenAIResponse,
+		ContentType:   sseOrJSON,
+	}},
+}
+
+// mixedTranslators 按客户端协议与上游提供商类型注册，这些提供商与原生提供商一起进入同一模型池，按次转换
+var mixedTranslators = map[string]map[string]*Translator{
+	consts.StyleOpenAI: {
+		consts.StyleOllama: {
+			From:          consts.StyleOpenAI,
+			ProviderTypes: []string{consts.StyleOllama},
+			Request:       openAIToOllamaRequest,
+			Response:      ollamaToOpenAIResponse,
+			ContentType:   sseOrJSON,
+		},
+	},
+}
+
+func sseOrJSON(stream bool) string {
+	if stream {
+		return "text/event-stream"
+	}
+	return "application/json"
+}
+
+// sseWriter 写出带 event 行的 SSE 事件
+type sseWriter struct {
+	w io.Writer
+}
+
+func (s sseWriter) event(name string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		if _, err := io.WriteString(s.w, "event: "+name+"\n"); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(s.w, "data: "+string(payload)+"\n\n")
+	return err
+}
+
+// pipeTranslate 在后台按流转换上游响应，转换失败时关闭管道并返回错误
+func pipeTranslate(body io.ReadCloser, fn func(r io.Reader, w io.Writer) error) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer body.Close()
+		pw.CloseWithError(fn(body, pw))
+	}()
+	return pr
+}
@@ -0,0 +1,387 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// anthropicToOpenAIRequest 将 Anthropic Messages 请求转换为 OpenAI Chat Completions 请求
+func anthropicToOpenAIRequest(raw []byte, stream bool) ([]byte, error) {
+	if !gjson.ValidBytes(raw) {
+		return nil, errors.New("invalid anthropic request body")
+	}
+	req := gjson.ParseBytes(raw)
+	body := map[string]any{
+		"model": req.Get("model").String(),
+	}
+
+	messages := make([]map[string]any, 0)
+	if system := anthropicText(req.Get("system")); system != "" {
+		messages = append(messages, map[string]any{"role": "system", "content": system})
+	}
+	req.Get("messages").ForEach(func(_, msg gjson.Result) bool {
+		messages = append(messages, anthropicMessageToOpenAI(msg)...)
+		return true
+	})
+	body["messages"] = messages
+
+	if v := req.Get("max_tokens"); v.Exists() {
+		body["max_tokens"] = v.Int()
+	}
+	for _, key := range []string{"temperature", "top_p"} {
+		if v := req.Get(key); v.Exists() {
+			body[key] = v.Value()
+		}
+	}
+	if v := req.Get("stop_sequences"); v.IsArray() && len(v.Array()) > 0 {
+		body["stop"] = v.Value()
+	}
+	if v := req.Get("metadata.user_id"); v.Exists() {
+		body["user"] = v.String()
+	}
+	if stream {
+		body["stream"] = true
+		body["stream_options"] = map[string]any{"include_usage": true}
+	}
+
+	if tools := req.Get("tools"); tools.IsArray() && len(tools.Array()) > 0 {
+		openaiTools := make([]map[string]any, 0)
+		tools.ForEach(func(_, tool gjson.Result) bool {
+			function := map[string]any{
+				"name":       tool.Get("name").String(),
+				"parameters": tool.Get("input_schema").Value(),
+			}
+			if desc := tool.Get("description"); desc.Exists() {
+				function["description"] = desc.String()
+			}
+			openaiTools = append(openaiTools, map[string]any{"type": "function", "function": function})
+			return true
+		})
+		body["tools"] = openaiTools
+
+		// OpenAI 在未传 tools 时会拒绝 tool_choice 与 parallel_tool_calls
+		if choice := req.Get("tool_choice"); choice.Exists() {
+			switch choice.Get("type").String() {
+			case "auto":
+				body["tool_choice"] = "auto"
+			case "any":
+				body["tool_choice"] = "required"
+			case "none":
+				body["tool_choice"] = "none"
+			case "tool":
+				body["tool_choice"] = map[string]any{"type": "function", "function": map[string]any{"name": choice.Get("name").String()}}
+			}
+			if choice.Get("disable_parallel_tool_use").Bool() {
+				body["parallel_tool_calls"] = false
+			}
+		}
+	}
+	return json.Marshal(body)
+}
+
+// anthropicMessageToOpenAI 单条 Anthropic 消息可能拆成多条 OpenAI 消息（tool_result 对应 role=tool）
+func anthropicMessageToOpenAI(msg gjson.Result) []map[string]any {
+	role := msg.Get("role").String()
+	content := msg.Get("content")
+	if content.Type == gjson.String {
+		return []map[string]any{{"role": role, "content": content.String()}}
+	}
+
+	var result []map[string]any
+	parts := make([]map[string]any, 0)
+	toolCalls := make([]map[string]any, 0)
+	content.ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").String() {
+		case "text":
+			parts = append(parts, map[string]any{"type": "text", "text": block.Get("text").String()})
+		case "image":
+			source := block.Get("source")
+			url := source.Get("url").String()
+			if source.Get("type").String() == "base64" {
+				url = "data:" + source.Get("media_type").String() + ";base64," + source.Get("data").String()
+			}
+			parts = append(parts, map[string]any{"type": "image_url", "image_url": map[string]any{"url": url}})
+		case "tool_use":
+			toolCalls = append(toolCalls, map[string]any{
+				"id":   block.Get("id").String(),
+				"type": "function",
+				"function": map[string]any{
+					"name":      block.Get("name").String(),
+					"arguments": block.Get("input").Raw,
+				},
+			})
+		case "tool_result":
+			result = append(result, map[string]any{
+				"role":         "tool",
+				"tool_call_id": block.Get("tool_use_id").String(),
+				"content":      anthropicText(block.Get("content")),
+			})
+		}
+		return true
+	})
+
+	if len(parts) == 0 && len(toolCalls) == 0 {
+		return result
+	}
+	message := map[string]any{"role": role}
+	if len(parts) > 0 {
+		message["content"] = parts
+		// 纯文本时使用字符串，兼容不支持 content 数组的上游
+		if text, ok := textOnlyParts(parts); ok {
+			message["content"] = text
+		}
+	} else {
+		message["content"] = nil
+	}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+	}
+	return append(result, message)
+}
+
+func textOnlyParts(parts []map[string]any) (string, bool) {
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part["type"] != "text" {
+			return "", false
+		}
+		texts = append(texts, part["text"].(string))
+	}
+	return strings.Join(texts, "\n"), true
+}
+
+// anthropicText 提取字符串或 text block 数组中的文本
+func anthropicText(v gjson.Result) string {
+	if v.Type == gjson.String {
+		return v.String()
+	}
+	texts := make([]string, 0)
+	v.ForEach(func(_, block gjson.Result) bool {
+		if block.Get("type").String() == "text" {
+			texts = append(texts, block.Get("text").String())
+		}
+		return true
+	})
+	return strings.Join(texts, "\n")
+}
+
+var openAIFinishToAnthropic = map[string]string{
+	"stop":           "end_turn",
+	"length":         "max_tokens",
+	"tool_calls":     "tool_use",
+	"function_call":  "tool_use",
+	"content_filter": "refusal",
+}
+
+func anthropicStopReason(finishReason string) string {
+	if reason, ok := openAIFinishToAnthropic[finishReason]; ok {
+		return reason
+	}
+	return "end_turn"
+}
+
+func anthropicUsageFromOpenAI(usage gjson.Result) map[string]any {
+	cached := usage.Get("prompt_tokens_details.cached_tokens").Int()
+	return map[string]any{
+		"input_tokens":            usage.Get("prompt_tokens").Int() - cached,
+		"output_tokens":           usage.Get("completion_tokens").Int(),
+		"cache_read_input_tokens": cached,
+	}
+}
+
+// openAIToAnthropicResponse 将 OpenAI Chat Completions 响应转换为 Anthropic Messages 响应
+func openAIToAnthropicResponse(body io.ReadCloser, stream bool, model string) io.ReadCloser {
+	if stream {
+		return pipeTranslate(body, func(r io.Reader, w io.Writer) error {
+			return openAIStreamToAnthropic(r, w, model)
+		})
+	}
+	return pipeTranslate(body, func(r io.Reader, w io.Writer) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		res := gjson.ParseBytes(data)
+		if errMsg := res.Get("error"); errMsg.Exists() {
+			return json.NewEncoder(w).Encode(anthropicError(errMsg))
+		}
+		choice := res.Get("choices.0")
+		content := make([]map[string]any, 0)
+		if text := choice.Get("message.content").String(); text != "" {
+			content = append(content, map[string]any{"type": "text", "text": text})
+		}
+		choice.Get("message.tool_calls").ForEach(func(_, call gjson.Result) bool {
+			content = append(content, map[string]any{
+				"type":  "tool_use",
+				"id":    call.Get("id").String(),
+				"name":  call.Get("function.name").String(),
+				"input": jsonObject(call.Get("function.arguments").String()),
+			})
+			return true
+		})
+		return json.NewEncoder(w).Encode(map[string]any{
+			"id":            "msg_" + res.Get("id").String(),
+			"type":          "message",
+			"role":          "assistant",
+			"model":         model,
+			"content":       content,
+			"stop_reason":   anthropicStopReason(choice.Get("finish_reason").String()),
+			"stop_sequence": nil,
+			"usage":         anthropicUsageFromOpenAI(res.Get("usage")),
+		})
+	})
+}
+
+func anthropicError(errMsg gjson.Result) map[string]any {
+	message := errMsg.Get("message").String()
+	if message == "" {
+		message = errMsg.String()
+	}
+	return map[string]any{
+		"type":  "error",
+		"error": map[string]any{"type": "api_error", "message": message},
+	}
+}
+
+func jsonObject(raw string) any {
+	if raw == "" || !gjson.Valid(raw) {
+		return map[string]any{}
+	}
+	return gjson.Parse(raw).Value()
+}
+
+// openAIStreamToAnthropic 将 OpenAI SSE 流转换为 Anthropic SSE 事件序列
+func openAIStreamToAnthropic(r io.Reader, w io.Writer, model string) error {
+	sse := sseWriter{w: w}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, InitScannerBufferSize), MaxScannerBufferSize)
+
+	started := false
+	blockIndex := -1
+	blockType := ""
+	toolIndex := map[int64]int{} // OpenAI tool_call index -> Anthropic content block index
+	stopReason := "end_turn"
+	var usage gjson.Result
+
+	closeBlock := func() error {
+		if blockType == "" {
+			return nil
+		}
+		blockType = ""
+		return sse.event("content_block_stop", map[string]any{"type": "content_block_stop", "index": blockIndex})
+	}
+	startBlock := func(block map[string]any) error {
+		if err := closeBlock(); err != nil {
+			return err
+		}
+		blockIndex++
+		blockType = block["type"].(string)
+		return sse.event("content_block_start", map[string]any{"type": "content_block_start", "index": blockIndex, "content_block": block})
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		chunk := gjson.Parse(data)
+		if errMsg := chunk.Get("error"); errMsg.Exists() {
+			return sse.event("error", anthropicError(errMsg))
+		}
+		if !started {
+			started = true
+			if err := sse.event("message_start", map[string]any{
+				"type": "message_start",
+				"message": map[string]any{
+					"id":            "msg_" + chunk.Get("id").String(),
+					"type":          "message",
+					"role":          "assistant",
+					"model":         model,
+					"content":       []any{},
+					"stop_reason":   nil,
+					"stop_sequence": nil,
+					"usage":         map[string]any{"input_tokens": 0, "output_tokens": 0},
+				},
+			}); err != nil {
+				return err
+			}
+		}
+		if u := chunk.Get("usage"); u.Exists() && u.Type != gjson.Null {
+			usage = u
+		}
+		choice := chunk.Get("choices.0")
+		if !choice.Exists() {
+			continue
+		}
+		if text := choice.Get("delta.content").String(); text != "" {
+			if blockType != "text" {
+				if err := startBlock(map[string]any{"type": "text", "text": ""}); err != nil {
+					return err
+				}
+			}
+			if err := sse.event("content_block_delta", map[string]any{
+				"type":  "content_block_delta",
+				"index": blockIndex,
+				"delta": map[string]any{"type": "text_delta", "text": text},
+			}); err != nil {
+				return err
+			}
+		}
+		var err error
+		choice.Get("delta.tool_calls").ForEach(func(_, call gjson.Result) bool {
+			idx := call.Get("index").Int()
+			if _, ok := toolIndex[idx]; !ok {
+				if err = startBlock(map[string]any{
+					"type":  "tool_use",
+					"id":    call.Get("id").String(),
+					"name":  call.Get("function.name").String(),
+					"input": map[string]any{},
+				}); err != nil {
+					return false
+				}
+				toolIndex[idx] = blockIndex
+			}
+			if args := call.Get("function.arguments").String(); args != "" {
+				err = sse.event("content_block_delta", map[string]any{
+					"type":  "content_block_delta",
+					"index": toolIndex[idx],
+					"delta": map[string]any{"type": "input_json_delta", "partial_json": args},
+				})
+			}
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+		if reason := choice.Get("finish_reason").String(); reason != "" {
+			stopReason = anthropicStopReason(reason)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !started {
+		return errors.New("upstream stream ended without data")
+	}
+	if err := closeBlock(); err != nil {
+		return err
+	}
+	if err := sse.event("message_delta", map[string]any{
+		"type":  "message_delta",
+		"delta": map[string]any{"stop_reason": stopReason, "stop_sequence": nil},
+		"usage": anthropicUsageFromOpenAI(usage),
+	}); err != nil {
+		return err
+	}
+	return sse.event("message_stop", map[string]any{"type": "message_stop"})
+}
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// openAIToAnthropicRequest 将 OpenAI Chat Completions 请求转换为 Anthropic Messages 请求
+func openAIToAnthropicRequest(raw []byte, stream bool) ([]byte, error) {
+	if !gjson.ValidBytes(raw) {
+		return nil, errors.New("invalid openai request body")
+	}
+	req := gjson.ParseBytes(raw)
+	// 未指定 max_tokens 时由 Anthropic 提供商按配置的默认值补全
+	body := map[string]any{
+		"model": req.Get("model").String(),
+	}
+	for _, key := range []string{"max_completion_tokens", "max_tokens"} {
+		if v := req.Get(key); v.Exists() && v.Int() > 0 {
+			body["max_tokens"] = v.Int()
+			break
+		}
+	}
+	if v := req.Get("temperature"); v.Exists() {
+		// OpenAI 取值 0-2，Anthropic 取值 0-1
+		body["temperature"] = min(v.Float(), 1)
+	}
+	if v := req.Get("top_p"); v.Exists() {
+		body["top_p"] = v.Float()
+	}
+	if v := req.Get("stop"); v.Exists() {
+		if v.Type == gjson.String {
+			body["stop_sequences"] = []string{v.String()}
+		} else {
+			body["stop_sequences"] = v.Value()
+		}
+	}
+	if v := req.Get("user"); v.Exists() {
+		body["metadata"] = map[string]any{"user_id": v.String()}
+	}
+	if stream {
+		body["stream"] = true
+	}
+
+	system := make([]string, 0)
+	messages := make([]map[string]any, 0)
+	appendBlocks := func(role string, blocks []map[string]any) {
+		if len(blocks) == 0 {
+			return
+		}
+		// Anthropic 要求 user/assistant 交替，合并相邻同角色消息
+		if n := len(messages); n > 0 && messages[n-1]["role"] == role {
+			messages[n-1]["content"] = append(messages[n-1]["content"].([]map[string]any), blocks...)
+			return
+		}
+		messages = append(messages, map[string]any{"role": role, "content": blocks})
+	}
+	req.Get("messages").ForEach(func(_, msg gjson.Result) bool {
+		switch role := msg.Get("role").String(); role {
+		case "system", "developer":
+			if text := openAIText(msg.Get("content")); text != "" {
+				system = append(system, text)
+			}
+		case "tool":
+			appendBlocks("user", []map[string]any{{
+				"type":        "tool_result",
+				"tool_use_id": msg.Get("tool_call_id").String(),
+				"content":     openAIText(msg.Get("content")),
+			}})
+		default:
+			blocks := openAIContentToAnthropic(msg.Get("content"))
+			msg.Get("tool_calls").ForEach(func(_, call gjson.Result) bool {
+				blocks = append(blocks, map[string]any{
+					"type":  "tool_use",
+					"id":    call.Get("id").String(),
+					"name":  call.Get("function.name").String(),
+					"input": jsonObject(call.Get("function.arguments").String()),
+				})
+				return true
+			})
+			appendBlocks(role, blocks)
+		}
+		return true
+	})
+	if len(system) > 0 {
+		body["system"] = strings.Join(system, "\n")
+	}
+	body["messages"] = messages
+
+	if tools := req.Get("tools"); tools.IsArray() && len(tools.Array()) > 0 {
+		anthropicTools := make([]map[string]any, 0)
+		tools.ForEach(func(_, tool gjson.Result) bool {
+			schema := tool.Get("function.parameters").Value()
+			if schema == nil {
+				schema = map[string]any{"type": "object"}
+			}
+			t := map[string]any{
+				"name":         tool.Get("function.name").String(),
+				"input_schema": schema,
+			}
+			if desc := tool.Get("function.description"); desc.Exists() {
+				t["description"] = desc.String()
+			}
+			anthropicTools = append(anthropicTools, t)
+			return true
+		})
+		body["tools"] = anthropicTools
+
+		var toolChoice map[string]any
+		switch choice := req.Get("tool_choice"); {
+		case choice.Type == gjson.String && choice.String() == "required":
+			toolChoice = map[string]any{"type": "any"}
+		case choice.Type == gjson.String && choice.String() == "none":
+			toolChoice = map[string]any{"type": "none"}
+		case choice.IsObject():
+			toolChoice = map[string]any{"type": "tool", "name": openAIToolChoiceName(choice)}
+		default:
+			toolChoice = map[string]any{"type": "auto"}
+		}
+		if v := req.Get("parallel_tool_calls"); v.Exists() && !v.Bool() {
+			toolChoice["disable_parallel_tool_use"] = true
+		}
+		body["tool_choice"] = toolChoice
+	}
+	return json.Marshal(body)
+}
+
+// openAIToolChoiceName 指定工具时的函数名，兼容 Responses 风格的 {"type":"function","name":"..."}
+func openAIToolChoiceName(choice gjson.Result) string {
+	if name := choice.Get("function.name"); name.Exists() {
+		return name.String()
+	}
+	return choice.Get("name").String()
+}
+
+// openAIContentToAnthropic 转换字符串或 content parts 为 Anthropic content blocks
+func openAIContentToAnthropic(content gjson.Result) []map[string]any {
+	blocks := make([]map[string]any, 0)
+	if content.Type == gjson.String {
+		if text := content.String(); text != "" {
+			blocks = append(blocks, map[string]any{"type": "text", "text": text})
+		}
+		return blocks
+	}
+	content.ForEach(func(_, part gjson.Result) bool {
+		switch part.Get("type").String() {
+		case "text":
+			blocks = append(blocks, map[string]any{"type": "text", "text": part.Get("text").String()})
+		case "image_url":
+			url := part.Get("image_url.url").String()
+			if mediaType, data, ok := parseDataURL(url); ok {
+				blocks = append(blocks, map[string]any{
+					"type":   "image",
+					"source": map[string]any{"type": "base64", "media_type": mediaType, "data": data},
+				})
+			} else {
+				blocks = append(blocks, map[string]any{
+					"type":   "image",
+					"source": map[string]any{"type": "url", "url": url},
+				})
+			}
+		}
+		return true
+	})
+	return blocks
+}
+
+// parseDataURL 解析 data:image/png;base64,xxx
+func parseDataURL(url string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return "", "", false
+	}
+	meta, data, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", "", false
+	}
+	mediaType, ok := strings.CutSuffix(meta, ";base64")
+	if !ok {
+		return "", "", false
+	}
+	return mediaType, data, true
+}
+
+// openAIText 提取字符串或 text parts 中的文本
+func openAIText(v gjson.Result) string {
+	if v.Type == gjson.String {
+		return v.String()
+	}
+	texts := make([]string, 0)
+	v.ForEach(func(_, part gjson.Result) bool {
+		if part.Get("type").String() == "text" {
+			texts = append(texts, part.Get("text").String())
+		}
+		return true
+	})
+	return strings.Join(texts, "\n")
+}
+
+var anthropicStopToOpenAI = map[string]string{
+	"end_turn":      "stop",
+	"stop_sequence": "stop",
+	"pause_turn":    "stop",
+	"max_tokens":    "length",
+	"tool_use":      "tool_calls",
+	"refusal":       "content_filter",
+}
+
+func openAIFinishReason(stopReason string) string {
+	if reason, ok := anthropicStopToOpenAI[stopReason]; ok {
+		return reason
+	}
+	return "stop"
+}
+
+func openAIUsageFromAnthropic(input, cacheRead, cacheCreation, output int64) map[string]any {
+	prompt := input + cacheRead + cacheCreation
+	return map[string]any{
+		"prompt_tokens":         prompt,
+		"completion_tokens":     output,
+		"total_tokens":          prompt + output,
+		"prompt_tokens_details": map[string]any{"cached_tokens": cacheRead},
+	}
+}
+
+func openAIError(errMsg gjson.Result) map[string]any {
+	return map[string]any{
+		"error": map[string]any{
+			"message": errMsg.Get("message").String(),
+			"type":    errMsg.Get("type").String(),
+		},
+	}
+}
+
+// anthropicToOpenAIResponse 将 Anthropic Messages 响应转换为 OpenAI Chat Completions 响应
+func anthropicToOpenAIResponse(body io.ReadCloser, stream bool, model string) io.ReadCloser {
+	if stream {
+		return pipeTranslate(body, func(r io.Reader, w io.Writer) error {
+			return anthropicStreamToOpenAI(r, w, model)
+		})
+	}
+	return pipeTranslate(body, func(r io.Reader, w io.Writer) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		res := gjson.ParseBytes(data)
+		if res.Get("type").String() == "error" {
+			return json.NewEncoder(w).Encode(openAIError(res.Get("error")))
+		}
+		texts := make([]string, 0)
+		thinking := make([]string, 0)
+		toolCalls := make([]map[string]any, 0)
+		res.Get("content").ForEach(func(_, block gjson.Result) bool {
+			switch block.Get("type").String() {
+			case "text":
+				texts = append(texts, block.Get("text").String())
+			case "thinking":
+				thinking = append(thinking, block.Get("thinking").String())
+			case "tool_use":
+				toolCalls = append(toolCalls, map[string]any{
+					"id":       block.Get("id").String(),
+					"type":     "function",
+					"function": map[string]any{"name": block.Get("name").String(), "arguments": block.Get("input").Raw},
+				})
+			}
+			return true
+		})
+		message := map[string]any{"role": "assistant", "content": nil}
+		if len(texts) > 0 {
+			message["content"] = strings.Join(texts, "")
+		}
+		if len(thinking) > 0 {
+			message["reasoning_content"] = strings.Join(thinking, "")
+		}
+		if len(toolCalls) > 0 {
+			message["tool_calls"] = toolCalls
+		}
+		usage := res.Get("usage")
+		return json.NewEncoder(w).Encode(map[string]any{
+			"id":      "chatcmpl-" + res.Get("id").String(),
+			"object":  "chat.completion",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"message":       message,
+				"finish_reason": openAIFinishReason(res.Get("stop_reason").String()),
+			}},
+			"usage": openAIUsageFromAnthropic(
+				usage.Get("input_tokens").Int(),
+				usage.Get("cache_read_input_tokens").Int(),
+				usage.Get("cache_creation_input_tokens").Int(),
+				usage.Get("output_tokens").Int(),
+			),
+		})
+	})
+}
+
+// anthropicStreamToOpenAI 将 Anthropic SSE 事件转换为 OpenAI chunk 流
+func anthropicStreamToOpenAI(r io.Reader, w io.Writer, model string) error {
+	sse := sseWriter{w: w}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, InitScannerBufferSize), MaxScannerBufferSize)
+
+	id := ""
+	created := time.Now().Unix()
+	toolIndex := -1
+	var input, cacheRead, cacheCreation, output int64
+	chunk := func(delta map[string]any, finishReason any) map[string]any {
+		return map[string]any{
+			"id":      "chatcmpl-" + id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+	}
+
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		if !ok {
+			continue
+		}
+		event := gjson.Parse(strings.TrimSpace(data))
+		var err error
+		switch event.Get("type").String() {
+		case "message_start":
+			message := event.Get("message")
+			id = message.Get("id").String()
+			input = message.Get("usage.input_tokens").Int()
+			cacheRead = message.Get("usage.cache_read_input_tokens").Int()
+			cacheCreation = message.Get("usage.cache_creation_input_tokens").Int()
+			output = message.Get("usage.output_tokens").Int()
+			err = sse.event("", chunk(map[string]any{"role": "assistant", "content": ""}, nil))
+		case "content_block_start":
+			block := event.Get("content_block")
+			if block.Get("type").String() == "tool_use" {
+				toolIndex++
+				err = sse.event("", chunk(map[string]any{"tool_calls": []map[string]any{{
+					"index":    toolIndex,
+					"id":       block.Get("id").String(),
+					"type":     "function",
+					"function": map[string]any{"name": block.Get("name").String(), "arguments": ""},
+				}}}, nil))
+			}
+		case "content_block_delta":
+			delta := event.Get("delta")
+			switch delta.Get("type").String() {
+			case "text_delta":
+				err = sse.event("", chunk(map[string]any{"content": delta.Get("text").String()}, nil))
+			case "thinking_delta":
+				err = sse.event("", chunk(map[string]any{"reasoning_content": delta.Get("thinking").String()}, nil))
+			case "input_json_delta":
+				err = sse.event("", chunk(map[string]any{"tool_calls": []map[string]any{{
+					"index":    toolIndex,
+					"function": map[string]any{"arguments": delta.Get("partial_json").String()},
+				}}}, nil))
+			}
+		case "message_delta":
+			usage := event.Get("usage")
+			if v := usage.Get("input_tokens"); v.Exists() {
+				input = v.Int()
+			}
+			if v := usage.Get("cache_read_input_tokens"); v.Exists() {
+				cacheRead = v.Int()
+			}
+			if v := usage.Get("cache_creation_input_tokens"); v.Exists() {
+				cacheCreation = v.Int()
+			}
+			if v := usage.Get("output_tokens"); v.Exists() {
+				output = v.Int()
+			}
+			err = sse.event("", chunk(map[string]any{}, openAIFinishReason(event.Get("delta.stop_reason").String())))
+		case "message_stop":
+			usageChunk := chunk(nil, nil)
+			usageChunk["choices"] = []any{}
+			usageChunk["usage"] = openAIUsageFromAnthropic(input, cacheRead, cacheCreation, output)
+			if err := sse.event("", usageChunk); err != nil {
+				return err
+			}
+			_, err := io.WriteString(w, "data: [DONE]\n\n")
+			return err
+		case "error":
+			return sse.event("", openAIError(event.Get("error")))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("upstream stream ended without message_stop")
+}
@@ -0,0 +1,461 @@
+package service
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// geminiMaxInlineBytes 远程图片转为 inlineData 的大小上限，与 Gemini 内联请求体限制一致
+const geminiMaxInlineBytes = 20 << 20
+
+// mediaMaxRedirects 下载远程图片时最多跟随的重定向次数
+const mediaMaxRedirects = 3
+
+var errMediaAddressBlocked = errors.New("media url resolves to a non-public address")
+
+// mediaFetchClient 下载远程图片的客户端，URL 由客户端提供，只允许连接公网地址，避免被用来访问内网或云元数据服务
+var mediaFetchClient = newMediaFetchClient(publicAddress)
+
+// newMediaFetchClient 在 DNS 解析后的实际连接地址上校验 allow，重定向同样经过该校验
+func newMediaFetchClient(allow func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !allow(addr.Unmap()) {
+				return fmt.Errorf("%w: %s", errMediaAddressBlocked, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// 经代理时实际连接的是代理地址，无法校验目标，因此不使用环境代理
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= mediaMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", mediaMaxRedirects)
+			}
+			return nil
+		},
+	}
+}
+
+// cgnatPrefix 运营商级 NAT 共享地址段
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddress 排除回环、私有、链路本地(含 169.254.169.254 元数据地址)、未指定与组播地址
+func publicAddress(addr netip.Addr) bool {
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !cgnatPrefix.Contains(addr)
+}
+
+// openAIToGeminiRequest 将 OpenAI Chat Completions 请求转换为 Gemini generateContent 请求，流式由请求路径区分
+func openAIToGeminiRequest(raw []byte, stream bool) ([]byte, error) {
+	if !gjson.ValidBytes(raw) {
+		return nil, errors.New("invalid openai request body")
+	}
+	req := gjson.ParseBytes(raw)
+	body := map[string]any{}
+
+	config := map[string]any{}
+	if v := req.Get("temperature"); v.Exists() {
+		config["temperature"] = v.Float()
+	}
+	if v := req.Get("top_p"); v.Exists() {
+		config["topP"] = v.Float()
+	}
+	for _, key := range []string{"max_completion_tokens", "max_tokens"} {
+		if v := req.Get(key); v.Exists() && v.Int() > 0 {
+			config["maxOutputTokens"] = v.Int()
+			break
+		}
+	}
+	if v := req.Get("n"); v.Exists() && v.Int() > 1 {
+		config["candidateCount"] = v.Int()
+	}
+	if v := req.Get("stop"); v.Exists() {
+		if v.Type == gjson.String {
+			config["stopSequences"] = []string{v.String()}
+		} else {
+			config["stopSequences"] = v.Value()
+		}
+	}
+	switch format := req.Get("response_format"); format.Get("type").String() {
+	case "json_object":
+		config["responseMimeType"] = "application/json"
+	case "json_schema":
+		config["responseMimeType"] = "application/json"
+		if schema := format.Get("json_schema.schema"); schema.Exists() {
+			config["responseJsonSchema"] = schema.Value()
+		}
+	}
+	if len(config) > 0 {
+		body["generationConfig"] = config
+	}
+
+	// tool 消息只有 tool_call_id，需要按此查找函数名
+	toolNames := map[string]string{}
+	system := make([]map[string]any, 0)
+	contents := make([]map[string]any, 0)
+	appendParts := func(role string, parts []map[string]any) {
+		if len(parts) == 0 {
+			return
+		}
+		if n := len(contents); n > 0 && contents[n-1]["role"] == role {
+			contents[n-1]["parts"] = append(contents[n-1]["parts"].([]map[string]any), parts...)
+			return
+		}
+		contents = append(contents, map[string]any{"role": role, "parts": parts})
+	}
+	var convErr error
+	req.Get("messages").ForEach(func(_, msg gjson.Result) bool {
+		switch role := msg.Get("role").String(); role {
+		case "system", "developer":
+			if text := openAIText(msg.Get("content")); text != "" {
+				system = append(system, map[string]any{"text": text})
+			}
+		case "tool":
+			id := msg.Get("tool_call_id").String()
+			appendParts("user", []map[string]any{{
+				"functionResponse": map[string]any{
+					"name":     toolNames[id],
+					"response": map[string]any{"content": openAIText(msg.Get("content"))},
+				},
+			}})
+		default:
+			parts, err := openAIContentToGemini(msg.Get("content"))
+			if err != nil {
+				convErr = err
+				return false
+			}
+			msg.Get("tool_calls").ForEach(func(_, call gjson.Result) bool {
+				name := call.Get("function.name").String()
+				toolNames[call.Get("id").String()] = name
+				parts = append(parts, map[string]any{
+					"functionCall": map[string]any{"name": name, "args": jsonObject(call.Get("function.arguments").String())},
+				})
+				return true
+			})
+			if role == "assistant" {
+				role = "model"
+			}
+			appendParts(role, parts)
+		}
+		return true
+	})
+	if convErr != nil {
+		return nil, convErr
+	}
+	if len(system) > 0 {
+		body["systemInstruction"] = map[string]any{"parts": system}
+	}
+	body["contents"] = contents
+
+	if tools := req.Get("tools"); tools.IsArray() && len(tools.Array()) > 0 {
+		declarations := make([]map[string]any, 0)
+		tools.ForEach(func(_, tool gjson.Result) bool {
+			declaration := map[string]any{"name": tool.Get("function.name").String()}
+			if desc := tool.Get("function.description"); desc.Exists() {
+				declaration["description"] = desc.String()
+			}
+			if params := tool.Get("function.parameters"); params.Exists() {
+				declaration["parametersJsonSchema"] = params.Value()
+			}
+			declarations = append(declarations, declaration)
+			return true
+		})
+		body["tools"] = []map[string]any{{"functionDeclarations": declarations}}
+
+		callingConfig := map[string]any{"mode": "AUTO"}
+		switch choice := req.Get("tool_choice"); {
+		case choice.Type == gjson.String && choice.String() == "required":
+			callingConfig["mode"] = "ANY"
+		case choice.Type == gjson.String && choice.String() == "none":
+			callingConfig["mode"] = "NONE"
+		case choice.IsObject():
+			callingConfig["mode"] = "ANY"
+			callingConfig["allowedFunctionNames"] = []string{openAIToolChoiceName(choice)}
+		}
+		body["toolConfig"] = map[string]any{"functionCallingConfig": callingConfig}
+	}
+	return json.Marshal(body)
+}
+
+// openAIContentToGemini 转换字符串或 content parts 为 Gemini parts，远程图片下载后内联
+func openAIContentToGemini(content gjson.Result) ([]map[string]any, error) {
+	parts := make([]map[string]any, 0)
+	if content.Type == gjson.String {
+		if text := content.String(); text != "" {
+			parts = append(parts, map[string]any{"text": text})
+		}
+		return parts, nil
+	}
+	for _, part := range content.Array() {
+		switch part.Get("type").String() {
+		case "text":
+			parts = append(parts, map[string]any{"text": part.Get("text").String()})
+		case "image_url":
+			p, err := geminiMediaPart(part.Get("image_url.url").String())
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, p)
+		case "input_audio":
+			parts = append(parts, map[string]any{"inlineData": map[string]any{
+				"mimeType": "audio/" + part.Get("input_audio.format").String(),
+				"data":     part.Get("input_audio.data").String(),
+			}})
+		case "file":
+			p, err := geminiMediaPart(part.Get("file.file_data").String())
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, p)
+		}
+	}
+	return parts, nil
+}
+
+// geminiMediaPart data URL 转为 inlineData，http(s) 地址下载后内联，其余 URI（如 gs://）使用 fileData
+func geminiMediaPart(url string) (map[string]any, error) {
+	if mediaType, data, ok := parseDataURL(url); ok {
+		return map[string]any{"inlineData": map[string]any{"mimeType": mediaType, "data": data}}, nil
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		fileData := map[string]any{"fileUri": url}
+		if mediaType := mime.TypeByExtension(path.Ext(url)); mediaType != "" {
+			fileData["mimeType"] = mediaType
+		}
+		return map[string]any{"fileData": fileData}, nil
+	}
+	mediaType, data, err := fetchMedia(url)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"inlineData": map[string]any{"mimeType": mediaType, "data": data}}, nil
+}
+
+// fetchMedia 下载远程媒体并返回 MIME 类型与 base64 内容
+func fetchMedia(url string) (string, string, error) {
+	res, err := mediaFetchClient.Get(url)
+	if err != nil {
+		return "", "", fmt.Errorf("fetch media: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("fetch media %s: status %d", url, res.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, geminiMaxInlineBytes+1))
+	if err != nil {
+		return "", "", fmt.Errorf("fetch media: %w", err)
+	}
+	if len(data) > geminiMaxInlineBytes {
+		return "", "", fmt.Errorf("fetch media %s: larger than %d bytes", url, geminiMaxInlineBytes)
+	}
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	return mediaType, base64.StdEncoding.EncodeToString(data), nil
+}
+
+func openAIFinishReasonFromGemini(reason string, toolCall bool) string {
+	if toolCall {
+		return "tool_calls"
+	}
+	switch normalizeFinishReason(reason) {
+	case FinishLength:
+		return "length"
+	case FinishContentFilter:
+		return "content_filter"
+	}
+	return "stop"
+}
+
+func openAIUsageFromGemini(usage gjson.Result) map[string]any {
+	completion := usage.Get("candidatesTokenCount").Int() + usage.Get("thoughtsTokenCount").Int()
+	return map[string]any{
+		"prompt_tokens":         usage.Get("promptTokenCount").Int(),
+		"completion_tokens":     completion,
+		"total_tokens":          usage.Get("totalTokenCount").Int(),
+		"prompt_tokens_details": map[string]any{"cached_tokens": usage.Get("cachedContentTokenCount").Int()},
+	}
+}
+
+// geminiToOpenAIResponse 将 Gemini generateContent 响应转换为 OpenAI Chat Completions 响应
+func geminiToOpenAIResponse(body io.ReadCloser, stream bool, model string) io.ReadCloser {
+	if stream {
+		return pipeTranslate(body, func(r io.Reader, w io.Writer) error {
+			return geminiStreamToOpenAI(r, w, model)
+		})
+	}
+	return pipeTranslate(body, func(r io.Reader, w io.Writer) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		res := gjson.ParseBytes(data)
+		if errMsg := res.Get("error"); errMsg.Exists() {
+			return json.NewEncoder(w).Encode(openAIError(errMsg))
+		}
+		choices := make([]map[string]any, 0)
+		for i, candidate := range res.Get("candidates").Array() {
+			texts := make([]string, 0)
+			thinking := make([]string, 0)
+			toolCalls := make([]map[string]any, 0)
+			for _, part := range candidate.Get("content.parts").Array() {
+				switch {
+				case part.Get("functionCall").Exists():
+					toolCalls = append(toolCalls, map[string]any{
+						"id":       fmt.Sprintf("call_%d", len(toolCalls)),
+						"type":     "function",
+						"function": map[string]any{"name": part.Get("functionCall.name").String(), "arguments": geminiArgs(part.Get("functionCall.args"))},
+					})
+				case part.Get("thought").Bool():
+					thinking = append(thinking, part.Get("text").String())
+				case part.Get("text").Exists():
+					texts = append(texts, part.Get("text").String())
+				}
+			}
+			message := map[string]any{"role": "assistant", "content": nil}
+			if len(texts) > 0 {
+				message["content"] = strings.Join(texts, "")
+			}
+			if len(thinking) > 0 {
+				message["reasoning_content"] = strings.Join(thinking, "")
+			}
+			if len(toolCalls) > 0 {
+				message["tool_calls"] = toolCalls
+			}
+			choices = append(choices, map[string]any{
+				"index":         i,
+				"message":       message,
+				"finish_reason": openAIFinishReasonFromGemini(candidate.Get("finishReason").String(), len(toolCalls) > 0),
+			})
+		}
+		return json.NewEncoder(w).Encode(map[string]any{
+			"id":      "chatcmpl-" + res.Get("responseId").String(),
+			"object":  "chat.completion",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": choices,
+			"usage":   openAIUsageFromGemini(res.Get("usageMetadata")),
+		})
+	})
+}
+
+// geminiStreamToOpenAI 将 Gemini SSE 分块转换为 OpenAI chunk 流，Gemini 没有结束事件，以 finishReason 判断完成
+func geminiStreamToOpenAI(r io.Reader, w io.Writer, model string) error {
+	sse := sseWriter{w: w}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, InitScannerBufferSize), MaxScannerBufferSize)
+
+	id := ""
+	created := time.Now().Unix()
+	started := false
+	toolIndex := 0
+	var finishReason string
+	var usage gjson.Result
+	chunk := func(delta map[string]any, finishReason any) map[string]any {
+		return map[string]any{
+			"id":      "chatcmpl-" + id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+	}
+
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		if !ok {
+			continue
+		}
+		event := gjson.Parse(strings.TrimSpace(data))
+		if errMsg := event.Get("error"); errMsg.Exists() {
+			return sse.event("", openAIError(errMsg))
+		}
+		if !started {
+			id = event.Get("responseId").String()
+			started = true
+			if err := sse.event("", chunk(map[string]any{"role": "assistant", "content": ""}, nil)); err != nil {
+				return err
+			}
+		}
+		if v := event.Get("usageMetadata"); v.Exists() {
+			usage = v
+		}
+		candidate := event.Get("candidates.0")
+		for _, part := range candidate.Get("content.parts").Array() {
+			var delta map[string]any
+			switch {
+			case part.Get("functionCall").Exists():
+				delta = map[string]any{"tool_calls": []map[string]any{{
+					"index":    toolIndex,
+					"id":       fmt.Sprintf("call_%d", toolIndex),
+					"type":     "function",
+					"function": map[string]any{"name": part.Get("functionCall.name").String(), "arguments": geminiArgs(part.Get("functionCall.args"))},
+				}}}
+				toolIndex++
+			case part.Get("thought").Bool():
+				delta = map[string]any{"reasoning_content": part.Get("text").String()}
+			case part.Get("text").Exists():
+				delta = map[string]any{"content": part.Get("text").String()}
+			default:
+				continue
+			}
+			if err := sse.event("", chunk(delta, nil)); err != nil {
+				return err
+			}
+		}
+		if reason := candidate.Get("finishReason").String(); reason != "" {
+			finishReason = reason
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if finishReason == "" {
+		return errors.New("upstream stream ended without finish reason")
+	}
+	if err := sse.event("", chunk(map[string]any{}, openAIFinishReasonFromGemini(finishReason, toolIndex > 0))); err != nil {
+		return err
+	}
+	usageChunk := chunk(nil, nil)
+	usageChunk["choices"] = []any{}
+	usageChunk["usage"] = openAIUsageFromGemini(usage)
+	if err := sse.event("", usageChunk); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "data: [DONE]\n\n")
+	return err
+}
+
+// geminiArgs 函数调用参数转为 OpenAI 的 JSON 字符串
+func geminiArgs(args gjson.Result) string {
+	if !args.Exists() {
+		return "{}"
+	}
+	return args.Raw
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// openAIToOllamaRequest 将 OpenAI Chat Completions 请求转换为 Ollama /api/chat 请求，Ollama 默认流式因此总是显式设置 stream
+func openAIToOllamaRequest(raw []byte, stream bool) ([]byte, error) {
+	if !gjson.ValidBytes(raw) {
+		return nil, errors.New("invalid openai request body")
+	}
+	req := gjson.ParseBytes(raw)
+	body := map[string]any{"stream": stream}
+
+	options := map[string]any{}
+	for key, option := range map[string]string{
+		"temperature":       "temperature",
+		"top_p":             "top_p",
+		"seed":              "seed",
+		"presence_penalty":  "presence_penalty",
+		"frequency_penalty": "frequency_penalty",
+	} {
+		if v := req.Get(key); v.Exists() && v.Type == gjson.Number {
+			options[option] = v.Value()
+		}
+	}
+	for _, key := range []string{"max_completion_tokens", "max_tokens"} {
+		if v := req.Get(key); v.Exists() && v.Int() > 0 {
+			options["num_predict"] = v.Int()
+			break
+		}
+	}
+	if v := req.Get("stop"); v.Exists() {
+		if v.Type == gjson.String {
+			options["stop"] = []string{v.String()}
+		} else {
+			options["stop"] = v.Value()
+		}
+	}
+	if len(options) > 0 {
+		body["options"] = options
+	}
+	switch format := req.Get("response_format"); format.Get("type").String() {
+	case "json_object":
+		body["format"] = "json"
+	case "json_schema":
+		body["format"] = "json"
+		if schema := format.Get("json_schema.schema"); schema.Exists() {
+			body["format"] = schema.Value()
+		}
+	}
+	if effort := req.Get("reasoning_effort"); effort.Exists() {
+		body["think"] = effort.String() != "none"
+	}
+
+	// tool 消息只有 tool_call_id，需要按此查找函数名
+	toolNames := map[string]string{}
+	messages := make([]map[string]any, 0)
+	var convErr error
+	req.Get("messages").ForEach(func(_, msg gjson.Result) bool {
+		role := msg.Get("role").String()
+		message := map[string]any{"role": role, "content": openAIText(msg.Get("content"))}
+		switch role {
+		case "developer":
+			message["role"] = "system"
+		case "tool":
+			message["tool_name"] = toolNames[msg.Get("tool_call_id").String()]
+		case "user":
+			images, err := ollamaImages(msg.Get("content"))
+			if err != nil {
+				convErr = err
+				return false
+			}
+			if len(images) > 0 {
+				message["images"] = images
+			}
+		case "assistant":
+			calls := make([]map[string]any, 0)
+			msg.Get("tool_calls").ForEach(func(_, call gjson.Result) bool {
+				name := call.Get("function.name").String()
+				toolNames[call.Get("id").String()] = name
+				calls = append(calls, map[string]any{
+					"function": map[string]any{"name": name, "arguments": jsonObject(call.Get("function.arguments").String())},
+				})
+				return true
+			})
+			if len(calls) > 0 {
+				message["tool_calls"] = calls
+			}
+		}
+		messages = append(messages, message)
+		return true
+	})
+	if convErr != nil {
+		return nil, convErr
+	}
+	body["messages"] = messages
+
+	// Ollama 不支持 tool_choice，none 时不下发工具，其余按 auto 处理
+	if tools := req.Get("tools"); tools.IsArray() && len(tools.Array()) > 0 && req.Get("tool_choice").String() != "none" {
+		body["tools"] = tools.Value()
+	}
+	return json.Marshal(body)
+}
+
+// ollamaImages 提取消息中的图片为 base64 列表，远程图片下载后内联
+func ollamaImages(content gjson.Result) ([]string, error) {
+	images := make([]string, 0)
+	for _, part := range content.Array() {
+		if part.Get("type").String() != "image_url" {
+			continue
+		}
+		url := part.Get("image_url.url").String()
+		if _, data, ok := parseDataURL(url); ok {
+			images = append(images, data)
+			continue
+		}
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, fmt.Errorf("unsupported image url for ollama: %s", url)
+		}
+		_, data, err := fetchMedia(url)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, data)
+	}
+	return images, nil
+}
+
+func openAIFinishReasonFromOllama(reason string, toolCall bool) string {
+	if toolCall {
+		return "tool_calls"
+	}
+	if reason == "length" {
+		return "length"
+	}
+	return "stop"
+}
+
+func openAIUsageFromOllama(res gjson.Result) map[string]any {
+	prompt, completion := res.Get("prompt_eval_count").Int(), res.Get("eval_count").Int()
+	return map[string]any{
+		"prompt_tokens":     prompt,
+		"completion_tokens": completion,
+		"total_tokens":      prompt + completion,
+	}
+}
+
+// ollamaToolCalls 转换 Ollama 工具调用，Ollama 不返回调用 ID，按序生成
+func ollamaToolCalls(calls gjson.Result, offset int) []map[string]any {
+	toolCalls := make([]map[string]any, 0)
+	for i, call := range calls.Array() {
+		toolCalls = append(toolCalls, map[string]any{
+			"index":    offset + i,
+			"id":       fmt.Sprintf("call_%d", offset+i),
+			"type":     "function",
+			"function": map[string]any{"name": call.Get("function.name").String(), "arguments": geminiArgs(call.Get("function.arguments"))},
+		})
+	}
+	return toolCalls
+}
+
+func ollamaCreated(res gjson.Result) int64 {
+	if created, err := time.Parse(time.RFC3339Nano, res.Get("created_at").String()); err == nil {
+		return created.Unix()
+	}
+	return time.Now().Unix()
+}
+
+// ollamaToOpenAIResponse 将 Ollama /api/chat 响应转换为 OpenAI Chat Completions 响应
+func ollamaToOpenAIResponse(body io.ReadCloser, stream bool, model string) io.ReadCloser {
+	if stream {
+		return pipeTranslate(body, func(r io.Reader, w io.Writer) error {
+			return ollamaStreamToOpenAI(r, w, model)
+		})
+	}
+	return pipeTranslate(body, func(r io.Reader, w io.Writer) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		res := gjson.ParseBytes(data)
+		if errMsg := res.Get("error"); errMsg.Exists() {
+			return json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": errMsg.String(), "type": "upstream_error"}})
+		}
+		message := map[string]any{"role": "assistant", "content": res.Get("message.content").String()}
+		if thinking := res.Get("message.thinking").String(); thinking != "" {
+			message["reasoning_content"] = thinking
+		}
+		toolCalls := ollamaToolCalls(res.Get("message.tool_calls"), 0)
+		if len(toolCalls) > 0 {
+			message["tool_calls"] = toolCalls
+		}
+		created := ollamaCreated(res)
+		return json.NewEncoder(w).Encode(map[string]any{
+			"id":      fmt.Sprintf("chatcmpl-ollama-%d", created),
+			"object":  "chat.completion",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"message":       message,
+				"finish_reason": openAIFinishReasonFromOllama(res.Get("done_reason").String(), len(toolCalls) > 0),
+			}},
+			"usage": openAIUsageFromOllama(res),
+		})
+	})
+}
+
+// ollamaStreamToOpenAI 将 Ollama 按行输出的 JSON 对象转换为 OpenAI chunk 流，以 done 为 true 的行判断完成
+func ollamaStreamToOpenAI(r io.Reader, w io.Writer, model string) error {
+	sse := sseWriter{w: w}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, InitScannerBufferSize), MaxScannerBufferSize)
+
+	id := ""
+	var created int64
+	started := false
+	toolIndex := 0
+	chunk := func(delta map[string]any, finishReason any) map[string]any {
+		return map[string]any{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !gjson.Valid(line) {
+			return fmt.Errorf("invalid ollama stream line: %s", line)
+		}
+		event := gjson.Parse(line)
+		if errMsg := event.Get("error"); errMsg.Exists() {
+			return sse.event("", map[string]any{"error": map[string]any{"message": errMsg.String(), "type": "upstream_error"}})
+		}
+		if !started {
+			created = ollamaCreated(event)
+			id = fmt.Sprintf("chatcmpl-ollama-%d", created)
+			started = true
+			if err := sse.event("", chunk(map[string]any{"role": "assistant", "content": ""}, nil)); err != nil {
+				return err
+			}
+		}
+		if thinking := event.Get("message.thinking").String(); thinking != "" {
+			if err := sse.event("", chunk(map[string]any{"reasoning_content": thinking}, nil)); err != nil {
+				return err
+			}
+		}
+		if content := event.Get("message.content").String(); content != "" {
+			if err := sse.event("", chunk(map[string]any{"content": content}, nil)); err != nil {
+				return err
+			}
+		}
+		if calls := ollamaToolCalls(event.Get("message.tool_calls"), toolIndex); len(calls) > 0 {
+			toolIndex += len(calls)
+			if err := sse.event("", chunk(map[string]any{"tool_calls": calls}, nil)); err != nil {
+				return err
+			}
+		}
+		if !event.Get("done").Bool() {
+			continue
+		}
+		if err := sse.event("", chunk(map[string]any{}, openAIFinishReasonFromOllama(event.Get("done_reason").String(), toolIndex > 0))); err != nil {
+			return err
+		}
+		usageChunk := chunk(nil, nil)
+		usageChunk["choices"] = []any{}
+		usageChunk["usage"] = openAIUsageFromOllama(event)
+		if err := sse.event("", usageChunk); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "data: [DONE]\n\n")
+		return err
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("upstream stream ended without done")
+}
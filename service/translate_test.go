@@ -0,0 +1,446 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/tidwall/gjson"
+)
+
+func TestAnthropicToOpenAIRequest(t *testing.T) {
+	raw := `{
+		"model": "claude",
+		"system": [{"type":"text","text":"be brief"}],
+		"max_tokens": 256,
+		"stop_sequences": ["END"],
+		"tools": [{"name":"get_weather","description":"weather","input_schema":{"type":"object"}}],
+		"tool_choice": {"type":"any"},
+		"messages": [
+			{"role":"user","content":"hi"},
+			{"role":"assistant","content":[{"type":"text","text":"calling"},{"type":"tool_use","id":"call_1","name":"get_weather","input":{"city":"nj"}}]},
+			{"role":"user","content":[{"type":"tool_result","tool_use_id":"call_1","content":"sunny"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAA"}}]}
+		]
+	}`
+	body, err := anthropicToOpenAIRequest([]byte(raw), true)
+	if err != nil {
+		t.Fatalf("anthropicToOpenAIRequest failed: %v", err)
+	}
+	res := gjson.ParseBytes(body)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"messages.0.role", "system"},
+		{"messages.0.content", "be brief"},
+		{"messages.1.content", "hi"},
+		{"messages.2.content", "calling"},
+		{"messages.2.tool_calls.0.function.arguments", `{"city":"nj"}`},
+		{"messages.3.role", "tool"},
+		{"messages.3.tool_call_id", "call_1"},
+		{"messages.3.content", "sunny"},
+		{"messages.4.content.0.image_url.url", "data:image/png;base64,AAA"},
+		{"max_tokens", "256"},
+		{"stop.0", "END"},
+		{"tools.0.function.name", "get_weather"},
+		{"tool_choice", "required"},
+		{"stream_options.include_usage", "true"},
+	}
+	for _, tt := range tests {
+		if got := res.Get(tt.path).String(); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAIStreamToAnthropic(t *testing.T) {
+	upstream := strings.Join([]string{
+		`data: {"id":"1","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`,
+		`data: {"id":"1","choices":[{"index":0,"delta":{"content":"lo"}}]}`,
+		`data: {"id":"1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"f","arguments":"{\"a\""}}]}}]}`,
+		`data: {"id":"1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":":1}"}}]},"finish_reason":"tool_calls"}]}`,
+		`data: {"id":"1","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":7,"total_tokens":19,"prompt_tokens_details":{"cached_tokens":2}}}`,
+		`data: [DONE]`,
+	}, "\n\n")
+	reader := openAIToAnthropicResponse(io.NopCloser(strings.NewReader(upstream)), true, "claude")
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read translated stream: %v", err)
+	}
+	text := string(out)
+	for _, want := range []string{
+		"event: message_start",
+		`"text":"Hel","type":"text_delta"`,
+		`"content_block":{"id":"call_1","input":{},"name":"f","type":"tool_use"}`,
+		`"partial_json":":1}"`,
+		`"stop_reason":"tool_use"`,
+		"event: message_stop",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("translated stream missing %s:\n%s", want, text)
+		}
+	}
+
+	log, _, err := ProcesserAnthropic(context.Background(), strings.NewReader(text), true, time.Now())
+	if err != nil {
+		t.Fatalf("ProcesserAnthropic failed: %v", err)
+	}
+	if log.PromptTokens != 12 || log.CompletionTokens != 7 || log.PromptTokensDetails.CachedTokens != 2 {
+		t.Fatalf("unexpected usage: %+v", log.Usage)
+	}
+}
+
+func TestOpenAIToAnthropicResponse(t *testing.T) {
+	upstream := `{"id":"1","choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"length"}],"usage":{"prompt_tokens":3,"completion_tokens":4}}`
+	out, err := io.ReadAll(openAIToAnthropicResponse(io.NopCloser(strings.NewReader(upstream)), false, "claude"))
+	if err != nil {
+		t.Fatalf("read translated body: %v", err)
+	}
+	res := gjson.ParseBytes(out)
+	if res.Get("content.0.text").String() != "hi" || res.Get("stop_reason").String() != "max_tokens" || res.Get("usage.output_tokens").Int() != 4 {
+		t.Fatalf("unexpected translated body: %s", out)
+	}
+}
+
+func TestOpenAIToAnthropicRequest(t *testing.T) {
+	raw := `{
+		"model": "gpt",
+		"temperature": 1.5,
+		"stop": "END",
+		"parallel_tool_calls": false,
+		"tools": [{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}],
+		"messages": [
+			{"role":"system","content":"be brief"},
+			{"role":"user","content":[{"type":"text","text":"hi"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAA"}}]},
+			{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"nj\"}"}}]},
+			{"role":"tool","tool_call_id":"call_1","content":"sunny"},
+			{"role":"user","content":"thanks"}
+		]
+	}`
+	body, err := openAIToAnthropicRequest([]byte(raw), false)
+	if err != nil {
+		t.Fatalf("openAIToAnthropicRequest failed: %v", err)
+	}
+	res := gjson.ParseBytes(body)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"system", "be brief"},
+		{"max_tokens", ""},
+		{"temperature", "1"},
+		{"stop_sequences.0", "END"},
+		{"messages.0.content.1.source.media_type", "image/png"},
+		{"messages.1.content.0.input.city", "nj"},
+		{"messages.2.content.0.type", "tool_result"},
+		{"messages.2.content.1.text", "thanks"},
+		{"messages.#", "3"},
+		{"tools.0.input_schema.type", "object"},
+		{"tool_choice.type", "auto"},
+		{"tool_choice.disable_parallel_tool_use", "true"},
+	}
+	for _, tt := range tests {
+		if got := res.Get(tt.path).String(); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestToolChoiceMapping(t *testing.T) {
+	tools := `"tools":[{"type":"function","function":{"name":"f","parameters":{"type":"object"}}}]`
+	anthropicTools := `"tools":[{"name":"f","input_schema":{"type":"object"}}]`
+	tests := []struct {
+		name      string
+		translate func([]byte, bool) ([]byte, error)
+		raw       string
+		path      string
+		want      string
+	}{
+		{"openai function to tool", openAIToAnthropicRequest, `{` + tools + `,"tool_choice":{"type":"function","function":{"name":"f"}}}`, "tool_choice.name", "f"},
+		{"openai flat function to tool", openAIToAnthropicRequest, `{` + tools + `,"tool_choice":{"type":"function","name":"f"}}`, "tool_choice.name", "f"},
+		{"openai required to any", openAIToAnthropicRequest, `{` + tools + `,"tool_choice":"required"}`, "tool_choice.type", "any"},
+		{"openai flat function to gemini", openAIToGeminiRequest, `{` + tools + `,"tool_choice":{"type":"function","name":"f"}}`, "toolConfig.functionCallingConfig.allowedFunctionNames.0", "f"},
+		{"anthropic tool to function", anthropicToOpenAIRequest, `{` + anthropicTools + `,"tool_choice":{"type":"tool","name":"f","disable_parallel_tool_use":true}}`, "tool_choice.function.name", "f"},
+		{"anthropic disable parallel", anthropicToOpenAIRequest, `{` + anthropicTools + `,"tool_choice":{"type":"auto","disable_parallel_tool_use":true}}`, "parallel_tool_calls", "false"},
+		{"anthropic choice without tools", anthropicToOpenAIRequest, `{"tool_choice":{"type":"any","disable_parallel_tool_use":true}}`, "parallel_tool_calls", ""},
+		{"anthropic choice without tools dropped", anthropicToOpenAIRequest, `{"tool_choice":{"type":"any"}}`, "tool_choice", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := tt.translate([]byte(tt.raw), false)
+			if err != nil {
+				t.Fatalf("translate failed: %v", err)
+			}
+			if got := gjson.GetBytes(body, tt.path).String(); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnthropicStreamToOpenAI(t *testing.T) {
+	upstream := strings.Join([]string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"m1\",\"usage\":{\"input_tokens\":10,\"cache_read_input_tokens\":4,\"output_tokens\":1}}}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"t1\",\"name\":\"f\",\"input\":{}}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{}\"}}",
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"tool_use\"},\"usage\":{\"output_tokens\":6}}",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+	}, "\n\n")
+	out, err := io.ReadAll(anthropicToOpenAIResponse(io.NopCloser(strings.NewReader(upstream)), true, "gpt"))
+	if err != nil {
+		t.Fatalf("read translated stream: %v", err)
+	}
+	text := string(out)
+	for _, want := range []string{`"content":"Hi"`, `"id":"t1"`, `"finish_reason":"tool_calls"`, "data: [DONE]"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("translated stream missing %s:\n%s", want, text)
+		}
+	}
+
+	log, _, err := ProcesserOpenAI(context.Background(), strings.NewReader(text), true, time.Now())
+	if err != nil {
+		t.Fatalf("ProcesserOpenAI failed: %v", err)
+	}
+	if log.PromptTokens != 14 || log.CompletionTokens != 6 || log.PromptTokensDetails.CachedTokens != 4 {
+		t.Fatalf("unexpected usage: %+v", log.Usage)
+	}
+}
+
+func TestOpenAIToGeminiRequest(t *testing.T) {
+	// 测试服务器监听回环地址，放开地址校验
+	defaultClient := mediaFetchClient
+	mediaFetchClient = newMediaFetchClient(func(netip.Addr) bool { return true })
+	t.Cleanup(func() { mediaFetchClient = defaultClient })
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("jpeg"))
+	}))
+	defer server.Close()
+
+	raw := `{
+		"model": "gemini",
+		"max_tokens": 128,
+		"stop": "END",
+		"response_format": {"type":"json_schema","json_schema":{"schema":{"type":"object"}}},
+		"tools": [{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}],
+		"tool_choice": {"type":"function","function":{"name":"get_weather"}},
+		"messages": [
+			{"role":"system","content":"be brief"},
+			{"role":"user","content":[
+				{"type":"text","text":"hi"},
+				{"type":"image_url","image_url":{"url":"data:image/png;base64,AAA"}},
+				{"type":"image_url","image_url":{"url":"` + server.URL + `/cat.jpg"}},
+				{"type":"image_url","image_url":{"url":"gs://bucket/dog.png"}}
+			]},
+			{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"nj\"}"}}]},
+			{"role":"tool","tool_call_id":"call_1","content":"sunny"},
+			{"role":"user","content":"thanks"}
+		]
+	}`
+	body, err := openAIToGeminiRequest([]byte(raw), false)
+	if err != nil {
+		t.Fatalf("openAIToGeminiRequest failed: %v", err)
+	}
+	res := gjson.ParseBytes(body)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"systemInstruction.parts.0.text", "be brief"},
+		{"contents.#", "3"},
+		{"contents.0.parts.1.inlineData.mimeType", "image/png"},
+		{"contents.0.parts.1.inlineData.data", "AAA"},
+		{"contents.0.parts.2.inlineData.mimeType", "image/jpeg"},
+		{"contents.0.parts.2.inlineData.data", base64.StdEncoding.EncodeToString([]byte("jpeg"))},
+		{"contents.0.parts.3.fileData.fileUri", "gs://bucket/dog.png"},
+		{"contents.0.parts.3.fileData.mimeType", "image/png"},
+		{"contents.1.role", "model"},
+		{"contents.1.parts.0.functionCall.args.city", "nj"},
+		{"contents.2.parts.0.functionResponse.name", "get_weather"},
+		{"contents.2.parts.1.text", "thanks"},
+		{"generationConfig.maxOutputTokens", "128"},
+		{"generationConfig.stopSequences.0", "END"},
+		{"generationConfig.responseJsonSchema.type", "object"},
+		{"tools.0.functionDeclarations.0.name", "get_weather"},
+		{"toolConfig.functionCallingConfig.mode", "ANY"},
+		{"toolConfig.functionCallingConfig.allowedFunctionNames.0", "get_weather"},
+	}
+	for _, tt := range tests {
+		if got := res.Get(tt.path).String(); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	missing := `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"` + server.URL + `/missing"}}]}]}`
+	server.Config.Handler = http.NotFoundHandler()
+	if _, err := openAIToGeminiRequest([]byte(missing), false); err == nil {
+		t.Fatal("expected error for unreachable image")
+	}
+}
+
+func TestFetchMediaRejectsPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	if _, _, err := fetchMedia(server.URL + "/latest/meta-data"); !errors.Is(err, errMediaAddressBlocked) {
+		t.Fatalf("loopback fetch err = %v, want errMediaAddressBlocked", err)
+	}
+
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestGeminiStreamToOpenAI(t *testing.T) {
+	upstream := strings.Join([]string{
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}],"responseId":"r1"}`,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"lo"},{"functionCall":{"name":"f","args":{"a":1}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":9,"candidatesTokenCount":5,"totalTokenCount":14}}`,
+	}, "\n\n")
+	out, err := io.ReadAll(geminiToOpenAIResponse(io.NopCloser(strings.NewReader(upstream)), true, "gemini"))
+	if err != nil {
+		t.Fatalf("read translated stream: %v", err)
+	}
+	text := string(out)
+	for _, want := range []string{`"content":"Hel"`, `"arguments":"{\"a\":1}"`, `"finish_reason":"tool_calls"`, "data: [DONE]"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("translated stream missing %s:\n%s", want, text)
+		}
+	}
+
+	log, _, err := ProcesserOpenAI(context.Background(), strings.NewReader(text), true, time.Now())
+	if err != nil {
+		t.Fatalf("ProcesserOpenAI failed: %v", err)
+	}
+	if log.PromptTokens != 9 || log.CompletionTokens != 5 {
+		t.Fatalf("unexpected usage: %+v", log.Usage)
+	}
+
+	body := `{"candidates":[{"content":{"parts":[{"text":"hi"}]},"finishReason":"MAX_TOKENS"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":4}}`
+	out, err = io.ReadAll(geminiToOpenAIResponse(io.NopCloser(strings.NewReader(body)), false, "gemini"))
+	if err != nil {
+		t.Fatalf("read translated body: %v", err)
+	}
+	res := gjson.ParseBytes(out)
+	if res.Get("choices.0.message.content").String() != "hi" || res.Get("choices.0.finish_reason").String() != "length" || res.Get("usage.completion_tokens").Int() != 4 {
+		t.Fatalf("unexpected translated body: %s", out)
+	}
+}
+
+func TestOpenAIToOllamaRequest(t *testing.T) {
+	raw := `{
+		"model": "llama",
+		"max_tokens": 64,
+		"temperature": 0.2,
+		"response_format": {"type":"json_object"},
+		"tools": [{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}],
+		"messages": [
+			{"role":"developer","content":"be brief"},
+			{"role":"user","content":[{"type":"text","text":"hi"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAA"}}]},
+			{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"nj\"}"}}]},
+			{"role":"tool","tool_call_id":"call_1","content":"sunny"}
+		]
+	}`
+	body, err := openAIToOllamaRequest([]byte(raw), true)
+	if err != nil {
+		t.Fatalf("openAIToOllamaRequest failed: %v", err)
+	}
+	res := gjson.ParseBytes(body)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"stream", "true"},
+		{"options.num_predict", "64"},
+		{"options.temperature", "0.2"},
+		{"format", "json"},
+		{"messages.0.role", "system"},
+		{"messages.1.content", "hi"},
+		{"messages.1.images.0", "AAA"},
+		{"messages.2.tool_calls.0.function.arguments.city", "nj"},
+		{"messages.3.tool_name", "get_weather"},
+		{"tools.0.function.name", "get_weather"},
+	}
+	for _, tt := range tests {
+		if got := res.Get(tt.path).String(); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := openAIToOllamaRequest([]byte(`{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"gs://bucket/a.png"}}]}]}`), false); err == nil {
+		t.Fatal("expected error for unsupported image url")
+	}
+}
+
+func TestOllamaStreamToOpenAI(t *testing.T) {
+	upstream := strings.Join([]string{
+		`{"model":"llama","created_at":"2026-01-02T03:04:05Z","message":{"role":"assistant","content":"Hel"},"done":false}`,
+		`{"model":"llama","created_at":"2026-01-02T03:04:05Z","message":{"role":"assistant","content":"lo","tool_calls":[{"function":{"name":"f","arguments":{"a":1}}}]},"done":false}`,
+		`{"model":"llama","created_at":"2026-01-02T03:04:06Z","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":9,"eval_count":5}`,
+	}, "\n")
+	out, err := io.ReadAll(ollamaToOpenAIResponse(io.NopCloser(strings.NewReader(upstream)), true, "llama"))
+	if err != nil {
+		t.Fatalf("read translated stream: %v", err)
+	}
+	text := string(out)
+	for _, want := range []string{`"content":"Hel"`, `"arguments":"{\"a\":1}"`, `"finish_reason":"tool_calls"`, "data: [DONE]"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("translated stream missing %s:\n%s", want, text)
+		}
+	}
+
+	log, _, err := ProcesserOpenAI(context.Background(), strings.NewReader(text), true, time.Now())
+	if err != nil {
+		t.Fatalf("ProcesserOpenAI failed: %v", err)
+	}
+	if log.PromptTokens != 9 || log.CompletionTokens != 5 {
+		t.Fatalf("unexpected usage: %+v", log.Usage)
+	}
+
+	// 流式聚合后按非流式转换
+	aggregated, err := aggregateStream(strings.NewReader(upstream), consts.StyleOllama)
+	if err != nil {
+		t.Fatalf("aggregateStream: %v", err)
+	}
+	out, err = io.ReadAll(ollamaToOpenAIResponse(io.NopCloser(strings.NewReader(string(aggregated))), false, "llama"))
+	if err != nil {
+		t.Fatalf("read translated body: %v", err)
+	}
+	res := gjson.ParseBytes(out)
+	if res.Get("choices.0.message.content").String() != "Hello" || res.Get("choices.0.finish_reason").String() != "tool_calls" || res.Get("usage.total_tokens").Int() != 14 {
+		t.Fatalf("unexpected translated body: %s", out)
+	}
+
+	if _, err := io.ReadAll(ollamaToOpenAIResponse(io.NopCloser(strings.NewReader(upstream[:strings.LastIndex(upstream, "\n")])), true, "llama")); err == nil {
+		t.Fatal("expected error for stream without done")
+	}
+}
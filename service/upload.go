@@ -0,0 +1,103 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+
+	"github.com/atopos31/llmio/providers"
+)
+
+// uploadMemoryLimit 上传请求体超过该大小时写入临时文件
+const uploadMemoryLimit = 8 << 20
+
+// Upload 按流转发的请求体，小文件保存在内存，大文件暂存到临时文件，每次重试都可重新读取
+type Upload struct {
+	ContentType string
+	data        []byte
+	file        *os.File
+	size        int64
+}
+
+// NewUpload 读取请求体，超过 uploadMemoryLimit 的部分写入临时文件
+func NewUpload(r io.Reader, contentType string) (*Upload, error) {
+	upload := &Upload{ContentType: contentType}
+	head, err := io.ReadAll(io.LimitReader(r, uploadMemoryLimit+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(head) <= uploadMemoryLimit {
+		upload.data = head
+		upload.size = int64(len(head))
+		return upload, nil
+	}
+
+	file, err := os.CreateTemp("", "llmio-upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("create upload spool: %w", err)
+	}
+	upload.file = file
+	n, err := io.Copy(file, io.MultiReader(bytes.NewReader(head), r))
+	if err != nil {
+		upload.Close()
+		return nil, fmt.Errorf("spool upload: %w", err)
+	}
+	upload.size = n
+	return upload, nil
+}
+
+// Reader 返回从头读取请求体的新 Reader
+func (u *Upload) Reader() io.Reader {
+	if u.file != nil {
+		return io.NewSectionReader(u.file, 0, u.size)
+	}
+	return bytes.NewReader(u.data)
+}
+
+func (u *Upload) Size() int64 {
+	return u.size
+}
+
+// Close 删除临时文件
+func (u *Upload) Close() error {
+	if u.file == nil {
+		return nil
+	}
+	u.file.Close()
+	return os.Remove(u.file.Name())
+}
+
+// NewImageFormBefore 从图片编辑/变体的 multipart 表单中解析模型参数，请求体不整体读入内存
+func NewImageFormBefore(upload *Upload) (*Before, error) {
+	_, params, err := mime.ParseMediaType(upload.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("parse content type: %w", err)
+	}
+	fields, err := multipartFields(upload.Reader(), params["boundary"], "model", "stream")
+	if err != nil {
+		return nil, err
+	}
+	if fields["model"] == "" {
+		return nil, errors.New("model is empty")
+	}
+	return &Before{
+		Model:           fields["model"],
+		Stream:          fields["stream"] == "true",
+		imageGeneration: true,
+		upload:          upload,
+	}, nil
+}
+
+// buildUploadReq 为支持表单上传的提供商构建按流转发的请求
+func buildUploadReq(ctx context.Context, chatModel providers.Provider, header http.Header, model string, upload *Upload) (*http.Request, error) {
+	builder, ok := chatModel.(providers.FormRequestBuilder)
+	if !ok {
+		return nil, errors.New("provider does not support multipart upload")
+	}
+	return builder.BuildFormReq(ctx, header, model, upload.Reader(), upload.ContentType)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"os"
+	"testing"
+)
+
+func TestUploadSpool(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("model", "gpt-image-1")
+	part, _ := writer.CreateFormFile("image", "large.png")
+	part.Write(bytes.Repeat([]byte{0x42}, uploadMemoryLimit))
+	writer.Close()
+	want := body.Bytes()
+
+	upload, err := NewUpload(bytes.NewReader(want), writer.FormDataContentType())
+	if err != nil {
+		t.Fatalf("NewUpload failed: %v", err)
+	}
+	if upload.file == nil || upload.data != nil {
+		t.Fatalf("large upload should be spooled to disk")
+	}
+	name := upload.file.Name()
+
+	before, err := NewImageFormBefore(upload)
+	if err != nil || before.Model != "gpt-image-1" || before.size() != len(want) {
+		t.Fatalf("NewImageFormBefore = %+v, %v", before, err)
+	}
+	// 每次读取都从头开始，保证重试时请求体完整
+	for range 2 {
+		got, err := io.ReadAll(upload.Reader())
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("reader returned %d bytes, err %v", len(got), err)
+		}
+	}
+
+	if err := upload.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Fatalf("spool file should be removed, stat err: %v", err)
+	}
+}
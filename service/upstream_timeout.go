@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+)
+
+// FirstByteTimeoutError 上游未在 ResponseHeaderTimeout 内返回响应头
+type FirstByteTimeoutError struct {
+	Provider string
+	Budget   time.Duration
+}
+
+func (e *FirstByteTimeoutError) Error() string {
+	return fmt.Sprintf("provider %s exceeded first-byte budget of %s", e.Provider, e.Budget)
+}
+
+// firstByteTimeout 将 net/http 的响应头超时转换为 FirstByteTimeoutError，其他错误原样返回
+func firstByteTimeout(err error, provider string, budget time.Duration) (error, bool) {
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() || !strings.Contains(err.Error(), "timeout awaiting response headers") {
+		return err, false
+	}
+	slog.Warn("upstream first byte timeout", "category", "timeout", "provider", provider, "budget", budget)
+	return &FirstByteTimeoutError{Provider: provider, Budget: budget}, true
+}
+
+// allFailedError 所有提供商均失败，最后一次失败为首字节超时时在错误中保留超时原因
+func allFailedError(lastStatus int, lastTimeout error, err error) *UpstreamError {
+	if lastTimeout != nil {
+		err = fmt.Errorf("%w (%v)", lastTimeout, err)
+	}
+	return &UpstreamError{Status: lastStatus, Err: err}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFirstByteTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{ResponseHeaderTimeout: 20 * time.Millisecond}}
+	_, err := client.Get(server.URL)
+	if err == nil {
+		t.Fatalf("expected response header timeout")
+	}
+	err, ok := firstByteTimeout(err, "slow", 20*time.Millisecond)
+	if !ok {
+		t.Fatalf("expected first byte timeout, got %v", err)
+	}
+	if err.Error() != "provider slow exceeded first-byte budget of 20ms" {
+		t.Fatalf("error = %q", err)
+	}
+
+	failed := allFailedError(0, err, errors.New("All retry failed, trace ID: abc"))
+	var timeoutErr *FirstByteTimeoutError
+	if !errors.As(failed, &timeoutErr) || timeoutErr.Provider != "slow" {
+		t.Fatalf("all failed error should wrap timeout: %v", failed)
+	}
+	if !strings.Contains(failed.Error(), "trace ID: abc") {
+		t.Fatalf("all failed error should keep trace ID: %v", failed)
+	}
+
+	other := errors.New("connection refused")
+	if got, ok := firstByteTimeout(other, "slow", time.Second); ok || got != other {
+		t.Fatalf("non-timeout error should pass through, got %v", got)
+	}
+}
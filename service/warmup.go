@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/atopos31/llmio/pkg/env"
+	"github.com/atopos31/llmio/providers"
+	"gorm.io/gorm"
+)
+
+// warming 正在预热的关联，预热完成前尽量不分配真实请求
+var warming sync.Map
+
+// warmUpRequests 关联启用或熔断恢复后发送的预热请求数，LLMIO_WARMUP_REQUESTS 为 0 时不预热
+func warmUpRequests() int {
+	return env.GetWithDefault("LLMIO_WARMUP_REQUESTS", 0)
+}
+
+// isWarming 关联是否正在预热
+func isWarming(id uint) bool {
+	_, ok := warming.Load(id)
+	return ok
+}
+
+// WarmUp 异步向关联发送预热请求，建立 TLS/HTTP2 连接并唤醒上游冷启动
+func WarmUp(id uint) {
+	count := warmUpRequests()
+	if count <= 0 {
+		return
+	}
+	if _, loaded := warming.LoadOrStore(id, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer warming.Delete(id)
+		ctx, cancel := context.WithTimeout(context.Background(), selftestTimeout)
+		defer cancel()
+		if err := warmUp(ctx, id, count); err != nil {
+			slog.Warn("warm up association", "model_provider_id", id, "error", err)
+			return
+		}
+		slog.Info("association warmed up", "model_provider_id", id, "requests", count)
+	}()
+}
+
+// WarmUpProvider 预热提供商下所有已启用的关联
+func WarmUpProvider(ctx context.Context, providerID uint) {
+	if warmUpRequests() <= 0 {
+		return
+	}
+	list, err := gorm.G[models.ModelWithProvider](models.DB).Where("provider_id = ?", providerID).Where("status = ?", true).Find(ctx)
+	if err != nil {
+		slog.Error("load associations for warm up", "provider_id", providerID, "error", err)
+		return
+	}
+	for _, mp := range list {
+		WarmUp(mp.ID)
+	}
+}
+
+// warmUp 交替发送非流式与流式请求，分别使用与真实请求相同的 HTTP 客户端以复用连接
+func warmUp(ctx context.Context, id uint, count int) error {
+	mp, err := gorm.G[models.ModelWithProvider](models.DB).Where("id = ?", id).First(ctx)
+	if err != nil {
+		return err
+	}
+	model, err := gorm.G[models.Model](models.DB).Where("id = ?", mp.ModelID).First(ctx)
+	if err != nil {
+		return err
+	}
+	provider, err := gorm.G[models.Provider](models.DB).Where("id = ?", mp.ProviderID).First(ctx)
+	if err != nil {
+		return err
+	}
+	chatModel, err := providers.New(ctx, provider.Type, provider.Config, provider.ClientOptions())
+	if err != nil {
+		return err
+	}
+	for i := range count {
+		stream := i%2 == 1
+		name := "chat"
+		if stream {
+			name = "stream"
+		}
+		body, ok := selftestBody(provider.Type, name)
+		if !ok {
+			return fmt.Errorf("provider type %s does not support warm up", provider.Type)
+		}
+		reqCtx := ctx
+		if provider.Type == consts.StyleGemini {
+			reqCtx = context.WithValue(ctx, consts.ContextKeyGeminiStream, stream)
+		}
+		header := BuildHeaders(nil, false, MergeProviderHeaders(provider, mp.CustomerHeaders), stream, provider.UserAgent)
+		req, err := chatModel.BuildReq(reqCtx, header, mp.ProviderModel, body)
+		if err != nil {
+			return err
+		}
+		ApplyQueryParams(req, provider.QueryParams)
+		timeout := time.Second * time.Duration(model.TimeOut)
+		if stream {
+			timeout = timeout / 3
+		}
+		res, err := providers.GetClient(timeout, provider.ClientOptions()).Do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("status: %d", res.StatusCode)
+		}
+	}
+	return nil
+}
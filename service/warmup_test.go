@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/tidwall/gjson"
+)
+
+func TestWarmUp(t *testing.T) {
+	requests := make(chan bool, 8)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		stream := gjson.GetBytes(body, "stream").Bool()
+		requests <- stream
+		if stream {
+			io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"yes\"}}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+		io.WriteString(w, `{"choices":[{"message":{"content":"yes"}}]}`)
+	}))
+	defer upstream.Close()
+
+	db := setupTestDB(t, &models.Provider{}, &models.Model{}, &models.ModelWithProvider{})
+
+	provider := models.Provider{Name: "openai", Type: consts.StyleOpenAI, Config: `{"base_url":"` + upstream.URL + `/v1","api_key":"k"}`}
+	db.Create(&provider)
+	model := models.Model{Name: "gpt", TimeOut: 30}
+	db.Create(&model)
+	mp := models.ModelWithProvider{ModelID: model.ID, ProviderID: provider.ID, ProviderModel: "gpt-4.1", Status: new(true)}
+	db.Create(&mp)
+
+	// 未配置预热请求数时不发送
+	WarmUp(mp.ID)
+	if isWarming(mp.ID) {
+		t.Fatal("warm up should be disabled by default")
+	}
+
+	t.Setenv("LLMIO_WARMUP_REQUESTS", "2")
+	WarmUp(mp.ID)
+	// 非流式与流式请求交替发送
+	for i, want := range []bool{false, true} {
+		select {
+		case stream := <-requests:
+			if stream != want {
+				t.Fatalf("warm up request %d stream = %v, want %v", i, stream, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("warm up request not received")
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for isWarming(mp.ID) {
+		if time.Now().After(deadline) {
+			t.Fatal("association still warming")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := warmUp(context.Background(), 999, 1); err == nil {
+		t.Fatal("warmUp should fail for unknown association")
+	}
+}
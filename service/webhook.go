@@ -0,0 +1,92 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+// webhookTimeout 单次回调的超时时间
+const webhookTimeout = 10 * time.Second
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// WebhookPayload 请求完成后发送到模型回调地址的内容，记录 IO 时附带完整输入输出
+type WebhookPayload struct {
+	MirrorRecord
+	Input        string   `json:"input,omitempty"`
+	Output       string   `json:"output,omitempty"`        // 非流式响应正文
+	OutputChunks []string `json:"output_chunks,omitempty"` // 流式响应的各个数据块
+}
+
+// dispatchWebhook 异步发送 webhook，避免慢速接收方占用日志写入名额
+func dispatchWebhook(ctx context.Context, webhook string, logID uint, before *Before, ioLog bool) {
+	if webhook == "" {
+		return
+	}
+	go sendWebhook(ctx, webhook, logID, before, ioLog)
+}
+
+// sendWebhook 读取日志最终状态并 POST 到模型的回调地址，未配置时不做任何操作
+func sendWebhook(ctx context.Context, webhook string, logID uint, before *Before, ioLog bool) {
+	if webhook == "" {
+		return
+	}
+	payload, err := buildWebhookPayload(ctx, logID, before, ioLog)
+	if err != nil {
+		slog.Error("build webhook payload", "logId", logID, "error", err)
+		return
+	}
+	if err := postWebhook(ctx, webhook, payload); err != nil {
+		slog.Warn("send webhook", "logId", logID, "webhook", webhook, "error", err)
+	}
+}
+
+func buildWebhookPayload(ctx context.Context, logID uint, before *Before, ioLog bool) (*WebhookPayload, error) {
+	log, err := gorm.G[models.ChatLog](models.DB).Omit("timeline").Where("id = ?", logID).First(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payload := &WebhookPayload{MirrorRecord: newMirrorRecord(log, before)}
+	if !ioLog {
+		return payload, nil
+	}
+	chatIO, err := gorm.G[models.ChatIO](models.DB).Where("log_id = ?", logID).First(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load chat io: %w", err)
+	}
+	payload.Input = chatIO.Input
+	payload.Output = chatIO.OfString
+	payload.OutputChunks = chatIO.OfStringArray
+	return payload, nil
+}
+
+func postWebhook(ctx context.Context, webhook string, payload *WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "llmio/"+consts.Version)
+	res, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook returned status %d", res.StatusCode)
+	}
+	return nil
+}
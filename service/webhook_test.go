@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+)
+
+func TestSendWebhook(t *testing.T) {
+	db := setupTestDB(t, &models.ChatLog{}, &models.ChatIO{})
+
+	log := models.ChatLog{Name: "gpt-4o", ProviderName: "openai", Status: consts.StatusSuccess, Usage: models.Usage{TotalTokens: 42}}
+	if err := db.Create(&log).Error; err != nil {
+		t.Fatalf("create log: %v", err)
+	}
+	if err := db.Create(&models.ChatIO{LogId: log.ID, Input: `{"model":"gpt-4o"}`, OutputUnion: models.OutputUnion{OfStringArray: []string{"data: a", "data: b"}}}).Error; err != nil {
+		t.Fatalf("create chat io: %v", err)
+	}
+
+	received := make(chan WebhookPayload, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		ioLog      bool
+		wantInput  string
+		wantChunks int
+	}{
+		{"summary only", false, "", 0},
+		{"full output with io log", true, `{"model":"gpt-4o"}`, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sendWebhook(context.Background(), server.URL, log.ID, &Before{Stream: true}, tt.ioLog)
+			got := <-received
+			if got.LogID != log.ID || got.Model != "gpt-4o" || got.TotalTokens != 42 || !got.Stream {
+				t.Fatalf("unexpected summary: %+v", got.MirrorRecord)
+			}
+			if got.Input != tt.wantInput || len(got.OutputChunks) != tt.wantChunks {
+				t.Fatalf("input = %q, chunks = %v", got.Input, got.OutputChunks)
+			}
+		})
+	}
+
+	// 未配置回调地址时不发送
+	sendWebhook(context.Background(), "", log.ID, nil, true)
+	if len(received) != 0 {
+		t.Fatal("webhook sent without url")
+	}
+
+	// 所有重试失败时为最后一次尝试发送
+	for _, terminal := range []bool{false, true} {
+		retryLog := make(chan models.ChatLog, 2)
+		var failed atomic.Bool
+		failed.Store(terminal)
+		retryLog <- models.ChatLog{Name: "gpt-4o", ProviderName: "a", Status: consts.StatusError}
+		retryLog <- models.ChatLog{Name: "gpt-4o", ProviderName: "b", Status: consts.StatusError}
+		close(retryLog)
+		RecordRetryLog(context.Background(), retryLog, &Before{}, server.URL, &failed)
+		if !terminal {
+			continue
+		}
+		select {
+		case got := <-received:
+			if got.ProviderName != "b" || got.Status != consts.StatusError {
+				t.Fatalf("unexpected failure webhook: %+v", got.MirrorRecord)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no webhook for terminal failure")
+		}
+	}
+	if len(received) != 0 {
+		t.Fatal("webhook sent for a recovered request")
+	}
+}
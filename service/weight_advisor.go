@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAdviceDays = 7
+	adviceMinSamples  = 10 // 样本不足时保持原权重
+	adviceBaseWeight  = 10
+)
+
+// WeightAdvice 单个关联的权重建议
+type WeightAdvice struct {
+	ModelProviderID uint    `json:"model_provider_id"`
+	ProviderName    string  `json:"provider_name"`
+	ProviderModel   string  `json:"provider_model"`
+	Requests        int64   `json:"requests"`
+	SuccessRate     float64 `json:"success_rate"`
+	AvgLatencyMs    float64 `json:"avg_latency_ms"`
+	AvgCost         float64 `json:"avg_cost"`
+	CurrentWeight   int     `json:"current_weight"`
+	SuggestedWeight int     `json:"suggested_weight"`
+	Reason          string  `json:"reason"`
+	Remark          string  `json:"remark,omitempty"`
+}
+
+// WeightImpact 按权重分配流量后的预期指标
+type WeightImpact struct {
+	SuccessRate  float64 `json:"success_rate"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	AvgCost      float64 `json:"avg_cost"`
+}
+
+// WeightAdviceReport 模型权重调整建议
+type WeightAdviceReport struct {
+	ModelID      uint           `json:"model_id"`
+	ModelName    string         `json:"model_name"`
+	Days         int            `json:"days"`
+	Associations []WeightAdvice `json:"associations"`
+	Current      WeightImpact   `json:"current"`
+	Expected     WeightImpact   `json:"expected"`
+}
+
+type associationStat struct {
+	ProviderName  string
+	ProviderModel string
+	Requests      int64
+	Successes     int64
+	Latency       float64 // 成功请求的总耗时之和，纳秒
+	Cost          float64 // 成功请求的费用之和
+}
+
+// AdviseWeights 根据近期成功率、延迟与成本为模型的已启用关联给出权重建议
+func AdviseWeights(ctx context.Context, modelID uint, days int) (*WeightAdviceReport, error) {
+	if days <= 0 {
+		days = defaultAdviceDays
+	}
+	model, err := gorm.G[models.Model](models.DB).Where("id = ?", modelID).First(ctx)
+	if err != nil {
+		return nil, err
+	}
+	associations, err := gorm.G[models.ModelWithProvider](models.DB).
+		Where("model_id = ?", modelID).
+		Where("status = ?", true).
+		Where("slot = ?", ActiveSlot()).
+		Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	providers, err := gorm.G[models.Provider](models.DB).
+		Where("id IN ?", lo.Map(associations, func(mp models.ModelWithProvider, _ int) uint { return mp.ProviderID })).
+		Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	providerNames := lo.SliceToMap(providers, func(p models.Provider) (uint, string) { return p.ID, p.Name })
+
+	var rows []associationStat
+	if err := models.ReadDB().WithContext(ctx).Model(&models.ChatLog{}).
+		Select(fmt.Sprintf(`provider_name, provider_model, COUNT(*) AS requests,
+			SUM(CASE WHEN status = @success THEN 1 ELSE 0 END) AS successes,
+			SUM(CASE WHEN status = @success THEN first_chunk_time + chunk_time ELSE 0 END) AS latency,
+			SUM(CASE WHEN status = @success THEN %s ELSE 0 END) AS cost`, logCostSQL), sql.Named("success", consts.StatusSuccess)).
+		Where("name = ?", model.Name).
+		Where("created_at >= ?", time.Now().AddDate(0, 0, -days)).
+		Where("status NOT IN ?", consts.HealthExcludedStatuses).
+		Group("provider_name, provider_model").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("aggregate logs: %w", err)
+	}
+	stats := lo.SliceToMap(rows, func(s associationStat) (string, associationStat) { return s.ProviderName + "/" + s.ProviderModel, s })
+
+	report := &WeightAdviceReport{
+		ModelID:      model.ID,
+		ModelName:    model.Name,
+		Days:         days,
+		Associations: make([]WeightAdvice, 0, len(associations)),
+	}
+	for _, mp := range associations {
+		name := providerNames[mp.ProviderID]
+		advice := WeightAdvice{
+			ModelProviderID: mp.ID,
+			ProviderName:    name,
+			ProviderModel:   mp.ProviderModel,
+			CurrentWeight:   mp.Weight,
+			Remark:          mp.Remark,
+		}
+		if stat, ok := stats[name+"/"+mp.ProviderModel]; ok {
+			advice.Requests = stat.Requests
+			advice.SuccessRate = float64(stat.Successes) / float64(stat.Requests)
+			if stat.Successes > 0 {
+				advice.AvgLatencyMs = stat.Latency / float64(stat.Successes) / float64(time.Millisecond)
+				advice.AvgCost = stat.Cost / float64(stat.Successes)
+			}
+		}
+		report.Associations = append(report.Associations, advice)
+	}
+	suggestWeights(report.Associations)
+	report.Current = weightImpact(report.Associations, func(a WeightAdvice) int { return a.CurrentWeight })
+	report.Expected = weightImpact(report.Associations, func(a WeightAdvice) int { return a.SuggestedWeight })
+	return report, nil
+}
+
+// ErrInvalidWeightAdvice 应用的权重包含不属于该模型的关联或负数权重
+var ErrInvalidWeightAdvice = errors.New("weights must be non-negative and belong to the model")
+
+// ApplyWeightAdvice 写入管理员预览确认的权重，weights 为关联 ID 到权重的映射
+// 不重新计算建议，避免预览与应用之间的新日志改变结果
+func ApplyWeightAdvice(ctx context.Context, modelID uint, weights map[uint]int) error {
+	if _, err := gorm.G[models.Model](models.DB).Where("id = ?", modelID).First(ctx); err != nil {
+		return err
+	}
+	if len(weights) == 0 {
+		return ErrInvalidWeightAdvice
+	}
+	err := models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for id, weight := range weights {
+			if weight < 0 {
+				return ErrInvalidWeightAdvice
+			}
+			result := tx.Model(&models.ModelWithProvider{}).Where("id = ? AND model_id = ?", id, modelID).Update("weight", weight)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrInvalidWeightAdvice
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	InvalidateRouteCache()
+	return nil
+}
+
+// suggestWeights 样本充足的非备用关联按 成功率² × 相对延迟 × √相对成本 打分，并按分数重新分配原有权重总和
+func suggestWeights(advices []WeightAdvice) {
+	var minLatency, minCost float64
+	total := 0
+	scored := 0
+	for _, a := range advices {
+		if !scoreable(a) {
+			continue
+		}
+		scored++
+		total += a.CurrentWeight
+		if a.AvgLatencyMs > 0 && (minLatency == 0 || a.AvgLatencyMs < minLatency) {
+			minLatency = a.AvgLatencyMs
+		}
+		if a.AvgCost > 0 && (minCost == 0 || a.AvgCost < minCost) {
+			minCost = a.AvgCost
+		}
+	}
+	if total == 0 {
+		total = adviceBaseWeight * scored
+	}
+
+	scores := make([]float64, len(advices))
+	var scoreSum float64
+	for i, a := range advices {
+		if !scoreable(a) {
+			continue
+		}
+		score := a.SuccessRate * a.SuccessRate
+		if a.AvgLatencyMs > 0 {
+			score *= minLatency / a.AvgLatencyMs
+		}
+		if a.AvgCost > 0 {
+			score *= math.Sqrt(minCost / a.AvgCost)
+		}
+		scores[i] = score
+		scoreSum += score
+	}
+
+	for i := range advices {
+		a := &advices[i]
+		switch {
+		case a.CurrentWeight <= 0:
+			a.SuggestedWeight = a.CurrentWeight
+			a.Reason = "standby"
+		case a.Requests < adviceMinSamples:
+			a.SuggestedWeight = a.CurrentWeight
+			a.Reason = "insufficient samples"
+		case scoreSum == 0:
+			a.SuggestedWeight = a.CurrentWeight
+			a.Reason = "no successful requests"
+		case a.SuccessRate == 0:
+			a.SuggestedWeight = 0
+			a.Reason = "all requests failed"
+		default:
+			a.SuggestedWeight = max(1, int(math.Round(float64(total)*scores[i]/scoreSum)))
+			a.Reason = "scored by success rate, latency and cost"
+		}
+	}
+}
+
+// scoreable 备用关联（权重为 0）保持备用，不参与打分
+func scoreable(a WeightAdvice) bool {
+	return a.Requests >= adviceMinSamples && a.CurrentWeight > 0
+}
+
+func weightImpact(advices []WeightAdvice, weight func(WeightAdvice) int) WeightImpact {
+	var impact WeightImpact
+	var total float64
+	for _, a := range advices {
+		w := float64(weight(a))
+		if w <= 0 || a.Requests == 0 {
+			continue
+		}
+		total += w
+		impact.SuccessRate += w * a.SuccessRate
+		impact.AvgLatencyMs += w * a.AvgLatencyMs
+		impact.AvgCost += w * a.AvgCost
+	}
+	if total == 0 {
+		return impact
+	}
+	impact.SuccessRate /= total
+	impact.AvgLatencyMs /= total
+	impact.AvgCost /= total
+	return impact
+}
+
+// logCachedTokensSQL 读取 prompt_tokens_details 中的缓存 token 数，旧数据为空时按 0 计
+const logCachedTokensSQL = "CASE WHEN json_valid(prompt_tokens_details) THEN COALESCE(json_extract(prompt_tokens_details, '$.cached_tokens'), 0) ELSE 0 END"
+
+// logCostSQL 与 logCost 相同的单次请求费用计算，用于在数据库中聚合
+var logCostSQL = fmt.Sprintf("COALESCE(cost, ((prompt_tokens - %[1]s) * COALESCE(input_price, 0) + %[1]s * COALESCE(cache_read_price, 0) + completion_tokens * COALESCE(output_price, 0)) / 1e6)", logCachedTokensSQL)
+
+// logCost 按每百万 token 单价计算单次请求费用，上游返回费用时以其为准
+func logCost(log models.ChatLog) float64 {
+	if log.Cost != nil {
+		return *log.Cost
+	}
+	cached := log.PromptTokensDetails.CachedTokens
+	input := log.PromptTokens - cached
+	return (float64(input)*log.InputPrice + float64(cached)*log.CacheReadPrice + float64(log.CompletionTokens)*log.OutputPrice) / 1e6
+}
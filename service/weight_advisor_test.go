@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/atopos31/llmio/consts"
+	"github.com/atopos31/llmio/models"
+	"gorm.io/gorm"
+)
+
+func TestSuggestWeights(t *testing.T) {
+	advices := []WeightAdvice{
+		{ModelProviderID: 1, Requests: 100, SuccessRate: 1, AvgLatencyMs: 1000, AvgCost: 0.01, CurrentWeight: 5},
+		{ModelProviderID: 2, Requests: 100, SuccessRate: 0.5, AvgLatencyMs: 2000, AvgCost: 0.01, CurrentWeight: 5},
+		{ModelProviderID: 3, Requests: 20, SuccessRate: 0, CurrentWeight: 3},
+		{ModelProviderID: 4, Requests: 2, SuccessRate: 1, CurrentWeight: 7},
+		{ModelProviderID: 5, Requests: 50, SuccessRate: 1, AvgLatencyMs: 500, CurrentWeight: 0},
+	}
+	suggestWeights(advices)
+
+	tests := []struct {
+		id   uint
+		want int
+	}{
+		{1, 12}, // 13 * 1 / (1 + 0.125)
+		{2, 1},
+		{3, 0},
+		{4, 7},
+		{5, 0},
+	}
+	for i, tt := range tests {
+		if got := advices[i].SuggestedWeight; got != tt.want {
+			t.Errorf("association %d suggested weight = %d, want %d", tt.id, got, tt.want)
+		}
+	}
+
+	current := weightImpact(advices, func(a WeightAdvice) int { return a.CurrentWeight })
+	expected := weightImpact(advices, func(a WeightAdvice) int { return a.SuggestedWeight })
+	if expected.SuccessRate <= current.SuccessRate {
+		t.Errorf("expected success rate %.2f to improve over %.2f", expected.SuccessRate, current.SuccessRate)
+	}
+}
+
+func TestAdviseWeightsAggregates(t *testing.T) {
+	db := setupTestDB(t, &models.Model{}, &models.Provider{}, &models.ModelWithProvider{}, &models.ChatLog{})
+
+	model := models.Model{Name: "gpt"}
+	provider := models.Provider{Name: "p", Type: consts.StyleOpenAI}
+	if err := db.Create(&model).Error; err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+	if err := db.Create(&provider).Error; err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	mp := models.ModelWithProvider{ModelID: model.ID, ProviderID: provider.ID, ProviderModel: "gpt-4o", Weight: 5, Status: new(true), Slot: ActiveSlot()}
+	if err := db.Create(&mp).Error; err != nil {
+		t.Fatalf("create association: %v", err)
+	}
+
+	logs := []models.ChatLog{
+		// 按单价计费：(1000-400)*2 + 400*1 + 500*4 = 3600 / 1e6
+		{Name: "gpt", ProviderName: "p", ProviderModel: "gpt-4o", Status: consts.StatusSuccess, FirstChunkTime: time.Second, ChunkTime: time.Second,
+			Usage: models.Usage{PromptTokens: 1000, CompletionTokens: 500, PromptTokensDetails: models.PromptTokensDetails{CachedTokens: 400}}, InputPrice: 2, CacheReadPrice: 1, OutputPrice: 4},
+		// 上游返回的费用优先
+		{Name: "gpt", ProviderName: "p", ProviderModel: "gpt-4o", Status: consts.StatusSuccess, ChunkTime: 4 * time.Second, Cost: new(0.01)},
+		{Name: "gpt", ProviderName: "p", ProviderModel: "gpt-4o", Status: consts.StatusError, FirstChunkTime: time.Minute},
+		{Name: "gpt", ProviderName: "p", ProviderModel: "gpt-4o", Status: consts.StatusCanceled},
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("create logs: %v", err)
+	}
+
+	report, err := AdviseWeights(context.Background(), model.ID, 1)
+	if err != nil {
+		t.Fatalf("AdviseWeights failed: %v", err)
+	}
+	if len(report.Associations) != 1 {
+		t.Fatalf("unexpected associations: %+v", report.Associations)
+	}
+	a := report.Associations[0]
+	if a.Requests != 3 || a.SuccessRate != 2.0/3 || a.AvgLatencyMs != 3000 {
+		t.Fatalf("unexpected stats: %+v", a)
+	}
+	if want := (0.0036 + 0.01) / 2; math.Abs(a.AvgCost-want) > 1e-12 {
+		t.Fatalf("avg cost = %v, want %v", a.AvgCost, want)
+	}
+}
+
+func TestApplyWeightAdvice(t *testing.T) {
+	db := setupTestDB(t, &models.Model{}, &models.ModelWithProvider{})
+	ctx := context.Background()
+
+	model := models.Model{Name: "gpt"}
+	if err := db.Create(&model).Error; err != nil {
+		t.Fatalf("create model: %v", err)
+	}
+	associations := []models.ModelWithProvider{
+		{ModelID: model.ID, ProviderID: 1, Weight: 5, Status: new(true)},
+		{ModelID: model.ID, ProviderID: 2, Weight: 5, Status: new(true)},
+		{ModelID: model.ID + 1, ProviderID: 3, Weight: 5, Status: new(true)},
+	}
+	if err := db.Create(&associations).Error; err != nil {
+		t.Fatalf("create associations: %v", err)
+	}
+
+	if err := ApplyWeightAdvice(ctx, model.ID, map[uint]int{associations[0].ID: 9, associations[1].ID: 1}); err != nil {
+		t.Fatalf("ApplyWeightAdvice failed: %v", err)
+	}
+	// 其他模型的关联与负数权重被拒绝，整批不生效
+	for _, weights := range []map[uint]int{
+		{associations[0].ID: 2, associations[2].ID: 2},
+		{associations[0].ID: -1},
+		{},
+	} {
+		if err := ApplyWeightAdvice(ctx, model.ID, weights); !errors.Is(err, ErrInvalidWeightAdvice) {
+			t.Fatalf("weights %v: expected ErrInvalidWeightAdvice, got %v", weights, err)
+		}
+	}
+	if err := ApplyWeightAdvice(ctx, model.ID+10, map[uint]int{associations[0].ID: 1}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected model not found, got %v", err)
+	}
+
+	var got []models.ModelWithProvider
+	db.Order("id").Find(&got)
+	if got[0].Weight != 9 || got[1].Weight != 1 || got[2].Weight != 5 {
+		t.Fatalf("unexpected weights: %d %d %d", got[0].Weight, got[1].Weight, got[2].Weight)
+	}
+}
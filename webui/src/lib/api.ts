@@ -10,6 +10,67 @@ export interface Provider {
   Console: string;
   Proxy: string;
   ErrorMatcher: string;
+  ErrorClasses?: ErrorClassRule[] | null;
+  CostHeaders?: string;
+  UserAgent?: string;
+  QuotaGroup?: string;
+  RetireState?: string;
+  RetireStartedAt?: string | null;
+  RetireObserveDays?: number;
+  RetiredAt?: string | null;
+  AuthDisabledAt?: string | null;
+  AuthDisabledReason?: string;
+  RateLimit?: RateLimit;
+  TLS?: ProviderTLS;
+  Budget?: ProviderBudget;
+  Headers?: Record<string, string> | null;
+  QueryParams?: Record<string, string> | null;
+  DNSServer?: string;
+  HostOverrides?: Record<string, string> | null;
+  ConfigVersion?: number;
+  Balance?: number | null;
+  BalanceCurrency?: string;
+  BalanceCheckedAt?: string | null;
+}
+
+// Statuses and samples must both match when both are set; an empty action uses the class default
+export interface ErrorClassRule {
+  class: 'quota_exhausted' | 'auth_failed' | 'content_filter' | 'transient';
+  statuses?: number[] | null;
+  samples?: string[] | null;
+  action?: '' | 'reduce_weight' | 'open_breaker' | 'disable_key' | 'fail_fast';
+}
+
+export interface ProviderTLS {
+  ca_cert: string;
+  insecure_skip_verify: boolean;
+}
+
+export interface ProviderBudget {
+  period: '' | 'day' | 'month';
+  tokens: number;
+  cost: number;
+}
+
+export interface ProviderBudgetUsage {
+  provider_id: number;
+  period: 'day' | 'month';
+  tokens: number;
+  cost: number;
+  token_limit: number;
+  cost_limit: number;
+  exhausted: boolean;
+  reset_at: string;
+}
+
+export interface ModelProviderStatus {
+  recent: boolean[];
+  budget: ProviderBudgetUsage | null;
+}
+
+export interface RateLimit {
+  rpm: number;
+  mode: '' | 'enforce' | 'observe';
 }
 
 export interface Model {
@@ -18,9 +79,64 @@ export interface Model {
   Remark: string;
   MaxRetry: number;
   TimeOut: number;
+  RetryReserve?: number;
+  StickyMinutes?: number;
+  HedgeAfter?: number;
+  StreamBuffer?: number;
+  Webhook?: string;
+  ContextLength?: number;
   Strategy: string;
   Breaker?: boolean | null;
+  ValidateResponse?: boolean | null;
+  RawPassthrough?: boolean | null;
   DisplayOrder?: number;
+  ParamRanges?: ParamRanges | null;
+  Deprecation?: Deprecation | null;
+  IOLogPolicy?: IOLogPolicy | null;
+  ResponseGuard?: ResponseGuard | null;
+  BreakerThresholds?: BreakerThresholds | null;
+  RetryPolicy?: RetryPolicy | null;
+}
+
+// Backoff values are milliseconds; an empty retry_on retries every upstream status
+export interface RetryPolicy {
+  backoff: number;
+  max_backoff: number;
+  retry_on: number[] | null;
+  retry_after: boolean;
+}
+
+// Zero or missing fields fall back to the default breaker thresholds
+export interface BreakerThresholds {
+  max_failures: number;
+  sleep_window: number;
+  max_requests: number;
+}
+
+export interface ResponseGuard {
+  blocklist: string[] | null;
+}
+
+export interface IOLogPolicy {
+  sample_rate: number | null;
+  redact: string[] | null;
+}
+
+export interface Deprecation {
+  message: string;
+  sunset_at: string | null;
+}
+
+export interface ParamRange {
+  min: number | null;
+  max: number | null;
+}
+
+export interface ParamRanges {
+  mode: '' | 'clamp' | 'reject';
+  temperature: ParamRange | null;
+  top_p: ParamRange | null;
+  max_tokens: ParamRange | null;
 }
 
 export interface ModelWithProvider {
@@ -31,15 +147,30 @@ export interface ModelWithProvider {
   ToolCall: boolean;
   StructuredOutput: boolean;
   Image: boolean;
+  Chat?: boolean | null;
+  Embedding?: boolean | null;
+  ImageGeneration?: boolean | null;
+  Rerank?: boolean | null;
+  Moderation?: boolean | null;
   WithHeader: boolean;
+  PseudoStream?: boolean | null;
+  StreamAggregate?: boolean | null;
   CustomerHeaders: Record<string, string> | null;
   ExtraBody: Record<string, unknown> | null;
   Status: boolean | null;
   Weight: number;
+  Priority?: number;
+  MaxInFlight?: number;
+  QueueTimeout?: number;
+  QuotaRPM?: number;
+  QuotaTPM?: number;
+  Schedule?: string;
   InputPrice: number;
   CacheReadPrice: number;
   OutputPrice: number;
   Currency: string;
+  Slot: RoutingSlotName;
+  Remark?: string;
 }
 
 export interface PaginatedResponse<T> {
@@ -59,11 +190,15 @@ export interface AuthKey {
   Key: string;
   Status: boolean;
   IOLog: boolean;
+  NoIOLog?: boolean;
   AllowAll: boolean;
   Models: string[] | null;
   ExpiresAt: string | null;
   UsageCount: number;
   LastUsedAt: string | null;
+  Remark?: string;
+  RateLimit?: RateLimit;
+  TrustedUser?: string;
 }
 
 export interface SystemConfig {
@@ -74,14 +209,6 @@ export interface SystemConfig {
   min_weight: number;
 }
 
-export interface SystemStatus {
-  total_providers: number;
-  total_models: number;
-  active_requests: number;
-  uptime: string;
-  version: string;
-}
-
 export interface ProviderMetric {
   provider_id: number;
   provider_name: string;
@@ -98,11 +225,14 @@ async function apiRequest<T>(endpoint: string, options: RequestInit = {}): Promi
 
   // Get token from localStorage
   const token = localStorage.getItem("authToken");
+  // Ask the backend to localize error messages to match the UI language
+  const language = localStorage.getItem("llmio-language");
 
   const response = await fetch(url, {
     headers: {
       'Content-Type': 'application/json',
       ...(token ? { 'Authorization': `Bearer ${token}` } : {}),
+      ...(language ? { 'Accept-Language': language } : {}),
       ...options.headers,
     },
     ...options,
@@ -130,6 +260,93 @@ export async function getVersion(): Promise<string> {
   return apiRequest<string>('/version');
 }
 
+export interface BreakerStatus {
+  model_provider_id: number;
+  model_name: string;
+  provider_name: string;
+  provider_model: string;
+  state: 'open' | 'half_open';
+  fail_count: number;
+  expiry?: string;
+}
+
+export async function getBreakerStatus(): Promise<BreakerStatus[]> {
+  return apiRequest<BreakerStatus[]>('/breaker/status');
+}
+
+export async function resetBreaker(modelProviderId: number): Promise<void> {
+  await apiRequest<void>(`/breaker/reset/${modelProviderId}`, {
+    method: 'POST',
+  });
+}
+
+// Download the anonymized support bundle (zip) to attach to an issue
+export async function downloadSupportBundle(): Promise<Blob> {
+  const token = localStorage.getItem("authToken");
+  const response = await fetch(`${API_BASE}/support-bundle`, {
+    headers: token ? { 'Authorization': `Bearer ${token}` } : {},
+  });
+  if (response.status === 401) {
+    window.location.href = '/login';
+    throw new Error('Unauthorized');
+  }
+  if (!response.ok) {
+    throw new Error(`API request failed: ${response.status} ${response.statusText}`);
+  }
+  return response.blob();
+}
+
+export interface SystemResourceStatus {
+  version: string;
+  uptime_seconds: number;
+  goroutines: number;
+  num_cpu: number;
+  cpu_percent: number;
+  rss_bytes: number;
+  heap_bytes: number;
+  open_fds: number;
+  connections: Record<string, number>;
+  proc_supported: boolean;
+  log_writer: LogWriterStats;
+  streams: StreamStats;
+}
+
+export interface StreamStats {
+  active: number;
+  max: number; // 0 means unlimited
+  rejected: number;
+}
+
+export interface LogWriterStats {
+  pending: number;
+  max_pending: number;
+  written: number;
+  failed: number;
+  last_flush_ms: number;
+  avg_flush_ms: number;
+  max_flush_ms: number;
+}
+
+export async function getSystemResourceStatus(): Promise<SystemResourceStatus> {
+  return apiRequest<SystemResourceStatus>('/system/status');
+}
+
+export interface PreflightCheck {
+  name: 'webui' | 'db_write' | 'clock';
+  status: 'pass' | 'fail' | 'skip';
+  message?: string;
+}
+
+export interface PreflightReport {
+  passed: boolean;
+  checked_at: string;
+  checks: PreflightCheck[];
+}
+
+export async function getPreflight(): Promise<PreflightReport> {
+  return apiRequest<PreflightReport>('/preflight');
+}
+
 // Provider API functions
 export async function getProviders(filters: {
   name?: string;
@@ -153,6 +370,18 @@ export async function createProvider(provider: {
   console: string;
   proxy: string;
   error_matcher: string;
+  error_classes?: ErrorClassRule[];
+  cost_headers?: string;
+  user_agent?: string;
+  // Providers sharing one upstream account share RPM and budget usage
+  quota_group?: string;
+  rate_limit?: RateLimit;
+  tls?: ProviderTLS;
+  budget?: ProviderBudget;
+  headers?: Record<string, string>;
+  query_params?: Record<string, string>;
+  dns_server?: string;
+  host_overrides?: Record<string, string>;
 }): Promise<Provider> {
   return apiRequest<Provider>('/providers', {
     method: 'POST',
@@ -167,6 +396,18 @@ export async function updateProvider(id: number, provider: {
   console?: string;
   proxy?: string;
   error_matcher?: string;
+  error_classes?: ErrorClassRule[];
+  cost_headers?: string;
+  user_agent?: string;
+  // Providers sharing one upstream account share RPM and budget usage
+  quota_group?: string;
+  rate_limit?: RateLimit;
+  tls?: ProviderTLS;
+  budget?: ProviderBudget;
+  headers?: Record<string, string>;
+  query_params?: Record<string, string>;
+  dns_server?: string;
+  host_overrides?: Record<string, string>;
 }): Promise<Provider> {
   return apiRequest<Provider>(`/providers/${id}`, {
     method: 'PUT',
@@ -180,6 +421,146 @@ export async function deleteProvider(id: number): Promise<void> {
   });
 }
 
+export interface ProviderRetireStatus {
+  provider_id: number;
+  provider_name: string;
+  state: '' | 'retiring' | 'archived';
+  started_at: string | null;
+  observe_days: number;
+  observe_until: string | null;
+  retired_at: string | null;
+  associations: number;
+  remaining_traffic: number;
+  ready_to_archive: boolean;
+}
+
+export async function getProviderRetirements(): Promise<ProviderRetireStatus[]> {
+  return apiRequest<ProviderRetireStatus[]>('/providers/retirements');
+}
+
+export async function getProviderRetireStatus(id: number): Promise<ProviderRetireStatus> {
+  return apiRequest<ProviderRetireStatus>(`/providers/${id}/retire`);
+}
+
+export async function startProviderRetire(id: number, observeDays?: number): Promise<ProviderRetireStatus> {
+  return apiRequest<ProviderRetireStatus>(`/providers/${id}/retire`, {
+    method: 'POST',
+    body: JSON.stringify({ observe_days: observeDays ?? 0 }),
+  });
+}
+
+export async function cancelProviderRetire(id: number): Promise<ProviderRetireStatus> {
+  return apiRequest<ProviderRetireStatus>(`/providers/${id}/retire/cancel`, {
+    method: 'POST',
+  });
+}
+
+export async function archiveProvider(id: number, force = false): Promise<ProviderRetireStatus> {
+  return apiRequest<ProviderRetireStatus>(`/providers/${id}/retire/archive${force ? '?force=true' : ''}`, {
+    method: 'POST',
+  });
+}
+
+export interface ProviderAuthStatus {
+  provider_id: number;
+  provider_name: string;
+  disabled_at: string;
+  reason: string;
+}
+
+export async function getAuthDisabledProviders(): Promise<ProviderAuthStatus[]> {
+  return apiRequest<ProviderAuthStatus[]>('/providers/auth-disabled');
+}
+
+export async function enableProviderAuth(id: number): Promise<void> {
+  await apiRequest<void>(`/providers/${id}/auth/enable`, {
+    method: 'POST',
+  });
+}
+
+export interface ProviderBalance {
+  provider_id: number;
+  provider_name: string;
+  remaining: number;
+  currency: string;
+  checked_at: string;
+  cached: boolean;
+}
+
+export async function getProviderBalance(id: number, refresh = false): Promise<ProviderBalance> {
+  return apiRequest<ProviderBalance>(`/providers/${id}/balance${refresh ? '?refresh=true' : ''}`);
+}
+
+// Provider selftest API functions
+export interface SelftestCase {
+  name: 'models' | 'chat' | 'stream' | 'tool_call' | 'json_mode' | 'vision';
+  status: 'pass' | 'fail' | 'skip';
+  latency_ms: number;
+  error?: string;
+}
+
+export interface ProviderSelftest {
+  ID: number;
+  CreatedAt: string;
+  ProviderID: number;
+  ProviderModel: string;
+  Passed: number;
+  Failed: number;
+  Cases: SelftestCase[];
+}
+
+export async function runProviderSelftest(id: number, model?: string): Promise<ProviderSelftest> {
+  const query = model ? `?model=${encodeURIComponent(model)}` : '';
+  return apiRequest<ProviderSelftest>(`/providers/${id}/selftest${query}`, {
+    method: 'POST',
+  });
+}
+
+export async function getProviderSelftests(id: number, limit = 20): Promise<ProviderSelftest[]> {
+  return apiRequest<ProviderSelftest[]>(`/providers/${id}/selftest?limit=${limit}`);
+}
+
+// Blue/green routing API functions
+export type RoutingSlotName = 'blue' | 'green';
+
+export interface RoutingSlot {
+  active: RoutingSlotName;
+  previous: RoutingSlotName | '';
+  switched_at: string | null;
+}
+
+export interface RoutingSlotStatus extends RoutingSlot {
+  slots: {
+    slot: RoutingSlotName;
+    associations: number;
+    enabled: number;
+  }[];
+}
+
+export async function getRoutingSlot(): Promise<RoutingSlotStatus> {
+  return apiRequest<RoutingSlotStatus>('/routing/slot');
+}
+
+export async function stageRoutingSlot(from: RoutingSlotName, to: RoutingSlotName): Promise<{ slot: RoutingSlotName; copied: number }> {
+  return apiRequest<{ slot: RoutingSlotName; copied: number }>('/routing/slot/stage', {
+    method: 'POST',
+    body: JSON.stringify({ from, to }),
+  });
+}
+
+export async function switchRoutingSlot(slot: RoutingSlotName): Promise<RoutingSlot> {
+  return apiRequest<RoutingSlot>('/routing/slot/switch', {
+    method: 'POST',
+    body: JSON.stringify({ slot }),
+  });
+}
+
+export async function rollbackRoutingSlot(): Promise<RoutingSlot> {
+  return apiRequest<RoutingSlot>('/routing/slot/rollback', {
+    method: 'POST',
+  });
+}
+
 // Model API functions
 export type ModelQuery = {
   page?: number;
@@ -207,8 +588,23 @@ export async function createModel(model: {
   remark: string;
   max_retry: number;
   time_out: number;
+  retry_reserve?: number;
+  sticky_minutes?: number;
+  hedge_after_ms?: number;
+  stream_buffer?: number;
+  webhook?: string;
+  context_length?: number;
   strategy: string;
   breaker: boolean;
+  validate_response?: boolean;
+  // Forward response bytes as-is: no usage, IO log or response guard
+  raw_passthrough?: boolean;
+  param_ranges?: ParamRanges;
+  deprecation?: Deprecation;
+  io_log_policy?: IOLogPolicy;
+  response_guard?: ResponseGuard;
+  breaker_thresholds?: BreakerThresholds;
+  retry_policy?: RetryPolicy;
 }): Promise<Model> {
   return apiRequest<Model>('/models', {
     method: 'POST',
@@ -221,8 +617,23 @@ export async function updateModel(id: number, model: {
   remark?: string;
   max_retry?: number;
   time_out?: number;
+  retry_reserve?: number;
+  sticky_minutes?: number;
+  hedge_after_ms?: number;
+  stream_buffer?: number;
+  webhook?: string;
+  context_length?: number;
   strategy?: string;
   breaker?: boolean;
+  validate_response?: boolean;
+  // Forward response bytes as-is: no usage, IO log or response guard
+  raw_passthrough?: boolean;
+  param_ranges?: ParamRanges;
+  deprecation?: Deprecation;
+  io_log_policy?: IOLogPolicy;
+  response_guard?: ResponseGuard;
+  breaker_thresholds?: BreakerThresholds;
+  retry_policy?: RetryPolicy;
 }): Promise<Model> {
   return apiRequest<Model>(`/models/${id}`, {
     method: 'PUT',
@@ -230,6 +641,120 @@ export async function updateModel(id: number, model: {
   });
 }
 
+export interface WeightAdvice {
+  model_provider_id: number;
+  provider_name: string;
+  provider_model: string;
+  requests: number;
+  success_rate: number;
+  avg_latency_ms: number;
+  avg_cost: number;
+  current_weight: number;
+  suggested_weight: number;
+  reason: string;
+  remark?: string;
+}
+
+export interface WeightImpact {
+  success_rate: number;
+  avg_latency_ms: number;
+  avg_cost: number;
+}
+
+export interface WeightAdviceReport {
+  model_id: number;
+  model_name: string;
+  days: number;
+  associations: WeightAdvice[];
+  current: WeightImpact;
+  expected: WeightImpact;
+}
+
+export async function getWeightAdvice(modelId: number, days?: number): Promise<WeightAdviceReport> {
+  const query = days ? `?days=${days}` : '';
+  return apiRequest<WeightAdviceReport>(`/models/${modelId}/weight-advice${query}`);
+}
+
+// Applies exactly the weights the admin previewed, keyed by model_provider_id
+export async function applyWeightAdvice(modelId: number, weights: Record<number, number>): Promise<void> {
+  await apiRequest<void>(`/models/${modelId}/weight-advice/apply`, {
+    method: 'POST',
+    body: JSON.stringify({ weights }),
+  });
+}
+
+export interface AssociationDistribution {
+  model_provider_id: number;
+  provider_name: string;
+  provider_model: string;
+  weight: number;
+  expected_share: number;
+  attempts: number;
+  successes: number;
+  attempt_share: number;
+  actual_share: number;
+  deviation: number;
+  breaker: 'closed' | 'open' | 'half_open';
+  flag: 'ok' | 'under' | 'over' | 'insufficient';
+  reason?: string;
+  remark?: string;
+}
+
+export interface DistributionReport {
+  model_id: number;
+  model_name: string;
+  hours: number;
+  requests: number;
+  associations: AssociationDistribution[];
+}
+
+export async function getWeightDistribution(modelId: number, hours?: number): Promise<DistributionReport> {
+  const query = hours ? `?hours=${hours}` : '';
+  return apiRequest<DistributionReport>(`/models/${modelId}/distribution${query}`);
+}
+
+export interface TailEvent {
+  type: 'start' | 'finish' | 'error';
+  log_id: number;
+  model: string;
+  provider_name?: string;
+  provider_model?: string;
+  retry: number;
+  error?: string;
+  total_tokens?: number;
+  first_chunk_ms?: number;
+  elapsed_ms?: number;
+  at: string;
+}
+
+// Stream live request events of a single model until the signal is aborted
+export async function tailModel(modelId: number, onEvent: (event: TailEvent) => void, signal: AbortSignal): Promise<void> {
+  const token = localStorage.getItem("authToken");
+  const response = await fetch(`${API_BASE}/models/${modelId}/tail`, {
+    headers: token ? { 'Authorization': `Bearer ${token}` } : {},
+    signal,
+  });
+  if (!response.ok || !response.body) {
+    throw new Error(`API request failed: ${response.status} ${response.statusText}`);
+  }
+
+  const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+  let buffer = '';
+  for (;;) {
+    const { value, done } = await reader.read();
+    if (done) return;
+    buffer += value;
+    const frames = buffer.split('\n\n');
+    buffer = frames.pop() ?? '';
+    for (const frame of frames) {
+      const event = frame.match(/^event:(.*)$/m)?.[1];
+      const data = frame.match(/^data:(.*)$/m)?.[1];
+      if (!data || (event !== 'start' && event !== 'finish' && event !== 'error')) continue;
+      onEvent(JSON.parse(data) as TailEvent);
+    }
+  }
+}
+
 export async function updateModelOrder(modelIds: number[]): Promise<{ updated: number }> {
   return apiRequest<{ updated: number }>('/models/order', {
     method: 'PATCH',
@@ -249,9 +774,14 @@ export type AuthKeyPayload = {
   key?: string;
   status: boolean;
   io_log: boolean;
+  // Never store this key's request and response bodies, even when io_log is on
+  no_io_log?: boolean;
   allow_all: boolean;
   models: string[];
   expires_at?: string | null;
+  remark?: string;
+  rate_limit?: RateLimit;
+  trusted_user?: string;
 };
 
 export async function getAuthKeys(params: {
@@ -309,17 +839,20 @@ export async function toggleAuthKeyStatus(id: number): Promise<AuthKey> {
 }
 
 // Model-Provider API functions
-export async function getModelProviders(modelId: number): Promise<ModelWithProvider[]> {
-  return apiRequest<ModelWithProvider[]>(`/model-providers?model_id=${modelId}`);
+// Defaults to the active routing slot; pass 'all' to list associations from every slot
+export async function getModelProviders(modelId: number, slot?: RoutingSlotName | 'all'): Promise<ModelWithProvider[]> {
+  const params = new URLSearchParams({ model_id: modelId.toString() });
+  if (slot) params.set('slot', slot);
+  return apiRequest<ModelWithProvider[]>(`/model-providers?${params.toString()}`);
 }
 
-export async function getModelProviderStatus(providerId: number, modelName: string, providerModel: string): Promise<boolean[]> {
+export async function getModelProviderStatus(providerId: number, modelName: string, providerModel: string): Promise<ModelProviderStatus> {
   const params = new URLSearchParams({
     provider_id: providerId.toString(),
     model_name: modelName,
     provider_model: providerModel
   });
-  return apiRequest<boolean[]>(`/model-providers/status?${params.toString()}`);
+  return apiRequest<ModelProviderStatus>(`/model-providers/status?${params.toString()}`);
 }
 
 export async function createModelProvider(association: {
@@ -329,14 +862,29 @@ export async function createModelProvider(association: {
   tool_call: boolean;
   structured_output: boolean;
   image: boolean;
+  chat?: boolean;
+  embedding?: boolean;
+  image_generation?: boolean;
+  rerank?: boolean;
+  moderation?: boolean;
   with_header: boolean;
+  pseudo_stream?: boolean;
+  stream_aggregate?: boolean;
   customer_headers: Record<string, string>;
   extra_body: Record<string, unknown>;
   weight: number;
+  priority?: number;
+  max_in_flight?: number;
+  queue_timeout_ms?: number;
+  quota_rpm?: number;
+  quota_tpm?: number;
+  schedule?: string;
   input_price: number;
   cache_read_price: number;
   output_price: number;
   currency: string;
+  slot?: RoutingSlotName;
+  remark?: string;
 }): Promise<ModelWithProvider> {
   return apiRequest<ModelWithProvider>('/model-providers', {
     method: 'POST',
@@ -351,14 +899,29 @@ export async function updateModelProvider(id: number, association: {
   tool_call?: boolean;
   structured_output?: boolean;
   image?: boolean;
+  chat?: boolean;
+  embedding?: boolean;
+  image_generation?: boolean;
+  rerank?: boolean;
+  moderation?: boolean;
   with_header?: boolean;
+  pseudo_stream?: boolean;
+  stream_aggregate?: boolean;
   customer_headers?: Record<string, string>;
   extra_body?: Record<string, unknown>;
   weight?: number;
+  priority?: number;
+  max_in_flight?: number;
+  queue_timeout_ms?: number;
+  quota_rpm?: number;
+  quota_tpm?: number;
+  schedule?: string;
   input_price?: number;
   cache_read_price?: number;
   output_price?: number;
   currency?: string;
+  slot?: RoutingSlotName;
+  remark?: string;
 }): Promise<ModelWithProvider> {
   return apiRequest<ModelWithProvider>(`/model-providers/${id}`, {
     method: 'PUT',
@@ -380,10 +943,6 @@ export async function deleteModelProvider(id: number): Promise<void> {
 }
 
 // System API functions
-export async function getSystemStatus(): Promise<SystemStatus> {
-  return apiRequest<SystemStatus>('/status');
-}
-
 export async function getProviderMetrics(): Promise<ProviderMetric[]> {
   return apiRequest<ProviderMetric[]>('/metrics/providers');
 }
@@ -416,6 +975,134 @@ export async function getProjectCounts(): Promise<ProjectCount[]> {
   return apiRequest<ProjectCount[]>('/metrics/projects');
 }
 
+export interface HistogramBucket {
+  lower: number;
+  upper: number | null;
+  count: number;
+}
+
+export interface ModelHistogram {
+  model: string;
+  requests: number;
+  prompt_tokens: HistogramBucket[];
+  completion_tokens: HistogramBucket[];
+  request_size: HistogramBucket[];
+  response_size: HistogramBucket[];
+}
+
+export async function getModelHistograms(days: number, model?: string): Promise<ModelHistogram[]> {
+  const query = model ? `?model=${encodeURIComponent(model)}` : '';
+  return apiRequest<ModelHistogram[]>(`/metrics/histograms/${days}${query}`);
+}
+
+export interface ProviderResponseSize {
+  provider: string;
+  requests: number;
+  avg_size: number;
+  avg_chunks: number;
+}
+
+export async function getProviderResponseSizes(days: number): Promise<ProviderResponseSize[]> {
+  return apiRequest<ProviderResponseSize[]>(`/metrics/response-sizes/${days}`);
+}
+
+export interface ProviderFinishReasons {
+  provider: string;
+  requests: number;
+  reasons: Record<string, number>;
+  truncated_rate: number;
+  filtered_rate: number;
+}
+
+export async function getFinishReasons(days: number): Promise<ProviderFinishReasons[]> {
+  return apiRequest<ProviderFinishReasons[]>(`/metrics/finish-reasons/${days}`);
+}
+
+// Latency percentiles of successful requests in milliseconds; first_chunk_* only counts requests with a first chunk time
+export interface LatencyPercentiles {
+  model?: string;
+  provider?: string;
+  requests: number;
+  first_chunk_p50_ms: number;
+  first_chunk_p95_ms: number;
+  first_chunk_p99_ms: number;
+  latency_p50_ms: number;
+  latency_p95_ms: number;
+  latency_p99_ms: number;
+}
+
+export async function getLatencyPercentiles(
+  days: number,
+  by: "model" | "provider" = "model",
+  filters: { model?: string; providerName?: string } = {}
+): Promise<LatencyPercentiles[]> {
+  const params = new URLSearchParams({ by });
+  if (filters.model) params.append("model", filters.model);
+  if (filters.providerName) params.append("provider_name", filters.providerName);
+  return apiRequest<LatencyPercentiles[]>(`/metrics/latency/${days}?${params.toString()}`);
+}
+
+// Pre-aggregated per model/provider/key; latency percentiles cover successful requests only
+export interface MetricRollup {
+  period: 'hour' | 'day';
+  bucket_start: string;
+  model: string;
+  provider_name: string;
+  auth_key_id: number;
+  requests: number;
+  errors: number;
+  prompt_tokens: number;
+  completion_tokens: number;
+  total_tokens: number;
+  first_chunk_p50_ms: number;
+  first_chunk_p95_ms: number;
+  first_chunk_p99_ms: number;
+  latency_p50_ms: number;
+  latency_p95_ms: number;
+  latency_p99_ms: number;
+  updated_at: string;
+}
+
+export async function getMetricRollups(filters: {
+  period?: 'hour' | 'day';
+  start?: string; // RFC3339
+  end?: string; // RFC3339, exclusive
+  model?: string;
+  providerName?: string;
+  authKeyId?: number;
+} = {}): Promise<MetricRollup[]> {
+  const params = new URLSearchParams();
+  if (filters.period) params.append("period", filters.period);
+  if (filters.start) params.append("start", filters.start);
+  if (filters.end) params.append("end", filters.end);
+  if (filters.model) params.append("model", filters.model);
+  if (filters.providerName) params.append("provider_name", filters.providerName);
+  if (filters.authKeyId !== undefined) params.append("auth_key_id", filters.authKeyId.toString());
+  return apiRequest<MetricRollup[]>(`/metrics/rollups?${params.toString()}`);
+}
+
+export interface VendorStatus {
+  vendor: string;
+  status: 'operational' | 'degraded';
+  since?: string;
+  requests: number;
+  errors: number;
+  error_rate: number;
+  failing_associations: number;
+}
+
+export interface StatusPage {
+  status: 'operational' | 'degraded';
+  window: string;
+  updated_at: string;
+  vendors: VendorStatus[];
+}
+
+// Admin view of vendor status at /api/status, grouped by base_url host
+export async function getStatusPage(): Promise<StatusPage> {
+  return apiRequest<StatusPage>('/status');
+}
+
 // Test API functions
 export async function testModelProvider(id: number): Promise<unknown> {
   return apiRequest<unknown>(`/test/${id}`);
@@ -443,6 +1130,32 @@ export async function getProviderModels(providerId: number): Promise<ProviderMod
   return apiRequest<ProviderModel[]>(`/providers/models/${providerId}`);
 }
 
+export interface ModelImportItem {
+  provider_model: string;
+  alias: string;
+  model_exists: boolean;
+  associated: boolean;
+  duplicate: boolean;
+}
+
+export interface ModelImportResult {
+  items: ModelImportItem[];
+  created: number;
+}
+
+// Import provider models as llmio models; use dry_run to preview the alias mapping first
+export async function importProviderModels(providerId: number, options: {
+  models?: string[];
+  aliases?: Record<string, string>;
+  auto_alias?: boolean;
+  dry_run?: boolean;
+}): Promise<ModelImportResult> {
+  return apiRequest<ModelImportResult>(`/providers/${providerId}/import`, {
+    method: 'POST',
+    body: JSON.stringify(options),
+  });
+}
+
 // Config API functions
 export interface AnthropicCountTokens {
   base_url: string;
@@ -453,6 +1166,15 @@ export interface AnthropicCountTokens {
 export interface LogCleanupPolicy {
   enabled: boolean;
   retention_days: number;
+  io_retention_days?: number; // 0 or missing keeps IO as long as the log
+  vacuum?: boolean;
+}
+
+// Stored under the model_fallback config key; unknown model names are sent
+// verbatim to the first matching rule's provider, then to default_provider_id
+export interface ModelFallback {
+  rules: { pattern: string; provider_id: number }[];
+  default_provider_id: number;
 }
 
 export interface ConfigResponse {
@@ -477,6 +1199,7 @@ export interface ChatLog {
   CreatedAt: string;
   Name: string;
   TraceID: string;
+  RequestID?: string;
   SessionID?: string;
   ProviderModel: string;
   ProviderName: string;
@@ -491,7 +1214,13 @@ export interface ChatLog {
   ChunkTime: number;
   Tps: number;
   ChatIO: boolean;
+  Strategy?: string;
+  ModelProviderID?: number;
   Size: number;
+  RequestSize: number;
+  ChunkCount: number;
+  ImageCount: number;
+  finish_reason?: string;
   prompt_tokens: number;
   completion_tokens: number;
   total_tokens: number;
@@ -501,22 +1230,23 @@ export interface ChatLog {
   cache_read_price: number;
   output_price: number;
   currency: string;
+  cost?: number | null;
+  timeline?: TimelineEvent[];
+  signature?: string;
 }
 
-export interface PromptTokensDetails {
-  cached_tokens: number;
+export interface TimelineEvent {
+  stage: string;
+  at: string;
+  elapsed_ms: number;
+  attempt?: number;
+  provider?: string;
+  status?: number;
+  error?: string;
 }
 
-export interface ChatIO {
-  ID: number;
-  CreatedAt: string;
-  UpdatedAt: string;
-  DeletedAt?: unknown;
-  LogId: number;
-  Input: string;
-  OfString?: string | null;
-  OfStringArray?: string[] | null;
-  Style?: string;
+export interface PromptTokensDetails {
+  cached_tokens: number;
 }
 
 export interface LogsResponse {
@@ -525,6 +1255,7 @@ export interface LogsResponse {
   page: number;
   page_size: number;
   pages: number;
+  next_cursor: number; // pass as beforeId to fetch the next page; 0 when there are no more rows
 }
 
 export async function getUserAgents(): Promise<string[]> {
@@ -542,8 +1273,14 @@ export async function getLogs(
     style?: string;
     authKeyId?: string;
     traceId?: string;
+    requestId?: string;
     sessionId?: string;
+    strategy?: string;
     id?: string;
+    error?: string;
+    start?: string; // RFC3339
+    end?: string; // RFC3339, exclusive
+    beforeId?: number;
   } = {}
 ): Promise<LogsResponse> {
   const params = new URLSearchParams();
@@ -557,14 +1294,39 @@ export async function getLogs(
   if (filters.style) params.append("style", filters.style);
   if (filters.authKeyId) params.append("auth_key_id", filters.authKeyId);
   if (filters.traceId) params.append("trace_id", filters.traceId);
+  if (filters.requestId) params.append("request_id", filters.requestId);
   if (filters.sessionId) params.append("session_id", filters.sessionId);
+  if (filters.strategy) params.append("strategy", filters.strategy);
   if (filters.id) params.append("id", filters.id);
+  if (filters.error) params.append("error", filters.error);
+  if (filters.start) params.append("start", filters.start);
+  if (filters.end) params.append("end", filters.end);
+  if (filters.beforeId) params.append("before_id", filters.beforeId.toString());
 
   return apiRequest<LogsResponse>(`/logs?${params.toString()}`);
 }
 
-export async function getChatIO(logId: number): Promise<ChatIO> {
-  return apiRequest<ChatIO>(`/logs/${logId}/chat-io`);
+export async function getRequestLog(logId: number): Promise<ChatLog> {
+  return apiRequest<ChatLog>(`/logs/${logId}`);
+}
+
+// Bodies with credentials masked; each field is cut to `limit` bytes (default 64 KiB)
+export interface LogIO {
+  log_id: number;
+  style: string;
+  created_at: string;
+  input: string;
+  input_size: number;
+  input_truncated: boolean;
+  output?: string;
+  output_chunks?: string[];
+  output_size: number;
+  output_truncated: boolean;
+}
+
+export async function getLogIO(logId: number, limit?: number): Promise<LogIO> {
+  const query = limit ? `?limit=${limit}` : '';
+  return apiRequest<LogIO>(`/logs/${logId}/io${query}`);
 }
 
 // Clean logs API
@@ -605,6 +1367,47 @@ export async function getCleanupHistory(params: {
   );
 }
 
+export interface LogVerifyReport {
+  checked: number;
+  unsigned: number;
+  invalid: number;
+  invalid_ids: number[];
+}
+
+export async function verifyLogs(): Promise<LogVerifyReport> {
+  return apiRequest<LogVerifyReport>('/logs/verify');
+}
+
+export interface RateViolation {
+  scope: 'auth_key' | 'provider';
+  target_id: number;
+  name?: string;
+  rpm: number;
+  count: number;
+  mode: 'enforce' | 'observe';
+  rejected: boolean;
+  at: string;
+}
+
+export interface RateViolationTotal {
+  scope: 'auth_key' | 'provider';
+  target_id: number;
+  name?: string;
+  mode: 'enforce' | 'observe';
+  violations: number;
+  rejected: number;
+  last_at: string;
+}
+
+export interface RateLimitReport {
+  totals: RateViolationTotal[];
+  recent: RateViolation[];
+}
+
+export async function getRateLimitReport(): Promise<RateLimitReport> {
+  return apiRequest<RateLimitReport>('/rate-limits');
+}
+
 // Test API functions
 export async function testCountTokens(): Promise<void> {
   return apiRequest<void>('/test/count_tokens');
@@ -5,6 +5,7 @@ import { useForm } from "react-hook-form";
 import { zodResolver } from "@hookform/resolvers/zod";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
+import { Textarea } from "@/components/ui/textarea";
 import { Label } from "@/components/ui/label";
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from "@/components/ui/select";
 import {
@@ -78,6 +79,7 @@ const formSchema = z.object({
   allow_all: z.boolean(),
   models: z.array(z.string()),
   expires_at: z.string().nullable().optional(),
+  remark: z.string(),
 }).refine((value) => value.allow_all || value.models.length > 0, {
   path: ["models"],
 });
@@ -91,6 +93,7 @@ const defaultFormValues: AuthKeyFormValues = {
   allow_all: true,
   models: [],
   expires_at: null,
+  remark: "",
 };
 
 type MobileInfoItemProps = {
@@ -227,6 +230,7 @@ export default function AuthKeysPage() {
       allow_all: key.AllowAll,
       models: key.Models ?? [],
       expires_at: key.ExpiresAt,
+      remark: key.Remark ?? "",
     });
     setDialogOpen(true);
   };
@@ -252,6 +256,7 @@ export default function AuthKeysPage() {
         allow_all: values.allow_all,
         models: values.allow_all ? [] : values.models,
         expires_at: values.expires_at ?? undefined,
+        remark: values.remark,
       };
       if (editingKey) {
         await updateAuthKey(editingKey.ID, payload);
@@ -425,6 +430,9 @@ export default function AuthKeysPage() {
                             <TableCell>
                               <div className="flex flex-col gap-1">
                                 <span className="font-medium">{item.Name}</span>
+                                {item.Remark && (
+                                  <span className="text-xs text-muted-foreground max-w-[240px] truncate" title={item.Remark}>{item.Remark}</span>
+                                )}
                               </div>
                             </TableCell>
                             <TableCell className="align-top">
@@ -532,6 +540,7 @@ export default function AuthKeysPage() {
                         <div className="min-w-0 flex-1">
                           <h3 className="font-semibold text-sm truncate">{item.Name}</h3>
                           <p className="text-[11px] text-muted-foreground">ID: {item.ID}</p>
+                          {item.Remark && <p className="text-[11px] text-muted-foreground break-words">{item.Remark}</p>}
                         </div>
                         <span
                           className={`text-[11px] font-medium px-2 py-0.5 rounded-full ${item.Status ? 'bg-emerald-100 text-emerald-700' : 'bg-red-100 text-red-700'}`}
@@ -709,6 +718,20 @@ export default function AuthKeysPage() {
                 )}
               />
 
+              <FormField
+                control={form.control}
+                name="remark"
+                render={({ field }) => (
+                  <FormItem>
+                    <FormLabel>{t('form.remark_label')}</FormLabel>
+                    <FormControl>
+                      <Textarea {...field} rows={2} placeholder={t('form.remark_placeholder')} />
+                    </FormControl>
+                    <FormMessage />
+                  </FormItem>
+                )}
+              />
+
               <FormField
                 control={form.control}
                 name="io_log"
@@ -438,7 +438,7 @@ export default function ConfigPage() {
                       </TableCell>
                       <TableCell>{record.Type}</TableCell>
                       <TableCell className="text-right">
-                        {record.Type === 'days' ? t('log_cleanup.retention_days_value', { days: record.RetentionDays }) : '-'}
+                        {record.Type === 'days' || record.Type === 'io_days' ? t('log_cleanup.retention_days_value', { days: record.RetentionDays }) : '-'}
                       </TableCell>
                       <TableCell className="text-right">{record.DeletedCount}</TableCell>
                       <TableCell className="text-right whitespace-nowrap">
@@ -8,9 +8,10 @@ import Loading from "@/components/loading";
 import {
   getMetrics,
   getModelCounts,
-  getProjectCounts
+  getProjectCounts,
+  getSystemResourceStatus
 } from "@/lib/api";
-import type { MetricsData, ModelCount, ProjectCount } from "@/lib/api";
+import type { MetricsData, ModelCount, ProjectCount, SystemResourceStatus } from "@/lib/api";
 import { toast } from "sonner";
 import { RefreshCw } from "lucide-react";
 
@@ -72,6 +73,63 @@ const HomeHeader = memo(({ onRefresh }: HomeHeaderProps) => {
   );
 });
 
+const formatBytes = (bytes: number) => {
+  const units = ["B", "KB", "MB", "GB"];
+  let value = bytes;
+  let unit = 0;
+  while (value >= 1024 && unit < units.length - 1) {
+    value /= 1024;
+    unit++;
+  }
+  return `${value.toFixed(unit === 0 ? 0 : 1)} ${units[unit]}`;
+};
+
+const formatUptime = (seconds: number) => {
+  const days = Math.floor(seconds / 86400);
+  const hours = Math.floor((seconds % 86400) / 3600);
+  const minutes = Math.floor((seconds % 3600) / 60);
+  return days > 0 ? `${days}d ${hours}h` : `${hours}h ${minutes}m`;
+};
+
+// 进程资源与写入队列状态，/proc 不可用时对应项显示为 -
+const SystemResourceCard = memo(({ status }: { status: SystemResourceStatus }) => {
+  const { t } = useTranslation('home');
+  const proc = (value: string) => (status.proc_supported ? value : "-");
+  const items = [
+    { label: t('system.version'), value: status.version },
+    { label: t('system.uptime'), value: formatUptime(status.uptime_seconds) },
+    { label: t('system.cpu'), value: proc(`${status.cpu_percent.toFixed(1)}% / ${status.num_cpu}`) },
+    { label: t('system.rss'), value: proc(formatBytes(status.rss_bytes)) },
+    { label: t('system.heap'), value: formatBytes(status.heap_bytes) },
+    { label: t('system.goroutines'), value: status.goroutines.toLocaleString() },
+    { label: t('system.open_fds'), value: proc(status.open_fds.toLocaleString()) },
+    {
+      label: t('system.streams'),
+      value: status.streams.max > 0 ? `${status.streams.active} / ${status.streams.max}` : String(status.streams.active),
+    },
+    { label: t('system.log_pending'), value: `${status.log_writer.pending} / ${status.log_writer.max_pending}` },
+    { label: t('system.log_flush'), value: `${status.log_writer.avg_flush_ms.toFixed(1)} ms` },
+  ];
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle>{t('system.title')}</CardTitle>
+        <CardDescription>{t('system.desc')}</CardDescription>
+      </CardHeader>
+      <CardContent>
+        <div className="grid grid-cols-2 md:grid-cols-5 gap-4">
+          {items.map((item) => (
+            <div key={item.label} className="min-w-0">
+              <div className="text-xs text-muted-foreground">{item.label}</div>
+              <div className="text-lg font-semibold truncate">{item.value}</div>
+            </div>
+          ))}
+        </div>
+      </CardContent>
+    </Card>
+  );
+});
+
 export default function Home() {
   const [loading, setLoading] = useState(true);
 
@@ -80,6 +138,7 @@ export default function Home() {
   const [totalMetrics, setTotalMetrics] = useState<MetricsData>({ reqs: 0, tokens: 0 });
   const [modelCounts, setModelCounts] = useState<ModelCount[]>([]);
   const [projectCounts, setProjectCounts] = useState<ProjectCount[]>([]);
+  const [systemStatus, setSystemStatus] = useState<SystemResourceStatus | null>(null);
 
   const { t } = useTranslation('home');
 
@@ -127,11 +186,22 @@ export default function Home() {
     }
   }, [t]);
 
+  const fetchSystemStatus = useCallback(async () => {
+    try {
+      const data = await getSystemResourceStatus();
+      setSystemStatus(data);
+    } catch (err) {
+      const message = err instanceof Error ? err.message : String(err);
+      toast.error(t('errors.system_status', { message }));
+      console.error(err);
+    }
+  }, [t]);
+
   const load = useCallback(async () => {
     setLoading(true);
-    await Promise.all([fetchTodayMetrics(), fetchTotalMetrics(), fetchModelCounts(), fetchProjectCounts()]);
+    await Promise.all([fetchTodayMetrics(), fetchTotalMetrics(), fetchModelCounts(), fetchProjectCounts(), fetchSystemStatus()]);
     setLoading(false);
-  }, [fetchModelCounts, fetchProjectCounts, fetchTodayMetrics, fetchTotalMetrics]);
+  }, [fetchModelCounts, fetchProjectCounts, fetchSystemStatus, fetchTodayMetrics, fetchTotalMetrics]);
 
   useEffect(() => {
     void load();
@@ -190,6 +260,8 @@ export default function Home() {
               </Card>
             </div>
 
+            {systemStatus && <SystemResourceCard status={systemStatus} />}
+
             <div className="grid grid-cols-1 lg:grid-cols-2 gap-4">
               <Suspense fallback={<div className="h-64 flex items-center justify-center">
                 <Loading message={t('loading_chart')} />
@@ -6,7 +6,7 @@ import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
 import { Badge } from "@/components/ui/badge";
 import Loading from "@/components/loading";
 import { useTheme } from "@/components/theme-provider";
-import { getChatIO, type ChatIO } from "@/lib/api";
+import { getLogIO, type LogIO } from "@/lib/api";
 import { Prism as SyntaxHighlighter } from "react-syntax-highlighter";
 import { duotoneDark } from "react-syntax-highlighter/dist/esm/styles/prism";
 import { duotoneLight } from "react-syntax-highlighter/dist/esm/styles/prism";
@@ -789,18 +789,18 @@ function ToolItem({ tool, syntaxStyle }: { tool: ToolInfo; syntaxStyle: SyntaxSt
 
 // ── Output Display ──
 
-function OutputSection({ chatIO, style, syntaxStyle }: { chatIO: ChatIO; style: string; syntaxStyle: SyntaxStyle }) {
+function OutputSection({ chatIO, style, syntaxStyle }: { chatIO: LogIO; style: string; syntaxStyle: SyntaxStyle }) {
   const { t } = useTranslation("logs");
   const parsed = useMemo(
-    () => parseOutput(style, chatIO.OfString, chatIO.OfStringArray),
-    [style, chatIO.OfString, chatIO.OfStringArray]
+    () => parseOutput(style, chatIO.output, chatIO.output_chunks),
+    [style, chatIO.output, chatIO.output_chunks]
   );
 
   const rawOutput = useMemo(() => {
-    if (chatIO.OfString) return chatIO.OfString;
-    if (chatIO.OfStringArray && chatIO.OfStringArray.length > 0) return JSON.stringify(chatIO.OfStringArray, null, 2);
+    if (chatIO.output) return chatIO.output;
+    if (chatIO.output_chunks && chatIO.output_chunks.length > 0) return JSON.stringify(chatIO.output_chunks, null, 2);
     return "";
-  }, [chatIO.OfString, chatIO.OfStringArray]);
+  }, [chatIO.output, chatIO.output_chunks]);
 
   const rawToggle = useRawJsonToggle(rawOutput);
 
@@ -812,8 +812,8 @@ function OutputSection({ chatIO, style, syntaxStyle }: { chatIO: ChatIO; style:
           <Badge variant={parsed.type === "streaming" ? "default" : "secondary"} className="text-xs">
             {parsed.type === "streaming" ? t("chat_io.streaming") : t("chat_io.complete")}
           </Badge>
-          {parsed.type === "streaming" && chatIO.OfStringArray && (
-            <span className="text-xs text-muted-foreground">{t("chat_io.chunks_count", { count: chatIO.OfStringArray.length })}</span>
+          {parsed.type === "streaming" && chatIO.output_chunks && (
+            <span className="text-xs text-muted-foreground">{t("chat_io.chunks_count", { count: chatIO.output_chunks.length })}</span>
           )}
         </div>
         {rawOutput && <RawJsonButton show={rawToggle.show} toggle={rawToggle.toggle} />}
@@ -854,10 +854,10 @@ function OutputSection({ chatIO, style, syntaxStyle }: { chatIO: ChatIO; style:
               </CollapsibleSection>
             ) : null}
 
-            {parsed.type === "streaming" && chatIO.OfStringArray && chatIO.OfStringArray.length > 0 && (
-              <CollapsibleSection title={t("chat_io.stream_chunks")} badge={`${chatIO.OfStringArray.length}`}>
+            {parsed.type === "streaming" && chatIO.output_chunks && chatIO.output_chunks.length > 0 && (
+              <CollapsibleSection title={t("chat_io.stream_chunks")} badge={`${chatIO.output_chunks.length}`}>
                 <div className="space-y-1 max-h-96 overflow-y-auto">
-                  {chatIO.OfStringArray.map((chunk, i) => (
+                  {chatIO.output_chunks.map((chunk, i) => (
                     <div key={i} className="text-xs font-mono bg-muted/30 rounded px-2 py-1 border break-all">
                       <span className="text-muted-foreground mr-2">#{i + 1}</span>
                       {chunk}
@@ -913,11 +913,11 @@ export default function LogChatPage() {
   const { t } = useTranslation(["logs", "common"]);
   const { logId } = useParams<{ logId: string }>();
   const navigate = useNavigate();
-  const [chatIO, setChatIO] = useState<ChatIO | null>(null);
+  const [chatIO, setChatIO] = useState<LogIO | null>(null);
   const [loading, setLoading] = useState(true);
   const [loadErrorMessage, setLoadErrorMessage] = useState<string | null>(null);
   const syntaxStyle = useSyntaxStyle();
-  const style = chatIO?.Style ?? "openai";
+  const style = chatIO?.style || "openai";
 
   useEffect(() => {
     if (!logId) {
@@ -939,7 +939,7 @@ export default function LogChatPage() {
 
     const fetchChatIO = async () => {
       try {
-        const data = await getChatIO(parsedId);
+        const data = await getLogIO(parsedId);
         setChatIO(data);
         setLoadErrorMessage(null);
       } catch (fetchError) {
@@ -991,6 +991,11 @@ export default function LogChatPage() {
 
       {!loadErrorMessage && chatIO && (
         <div className="space-y-6">
+          {(chatIO.input_truncated || chatIO.output_truncated) && (
+            <p className="text-sm text-muted-foreground">
+              {t("chat_io.truncated", { input: chatIO.input_size, output: chatIO.output_size })}
+            </p>
+          )}
           <InputSection raw={chatIO.Input} style={style} syntaxStyle={syntaxStyle} />
           <OutputSection chatIO={chatIO} style={style} syntaxStyle={syntaxStyle} />
         </div>
@@ -60,6 +60,8 @@ const getStatusTextClass = (status: string) => {
       return "text-green-500";
     case "running":
       return "text-amber-500";
+    case "canceled":
+      return "text-gray-500";
     default:
       return "text-red-500";
   }
@@ -71,6 +73,8 @@ const getStatusPillClass = (status: string) => {
       return "bg-green-100 text-green-700";
     case "running":
       return "bg-amber-100 text-amber-700";
+    case "canceled":
+      return "bg-gray-100 text-gray-700";
     default:
       return "bg-red-100 text-red-700";
   }
@@ -82,6 +86,8 @@ const getStatusDetailClass = (status: string) => {
       return "text-green-600";
     case "running":
       return "text-amber-600";
+    case "canceled":
+      return "text-gray-600";
     default:
       return "text-red-600";
   }
@@ -110,6 +116,7 @@ export default function LogsPage() {
   const traceIdFilter = searchParams.get('traceId') ?? '';
   const sessionIdFilter = searchParams.get('sessionId') ?? '';
   const idFilter = searchParams.get('id') ?? '';
+  const errorFilter = searchParams.get('error') ?? '';
 
   const patchParams = (patch: Record<string, string | number>) => {
     setSearchParams(prev => {
@@ -135,6 +142,7 @@ export default function LogsPage() {
   const setTraceIdFilter = (v: string) => patchParams({ traceId: v, page: 1 });
   const setSessionIdFilter = (v: string) => patchParams({ sessionId: v, page: 1 });
   const setIdFilter = (v: string) => patchParams({ id: v, page: 1 });
+  const setErrorFilter = (v: string) => patchParams({ error: v, page: 1 });
   // 详情弹窗
   const [selectedLog, setSelectedLog] = useState<ChatLog | null>(null);
   const [isDialogOpen, setIsDialogOpen] = useState(false);
@@ -183,6 +191,7 @@ export default function LogsPage() {
         traceId: traceIdFilter.trim() || undefined,
         sessionId: sessionIdFilter.trim() || undefined,
         id: idFilter.trim() || undefined,
+        error: errorFilter.trim() || undefined,
       });
       setLogs(result.data);
       setTotal(result.total);
@@ -198,7 +207,7 @@ export default function LogsPage() {
     fetchModels();
     fetchAuthKeys();
     fetchLogs();
-  }, [page, pageSize, providerNameFilter, modelFilter, statusFilter, styleFilter, authKeyFilter, traceIdFilter, sessionIdFilter, idFilter]);
+  }, [page, pageSize, providerNameFilter, modelFilter, statusFilter, styleFilter, authKeyFilter, traceIdFilter, sessionIdFilter, idFilter, errorFilter]);
   const handlePageChange = (newPage: number) => {
     if (newPage >= 1 && newPage <= pages) patchParams({ page: newPage });
   };
@@ -234,7 +243,7 @@ export default function LogsPage() {
     setSelectedLog(log);
     setIsDialogOpen(true);
   };
-  const canViewChatIO = (log: ChatLog) => log.Status === 'success' && log.ChatIO;
+  const canViewChatIO = (log: ChatLog) => (log.Status === 'success' || log.Status === 'canceled') && log.ChatIO;
   const handleViewChatIO = (log: ChatLog) => {
     if (!canViewChatIO(log)) return;
     navigate(`/logs/${log.ID}/chat-io`);
@@ -274,6 +283,15 @@ export default function LogsPage() {
                 className="h-8 text-xs w-44 lg:w-64 pl-7"
               />
             </div>
+            <div className="relative">
+              <Search className="size-3.5 absolute left-2 top-1/2 -translate-y-1/2 text-muted-foreground" />
+              <Input
+                placeholder={t('error_placeholder')}
+                value={errorFilter}
+                onChange={(e) => setErrorFilter(e.target.value)}
+                className="h-8 text-xs w-44 lg:w-64 pl-7"
+              />
+            </div>
           </div>
           <div className="flex gap-2">
             <Button
@@ -341,6 +359,7 @@ export default function LogsPage() {
                 <SelectItem value="success">{t('filters.status_success')}</SelectItem>
                 <SelectItem value="running">{t('filters.status_running')}</SelectItem>
                 <SelectItem value="error">{t('filters.status_error')}</SelectItem>
+                <SelectItem value="canceled">{t('filters.status_canceled')}</SelectItem>
               </SelectContent>
             </Select>
           </div>
@@ -569,6 +588,12 @@ export default function LogsPage() {
                         <span className="font-mono text-xs break-all">{selectedLog.TraceID}</span>
                       </div>
                     )}
+                    {selectedLog.RequestID && (
+                      <div className="text-sm">
+                        <span className="text-muted-foreground">{t('detail.request_id')}</span>
+                        <span className="font-mono text-xs break-all">{selectedLog.RequestID}</span>
+                      </div>
+                    )}
                     {selectedLog.SessionID && (
                       <div className="text-sm">
                         <span className="text-muted-foreground">{t('detail.session_id')}</span>
@@ -626,15 +651,17 @@ export default function LogsPage() {
                   </div>
                 </div>
                 {(() => {
-                  const hasPricing = (selectedLog.input_price ?? 0) > 0 || (selectedLog.output_price ?? 0) > 0;
+                  const reportedCost = selectedLog.cost ?? null;
+                  const hasPricing = reportedCost !== null || (selectedLog.input_price ?? 0) > 0 || (selectedLog.output_price ?? 0) > 0;
                   const sym = selectedLog.currency === "USD" ? "$" : "¥";
                   const cached = selectedLog.prompt_tokens_details?.cached_tokens ?? 0;
                   const inputTokens = Math.max(0, selectedLog.prompt_tokens - cached);
                   const fmtCost = (tokens: number, price: number) =>
                     hasPricing && price > 0 ? `${sym}${(tokens / 1_000_000 * price).toFixed(6)}` : "-";
-                  const totalCost = inputTokens / 1e6 * (selectedLog.input_price ?? 0)
+                  // 上游响应头返回的费用优先于本地单价
+                  const totalCost = reportedCost ?? (inputTokens / 1e6 * (selectedLog.input_price ?? 0)
                     + cached / 1e6 * (selectedLog.cache_read_price ?? 0)
-                    + selectedLog.completion_tokens / 1e6 * (selectedLog.output_price ?? 0);
+                    + selectedLog.completion_tokens / 1e6 * (selectedLog.output_price ?? 0));
                   return (
                     <div className="space-y-3">
                       <p className="text-xs font-semibold uppercase tracking-wide text-muted-foreground">{t('detail.billing')}</p>
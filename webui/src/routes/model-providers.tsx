@@ -238,7 +238,7 @@ export default function ModelProvidersPage() {
             selectedModel.Name,
             provider.ProviderModel
           );
-          newStatus[provider.ID] = status;
+          newStatus[provider.ID] = status.recent;
         } catch (error) {
           console.error(`Failed to load status for provider ${provider.ID}:`, error);
           newStatus[provider.ID] = [];
@@ -1099,8 +1099,9 @@ export default function ModelProvidersPage() {
                       return (
                         <TableRow key={association.ID}>
                           <TableCell className="font-mono text-xs text-muted-foreground">{association.ID}</TableCell>
-                          <TableCell className="max-w-[200px] truncate" title={association.ProviderModel}>
-                            {association.ProviderModel}
+                          <TableCell className="max-w-[200px]" title={association.Remark ? `${association.ProviderModel}\n${association.Remark}` : association.ProviderModel}>
+                            <div className="truncate">{association.ProviderModel}</div>
+                            {association.Remark && <div className="truncate text-xs text-muted-foreground">{association.Remark}</div>}
                           </TableCell>
                           <TableCell>{provider?.Type ?? t('common:unknown')}</TableCell>
                           <TableCell>{provider?.Name ?? t('common:unknown')}</TableCell>
@@ -1206,6 +1207,7 @@ export default function ModelProvidersPage() {
                       <div className="min-w-0 flex-1">
                         <h3 className="font-semibold text-sm truncate">{provider?.Name ?? t('association_table.unknown_provider')}</h3>
                         <p className="text-[11px] text-muted-foreground">{t('association_table.mobile.provider_type')}: {association.ProviderModel}</p>
+                        {association.Remark && <p className="text-[11px] text-muted-foreground break-words">{association.Remark}</p>}
                       </div>
                       <span
                         className={`text-[11px] font-medium px-2 py-0.5 rounded-full ${isAssociationEnabled ? 'bg-emerald-100 text-emerald-700' : 'bg-red-100 text-red-700'}`}
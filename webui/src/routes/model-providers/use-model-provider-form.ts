@@ -18,14 +18,22 @@ export const modelProviderFormSchema = z.object({
   tool_call: z.boolean(),
   structured_output: z.boolean(),
   image: z.boolean(),
+  chat: z.boolean(),
+  embedding: z.boolean(),
+  rerank: z.boolean(),
+  image_generation: z.boolean(),
+  moderation: z.boolean(),
   with_header: z.boolean(),
-  weight: z.number().positive({ message: "权重必须大于0" }),
+  pseudo_stream: z.boolean(),
+  stream_aggregate: z.boolean(),
+  weight: z.number().int().min(0, { message: "权重不能小于0" }),
   customer_headers: z.array(headerPairSchema).default([]),
   extra_body: z.string().default(""),
   input_price: z.number().min(0).default(0),
   cache_read_price: z.number().min(0).default(0),
   output_price: z.number().min(0).default(0),
   currency: z.enum(["CNY", "USD"]).default("CNY"),
+  remark: z.string().default(""),
 });
 
 export type ModelProviderFormValues = z.input<typeof modelProviderFormSchema>;
@@ -58,7 +66,14 @@ export const useModelProviderForm = ({
       tool_call: false,
       structured_output: false,
       image: false,
+      chat: true,
+      embedding: false,
+      rerank: false,
+      image_generation: false,
+      moderation: false,
       with_header: false,
+      pseudo_stream: false,
+      stream_aggregate: false,
       weight: 1,
       customer_headers: [],
       extra_body: "",
@@ -66,6 +81,7 @@ export const useModelProviderForm = ({
       cache_read_price: 0,
       output_price: 0,
       currency: "CNY",
+      remark: "",
     };
   };
 
@@ -113,7 +129,14 @@ export const useModelProviderForm = ({
       tool_call: values.tool_call,
       structured_output: values.structured_output,
       image: values.image,
+      chat: values.chat,
+      embedding: values.embedding,
+      rerank: values.rerank,
+      image_generation: values.image_generation,
+      moderation: values.moderation,
       with_header: values.with_header,
+      pseudo_stream: values.pseudo_stream,
+      stream_aggregate: values.stream_aggregate,
       customer_headers: headers,
       extra_body: extraBody,
       weight: values.weight,
@@ -121,6 +144,7 @@ export const useModelProviderForm = ({
       cache_read_price: values.cache_read_price ?? 0,
       output_price: values.output_price ?? 0,
       currency: values.currency ?? "CNY",
+      remark: values.remark ?? "",
     };
   };
 
@@ -141,7 +165,14 @@ export const useModelProviderForm = ({
       tool_call: association.ToolCall,
       structured_output: association.StructuredOutput,
       image: association.Image,
+      chat: association.Chat ?? true,
+      embedding: association.Embedding ?? false,
+      rerank: association.Rerank ?? false,
+      image_generation: association.ImageGeneration ?? false,
+      moderation: association.Moderation ?? false,
       with_header: association.WithHeader,
+      pseudo_stream: association.PseudoStream ?? false,
+      stream_aggregate: association.StreamAggregate ?? false,
       weight: association.Weight,
       customer_headers: headerPairs.length ? headerPairs : [],
       extra_body: extraBodyStr,
@@ -149,6 +180,7 @@ export const useModelProviderForm = ({
       cache_read_price: association.CacheReadPrice ?? 0,
       output_price: association.OutputPrice ?? 0,
       currency: (association.Currency as "CNY" | "USD") || "CNY",
+      remark: association.Remark ?? "",
     });
     setOpen(true);
   };
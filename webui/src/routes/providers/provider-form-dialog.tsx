@@ -229,6 +229,40 @@ export function ProviderFormDialog({
               )}
             />
 
+            <FormField
+              control={form.control}
+              name="cost_headers"
+              render={({ field }) => (
+                <FormItem>
+                  <FormLabel>{t('form.cost_headers_label')}</FormLabel>
+                  <FormControl>
+                    <Input {...field} placeholder="x-openrouter-cost" />
+                  </FormControl>
+                  <p className="text-xs text-muted-foreground">
+                    {t('form.cost_headers_hint')}
+                  </p>
+                  <FormMessage />
+                </FormItem>
+              )}
+            />
+
+            <FormField
+              control={form.control}
+              name="user_agent"
+              render={({ field }) => (
+                <FormItem>
+                  <FormLabel>{t('form.user_agent_label')}</FormLabel>
+                  <FormControl>
+                    <Input {...field} placeholder="llmio/<version>" />
+                  </FormControl>
+                  <p className="text-xs text-muted-foreground">
+                    {t('form.user_agent_hint')}
+                  </p>
+                  <FormMessage />
+                </FormItem>
+              )}
+            />
+
             <FormField
               control={form.control}
               name="console"
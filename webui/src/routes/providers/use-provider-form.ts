@@ -20,6 +20,8 @@ export const providerFormSchema = z.object({
   console: z.string().optional(),
   proxy: z.string().optional(),
   error_matcher: z.string().optional(),
+  cost_headers: z.string().optional(),
+  user_agent: z.string().optional(),
 });
 
 export type ProviderFormValues = z.infer<typeof providerFormSchema>;
@@ -31,6 +33,8 @@ const defaultFormValues: ProviderFormValues = {
   console: "",
   proxy: "",
   error_matcher: "",
+  cost_headers: "",
+  user_agent: "",
 };
 
 type UseProviderFormParams = {
@@ -129,6 +133,8 @@ export const useProviderForm = ({
       console: provider.Console || "",
       proxy: provider.Proxy || "",
       error_matcher: provider.ErrorMatcher || "",
+      cost_headers: provider.CostHeaders || "",
+      user_agent: provider.UserAgent || "",
     });
     setOpen(true);
   };
@@ -150,6 +156,8 @@ export const useProviderForm = ({
       console: "",
       proxy: "",
       error_matcher: "",
+      cost_headers: "",
+      user_agent: "",
     });
     setOpen(true);
   };
@@ -164,6 +172,8 @@ export const useProviderForm = ({
           console: values.console || "",
           proxy: values.proxy || "",
           error_matcher: values.error_matcher || "",
+          cost_headers: values.cost_headers || "",
+          user_agent: values.user_agent || "",
         });
         toast.success(`提供商 ${values.name} 更新成功`);
         setEditingProvider(null);
@@ -175,6 +185,8 @@ export const useProviderForm = ({
           console: values.console || "",
           proxy: values.proxy || "",
           error_matcher: values.error_matcher || "",
+          cost_headers: values.cost_headers || "",
+          user_agent: values.user_agent || "",
         });
         toast.success(`提供商 ${values.name} 创建成功`);
       }